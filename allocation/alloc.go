@@ -0,0 +1,140 @@
+package allocation
+
+import "sync"
+
+// Node is a node of a binary search tree, the structure every allocator builds
+type Node struct {
+	Key         int
+	Left, Right *Node
+}
+
+// Allocator hands out the nodes for one tree and takes them all back when the tree is done with
+type Allocator interface {
+	// New returns a zeroed node
+	New() *Node
+	// Release is called once the tree rooted at root is no longer used, none of its nodes may be touched after
+	Release(root *Node)
+}
+
+// Insert adds key to the tree rooted at root, a node from a for it, and returns the root, a key already in the tree is
+// left as it was
+func Insert(a Allocator, root *Node, key int) *Node {
+	link := &root
+	for *link != nil {
+		switch n := *link; {
+		case key < n.Key:
+			link = &n.Left
+		case key > n.Key:
+			link = &n.Right
+		default:
+			return root
+		}
+	}
+	n := a.New()
+	n.Key = key
+	*link = n
+	return root
+}
+
+// Height is the number of nodes on the longest path from the root down
+func Height(root *Node) int {
+	if root == nil {
+		return 0
+	}
+	return 1 + max(Height(root.Left), Height(root.Right))
+}
+
+// walk calls fn with every node, children before their parent, so fn may reuse a node once it's called
+func walk(n *Node, fn func(*Node)) {
+	if n == nil {
+		return
+	}
+	walk(n.Left, fn)
+	walk(n.Right, fn)
+	fn(n)
+}
+
+// Heap allocates every node from the heap and leaves the garbage collector to find them once the tree is dropped
+type Heap struct{}
+
+func (Heap) New() *Node { return &Node{} }
+
+func (Heap) Release(*Node) {}
+
+// Pool reuses nodes through a sync.Pool, safe for any number of goroutines, but the pool is emptied over two garbage
+// collections, so nodes released before a collection may be allocated again after it
+type Pool struct {
+	pool sync.Pool
+}
+
+// NewPool creates an empty pool
+func NewPool() *Pool {
+	return &Pool{pool: sync.Pool{New: func() any { return &Node{} }}}
+}
+
+func (p *Pool) New() *Node {
+	return p.pool.Get().(*Node)
+}
+
+func (p *Pool) Release(root *Node) {
+	walk(root, func(n *Node) {
+		*n = Node{}
+		p.pool.Put(n)
+	})
+}
+
+// FreeList reuses nodes through a list threaded through their Left links, for a single goroutine, nothing takes the
+// released nodes away again so it holds on to as many as the biggest tree needed
+type FreeList struct {
+	free *Node
+}
+
+func (f *FreeList) New() *Node {
+	n := f.free
+	if n == nil {
+		return &Node{}
+	}
+	f.free = n.Left
+	n.Left = nil
+	return n
+}
+
+func (f *FreeList) Release(root *Node) {
+	walk(root, func(n *Node) {
+		*n = Node{Left: f.free}
+		f.free = n
+	})
+}
+
+// Slab hands out nodes from slabs, slices of nodes allocated size at a time, one allocation for every size nodes, and
+// releasing a tree releases every node at once by starting again at the first slab
+// Like an arena it frees everything together, a node of one tree can't be freed while another tree still uses the
+// slab, and the slabs stay allocated for the next tree
+type Slab struct {
+	slabs [][]Node
+	size  int
+	// slab and next are where the next node comes from
+	slab, next int
+}
+
+// NewSlab creates a slab allocator that allocates size nodes at a time
+func NewSlab(size int) *Slab {
+	return &Slab{size: max(size, 1)}
+}
+
+func (s *Slab) New() *Node {
+	if s.next == s.size {
+		s.slab, s.next = s.slab+1, 0
+	}
+	if s.slab == len(s.slabs) {
+		s.slabs = append(s.slabs, make([]Node, s.size))
+	}
+	n := &s.slabs[s.slab][s.next]
+	s.next++
+	*n = Node{}
+	return n
+}
+
+func (s *Slab) Release(*Node) {
+	s.slab, s.next = 0, 0
+}
@@ -0,0 +1,89 @@
+package allocation
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// inOrder returns the tree's keys in order
+func inOrder(root *Node) []int {
+	keys := []int{}
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		if n != nil {
+			visit(n.Left)
+			keys = append(keys, n.Key)
+			visit(n.Right)
+		}
+	}
+	visit(root)
+	return keys
+}
+
+func TestAllocatorsBuildTheSameTree(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	keys := make([]int, 2000)
+	for i := range keys {
+		keys[i] = rng.Intn(1500)
+	}
+	want := slices.Compact(slices.Sorted(slices.Values(keys)))
+	allocators := map[string]Allocator{"heap": Heap{}, "pool": NewPool(), "free list": &FreeList{}, "slab": NewSlab(100)}
+	for name, a := range allocators {
+		var height int
+		for round := range 3 {
+			var root *Node
+			for _, k := range keys {
+				root = Insert(a, root, k)
+			}
+			if got := inOrder(root); !slices.Equal(got, want) {
+				t.Fatalf("%s round %d: in order the tree holds %d keys, want %d", name, round, len(got), len(want))
+			}
+			if round > 0 && Height(root) != height {
+				t.Fatalf("%s round %d: height %d, want %d as before", name, round, Height(root), height)
+			}
+			height = Height(root)
+			a.Release(root)
+		}
+	}
+
+	arena := NewArena(0)
+	for round := range 3 {
+		for _, k := range keys {
+			arena.Insert(k)
+		}
+		if got := arena.Keys(); !slices.Equal(got, want) {
+			t.Fatalf("arena round %d: in order the tree holds %d keys, want %d", round, len(got), len(want))
+		}
+		arena.Reset()
+	}
+}
+
+func TestReusingAllocatorsStopAllocating(t *testing.T) {
+	keys := rand.New(rand.NewSource(2)).Perm(1000)
+	build := func(a Allocator) {
+		var root *Node
+		for _, k := range keys {
+			root = Insert(a, root, k)
+		}
+		a.Release(root)
+	}
+	for name, a := range map[string]Allocator{"free list": &FreeList{}, "slab": NewSlab(64)} {
+		build(a)
+		if allocs := testing.AllocsPerRun(5, func() { build(a) }); allocs != 0 {
+			t.Errorf("%s: a second tree made %g allocations, want none", name, allocs)
+		}
+	}
+	if allocs := testing.AllocsPerRun(5, func() { build(Heap{}) }); allocs != float64(len(keys)) {
+		t.Errorf("heap: a tree of %d nodes made %g allocations, want one a node", len(keys), allocs)
+	}
+	arena := NewArena(len(keys))
+	if allocs := testing.AllocsPerRun(5, func() {
+		for _, k := range keys {
+			arena.Insert(k)
+		}
+		arena.Reset()
+	}); allocs != 0 {
+		t.Errorf("arena: a tree within its capacity made %g allocations, want none", allocs)
+	}
+}
@@ -0,0 +1,92 @@
+package allocation
+
+// none is the index of a missing child
+const none = -1
+
+// indexNode is a node of an Arena, its children are indexes into the arena's nodes rather than pointers
+type indexNode struct {
+	key         int
+	left, right int32
+}
+
+// Arena is a binary search tree whose nodes all live in one slice and point to each other by index, with no pointers
+// in it the garbage collector has nothing to scan however big it grows, and Reset frees every node at once by
+// emptying the slice, keeping its memory for the next tree
+type Arena struct {
+	nodes []indexNode
+}
+
+// NewArena creates an empty arena with room for capacity nodes
+func NewArena(capacity int) *Arena {
+	return &Arena{nodes: make([]indexNode, 0, capacity)}
+}
+
+// Insert adds key to the tree, a key already in it is left as it was
+func (a *Arena) Insert(key int) {
+	if len(a.nodes) == 0 {
+		a.nodes = append(a.nodes, indexNode{key: key, left: none, right: none})
+		return
+	}
+	i := int32(0)
+	for {
+		n := &a.nodes[i]
+		var link *int32
+		switch {
+		case key < n.key:
+			link = &n.left
+		case key > n.key:
+			link = &n.right
+		default:
+			return
+		}
+		if *link == none {
+			*link = int32(len(a.nodes))
+			a.nodes = append(a.nodes, indexNode{key: key, left: none, right: none})
+			return
+		}
+		i = *link
+	}
+}
+
+// Len is the number of keys in the tree
+func (a *Arena) Len() int {
+	return len(a.nodes)
+}
+
+// Height is the number of nodes on the longest path from the root down
+func (a *Arena) Height() int {
+	if len(a.nodes) == 0 {
+		return 0
+	}
+	var height func(i int32) int
+	height = func(i int32) int {
+		if i == none {
+			return 0
+		}
+		return 1 + max(height(a.nodes[i].left), height(a.nodes[i].right))
+	}
+	return height(0)
+}
+
+// Keys returns the keys in order
+func (a *Arena) Keys() []int {
+	keys := make([]int, 0, len(a.nodes))
+	var inOrder func(i int32)
+	inOrder = func(i int32) {
+		if i == none {
+			return
+		}
+		inOrder(a.nodes[i].left)
+		keys = append(keys, a.nodes[i].key)
+		inOrder(a.nodes[i].right)
+	}
+	if len(a.nodes) > 0 {
+		inOrder(0)
+	}
+	return keys
+}
+
+// Reset empties the tree, keeping the slice's memory
+func (a *Arena) Reset() {
+	a.nodes = a.nodes[:0]
+}
@@ -0,0 +1,236 @@
+// Package allocation is the allocation strategies lesson, teachgo alloc, building and dropping the same tree over and
+// over with a node allocated from the heap each time, reused through a sync.Pool or a free list, or handed out from
+// slabs and an arena allocated once, and what each costs in allocations and garbage collection
+package allocation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo alloc -h, its first line is the summary teachgo help lists
+const Description = `Allocation strategies, the heap against pools, slabs and arenas
+
+Builds a binary search tree of random keys, drops it and builds it again, round after round, the way a server
+builds and drops a structure for every request, taking its nodes from a different allocator each time. Every node
+allocated from the heap, left for the garbage collector, against nodes reused through a sync.Pool, reused through a
+free list, and handed out from slabs of nodes allocated a thousand at a time, and a tree whose nodes live in one
+slice and point to each other by index, an arena the collector doesn't need to scan. Reports the time per node, the
+allocations and bytes per node and the collections and pause time the rounds cost, read from runtime.MemStats, with
+-gogc to make the collector run more or less often, or -1 to turn it off.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "alloc",
+		Description: Description,
+		Difficulty:  lesson.Advanced,
+		Topics:      []string{"memory allocation", "garbage collection", "sync.Pool", "arenas"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Nodes  int   `json:"nodes"`
+	Rounds int   `json:"rounds"`
+	Slab   int   `json:"slab"`
+	GOGC   int   `json:"gogc"`
+	Seed   int64 `json:"seed"`
+}
+
+// AllocatorResult is the rounds built with one allocator, Allocs and Bytes are per node, GCs the collections that ran
+// during the rounds and PauseNs the time they stopped the world for, Height the tree's height, the same for every
+// allocator as they all build the same tree
+type AllocatorResult struct {
+	Name    string  `json:"name"`
+	Ns      float64 `json:"ns"`
+	Allocs  float64 `json:"allocs"`
+	Bytes   float64 `json:"bytes"`
+	GCs     uint32  `json:"gcs"`
+	PauseNs uint64  `json:"pause_ns"`
+	Height  int     `json:"height"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config     RunConfig         `json:"config"`
+	Env        bench.Env         `json:"env"`
+	Allocators []AllocatorResult `json:"allocators"`
+}
+
+// measure collects the garbage left so far, then runs the rounds and reads what they cost from runtime.MemStats
+func measure(ctx context.Context, name string, nodes, rounds int, round func(last bool) int) AllocatorResult {
+	result := AllocatorResult{Name: name}
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	elapsed := bench.Phase(ctx, name, func() {
+		for r := range rounds {
+			if height := round(r == rounds-1); r == rounds-1 {
+				result.Height = height
+			}
+		}
+	})
+	runtime.ReadMemStats(&after)
+	built := nodes * rounds
+	result.Ns = perOp(elapsed, built)
+	result.Allocs = float64(after.Mallocs-before.Mallocs) / float64(built)
+	result.Bytes = float64(after.TotalAlloc-before.TotalAlloc) / float64(built)
+	result.GCs = after.NumGC - before.NumGC
+	result.PauseNs = after.PauseTotalNs - before.PauseTotalNs
+	return result
+}
+
+// measureAllocator builds the tree of keys with a, releasing it at the end of each round, and returns its height on
+// the last
+func measureAllocator(ctx context.Context, name string, a Allocator, keys []int, rounds int) AllocatorResult {
+	return measure(ctx, name, len(keys), rounds, func(last bool) int {
+		var root *Node
+		for _, k := range keys {
+			root = Insert(a, root, k)
+		}
+		height := 0
+		if last {
+			height = Height(root)
+		}
+		a.Release(root)
+		return height
+	})
+}
+
+// measureArena builds the tree of keys in an arena, reset at the end of each round, the arena starts empty and grows
+// on the first round like the other allocators
+func measureArena(ctx context.Context, keys []int, rounds int) AllocatorResult {
+	arena := NewArena(0)
+	return measure(ctx, "Index arena", len(keys), rounds, func(last bool) int {
+		for _, k := range keys {
+			arena.Insert(k)
+		}
+		height := 0
+		if last {
+			height = arena.Height()
+		}
+		arena.Reset()
+		return height
+	})
+}
+
+func perOp(d time.Duration, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(d.Nanoseconds()) / float64(n)
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Allocation Strategies")
+	fmt.Fprintln(w, "=====================")
+	gogc := fmt.Sprint(config.GOGC)
+	if config.GOGC < 0 {
+		gogc = "off"
+	}
+	fmt.Fprintf(w, "Tree: %d nodes, built %d times\nSlab: %d nodes\nGOGC: %s\n", config.Nodes, config.Rounds, config.Slab, gogc)
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%-12s %12s %12s %12s %6s %12s %8s\n", "Allocator", "Time/node", "Allocs/node", "Bytes/node", "GCs", "GC pause", "Height")
+	for _, a := range result.Allocators {
+		fmt.Fprintf(w, "%-12s %12v %12.3f %12.1f %6d %12v %8d\n", a.Name, time.Duration(a.Ns), a.Allocs, a.Bytes, a.GCs,
+			time.Duration(a.PauseNs), a.Height)
+	}
+	fmt.Fprintln(w, "\nThe heap allocates every node of every round and the collector has to find them all again, the pool")
+	fmt.Fprintln(w, "and free list reuse the nodes of the last round, the slabs and the arena allocate once and free a whole")
+	fmt.Fprintln(w, "tree by starting over, the arena's nodes hold no pointers so the collector never looks inside them")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo alloc
+func Main(args []string) {
+	fs := bench.NewFlagSet("alloc", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the allocation strategies lesson measures a single run"
+	nodes := fs.Int("nodes", 100_000, "the number of nodes in the tree")
+	rounds := fs.Int("rounds", 20, "the number of times each allocator builds and drops the tree")
+	slab := fs.Int("slab", 1024, "the number of nodes the slab allocator allocates at a time")
+	gogc := fs.Int("gogc", 100, "the GOGC the rounds run with, the percentage the heap grows by between collections, -1 turns the collector off")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "alloc measures a single run, -trials isn't supported")
+	v.AtLeast("nodes", *nodes, 1)
+	v.AtLeast("rounds", *rounds, 1)
+	v.AtLeast("slab", *slab, 1)
+	v.AtLeast("gogc", *gogc, -1)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	rng := rand.New(rand.NewSource(globals.Seed))
+	keys := rng.Perm(*nodes)
+	result := RunResult{
+		Config: RunConfig{Nodes: *nodes, Rounds: *rounds, Slab: *slab, GOGC: *gogc, Seed: globals.Seed},
+		Env:    bench.CaptureEnv(),
+	}
+	defer debug.SetGCPercent(debug.SetGCPercent(*gogc))
+	ctx := context.Background()
+	slog.Info("building trees", "nodes", *nodes, "rounds", *rounds)
+	result.Allocators = []AllocatorResult{
+		measureAllocator(ctx, "Heap", Heap{}, keys, *rounds),
+		measureAllocator(ctx, "sync.Pool", NewPool(), keys, *rounds),
+		measureAllocator(ctx, "Free list", &FreeList{}, keys, *rounds),
+		measureAllocator(ctx, "Slab", NewSlab(*slab), keys, *rounds),
+		measureArena(ctx, keys, *rounds),
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per allocator, with the allocations, bytes, collections and pause time per node
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/allocation"); err != nil {
+		return err
+	}
+	config := result.Config
+	built := float64(config.Nodes * config.Rounds)
+	benchmarks := []bench.Benchmark{}
+	for _, a := range result.Allocators {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: fmt.Sprintf("Build/%s/nodes=%d/gogc=%d", a.Name, config.Nodes, config.GOGC),
+			N:    int64(config.Nodes * config.Rounds),
+			Metrics: []bench.Metric{
+				{Value: a.Ns, Unit: "ns/op"},
+				{Value: a.Bytes, Unit: "B/op"},
+				{Value: a.Allocs, Unit: "allocs/op"},
+				{Value: float64(a.GCs), Unit: "gcs"},
+				{Value: float64(a.PauseNs) / built, Unit: "pause-ns/op"},
+			},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
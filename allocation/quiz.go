@@ -0,0 +1,63 @@
+package allocation
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz alloc, it checks why the heap allocator is the one paying for collections and what the
+// free list, sync.Pool, slab and arena trade away to avoid them
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does building the tree from the heap cost garbage collections when the other allocators cost almost none?",
+		Choices: []string{
+			"Heap nodes carry a header the collector has to scan, the other allocators strip that header from their nodes",
+			"The other allocators pin their memory, and the collector only runs when nothing in the process is pinned",
+			"The heap allocator frees each node as soon as it is removed, and every free schedules a little collection work",
+			"Every round allocates a tree of new nodes and grows the heap until a collection is due, the others reuse nodes",
+		},
+		Answer:      3,
+		Explanation: "the collector runs when the heap has grown by GOGC percent since the last one, an allocator that doesn't allocate never makes it grow",
+	},
+	{
+		Prompt: "Why might a sync.Pool allocate again in later rounds when the free list never does?",
+		Choices: []string{
+			"The pool empties itself over two garbage collections, so nodes put back before one may be gone when asked for",
+			"The pool holds at most one node per P, so any nodes put back beyond that are dropped and have to be made again",
+			"The pool hands out nodes in the order they were put back, and the oldest ones are freed to make room",
+			"Get on a pool always allocates the first time each goroutine calls it, even when the pool holds nodes",
+		},
+		Answer:      0,
+		Explanation: "the pool is built to be shared between goroutines and never to hold memory for long, the free list is for one goroutine and keeps everything it's given",
+	},
+	{
+		Prompt: "Why does the slab allocator make a fraction of an allocation per node even on its first round?",
+		Choices: []string{
+			"It takes its first nodes from the sync.Pool the previous allocator filled, so only the rest are new",
+			"The compiler sees the slab's nodes don't escape and places most of them on the goroutine's stack",
+			"Each allocation is a slab of many nodes, a slab of 1024 is one allocation for 1024 of them",
+			"It only allocates a node for the first copy of each key and reuses it for every duplicate",
+		},
+		Answer:      2,
+		Explanation: "the slabs are then kept and handed out again, releasing a tree just starts over at the first slab",
+	},
+	{
+		Prompt: "Why doesn't the garbage collector need to look inside the index arena's nodes?",
+		Choices: []string{
+			"The arena is allocated outside the Go heap with a system call, where the collector never looks",
+			"Its nodes point to each other by index, so the slice holds no pointers and counts as plain data",
+			"The arena is marked as in use for the whole program, so the collector skips scanning it each cycle",
+			"The nodes are small enough to fit in one size class, and that class is scanned in a single step",
+		},
+		Answer:      1,
+		Explanation: "memory with no pointers in it is marked as having none, the collector only has to keep it alive, however many nodes it holds",
+	},
+	{
+		Prompt: "What does an arena or slab give up for freeing a whole tree at once?",
+		Choices: []string{
+			"Fast allocation, each node has to be zeroed by hand before it can be handed out again",
+			"Concurrent reads, the arena's slice can be grown at any time so readers have to take a lock",
+			"Keys of different types, a slab only holds one size of node so every key must be the same type",
+			"Freeing nodes one at a time, a node is only reclaimed with the whole arena, and stale ones read other data",
+		},
+		Answer:      3,
+		Explanation: "the cost moves from the collector to the program, which has to know when everything in the arena is done with",
+	},
+}
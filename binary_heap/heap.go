@@ -0,0 +1,178 @@
+package binaryheap
+
+import (
+	"cmp"
+	"container/heap"
+	"slices"
+)
+
+// Queue is a priority queue of ints, Pop always removes the smallest
+type Queue interface {
+	Push(x int)
+	// Pop removes and returns the smallest item, the queue must not be empty
+	Pop() int
+	Len() int
+}
+
+// Heap is a binary min-heap built from scratch, a complete binary tree kept in a slice, the children of the item at
+// i are at 2i+1 and 2i+2 and every item is no bigger than its children, so the smallest is always at 0
+// less orders the items, a Heap of any type needs only that
+type Heap[T any] struct {
+	items []T
+	less  func(a, b T) bool
+}
+
+// NewHeap creates an empty heap ordered by less, the item less puts first is popped first
+func NewHeap[T any](less func(a, b T) bool) *Heap[T] {
+	return &Heap[T]{less: less}
+}
+
+// Push appends the item as the last leaf then swaps it up past every parent bigger than it, O(log n)
+func (h *Heap[T]) Push(x T) {
+	h.items = append(h.items, x)
+	h.up(len(h.items) - 1)
+}
+
+// Pop takes the root, moves the last leaf into its place and swaps that down past every smaller child, O(log n)
+func (h *Heap[T]) Pop() T {
+	root := h.items[0]
+	last := len(h.items) - 1
+	h.items[0] = h.items[last]
+	var zero T
+	h.items[last] = zero
+	h.items = h.items[:last]
+	h.down(0)
+	return root
+}
+
+// Peek returns the smallest item without removing it, the heap must not be empty
+func (h *Heap[T]) Peek() T {
+	return h.items[0]
+}
+
+func (h *Heap[T]) Len() int {
+	return len(h.items)
+}
+
+func (h *Heap[T]) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !h.less(h.items[i], h.items[parent]) {
+			return
+		}
+		h.items[i], h.items[parent] = h.items[parent], h.items[i]
+		i = parent
+	}
+}
+
+func (h *Heap[T]) down(i int) {
+	n := len(h.items)
+	for {
+		smallest, left, right := i, 2*i+1, 2*i+2
+		if left < n && h.less(h.items[left], h.items[smallest]) {
+			smallest = left
+		}
+		if right < n && h.less(h.items[right], h.items[smallest]) {
+			smallest = right
+		}
+		if smallest == i {
+			return
+		}
+		h.items[i], h.items[smallest] = h.items[smallest], h.items[i]
+		i = smallest
+	}
+}
+
+// intHeap is the slice container/heap needs, the package supplies the algorithm and the slice the five methods it
+// calls, Push and Pop here only append and truncate, heap.Push and heap.Pop do the sifting
+type intHeap []int
+
+func (h intHeap) Len() int           { return len(h) }
+func (h intHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h intHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *intHeap) Push(x any) {
+	*h = append(*h, x.(int))
+}
+
+func (h *intHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// ContainerHeap is a Queue on container/heap, every call goes through the heap.Interface methods and boxes the item
+// in an any, the price of the standard library's one implementation for every type before generics
+type ContainerHeap struct {
+	h intHeap
+}
+
+func (c *ContainerHeap) Push(x int) {
+	heap.Push(&c.h, x)
+}
+
+func (c *ContainerHeap) Pop() int {
+	return heap.Pop(&c.h).(int)
+}
+
+func (c *ContainerHeap) Len() int {
+	return c.h.Len()
+}
+
+// SortedSlice is a Queue that keeps its items sorted largest first, so the smallest pops off the end in O(1), but
+// each Push binary searches for its place and shifts everything after it along, O(n)
+type SortedSlice struct {
+	items []int
+}
+
+func (s *SortedSlice) Push(x int) {
+	i, _ := slices.BinarySearchFunc(s.items, x, func(item, target int) int { return cmp.Compare(target, item) })
+	s.items = slices.Insert(s.items, i, x)
+}
+
+func (s *SortedSlice) Pop() int {
+	last := len(s.items) - 1
+	x := s.items[last]
+	s.items = s.items[:last]
+	return x
+}
+
+func (s *SortedSlice) Len() int {
+	return len(s.items)
+}
+
+// heapQueue adapts an int Heap to Queue
+type heapQueue struct {
+	*Heap[int]
+}
+
+func newHeapQueue() Queue {
+	return heapQueue{NewHeap(func(a, b int) bool { return a < b })}
+}
+
+// TopK returns the k largest values of the stream, largest first, holding only k of them at once in a min-heap whose
+// root is the smallest of the best so far, a value bigger than the root replaces it, O(n log k) for the stream
+func TopK(stream []int, k int) []int {
+	h := NewHeap(func(a, b int) bool { return a < b })
+	for _, x := range stream {
+		if h.Len() < k {
+			h.Push(x)
+		} else if k > 0 && x > h.Peek() {
+			h.items[0] = x
+			h.down(0)
+		}
+	}
+	top := make([]int, h.Len())
+	for i := len(top) - 1; i >= 0; i-- {
+		top[i] = h.Pop()
+	}
+	return top
+}
+
+// TopKBySorting sorts a copy of the whole stream to take its k largest, O(n log n) and a copy of every value
+func TopKBySorting(stream []int, k int) []int {
+	sorted := slices.Clone(stream)
+	slices.SortFunc(sorted, func(a, b int) int { return cmp.Compare(b, a) })
+	return sorted[:min(k, len(sorted))]
+}
@@ -0,0 +1,67 @@
+package binaryheap
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestQueuesPopInOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	items := make([]int, 1000)
+	for i := range items {
+		items[i] = rng.Intn(100) // plenty of duplicates
+	}
+	want := slices.Sorted(slices.Values(items))
+	for _, c := range candidates() {
+		queue := c.new()
+		for _, x := range items {
+			queue.Push(x)
+		}
+		if queue.Len() != len(items) {
+			t.Errorf("%s: Len() = %d after %d pushes", c.name, queue.Len(), len(items))
+		}
+		got := []int{}
+		for queue.Len() > 0 {
+			got = append(got, queue.Pop())
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s popped %v..., want %v...", c.name, got[:10], want[:10])
+		}
+	}
+}
+
+func TestHeapOrdersAnyType(t *testing.T) {
+	type task struct {
+		name     string
+		priority int
+	}
+	h := NewHeap(func(a, b task) bool { return a.priority > b.priority })
+	for _, t := range []task{{"write", 2}, {"deploy", 5}, {"test", 3}} {
+		h.Push(t)
+	}
+	if h.Peek().name != "deploy" {
+		t.Errorf("Peek() = %v, want the highest priority task, deploy", h.Peek())
+	}
+	for _, want := range []string{"deploy", "test", "write"} {
+		if got := h.Pop().name; got != want {
+			t.Errorf("Pop() = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestTopK(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	stream := make([]int, 10000)
+	for i := range stream {
+		stream[i] = rng.Intn(1000000)
+	}
+	for _, k := range []int{1, 10, 100} {
+		if got, want := TopK(stream, k), TopKBySorting(stream, k); !slices.Equal(got, want) {
+			t.Errorf("TopK(stream, %d) = %v, want %v", k, got, want)
+		}
+	}
+	if got := TopK([]int{3, 1, 2}, 5); !slices.Equal(got, []int{3, 2, 1}) {
+		t.Errorf("TopK of a stream shorter than K = %v, want the whole stream largest first, [3 2 1]", got)
+	}
+}
@@ -0,0 +1,234 @@
+// Package binaryheap is the binary heap lesson, teachgo heap, a priority queue built from scratch and on
+// container/heap, timed against a sorted slice, and a top-K over a stream
+package binaryheap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/datagen"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo heap -h, its first line is the summary teachgo help lists
+const Description = `Binary heaps and priority queues, from scratch and with container/heap
+
+Builds a generic binary min-heap from scratch and a priority queue on the standard library's container/heap, then
+times pushing a batch of items into each and popping them back out in order, against a slice kept sorted by
+insertion. Then finds the K largest values of a long stream with a heap of K items, against sorting the whole
+stream, showing O(log n) pushes beating a sorted slice's O(n) inserts and O(n log K) beating O(n log n).`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "heap",
+		Description: Description,
+		Difficulty:  lesson.Beginner,
+		Topics:      []string{"binary heaps", "priority queues", "container/heap", "generics", "top-K"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Items        int    `json:"items"`
+	Stream       int    `json:"stream"`
+	K            int    `json:"k"`
+	Distribution string `json:"distribution"`
+	Seed         int64  `json:"seed"`
+}
+
+// QueueResult is the time per push and per pop of one queue
+type QueueResult struct {
+	Name   string  `json:"name"`
+	PushNs float64 `json:"push_ns"`
+	PopNs  float64 `json:"pop_ns"`
+	// Ordered is whether every pop returned the smallest item left, a queue that got it wrong would be fast for nothing
+	Ordered bool `json:"ordered"`
+}
+
+// TopKResult is the time one way of finding the K largest values took over the whole stream
+type TopKResult struct {
+	Name string  `json:"name"`
+	Ns   float64 `json:"ns"`
+	Top  []int   `json:"top"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config RunConfig     `json:"config"`
+	Env    bench.Env     `json:"env"`
+	Queues []QueueResult `json:"queues"`
+	TopK   []TopKResult  `json:"top_k"`
+}
+
+// candidate is a queue under test, new builds an empty one so every queue starts from the same point
+type candidate struct {
+	name string
+	new  func() Queue
+}
+
+func candidates() []candidate {
+	return []candidate{
+		{"Heap", newHeapQueue},
+		{"container/heap", func() Queue { return &ContainerHeap{} }},
+		{"Sorted slice", func() Queue { return &SortedSlice{} }},
+	}
+}
+
+// measure pushes every item then pops them all, checking they come out smallest first
+func measure(ctx context.Context, c candidate, items []int) QueueResult {
+	result := QueueResult{Name: c.name}
+	queue := c.new()
+	push := bench.Phase(ctx, "push "+c.name, func() {
+		for _, x := range items {
+			queue.Push(x)
+		}
+	})
+	popped := make([]int, 0, len(items))
+	pop := bench.Phase(ctx, "pop "+c.name, func() {
+		for queue.Len() > 0 {
+			popped = append(popped, queue.Pop())
+		}
+	})
+	result.Ordered = len(popped) == len(items) && slices.IsSorted(popped)
+	result.PushNs = perOp(push, len(items))
+	result.PopNs = perOp(pop, len(items))
+	return result
+}
+
+func perOp(d time.Duration, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(d.Nanoseconds()) / float64(n)
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Priority Queue Comparison")
+	fmt.Fprintln(w, "=========================")
+	fmt.Fprintf(w, "Items: %d\nStream: %d\nK: %d\nDistribution: %s\n", config.Items, config.Stream, config.K, config.Distribution)
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%-16s %12s %12s %10s\n", "Queue", "Push/op", "Pop/op", "In order")
+	for _, q := range result.Queues {
+		fmt.Fprintf(w, "%-16s %12v %12v %10v\n", q.Name, time.Duration(q.PushNs), time.Duration(q.PopNs), q.Ordered)
+	}
+	fmt.Fprintln(w, "\nThe heaps push and pop in O(log n), a sorted slice pops in O(1) but shifts half of it along on a typical push")
+	fmt.Fprintln(w, "container/heap calls through an interface and boxes each item in an any, the generic heap does neither")
+
+	fmt.Fprintf(w, "\n=====Top %d of %d=====\n", config.K, config.Stream)
+	fmt.Fprintf(w, "%-16s %12s\n", "Method", "Time")
+	for _, t := range result.TopK {
+		fmt.Fprintf(w, "%-16s %12v\n", t.Name, time.Duration(t.Ns))
+	}
+	if len(result.TopK) > 0 {
+		fmt.Fprintf(w, "Top values: %v\n", result.TopK[0].Top)
+	}
+	fmt.Fprintln(w, "\nA heap of K items compares each value with its root, the smallest of the best so far, and only sifts the")
+	fmt.Fprintln(w, "few that beat it, O(n log K) in K items of memory, sorting the stream is O(n log n) and needs all of it at once")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo heap
+func Main(args []string) {
+	fs := bench.NewFlagSet("heap", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the heap lesson measures a single run"
+	numItems := fs.Int("items", 100000, "the number of items pushed into and popped from each queue")
+	streamLength := fs.Int("stream", 1000000, "the number of values the top-K is found among")
+	k := fs.Int("k", 10, "the number of largest values to find in the stream")
+	distribution := fs.String("dist", "uniform", "how the items and stream values are drawn, over [0, 10 x items): "+datagen.Usage)
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "heap measures a single run, -trials isn't supported")
+	v.AtLeast("items", *numItems, 1)
+	v.AtLeast("stream", *streamLength, 1)
+	v.AtLeast("k", *k, 1)
+	if *numItems >= 1 {
+		_, err := datagen.Parse(*distribution, 10**numItems)
+		v.Add(err)
+	}
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	dist := datagen.MustParse(*distribution, 10**numItems)
+	rng := rand.New(rand.NewSource(globals.Seed))
+	slog.Info("generating values", "items", *numItems, "stream", *streamLength, "distribution", dist)
+	items := dist.Fill(rng, *numItems)
+	stream := dist.Fill(rng, *streamLength)
+
+	result := RunResult{
+		Config: RunConfig{Items: *numItems, Stream: *streamLength, K: *k, Distribution: dist.String(), Seed: globals.Seed},
+		Env:    bench.CaptureEnv(),
+	}
+	ctx := context.Background()
+	for _, c := range candidates() {
+		slog.Info("measuring", "queue", c.name)
+		result.Queues = append(result.Queues, measure(ctx, c, items))
+	}
+
+	slog.Info("finding the top values", "k", *k, "stream", *streamLength)
+	for _, method := range []struct {
+		name string
+		topK func([]int, int) []int
+	}{
+		{"Heap of K", TopK},
+		{"Sort stream", TopKBySorting},
+	} {
+		var top []int
+		elapsed := bench.Phase(ctx, method.name, func() { top = method.topK(stream, *k) })
+		result.TopK = append(result.TopK, TopKResult{Name: method.name, Ns: float64(elapsed.Nanoseconds()), Top: top})
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a push and a pop line per queue, and a line per top-K method timing the whole stream
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/binary_heap"); err != nil {
+		return err
+	}
+	config := result.Config
+	items := fmt.Sprintf("items=%d", config.Items)
+	benchmarks := []bench.Benchmark{}
+	for _, q := range result.Queues {
+		benchmarks = append(benchmarks,
+			bench.Benchmark{Name: "Push/" + q.Name + "/" + items, N: int64(config.Items), Metrics: []bench.Metric{{Value: q.PushNs, Unit: "ns/op"}}},
+			bench.Benchmark{Name: "Pop/" + q.Name + "/" + items, N: int64(config.Items), Metrics: []bench.Metric{{Value: q.PopNs, Unit: "ns/op"}}},
+		)
+	}
+	for _, t := range result.TopK {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name:    fmt.Sprintf("TopK/%s/k=%d/stream=%d", t.Name, config.K, config.Stream),
+			N:       1,
+			Metrics: []bench.Metric{{Value: t.Ns, Unit: "ns/op"}},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
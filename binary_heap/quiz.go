@@ -0,0 +1,63 @@
+package binaryheap
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz heap, it covers how a heap lives in a slice, why it beats a sorted slice on push, what
+// container/heap's interface costs, and the min-heap trick behind top-K
+var quiz = []lesson.Question{
+	{
+		Prompt: "Where are the children of the item at index i in a slice backed binary heap?",
+		Choices: []string{
+			"At i+1 and i+2",
+			"At 2i and 2i+1",
+			"At 2i+1 and 2i+2",
+			"At (i-1)/2 and (i+1)/2",
+		},
+		Answer:      2,
+		Explanation: "a complete binary tree packs level by level into a slice, so a heap needs no pointers, the parent of i is at (i-1)/2",
+	},
+	{
+		Prompt: "Why does the sorted slice fall so far behind the heaps on push?",
+		Choices: []string{
+			"Each insert shifts every item after its place along one, O(n), a heap sifts one item up O(log n) levels",
+			"Its binary search has to compare every item when they are equal, the heaps stop at the first equal one",
+			"It has to grow its backing array on every push, the heaps grow theirs by doubling only when full",
+			"It keeps the whole slice sorted by calling sort.Slice after every push, O(n log n) per insert",
+		},
+		Answer:      0,
+		Explanation: "finding the place is O(log n) but making room for it is O(n), try -dist sequential, ascending items all go at the front and shift everything",
+	},
+	{
+		Prompt: "Why is container/heap usually slower than the generic heap built here?",
+		Choices: []string{
+			"It is a pairing heap rather than a binary heap, which has a larger constant factor on every pop",
+			"It re-heapifies the whole slice after each Push and Pop rather than sifting just one item",
+			"It takes a lock on every operation, so it is safe to share between goroutines even when it isn't",
+			"Less, Swap, Push and Pop are interface calls, and Push and Pop box every item in an any",
+		},
+		Answer:      3,
+		Explanation: "container/heap predates generics, so the algorithm reaches the items only through heap.Interface, the generic heap's comparisons can be inlined",
+	},
+	{
+		Prompt: "To find the K largest values of a stream, why does the heap hold the smallest of them at its root?",
+		Choices: []string{
+			"So the results come out in ascending order, smallest first, without a sort at the end",
+			"The root is the value to beat, a value bigger than it replaces it, the rest cost one comparison",
+			"A min-heap uses less memory than a max-heap because its root never has to move",
+			"A max-heap would hold the largest at the root, and that would have to be popped for every value",
+		},
+		Answer:      1,
+		Explanation: "most values in a long stream lose to the root and cost one comparison, only the few that win sift down the K items, O(n log K) overall",
+	},
+	{
+		Prompt: "What does the top-K heap need that sorting the stream doesn't?",
+		Choices: []string{
+			"More memory, it keeps a copy of every value",
+			"The stream's values to be distinct",
+			"The stream to be sorted beforehand",
+			"A fixed K known before the stream starts",
+		},
+		Answer:      3,
+		Explanation: "the heap keeps K values and can run over a stream too big to hold, sorting needs every value at once but then answers any K",
+	},
+}
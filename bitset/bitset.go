@@ -0,0 +1,121 @@
+package bitset
+
+import (
+	"iter"
+	"math/bits"
+)
+
+// Bitset is a set of the integers from 0 to n-1, one bit each, bit i%64 of word i/64 is set when i is in the set, so
+// a million possible members take 125 KiB however many are in it, and set operations work on 64 members at a time
+// The set operations take sets of the same size and return new ones
+type Bitset struct {
+	words []uint64
+	n     int
+}
+
+// New creates an empty set of the integers from 0 to n-1
+func New(n int) *Bitset {
+	return &Bitset{words: make([]uint64, (n+63)/64), n: n}
+}
+
+// Len is the size of the universe, n, not the number of members, PopCount is that
+func (b *Bitset) Len() int {
+	return b.n
+}
+
+// Set adds i
+func (b *Bitset) Set(i int) {
+	b.words[i/64] |= 1 << (i % 64)
+}
+
+// Clear removes i
+func (b *Bitset) Clear(i int) {
+	b.words[i/64] &^= 1 << (i % 64)
+}
+
+// Toggle adds i if it's missing and removes it if it's there
+func (b *Bitset) Toggle(i int) {
+	b.words[i/64] ^= 1 << (i % 64)
+}
+
+// Has reports whether i is in the set
+func (b *Bitset) Has(i int) bool {
+	return b.words[i/64]&(1<<(i%64)) != 0
+}
+
+// And returns the members of both sets, 64 at a time with one & per word
+func (b *Bitset) And(other *Bitset) *Bitset {
+	result := New(b.n)
+	for i, w := range b.words {
+		result.words[i] = w & other.words[i]
+	}
+	return result
+}
+
+// Or returns the members of either set
+func (b *Bitset) Or(other *Bitset) *Bitset {
+	result := New(b.n)
+	for i, w := range b.words {
+		result.words[i] = w | other.words[i]
+	}
+	return result
+}
+
+// Xor returns the members of one set but not both
+func (b *Bitset) Xor(other *Bitset) *Bitset {
+	result := New(b.n)
+	for i, w := range b.words {
+		result.words[i] = w ^ other.words[i]
+	}
+	return result
+}
+
+// AndNot returns the members of this set that aren't in other
+func (b *Bitset) AndNot(other *Bitset) *Bitset {
+	result := New(b.n)
+	for i, w := range b.words {
+		result.words[i] = w &^ other.words[i]
+	}
+	return result
+}
+
+// Not returns the integers below n that aren't in the set, the bits of the last word past n stay clear
+func (b *Bitset) Not() *Bitset {
+	result := New(b.n)
+	for i, w := range b.words {
+		result.words[i] = ^w
+	}
+	if extra := b.n % 64; extra != 0 {
+		result.words[len(result.words)-1] &= 1<<extra - 1
+	}
+	return result
+}
+
+// PopCount is the number of members, counting the set bits of each word, a single instruction on most CPUs
+func (b *Bitset) PopCount() int {
+	count := 0
+	for _, w := range b.words {
+		count += bits.OnesCount64(w)
+	}
+	return count
+}
+
+// All yields the members in order, skipping a word at a time past empty stretches and jumping straight to each set
+// bit with TrailingZeros, clearing it with w & (w-1)
+func (b *Bitset) All() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i, w := range b.words {
+			for w != 0 {
+				if !yield(i*64 + bits.TrailingZeros64(w)) {
+					return
+				}
+				w &= w - 1
+			}
+		}
+	}
+}
+
+// Bytes is the memory the bits take
+func (b *Bitset) Bytes() int {
+	return 8 * len(b.words)
+}
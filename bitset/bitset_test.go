@@ -0,0 +1,73 @@
+package bitset
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestBitsetMatchesAMap(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 63, 64, 65, 1000} {
+		a, b := New(n), New(n)
+		inA, inB := map[int]bool{}, map[int]bool{}
+		for range 2 * n {
+			x := rng.Intn(n)
+			switch rng.Intn(3) {
+			case 0:
+				a.Set(x)
+				inA[x] = true
+			case 1:
+				a.Toggle(x)
+				inA[x] = !inA[x]
+			default:
+				b.Set(x)
+				inB[x] = true
+			}
+		}
+		sets := map[string]*Bitset{"a": a, "And": a.And(b), "Or": a.Or(b), "Xor": a.Xor(b), "AndNot": a.AndNot(b), "Not": a.Not()}
+		want := map[string]func(x int) bool{
+			"a":      func(x int) bool { return inA[x] },
+			"And":    func(x int) bool { return inA[x] && inB[x] },
+			"Or":     func(x int) bool { return inA[x] || inB[x] },
+			"Xor":    func(x int) bool { return inA[x] != inB[x] },
+			"AndNot": func(x int) bool { return inA[x] && !inB[x] },
+			"Not":    func(x int) bool { return !inA[x] },
+		}
+		for name, set := range sets {
+			members := []int{}
+			for x := range n {
+				if want[name](x) {
+					members = append(members, x)
+				}
+			}
+			if got := slices.Collect(set.All()); !slices.Equal(got, members) {
+				t.Fatalf("n=%d %s: members %v, want %v", n, name, got, members)
+			}
+			if set.PopCount() != len(members) {
+				t.Fatalf("n=%d %s: PopCount() = %d, want %d", n, name, set.PopCount(), len(members))
+			}
+			for _, x := range members {
+				if !set.Has(x) {
+					t.Fatalf("n=%d %s: Has(%d) = false", n, name, x)
+				}
+			}
+		}
+	}
+}
+
+func TestClear(t *testing.T) {
+	b := New(100)
+	b.Set(70)
+	b.Set(3)
+	b.Clear(70)
+	if b.Has(70) || !b.Has(3) || b.PopCount() != 1 {
+		t.Errorf("after Set(70), Set(3), Clear(70) the set holds %v", slices.Collect(b.All()))
+	}
+}
+
+func TestNotStaysInTheUniverse(t *testing.T) {
+	if got := New(70).Not().PopCount(); got != 70 {
+		t.Errorf("the complement of the empty set of 70 has %d members, want 70", got)
+	}
+}
@@ -0,0 +1,421 @@
+// Package bitset is the bitset lesson, teachgo bitset, a set of small integers kept one bit each, its set operations
+// working on 64 members at a time, against map[int]bool holding the same members
+package bitset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/bits"
+	"math/rand"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo bitset -h, its first line is the summary teachgo help lists
+const Description = `Bitsets, a set of integers in one bit each
+
+Fills two sets with random members of the integers below -universe, once as bitsets, a bit for every possible
+member, and once as map[int]bool, then times adding and looking up members, intersecting, joining and complementing
+the sets, counting and iterating over their members, and measures the memory each takes. A bitset's operations go a
+64 bit word at a time, an & of two words intersects 64 members at once, and popcount counts them in one instruction.
+Then repeats the memory and intersection at a range of densities, showing the map only winning once the set is so
+sparse that most of the bitset's bits are spent saying what isn't there, and works through the bit tricks the
+bitset is built on, on a word of the first set.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "bitset",
+		Description: Description,
+		Difficulty:  lesson.Beginner,
+		Topics:      []string{"bit manipulation", "sets", "memory layout", "popcount"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// densitySweep is the densities the memory and intersection are compared at
+var densitySweep = []float64{0.0001, 0.001, 0.01, 0.1, 0.5, 0.9}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Universe int     `json:"universe"`
+	Density  float64 `json:"density"`
+	Queries  int     `json:"queries"`
+	Seed     int64   `json:"seed"`
+}
+
+// OpResult is an operation done to the bitsets and the maps, the times are per Unit, Result is what both gave, a
+// count of members, Agree whether they gave the same
+type OpResult struct {
+	Name     string  `json:"name"`
+	Unit     string  `json:"unit"`
+	BitsetNs float64 `json:"bitset_ns"`
+	MapNs    float64 `json:"map_ns"`
+	Result   int     `json:"result"`
+	Agree    bool    `json:"agree"`
+}
+
+// DensityResult is the two ways of holding a set at one density, the bytes are per member
+type DensityResult struct {
+	Density     float64 `json:"density"`
+	Members     int     `json:"members"`
+	BitsetBytes float64 `json:"bitset_bytes"`
+	MapBytes    float64 `json:"map_bytes"`
+	BitsetAndNs float64 `json:"bitset_and_ns"`
+	MapAndNs    float64 `json:"map_and_ns"`
+}
+
+// Trick is one of the bit tricks applied to a word
+type Trick struct {
+	Name   string `json:"name"`
+	Expr   string `json:"expr"`
+	Result uint16 `json:"result"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+// The bytes are per member of the first set, the map's measured as what building it at its final size allocated
+type RunResult struct {
+	Config      RunConfig       `json:"config"`
+	Env         bench.Env       `json:"env"`
+	Members     [2]int          `json:"members"`
+	BitsetBytes float64         `json:"bitset_bytes"`
+	MapBytes    float64         `json:"map_bytes"`
+	Ops         []OpResult      `json:"ops"`
+	Densities   []DensityResult `json:"densities"`
+	Word        uint16          `json:"word"`
+	Tricks      []Trick         `json:"tricks"`
+}
+
+// draw picks the members of a set, each integer below universe with probability density
+func draw(rng *rand.Rand, universe int, density float64) []int {
+	members := []int{}
+	for i := range universe {
+		if rng.Float64() < density {
+			members = append(members, i)
+		}
+	}
+	return members
+}
+
+// allocatedBytes returns the bytes build allocated, and what build returned
+// Counting allocations rather than the live heap gives the same answer every run, however the collector is timed
+func allocatedBytes[T any](build func() T) (T, uint64) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	built := build()
+	runtime.ReadMemStats(&after)
+	return built, after.TotalAlloc - before.TotalAlloc
+}
+
+func buildBitset(universe int, members []int) *Bitset {
+	b := New(universe)
+	for _, m := range members {
+		b.Set(m)
+	}
+	return b
+}
+
+func buildMap(members []int) map[int]bool {
+	m := map[int]bool{}
+	for _, x := range members {
+		m[x] = true
+	}
+	return m
+}
+
+// sizedMap builds the map sized for its members up front, so nothing it allocates is left behind by growing, what's
+// allocated is what it holds
+func sizedMap(members []int) map[int]bool {
+	m := make(map[int]bool, len(members))
+	for _, x := range members {
+		m[x] = true
+	}
+	return m
+}
+
+// intersectMaps walks the smaller map looking each member up in the other
+func intersectMaps(a, b map[int]bool) map[int]bool {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	result := map[int]bool{}
+	for x := range a {
+		if b[x] {
+			result[x] = true
+		}
+	}
+	return result
+}
+
+func unionMaps(a, b map[int]bool) map[int]bool {
+	result := make(map[int]bool, len(a))
+	for x := range a {
+		result[x] = true
+	}
+	for x := range b {
+		result[x] = true
+	}
+	return result
+}
+
+// complementMap has to try every integer in the universe, the map only knows what's in it
+func complementMap(a map[int]bool, universe int) map[int]bool {
+	result := map[int]bool{}
+	for x := range universe {
+		if !a[x] {
+			result[x] = true
+		}
+	}
+	return result
+}
+
+// measureOps fills the sets both ways and times every operation on them
+func measureOps(ctx context.Context, rng *rand.Rand, universe int, members [2][]int, queries int) []OpResult {
+	var bitsets [2]*Bitset
+	var maps [2]map[int]bool
+	added := len(members[0]) + len(members[1])
+	bitsetTime := bench.Phase(ctx, "bitset add", func() {
+		for i := range bitsets {
+			bitsets[i] = buildBitset(universe, members[i])
+		}
+	})
+	mapTime := bench.Phase(ctx, "map add", func() {
+		for i := range maps {
+			maps[i] = buildMap(members[i])
+		}
+	})
+	ops := []OpResult{{Name: "Add", Unit: "member", BitsetNs: perOp(bitsetTime, added), MapNs: perOp(mapTime, added),
+		Result: bitsets[0].PopCount() + bitsets[1].PopCount(), Agree: bitsets[0].PopCount()+bitsets[1].PopCount() == len(maps[0])+len(maps[1])}}
+
+	lookups := make([]int, queries)
+	for i := range lookups {
+		lookups[i] = rng.Intn(universe)
+	}
+	bitsetFound, mapFound := 0, 0
+	bitsetTime = bench.Phase(ctx, "bitset lookup", func() {
+		for _, x := range lookups {
+			if bitsets[0].Has(x) {
+				bitsetFound++
+			}
+		}
+	})
+	mapTime = bench.Phase(ctx, "map lookup", func() {
+		for _, x := range lookups {
+			if maps[0][x] {
+				mapFound++
+			}
+		}
+	})
+	ops = append(ops, OpResult{Name: "Look up", Unit: "lookup", BitsetNs: perOp(bitsetTime, queries), MapNs: perOp(mapTime, queries),
+		Result: bitsetFound, Agree: bitsetFound == mapFound})
+
+	// setOp times an operation on whole sets, each giving a set whose members are counted outside the timing
+	setOp := func(name string, onBitsets func() *Bitset, onMaps func() map[int]bool) {
+		var b *Bitset
+		var m map[int]bool
+		bitsetTime := bench.Phase(ctx, "bitset "+name, func() { b = onBitsets() })
+		mapTime := bench.Phase(ctx, "map "+name, func() { m = onMaps() })
+		ops = append(ops, OpResult{Name: name, Unit: "set", BitsetNs: float64(bitsetTime.Nanoseconds()), MapNs: float64(mapTime.Nanoseconds()),
+			Result: b.PopCount(), Agree: b.PopCount() == len(m)})
+	}
+	setOp("Intersect", func() *Bitset { return bitsets[0].And(bitsets[1]) }, func() map[int]bool { return intersectMaps(maps[0], maps[1]) })
+	setOp("Union", func() *Bitset { return bitsets[0].Or(bitsets[1]) }, func() map[int]bool { return unionMaps(maps[0], maps[1]) })
+	setOp("Complement", bitsets[0].Not, func() map[int]bool { return complementMap(maps[0], universe) })
+
+	var bitsetCount, mapCount int
+	bitsetTime = bench.Phase(ctx, "bitset count", func() { bitsetCount = bitsets[0].PopCount() })
+	mapTime = bench.Phase(ctx, "map count", func() { mapCount = len(maps[0]) })
+	ops = append(ops, OpResult{Name: "Count", Unit: "set", BitsetNs: float64(bitsetTime.Nanoseconds()), MapNs: float64(mapTime.Nanoseconds()),
+		Result: bitsetCount, Agree: bitsetCount == mapCount})
+
+	var bitsetSum, mapSum, bitsetSeen, mapSeen int
+	bitsetTime = bench.Phase(ctx, "bitset iterate", func() {
+		for x := range bitsets[0].All() {
+			bitsetSum += x
+			bitsetSeen++
+		}
+	})
+	mapTime = bench.Phase(ctx, "map iterate", func() {
+		for x := range maps[0] {
+			mapSum += x
+			mapSeen++
+		}
+	})
+	ops = append(ops, OpResult{Name: "Iterate", Unit: "member", BitsetNs: perOp(bitsetTime, bitsetSeen), MapNs: perOp(mapTime, mapSeen),
+		Result: bitsetSeen, Agree: bitsetSeen == mapSeen && bitsetSum == mapSum})
+	return ops
+}
+
+// measureDensity builds two sets of a density both ways, measuring the memory the first takes and timing their
+// intersection
+func measureDensity(ctx context.Context, rng *rand.Rand, universe int, density float64) DensityResult {
+	a, b := draw(rng, universe, density), draw(rng, universe, density)
+	result := DensityResult{Density: density, Members: len(a)}
+	bitsetA := buildBitset(universe, a)
+	mapA, mapBytes := allocatedBytes(func() map[int]bool { return sizedMap(a) })
+	bitsetB, mapB := buildBitset(universe, b), buildMap(b)
+	if len(a) > 0 {
+		result.BitsetBytes = float64(bitsetA.Bytes()) / float64(len(a))
+		result.MapBytes = float64(mapBytes) / float64(len(a))
+	}
+	result.BitsetAndNs = float64(bench.Phase(ctx, "bitset and", func() { bitsetA.And(bitsetB) }).Nanoseconds())
+	result.MapAndNs = float64(bench.Phase(ctx, "map and", func() { intersectMaps(mapA, mapB) }).Nanoseconds())
+	return result
+}
+
+// tricks works through the bit tricks on a word
+func tricks(x uint16) []Trick {
+	return []Trick{
+		{"Clear the lowest set bit", "x & (x-1)", x & (x - 1)},
+		{"Keep only the lowest set bit", "x & -x", x & -x},
+		{"Set the lowest clear bit", "x | (x+1)", x | (x + 1)},
+		{"Set bits, popcount", "OnesCount(x)", uint16(bits.OnesCount16(x))},
+		{"Index of the lowest set bit", "TrailingZeros(x)", uint16(bits.TrailingZeros16(x))},
+		{"Clear bit 3", "x &^ (1<<3)", x &^ (1 << 3)},
+		{"Toggle bit 15", "x ^ (1<<15)", x ^ (1 << 15)},
+	}
+}
+
+func perOp(d time.Duration, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(d.Nanoseconds()) / float64(n)
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Bitsets")
+	fmt.Fprintln(w, "=======")
+	fmt.Fprintf(w, "Universe: the integers below %d\nDensity: %g, %d and %d members\nQueries: %d\n",
+		config.Universe, config.Density, result.Members[0], result.Members[1], config.Queries)
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%-12s %-8s %14s %14s %10s %10s\n", "Operation", "Per", "Bitset", "map[int]bool", "Speedup", "Result")
+	for _, op := range result.Ops {
+		res := fmt.Sprint(op.Result)
+		if !op.Agree {
+			res += " (disagree)"
+		}
+		speedup := "-"
+		if op.BitsetNs > 0 && op.MapNs > 0 {
+			speedup = fmt.Sprintf("%.2fx", op.MapNs/op.BitsetNs)
+		}
+		fmt.Fprintf(w, "%-12s %-8s %14v %14v %10s %10s\n", op.Name, op.Unit, time.Duration(op.BitsetNs), time.Duration(op.MapNs),
+			speedup, res)
+	}
+	fmt.Fprintf(w, "\nMemory per member of the first set, bitset %.2f bytes, map %.1f bytes\n", result.BitsetBytes, result.MapBytes)
+	fmt.Fprintln(w, "The bitset's set operations take a word of 64 members at a time, the map's a member at a time, only")
+	fmt.Fprintln(w, "counting favours the map, which keeps its length where the bitset has to count every word")
+
+	fmt.Fprintln(w, "\n=====Memory and intersection by density=====")
+	fmt.Fprintf(w, "%10s %10s %14s %14s %14s %14s\n", "Density", "Members", "Bitset B/mbr", "Map B/mbr", "Bitset and", "Map and")
+	for _, d := range result.Densities {
+		fmt.Fprintf(w, "%10g %10d %14.2f %14.1f %14v %14v\n", d.Density, d.Members, d.BitsetBytes, d.MapBytes,
+			time.Duration(d.BitsetAndNs), time.Duration(d.MapAndNs))
+	}
+	fmt.Fprintln(w, "\nThe bitset costs a bit for every integer that could be a member, the map tens of bytes for every one")
+	fmt.Fprintln(w, "that is, so the map only takes less memory once fewer than about one in a few hundred are members")
+
+	fmt.Fprintf(w, "\n=====Bit tricks on the first set's lowest 16 bits, x = %016b=====\n", result.Word)
+	for _, t := range result.Tricks {
+		value := fmt.Sprintf("%016b", t.Result)
+		if t.Expr == "OnesCount(x)" || t.Expr == "TrailingZeros(x)" {
+			value = fmt.Sprint(t.Result)
+		}
+		fmt.Fprintf(w, "%-30s %-18s %s\n", t.Name, t.Expr, value)
+	}
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo bitset
+func Main(args []string) {
+	fs := bench.NewFlagSet("bitset", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the bitset lesson measures a single run"
+	universe := fs.Int("universe", 1_000_000, "the number of integers that could be members, 0 to universe-1")
+	density := fs.Float64("density", 0.5, "the fraction of the universe in each set")
+	queries := fs.Int("queries", 1_000_000, "the number of lookups timed")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "bitset measures a single run, -trials isn't supported")
+	v.AtLeast("universe", *universe, 64)
+	v.Fraction("density", *density)
+	v.AtLeast("queries", *queries, 1)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	rng := rand.New(rand.NewSource(globals.Seed))
+	result := RunResult{
+		Config: RunConfig{Universe: *universe, Density: *density, Queries: *queries, Seed: globals.Seed},
+		Env:    bench.CaptureEnv(),
+	}
+	ctx := context.Background()
+	members := [2][]int{draw(rng, *universe, *density), draw(rng, *universe, *density)}
+	result.Members = [2]int{len(members[0]), len(members[1])}
+	slog.Info("timing set operations", "universe", *universe, "density", *density)
+	result.Ops = measureOps(ctx, rng, *universe, members, *queries)
+	first := buildBitset(*universe, members[0])
+	_, mapBytes := allocatedBytes(func() map[int]bool { return sizedMap(members[0]) })
+	if n := len(members[0]); n > 0 {
+		result.BitsetBytes = float64(first.Bytes()) / float64(n)
+		result.MapBytes = float64(mapBytes) / float64(n)
+	}
+
+	slog.Info("comparing densities", "densities", densitySweep)
+	for _, d := range densitySweep {
+		result.Densities = append(result.Densities, measureDensity(ctx, rng, *universe, d))
+	}
+	result.Word = uint16(first.words[0])
+	result.Tricks = tricks(result.Word)
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per operation and way of holding the set, and a line per density with the bytes per
+// member
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/bitset"); err != nil {
+		return err
+	}
+	config := result.Config
+	size := fmt.Sprintf("universe=%d/density=%g", config.Universe, config.Density)
+	benchmarks := []bench.Benchmark{}
+	for _, op := range result.Ops {
+		benchmarks = append(benchmarks,
+			bench.Benchmark{Name: op.Name + "/Bitset/" + size, N: 1, Metrics: []bench.Metric{{Value: op.BitsetNs, Unit: "ns/op"}}},
+			bench.Benchmark{Name: op.Name + "/Map/" + size, N: 1, Metrics: []bench.Metric{{Value: op.MapNs, Unit: "ns/op"}}},
+		)
+	}
+	for _, d := range result.Densities {
+		name := fmt.Sprintf("universe=%d/density=%g", config.Universe, d.Density)
+		benchmarks = append(benchmarks,
+			bench.Benchmark{Name: "And/Bitset/" + name, N: 1, Metrics: []bench.Metric{{Value: d.BitsetAndNs, Unit: "ns/op"}, {Value: d.BitsetBytes, Unit: "B/member"}}},
+			bench.Benchmark{Name: "And/Map/" + name, N: 1, Metrics: []bench.Metric{{Value: d.MapAndNs, Unit: "ns/op"}, {Value: d.MapBytes, Unit: "B/member"}}},
+		)
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
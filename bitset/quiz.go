@@ -0,0 +1,63 @@
+package bitset
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz bitset, it asks where word at a time set operations beat a map, where the map still
+// wins on counting and memory, and what the bit tricks behind iteration do
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why is intersecting the bitsets so much faster than intersecting the maps?",
+		Choices: []string{
+			"The bitset sorts its members first, so the intersection is a merge of two sorted runs",
+			"One & of two words intersects 64 members at once, the map hashes and probes for each member",
+			"The bitset keeps the intersection of every pair of sets it has seen and returns it from memory",
+			"The map has to allocate a new bucket for every member of the result as it goes",
+		},
+		Answer:      1,
+		Explanation: "the bitset's work is proportional to the universe over 64, the map's to the number of members, each a hash and a probe",
+	},
+	{
+		Prompt: "Why is counting the only operation where the map wins?",
+		Choices: []string{
+			"The bitset has to test each bit of every word in turn, 64 tests per word",
+			"popcount isn't a hardware instruction on most machines, so Go emulates it in software",
+			"The map keeps its length as it goes, len is one read, the bitset has to popcount every word",
+			"The map's members are stored contiguously, so counting them is a single pass",
+		},
+		Answer:      2,
+		Explanation: "a bitset could keep a count too, updating it on every Set and Clear, at the cost of checking whether each bit was already set",
+	},
+	{
+		Prompt: "At what kind of density does the map take less memory than the bitset?",
+		Choices: []string{
+			"Very dense sets, where nearly every integer in the universe is a member",
+			"Very sparse sets, where a bit for every integer costs more than the map's bytes per member",
+			"At every density, the map only stores the members while the bitset stores every word",
+			"Sets of about half density, where the bitset's words are as much zeros as ones",
+		},
+		Answer:      1,
+		Explanation: "the bitset's size depends only on the universe, the map's only on the members, so they cross at a density of about one bit over the map's bits per member",
+	},
+	{
+		Prompt: "What does x & (x-1) do?",
+		Choices: []string{
+			"Clears the lowest set bit",
+			"Isolates the lowest set bit",
+			"Sets the lowest clear bit",
+			"Clears the highest set bit",
+		},
+		Answer:      0,
+		Explanation: "subtracting one flips the lowest set bit and every zero below it, so the & keeps only the bits above, iterating a bitset uses it, TrailingZeros finds the lowest member, x & (x-1) removes it, until the word is zero",
+	},
+	{
+		Prompt: "Why does complementing the map take so much longer than complementing the bitset?",
+		Choices: []string{
+			"The map has to be copied before it can be changed, the bitset flips its words in place",
+			"The complement of a sparse set is dense, and the map rehashes every time it grows",
+			"The bitset's complement is lazy, it flips a flag and inverts each word as it is read",
+			"The map has to try every integer in the universe and insert the missing ones, the bitset flips 64 with one ^",
+		},
+		Answer:      3,
+		Explanation: "the last word's bits past the universe have to be cleared afterwards, or the complement would hold integers that were never in the universe",
+	},
+}
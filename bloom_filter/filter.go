@@ -0,0 +1,101 @@
+package bloomfilter
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Filter is a Bloom filter over uint64 keys, a set that answers "definitely not in it" or "probably in it" from a
+// fixed array of bits, however many keys are added
+// Adding a key sets k bits picked by hashing it, a lookup checks those k bits, a key that was added always finds them
+// set, a key that wasn't finds them set by other keys with a probability that grows as the bits fill
+type Filter struct {
+	bits   []uint64
+	m      uint64
+	k      int
+	length int
+}
+
+// NewFilter creates a filter of m bits, rounded up to a whole number of words, setting k bits per key
+func NewFilter(m, k int) *Filter {
+	words := (max(m, 1) + 63) / 64
+	return &Filter{bits: make([]uint64, words), m: uint64(words * 64), k: max(k, 1)}
+}
+
+// mix is splitmix64's finalizer, it scatters keys that differ in a bit or two, like 1, 2, 3, across all 64 bits
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// hashes splits one 64 bit hash of the key into two 32 bit ones, the k bit positions are h1 + i*h2 for i below k,
+// double hashing, as good as k independent hashes for a Bloom filter at the cost of one
+// h2 is made odd so the positions never all land on the same bit
+func hashes(key uint64) (h1, h2 uint64) {
+	h := mix(key)
+	return h & math.MaxUint32, h>>32 | 1
+}
+
+// Add sets the key's k bits
+func (f *Filter) Add(key uint64) {
+	h1, h2 := hashes(key)
+	for i := range uint64(f.k) {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.length++
+}
+
+// Contains reports whether the key might have been added, false is certain, true is wrong at the false positive rate
+func (f *Filter) Contains(key uint64) bool {
+	h1, h2 := hashes(key)
+	for i := range uint64(f.k) {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Len is the number of keys added, counting a key added twice twice, the filter has no way to tell
+func (f *Filter) Len() int {
+	return f.length
+}
+
+// Bits is the size of the filter in bits, m
+func (f *Filter) Bits() int {
+	return int(f.m)
+}
+
+// Hashes is the number of bits set per key, k
+func (f *Filter) Hashes() int {
+	return f.k
+}
+
+// FillRatio is the fraction of the bits that are set, a lookup of a key that wasn't added finds each of its k bits
+// set with about this probability
+func (f *Filter) FillRatio() float64 {
+	set := 0
+	for _, word := range f.bits {
+		set += bits.OnesCount64(word)
+	}
+	return float64(set) / float64(f.m)
+}
+
+// FalsePositiveRate is the rate theory predicts for a filter of m bits and k hashes holding n keys, each bit is
+// still clear after the n*k bits are set with probability (1 - 1/m)^(kn), about e^(-kn/m), and a false positive
+// needs all k of a key's bits set
+func FalsePositiveRate(m, k, n int) float64 {
+	return math.Pow(1-math.Exp(-float64(k)*float64(n)/float64(m)), float64(k))
+}
+
+// OptimalHashes is the k that minimizes the false positive rate at m/n bits per key, (m/n) ln 2, which leaves half
+// the bits set
+func OptimalHashes(bitsPerKey float64) int {
+	return max(1, int(math.Round(bitsPerKey*math.Ln2)))
+}
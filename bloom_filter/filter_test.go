@@ -0,0 +1,73 @@
+package bloomfilter
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestFilterHasNoFalseNegatives(t *testing.T) {
+	f := NewFilter(1000, 3)
+	rng := rand.New(rand.NewSource(1))
+	keys := make([]uint64, 500)
+	for i := range keys {
+		keys[i] = rng.Uint64()
+		f.Add(keys[i])
+	}
+	for _, key := range keys {
+		if !f.Contains(key) {
+			t.Fatalf("Contains(%d) = false for a key that was added", key)
+		}
+	}
+	if f.Len() != 500 {
+		t.Errorf("Len() = %d, want 500", f.Len())
+	}
+	if f.Bits() != 1024 {
+		t.Errorf("Bits() = %d, want 1000 rounded up to whole words, 1024", f.Bits())
+	}
+}
+
+func TestEmptyFilterContainsNothing(t *testing.T) {
+	f := NewFilter(64, 4)
+	for key := range uint64(1000) {
+		if f.Contains(key) {
+			t.Fatalf("an empty filter contains %d", key)
+		}
+	}
+	if f.FillRatio() != 0 {
+		t.Errorf("FillRatio() = %g, want 0", f.FillRatio())
+	}
+}
+
+// the measured rate is a sample, with 100000 absent keys at around 1% it should land well within 20% of the theory
+func TestFalsePositiveRateMatchesTheory(t *testing.T) {
+	present, absent := makeKeys(rand.New(rand.NewSource(2)), 10000, 100000)
+	for _, k := range []int{2, OptimalHashes(10), 12} {
+		e := experiment(10, k, present, absent)
+		if math.Abs(e.Measured-e.Theoretical) > 0.2*e.Theoretical {
+			t.Errorf("k=%d: measured false positive rate %.4f, theory %.4f", k, e.Measured, e.Theoretical)
+		}
+	}
+}
+
+func TestOptimalHashes(t *testing.T) {
+	tests := []struct {
+		bitsPerKey float64
+		want       int
+	}{
+		{1, 1},
+		{8, 6},
+		{10, 7},
+		{16, 11},
+	}
+	for _, tt := range tests {
+		if got := OptimalHashes(tt.bitsPerKey); got != tt.want {
+			t.Errorf("OptimalHashes(%g) = %d, want %d", tt.bitsPerKey, got, tt.want)
+		}
+	}
+	// at the optimal number of hashes about half the bits are set
+	present, absent := makeKeys(rand.New(rand.NewSource(3)), 10000, 1000)
+	if e := experiment(10, OptimalHashes(10), present, absent); math.Abs(e.FillRatio-0.5) > 0.02 {
+		t.Errorf("FillRatio() = %.3f at the optimal hashes, want about 0.5", e.FillRatio)
+	}
+}
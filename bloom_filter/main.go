@@ -0,0 +1,293 @@
+// Package bloomfilter is the Bloom filter lesson, teachgo bloom, a Bloom filter built from scratch, timed against Go's
+// map and measured for false positives against what theory predicts
+package bloomfilter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo bloom -h, its first line is the summary teachgo help lists
+const Description = `Bloom filters, false positives measured against theory
+
+Builds a Bloom filter, a bit array that each key sets a few hashed bits of, and times inserts and lookups against
+Go's map holding the same keys. Then fills filters at several numbers of hashes and bits per key, looks up keys
+that were never added and compares the false positive rate seen with the rate theory predicts, showing the filter
+trading a small, predictable error for a fraction of the memory.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "bloom",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"probabilistic data structures", "hashing", "bit arrays", "false positives"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Keys       int     `json:"keys"`
+	Queries    int     `json:"queries"`
+	BitsPerKey float64 `json:"bits_per_key"`
+	Hashes     int     `json:"hashes"`
+	Seed       int64   `json:"seed"`
+}
+
+// SetResult is what a run measured of one set, the times are per operation, FalsePositives counts the absent keys
+// the set claimed to hold
+type SetResult struct {
+	Name           string  `json:"name"`
+	InsertNs       float64 `json:"insert_ns"`
+	HitNs          float64 `json:"hit_ns"`
+	MissNs         float64 `json:"miss_ns"`
+	Found          int     `json:"found"`
+	FalsePositives int     `json:"false_positives"`
+	Bytes          int     `json:"bytes,omitempty"`
+}
+
+// Experiment is the false positive rate of a filter of one size and number of hashes, measured and predicted
+type Experiment struct {
+	BitsPerKey  float64 `json:"bits_per_key"`
+	Hashes      int     `json:"hashes"`
+	FillRatio   float64 `json:"fill_ratio"`
+	Measured    float64 `json:"measured"`
+	Theoretical float64 `json:"theoretical"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config  RunConfig    `json:"config"`
+	Env     bench.Env    `json:"env"`
+	Sets    []SetResult  `json:"sets"`
+	ByHash  []Experiment `json:"by_hash"`
+	BySize  []Experiment `json:"by_size"`
+	Optimal int          `json:"optimal_hashes"`
+}
+
+// set is what the timing needs of the filter and the map
+type set interface {
+	Add(key uint64)
+	Contains(key uint64) bool
+}
+
+// mapSet is Go's map used as a set, exact, it never reports a key it doesn't hold
+type mapSet map[uint64]struct{}
+
+func (s mapSet) Add(key uint64) {
+	s[key] = struct{}{}
+}
+
+func (s mapSet) Contains(key uint64) bool {
+	_, ok := s[key]
+	return ok
+}
+
+// makeKeys draws the keys to add and keys certain to be absent, the added keys are even and the absent ones odd
+// The hash scatters every bit of a key, so which bit tells them apart makes no difference to the filter
+func makeKeys(rng *rand.Rand, keys, queries int) (present, absent []uint64) {
+	present = make([]uint64, keys)
+	for i := range present {
+		present[i] = rng.Uint64() &^ 1
+	}
+	absent = make([]uint64, queries)
+	for i := range absent {
+		absent[i] = rng.Uint64() | 1
+	}
+	return present, absent
+}
+
+// measure adds every present key, then looks up as many present keys as there are queries and every absent key
+func measure(ctx context.Context, name string, s set, present, absent []uint64) SetResult {
+	result := SetResult{Name: name}
+	insert := bench.Phase(ctx, "insert "+name, func() {
+		for _, key := range present {
+			s.Add(key)
+		}
+	})
+	hit := bench.Phase(ctx, "lookup present "+name, func() {
+		for i := range absent {
+			if s.Contains(present[i%len(present)]) {
+				result.Found++
+			}
+		}
+	})
+	miss := bench.Phase(ctx, "lookup absent "+name, func() {
+		for _, key := range absent {
+			if s.Contains(key) {
+				result.FalsePositives++
+			}
+		}
+	})
+	result.InsertNs = perOp(insert, len(present))
+	result.HitNs = perOp(hit, len(absent))
+	result.MissNs = perOp(miss, len(absent))
+	return result
+}
+
+func perOp(d time.Duration, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(d.Nanoseconds()) / float64(n)
+}
+
+// experiment fills a filter of bitsPerKey bits per present key and k hashes, then counts the absent keys it claims
+func experiment(bitsPerKey float64, k int, present, absent []uint64) Experiment {
+	f := NewFilter(int(bitsPerKey*float64(len(present))), k)
+	for _, key := range present {
+		f.Add(key)
+	}
+	falsePositives := 0
+	for _, key := range absent {
+		if f.Contains(key) {
+			falsePositives++
+		}
+	}
+	return Experiment{
+		BitsPerKey:  bitsPerKey,
+		Hashes:      k,
+		FillRatio:   f.FillRatio(),
+		Measured:    float64(falsePositives) / float64(len(absent)),
+		Theoretical: FalsePositiveRate(f.Bits(), k, len(present)),
+	}
+}
+
+// printResults writes the timings and both experiments
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Bloom Filter")
+	fmt.Fprintln(w, "============")
+	fmt.Fprintf(w, "Keys: %d\nQueries: %d\nBits per key: %g\nHashes: %d\n", config.Keys, config.Queries, config.BitsPerKey, config.Hashes)
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%-14s %12s %12s %12s %8s %16s %12s\n", "Set", "Insert/op", "Hit/op", "Miss/op", "Found", "False positives", "Memory")
+	for _, s := range result.Sets {
+		memory := "-"
+		if s.Bytes > 0 {
+			memory = fmt.Sprintf("%d KiB", s.Bytes/1024)
+		}
+		fmt.Fprintf(w, "%-14s %12v %12v %12v %8d %16d %12s\n", s.Name,
+			time.Duration(s.InsertNs), time.Duration(s.HitNs), time.Duration(s.MissNs), s.Found, s.FalsePositives, memory)
+	}
+	fmt.Fprintf(w, "\nThe filter never misses a key it holds, but claims %d of %d keys it was never given\n",
+		result.Sets[0].FalsePositives, config.Queries)
+	fmt.Fprintf(w, "The keys alone take 64 bits each, the filter %g, the map more than the keys for its buckets and spare room\n", config.BitsPerKey)
+
+	printExperiments(w, fmt.Sprintf("False positives by hashes, at %g bits per key", config.BitsPerKey), result.ByHash)
+	fmt.Fprintf(w, "\nToo few hashes and a key's few bits are easily all set by others, too many and the bits fill up,\n")
+	fmt.Fprintf(w, "the rate is lowest at (m/n) ln 2 hashes, %d here, where half the bits are set\n", result.Optimal)
+
+	printExperiments(w, "False positives by bits per key, at the best number of hashes", result.BySize)
+	fmt.Fprintln(w, "\nEach extra bit per key cuts the false positive rate by about 40% at the best number of hashes,")
+	fmt.Fprintln(w, "about 4.8 bits per key for every tenfold drop, 1% takes under 10 bits per key however big the keys are")
+}
+
+func printExperiments(w io.Writer, title string, experiments []Experiment) {
+	fmt.Fprintf(w, "\n=====%s=====\n", title)
+	fmt.Fprintf(w, "%12s %8s %10s %12s %12s\n", "Bits/key", "Hashes", "Bits set", "Measured", "Theory")
+	for _, e := range experiments {
+		fmt.Fprintf(w, "%12g %8d %9.1f%% %11.3f%% %11.3f%%\n", e.BitsPerKey, e.Hashes, 100*e.FillRatio, 100*e.Measured, 100*e.Theoretical)
+	}
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo bloom
+func Main(args []string) {
+	fs := bench.NewFlagSet("bloom", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the Bloom filter lesson measures a single run"
+	numKeys := fs.Int("keys", 1000000, "the number of keys to add")
+	numQueries := fs.Int("queries", 1000000, "the number of lookups of keys that were added, and of keys that weren't")
+	bitsPerKey := fs.Float64("bits-per-key", 10, "the size of the filter, m/n, bits for each key added")
+	hashes := fs.Int("hashes", 0, "the number of bits each key sets, k, 0 for the best number at -bits-per-key")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "bloom measures a single run, -trials isn't supported")
+	v.AtLeast("keys", *numKeys, 1)
+	v.AtLeast("queries", *numQueries, 1)
+	v.Check(*bitsPerKey >= 1 && *bitsPerKey <= 64, "-bits-per-key must be from 1 to 64, got %g", *bitsPerKey)
+	v.AtLeast("hashes", *hashes, 0)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	optimal := OptimalHashes(*bitsPerKey)
+	k := *hashes
+	if k == 0 {
+		k = optimal
+	}
+	result := RunResult{
+		Config:  RunConfig{Keys: *numKeys, Queries: *numQueries, BitsPerKey: *bitsPerKey, Hashes: k, Seed: globals.Seed},
+		Env:     bench.CaptureEnv(),
+		Optimal: optimal,
+	}
+
+	rng := rand.New(rand.NewSource(globals.Seed))
+	slog.Info("generating keys", "keys", *numKeys, "queries", *numQueries)
+	present, absent := makeKeys(rng, *numKeys, *numQueries)
+
+	ctx := context.Background()
+	filter := NewFilter(int(*bitsPerKey*float64(*numKeys)), k)
+	bloom := measure(ctx, "Bloom filter", filter, present, absent)
+	bloom.Bytes = filter.Bits() / 8
+	result.Sets = append(result.Sets, bloom, measure(ctx, "Map", make(mapSet), present, absent))
+
+	slog.Info("measuring false positives by hashes", "bits_per_key", *bitsPerKey)
+	for hashes := 1; hashes <= max(2*optimal, k); hashes++ {
+		result.ByHash = append(result.ByHash, experiment(*bitsPerKey, hashes, present, absent))
+	}
+	slog.Info("measuring false positives by bits per key")
+	for _, size := range []float64{2, 4, 6, 8, 10, 12, 16, 20} {
+		result.BySize = append(result.BySize, experiment(size, OptimalHashes(size), present, absent))
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a benchmark line per operation and set, the miss lines carry the false positive rate as
+// well so benchstat shows a change in either
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/bloom_filter"); err != nil {
+		return err
+	}
+	config := result.Config
+	keys := fmt.Sprintf("keys=%d", config.Keys)
+	benchmarks := []bench.Benchmark{}
+	for _, s := range result.Sets {
+		benchmarks = append(benchmarks,
+			bench.Benchmark{Name: "Insert/" + s.Name + "/" + keys, N: int64(config.Keys), Metrics: []bench.Metric{{Value: s.InsertNs, Unit: "ns/op"}}},
+			bench.Benchmark{Name: "Hit/" + s.Name + "/" + keys, N: int64(config.Queries), Metrics: []bench.Metric{{Value: s.HitNs, Unit: "ns/op"}}},
+			bench.Benchmark{Name: "Miss/" + s.Name + "/" + keys, N: int64(config.Queries), Metrics: []bench.Metric{
+				{Value: s.MissNs, Unit: "ns/op"},
+				{Value: float64(s.FalsePositives) / float64(config.Queries), Unit: "fp/op"},
+			}},
+		)
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
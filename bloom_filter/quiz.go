@@ -0,0 +1,63 @@
+package bloomfilter
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz bloom, it checks the one sided answer a Bloom filter gives, how the number of hashes
+// and bits per key set its false positive rate, and why a plain filter can't delete
+var quiz = []lesson.Question{
+	{
+		Prompt: "A Bloom filter's Contains returns false, what does that tell you?",
+		Choices: []string{
+			"The key is probably absent, with the filter's false positive rate as the chance it's there",
+			"The key was added once but has since been pushed out by newer keys",
+			"The key was certainly never added, every added key finds all of its bits set",
+			"Nothing certain, a false answer is as likely to be wrong as a true one",
+		},
+		Answer:      2,
+		Explanation: "adding a key sets all of its bits and nothing ever clears them, so every added key finds its bits set, the Found column always matches the lookups",
+	},
+	{
+		Prompt: "Why does the false positive rate rise again when there are too many hashes?",
+		Choices: []string{
+			"Every key sets more bits, the array fills, and an absent key's bits are more likely all set",
+			"The hashes are derived from two base hashes, and past a few they start repeating positions",
+			"Every extra hash costs time, so lookups give up early and report the key as present",
+			"The bit array is resized when too many bits are set, and a resize loses some of them",
+		},
+		Answer:      0,
+		Explanation: "more bits per lookup are more chances to find a clear one, but the bits set climb towards 100%, the balance is (m/n) ln 2 hashes with half the bits set",
+	},
+	{
+		Prompt: "How does the filter's memory depend on the size of the keys?",
+		Choices: []string{
+			"It grows with the average key length, each key is hashed into more bits",
+			"It is a fixed fraction of the keys' total size, about a tenth of a map's",
+			"It doesn't, the filter keeps a few bits per key and never the keys themselves",
+			"It doubles for string keys, which are hashed twice to spread their bits",
+		},
+		Answer:      2,
+		Explanation: "the filter's bits per key set its false positive rate whether the keys are 8 byte integers or kilobyte URLs, a map has to keep every key to answer exactly",
+	},
+	{
+		Prompt: "About how many bits per key does a filter need for a 1% false positive rate, at the best number of hashes?",
+		Choices: []string{
+			"About 2",
+			"About 5",
+			"About 20",
+			"About 10",
+		},
+		Answer:      3,
+		Explanation: "the rate at the best k is about 0.6185^(m/n), 9.6 bits per key gives 1%, and each further 4.8 bits divides it by ten",
+	},
+	{
+		Prompt: "Why can't a key be removed from a plain Bloom filter by clearing its bits?",
+		Choices: []string{
+			"The filter only stores hashes, so it can't recompute which bits a key set",
+			"Other keys may share those bits, clearing them makes those keys look absent",
+			"Clearing bits would have to shift every bit after them, O(m) per removal",
+			"A cleared bit can't be set again, so the key could never be added back",
+		},
+		Answer:      1,
+		Explanation: "a counting Bloom filter keeps a small counter per position instead of a bit, so removing a key only decrements, at several times the memory",
+	},
+}
@@ -0,0 +1,154 @@
+package boundedbuffer
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// Buffer is a bounded first in first out buffer between producers and consumers, Put blocks while it's full, the
+// backpressure that slows producers to the consumers' pace, and Get blocks while it's empty
+type Buffer[T any] interface {
+	Put(v T)
+	Get() T
+	Len() int
+	Cap() int
+}
+
+// ChanBuffer is a buffered channel, the runtime keeps its waiting senders and receivers in queues and hands an item
+// or a slot straight to the one at the front, so whoever has waited longest goes next
+type ChanBuffer[T any] struct {
+	ch chan T
+}
+
+// NewChanBuffer creates a channel holding up to capacity items
+func NewChanBuffer[T any](capacity int) *ChanBuffer[T] {
+	return &ChanBuffer[T]{ch: make(chan T, max(capacity, 1))}
+}
+
+func (c *ChanBuffer[T]) Put(v T) {
+	c.ch <- v
+}
+
+func (c *ChanBuffer[T]) Get() T {
+	return <-c.ch
+}
+
+func (c *ChanBuffer[T]) Len() int {
+	return len(c.ch)
+}
+
+func (c *ChanBuffer[T]) Cap() int {
+	return cap(c.ch)
+}
+
+// CondBuffer is a slice guarded by a mutex, with a condition each for producers waiting for room and consumers
+// waiting for an item
+// A Signal only wakes a waiter, it doesn't hand it the slot, the waiter has to take the mutex again and check, and a
+// producer that arrives in the meantime can take the slot first, barging past the waiters, so a waiter can lose
+// again and again
+type CondBuffer[T any] struct {
+	mu       sync.Mutex
+	notFull  sync.Cond
+	notEmpty sync.Cond
+	items    []T
+	capacity int
+}
+
+// NewCondBuffer creates a slice buffer holding up to capacity items
+func NewCondBuffer[T any](capacity int) *CondBuffer[T] {
+	c := &CondBuffer[T]{items: make([]T, 0, capacity), capacity: max(capacity, 1)}
+	c.notFull.L = &c.mu
+	c.notEmpty.L = &c.mu
+	return c
+}
+
+func (c *CondBuffer[T]) Put(v T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.items) == c.capacity {
+		c.notFull.Wait()
+	}
+	c.items = append(c.items, v)
+	c.notEmpty.Signal()
+}
+
+func (c *CondBuffer[T]) Get() T {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.items) == 0 {
+		c.notEmpty.Wait()
+	}
+	v := c.items[0]
+	var zero T
+	c.items[0] = zero
+	// slicing the front off leaves the slice's room behind the items, append moves them back to a fresh array once
+	// it runs out
+	c.items = c.items[1:]
+	c.notFull.Signal()
+	return v
+}
+
+func (c *CondBuffer[T]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+func (c *CondBuffer[T]) Cap() int {
+	return c.capacity
+}
+
+// SemaphoreBuffer is the classic bounded buffer, a semaphore counting the free slots, which producers acquire and
+// consumers release, and one counting the items, the other way round, with a mutex held only to touch the slice
+// x/sync's semaphore queues its waiters and won't let a newcomer past them, so it's first come first served like a
+// channel
+type SemaphoreBuffer[T any] struct {
+	slots    *semaphore.Weighted
+	full     *semaphore.Weighted
+	mu       sync.Mutex
+	items    []T
+	capacity int
+}
+
+// NewSemaphoreBuffer creates a semaphore buffer holding up to capacity items
+func NewSemaphoreBuffer[T any](capacity int) *SemaphoreBuffer[T] {
+	capacity = max(capacity, 1)
+	s := &SemaphoreBuffer[T]{slots: semaphore.NewWeighted(int64(capacity)), full: semaphore.NewWeighted(int64(capacity)),
+		items: make([]T, 0, capacity), capacity: capacity}
+	// the items semaphore starts with every unit taken, there's nothing to get
+	s.full.Acquire(context.Background(), int64(capacity))
+	return s
+}
+
+func (s *SemaphoreBuffer[T]) Put(v T) {
+	s.slots.Acquire(context.Background(), 1)
+	s.mu.Lock()
+	s.items = append(s.items, v)
+	s.mu.Unlock()
+	s.full.Release(1)
+}
+
+func (s *SemaphoreBuffer[T]) Get() T {
+	// acquiring the items semaphore is taking a unit back that a Put released
+	s.full.Acquire(context.Background(), 1)
+	s.mu.Lock()
+	v := s.items[0]
+	var zero T
+	s.items[0] = zero
+	s.items = s.items[1:]
+	s.mu.Unlock()
+	s.slots.Release(1)
+	return v
+}
+
+func (s *SemaphoreBuffer[T]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+func (s *SemaphoreBuffer[T]) Cap() int {
+	return s.capacity
+}
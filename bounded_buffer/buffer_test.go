@@ -0,0 +1,82 @@
+package boundedbuffer
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func buffers(capacity int) map[string]Buffer[int] {
+	return map[string]Buffer[int]{
+		"channel":   NewChanBuffer[int](capacity),
+		"cond":      NewCondBuffer[int](capacity),
+		"semaphore": NewSemaphoreBuffer[int](capacity),
+	}
+}
+
+func TestFirstInFirstOut(t *testing.T) {
+	for name, buf := range buffers(4) {
+		for round := range 3 {
+			for i := range 4 {
+				buf.Put(round*4 + i)
+			}
+			if buf.Len() != 4 || buf.Cap() != 4 {
+				t.Fatalf("%s: full buffer has length %d and capacity %d, want 4 and 4", name, buf.Len(), buf.Cap())
+			}
+			for i := range 4 {
+				if got := buf.Get(); got != round*4+i {
+					t.Fatalf("%s: got %d, want %d", name, got, round*4+i)
+				}
+			}
+		}
+	}
+}
+
+func TestPutWaitsForRoom(t *testing.T) {
+	for name, buf := range buffers(1) {
+		buf.Put(1)
+		put := make(chan struct{})
+		go func() {
+			buf.Put(2)
+			close(put)
+		}()
+		select {
+		case <-put:
+			t.Fatalf("%s: put into a full buffer didn't wait", name)
+		case <-time.After(10 * time.Millisecond):
+		}
+		if got := buf.Get(); got != 1 {
+			t.Fatalf("%s: got %d, want 1", name, got)
+		}
+		<-put
+		if got := buf.Get(); got != 2 {
+			t.Fatalf("%s: got %d, want 2", name, got)
+		}
+	}
+}
+
+func TestEveryItemArrivesOnce(t *testing.T) {
+	for name, buf := range buffers(3) {
+		const items, producers = 10000, 4
+		var wg sync.WaitGroup
+		for p := range producers {
+			wg.Go(func() {
+				for v := p; v < items; v += producers {
+					buf.Put(v)
+				}
+			})
+		}
+		seen := make([]bool, items)
+		for range items {
+			v := buf.Get()
+			if seen[v] {
+				t.Fatalf("%s: got %d twice", name, v)
+			}
+			seen[v] = true
+		}
+		wg.Wait()
+		if buf.Len() != 0 {
+			t.Fatalf("%s: %d items left over", name, buf.Len())
+		}
+	}
+}
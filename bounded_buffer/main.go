@@ -0,0 +1,325 @@
+// Package boundedbuffer is the bounded buffer lesson, teachgo boundedbuffer, producers and consumers sharing a
+// buffered channel, a slice guarded by a mutex and conditions, and a slice guarded by semaphores, their throughput,
+// and how fairly each shares a full buffer among producers held back by a slow consumer
+package boundedbuffer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo boundedbuffer -h, its first line is the summary teachgo
+// help lists
+const Description = `Bounded buffers, a channel, a mutex and conditions, and semaphores, for throughput and fairness
+
+Builds three bounded buffers of -capacity items, a buffered channel, a slice guarded by a mutex with a condition
+each for waiting producers and consumers, and a slice guarded by a pair of semaphores, one counting free slots and
+one items. Passes -items items through each with producers and consumers in each of the -shapes, checking every
+item arrives once, for the time per item. Then holds -producers producers back behind one slow consumer that wakes
+every -pause to take -batch items, so the buffer stays full and the producers go at the consumer's pace, the
+backpressure, and measures how long each Put waits for room, where a buffer that lets a producer barge past the
+ones already waiting has most Puts go straight in and a few starved for round after round.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "boundedbuffer",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"producer-consumer", "channels", "sync.Cond", "semaphores", "backpressure", "starvation"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// candidate is a buffer the lesson compares
+type candidate struct {
+	name string
+	new  func(capacity int) Buffer[int]
+}
+
+func candidates() []candidate {
+	return []candidate{
+		{"Channel", func(capacity int) Buffer[int] { return NewChanBuffer[int](capacity) }},
+		{"Cond", func(capacity int) Buffer[int] { return NewCondBuffer[int](capacity) }},
+		{"Semaphore", func(capacity int) Buffer[int] { return NewSemaphoreBuffer[int](capacity) }},
+	}
+}
+
+// Shape is a number of producers and of consumers sharing a buffer
+type Shape struct {
+	Producers int `json:"producers"`
+	Consumers int `json:"consumers"`
+}
+
+func (s Shape) String() string {
+	return fmt.Sprintf("%dx%d", s.Producers, s.Consumers)
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Items     int           `json:"items"`
+	Capacity  int           `json:"capacity"`
+	Shapes    []Shape       `json:"shapes"`
+	Producers int           `json:"producers"`
+	Pause     time.Duration `json:"pause"`
+	Batch     int           `json:"batch"`
+	SlowItems int           `json:"slow_items"`
+}
+
+// ThroughputResult is the items passed through a buffer with one shape of producers and consumers
+type ThroughputResult struct {
+	Buffer    string  `json:"buffer"`
+	Shape     Shape   `json:"shape"`
+	ElapsedNs float64 `json:"elapsed_ns"`
+	Correct   bool    `json:"correct"`
+}
+
+// FairnessResult is the producers held back behind a slow consumer, the Put latencies are how long they waited for
+// room
+type FairnessResult struct {
+	Buffer    string  `json:"buffer"`
+	ElapsedNs float64 `json:"elapsed_ns"`
+	PutP50Ns  float64 `json:"put_p50_ns"`
+	PutP99Ns  float64 `json:"put_p99_ns"`
+	PutMaxNs  float64 `json:"put_max_ns"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config     RunConfig          `json:"config"`
+	Env        bench.Env          `json:"env"`
+	Throughput []ThroughputResult `json:"throughput"`
+	Fairness   []FairnessResult   `json:"fairness"`
+}
+
+// throughput passes the items 1 to items through buf, each producer putting its own share and each consumer getting
+// its own, and checks they add up to what was put
+func throughput(ctx context.Context, name string, buf Buffer[int], shape Shape, items int) ThroughputResult {
+	var sum atomic.Int64
+	elapsed := bench.Phase(ctx, name, func() {
+		var wg sync.WaitGroup
+		for c := range shape.Consumers {
+			wg.Go(func() {
+				var local int64
+				// consumer c gets an even share, the first ones one more while there's a remainder
+				n := items / shape.Consumers
+				if c < items%shape.Consumers {
+					n++
+				}
+				for range n {
+					local += int64(buf.Get())
+				}
+				sum.Add(local)
+			})
+		}
+		for p := range shape.Producers {
+			wg.Go(func() {
+				// producer p puts every value congruent to p+1 modulo producers, between them exactly 1 to items
+				for v := p + 1; v <= items; v += shape.Producers {
+					buf.Put(v)
+				}
+			})
+		}
+		wg.Wait()
+	})
+	return ThroughputResult{Shape: shape, ElapsedNs: float64(elapsed.Nanoseconds()),
+		Correct: sum.Load() == int64(items)*int64(items+1)/2}
+}
+
+// fairness has producers race to put items until items have been put between them, behind one consumer that sleeps
+// for pause then takes batch items, timing every Put
+// Taking a batch frees several slots at once, which is when a producer that has just put can come straight back and
+// take one of them ahead of the producers woken for them
+func fairness(ctx context.Context, name string, buf Buffer[int], producers, items, batch int, pause time.Duration) FairnessResult {
+	var result FairnessResult
+	put := bench.NewStats()
+	var tickets atomic.Int64
+	elapsed := bench.Phase(ctx, name, func() {
+		var wg sync.WaitGroup
+		wg.Go(func() {
+			for got := 0; got < items; {
+				time.Sleep(pause)
+				for i := 0; i < batch && got < items; i++ {
+					buf.Get()
+					got++
+				}
+			}
+		})
+		for p := range producers {
+			wg.Go(func() {
+				for tickets.Add(1) <= int64(items) {
+					start := time.Now()
+					buf.Put(p)
+					put.Record(time.Since(start))
+				}
+			})
+		}
+		wg.Wait()
+	})
+	result.ElapsedNs = float64(elapsed.Nanoseconds())
+	result.PutP50Ns = float64(put.Percentile(0.5).Nanoseconds())
+	result.PutP99Ns = float64(put.Percentile(0.99).Nanoseconds())
+	result.PutMaxNs = float64(put.Max().Nanoseconds())
+	return result
+}
+
+// perOp is elapsed divided over n operations
+func perOp(elapsedNs float64, n int) time.Duration {
+	if n == 0 {
+		return 0
+	}
+	return time.Duration(elapsedNs / float64(n))
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Bounded Buffers")
+	fmt.Fprintln(w, "===============")
+	fmt.Fprintf(w, "Items: %d\n", config.Items)
+	fmt.Fprintf(w, "Capacity: %d\n", config.Capacity)
+	fmt.Fprintf(w, "Machine: %s\n", result.Env)
+
+	fmt.Fprintln(w, "\n=====Throughput=====")
+	fmt.Fprintf(w, "%-10s %-7s %12s %14s %8s\n", "Buffer", "Shape", "Time/item", "Throughput", "Correct")
+	for _, r := range result.Throughput {
+		fmt.Fprintf(w, "%-10s %-7s %12v %14s %8v\n", r.Buffer, r.Shape, perOp(r.ElapsedNs, config.Items),
+			bench.FormatRate(float64(config.Items)/(r.ElapsedNs/1e9)), r.Correct)
+	}
+	fmt.Fprintln(w, "\nA shape is producers x consumers, Correct that the items got added up to the items put. The channel's")
+	fmt.Fprintln(w, "lock and queues of waiters are built into the runtime, the semaphores each take a mutex of their own on")
+	fmt.Fprintln(w, "every acquire and release, on top of the one around the slice")
+
+	fmt.Fprintf(w, "\n=====%d producers behind a consumer taking %d items every %v=====\n", config.Producers, config.Batch, config.Pause)
+	fmt.Fprintf(w, "%-10s %12s %12s %12s %12s %12s\n", "Buffer", "Elapsed", "Throughput", "Put p50", "Put p99", "Put max")
+	for _, r := range result.Fairness {
+		fmt.Fprintf(w, "%-10s %12v %12s %12v %12v %12v\n", r.Buffer, time.Duration(r.ElapsedNs),
+			bench.FormatRate(float64(config.SlowItems)/(r.ElapsedNs/1e9)), time.Duration(r.PutP50Ns), time.Duration(r.PutP99Ns),
+			time.Duration(r.PutMaxNs))
+	}
+	fmt.Fprintln(w, "\nEvery buffer goes at the consumer's pace however many producers there are, a full buffer makes them wait,")
+	fmt.Fprintln(w, "that's backpressure. The channel and the semaphores hand each freed slot to the producer that has waited")
+	fmt.Fprintln(w, "longest, so every Put waits about the same, its turn in the queue. A condition's Signal only wakes a")
+	fmt.Fprintln(w, "producer, which has to take the mutex and look again, and a producer that has just put can get there")
+	fmt.Fprintln(w, "first and take the slot, so most Puts go straight in while the ones they barge past wait round after")
+	fmt.Fprintln(w, "round, a median better than fair and a tail far worse, that's starvation")
+}
+
+// parseShapes reads a comma separated list of producers x consumers, like 1x1,4x4
+func parseShapes(v *bench.Validator, list string) []Shape {
+	shapes := []Shape{}
+	for _, field := range strings.Split(list, ",") {
+		producers, consumers, ok := strings.Cut(strings.TrimSpace(field), "x")
+		p, perr := strconv.Atoi(producers)
+		c, cerr := strconv.Atoi(consumers)
+		v.Check(ok && perr == nil && cerr == nil && p >= 1 && c >= 1,
+			"-shapes must be producers x consumers, whole numbers of at least 1 like 4x4, got %q", field)
+		shapes = append(shapes, Shape{Producers: p, Consumers: c})
+	}
+	return shapes
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo boundedbuffer
+func Main(args []string) {
+	fs := bench.NewFlagSet("boundedbuffer", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the bounded buffer lesson measures a single run"
+	items := fs.Int("items", 200000, "the items passed through each buffer with each shape")
+	capacity := fs.Int("capacity", 16, "the items each buffer holds")
+	shapeList := fs.String("shapes", "1x1,4x4,16x1,1x16", "comma separated producers x consumers to pass the items through")
+	producers := fs.Int("producers", 8, "the producers held back behind the slow consumer")
+	pause := fs.Duration("pause", time.Millisecond, "how long the slow consumer sleeps before taking each batch")
+	batch := fs.Int("batch", 4, "the items the slow consumer takes each time it wakes")
+	slowItems := fs.Int("slow-items", 1000, "the items the producers put for the slow consumer")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "boundedbuffer measures a single run, -trials isn't supported")
+	v.AtLeast("items", *items, 1)
+	v.AtLeast("capacity", *capacity, 1)
+	shapes := parseShapes(v, *shapeList)
+	v.AtLeast("producers", *producers, 1)
+	v.NotNegative("pause", *pause)
+	v.AtLeast("batch", *batch, 1)
+	v.AtLeast("slow-items", *slowItems, 1)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	config := RunConfig{Items: *items, Capacity: *capacity, Shapes: shapes, Producers: *producers, Pause: *pause,
+		Batch: *batch, SlowItems: *slowItems}
+	result := RunResult{Config: config, Env: bench.CaptureEnv()}
+	ctx := context.Background()
+	for _, shape := range shapes {
+		for _, c := range candidates() {
+			slog.Info("passing items through", "buffer", c.name, "shape", shape.String())
+			r := throughput(ctx, c.name+" "+shape.String(), c.new(*capacity), shape, *items)
+			r.Buffer = c.name
+			result.Throughput = append(result.Throughput, r)
+		}
+	}
+	for _, c := range candidates() {
+		slog.Info("holding producers back", "buffer", c.name, "producers", *producers)
+		r := fairness(ctx, c.name+" slow consumer", c.new(*capacity), *producers, *slowItems, *batch, *pause)
+		r.Buffer = c.name
+		result.Fairness = append(result.Fairness, r)
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per run, an op is one item through the buffer
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/bounded_buffer"); err != nil {
+		return err
+	}
+	config := result.Config
+	benchmarks := []bench.Benchmark{}
+	for _, r := range result.Throughput {
+		n := int64(config.Items)
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name:    fmt.Sprintf("Throughput/%s/shape=%s", r.Buffer, r.Shape),
+			N:       n,
+			Metrics: []bench.Metric{bench.NsPerOp(r.ElapsedNs, n)},
+		})
+	}
+	for _, r := range result.Fairness {
+		n := int64(config.SlowItems)
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: fmt.Sprintf("SlowConsumer/%s/producers=%d", r.Buffer, config.Producers),
+			N:    n,
+			Metrics: []bench.Metric{
+				bench.NsPerOp(r.ElapsedNs, n),
+				{Value: r.PutP50Ns, Unit: "put-p50-ns"},
+				{Value: r.PutMaxNs, Unit: "put-max-ns"},
+			},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
@@ -0,0 +1,63 @@
+package boundedbuffer
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz boundedbuffer, it is about backpressure from a slow consumer and how barging under a
+// condition variable trades a fast median for a starved tail that the channel and semaphores avoid
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does every buffer go at the same pace behind the slow consumer, however many producers there are?",
+		Choices: []string{
+			"The runtime runs the producers on a single thread once they block, so only one can put at a time",
+			"Every buffer is a channel underneath, so they all share the channel's scheduling",
+			"Once full, a Put only goes in when the consumer takes an item out, holding producers to its pace",
+			"The lesson rate limits the producers to match the consumer's configured delay",
+		},
+		Answer:      2,
+		Explanation: "that's backpressure, a bounded buffer passes the consumer's pace back to the producers rather than growing without limit",
+	},
+	{
+		Prompt: "Why do most of the condition buffer's Puts go straight in behind the slow consumer, when the others' all wait?",
+		Choices: []string{
+			"A producer that just put can come back and take a freed slot before the one Signal woke has the mutex",
+			"Signal wakes every waiting producer at once and whichever is fastest takes the slot",
+			"The condition buffer checks whether it's full before taking the mutex, so it skips the wait",
+			"The condition buffer has a spare slot the others don't, so it is full less often",
+		},
+		Answer:      0,
+		Explanation: "that's barging, Signal wakes a waiter but doesn't hand it the slot, it has to take the mutex and look again",
+	},
+	{
+		Prompt: "Why is the condition buffer's longest Put so much longer than the channel's?",
+		Choices: []string{
+			"sync.Cond allocates a wait list entry on every Wait, and the collector pauses it",
+			"A woken producer whose slot was taken by a barger waits again, and can lose round after round",
+			"The consumer favours the channel, taking from it first whenever both have items",
+			"Its mutex spins before it parks, so a waiting producer burns its time slice",
+		},
+		Answer:      1,
+		Explanation: "a median better than fair with a tail far worse, fairness costs the lucky producers and saves the unlucky ones",
+	},
+	{
+		Prompt: "Why does every Put through the channel and the semaphores wait about as long as every other?",
+		Choices: []string{
+			"They never block, a Put that finds no room is dropped and retried after a fixed sleep",
+			"They hand out slots at random among the waiters, so on average each waits as long",
+			"They only let one producer in at a time, so the producers take strict turns",
+			"A freed slot goes to the waiter at the front of the queue and newcomers join the back",
+		},
+		Answer:      3,
+		Explanation: "a receive from a full channel moves the first waiting sender's item into the buffer, and x/sync's semaphore won't let a newcomer past its waiters",
+	},
+	{
+		Prompt: "Why is the semaphore buffer the slowest when nothing is slow?",
+		Choices: []string{
+			"It copies the slice on every Get so the consumer never shares memory with a producer",
+			"Each Put and Get acquires one semaphore and releases the other, on top of the slice's mutex",
+			"Its semaphores sleep for a short while between attempts rather than waiting to be woken",
+			"It checks the buffer for room twice, once before and once after acquiring its lock",
+		},
+		Answer:      1,
+		Explanation: "three locks where a condition buffer takes one and a channel's is built into the runtime, the classic textbook design isn't the quick one",
+	},
+}
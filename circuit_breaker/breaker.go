@@ -0,0 +1,153 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by Allow when the breaker is open, or half open with every probe already out
+var ErrOpen = errors.New("the circuit breaker is open")
+
+// State is where a breaker is in its cycle
+type State int
+
+const (
+	// Closed lets every call through, counting failures in a row
+	Closed State = iota
+	// Open fails every call at once, without calling the dependency, until OpenFor has passed
+	Open
+	// HalfOpen lets Probes calls through to find out whether the dependency has recovered
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	default:
+		return "half-open"
+	}
+}
+
+// Config is how a breaker decides to open and close
+type Config struct {
+	// FailureThreshold is the failures in a row that open a closed breaker
+	FailureThreshold int
+	// OpenFor is how long an open breaker waits before going half open
+	OpenFor time.Duration
+	// Probes is how many calls a half open breaker lets through, when they all succeed it closes, when any fails it
+	// opens again
+	Probes int
+}
+
+// Transition is a breaker changing state, At is when
+type Transition struct {
+	At   time.Time
+	From State
+	To   State
+}
+
+// Breaker stands between callers and a dependency, once the dependency has failed FailureThreshold times in a row
+// it stops calling it, failing calls straight away, so callers don't wait on timeouts from a dependency that's down
+// and the dependency isn't buried in calls as it tries to come back, then after OpenFor it lets a few probes through
+// to see whether it's recovered
+// Every method takes the time rather than reading the clock, so a simulation can drive it, and a call's outcome is
+// recorded when it finishes, which may be after the breaker has moved on, so each call carries the generation it
+// started in, and outcomes from an earlier generation are ignored
+type Breaker struct {
+	mu         sync.Mutex
+	config     Config
+	state      State
+	generation uint64
+	failures   int
+	probes     int
+	succeeded  int
+	openedAt   time.Time
+	// OnTransition, when set, is called with every change of state, with the breaker locked
+	OnTransition func(Transition)
+}
+
+// New creates a closed breaker
+func New(config Config) *Breaker {
+	return &Breaker{config: config}
+}
+
+// State is the breaker's state at now, an open breaker whose OpenFor has passed is half open
+func (b *Breaker) State(now time.Time) State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expire(now)
+	return b.state
+}
+
+// Allow asks whether a call may go ahead at now, returning ErrOpen if it mayn't, and otherwise a done function to be
+// called with the time the call finished and its error
+func (b *Breaker) Allow(now time.Time) (done func(end time.Time, err error), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expire(now)
+	switch b.state {
+	case Open:
+		return nil, ErrOpen
+	case HalfOpen:
+		if b.probes == b.config.Probes {
+			return nil, ErrOpen
+		}
+		b.probes++
+	}
+	generation := b.generation
+	return func(end time.Time, err error) { b.record(generation, end, err) }, nil
+}
+
+// record counts a call's outcome, if the breaker hasn't changed state since it started
+func (b *Breaker) record(generation uint64, end time.Time, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation != b.generation {
+		return
+	}
+	switch b.state {
+	case Closed:
+		if err == nil {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.config.FailureThreshold {
+			b.move(end, Open)
+		}
+	case HalfOpen:
+		if err != nil {
+			b.move(end, Open)
+			return
+		}
+		b.succeeded++
+		if b.succeeded == b.config.Probes {
+			b.move(end, Closed)
+		}
+	}
+}
+
+// expire moves an open breaker to half open once OpenFor has passed
+func (b *Breaker) expire(now time.Time) {
+	if b.state == Open && !now.Before(b.openedAt.Add(b.config.OpenFor)) {
+		b.move(b.openedAt.Add(b.config.OpenFor), HalfOpen)
+	}
+}
+
+// move changes state, starting a new generation with its counts cleared
+func (b *Breaker) move(at time.Time, to State) {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.failures, b.probes, b.succeeded = 0, 0, 0
+	if to == Open {
+		b.openedAt = at
+	}
+	if b.OnTransition != nil {
+		b.OnTransition(Transition{At: at, From: from, To: to})
+	}
+}
@@ -0,0 +1,114 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errFailed = errors.New("failed")
+
+// call makes a call through b at now that finishes at once with err
+func call(t *testing.T, b *Breaker, now time.Time, err error) {
+	t.Helper()
+	done, allowErr := b.Allow(now)
+	if allowErr != nil {
+		t.Fatalf("call at %v rejected: %v", now, allowErr)
+	}
+	done(now, err)
+}
+
+func TestOpensAfterThresholdFailuresInARow(t *testing.T) {
+	b := New(Config{FailureThreshold: 3, OpenFor: time.Second, Probes: 1})
+	now := time.Unix(0, 0)
+	call(t, b, now, errFailed)
+	call(t, b, now, errFailed)
+	call(t, b, now, nil)
+	call(t, b, now, errFailed)
+	call(t, b, now, errFailed)
+	if state := b.State(now); state != Closed {
+		t.Fatalf("state after a success broke the run of failures is %v, want closed", state)
+	}
+	call(t, b, now, errFailed)
+	if state := b.State(now); state != Open {
+		t.Fatalf("state after 3 failures in a row is %v, want open", state)
+	}
+	if _, err := b.Allow(now); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow on an open breaker returned %v, want ErrOpen", err)
+	}
+}
+
+func TestHalfOpenLetsProbesThroughThenCloses(t *testing.T) {
+	b := New(Config{FailureThreshold: 1, OpenFor: time.Second, Probes: 2})
+	now := time.Unix(0, 0)
+	call(t, b, now, errFailed)
+	if state := b.State(now.Add(time.Second - 1)); state != Open {
+		t.Fatalf("state just before OpenFor passed is %v, want open", state)
+	}
+	now = now.Add(time.Second)
+	if state := b.State(now); state != HalfOpen {
+		t.Fatalf("state once OpenFor passed is %v, want half-open", state)
+	}
+	first, err := b.Allow(now)
+	if err != nil {
+		t.Fatalf("first probe rejected: %v", err)
+	}
+	second, err := b.Allow(now)
+	if err != nil {
+		t.Fatalf("second probe rejected: %v", err)
+	}
+	if _, err := b.Allow(now); !errors.Is(err, ErrOpen) {
+		t.Fatalf("a third call with 2 probes out returned %v, want ErrOpen", err)
+	}
+	first(now, nil)
+	second(now, nil)
+	if state := b.State(now); state != Closed {
+		t.Fatalf("state after every probe succeeded is %v, want closed", state)
+	}
+}
+
+func TestFailedProbeReopens(t *testing.T) {
+	b := New(Config{FailureThreshold: 1, OpenFor: time.Second, Probes: 2})
+	var transitions []Transition
+	b.OnTransition = func(tr Transition) { transitions = append(transitions, tr) }
+	now := time.Unix(0, 0)
+	call(t, b, now, errFailed)
+	now = now.Add(time.Second)
+	call(t, b, now, nil)
+	call(t, b, now, errFailed)
+	if state := b.State(now.Add(time.Second - 1)); state != Open {
+		t.Fatalf("state after a probe failed is %v, want open", state)
+	}
+	want := []Transition{{time.Unix(0, 0), Closed, Open}, {now, Open, HalfOpen}, {now, HalfOpen, Open}}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if !transitions[i].At.Equal(want[i].At) || transitions[i].From != want[i].From || transitions[i].To != want[i].To {
+			t.Fatalf("transition %d is %v, want %v", i, transitions[i], want[i])
+		}
+	}
+}
+
+func TestOutcomesFromAnEarlierStateAreIgnored(t *testing.T) {
+	b := New(Config{FailureThreshold: 1, OpenFor: time.Second, Probes: 1})
+	now := time.Unix(0, 0)
+	slow, err := b.Allow(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call(t, b, now, errFailed)
+	now = now.Add(time.Second)
+	probe, err := b.Allow(now)
+	if err != nil {
+		t.Fatalf("probe rejected: %v", err)
+	}
+	slow(now, errFailed)
+	if state := b.State(now); state != HalfOpen {
+		t.Fatalf("a failure started while closed moved the half open breaker to %v", state)
+	}
+	probe(now, nil)
+	if state := b.State(now); state != Closed {
+		t.Fatalf("state after the probe succeeded is %v, want closed", state)
+	}
+}
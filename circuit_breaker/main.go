@@ -0,0 +1,375 @@
+// Package circuitbreaker is the circuit breaker lesson, teachgo breaker, a breaker with closed, open and half open
+// states in front of a simulated dependency that goes down and comes back, against calling it without one
+package circuitbreaker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	binaryheap "github.com/joshdurbin/teaching-go/binary_heap"
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo breaker -h, its first line is the summary teachgo help
+// lists
+const Description = `Circuit breakers, failing fast while a dependency is down and probing for its recovery
+
+Simulates -rate requests a second for -duration to a dependency that's healthy, then down, then failing half its
+calls, then healthy again, where a successful call takes -latency and a failed one the -timeout it takes to give up.
+Runs the requests once calling the dependency every time, and once through a circuit breaker that opens after
+-threshold failures in a row, failing calls at once for -open-for, then goes half open, letting -probes calls
+through and closing if they all succeed. Lists the breaker's state transitions and graphs what happened to the
+requests over time, showing the calls left waiting on timeouts without the breaker, and how soon each gets back to
+full throughput once the dependency recovers. The clock is simulated, so every run of a
+seed is the same.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "breaker",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"circuit breakers", "failure handling", "state machines", "timeouts", "simulated time"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// health is the dependency's health over a run, each phase lasting until a fraction of -duration and failing a
+// fraction of calls
+var health = []struct {
+	until   float64
+	failure float64
+}{
+	{0.25, 0.01},
+	{0.5, 1},
+	{0.6, 0.5},
+	{1, 0.01},
+}
+
+// recoveredAt is the fraction of the run when the dependency is healthy again
+var recoveredAt = health[len(health)-2].until
+
+// chartRows and chartWidth are the shape of the graph, a row for each slice of the run
+const (
+	chartRows  = 30
+	chartWidth = 40
+)
+
+// maxTransitions is the most state transitions listed, the rest are counted
+const maxTransitions = 20
+
+// errUnavailable is what the simulated dependency's failed calls return
+var errUnavailable = errors.New("the dependency timed out")
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Rate      float64       `json:"rate"`
+	Duration  time.Duration `json:"duration"`
+	Latency   time.Duration `json:"latency"`
+	Timeout   time.Duration `json:"timeout"`
+	Threshold int           `json:"threshold"`
+	OpenFor   time.Duration `json:"open_for"`
+	Probes    int           `json:"probes"`
+	Seed      int64         `json:"seed"`
+}
+
+// request is one simulated request, when it arrives and the draw that decides whether the dependency fails it
+type request struct {
+	at   time.Duration
+	draw float64
+}
+
+// Slice is one row of the graph, what happened to the requests that arrived in a slice of the run, State is the
+// breaker's at the slice's end
+type Slice struct {
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+	Rejected  int    `json:"rejected"`
+	State     string `json:"state,omitempty"`
+}
+
+// Run is every request made once, with or without the breaker
+// Recovered is how long after the dependency was healthy again the run got back to full throughput, nine in ten
+// requests in a slice succeeding
+type Run struct {
+	Name          string         `json:"name"`
+	Succeeded     int            `json:"succeeded"`
+	Failed        int            `json:"failed"`
+	Rejected      int            `json:"rejected"`
+	MeanLatencyNs float64        `json:"mean_latency_ns"`
+	PeakInFlight  int            `json:"peak_in_flight"`
+	RecoveredNs   float64        `json:"recovered_ns"`
+	Slices        []Slice        `json:"slices"`
+	Transitions   []TransitionAt `json:"transitions,omitempty"`
+}
+
+// TransitionAt is a breaker's transition, timed from the start of the run
+type TransitionAt struct {
+	AtNs float64 `json:"at_ns"`
+	From string  `json:"from"`
+	To   string  `json:"to"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config   RunConfig `json:"config"`
+	Env      bench.Env `json:"env"`
+	Requests int       `json:"requests"`
+	Runs     []Run     `json:"runs"`
+}
+
+// failure is the fraction of calls the dependency fails at t
+func failure(t, duration time.Duration) float64 {
+	for _, phase := range health {
+		if t.Seconds() < phase.until*duration.Seconds() {
+			return phase.failure
+		}
+	}
+	return health[len(health)-1].failure
+}
+
+// requests draws the requests, random arrivals at rate, the same for both runs
+func requests(rng *rand.Rand, rate float64, duration time.Duration) []request {
+	reqs := []request{}
+	for t := rng.ExpFloat64() / rate; t < duration.Seconds(); t += rng.ExpFloat64() / rate {
+		reqs = append(reqs, request{at: time.Duration(t * float64(time.Second)), draw: rng.Float64()})
+	}
+	return reqs
+}
+
+// inFlight is a call the dependency hasn't answered yet, done is the breaker's, nil without one
+type inFlight struct {
+	end  time.Duration
+	err  error
+	done func(time.Time, error)
+}
+
+// simulate makes every request, through the breaker if there is one, finishing each call in flight when the
+// simulated clock reaches its end, so the breaker hears of a failure only once its timeout has passed
+func simulate(name string, config RunConfig, reqs []request, breaker *Breaker) Run {
+	start := time.Unix(0, 0)
+	run := Run{Name: name, Slices: make([]Slice, chartRows)}
+	sliceLength := config.Duration / chartRows
+	slice := func(t time.Duration) *Slice { return &run.Slices[min(int(t/sliceLength), chartRows-1)] }
+	if breaker != nil {
+		breaker.OnTransition = func(tr Transition) {
+			run.Transitions = append(run.Transitions, TransitionAt{AtNs: float64(tr.At.Sub(start).Nanoseconds()),
+				From: tr.From.String(), To: tr.To.String()})
+		}
+	}
+	calls := binaryheap.NewHeap(func(a, b inFlight) bool { return a.end < b.end })
+	finish := func(until time.Duration) {
+		for calls.Len() > 0 && calls.Peek().end <= until {
+			call := calls.Pop()
+			if call.done != nil {
+				call.done(start.Add(call.end), call.err)
+			}
+		}
+	}
+	var totalLatency time.Duration
+	for _, r := range reqs {
+		finish(r.at)
+		var done func(time.Time, error)
+		if breaker != nil {
+			var err error
+			if done, err = breaker.Allow(start.Add(r.at)); err != nil {
+				run.Rejected++
+				slice(r.at).Rejected++
+				continue
+			}
+		}
+		call := inFlight{end: r.at + config.Latency, done: done}
+		if r.draw < failure(r.at, config.Duration) {
+			call.end, call.err = r.at+config.Timeout, errUnavailable
+			run.Failed++
+			slice(r.at).Failed++
+		} else {
+			run.Succeeded++
+			slice(r.at).Succeeded++
+		}
+		totalLatency += call.end - r.at
+		calls.Push(call)
+		run.PeakInFlight = max(run.PeakInFlight, calls.Len())
+	}
+	finish(config.Duration + config.Timeout)
+	if len(reqs) > 0 {
+		run.MeanLatencyNs = float64(totalLatency.Nanoseconds()) / float64(len(reqs))
+	}
+
+	if breaker != nil {
+		for i := range run.Slices {
+			run.Slices[i].State = breaker.stateAt(start.Add(time.Duration(i+1)*sliceLength), run.Transitions, start)
+		}
+	}
+	recovery := time.Duration(recoveredAt * float64(config.Duration))
+	run.RecoveredNs = -1
+	for i, s := range run.Slices {
+		from := time.Duration(i) * sliceLength
+		offered := s.Succeeded + s.Failed + s.Rejected
+		if from >= recovery && offered > 0 && s.Succeeded*10 >= offered*9 {
+			run.RecoveredNs = float64((from - recovery).Nanoseconds())
+			break
+		}
+	}
+	return run
+}
+
+// stateAt is the state the transitions left the breaker in at t, counting an open breaker whose OpenFor has passed
+// as half open as Allow would
+func (b *Breaker) stateAt(t time.Time, transitions []TransitionAt, start time.Time) string {
+	state, opened := Closed.String(), time.Time{}
+	for _, tr := range transitions {
+		at := start.Add(time.Duration(tr.AtNs))
+		if at.After(t) {
+			break
+		}
+		state = tr.To
+		if state == Open.String() {
+			opened = at
+		}
+	}
+	if state == Open.String() && !t.Before(opened.Add(b.config.OpenFor)) {
+		return HalfOpen.String()
+	}
+	return state
+}
+
+// bar draws a row of the graph, # for requests that succeeded, x for failed and - for rejected, scaled so a slice
+// of requests at the rate fills the width
+func bar(s Slice, full float64) string {
+	scale := func(n int) int { return int(float64(n)*chartWidth/full + 0.5) }
+	return strings.Repeat("#", scale(s.Succeeded)) + strings.Repeat("x", scale(s.Failed)) + strings.Repeat("-", scale(s.Rejected))
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Circuit Breakers")
+	fmt.Fprintln(w, "================")
+	fmt.Fprintf(w, "Requests: %d, %g a second for %v\n", result.Requests, config.Rate, config.Duration)
+	fmt.Fprintf(w, "Dependency: healthy, down from %v, failing half its calls from %v, healthy from %v, calls take %v, failures %v\n",
+		time.Duration(health[0].until*float64(config.Duration)), time.Duration(health[1].until*float64(config.Duration)),
+		time.Duration(recoveredAt*float64(config.Duration)), config.Latency, config.Timeout)
+	fmt.Fprintf(w, "Breaker: opens after %d failures in a row, stays open %v, closes after %d probes succeed\n",
+		config.Threshold, config.OpenFor, config.Probes)
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%-12s %10s %8s %9s %14s %10s %12s\n", "Run", "Succeeded", "Failed", "Rejected", "Mean latency", "In flight", "Recovered")
+	for _, run := range result.Runs {
+		recovered := "never"
+		if run.RecoveredNs >= 0 {
+			recovered = "after " + time.Duration(run.RecoveredNs).String()
+		}
+		fmt.Fprintf(w, "%-12s %10d %8d %9d %14v %10d %12s\n", run.Name, run.Succeeded, run.Failed, run.Rejected,
+			time.Duration(run.MeanLatencyNs).Round(time.Microsecond), run.PeakInFlight, recovered)
+	}
+	fmt.Fprintln(w, "\nIn flight is the most calls waiting on the dependency at once, Recovered how long after the dependency")
+	fmt.Fprintln(w, "was healthy again nine in ten requests succeeded. Without the breaker every call to the dead dependency")
+	fmt.Fprintln(w, "waits out the timeout, with it most are rejected at once and the dependency is left alone, the price is")
+	fmt.Fprintln(w, "rejecting calls that would have succeeded until a round of probes finds it healthy")
+
+	without, with := result.Runs[0], result.Runs[1]
+	fmt.Fprintln(w, "\n=====Breaker state transitions=====")
+	for i, tr := range with.Transitions {
+		if i == maxTransitions {
+			fmt.Fprintf(w, "and %d more\n", len(with.Transitions)-maxTransitions)
+			break
+		}
+		fmt.Fprintf(w, "%12v %10s -> %s\n", time.Duration(tr.AtNs).Round(time.Millisecond), tr.From, tr.To)
+	}
+
+	sliceLength := config.Duration / chartRows
+	fmt.Fprintf(w, "\n=====Requests every %v, through the breaker succeeded #, failed x and rejected -=====\n", sliceLength)
+	fmt.Fprintf(w, "%10s %9s %9s %9s %9s %9s %10s\n", "From", "No: ok", "No: fail", "ok", "fail", "rejected", "State")
+	full := config.Rate * sliceLength.Seconds()
+	for i, s := range with.Slices {
+		n := without.Slices[i]
+		fmt.Fprintf(w, "%10v %9d %9d %9d %9d %9d %10s |%s\n", time.Duration(i)*sliceLength, n.Succeeded, n.Failed,
+			s.Succeeded, s.Failed, s.Rejected, s.State, bar(s, full))
+	}
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo breaker
+func Main(args []string) {
+	fs := bench.NewFlagSet("breaker", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the circuit breaker lesson simulates a single run"
+	rate := fs.Float64("rate", 100, "the requests a second")
+	duration := fs.Duration("duration", time.Minute, "the simulated time the requests arrive over")
+	latency := fs.Duration("latency", 20*time.Millisecond, "how long a successful call to the dependency takes")
+	timeout := fs.Duration("timeout", time.Second, "how long a failed call takes to give up")
+	threshold := fs.Int("threshold", 5, "the failures in a row that open the breaker")
+	openFor := fs.Duration("open-for", 5*time.Second, "how long the breaker stays open before probing")
+	probes := fs.Int("probes", 3, "the calls a half open breaker lets through, closing when they all succeed")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "breaker simulates a single run, -trials isn't supported")
+	v.Check(*rate > 0, "-rate must be more than 0, got %g", *rate)
+	v.Check(*duration >= time.Second, "-duration must be at least 1s, got %v", *duration)
+	v.NotNegative("latency", *latency)
+	v.NotNegative("timeout", *timeout)
+	v.AtLeast("threshold", *threshold, 1)
+	v.NotNegative("open-for", *openFor)
+	v.AtLeast("probes", *probes, 1)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	config := RunConfig{Rate: *rate, Duration: *duration, Latency: *latency, Timeout: *timeout, Threshold: *threshold,
+		OpenFor: *openFor, Probes: *probes, Seed: globals.Seed}
+	result := RunResult{Config: config, Env: bench.CaptureEnv()}
+	rng := rand.New(rand.NewSource(globals.Seed))
+	reqs := requests(rng, *rate, *duration)
+	result.Requests = len(reqs)
+	slog.Info("simulating", "requests", len(reqs))
+	result.Runs = []Run{
+		simulate("no breaker", config, reqs, nil),
+		simulate("breaker", config, reqs, New(Config{FailureThreshold: *threshold, OpenFor: *openFor, Probes: *probes})),
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per run, an op is one request and its ns/op the simulated latency, with the share of
+// requests that succeeded and the calls in flight at the peak
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/circuit_breaker"); err != nil {
+		return err
+	}
+	benchmarks := []bench.Benchmark{}
+	for _, run := range result.Runs {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: "Requests/" + strings.ReplaceAll(run.Name, " ", "-"),
+			N:    int64(result.Requests),
+			Metrics: []bench.Metric{
+				{Value: run.MeanLatencyNs, Unit: "ns/op"},
+				{Value: float64(run.Succeeded) / float64(max(result.Requests, 1)), Unit: "succeeded/op"},
+				{Value: float64(run.PeakInFlight), Unit: "in-flight"},
+			},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
@@ -0,0 +1,63 @@
+package circuitbreaker
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz breaker, it walks through the breaker's states, why rejecting fast lowers latency,
+// what delays opening and closing, and why each outcome is tagged with the generation it started in
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why is the mean latency so much lower through the breaker, when the dependency is the same?",
+		Choices: []string{
+			"The breaker retries a failed call once, and the retry usually lands after the dependency recovers",
+			"The breaker caches the dependency's last good response and serves it while the dependency is down",
+			"The breaker sends fewer requests in total, so the dependency is less loaded and answers faster",
+			"While it's open, calls are rejected at once instead of each waiting out the timeout",
+		},
+		Answer:      3,
+		Explanation: "a rejection still isn't a success, but a caller told straight away can fall back or give up without tying up a goroutine for the timeout",
+	},
+	{
+		Prompt: "Why does the breaker open a second or so after the dependency goes down, not at once?",
+		Choices: []string{
+			"A failure is only heard when the call gives up at the timeout, then the threshold has to be met",
+			"It polls the dependency's health endpoint once a second and only opens on the next poll",
+			"The threshold is a failure rate over a one second window, so it needs a full window of data",
+			"The first few calls after it goes down still succeed from the dependency's connection pool",
+		},
+		Answer:      0,
+		Explanation: "a shorter timeout opens the breaker sooner, and also fails healthy calls that happen to be slow",
+	},
+	{
+		Prompt: "Why does the half open breaker go back to open so often while the dependency is failing half its calls?",
+		Choices: []string{
+			"The probes are sent together and time out on the breaker's own lock before reaching the dependency",
+			"Half open only lets a single probe through, and one failure in two is enough to reopen it",
+			"It closes only if every probe succeeds, so most rounds of probes include at least one failure",
+			"Each trip doubles the open period, so the breaker spends longer open after every failure",
+		},
+		Answer:      2,
+		Explanation: "with three probes each succeeding half the time, only one round in eight closes it",
+	},
+	{
+		Prompt: "Why does the breaker's run get back to full throughput later than calling the dependency every time?",
+		Choices: []string{
+			"The breaker's bookkeeping adds latency to every call, so it never quite reaches the direct run's rate",
+			"It may still be open when the dependency recovers, rejecting calls until probes succeed",
+			"Calls that were rejected are queued and replayed after recovery, delaying the new ones",
+			"It has to wait for every call still in flight to time out before it will change state",
+		},
+		Answer:      1,
+		Explanation: "that's the price of leaving the dependency alone, a shorter -open-for finds the recovery sooner and probes a dependency that's still down more often",
+	},
+	{
+		Prompt: "Why does a call's outcome carry the generation the breaker was in when it started?",
+		Choices: []string{
+			"So a failed call is retried within the same generation rather than in a later one",
+			"To count how many calls each generation made for the report at the end of the run",
+			"So that transitions are applied in order when two calls finish at the same moment",
+			"A slow call from before it opened mustn't count as a failed probe once it has moved on",
+		},
+		Answer:      3,
+		Explanation: "the failures still in flight when the breaker opens finish while it's open or half open, and without the generation each would count against the new state",
+	},
+}
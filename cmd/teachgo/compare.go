@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+)
+
+// resultFile is a lesson's JSON result flattened into metrics, keyed by their path through the document, e.g.
+// counters/Mutex/p99_ns, config and env are kept apart as they describe the run and the machine rather than
+// measuring anything
+type resultFile struct {
+	path    string
+	config  any
+	env     any
+	metrics map[string]float64
+	order   []string
+}
+
+func readResult(path string) (resultFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return resultFile{}, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return resultFile{}, fmt.Errorf("%s isn't a JSON result: %w", path, err)
+	}
+
+	result := resultFile{path: path, config: doc["config"], env: doc["env"], metrics: map[string]float64{}}
+	delete(doc, "config")
+	delete(doc, "env")
+	result.flatten("", doc)
+	return result, nil
+}
+
+// flatten walks the document collecting every number and boolean, a list of objects with a name, like the counters
+// of a counters result, is keyed by those names so results listing them in a different order still line up
+func (r *resultFile) flatten(prefix string, value any) {
+	key := func(name string) string {
+		if prefix == "" {
+			return name
+		}
+		return prefix + "/" + name
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		names := []string{}
+		for name := range v {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			if name != "name" {
+				r.flatten(key(name), v[name])
+			}
+		}
+	case []any:
+		for i, item := range v {
+			name := fmt.Sprint(i)
+			if object, ok := item.(map[string]any); ok {
+				if n, ok := object["name"].(string); ok {
+					name = n
+				}
+			}
+			r.flatten(key(name), item)
+		}
+	case float64:
+		r.add(prefix, v)
+	case bool:
+		if v {
+			r.add(prefix, 1)
+		} else {
+			r.add(prefix, 0)
+		}
+	}
+}
+
+func (r *resultFile) add(name string, value float64) {
+	r.metrics[name] = value
+	r.order = append(r.order, name)
+}
+
+// betterWhen says which direction is an improvement for a metric, by the naming conventions of the result files,
+// -1 for timings where lower is better, 1 for throughput and correctness, 0 for counts that are only reported
+func betterWhen(metric string) int {
+	switch {
+	case strings.HasSuffix(metric, "_ns"):
+		return -1
+	case strings.HasSuffix(metric, "_per_sec"), strings.HasSuffix(metric, "/correct"):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func formatMetric(metric string, value float64) string {
+	switch {
+	case strings.HasSuffix(metric, "_ns"):
+		return time.Duration(value).String()
+	case strings.HasSuffix(metric, "_per_sec"):
+		return bench.FormatRate(value)
+	case strings.HasSuffix(metric, "/correct"):
+		return fmt.Sprint(value == 1)
+	default:
+		return fmt.Sprintf("%g", value)
+	}
+}
+
+// runCompare prints every metric of each result file against the first, the baseline, marking each change in the
+// wrong direction beyond the threshold as a regression, and exits with status 1 when there is any, so it can gate a
+// script or a CI job
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("teachgo compare", flag.ExitOnError)
+	threshold := fs.Float64("threshold", 5, "the percentage change in the wrong direction that counts as a regression, smaller changes are noise")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: teachgo compare [flags] baseline.json result.json [more results...]")
+		fmt.Fprintln(fs.Output(), "\nCompares lesson results written with -format json against the first, the baseline\n\nFlags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	results := []resultFile{}
+	for _, path := range fs.Args() {
+		result, err := readResult(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		results = append(results, result)
+	}
+
+	if regressions := compareResults(os.Stdout, results, *threshold); regressions > 0 {
+		os.Exit(1)
+	}
+}
+
+// compareResults prints the comparison table and returns the number of regressions in it
+func compareResults(w io.Writer, results []resultFile, threshold float64) int {
+	baseline := results[0]
+	for _, result := range results[1:] {
+		if !reflect.DeepEqual(result.config, baseline.config) {
+			fmt.Fprintf(w, "Warning: %s was run with different settings from %s, differences may not be down to the code\n",
+				result.path, baseline.path)
+		}
+		if !reflect.DeepEqual(result.env, baseline.env) {
+			fmt.Fprintf(w, "Warning: %s was run on a different machine or Go version from %s, compare them with care\n",
+				result.path, baseline.path)
+		}
+	}
+
+	// metrics only some files have, like a counter added since the baseline, are listed after the shared ones
+	order := slices.Clone(baseline.order)
+	for _, result := range results[1:] {
+		for _, metric := range result.order {
+			if !slices.Contains(order, metric) {
+				order = append(order, metric)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "%-44s", "Metric")
+	for _, result := range results {
+		fmt.Fprintf(w, " %24s", filepath.Base(result.path))
+	}
+	fmt.Fprintln(w)
+
+	regressions := 0
+	for _, metric := range order {
+		base, inBase := baseline.metrics[metric]
+		line := fmt.Sprintf("%-44s", metric)
+		if inBase {
+			line += fmt.Sprintf(" %24s", formatMetric(metric, base))
+		} else {
+			line += fmt.Sprintf(" %24s", "-")
+		}
+
+		marks := []string{}
+		for _, result := range results[1:] {
+			value, ok := result.metrics[metric]
+			switch {
+			case !ok:
+				line += fmt.Sprintf(" %24s", "-")
+				continue
+			case !inBase || base == value:
+				line += fmt.Sprintf(" %24s", formatMetric(metric, value))
+				continue
+			}
+
+			delta := (value - base) / math.Abs(base) * 100
+			if base == 0 {
+				delta = math.Copysign(math.Inf(1), value)
+			}
+			line += fmt.Sprintf(" %24s", fmt.Sprintf("%s (%+.1f%%)", formatMetric(metric, value), delta))
+
+			direction := betterWhen(metric)
+			switch {
+			case direction != 0 && delta*float64(direction) < -threshold:
+				marks = append(marks, "REGRESSION in "+filepath.Base(result.path))
+				regressions++
+			case direction != 0 && delta*float64(direction) > threshold:
+				marks = append(marks, "improved in "+filepath.Base(result.path))
+			}
+		}
+		if len(marks) > 0 {
+			line += "  " + strings.Join(marks, ", ")
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	fmt.Fprintf(w, "\n%d regressions beyond %.1f%%\n", regressions, threshold)
+	return regressions
+}
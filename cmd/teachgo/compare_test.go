@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareResults(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, result string) resultFile {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(result), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		r, err := readResult(path)
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		return r
+	}
+
+	baseline := write("baseline.json", `{"config": {"routines": 4}, "counters": [
+		{"name": "Mutex", "correct": true, "throughput_ops_per_sec": 1000, "p99_ns": 100},
+		{"name": "CAS", "correct": true, "throughput_ops_per_sec": 2000, "p99_ns": 50}]}`)
+	// the counters listed the other way round, Mutex slower and CAS broken, with a p99 too close to call
+	changed := write("changed.json", `{"config": {"routines": 4}, "counters": [
+		{"name": "CAS", "correct": false, "throughput_ops_per_sec": 2000, "p99_ns": 51},
+		{"name": "Mutex", "correct": true, "throughput_ops_per_sec": 800, "p99_ns": 100}]}`)
+
+	if v := baseline.metrics["counters/CAS/p99_ns"]; v != 50 {
+		t.Errorf("counters/CAS/p99_ns is %v, want 50", v)
+	}
+	if regressions := compareResults(io.Discard, []resultFile{baseline, changed}, 5); regressions != 2 {
+		t.Errorf("found %d regressions, want 2", regressions)
+	}
+	if regressions := compareResults(io.Discard, []resultFile{changed, baseline}, 5); regressions != 0 {
+		t.Errorf("found %d regressions comparing the other way, want 0", regressions)
+	}
+}
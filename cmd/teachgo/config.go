@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A config file sets flags from YAML so an experiment can be saved, shared and rerun exactly, for example
+//
+//	global:
+//	  seed: 42
+//	counters:
+//	  routines: 8
+//	  counters: [mutex, atomicint64, sharded]
+//	  runs:
+//	    - procs: 1
+//	    - procs: 4
+//	skiplist:
+//	  elements: 100000
+//
+// The global section holds the flags every lesson shares, every other section is named after a lesson and holds its
+// flags, each key a flag name without the dash, a list is joined with commas for flags like -counters
+// runs turns one lesson into a series of runs, each run applying its own flags on top of the section's
+// Anything given on the command line wins over the file
+
+// configRun is one run of a lesson, the arguments are flags in the -name=value form a FlagSet parses
+type configRun struct {
+	lesson string
+	args   []string
+}
+
+// readConfig reads a config file into the runs it describes, in the order its sections appear, only the named
+// lesson's when one is given
+func readConfig(path, only string) ([]configRun, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// decoding into a node rather than a map keeps the sections in the order they were written
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected sections of flags, global and one per lesson", path)
+	}
+
+	global := []string{}
+	sections := map[string]*yaml.Node{}
+	order := []string{}
+	for i := 0; i < len(root.Content); i += 2 {
+		name, section := root.Content[i].Value, root.Content[i+1]
+		if name == "global" {
+			if global, err = flagArgs(section); err != nil {
+				return nil, fmt.Errorf("%s: global: %w", path, err)
+			}
+			continue
+		}
+		sections[name] = section
+		order = append(order, name)
+	}
+
+	if only != "" {
+		if _, ok := sections[only]; !ok {
+			// a lesson without a section still takes the global flags
+			return []configRun{{lesson: only, args: global}}, nil
+		}
+		order = []string{only}
+	}
+
+	runs := []configRun{}
+	for _, name := range order {
+		lessonRuns, err := sectionRuns(name, sections[name], global)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, name, err)
+		}
+		runs = append(runs, lessonRuns...)
+	}
+	return runs, nil
+}
+
+// sectionRuns expands a lesson's section into one run, or one per entry of its runs list
+func sectionRuns(name string, section *yaml.Node, global []string) ([]configRun, error) {
+	if section.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected flags")
+	}
+
+	base := append([]string{}, global...)
+	var runsNode *yaml.Node
+	for i := 0; i < len(section.Content); i += 2 {
+		if section.Content[i].Value == "runs" {
+			runsNode = section.Content[i+1]
+			continue
+		}
+		arg, err := flagArg(section.Content[i], section.Content[i+1])
+		if err != nil {
+			return nil, err
+		}
+		base = append(base, arg)
+	}
+
+	if runsNode == nil {
+		return []configRun{{lesson: name, args: base}}, nil
+	}
+	if runsNode.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("runs must be a list of flags for each run")
+	}
+	runs := []configRun{}
+	for i, run := range runsNode.Content {
+		args, err := flagArgs(run)
+		if err != nil {
+			return nil, fmt.Errorf("run %d: %w", i+1, err)
+		}
+		runs = append(runs, configRun{lesson: name, args: append(append([]string{}, base...), args...)})
+	}
+	return runs, nil
+}
+
+// flagArgs turns a mapping of flag names to values into arguments
+func flagArgs(node *yaml.Node) ([]string, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected flags, found %q", node.Value)
+	}
+	args := []string{}
+	for i := 0; i < len(node.Content); i += 2 {
+		arg, err := flagArg(node.Content[i], node.Content[i+1])
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+func flagArg(key, value *yaml.Node) (string, error) {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return fmt.Sprintf("-%s=%s", key.Value, value.Value), nil
+	case yaml.SequenceNode:
+		items := []string{}
+		for _, item := range value.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("%s: a list of flag values can't be nested", key.Value)
+			}
+			items = append(items, item.Value)
+		}
+		return fmt.Sprintf("-%s=%s", key.Value, strings.Join(items, ",")), nil
+	default:
+		return "", fmt.Errorf("%s: expected a value or a list of values", key.Value)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.yaml")
+	config := `
+global:
+  seed: 42
+counters:
+  routines: 8
+  counters: [mutex, cas]
+  runs:
+    - procs: 1
+    - procs: 4
+skiplist:
+  elements: 1000
+`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := readConfig(path, "")
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	want := []configRun{
+		{"counters", []string{"-seed=42", "-routines=8", "-counters=mutex,cas", "-procs=1"}},
+		{"counters", []string{"-seed=42", "-routines=8", "-counters=mutex,cas", "-procs=4"}},
+		{"skiplist", []string{"-seed=42", "-elements=1000"}},
+	}
+	if !reflect.DeepEqual(runs, want) {
+		t.Errorf("runs are %v, want %v", runs, want)
+	}
+
+	runs, err = readConfig(path, "skiplist")
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if !reflect.DeepEqual(runs, want[2:]) {
+		t.Errorf("only the skiplist runs are %v, want %v", runs, want[2:])
+	}
+}
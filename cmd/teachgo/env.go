@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+)
+
+// printEnv lists the machine details every lesson records alongside its results
+func printEnv(w io.Writer, env bench.Env) {
+	fmt.Fprintf(w, "%-12s %s\n", "OS", env.GOOS)
+	fmt.Fprintf(w, "%-12s %s\n", "Arch", env.GOARCH)
+	fmt.Fprintf(w, "%-12s %s\n", "CPU", env.CPU)
+	fmt.Fprintf(w, "%-12s %d\n", "Cores", env.NumCPU)
+	fmt.Fprintf(w, "%-12s %d\n", "GOMAXPROCS", env.GOMAXPROCS)
+	fmt.Fprintf(w, "%-12s %s\n", "Go", env.GoVersion)
+}
+
+// runEnv prints the machine teachgo is running on, the details to share along with any numbers it measured
+func runEnv(args []string) {
+	fs := flag.NewFlagSet("teachgo env", flag.ExitOnError)
+	format := fs.String("format", "text", "the output format, text or json, json matches the env of a lesson's JSON result")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: teachgo env [flags]")
+		fmt.Fprintln(fs.Output(), "\nPrints the machine details every lesson records with its results\n\nFlags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 0 || (*format != "text" && *format != "json") {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	env := bench.CaptureEnv()
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(env); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	printEnv(os.Stdout, env)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/exercise"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+	"github.com/joshdurbin/teaching-go/internal/progress"
+)
+
+// curriculumExercises returns every exercise in the order of the lessons they follow on from
+func curriculumExercises() []exercise.Exercise {
+	exercises := []exercise.Exercise{}
+	for _, l := range lesson.All() {
+		exercises = append(exercises, exercise.ForLesson(l.Name)...)
+	}
+	return exercises
+}
+
+// listExercises prints every exercise with the lesson it follows on from
+func listExercises(w io.Writer) {
+	width := nameWidth()
+	fmt.Fprintf(w, "%-18s %-*s %s\n", "Exercise", width, "Lesson", "Summary")
+	for _, e := range curriculumExercises() {
+		fmt.Fprintf(w, "%-18s %-*s %s\n", e.Name, width, e.Lesson, e.Summary())
+	}
+	fmt.Fprintln(w, "\nRun teachgo exercises <exercise> for its instructions")
+}
+
+// showExercise prints an exercise's instructions and where to start
+func showExercise(w io.Writer, e exercise.Exercise) {
+	fmt.Fprintf(w, "%s, following on from teachgo %s\n\n%s\n\nEdit %s, then run teachgo verify %s to check your work\n",
+		e.Name, e.Lesson, e.Instructions, e.Stub, e.Name)
+}
+
+// findExercise looks an exercise up, exiting with a pointer to the list when there is no such exercise
+func findExercise(name string) exercise.Exercise {
+	e, ok := exercise.Find(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown exercise %q, run teachgo exercises to see them all\n", name)
+		os.Exit(2)
+	}
+	return e
+}
+
+// runVerify runs the checks of the named exercises, or every exercise, exiting with status 1 unless they all pass
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("teachgo verify", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 10*time.Second, "how long each check may run before it is reported as stuck")
+	student := fs.String("student", progress.DefaultStudent(), "the student to record the results for, $"+progress.StudentEnv+" or your user name by default")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: teachgo verify [flags] <exercise>... | all")
+		fmt.Fprintln(fs.Output(), "\nRuns the checks of your implementation of each exercise\n\nFlags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	exercises := []exercise.Exercise{}
+	if fs.NArg() == 1 && fs.Arg(0) == "all" {
+		exercises = curriculumExercises()
+	} else {
+		for _, name := range fs.Args() {
+			exercises = append(exercises, findExercise(name))
+		}
+	}
+
+	if !verify(os.Stdout, *student, exercises, *timeout) {
+		os.Exit(1)
+	}
+}
+
+// verify prints each check's outcome, records how many of each exercise's checks passed in the student's progress, and
+// reports whether every one passed
+func verify(w io.Writer, student string, exercises []exercise.Exercise, timeout time.Duration) bool {
+	passed := true
+	for _, e := range exercises {
+		fmt.Fprintf(w, "%s (%s)\n", e.Name, e.Stub)
+		counts := map[exercise.Outcome]int{}
+		for _, result := range exercise.Verify(e, timeout) {
+			counts[result.Outcome]++
+			fmt.Fprintf(w, "  %-7s %s\n", result.Outcome, result.Check)
+			if result.Err != nil {
+				fmt.Fprintf(w, "          %v\n", result.Err)
+			}
+		}
+
+		// a stub nobody has touched yet isn't an attempt
+		if counts[exercise.NotImplemented] < len(e.Checks) {
+			recordProgress(student, func(s *progress.Student) {
+				s.RecordExercise(e.Name, counts[exercise.Passed], len(e.Checks), time.Now())
+			})
+		}
+
+		switch {
+		case counts[exercise.Passed] == len(e.Checks):
+			fmt.Fprintf(w, "  all %d checks passed\n\n", len(e.Checks))
+		case counts[exercise.NotImplemented] == len(e.Checks):
+			passed = false
+			fmt.Fprintf(w, "  not started, run teachgo exercises %s for the instructions\n\n", e.Name)
+		default:
+			passed = false
+			fmt.Fprintf(w, "  %d of %d checks passed\n\n", counts[exercise.Passed], len(e.Checks))
+		}
+	}
+	return passed
+}
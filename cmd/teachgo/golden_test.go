@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/joshdurbin/teaching-go/internal/progress"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files under testdata/golden with the current output")
+
+// goldenChildEnv marks the test binary as standing in for teachgo, run by the golden tests as a child process so a
+// lesson's os.Exit and its writes to stdout stay out of the test's own process
+const goldenChildEnv = "TEACHGO_GOLDEN_CHILD"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(goldenChildEnv) == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// goldenRuns are small, seeded runs of every lesson, the counters picked are ones whose output doesn't depend on how
+// the scheduler interleaves the routines, so only the timings vary from one run to the next
+var goldenRuns = []struct {
+	name string
+	args []string
+}{
+	{"list", []string{"list"}},
+	{"counters", []string{"counters", "-seed", "1", "-routines", "4", "-loops", "1000", "-quiet", "-counters", "Mutex,AtomicInt,Sharded,Local"}},
+	{"counters-keys", []string{"counters", "-seed", "1", "-routines", "4", "-loops", "500", "-keys", "50", "-key-dist", "zipfian"}},
+	{"skiplist", []string{"skiplist", "-seed", "1", "-elements", "2000", "-searches", "200"}},
+	{"hashtable", []string{"hashtable", "-seed", "1", "-keys", "5000", "-lookups", "1000"}},
+	{"lru", []string{"lru", "-seed", "1", "-capacity", "100", "-keys", "1000", "-ops", "20000"}},
+	{"bloom", []string{"bloom", "-seed", "1", "-keys", "5000", "-queries", "20000"}},
+	{"heap", []string{"heap", "-seed", "1", "-items", "2000", "-stream", "20000", "-k", "5"}},
+	{"trie", []string{"trie", "-seed", "1", "-generate", "2000", "-queries", "500"}},
+	{"ring", []string{"ring", "-items", "10000", "-capacity", "8", "-modes", "blocking"}},
+	{"unionfind", []string{"unionfind", "-seed", "1", "-elements", "5000", "-ops", "10000", "-chain", "2000"}},
+	{"graph", []string{"graph", "-seed", "1", "-nodes", "2000", "-degree", "4"}},
+	{"graph-maze", []string{"graph", "-seed", "1", "-maze", "-maze-width", "16", "-maze-height", "6"}},
+	{"sort", []string{"sort", "-seed", "1", "-sizes", "100,1000"}},
+	{"hashring", []string{"hashring", "-seed", "1", "-keys", "20000"}},
+	{"countmin", []string{"countmin", "-seed", "1", "-stream", "50000", "-universe", "10000", "-k", "5"}},
+	{"hll", []string{"hll", "-seed", "1", "-stream", "200000", "-distinct", "50000", "-precision", "10"}},
+	{"merkle", []string{"merkle", "-seed", "1", "-size", "1", "-chunk", "1024", "-proofs", "100"}},
+	{"rangetree", []string{"rangetree", "-seed", "1", "-elements", "5000", "-intervals", "2000", "-max-length", "100", "-queries", "500"}},
+	{"persistent", []string{"persistent", "-seed", "1", "-keys", "2000", "-versions", "100", "-lookups", "10000", "-accounts", "100", "-sums", "20", "-transfers", "1000"}},
+	{"alloc", []string{"alloc", "-seed", "1", "-nodes", "2000", "-rounds", "5", "-gogc", "-1"}},
+	{"cuckoo", []string{"cuckoo", "-seed", "1", "-keys", "3500", "-queries", "20000"}},
+	{"extsort", []string{"extsort", "-seed", "1", "-size", "2", "-memory", "1"}},
+	{"bitset", []string{"bitset", "-seed", "1", "-universe", "20000", "-queries", "10000"}},
+	{"strsearch", []string{"strsearch", "-seed", "1", "-size", "20000", "-length", "16"}},
+	{"workerpool", []string{"workerpool", "-seed", "1", "-jobs", "100"}},
+	{"pipeline", []string{"pipeline", "-items", "200", "-fan-out", "1,8"}},
+	{"ratelimit", []string{"ratelimit", "-seed", "1", "-rate", "100", "-burst", "10", "-duration", "10s"}},
+	{"breaker", []string{"breaker", "-seed", "1", "-duration", "30s"}},
+	{"pubsub", []string{"pubsub", "-messages", "100", "-subscribers", "1,100"}},
+	{"boundedbuffer", []string{"boundedbuffer", "-items", "10000", "-shapes", "1x1,4x4", "-slow-items", "200"}},
+	{"lockfree", []string{"lockfree", "-ops", "100000", "-goroutines", "1,4", "-stress-values", "2000"}},
+	{"workstealing", []string{"workstealing", "-seed", "1", "-workers", "1", "-fib", "20", "-fib-cutoff", "10", "-sort", "20000", "-sort-cutoff", "512"}},
+	{"futures", []string{"futures", "-seed", "1", "-pages", "20", "-latency", "2ms", "-timeout", "20ms", "-slow", "0.15"}},
+	{"singleflight", []string{"singleflight", "-requests", "40", "-keys", "2", "-rounds", "2", "-latency", "5ms", "-capacity", "10"}},
+	{"leaks", []string{"leaks", "-calls", "20"}},
+	{"memorymodel", []string{"memorymodel", "-procs", "1", "-iterations", "2000", "-rounds", "200"}},
+	{"skiplist-sweep", []string{"skiplist", "-seed", "1", "-sweep", "-sweep-min", "1000", "-sweep-max", "5000", "-searches", "100"}},
+}
+
+// skipUnderRace are the golden runs that can't pass under the race detector, and why, the child process is the test
+// binary, built with -race as well
+var skipUnderRace = map[string]string{
+	"alloc":       "the detector has sync.Pool drop items at random, so the pool's allocations per node vary",
+	"memorymodel": "races on purpose, the detector reports it and the child exits with status 66",
+}
+
+var (
+	machineLine = regexp.MustCompile(`(?m)^Machine: .*$`)
+	rate        = regexp.MustCompile(`\b\d+(\.\d+)?[KMG]?/s\b`)
+	duration    = regexp.MustCompile(`\b(\d+h)?(\d+m)?\d+(\.\d+)?(ns|µs|ms|s)\b`)
+	ratio       = regexp.MustCompile(`\b\d+\.\d+x\b`)
+	padding     = regexp.MustCompile(` {2,}`)
+)
+
+// normalize replaces everything that changes from run to run, the machine, timings, rates and speedups, and the
+// padding around them, which widens and narrows with the timings
+func normalize(output string) string {
+	output = machineLine.ReplaceAllString(output, "Machine: <machine>")
+	output = rate.ReplaceAllString(output, "<rate>")
+	output = duration.ReplaceAllString(output, "<duration>")
+	output = ratio.ReplaceAllString(output, "<ratio>")
+	return padding.ReplaceAllString(output, " ")
+}
+
+// TestGolden runs each lesson with a fixed seed and compares its output against testdata/golden, so a refactor
+// can't quietly change what a lesson prints, go test ./cmd/teachgo -run Golden -update rewrites the files after a
+// change that was meant
+func TestGolden(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs every lesson, skipped with -short")
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, run := range goldenRuns {
+		t.Run(run.name, func(t *testing.T) {
+			if reason, ok := skipUnderRace[run.name]; ok && raceEnabled {
+				t.Skip(reason)
+			}
+			cmd := exec.Command(executable, run.args...)
+			cmd.Env = append(os.Environ(), goldenChildEnv+"=1", progress.PathEnv+"="+filepath.Join(t.TempDir(), "progress.json"))
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout, cmd.Stderr = &stdout, &stderr
+			if err := cmd.Run(); err != nil {
+				t.Fatalf("teachgo %s: %v\n%s", strings.Join(run.args, " "), err, stderr.String())
+			}
+			got := normalize(stdout.String())
+
+			path := filepath.Join("testdata", "golden", run.name+".golden")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v, run go test -run Golden -update to create it", err)
+			}
+			if got != string(want) {
+				t.Errorf("teachgo %s no longer matches %s:\n%s", strings.Join(run.args, " "), path, lineDiff(string(want), got))
+			}
+		})
+	}
+}
+
+// lineDiff lists the lines that differ between the golden file and the output, enough to see what changed without
+// a diff library
+func lineDiff(want, got string) string {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var diff strings.Builder
+	for i := range max(len(wantLines), len(gotLines)) {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			fmt.Fprintf(&diff, "line %d:\n  want: %s\n  got:  %s\n", i+1, w, g)
+		}
+	}
+	return diff.String()
+}
@@ -0,0 +1,262 @@
+// Command teachgo runs every lesson in the repository from one binary, and the tools around them, browsing the
+// curriculum, comparing and charting results, checking exercises, quizzes and a student's progress
+//
+// Usage:
+//
+//	teachgo [global flags] <lesson> [flags]       run a lesson, -h lists its flags
+//	teachgo list                                  browse the curriculum
+//	teachgo describe <lesson>                     explain a lesson
+//	teachgo compare baseline.json result.json...  set the JSON results of runs side by side
+//	teachgo plot result.json...                   chart the JSON results of runs
+//	teachgo exercises [exercise]                  list the exercises
+//	teachgo verify <exercise>... | all            check a student's solutions to them
+//	teachgo quiz [lesson]                         ask questions about the lesson just run
+//	teachgo progress                              show how far through the course a student is
+//	teachgo env                                   print the machine details recorded with every result
+//	teachgo serve [-addr localhost:8080]          run the lessons from a browser
+//	teachgo -config run.yaml [lesson]             run the lessons with the flags in a YAML file
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/exercise"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+
+	// every lesson registers itself with the curriculum when its package is imported
+	_ "github.com/joshdurbin/teaching-go/allocation"
+	_ "github.com/joshdurbin/teaching-go/binary_heap"
+	_ "github.com/joshdurbin/teaching-go/bitset"
+	_ "github.com/joshdurbin/teaching-go/bloom_filter"
+	_ "github.com/joshdurbin/teaching-go/bounded_buffer"
+	_ "github.com/joshdurbin/teaching-go/circuit_breaker"
+	_ "github.com/joshdurbin/teaching-go/concurrency_matters"
+	_ "github.com/joshdurbin/teaching-go/consistent_hashing"
+	_ "github.com/joshdurbin/teaching-go/count_min_sketch"
+	_ "github.com/joshdurbin/teaching-go/cuckoo_filter"
+	_ "github.com/joshdurbin/teaching-go/external_sort"
+	_ "github.com/joshdurbin/teaching-go/futures"
+	_ "github.com/joshdurbin/teaching-go/goroutine_leaks"
+	_ "github.com/joshdurbin/teaching-go/graphs"
+	_ "github.com/joshdurbin/teaching-go/hash_tables"
+	_ "github.com/joshdurbin/teaching-go/hyperloglog"
+	_ "github.com/joshdurbin/teaching-go/lock_free"
+	_ "github.com/joshdurbin/teaching-go/lru_cache"
+	_ "github.com/joshdurbin/teaching-go/memory_model"
+	_ "github.com/joshdurbin/teaching-go/merkle_tree"
+	_ "github.com/joshdurbin/teaching-go/persistent"
+	_ "github.com/joshdurbin/teaching-go/pipeline"
+	_ "github.com/joshdurbin/teaching-go/pub_sub"
+	_ "github.com/joshdurbin/teaching-go/range_trees"
+	_ "github.com/joshdurbin/teaching-go/rate_limiter"
+	_ "github.com/joshdurbin/teaching-go/ring_buffer"
+	_ "github.com/joshdurbin/teaching-go/single_flight"
+	_ "github.com/joshdurbin/teaching-go/skip_lists"
+	_ "github.com/joshdurbin/teaching-go/sorting"
+	_ "github.com/joshdurbin/teaching-go/string_search"
+	_ "github.com/joshdurbin/teaching-go/tries"
+	_ "github.com/joshdurbin/teaching-go/union_find"
+	_ "github.com/joshdurbin/teaching-go/web_ui"
+	_ "github.com/joshdurbin/teaching-go/work_stealing"
+	_ "github.com/joshdurbin/teaching-go/worker_pool"
+)
+
+func usage(fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprintln(w, "Usage: teachgo [global flags] <lesson> [flags]")
+	fmt.Fprintln(w, "       teachgo list")
+	fmt.Fprintln(w, "       teachgo describe <lesson>")
+	fmt.Fprintln(w, "       teachgo compare baseline.json result.json...")
+	fmt.Fprintln(w, "       teachgo plot result.json...")
+	fmt.Fprintln(w, "       teachgo exercises [exercise]")
+	fmt.Fprintln(w, "       teachgo verify <exercise>... | all")
+	fmt.Fprintln(w, "       teachgo quiz [lesson]")
+	fmt.Fprintln(w, "       teachgo progress")
+	fmt.Fprintln(w, "       teachgo env")
+	fmt.Fprintln(w, "       teachgo serve [-addr localhost:8080]")
+	fmt.Fprintln(w, "       teachgo -config run.yaml [lesson]")
+	fmt.Fprintln(w, "\nLessons:")
+	width := nameWidth()
+	for _, l := range lesson.All() {
+		fmt.Fprintf(w, "  %-*s %s\n", width, l.Name, l.Summary())
+	}
+	fmt.Fprintln(w, "\nRun teachgo <lesson> -h for a lesson's description and flags")
+	fmt.Fprintln(w, "\nGlobal flags, shared by every lesson:")
+	fs.PrintDefaults()
+}
+
+// nameWidth is the width of a column of lesson names, wide enough for the longest registered one
+func nameWidth() int {
+	width := len("Lesson")
+	for _, l := range lesson.All() {
+		width = max(width, len(l.Name))
+	}
+	return width
+}
+
+// list prints the curriculum in the order a student would work through it
+func list(w io.Writer) {
+	width := nameWidth()
+	fmt.Fprintf(w, "%-*s %-13s %s\n", width, "Lesson", "Difficulty", "Summary")
+	for _, l := range lesson.All() {
+		fmt.Fprintf(w, "%-*s %-13s %s\n", width, l.Name, l.Difficulty, l.Summary())
+		fmt.Fprintf(w, "%-*s %-13s topics: %s\n", width, "", "", strings.Join(l.Topics, ", "))
+	}
+}
+
+// describe prints everything the curriculum knows about one lesson, along with the exercises following on from it
+func describe(w io.Writer, l lesson.Lesson) {
+	fmt.Fprintf(w, "%s, %s\n\n%s\n\nTopics: %s\n\nRun teachgo %s -h for its flags\n",
+		l.Name, l.Difficulty, l.Description, strings.Join(l.Topics, ", "), l.Name)
+	if exercises := exercise.ForLesson(l.Name); len(exercises) > 0 {
+		fmt.Fprintln(w, "\nExercises:")
+		for _, e := range exercises {
+			fmt.Fprintf(w, "  %-18s %s\n", e.Name, e.Summary())
+		}
+	}
+}
+
+// findLesson looks a lesson up, exiting with a pointer to the list when there is no such lesson
+func findLesson(name string) lesson.Lesson {
+	l, ok := lesson.Find(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown lesson %q, run teachgo list to see them all\n", name)
+		os.Exit(2)
+	}
+	return l
+}
+
+// runConfig runs each lesson run a config file describes in turn, the command line's flags applied over the file's
+// Every run is a child process of this same binary, as teachgo serve runs them, so nothing one lesson changes in the
+// process, its GOMAXPROCS or the metrics it publishes, carries over into the next, and one that exits can't stop the rest
+func runConfig(path, only string, args []string) {
+	runs, err := readConfig(path, only)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read config: %v\n", err)
+		os.Exit(2)
+	}
+	if len(runs) == 0 {
+		fmt.Fprintf(os.Stderr, "%s has no lessons to run\n", path)
+		os.Exit(2)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to find the teachgo binary to run lessons with: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Ctrl+C is for the run in progress, it ends one still serving metrics, so the config carries on with the next
+	// rather than stopping here, the signal only being caught in this process so each child still sees it
+	signal.Notify(make(chan os.Signal, 1), os.Interrupt)
+
+	failed := 0
+	for i, run := range runs {
+		l := findLesson(run.lesson)
+		runArgs := append(run.args, args...)
+		if len(runs) > 1 {
+			fmt.Fprintf(os.Stderr, "\n=== Run %d of %d: teachgo %s %s ===\n", i+1, len(runs), l.Name, strings.Join(runArgs, " "))
+		}
+
+		cmd := exec.Command(executable, append([]string{l.Name}, runArgs...)...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "run %d, teachgo %s, failed: %v\n", i+1, l.Name, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// isHelpFlag reports whether an argument asks for help, as the flag package understands it
+func isHelpFlag(arg string) bool {
+	switch strings.TrimLeft(arg, "-") {
+	case "h", "help":
+		return strings.HasPrefix(arg, "-")
+	}
+	return false
+}
+
+func main() {
+	// the global flags are parsed here only to validate them and print help, the lesson registers the same flags and
+	// is handed them ahead of its own arguments, where a flag given again after the lesson's name wins
+	fs := flag.NewFlagSet("teachgo", flag.ExitOnError)
+	bench.RegisterGlobals(fs)
+	configPath := fs.String("config", "", "read flags for the lessons from this YAML file, e.g. run.yaml, and run every lesson in it, or just the one named")
+	fs.Usage = func() { usage(fs) }
+	fs.Parse(os.Args[1:])
+
+	globalArgs := []string{}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name != "config" {
+			globalArgs = append(globalArgs, fmt.Sprintf("-%s=%s", f.Name, f.Value))
+		}
+	})
+
+	if *configPath != "" {
+		runConfig(*configPath, fs.Arg(0), append(globalArgs, fs.Args()[min(fs.NArg(), 1):]...))
+		return
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	name, args := fs.Arg(0), fs.Args()[1:]
+
+	switch name {
+	case "help":
+		if len(args) == 0 {
+			fs.SetOutput(os.Stdout)
+			fs.Usage()
+			return
+		}
+		findLesson(args[0]).Main([]string{"-h"})
+	case "list":
+		list(os.Stdout)
+	case "describe":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: teachgo describe <lesson>")
+			os.Exit(2)
+		}
+		describe(os.Stdout, findLesson(args[0]))
+	case "compare":
+		runCompare(args)
+	case "plot":
+		runPlot(args)
+	case "exercises":
+		switch len(args) {
+		case 0:
+			listExercises(os.Stdout)
+		case 1:
+			showExercise(os.Stdout, findExercise(args[0]))
+		default:
+			fmt.Fprintln(os.Stderr, "Usage: teachgo exercises [exercise]")
+			os.Exit(2)
+		}
+	case "verify":
+		runVerify(args)
+	case "quiz":
+		runQuiz(args)
+	case "progress":
+		runProgress(args)
+	case "env":
+		runEnv(args)
+	default:
+		l := findLesson(name)
+		// asking for a lesson's flags isn't running it
+		if !slices.ContainsFunc(args, isHelpFlag) {
+			recordLessonRun(l.Name)
+		}
+		l.Main(append(globalArgs, args...))
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/joshdurbin/teaching-go/internal/plot"
+)
+
+// plotDefaults are the axes plotted when none are given, chosen by the lesson the results came from
+var plotDefaults = map[string]struct{ x, y string }{
+	"counters": {"routines", "throughput_ops_per_sec"},
+	"skiplist": {"elements", "linked_list_search_ns,skip_list_search_ns"},
+}
+
+// resultLesson guesses which lesson wrote a result by its metrics
+func resultLesson(r resultFile) string {
+	if slices.ContainsFunc(r.order, func(metric string) bool { return strings.HasPrefix(metric, "counters/") }) {
+		return "counters"
+	}
+	return "skiplist"
+}
+
+// configValue is a numeric setting of the run a result came from
+func configValue(r resultFile, name string) (float64, bool) {
+	config, ok := r.config.(map[string]any)
+	if !ok {
+		return 0, false
+	}
+	v, ok := config[name].(float64)
+	return v, ok
+}
+
+// readPlotResults reads a result file, a sweep's file, like teachgo skiplist -sweep -format json writes, holds a list
+// of points, each becoming a result of its own with its fields as the settings to plot along x
+func readPlotResults(path string) ([]resultFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sweep struct {
+		Points []map[string]any `json:"points"`
+	}
+	if err := json.Unmarshal(data, &sweep); err != nil || len(sweep.Points) == 0 {
+		result, err := readResult(path)
+		return []resultFile{result}, err
+	}
+
+	results := []resultFile{}
+	for i, point := range sweep.Points {
+		result := resultFile{path: fmt.Sprintf("%s point %d", path, i+1), config: point, metrics: map[string]float64{}}
+		result.flatten("", point)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// plotSeries gathers a series for each metric matching one of the names, across every result, with the x of each
+// point read from the result's config, e.g. counters/Mutex/throughput_ops_per_sec of each result becomes a point of
+// the Mutex series
+func plotSeries(results []resultFile, x string, ys []string) ([]plot.Series, error) {
+	series := []plot.Series{}
+	index := map[string]int{}
+	for _, r := range results {
+		xv, ok := configValue(r, x)
+		if !ok {
+			return nil, fmt.Errorf("%s has no numeric setting %q to plot along x", r.path, x)
+		}
+		for _, metric := range r.order {
+			for _, y := range ys {
+				prefix, found := strings.CutSuffix(metric, y)
+				if !found || (prefix != "" && !strings.HasSuffix(prefix, "/")) {
+					continue
+				}
+				name := strings.TrimSuffix(strings.TrimPrefix(prefix, "counters/"), "/")
+				switch {
+				case name == "":
+					name = y
+				case len(ys) > 1:
+					name += " " + y
+				}
+				i, ok := index[name]
+				if !ok {
+					i = len(series)
+					index[name] = i
+					series = append(series, plot.Series{Name: name})
+				}
+				series[i].X = append(series[i].X, xv)
+				series[i].Y = append(series[i].Y, r.metrics[metric])
+			}
+		}
+	}
+	if len(series) == 0 {
+		return nil, fmt.Errorf("no metric in the results is named %s", strings.Join(ys, " or "))
+	}
+	return series, nil
+}
+
+// runPlot draws lesson results written with -format json as an SVG chart, a line per counter or measurement across
+// the runs, or bars for a single run
+func runPlot(args []string) {
+	fs := flag.NewFlagSet("teachgo plot", flag.ExitOnError)
+	x := fs.String("x", "", "the setting of each run to plot along x, e.g. routines or elements, by default the one the lesson is usually swept over")
+	y := fs.String("y", "", "comma separated metrics to plot, e.g. p99_ns, by default throughput for counters and search times for skiplist")
+	out := fs.String("o", "plot.svg", "the SVG file to write")
+	title := fs.String("title", "", "the chart's title, by default the metrics against the setting")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: teachgo plot [flags] result.json...")
+		fmt.Fprintln(fs.Output(), "\nPlots lesson results written with -format json, run a lesson at several settings first, e.g.")
+		fmt.Fprintln(fs.Output(), "  for n in 1 2 4 8 16; do teachgo counters -routines $n -format json > routines-$n.json; done")
+		fmt.Fprintln(fs.Output(), "  teachgo plot routines-*.json\n\nFlags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	results := []resultFile{}
+	for _, path := range fs.Args() {
+		fileResults, err := readPlotResults(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		results = append(results, fileResults...)
+	}
+
+	defaults := plotDefaults[resultLesson(results[0])]
+	*x = cmp.Or(*x, defaults.x)
+	ys := strings.Split(cmp.Or(*y, defaults.y), ",")
+	series, err := plotSeries(results, *x, ys)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	chart := plot.Chart{
+		Title:   cmp.Or(*title, fmt.Sprintf("%s against %s", strings.Join(ys, ", "), *x)),
+		XLabel:  *x,
+		YLabel:  strings.Join(ys, ", "),
+		Series:  series,
+		FormatY: func(v float64) string { return formatMetric(ys[0], v) },
+	}
+	if err := plot.WriteFile(*out, chart); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write the chart: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s, %d series over %d results\n", *out, len(series), len(results))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/exercise"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+	"github.com/joshdurbin/teaching-go/internal/progress"
+)
+
+// recordProgress applies fn to the student's progress, progress is a nicety so failing to save it never stops a
+// lesson or a verification
+func recordProgress(student string, fn func(*progress.Student)) {
+	if err := progress.Update(student, fn); err != nil {
+		slog.Debug("failed to record progress", "err", err)
+	}
+}
+
+// recordLessonRun counts a run of the lesson and remembers it as the student's latest, for teachgo quiz to ask about
+func recordLessonRun(name string) {
+	recordProgress(progress.DefaultStudent(), func(s *progress.Student) { s.RecordLesson(name, time.Now()) })
+}
+
+// lessonStatus is where a student is with a lesson, complete once it has been run, its quiz passed and every one of
+// its exercises solved, next is the command that moves them on when it isn't
+func lessonStatus(s *progress.Student, l lesson.Lesson) (status, next string) {
+	if s.Lessons[l.Name].Runs == 0 {
+		return "not started", "teachgo " + l.Name
+	}
+	if len(l.Quiz) > 0 && !s.PassedQuiz(l.Name) {
+		return "in progress", "teachgo quiz " + l.Name
+	}
+	for _, e := range exercise.ForLesson(l.Name) {
+		if !s.Exercises[e.Name].Passed {
+			return "in progress", "teachgo exercises " + e.Name
+		}
+	}
+	return "complete", ""
+}
+
+// printProgress prints where the student is with each lesson in the order of the curriculum, and what to do next
+func printProgress(w io.Writer, name string, s *progress.Student) {
+	width := nameWidth()
+	fmt.Fprintf(w, "%-*s %-12s %5s  %-10s %s\n", width, "Lesson", "Status", "Runs", "Quiz", "Exercises")
+	complete, next := 0, ""
+	lessons := lesson.All()
+	for _, l := range lessons {
+		status, lessonNext := lessonStatus(s, l)
+		if status == "complete" {
+			complete++
+		} else if next == "" {
+			next = lessonNext
+		}
+
+		quiz := "-"
+		if result, ok := s.Quizzes[l.Name]; ok {
+			quiz = fmt.Sprintf("%d/%d best", result.Best, result.Total)
+		}
+		exercises := exercise.ForLesson(l.Name)
+		passed := 0
+		for _, e := range exercises {
+			if s.Exercises[e.Name].Passed {
+				passed++
+			}
+		}
+		fmt.Fprintf(w, "%-*s %-12s %5d  %-10s %d/%d solved\n", width, l.Name, status, s.Lessons[l.Name].Runs, quiz, passed, len(exercises))
+	}
+
+	fmt.Fprintf(w, "\n%s has completed %d of %d lessons\n", name, complete, len(lessons))
+	if next != "" {
+		fmt.Fprintf(w, "Next: %s\n", next)
+	} else {
+		fmt.Fprintln(w, "Every lesson is complete")
+	}
+}
+
+// runProgress prints a student's progress through the course
+func runProgress(args []string) {
+	fs := flag.NewFlagSet("teachgo progress", flag.ExitOnError)
+	student := fs.String("student", progress.DefaultStudent(), "the student to show the progress of, $"+progress.StudentEnv+" or your user name by default")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: teachgo progress [flags]")
+		fmt.Fprintln(fs.Output(), "\nShows which lessons you've run, your quiz scores, the exercises you've solved and what to do next\n\nFlags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	path, err := progress.Path()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	f, err := progress.Load(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read progress: %v\n", err)
+		os.Exit(1)
+	}
+	printProgress(os.Stdout, *student, f.Student(*student))
+	fmt.Printf("\nProgress is kept in %s\n", path)
+}
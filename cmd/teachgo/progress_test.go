@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+	"github.com/joshdurbin/teaching-go/internal/progress"
+)
+
+func TestLessonStatus(t *testing.T) {
+	l := lesson.Lesson{Name: "example", Quiz: []lesson.Question{{Prompt: "?", Choices: []string{"a", "b"}}}}
+	s := &progress.Student{}
+	at := time.Now()
+
+	steps := []struct {
+		do           func()
+		status, next string
+	}{
+		{func() {}, "not started", "teachgo example"},
+		{func() { s.RecordLesson("example", at) }, "in progress", "teachgo quiz example"},
+		{func() { s.RecordQuiz("example", 0, 1, at) }, "in progress", "teachgo quiz example"},
+		{func() { s.RecordQuiz("example", 1, 1, at) }, "complete", ""},
+	}
+	for i, step := range steps {
+		step.do()
+		if status, next := lessonStatus(s, l); status != step.status || next != step.next {
+			t.Errorf("step %d: status %q next %q, want %q and %q", i, status, next, step.status, step.next)
+		}
+	}
+}
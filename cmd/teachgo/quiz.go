@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+	"github.com/joshdurbin/teaching-go/internal/progress"
+)
+
+// errQuizAbandoned is returned when the input ends before every question is answered
+var errQuizAbandoned = errors.New("quiz abandoned before the last question")
+
+// runQuiz asks the quiz of the named lesson, or of the lesson the student ran last, and records the score
+func runQuiz(args []string) {
+	fs := flag.NewFlagSet("teachgo quiz", flag.ExitOnError)
+	student := fs.String("student", progress.DefaultStudent(), "the student to record the score for, $"+progress.StudentEnv+" or your user name by default")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: teachgo quiz [flags] [lesson]")
+		fmt.Fprintln(fs.Output(), "\nAsks questions about a lesson, the one you ran last unless another is named\n\nFlags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	name := fs.Arg(0)
+	if name == "" {
+		if path, err := progress.Path(); err == nil {
+			if f, err := progress.Load(path); err == nil {
+				name = f.Student(*student).LastLesson
+			}
+		}
+	}
+	if name == "" {
+		fmt.Fprintln(os.Stderr, "no lesson run yet to ask about, run one first or name it, teachgo quiz <lesson>")
+		os.Exit(2)
+	}
+	l := findLesson(name)
+	if len(l.Quiz) == 0 {
+		fmt.Fprintf(os.Stderr, "%s has no quiz yet\n", l.Name)
+		os.Exit(2)
+	}
+
+	fmt.Printf("Quiz on the %s lesson, %d questions, answer with the letter of your choice\n", l.Name, len(l.Quiz))
+	correct, err := askQuiz(os.Stdin, os.Stdout, l.Quiz, rand.New(rand.NewSource(time.Now().UnixNano())))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var result progress.QuizResult
+	err = progress.Update(*student, func(s *progress.Student) {
+		s.RecordQuiz(l.Name, correct, len(l.Quiz), time.Now())
+		result = s.Quizzes[l.Name]
+	})
+	fmt.Printf("\nYou scored %d of %d", correct, len(l.Quiz))
+	if err != nil {
+		fmt.Println()
+		fmt.Fprintf(os.Stderr, "failed to record the score: %v\n", err)
+		os.Exit(1)
+	}
+	if result.Attempts > 1 {
+		fmt.Printf(", your best is %d over %d attempts", result.Best, result.Attempts)
+	}
+	fmt.Println()
+}
+
+// askQuiz asks each question in turn, asking again after an answer that isn't one of the choices, and returns the
+// number answered correctly
+// The choices are shuffled by rng every time a question is asked, so the right answer's letter can't be learned
+// from one attempt to the next, or guessed from where the quizzes tend to put it
+func askQuiz(r io.Reader, w io.Writer, questions []lesson.Question, rng *rand.Rand) (int, error) {
+	input := bufio.NewScanner(r)
+	correct := 0
+	for i, q := range questions {
+		fmt.Fprintf(w, "\n%d. %s\n", i+1, q.Prompt)
+		// order[j] is the choice shown as the j-th letter
+		order := rng.Perm(len(q.Choices))
+		right := 0
+		for j, choice := range order {
+			fmt.Fprintf(w, "   %c) %s\n", 'a'+j, q.Choices[choice])
+			if choice == q.Answer {
+				right = j
+			}
+		}
+
+		answer := -1
+		for answer < 0 {
+			fmt.Fprint(w, "> ")
+			if !input.Scan() {
+				return correct, errQuizAbandoned
+			}
+			answer = parseChoice(input.Text(), len(q.Choices))
+			if answer < 0 {
+				fmt.Fprintf(w, "Answer with a letter from a to %c\n", 'a'+len(q.Choices)-1)
+			}
+		}
+
+		if answer == right {
+			correct++
+			fmt.Fprintf(w, "Correct, %s\n", q.Explanation)
+		} else {
+			fmt.Fprintf(w, "Not quite, the answer is %c, %s\n", 'a'+right, q.Explanation)
+		}
+	}
+	return correct, nil
+}
+
+// parseChoice turns an answer, a letter or the choice's number, into the index of the choice, -1 when it isn't one
+func parseChoice(text string, choices int) int {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if len(text) == 1 && text[0] >= 'a' && int(text[0]-'a') < choices {
+		return int(text[0] - 'a')
+	}
+	if n, err := strconv.Atoi(text); err == nil && n >= 1 && n <= choices {
+		return n - 1
+	}
+	return -1
+}
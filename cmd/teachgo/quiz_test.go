@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+func TestAskQuiz(t *testing.T) {
+	questions := []lesson.Question{
+		{Prompt: "first", Choices: []string{"yes", "no"}, Answer: 0},
+		{Prompt: "second", Choices: []string{"yes", "no", "maybe"}, Answer: 2},
+		{Prompt: "third", Choices: []string{"yes", "no"}, Answer: 1},
+	}
+
+	// the letters the right answers are shown at, shuffled the same way askQuiz will with the same seed
+	letters := []string{}
+	rng := rand.New(rand.NewSource(1))
+	for _, q := range questions {
+		letters = append(letters, string(rune('a'+slices.Index(rng.Perm(len(q.Choices)), q.Answer))))
+	}
+	wrong := string(rune('a' + (letters[2][0]-'a'+1)%2))
+
+	// an answer out of range is asked again, letters and numbers both work
+	input := strings.ToUpper(letters[0]) + "\nd\n" + strconv.Itoa(int(letters[1][0]-'a')+1) + "\n" + wrong + "\n"
+	correct, err := askQuiz(strings.NewReader(input), io.Discard, questions, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("quiz failed: %v", err)
+	}
+	if correct != 2 {
+		t.Errorf("scored %d, want 2", correct)
+	}
+
+	if _, err := askQuiz(strings.NewReader("a\n"), io.Discard, questions, rng); !errors.Is(err, errQuizAbandoned) {
+		t.Errorf("running out of answers returned %v, want errQuizAbandoned", err)
+	}
+}
+
+func TestAskQuizShufflesChoices(t *testing.T) {
+	questions := []lesson.Question{{Prompt: "which", Choices: []string{"w", "x", "y", "z"}, Answer: 1}}
+	rng := rand.New(rand.NewSource(1))
+	right := map[int]bool{}
+	for range 50 {
+		// always answering b should only be right when the shuffle happens to put the answer there
+		correct, err := askQuiz(strings.NewReader("b\n"), io.Discard, questions, rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		right[correct] = true
+	}
+	if !right[0] || !right[1] {
+		t.Errorf("always answering b was right %v of the time over 50 asks, want sometimes and sometimes not", right)
+	}
+}
@@ -0,0 +1,184 @@
+package concurrencymatters
+
+import (
+	"context"
+	"sync"
+)
+
+// OperationKind identifies what an Operation asks the actor to do
+type OperationKind int
+
+const (
+	OpIncrement OperationKind = iota
+	OpDecrement
+	OpValue
+	// OpReset is never scheduled by a workload, it only exists as a message for the actor
+	OpReset
+)
+
+// Operation is the single message type the ActorCounter understands, reply is only set for OpValue
+type Operation struct {
+	Kind  OperationKind
+	Value int
+	Reply chan int
+}
+
+// ActorCounter is the canonical actor pattern, one goroutine owns the count and everything else talks to it through a
+// single channel of typed operations
+// Compared to ChannelCounter's separate increment, decrement and value channels, a single channel keeps every request
+// in the order it was sent, so a Value request is only answered after every operation queued ahead of it is applied
+// Like ChannelCounter it only settles once Drain has stopped new operations and the actor has worked through its queue
+type ActorCounter struct {
+	ctx          context.Context
+	ops          chan Operation
+	closeRequest chan struct{}
+	done         chan struct{}
+	count        int
+
+	// senders hold sendMu for reading while they send, as for ChannelCounter, so once closed is set every accepted
+	// operation is already queued for the actor to apply
+	sendMu    sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+}
+
+func CreateAndRunActorCounter(ctx context.Context) *ActorCounter {
+	c := &ActorCounter{
+		ctx:          ctx,
+		ops:          make(chan Operation, 64),
+		closeRequest: make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+func (c *ActorCounter) run() {
+	defer close(c.done)
+
+	ctxDone := c.ctx.Done()
+	for {
+		select {
+		case op := <-c.ops:
+			c.apply(op)
+		case <-c.closeRequest:
+			c.drain()
+			return
+		case <-ctxDone:
+			// cancellation closes the actor the way Drain does, it keeps serving while Close waits out the senders
+			ctxDone = nil
+			go c.Close()
+		}
+	}
+}
+
+func (c *ActorCounter) apply(op Operation) {
+	switch op.Kind {
+	case OpIncrement:
+		c.count += op.Value
+	case OpDecrement:
+		c.count -= op.Value
+	case OpValue:
+		op.Reply <- c.count
+	case OpReset:
+		c.count = 0
+	}
+}
+
+// drain applies everything still queued, only called once no more sends can happen
+func (c *ActorCounter) drain() {
+	for {
+		select {
+		case op := <-c.ops:
+			c.apply(op)
+		default:
+			return
+		}
+	}
+}
+
+// send queues the operation for the actor, once the actor has closed it is applied straight to the count instead, for
+// the reason ChannelCounter's applyLate gives
+func (c *ActorCounter) send(op Operation) {
+	c.sendMu.RLock()
+	if !c.closed {
+		defer c.sendMu.RUnlock()
+		c.ops <- op
+		return
+	}
+	c.sendMu.RUnlock()
+
+	<-c.done
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.apply(op)
+}
+
+func (c *ActorCounter) IncrementBy(value int) {
+	c.send(Operation{Kind: OpIncrement, Value: value})
+}
+
+func (c *ActorCounter) DecrementBy(value int) {
+	c.send(Operation{Kind: OpDecrement, Value: value})
+}
+
+// Value is answered by the actor, by its drain when the request was queued just before it closed, or straight from
+// the count once it has, the reply channel is buffered so none of them wait for the reader
+func (c *ActorCounter) Value() int {
+	reply := make(chan int, 1)
+	c.send(Operation{Kind: OpValue, Reply: reply})
+	return <-reply
+}
+
+func (c *ActorCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+// Reset is just another message, the single channel keeps it in order, so everything sent before it is wiped out and
+// everything sent after counts from zero
+// A closed actor can't take the message, so it is started again from zero the way ChannelCounter's Reset does, Reset
+// must not run alongside Close or Drain
+func (c *ActorCounter) Reset() {
+	c.sendMu.RLock()
+	closed := c.closed
+	c.sendMu.RUnlock()
+	if !closed {
+		c.send(Operation{Kind: OpReset})
+		return
+	}
+	<-c.done
+
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.count = 0
+	c.closed = false
+	c.closeOnce = sync.Once{}
+	c.closeRequest = make(chan struct{})
+	c.done = make(chan struct{})
+	go c.run()
+}
+
+// Close stops the actor accepting new operations, applies everything already queued and stops it
+// It is safe to call more than once, later calls just wait for the first to finish
+func (c *ActorCounter) Close() {
+	c.closeOnce.Do(func() {
+		c.sendMu.Lock()
+		c.closed = true
+		c.sendMu.Unlock()
+
+		select {
+		case c.closeRequest <- struct{}{}:
+		case <-c.done:
+		}
+	})
+	<-c.done
+}
+
+// Drain closes the actor and returns the settled value with every accepted operation applied
+func (c *ActorCounter) Drain() int {
+	c.Close()
+
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+	return c.count
+}
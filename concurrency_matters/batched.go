@@ -0,0 +1,246 @@
+package concurrencymatters
+
+import (
+	"context"
+	"sync"
+)
+
+// BatchedChannelCounter is the channel and worker design with the senders batching their operations
+// Each goroutine collects operations in its own buffer and only sends once it holds batchSize of them, so the cost of
+// a channel send, and the worker's wake up to receive it, is shared across the whole batch rather than paid per operation
+// Batches still queued when the workload ends are applied by Drain, just as ChannelCounter applies its buffers
+type BatchedChannelCounter struct {
+	ctx            context.Context
+	batches        chan []int
+	valueRetrieval chan chan int
+	resets         chan chan struct{}
+	closeRequest   chan struct{}
+	done           chan struct{}
+	batchSize      int
+	count          int
+
+	// senders hold sendMu for reading while they send, as for ChannelCounter, so once closed is set every accepted
+	// batch is already queued for the worker to apply
+	sendMu    sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+}
+
+// channelBatcher is the per-goroutine view of a BatchedChannelCounter holding the not yet sent operations
+type channelBatcher struct {
+	parent  *BatchedChannelCounter
+	pending []int
+}
+
+func CreateAndRunBatchedChannelCounter(ctx context.Context, batchSize int) *BatchedChannelCounter {
+	c := &BatchedChannelCounter{
+		ctx:            ctx,
+		batches:        make(chan []int, 64),
+		valueRetrieval: make(chan chan int),
+		resets:         make(chan chan struct{}),
+		closeRequest:   make(chan struct{}),
+		done:           make(chan struct{}),
+		batchSize:      batchSize,
+	}
+	go c.run()
+	return c
+}
+
+func (c *BatchedChannelCounter) run() {
+	defer close(c.done)
+
+	ctxDone := c.ctx.Done()
+	for {
+		select {
+		case batch := <-c.batches:
+			for _, v := range batch {
+				c.count += v
+			}
+		case reply := <-c.valueRetrieval:
+			// select picks between ready cases at random, so apply every batch already queued before answering or a
+			// read straight after a Flush could miss it
+			c.applyQueued()
+			reply <- c.count
+		case done := <-c.resets:
+			c.discardQueued()
+			c.count = 0
+			close(done)
+		case <-c.closeRequest:
+			c.applyQueued()
+			return
+		case <-ctxDone:
+			// cancellation closes the counter the way Drain does, the worker keeps serving while Close waits out the
+			// senders
+			ctxDone = nil
+			go c.Close()
+		}
+	}
+}
+
+func (c *BatchedChannelCounter) applyQueued() {
+	for {
+		select {
+		case batch := <-c.batches:
+			for _, v := range batch {
+				c.count += v
+			}
+		default:
+			return
+		}
+	}
+}
+
+// discardQueued throws away the batches sent before a reset, for the same reason a read applies them first, without
+// this they could be applied after the count was zeroed
+func (c *BatchedChannelCounter) discardQueued() {
+	for {
+		select {
+		case <-c.batches:
+		default:
+			return
+		}
+	}
+}
+
+// send queues the batch for the worker, once the counter has closed it is applied straight to the count instead, for
+// the reason ChannelCounter's applyLate gives, here it isn't even rare, the workers flush their batchers as they stop,
+// which is after cancellation has already closed it
+func (c *BatchedChannelCounter) send(batch []int) {
+	c.sendMu.RLock()
+	if !c.closed {
+		defer c.sendMu.RUnlock()
+		c.batches <- batch
+		return
+	}
+	c.sendMu.RUnlock()
+
+	<-c.done
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	for _, v := range batch {
+		c.count += v
+	}
+}
+
+func (c *BatchedChannelCounter) ForWorker(id int) Counter {
+	return &channelBatcher{
+		parent:  c,
+		pending: make([]int, 0, c.batchSize),
+	}
+}
+
+// IncrementBy is only used by callers without a batcher, the operation is sent on its own
+func (c *BatchedChannelCounter) IncrementBy(value int) {
+	c.send([]int{value})
+}
+
+// DecrementBy is only used by callers without a batcher, the operation is sent on its own
+func (c *BatchedChannelCounter) DecrementBy(value int) {
+	c.send([]int{-value})
+}
+
+func (c *BatchedChannelCounter) Value() int {
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+
+	reply := make(chan int)
+	select {
+	case c.valueRetrieval <- reply:
+		return <-reply
+	case <-c.done:
+		return c.count
+	}
+}
+
+func (c *BatchedChannelCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+// Reset waits for the worker to finish resetting, returning as soon as it had received the request would let batches
+// sent straight afterwards be discarded along with the ones that came before
+// A closed counter has no worker to ask, so it is started again from zero the way ChannelCounter's Reset does, Reset
+// must not run alongside Close or Drain
+func (c *BatchedChannelCounter) Reset() {
+	c.sendMu.RLock()
+	if !c.closed {
+		defer c.sendMu.RUnlock()
+		done := make(chan struct{})
+		c.resets <- done
+		<-done
+		return
+	}
+	c.sendMu.RUnlock()
+	<-c.done
+
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.count = 0
+	c.closed = false
+	c.closeOnce = sync.Once{}
+	c.closeRequest = make(chan struct{})
+	c.done = make(chan struct{})
+	go c.run()
+}
+
+// Close stops the counter accepting new batches, applies everything already queued and stops the worker
+// It is safe to call more than once, later calls just wait for the first to finish
+func (c *BatchedChannelCounter) Close() {
+	c.closeOnce.Do(func() {
+		c.sendMu.Lock()
+		c.closed = true
+		c.sendMu.Unlock()
+
+		select {
+		case c.closeRequest <- struct{}{}:
+		case <-c.done:
+		}
+	})
+	<-c.done
+}
+
+// Drain closes the counter and returns the settled value with every sent batch applied
+// Operations a batcher is still holding aren't included, the workers flush their batchers before Drain is called
+func (c *BatchedChannelCounter) Drain() int {
+	c.Close()
+
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+	return c.count
+}
+
+func (b *channelBatcher) IncrementBy(value int) {
+	b.pending = append(b.pending, value)
+	if len(b.pending) >= b.parent.batchSize {
+		b.Flush()
+	}
+}
+
+func (b *channelBatcher) DecrementBy(value int) {
+	b.pending = append(b.pending, -value)
+	if len(b.pending) >= b.parent.batchSize {
+		b.Flush()
+	}
+}
+
+func (b *channelBatcher) Value() int {
+	return b.parent.Value()
+}
+
+func (b *channelBatcher) Snapshot() Snapshot {
+	return newSnapshot(b.Value())
+}
+
+// Reset drops the operations this batcher hasn't sent yet along with everything the worker has applied
+func (b *channelBatcher) Reset() {
+	b.pending = b.pending[:0]
+	b.parent.Reset()
+}
+
+// Flush hands the pending batch to the worker, the slice now belongs to the worker so a new one is started
+func (b *channelBatcher) Flush() {
+	if len(b.pending) == 0 {
+		return
+	}
+	b.parent.send(b.pending)
+	b.pending = make([]int, 0, b.parent.batchSize)
+}
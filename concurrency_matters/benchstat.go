@@ -0,0 +1,58 @@
+package concurrencymatters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+)
+
+// benchPackage is the package benchstat shows above the results, as go test would for a package's own benchmarks
+const benchPackage = "github.com/joshdurbin/teaching-go/concurrency_matters"
+
+// counterBenchmarks turns a run's counters into benchmark lines, ns/op is the mean time of one operation and ops/s
+// the throughput, the two numbers the text report leads with
+// The routines are part of the name, so benchstat keeps runs at different routine counts apart
+func counterBenchmarks(counters []*TimedCounter, routines int) []bench.Benchmark {
+	benchmarks := []bench.Benchmark{}
+	for _, counter := range counters {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: fmt.Sprintf("Counter/%s/routines=%d", counter.Name(), routines),
+			N:    counter.TotalOps(),
+			Metrics: []bench.Metric{
+				bench.NsPerOp(float64(counter.TotalTime().Nanoseconds()), counter.TotalOps()),
+				{Value: counter.Throughput(parallelism(routines)), Unit: "ops/s"},
+			},
+		})
+	}
+	return benchmarks
+}
+
+// runBenchmarks runs the workload against fresh counters once per trial, writing each trial's results as benchmark
+// lines as soon as it finishes, e.g. -format bench -trials 10 > old.txt, then again after a change to new.txt, and
+// benchstat old.txt new.txt shows which differences are bigger than the noise
+func runBenchmarks(ctx context.Context, w io.Writer, cfg CounterConfig, selected []string, workload Workload, trials int) error {
+	if err := bench.WriteBenchHeader(w, bench.CaptureEnv(), benchPackage); err != nil {
+		return err
+	}
+	for trial := range trials {
+		slog.Info("running trial", "trial", trial+1, "of", trials)
+
+		// each trial gets its own context so the channel counter's worker exits once the trial is done
+		trialCtx, cancel := context.WithCancel(ctx)
+		cfg.Ctx = trialCtx
+		cfg.Routines = workload.Routines
+		counters := newCounters(cfg, selected)
+		_, _, err := runWorkload(trialCtx, counters, workload)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if err := bench.WriteBenchmarks(w, bench.CaptureEnv(), counterBenchmarks(counters, workload.Routines)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
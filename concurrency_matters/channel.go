@@ -0,0 +1,373 @@
+package concurrencymatters
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ChannelCounter confines the count to a single worker goroutine fed by buffered channels
+// Operations still sitting in the buffers when the workload ends have not been applied yet, so the count only settles
+// once Drain has stopped new operations and emptied the buffers
+type ChannelCounter struct {
+	ctx            context.Context
+	increments     chan int
+	decrements     chan int
+	valueRetrieval chan chan int
+	closeRequest   chan struct{}
+	done           chan struct{}
+	count          int
+
+	// senders hold sendMu for reading while they send, Close takes it for writing so that once closed is set no
+	// send can still be in flight and every accepted operation is already in a buffer for the worker to drain
+	sendMu    sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+
+	// sends counts every increment and decrement sent, blocked those that found their buffer full and had to wait
+	// for the worker, the backpressure that slows senders down to the pace the worker can keep
+	sends   atomic.Int64
+	blocked atomic.Int64
+	// late counts the operations that arrived once the counter had closed and were applied straight to the count
+	late atomic.Int64
+}
+
+// CreateAndRunChannelCounter starts the worker with increment and decrement channels of the given buffer size, zero
+// makes them unbuffered so every send waits for the worker to receive it
+func CreateAndRunChannelCounter(ctx context.Context, buffer int) *ChannelCounter {
+	c := &ChannelCounter{
+		ctx:            ctx,
+		increments:     make(chan int, buffer),
+		decrements:     make(chan int, buffer),
+		valueRetrieval: make(chan chan int),
+		closeRequest:   make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+func (c *ChannelCounter) run() {
+	defer close(c.done)
+
+	ctxDone := c.ctx.Done()
+	for {
+		select {
+		case v := <-c.increments:
+			c.count += v
+		case v := <-c.decrements:
+			c.count -= v
+		case reply := <-c.valueRetrieval:
+			reply <- c.count
+		case <-c.closeRequest:
+			c.drain()
+			return
+		case <-ctxDone:
+			// cancellation closes the counter the same way Drain does, the worker keeps serving while Close waits out
+			// any in-flight senders, and the nil channel stops this case from firing again
+			ctxDone = nil
+			go c.Close()
+		}
+	}
+}
+
+// drain applies everything still buffered, only called once no more sends can happen
+func (c *ChannelCounter) drain() {
+	for {
+		select {
+		case v := <-c.increments:
+			c.count += v
+		case v := <-c.decrements:
+			c.count -= v
+		default:
+			return
+		}
+	}
+}
+
+func (c *ChannelCounter) IncrementBy(value int) {
+	if !c.send(c.increments, value) {
+		c.applyLate(value)
+	}
+}
+
+func (c *ChannelCounter) DecrementBy(value int) {
+	if !c.send(c.decrements, value) {
+		c.applyLate(-value)
+	}
+}
+
+// send tries the channel without waiting first so the sends that had to wait can be counted, false when the counter
+// has already closed
+func (c *ChannelCounter) send(ch chan int, value int) bool {
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+	if c.closed {
+		return false
+	}
+
+	c.sends.Add(1)
+	select {
+	case ch <- value:
+	default:
+		c.blocked.Add(1)
+		ch <- value
+	}
+	return true
+}
+
+// applyLate applies an operation that arrived once the counter had closed straight to the count
+// Cancellation closes the counter as soon as the context is done, while a routine that had already checked the
+// context can still be about to send, turning that operation away would lose one the reference has counted
+// Waiting for done keeps it from racing the worker's drain, the write lock from racing a read of the settled count
+func (c *ChannelCounter) applyLate(value int) {
+	c.late.Add(1)
+	<-c.done
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.count += value
+}
+
+func (c *ChannelCounter) Report() string {
+	sends := c.sends.Load()
+	blockedPercent := 0.0
+	if sends > 0 {
+		blockedPercent = 100 * float64(c.blocked.Load()) / float64(sends)
+	}
+	report := fmt.Sprintf("buffers of %d, senders blocked on a full buffer in %d of %d sends (%.1f%%)",
+		cap(c.increments), c.blocked.Load(), sends, blockedPercent)
+	if late := c.late.Load(); late > 0 {
+		report += fmt.Sprintf(", %d operations arrived after it was closed and were applied directly", late)
+	}
+	return report
+}
+
+func (c *ChannelCounter) Value() int {
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+
+	reply := make(chan int)
+	select {
+	case c.valueRetrieval <- reply:
+		return <-reply
+	case <-c.done:
+		return c.count
+	}
+}
+
+func (c *ChannelCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+// Reset settles and stops the worker the way Close does, then starts a fresh one from zero, a counter that has been
+// drained at the end of one phase comes back to life for the next
+// Senders are held off while the channels are replaced, an operation that raced the reset has either been applied
+// and wiped out or was applied to the closed counter's count before it was zeroed, either way the new worker starts from a clean zero
+// Reset must not run alongside Close or Drain
+func (c *ChannelCounter) Reset() {
+	c.Close()
+
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.count = 0
+	c.sends.Store(0)
+	c.blocked.Store(0)
+	c.late.Store(0)
+	c.closed = false
+	c.closeOnce = sync.Once{}
+	c.closeRequest = make(chan struct{})
+	c.done = make(chan struct{})
+	go c.run()
+}
+
+// Close stops the counter accepting new operations, applies everything already buffered and stops the worker
+// It is safe to call more than once, later calls just wait for the first to finish
+func (c *ChannelCounter) Close() {
+	c.closeOnce.Do(func() {
+		c.sendMu.Lock()
+		c.closed = true
+		c.sendMu.Unlock()
+
+		select {
+		case c.closeRequest <- struct{}{}:
+		case <-c.done:
+		}
+	})
+	<-c.done
+}
+
+// Drain closes the counter and returns the settled value with every accepted operation applied
+func (c *ChannelCounter) Drain() int {
+	c.Close()
+
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+	return c.count
+}
+
+// UnboundedChannelCounter puts an ever growing queue between the senders and the worker, so a send never waits for
+// the worker to catch up, the backlog just grows in memory instead
+// That hides backpressure rather than getting rid of it, a worker that can't keep up shows up as memory use and stale
+// reads rather than slow senders, which is why bounded buffers are usually the better default
+type UnboundedChannelCounter struct {
+	ctx            context.Context
+	intake         chan int
+	queued         chan int
+	valueRetrieval chan chan int
+	done           chan struct{}
+	count          int
+	peakBacklog    atomic.Int64
+
+	// senders hold sendMu for reading while they send, just as for ChannelCounter, so intake is never closed under them
+	sendMu    sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+}
+
+func CreateAndRunUnboundedChannelCounter(ctx context.Context) *UnboundedChannelCounter {
+	c := &UnboundedChannelCounter{ctx: ctx}
+	c.start()
+	return c
+}
+
+func (c *UnboundedChannelCounter) start() {
+	c.intake = make(chan int)
+	c.queued = make(chan int)
+	c.valueRetrieval = make(chan chan int)
+	c.done = make(chan struct{})
+	go c.queue()
+	go c.run()
+}
+
+// queue holds everything the worker hasn't taken yet, always ready to accept another send
+// The outgoing channel is only set while the backlog has something to hand over, sending on a nil channel blocks
+// forever so that case of the select is switched off whenever the backlog is empty
+func (c *UnboundedChannelCounter) queue() {
+	defer close(c.queued)
+
+	var backlog []int
+	intake := c.intake
+	for intake != nil || len(backlog) > 0 {
+		var out chan int
+		var next int
+		if len(backlog) > 0 {
+			out = c.queued
+			next = backlog[0]
+		}
+
+		select {
+		case v, ok := <-intake:
+			if !ok {
+				// closed, stop accepting and hand over whatever is left
+				intake = nil
+				continue
+			}
+			backlog = append(backlog, v)
+			if n := int64(len(backlog)); n > c.peakBacklog.Load() {
+				c.peakBacklog.Store(n)
+			}
+		case out <- next:
+			backlog = backlog[1:]
+		}
+	}
+}
+
+func (c *UnboundedChannelCounter) run() {
+	defer close(c.done)
+
+	ctxDone := c.ctx.Done()
+	for {
+		select {
+		case v, ok := <-c.queued:
+			if !ok {
+				return
+			}
+			c.count += v
+		case reply := <-c.valueRetrieval:
+			reply <- c.count
+		case <-ctxDone:
+			ctxDone = nil
+			go c.Close()
+		}
+	}
+}
+
+// send hands the value to the queue, once the counter has closed it is applied straight to the count instead, for the
+// reason ChannelCounter's applyLate gives
+func (c *UnboundedChannelCounter) send(value int) {
+	c.sendMu.RLock()
+	if !c.closed {
+		defer c.sendMu.RUnlock()
+		c.intake <- value
+		return
+	}
+	c.sendMu.RUnlock()
+
+	<-c.done
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.count += value
+}
+
+func (c *UnboundedChannelCounter) IncrementBy(value int) {
+	c.send(value)
+}
+
+func (c *UnboundedChannelCounter) DecrementBy(value int) {
+	c.send(-value)
+}
+
+// Value only sees what the worker has taken off the queue so far, anything still in the backlog is missing from it
+func (c *UnboundedChannelCounter) Value() int {
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+
+	reply := make(chan int)
+	select {
+	case c.valueRetrieval <- reply:
+		return <-reply
+	case <-c.done:
+		return c.count
+	}
+}
+
+func (c *UnboundedChannelCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+// Close stops accepting operations and waits for the worker to work through the whole backlog
+func (c *UnboundedChannelCounter) Close() {
+	c.closeOnce.Do(func() {
+		c.sendMu.Lock()
+		defer c.sendMu.Unlock()
+		c.closed = true
+		close(c.intake)
+	})
+	<-c.done
+}
+
+func (c *UnboundedChannelCounter) Drain() int {
+	c.Close()
+
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+	return c.count
+}
+
+// Reset closes the counter and starts it again from zero, like ChannelCounter it must not run alongside Close or Drain
+func (c *UnboundedChannelCounter) Reset() {
+	c.Close()
+
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.count = 0
+	c.peakBacklog.Store(0)
+	c.closed = false
+	c.closeOnce = sync.Once{}
+	c.start()
+}
+
+func (c *UnboundedChannelCounter) Report() string {
+	return fmt.Sprintf("peak backlog of %d operations queued ahead of the worker", c.peakBacklog.Load())
+}
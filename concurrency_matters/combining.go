@@ -0,0 +1,165 @@
+package concurrencymatters
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// FlatCombiningCounter lets whichever goroutine gets the lock apply everyone's operations at once
+// Each worker publishes its operation in a slot of its own and then tries for the lock, the winner becomes the combiner
+// and sweeps every slot, applying whatever is waiting there, while the losers simply wait for their slot to be cleared
+// Under contention a single lock acquisition now covers many operations and the count stays in the combiner's cache,
+// where a mutex would have handed both the lock and the count from core to core once per operation
+type FlatCombiningCounter struct {
+	mu    sync.Mutex
+	count int
+	slots []combiningSlot
+
+	// passes counts the combiner's sweeps, combined the operations those sweeps applied
+	passes   atomic.Int64
+	combined atomic.Int64
+}
+
+// combiningSlot is a worker's publication record, padded so publishing never invalidates a neighbour's cache line
+type combiningSlot struct {
+	delta   int
+	pending atomic.Bool
+	_       [48]byte
+}
+
+// combiningHandle is a worker's view of the counter, bound to the slot it publishes into
+type combiningHandle struct {
+	parent *FlatCombiningCounter
+	slot   *combiningSlot
+}
+
+func NewFlatCombiningCounter(workers int) *FlatCombiningCounter {
+	return &FlatCombiningCounter{
+		slots: make([]combiningSlot, workers),
+	}
+}
+
+func (c *FlatCombiningCounter) ForWorker(id int) Counter {
+	return &combiningHandle{parent: c, slot: &c.slots[id%len(c.slots)]}
+}
+
+// combine applies every published operation, only called while holding the lock
+func (c *FlatCombiningCounter) combine() {
+	applied := int64(0)
+	for i := range c.slots {
+		s := &c.slots[i]
+		if s.pending.Load() {
+			c.count += s.delta
+			s.pending.Store(false)
+			applied++
+		}
+	}
+	c.passes.Add(1)
+	c.combined.Add(applied)
+}
+
+// IncrementBy is only used by callers without a slot, they take the lock and apply the change themselves
+func (c *FlatCombiningCounter) IncrementBy(value int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count += value
+}
+
+// DecrementBy is only used by callers without a slot, they take the lock and apply the change themselves
+func (c *FlatCombiningCounter) DecrementBy(value int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count -= value
+}
+
+// Value combines anything still published before reading so the count includes every operation already handed over
+func (c *FlatCombiningCounter) Value() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.combine()
+	return c.count
+}
+
+func (c *FlatCombiningCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+func (c *FlatCombiningCounter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count = 0
+	c.passes.Store(0)
+	c.combined.Store(0)
+}
+
+func (c *FlatCombiningCounter) Report() string {
+	passes := c.passes.Load()
+	perPass := 0.0
+	if passes > 0 {
+		perPass = float64(c.combined.Load()) / float64(passes)
+	}
+	return fmt.Sprintf("%d combining passes applied %d operations, %.2f per pass", passes, c.combined.Load(), perPass)
+}
+
+// apply publishes the change and waits until some combiner, possibly this goroutine, has applied it
+func (h *combiningHandle) apply(delta int) {
+	h.slot.delta = delta
+	h.slot.pending.Store(true)
+
+	for spins := 1; h.slot.pending.Load(); spins++ {
+		if h.parent.mu.TryLock() {
+			h.parent.combine()
+			h.parent.mu.Unlock()
+			return
+		}
+		if spins%spinsBeforeYield == 0 {
+			runtime.Gosched()
+		}
+	}
+}
+
+func (h *combiningHandle) IncrementBy(value int) {
+	h.apply(value)
+}
+
+func (h *combiningHandle) DecrementBy(value int) {
+	h.apply(-value)
+}
+
+func (h *combiningHandle) Value() int {
+	return h.parent.Value()
+}
+
+func (h *combiningHandle) Snapshot() Snapshot {
+	return h.parent.Snapshot()
+}
+
+func (h *combiningHandle) Reset() {
+	h.parent.Reset()
+}
+
+// printCombiningComparison sets flat combining's mean cost per operation against the two designs it borrows from,
+// a lock around the count as in Mutex, and a single goroutine applying everyone's operations as in Channel
+func printCombiningComparison(counters []*TimedCounter) {
+	byName := map[string]*TimedCounter{}
+	for _, counter := range counters {
+		if counter.TotalOps() > 0 {
+			byName[counter.Name()] = counter
+		}
+	}
+	combining, ok := byName["FlatCombining"]
+	if !ok {
+		return
+	}
+
+	combiningMean := meanOpNs(combining)
+	fmt.Printf("\nFlat combining averaged %.1fns per operation", combiningMean)
+	for _, name := range []string{"Mutex", "Channel"} {
+		if other, ok := byName[name]; ok {
+			fmt.Printf(", %s %.1fns (%.2fx)", name, meanOpNs(other), meanOpNs(other)/combiningMean)
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,146 @@
+package concurrencymatters
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CondCounter is a mutex guarded counter that goroutines can also wait on, sleeping until the count reaches a
+// threshold rather than polling Value() in a loop
+// Every change broadcasts on a sync.Cond, waking every waiter to recheck its own threshold under the lock, which is
+// why the wait always sits in a loop, a waiter woken for someone else's threshold simply goes back to sleep
+type CondCounter struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	count int
+	// wakeups counts every time a waiter was woken, wasted the times it found its threshold still out of reach
+	wakeups atomic.Int64
+	wasted  atomic.Int64
+}
+
+func NewCondCounter() *CondCounter {
+	c := &CondCounter{}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *CondCounter) IncrementBy(value int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count += value
+	c.cond.Broadcast()
+}
+
+func (c *CondCounter) DecrementBy(value int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count -= value
+	c.cond.Broadcast()
+}
+
+func (c *CondCounter) Value() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count
+}
+
+func (c *CondCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+// Reset zeroes the count, no waiter can be satisfied by a count going down so there's no one to wake
+func (c *CondCounter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count = 0
+	c.wakeups.Store(0)
+	c.wasted.Store(0)
+}
+
+// WaitUntil blocks until the count is at least threshold, returning the count it saw when it was released
+// A sync.Cond can't be selected on alongside a context, so cancellation wakes every waiter with a broadcast of its
+// own and each one notices the context is done when it rechecks
+func (c *CondCounter) WaitUntil(ctx context.Context, threshold int) (int, error) {
+	stop := context.AfterFunc(ctx, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.cond.Broadcast()
+	})
+	defer stop()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.count < threshold {
+		if err := ctx.Err(); err != nil {
+			return c.count, err
+		}
+		c.cond.Wait()
+		c.wakeups.Add(1)
+		if c.count < threshold {
+			c.wasted.Add(1)
+		}
+	}
+	return c.count, nil
+}
+
+func (c *CondCounter) Report() string {
+	return fmt.Sprintf("waiters woken %d times, %d of them before their threshold was reached", c.wakeups.Load(), c.wasted.Load())
+}
+
+// runCondDemo has routines increment a CondCounter by one while waiters sleep on it until a quarter, half, three
+// quarters and all of the increments have landed, printing each waiter's release as it happens
+func runCondDemo(ctx context.Context, numRoutines, loops int) {
+	counter := NewCondCounter()
+	target := numRoutines * loops
+	thresholds := []int{target / 4, target / 2, target * 3 / 4, target}
+
+	type release struct {
+		threshold int
+		value     int
+		elapsed   time.Duration
+		err       error
+	}
+	releases := make(chan release, len(thresholds))
+
+	start := time.Now()
+	var waiters sync.WaitGroup
+	for _, threshold := range thresholds {
+		waiters.Go(func() {
+			value, err := counter.WaitUntil(ctx, threshold)
+			releases <- release{threshold, value, time.Since(start), err}
+		})
+	}
+
+	fmt.Printf("%d waiters are sleeping until the count reaches %v, %d routines now increment it %d times each\n",
+		len(thresholds), thresholds, numRoutines, loops)
+
+	var workers sync.WaitGroup
+	for range numRoutines {
+		workers.Go(func() {
+			for range loops {
+				if ctx.Err() != nil {
+					return
+				}
+				counter.IncrementBy(1)
+			}
+		})
+	}
+
+	go func() {
+		waiters.Wait()
+		close(releases)
+	}()
+	for r := range releases {
+		if r.err != nil {
+			fmt.Printf("  waiter for %d gave up after %v at %d: %v\n", r.threshold, r.elapsed, r.value, r.err)
+			continue
+		}
+		fmt.Printf("  waiter for %d released after %v, the count had reached %d\n", r.threshold, r.elapsed, r.value)
+	}
+	workers.Wait()
+
+	fmt.Printf("Final value %d, %s\n", counter.Value(), counter.Report())
+}
@@ -0,0 +1,273 @@
+package concurrencymatters
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestCounter builds a registered counter with small settings, any goroutine it starts exits when the test ends
+func newTestCounter(tb testing.TB, factory CounterFactory, routines int) Counter {
+	return newTestCounterWithContext(tb.Context(), factory, routines)
+}
+
+// newTestCounterWithContext builds a registered counter with small settings, closed as soon as ctx is done
+func newTestCounterWithContext(ctx context.Context, factory CounterFactory, routines int) Counter {
+	return factory(CounterConfig{
+		Ctx:           ctx,
+		Routines:      routines,
+		Shards:        4,
+		BatchSize:     8,
+		MaxInFlight:   2,
+		ChannelBuffer: 16,
+		PoolSize:      3,
+		Stripes:       4,
+	})
+}
+
+// settledValue reads a counter once everything sent to it has been applied, counters that queue operations for a
+// worker only promise that after a drain
+func settledValue(counter Counter) int {
+	if drainer, ok := counter.(Drainer); ok {
+		return drainer.Drain()
+	}
+	return counter.Value()
+}
+
+// TestCounterConformance checks every registered counter honours the Counter contract when used from one goroutine
+func TestCounterConformance(t *testing.T) {
+	for _, registered := range counterRegistry {
+		t.Run(registered.name, func(t *testing.T) {
+			t.Parallel()
+			counter := newTestCounter(t, registered.factory, 1)
+
+			if got := counter.Value(); got != 0 {
+				t.Fatalf("new counter has value %d, want 0", got)
+			}
+
+			counter.IncrementBy(5)
+			counter.DecrementBy(2)
+			counter.IncrementBy(7)
+			if got := settledValue(counter); got != 10 {
+				t.Fatalf("after +5 -2 +7 value is %d, want 10", got)
+			}
+
+			before := time.Now()
+			snapshot := counter.Snapshot()
+			if snapshot.Value != 10 {
+				t.Errorf("snapshot value is %d, want 10", snapshot.Value)
+			}
+			if snapshot.At.Before(before) || snapshot.At.After(time.Now()) {
+				t.Errorf("snapshot taken at %v, outside the call", snapshot.At)
+			}
+
+			// nothing is queued straight after a reset, so Value is exact even for the counters with workers, a drain here
+			// would close them
+			counter.Reset()
+			if got := counter.Value(); got != 0 {
+				t.Fatalf("after reset value is %d, want 0", got)
+			}
+
+			// a counter must keep working after a reset, drained ones included
+			counter.IncrementBy(3)
+			if got := settledValue(counter); got != 3 {
+				t.Fatalf("after reset and +3 value is %d, want 3", got)
+			}
+		})
+	}
+}
+
+// TestCountersConcurrent runs a mixed workload against every thread safe counter and checks each one ends up at the
+// value the reference count says it should hold, run it with -race to also check the counters for data races
+func TestCountersConcurrent(t *testing.T) {
+	workload := Workload{
+		Routines:        8,
+		LoopsPerRoutine: 2000,
+		ReadRatio:       0.1,
+		IncrementRatio:  0.5,
+		ValueRange:      5,
+		Seed:            1,
+		Orchestration:   "waitgroup",
+	}
+
+	for _, registered := range counterRegistry {
+		if registered.name == "Unsafe" {
+			continue
+		}
+		t.Run(registered.name, func(t *testing.T) {
+			t.Parallel()
+			counter := NewTimedCounter(registered.name, newTestCounter(t, registered.factory, workload.Routines))
+
+			_, expected, err := runWorkload(t.Context(), []*TimedCounter{counter}, workload)
+			if err != nil {
+				t.Fatalf("workload failed: %v", err)
+			}
+			if got := counter.FinalValue(); got != expected {
+				t.Errorf("value is %d, want %d", got, expected)
+			}
+			if got, want := counter.TotalOps(), int64(workload.Routines*workload.LoopsPerRoutine); got != want {
+				t.Errorf("recorded %d operations, want %d", got, want)
+			}
+		})
+	}
+}
+
+// TestCountersAcrossPhases checks a reset between two runs leaves every counter exactly as good as a new one
+func TestCountersAcrossPhases(t *testing.T) {
+	workload := Workload{
+		Routines:        4,
+		LoopsPerRoutine: 500,
+		IncrementRatio:  0.5,
+		ValueRange:      5,
+		Seed:            2,
+	}
+
+	for _, registered := range counterRegistry {
+		if registered.name == "Unsafe" {
+			continue
+		}
+		t.Run(registered.name, func(t *testing.T) {
+			t.Parallel()
+			counter := NewTimedCounter(registered.name, newTestCounter(t, registered.factory, workload.Routines))
+
+			for phase := range 2 {
+				if phase > 0 {
+					counter.Reset()
+				}
+				_, expected, err := runWorkload(t.Context(), []*TimedCounter{counter}, workload)
+				if err != nil {
+					t.Fatalf("phase %d failed: %v", phase, err)
+				}
+				if got := counter.FinalValue(); got != expected {
+					t.Errorf("phase %d value is %d, want %d", phase, got, expected)
+				}
+			}
+		})
+	}
+}
+
+// TestCountersCanceledMidRun cancels the run part way through, as -timeout and -cancel-after do, every counter is built
+// on the canceled context just as the main program builds them, so the ones with workers close while routines may still
+// be sending, and each has to settle on the net of the operations the routines actually issued
+func TestCountersCanceledMidRun(t *testing.T) {
+	workload := Workload{
+		Routines:        8,
+		LoopsPerRoutine: 2000,
+		ReadRatio:       0.1,
+		IncrementRatio:  0.5,
+		ValueRange:      5,
+		Seed:            3,
+		Orchestration:   "waitgroup",
+		ThinkTime:       50 * time.Microsecond,
+		Burst:           4,
+	}
+	scheduled := int64(workload.Routines * workload.LoopsPerRoutine)
+
+	for _, registered := range counterRegistry {
+		if registered.name == "Unsafe" {
+			continue
+		}
+		t.Run(registered.name, func(t *testing.T) {
+			t.Parallel()
+			ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+			defer cancel()
+			counter := NewTimedCounter(registered.name, newTestCounterWithContext(ctx, registered.factory, workload.Routines))
+
+			_, expected, err := runWorkload(ctx, []*TimedCounter{counter}, workload)
+			if err != nil {
+				t.Fatalf("workload failed: %v", err)
+			}
+			if ops := counter.TotalOps(); ops == scheduled {
+				t.Skipf("all %d operations finished before the run was canceled", ops)
+			}
+			if got := counter.FinalValue(); got != expected {
+				t.Errorf("value after the canceled run is %d, want %d, the net of the operations issued", got, expected)
+			}
+		})
+	}
+}
+
+func TestCondCounterWaitUntil(t *testing.T) {
+	counter := NewCondCounter()
+
+	released := make(chan int, 1)
+	go func() {
+		value, err := counter.WaitUntil(t.Context(), 10)
+		if err != nil {
+			t.Errorf("wait failed: %v", err)
+		}
+		released <- value
+	}()
+
+	for range 9 {
+		counter.IncrementBy(1)
+	}
+	select {
+	case value := <-released:
+		t.Fatalf("waiter released at %d, before the threshold", value)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	counter.IncrementBy(1)
+	if value := <-released; value < 10 {
+		t.Errorf("waiter released at %d, want at least 10", value)
+	}
+}
+
+func TestCondCounterWaitUntilCanceled(t *testing.T) {
+	counter := NewCondCounter()
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := counter.WaitUntil(ctx, 1); err == nil {
+		t.Error("wait on a canceled context returned no error")
+	}
+}
+
+// TestAtomicCounterOf checks the bit conversions of the generic atomic counter keep signs and fractions intact
+func TestAtomicCounterOf(t *testing.T) {
+	ints := &AtomicCounterOf[int32]{}
+	ints.IncrementBy(5)
+	ints.DecrementBy(12)
+	if got := ints.Value(); got != -7 {
+		t.Errorf("int32 counter value is %d, want -7", got)
+	}
+
+	floats := &AtomicCounterOf[float64]{}
+	floats.IncrementBy(0.25)
+	floats.DecrementBy(1.5)
+	if got := floats.Value(); got != -1.25 {
+		t.Errorf("float64 counter value is %v, want -1.25", got)
+	}
+}
+
+// BenchmarkCounters increments every counter from GOMAXPROCS goroutines at once, the ns/op it reports is wall clock
+// per operation across all of them, the inverse of the throughput the main program prints
+// Run it with -cpu 1,2,4,8 to see the counters scale, or collapse, as goroutines are added
+func BenchmarkCounters(b *testing.B) {
+	for _, registered := range counterRegistry {
+		b.Run(registered.name, func(b *testing.B) {
+			if registered.name == "Unsafe" && raceEnabled {
+				b.Skip("the unsafe counter races by design")
+			}
+			counter := newTestCounter(b, registered.factory, runtime.GOMAXPROCS(0))
+
+			// RunParallel starts GOMAXPROCS goroutines, each takes the next worker id for counters that hand out slots
+			var nextWorker atomic.Int64
+			b.RunParallel(func(pb *testing.PB) {
+				view := counter
+				if local, ok := counter.(WorkerLocal); ok {
+					view = local.ForWorker(int(nextWorker.Add(1) - 1))
+				}
+				for pb.Next() {
+					view.IncrementBy(1)
+				}
+				if flusher, ok := view.(Flusher); ok {
+					flusher.Flush()
+				}
+			})
+		})
+	}
+}
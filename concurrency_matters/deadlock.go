@@ -0,0 +1,147 @@
+package concurrencymatters
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+)
+
+// deadlockTimeout is how long the demonstration waits for the transfers before declaring them deadlocked, far longer
+// than a couple of lock acquisitions could ever take
+const deadlockTimeout = time.Second
+
+// account is a counter taking part in transfers, rank fixes where its lock sits in the global lock order
+type account struct {
+	name    string
+	rank    int
+	counter *MutexCounter
+}
+
+// transfer moves amount between two accounts holding both their locks, so nothing ever sees the amount in neither or
+// in both of them
+// Unordered, it locks the source first, two transfers in opposite directions then each take the lock the other needs
+// next, ordered, it locks the lower ranked account first whichever way the amount moves so no such cycle can form
+// The pause between the two locks stands in for real work and makes the unlucky interleaving a certainty
+func transfer(from, to *account, amount int, ordered bool) {
+	first, second := from, to
+	if ordered && to.rank < from.rank {
+		first, second = to, from
+	}
+
+	first.counter.mu.Lock()
+	defer first.counter.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	second.counter.mu.Lock()
+	defer second.counter.mu.Unlock()
+
+	from.counter.count -= amount
+	to.counter.count += amount
+}
+
+// runTransfers moves amount from a to b and from b to a at the same time, reporting whether both finished in time
+// Each transfer runs under pprof labels naming its direction, which is how the goroutine dump tells them apart
+func runTransfers(ctx context.Context, a, b *account, amount int, ordered bool) bool {
+	var wg sync.WaitGroup
+	for _, pair := range [][2]*account{{a, b}, {b, a}} {
+		from, to := pair[0], pair[1]
+		wg.Go(func() {
+			pprof.Do(ctx, pprof.Labels("transfer_from", from.name, "transfer_to", to.name), func(context.Context) {
+				transfer(from, to, amount, ordered)
+			})
+		})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(deadlockTimeout):
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// goroutineLabels matches the labels line the goroutine profile prints above each group of goroutines
+var goroutineLabels = regexp.MustCompile(`# labels: \{"transfer_from":"(\w+)", "transfer_to":"(\w+)"\}`)
+
+// printDeadlockedGoroutines prints the stacks of the stuck transfers from the goroutine profile, the same stacks
+// SIGQUIT or a hung test's timeout would dump, with a note on which lock each one holds and which it is waiting for
+func printDeadlockedGoroutines() {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		fmt.Printf("failed to dump goroutines: %v\n", err)
+		return
+	}
+
+	for _, block := range strings.Split(buf.String(), "\n\n") {
+		labels := goroutineLabels.FindStringSubmatch(block)
+		if labels == nil {
+			continue
+		}
+		from, to := labels[1], labels[2]
+		fmt.Printf("  transfer %s -> %s holds %s's lock and is blocked in sync.(*Mutex).Lock waiting for %s's:\n", from, to, from, to)
+
+		for _, line := range strings.Split(block, "\n") {
+			// frames look like "#\t0x4b1c2d\tsync.(*Mutex).Lock+0x4c\t\t/usr/local/go/src/sync/mutex.go:46", the
+			// function names are padded out to line up with a varying number of tabs
+			fields := strings.FieldsFunc(line, func(r rune) bool { return r == '\t' })
+			if len(fields) != 4 || fields[0] != "#" {
+				continue
+			}
+			note := ""
+			if strings.HasPrefix(fields[2], "main.transfer+") {
+				note = "  <- the second Lock, which never returns"
+			}
+			fmt.Printf("      %s%s\n          %s\n", fields[2], note, fields[3])
+		}
+		fmt.Println()
+	}
+}
+
+// runDeadlockDemo deadlocks two transfers between a pair of mutex counters, shows what the stuck goroutines look like,
+// then repeats the transfers taking the locks in a fixed order
+// The runtime only reports "all goroutines are asleep" when every goroutine is blocked, the main goroutine here is
+// still waiting on a timer, just as a server's other goroutines would still be serving requests, so a real deadlock
+// like this one goes unnoticed until something times out
+func runDeadlockDemo(ctx context.Context) {
+	a := &account{name: "A", rank: 0, counter: &MutexCounter{}}
+	b := &account{name: "B", rank: 1, counter: &MutexCounter{}}
+	a.counter.IncrementBy(100)
+	b.counter.IncrementBy(100)
+
+	fmt.Println("Transferring 10 from A to B and 10 from B to A at the same time, each transfer locks its source first")
+	if runTransfers(ctx, a, b, 10, false) {
+		fmt.Println("Both transfers completed, this time the goroutines didn't interleave badly enough to deadlock")
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	fmt.Printf("Neither transfer finished within %v, they are deadlocked, each holds the lock the other is waiting for\n\n", deadlockTimeout)
+	printDeadlockedGoroutines()
+
+	// the deadlocked goroutines and the locks they hold can never be recovered, so the fix gets accounts of its own
+	a = &account{name: "A", rank: 0, counter: &MutexCounter{}}
+	b = &account{name: "B", rank: 1, counter: &MutexCounter{}}
+	a.counter.IncrementBy(100)
+	b.counter.IncrementBy(100)
+
+	fmt.Println("Repeating the transfers with both always locking A before B, whichever way the amount moves")
+	start := time.Now()
+	if !runTransfers(ctx, a, b, 10, true) {
+		fmt.Println("The ordered transfers didn't finish either")
+		return
+	}
+	fmt.Printf("Both transfers completed in %v, A is %d and B is %d\n", time.Since(start).Round(time.Millisecond), a.counter.Value(), b.counter.Value())
+}
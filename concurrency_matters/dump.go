@@ -0,0 +1,62 @@
+package concurrencymatters
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// watchDumpSignal prints a dump of every counter to w each time the process receives one of the dump signals, the run
+// carries on regardless, until the returned stop function is called
+// Ctrl+C asks the program to stop, a dump signal only asks it what it is doing, the same channel based signal.Notify
+// serves both, it is the choice of signal that gives one its meaning
+func watchDumpSignal(w io.Writer, counters []*TimedCounter) (stop func()) {
+	if len(dumpSignals) == 0 {
+		return func() {}
+	}
+
+	// signal.Notify never blocks sending, a buffer of one keeps a signal that arrives mid-dump rather than dropping it
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, dumpSignals...)
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	start := time.Now()
+
+	go func() {
+		defer close(finished)
+		for {
+			select {
+			case sig := <-signals:
+				dumpCounters(w, counters, sig, time.Since(start))
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+		<-finished
+	}
+}
+
+// dumpCounters prints each counter's current value and statistics as they stand mid-run
+func dumpCounters(w io.Writer, counters []*TimedCounter, sig os.Signal, elapsed time.Duration) {
+	fmt.Fprintf(w, "\n--- %v, %v into the run, which carries on ---\n", sig, elapsed.Round(time.Millisecond))
+	for _, counter := range counters {
+		value := "unreadable mid-run"
+		if live, ok := counter.LiveValue(); ok {
+			value = fmt.Sprint(live)
+		}
+		fmt.Fprintf(w, "%-20s value %s, %d ops in %v, p50 %v, p99 %v\n", counter.Name(), value,
+			counter.TotalOps(), counter.TotalTime(), counter.Percentile(0.50), counter.Percentile(0.99))
+		if report := counter.Report(); report != "" {
+			fmt.Fprintf(w, "%-20s %s\n", "", report)
+		}
+	}
+	fmt.Fprintln(w, "---")
+}
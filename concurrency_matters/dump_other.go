@@ -0,0 +1,8 @@
+//go:build !unix
+
+package concurrencymatters
+
+import "os"
+
+// dumpSignals is empty where there is no SIGUSR1, Windows has no user defined signals
+var dumpSignals = []os.Signal{}
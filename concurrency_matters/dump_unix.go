@@ -0,0 +1,11 @@
+//go:build unix
+
+package concurrencymatters
+
+import (
+	"os"
+	"syscall"
+)
+
+// dumpSignals print a dump of the counters without stopping the run, kill -USR1 <pid> sends one
+var dumpSignals = []os.Signal{syscall.SIGUSR1}
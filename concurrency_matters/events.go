@@ -0,0 +1,262 @@
+package concurrencymatters
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxEventsPerRoutine bounds the event log, it is meant for runs small enough to read through, not for profiling
+const maxEventsPerRoutine = 10000
+
+// Event is a single operation one routine applied to one counter, its times are relative to the start of the run
+// Go deliberately has no goroutine ids, the routine's index in the workload identifies it instead
+type Event struct {
+	Routine int
+	Counter string
+	Kind    OperationKind
+	Value   int
+	Start   time.Duration
+	End     time.Duration
+}
+
+// EventLog records every operation of a run, each routine appends to a buffer of its own so recording takes no lock
+// and routines never contend on the log, the buffers are only merged once the run is over
+type EventLog struct {
+	start    time.Time
+	routines [][]Event
+}
+
+// begin clears the log for a run starting now with the given number of routines
+func (l *EventLog) begin(routines int, start time.Time) {
+	l.start = start
+	l.routines = make([][]Event, routines)
+}
+
+// record is only ever called by the routine that owns the buffer, operations beyond the limit aren't kept
+func (l *EventLog) record(routine int, counter string, op scheduledOp, began, ended time.Time) {
+	if len(l.routines[routine]) >= maxEventsPerRoutine {
+		return
+	}
+	l.routines[routine] = append(l.routines[routine], Event{
+		Routine: routine,
+		Counter: counter,
+		Kind:    op.kind,
+		Value:   op.value,
+		Start:   began.Sub(l.start),
+		End:     ended.Sub(l.start),
+	})
+}
+
+// Events merges every routine's buffer into one log ordered by start time
+func (l *EventLog) Events() []Event {
+	events := slices.Concat(l.routines...)
+	slices.SortStableFunc(events, func(a, b Event) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+	return events
+}
+
+var operationNames = map[OperationKind]string{
+	OpIncrement: "increment",
+	OpDecrement: "decrement",
+	OpValue:     "read",
+}
+
+// WriteEvents saves events as CSV, one row per operation with its times in nanoseconds
+func WriteEvents(path string, events []Event) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	writer := csv.NewWriter(f)
+	writer.Write([]string{"routine", "counter", "op", "value", "start_ns", "end_ns"})
+	for _, event := range events {
+		writer.Write([]string{
+			strconv.Itoa(event.Routine),
+			event.Counter,
+			operationNames[event.Kind],
+			strconv.Itoa(event.Value),
+			strconv.FormatInt(event.Start.Nanoseconds(), 10),
+			strconv.FormatInt(event.End.Nanoseconds(), 10),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// ReadEvents loads an event log written by WriteEvents
+func ReadEvents(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+
+	events := []Event{}
+	for i, row := range rows[1:] {
+		event, err := parseEvent(row)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, i+2, err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func parseEvent(row []string) (Event, error) {
+	if len(row) != 6 {
+		return Event{}, fmt.Errorf("expected 6 fields, found %d", len(row))
+	}
+
+	event := Event{Counter: row[1]}
+	kind := -1
+	for k, name := range operationNames {
+		if name == row[2] {
+			kind = int(k)
+		}
+	}
+	if kind < 0 {
+		return Event{}, fmt.Errorf("unknown operation %q", row[2])
+	}
+	event.Kind = OperationKind(kind)
+
+	numbers := []int64{}
+	for _, field := range []string{row[0], row[3], row[4], row[5]} {
+		n, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return Event{}, err
+		}
+		numbers = append(numbers, n)
+	}
+	event.Routine = int(numbers[0])
+	event.Value = int(numbers[1])
+	event.Start = time.Duration(numbers[2])
+	event.End = time.Duration(numbers[3])
+	return event, nil
+}
+
+// overlap is a pair of writes to the same counter by different routines that were in progress at the same time
+type overlap struct {
+	first, second Event
+}
+
+// findOverlaps returns every pair of overlapping writes to the given counter, on a counter without synchronization
+// each one is a chance for both routines to read the same old count and for one of their updates to be lost
+func findOverlaps(events []Event, counter string) []overlap {
+	writes := []Event{}
+	for _, event := range events {
+		if event.Counter == counter && event.Kind != OpValue {
+			writes = append(writes, event)
+		}
+	}
+
+	overlaps := []overlap{}
+	for i, first := range writes {
+		for _, second := range writes[i+1:] {
+			if second.Start >= first.End {
+				break
+			}
+			if second.Routine != first.Routine {
+				overlaps = append(overlaps, overlap{first, second})
+			}
+		}
+	}
+	return overlaps
+}
+
+// timelineSymbols marks each operation type in the timeline, a write that overlapped another routine's write on the
+// same counter is marked with a * instead
+var timelineSymbols = map[OperationKind]byte{
+	OpIncrement: '+',
+	OpDecrement: '-',
+	OpValue:     'r',
+}
+
+// renderTimeline draws every counter's operations as one lane per routine across the given number of columns, so
+// the way the routines' operations interleave can be read straight off the page, followed by the overlapping writes
+func renderTimeline(w io.Writer, events []Event, width int) {
+	if len(events) == 0 {
+		fmt.Fprintln(w, "The event log is empty")
+		return
+	}
+
+	var end time.Duration
+	routines := 0
+	counters := []string{}
+	for _, event := range events {
+		end = max(end, event.End)
+		routines = max(routines, event.Routine+1)
+		if !slices.Contains(counters, event.Counter) {
+			counters = append(counters, event.Counter)
+		}
+	}
+	column := max(end/time.Duration(width), 1)
+	fmt.Fprintf(w, "Timeline of %d operations over %v, each column is %v, %s\n",
+		len(events), end, column, "+ increment, - decrement, r read, * a write that overlapped another routine's write")
+
+	for _, counter := range counters {
+		overlaps := findOverlaps(events, counter)
+		overlapped := map[Event]bool{}
+		for _, o := range overlaps {
+			overlapped[o.first] = true
+			overlapped[o.second] = true
+		}
+
+		lanes := make([][]byte, routines)
+		for i := range lanes {
+			lanes[i] = []byte(strings.Repeat(" ", width))
+		}
+		for _, event := range events {
+			if event.Counter != counter {
+				continue
+			}
+			symbol := timelineSymbols[event.Kind]
+			if overlapped[event] {
+				symbol = '*'
+			}
+			lane := lanes[event.Routine]
+			for col := min(int(event.Start/column), width-1); col <= min(int(event.End/column), width-1); col++ {
+				// an overlap is the interesting part, never draw over one
+				if lane[col] != '*' {
+					lane[col] = symbol
+				}
+			}
+		}
+
+		fmt.Fprintf(w, "\n%s\n", counter)
+		for i, lane := range lanes {
+			fmt.Fprintf(w, "  routine %-3d |%s|\n", i, lane)
+		}
+
+		if len(overlaps) == 0 {
+			fmt.Fprintln(w, "  no two routines' writes overlapped")
+			continue
+		}
+		fmt.Fprintf(w, "  %d pairs of writes overlapped, without synchronization each pair can lose an update, the first few:\n", len(overlaps))
+		for _, o := range overlaps[:min(len(overlaps), 5)] {
+			fmt.Fprintf(w, "    routine %d %s %d from %v to %v overlapped routine %d %s %d from %v to %v\n",
+				o.first.Routine, operationNames[o.first.Kind], o.first.Value, o.first.Start, o.first.End,
+				o.second.Routine, operationNames[o.second.Kind], o.second.Value, o.second.Start, o.second.End)
+		}
+	}
+}
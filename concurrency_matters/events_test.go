@@ -0,0 +1,49 @@
+package concurrencymatters
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEventsRoundTrip(t *testing.T) {
+	events := []Event{
+		{Routine: 0, Counter: "Unsafe", Kind: OpIncrement, Value: 3, Start: 100, End: 180},
+		{Routine: 1, Counter: "Unsafe", Kind: OpDecrement, Value: 2, Start: 150, End: 210},
+		{Routine: 1, Counter: "Mutex", Kind: OpValue, Start: 220, End: 240},
+	}
+
+	path := filepath.Join(t.TempDir(), "events.csv")
+	if err := WriteEvents(path, events); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	read, err := ReadEvents(path)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if !reflect.DeepEqual(read, events) {
+		t.Errorf("read back %v, want %v", read, events)
+	}
+}
+
+func TestFindOverlaps(t *testing.T) {
+	events := []Event{
+		{Routine: 0, Counter: "Unsafe", Kind: OpIncrement, Start: 100, End: 180},
+		{Routine: 1, Counter: "Unsafe", Kind: OpDecrement, Start: 150, End: 210},
+		// a read overlapping the writes, reads can't lose an update
+		{Routine: 2, Counter: "Unsafe", Kind: OpValue, Start: 160, End: 170},
+		// the same routine's next write, and a write after everything else finished
+		{Routine: 1, Counter: "Unsafe", Kind: OpIncrement, Start: 210, End: 220},
+		{Routine: 0, Counter: "Unsafe", Kind: OpIncrement, Start: 300, End: 310},
+		// a write to another counter at the same time
+		{Routine: 2, Counter: "Mutex", Kind: OpIncrement, Start: 100, End: 200},
+	}
+
+	overlaps := findOverlaps(events, "Unsafe")
+	if len(overlaps) != 1 {
+		t.Fatalf("found %d overlaps, want 1: %v", len(overlaps), overlaps)
+	}
+	if overlaps[0].first.Routine != 0 || overlaps[0].second.Routine != 1 {
+		t.Errorf("overlap is between routines %d and %d, want 0 and 1", overlaps[0].first.Routine, overlaps[0].second.Routine)
+	}
+}
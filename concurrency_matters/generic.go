@@ -0,0 +1,201 @@
+package concurrencymatters
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// Number is every type a generic counter can count in, the ~ admits named types built on them too
+type Number interface {
+	~int | ~int32 | ~int64 | ~float32 | ~float64
+}
+
+// CounterOf is the Counter contract over any numeric type, the same operations counting in T instead of int
+type CounterOf[T Number] interface {
+	IncrementBy(value T)
+	DecrementBy(value T)
+	Value() T
+	Reset()
+}
+
+// MutexCounterOf is MutexCounter written once for every Number, the compiler produces a copy for each type it is used
+// with, so the lock and the arithmetic are exactly as cheap as in the hand written int version
+type MutexCounterOf[T Number] struct {
+	mu    sync.Mutex
+	count T
+}
+
+func (c *MutexCounterOf[T]) IncrementBy(value T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count += value
+}
+
+func (c *MutexCounterOf[T]) DecrementBy(value T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count -= value
+}
+
+func (c *MutexCounterOf[T]) Value() T {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count
+}
+
+func (c *MutexCounterOf[T]) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count = 0
+}
+
+// AtomicCounterOf is a lock free counter for every Number, floats included
+// CPUs have no atomic floating point add, so there is no atomic.AddFloat64, instead the value is kept as its raw 64
+// bits in an atomic.Uint64 and every change loads the bits, converts them back to a T, adds, converts the result to
+// bits and compare-and-swaps it in, retrying whenever another goroutine changed the bits in between
+// Integers take the same path here to keep it to one implementation, atomic.Int64's single instruction add is faster
+type AtomicCounterOf[T Number] struct {
+	bits    atomic.Uint64
+	retries atomic.Int64
+}
+
+// isFloat reports whether T is a floating point type, a half survives conversion to a float but truncates to an
+// integer's zero, the conversion has to be of a variable as converting the constant 0.5 to an integer doesn't compile
+func isFloat[T Number]() bool {
+	half := 0.5
+	return T(half) != 0
+}
+
+// toBits and fromBits convert a T to and from the 64 bits stored, floats by their IEEE 754 encoding and integers as
+// two's complement
+func toBits[T Number](value T) uint64 {
+	if isFloat[T]() {
+		return math.Float64bits(float64(value))
+	}
+	return uint64(int64(value))
+}
+
+func fromBits[T Number](bits uint64) T {
+	if isFloat[T]() {
+		return T(math.Float64frombits(bits))
+	}
+	return T(int64(bits))
+}
+
+func (c *AtomicCounterOf[T]) add(delta T) {
+	for {
+		current := c.bits.Load()
+		next := toBits(fromBits[T](current) + delta)
+		if c.bits.CompareAndSwap(current, next) {
+			return
+		}
+		c.retries.Add(1)
+	}
+}
+
+func (c *AtomicCounterOf[T]) IncrementBy(value T) {
+	c.add(value)
+}
+
+func (c *AtomicCounterOf[T]) DecrementBy(value T) {
+	c.add(-value)
+}
+
+func (c *AtomicCounterOf[T]) Value() T {
+	return fromBits[T](c.bits.Load())
+}
+
+func (c *AtomicCounterOf[T]) Reset() {
+	c.bits.Store(toBits(T(0)))
+	c.retries.Store(0)
+}
+
+func (c *AtomicCounterOf[T]) Report() string {
+	return fmt.Sprintf("compare and swap retries on the %T bits: %d", T(0), c.retries.Load())
+}
+
+// intCounter adapts a CounterOf any Number to the int based Counter the benchmark drives, so the generic counters run
+// alongside every other implementation
+type intCounter[T Number] struct {
+	inner CounterOf[T]
+}
+
+func (c intCounter[T]) IncrementBy(value int) {
+	c.inner.IncrementBy(T(value))
+}
+
+func (c intCounter[T]) DecrementBy(value int) {
+	c.inner.DecrementBy(T(value))
+}
+
+func (c intCounter[T]) Value() int {
+	return int(math.Round(float64(c.inner.Value())))
+}
+
+func (c intCounter[T]) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+func (c intCounter[T]) Reset() {
+	c.inner.Reset()
+}
+
+func (c intCounter[T]) Report() string {
+	if reporter, ok := c.inner.(Reporter); ok {
+		return reporter.Report()
+	}
+	return ""
+}
+
+// runFloatDemo has every routine add its own fraction to float64 counters to show that floating point addition isn't
+// associative, each addition rounds, so the order the additions happen in changes the total, and concurrency makes
+// that order different on every run
+// The sequential sum adds each routine's share in turn, the concurrent ones interleave them however the scheduler likes
+func runFloatDemo(ctx context.Context, numRoutines, loops int) {
+	step := func(routine int) float64 {
+		return 1 / float64(routine+3)
+	}
+	fmt.Printf("%d routines each add a fraction of their own, 1/3, 1/4, 1/5 and so on, %d times to float64 counters\n\n",
+		numRoutines, loops)
+
+	sequential := &MutexCounterOf[float64]{}
+	for i := range numRoutines {
+		for range loops {
+			sequential.IncrementBy(step(i))
+		}
+	}
+	fmt.Printf("%-26s %.17g\n", "Sequential", sequential.Value())
+
+	for _, counter := range []struct {
+		name    string
+		counter CounterOf[float64]
+	}{
+		{"MutexCounterOf[float64]", &MutexCounterOf[float64]{}},
+		{"AtomicCounterOf[float64]", &AtomicCounterOf[float64]{}},
+	} {
+		var wg sync.WaitGroup
+		for i := range numRoutines {
+			wg.Go(func() {
+				for range loops {
+					if ctx.Err() != nil {
+						return
+					}
+					counter.counter.IncrementBy(step(i))
+				}
+			})
+		}
+		wg.Wait()
+
+		report := ""
+		if reporter, ok := counter.counter.(Reporter); ok {
+			report = ", " + reporter.Report()
+		}
+		fmt.Printf("%-26s %.17g, %+g from sequential%s\n", counter.name, counter.counter.Value(),
+			counter.counter.Value()-sequential.Value(), report)
+	}
+	fmt.Println("\nNo addition was lost, yet the totals can disagree in their last digits, each one rounded its additions")
+	fmt.Println("in a different order, run it again and the concurrent totals will likely change")
+}
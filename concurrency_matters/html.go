@@ -0,0 +1,65 @@
+package concurrencymatters
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/report"
+)
+
+// htmlReport charts a run's throughput and latency percentiles for every counter, along with the settings it ran with
+func htmlReport(result RunResult) report.Report {
+	config := result.Config
+	r := report.Report{
+		Title: "Concurrency matters, " + config.Mode + " run",
+		Settings: []report.Setting{
+			{Name: "Routines", Value: strconv.Itoa(config.Routines)},
+			{Name: "Loops per routine", Value: strconv.Itoa(config.LoopsPerRoutine)},
+			{Name: "Read ratio", Value: strconv.FormatFloat(config.ReadRatio, 'g', -1, 64)},
+			{Name: "Increment ratio", Value: strconv.FormatFloat(config.IncrementRatio, 'g', -1, 64)},
+			{Name: "Seed", Value: strconv.FormatInt(config.Seed, 10)},
+			{Name: "GOMAXPROCS", Value: strconv.Itoa(config.GOMAXPROCS)},
+			{Name: "Shards / stripes", Value: fmt.Sprintf("%d / %d", config.Shards, config.Stripes)},
+			{Name: "Warm-up operations per routine", Value: strconv.Itoa(config.Warmup)},
+			{Name: "Machine", Value: result.Env.String()},
+		},
+	}
+
+	names := []string{}
+	throughput := []float64{}
+	percentiles := report.Chart{
+		Title:  "Latency percentiles, across every operation type",
+		Series: []string{"p50", "p90", "p99"},
+		Format: report.Nanoseconds,
+	}
+	wrong := []string{}
+	for _, counter := range result.Counters {
+		names = append(names, counter.Name)
+		throughput = append(throughput, counter.ThroughputOpsPerSec)
+		percentiles.Groups = append(percentiles.Groups, report.Group{
+			Label:  counter.Name,
+			Values: []float64{float64(counter.P50Ns), float64(counter.P90Ns), float64(counter.P99Ns)},
+		})
+		if !counter.Correct {
+			wrong = append(wrong, fmt.Sprintf("%s ended at %d", counter.Name, counter.Value))
+		}
+	}
+
+	throughputTitle := "Throughput, operations per second estimated from the time spent in each counter"
+	if config.Mode == "separate" {
+		throughputTitle = "Throughput, operations per second of wall clock with each counter run on its own"
+	}
+	r.Charts = append(r.Charts, report.Bars(throughputTitle, bench.FormatRate, names, throughput), percentiles)
+
+	if len(result.Counters) > 0 {
+		expected := result.Counters[0].Expected
+		if len(wrong) == 0 {
+			r.Notes = append(r.Notes, fmt.Sprintf("Every counter ended at the expected value of %d", expected))
+		} else {
+			r.Notes = append(r.Notes, fmt.Sprintf("Expected every counter to end at %d, lost updates left %s", expected, strings.Join(wrong, ", ")))
+		}
+	}
+	return r
+}
@@ -1,16 +1,36 @@
-package main
+// Package concurrencymatters is the counters lesson, run it with teachgo counters
+package concurrencymatters
 
 import (
+	"cmp"
 	"context"
-	"flag"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"math"
 	"math/rand"
 	"os"
 	"os/signal"
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/datagen"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+	"github.com/joshdurbin/teaching-go/internal/report"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
 )
 
 // Counter defines the common contract for the counters
@@ -18,22 +38,110 @@ type Counter interface {
 	IncrementBy(value int)
 	DecrementBy(value int)
 	Value() int
+	// Snapshot returns the value along with the moment it was read
+	Snapshot() Snapshot
+	// Reset returns the count, and any statistics the counter reports, to zero so the counter can be reused for
+	// another phase of a benchmark, operations racing the reset land either before or after it
+	Reset()
+}
+
+// Snapshot is a counter's value at a point in time
+type Snapshot struct {
+	Value int
+	At    time.Time
+}
+
+func newSnapshot(value int) Snapshot {
+	return Snapshot{Value: value, At: time.Now()}
+}
+
+// Reporter is implemented by counters that have implementation specific statistics worth including in the report
+type Reporter interface {
+	Report() string
+}
+
+// Flusher is implemented by counters that buffer operations and must push them through before the count is read
+type Flusher interface {
+	Flush()
+}
+
+// FinalValueOnly is implemented by counters whose Value is only safe to call once every worker has finished
+type FinalValueOnly interface {
+	FinalValueOnly()
+}
+
+// Drainer is implemented by counters that may still hold accepted but unapplied operations, Drain stops new
+// operations, applies the outstanding ones and returns the settled value
+type Drainer interface {
+	Drain() int
+}
+
+// WorkerLocal is implemented by counters that hand each goroutine its own private slot to write into
+type WorkerLocal interface {
+	ForWorker(id int) Counter
 }
 
 // This is a decorator pattern implementation that adds timing functionality and name to track any Counter implementation
 // and keeps the total operation count and total time spent in operations and reduces code duplication
 // within the counters themselves.
 type TimedCounter struct {
-	name        string
-	delegate    Counter
-	totalTimeNs atomic.Int64
-	totalOps    atomic.Int64
+	name     string
+	delegate Counter
+	stats    *timingStats
+}
+
+// timingStats is held by pointer so per-worker views of a TimedCounter accumulate into the same totals
+type timingStats struct {
+	increments bench.Stats
+	decrements bench.Stats
+	reads      bench.Stats
+}
+
+// byType pairs each operation type's stats with the name it's reported under
+func (s *timingStats) byType() []namedOpStats {
+	return []namedOpStats{
+		{"increment", &s.increments},
+		{"decrement", &s.decrements},
+		{"read", &s.reads},
+	}
+}
+
+type namedOpStats struct {
+	name  string
+	stats *bench.Stats
+}
+
+func newTimingStats() *timingStats {
+	stats := &timingStats{}
+	stats.reset()
+	return stats
+}
+
+func (s *timingStats) reset() {
+	for _, op := range s.byType() {
+		op.stats.Reset()
+	}
 }
 
 func NewTimedCounter(name string, delegate Counter) *TimedCounter {
 	return &TimedCounter{
 		name:     name,
 		delegate: delegate,
+		stats:    newTimingStats(),
+	}
+}
+
+// ForWorker returns the view of this counter a single goroutine should use, for WorkerLocal delegates that is a
+// TimedCounter over the worker's own slot sharing this counter's stats, for everything else it is the counter itself
+func (c *TimedCounter) ForWorker(id int) *TimedCounter {
+	local, ok := c.delegate.(WorkerLocal)
+	if !ok {
+		return c
+	}
+	return &TimedCounter{
+		name:     c.name,
+		delegate: local.ForWorker(id),
+		stats:    c.stats,
 	}
 }
 
@@ -41,37 +149,122 @@ func (c *TimedCounter) Name() string {
 	return c.name
 }
 
+// IncrementBy times the delegate call itself, only the delegate sits between the two clock reads so the
+// bookkeeping cost is kept out of the measurement
 func (c *TimedCounter) IncrementBy(value int) {
 	start := time.Now()
-	c.totalOps.Add(1)
-	c.totalTimeNs.Add(time.Since(start).Nanoseconds())
 	c.delegate.IncrementBy(value)
+	c.stats.increments.Record(time.Since(start))
 }
 
 func (c *TimedCounter) DecrementBy(value int) {
 	start := time.Now()
-	c.totalOps.Add(1)
-	c.totalTimeNs.Add(time.Since(start).Nanoseconds())
 	c.delegate.DecrementBy(value)
+	c.stats.decrements.Record(time.Since(start))
 }
 
-// Value retrieves the current value from the underlying counter
-// Is not necessary to time or count these operations as they happen in the main thread after all operations are complete
+// Value retrieves the current value from the underlying counter, reads made by the workers are timed like any other
+// operation as their cost differs wildly between implementations
 func (c *TimedCounter) Value() int {
+	start := time.Now()
 	val := c.delegate.Value()
+	c.stats.reads.Record(time.Since(start))
 	return val
 }
 
+// FinalValue reads the underlying counter without recording the read, for reporting once the run is over
+func (c *TimedCounter) FinalValue() int {
+	return c.delegate.Value()
+}
+
+// Snapshot reads the underlying counter without recording the read, like FinalValue
+func (c *TimedCounter) Snapshot() Snapshot {
+	return c.delegate.Snapshot()
+}
+
+// Reset zeroes the underlying counter along with every statistic recorded against it
+func (c *TimedCounter) Reset() {
+	c.delegate.Reset()
+	c.stats.reset()
+}
+
 func (c *TimedCounter) TotalTime() time.Duration {
-	return time.Duration(c.totalTimeNs.Load())
+	var total time.Duration
+	for _, op := range c.stats.byType() {
+		total += op.stats.Total()
+	}
+	return total
 }
 
 func (c *TimedCounter) TotalOps() int64 {
-	return c.totalOps.Load()
+	var total int64
+	for _, op := range c.stats.byType() {
+		total += op.stats.Count()
+	}
+	return total
+}
+
+// Flush pushes through any operations the delegate is still buffering, the time it takes is not recorded as it is
+// paid once per worker rather than per operation
+func (c *TimedCounter) Flush() {
+	if flusher, ok := c.delegate.(Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// DelegateType is the name of the wrapped implementation's type, MutexCounter for example
+func (c *TimedCounter) DelegateType() string {
+	t := reflect.TypeOf(c.delegate)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// LiveValue returns the current value while the run is still going, false when the delegate can't be read mid-run
+func (c *TimedCounter) LiveValue() (int, bool) {
+	if _, ok := c.delegate.(FinalValueOnly); ok {
+		return 0, false
+	}
+	return c.delegate.Value(), true
+}
+
+// Drain settles the delegate if it buffers operations, returning its final value
+func (c *TimedCounter) Drain() int {
+	if drainer, ok := c.delegate.(Drainer); ok {
+		return drainer.Drain()
+	}
+	return c.delegate.Value()
+}
+
+// Report returns the delegate's own statistics, if it keeps any
+func (c *TimedCounter) Report() string {
+	if reporter, ok := c.delegate.(Reporter); ok {
+		return reporter.Report()
+	}
+	return ""
+}
+
+// Percentile returns the latency below which the given fraction of all operations, of every type, completed
+func (c *TimedCounter) Percentile(q float64) time.Duration {
+	return bench.MergedPercentile(q, &c.stats.increments, &c.stats.decrements, &c.stats.reads)
+}
+
+func (c *TimedCounter) Increments() *bench.Stats {
+	return &c.stats.increments
+}
+
+func (c *TimedCounter) Decrements() *bench.Stats {
+	return &c.stats.decrements
+}
+
+func (c *TimedCounter) Reads() *bench.Stats {
+	return &c.stats.reads
 }
 
+// MutexCounter guards its count with an exclusive lock, readers wait on each other just as writers do
 type MutexCounter struct {
-	mu    sync.RWMutex
+	mu    sync.Mutex
 	count int
 }
 
@@ -88,11 +281,57 @@ func (c *MutexCounter) DecrementBy(value int) {
 }
 
 func (c *MutexCounter) Value() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count
+}
+
+func (c *MutexCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+func (c *MutexCounter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count = 0
+}
+
+// RWMutexCounter lets any number of readers hold the lock at once, only writers need it exclusively
+// The bookkeeping that makes that possible costs more than a plain mutex, so it only pays off when reads dominate
+// and readers actually overlap
+type RWMutexCounter struct {
+	mu    sync.RWMutex
+	count int
+}
+
+func (c *RWMutexCounter) IncrementBy(value int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count += value
+}
+
+func (c *RWMutexCounter) DecrementBy(value int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count -= value
+}
+
+func (c *RWMutexCounter) Value() int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return c.count
 }
 
+func (c *RWMutexCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+func (c *RWMutexCounter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count = 0
+}
+
 type ThreadUnsafeCounter struct {
 	count int
 }
@@ -109,6 +348,14 @@ func (c *ThreadUnsafeCounter) Value() int {
 	return c.count
 }
 
+func (c *ThreadUnsafeCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+func (c *ThreadUnsafeCounter) Reset() {
+	c.count = 0
+}
+
 type AtomicIntCounter struct {
 	count atomic.Int32
 }
@@ -125,123 +372,2187 @@ func (c *AtomicIntCounter) Value() int {
 	return int(c.count.Load())
 }
 
-type ChannelCounter struct {
-	ctx            context.Context
-	increments     chan int
-	decrements     chan int
-	valueRetrieval chan chan int
-	count          int
+func (c *AtomicIntCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+func (c *AtomicIntCounter) Reset() {
+	c.count.Store(0)
+}
+
+// AtomicInt64Counter is AtomicIntCounter with room to grow, an int32 wraps past 2,147,483,647 which a long enough
+// run of increments will eventually reach, an int64 won't in any realistic workload
+type AtomicInt64Counter struct {
+	count atomic.Int64
+}
+
+func (c *AtomicInt64Counter) IncrementBy(value int) {
+	c.count.Add(int64(value))
+}
+
+func (c *AtomicInt64Counter) DecrementBy(value int) {
+	c.count.Add(int64(-value))
+}
+
+func (c *AtomicInt64Counter) Value() int {
+	return int(c.count.Load())
+}
+
+func (c *AtomicInt64Counter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+func (c *AtomicInt64Counter) Reset() {
+	c.count.Store(0)
+}
+
+// OverflowCheckedCounter keeps the int32 storage of AtomicIntCounter but notices when a change wraps around
+// atomic.Add can't tell us the value it replaced, so changes go through a compare and swap loop, which knows both the
+// old and new value and can spot an increment that made the count smaller or a decrement that made it larger
+type OverflowCheckedCounter struct {
+	count     atomic.Int32
+	overflows atomic.Int64
 }
 
-func CreateAndRunChannelCounter(ctx context.Context) *ChannelCounter {
-	c := &ChannelCounter{
-		ctx:            ctx,
-		increments:     make(chan int, 64),
-		decrements:     make(chan int, 64),
-		valueRetrieval: make(chan chan int),
+func (c *OverflowCheckedCounter) add(delta int32) {
+	for {
+		current := c.count.Load()
+		next := current + delta
+		if c.count.CompareAndSwap(current, next) {
+			if (delta > 0 && next < current) || (delta < 0 && next > current) {
+				c.overflows.Add(1)
+			}
+			return
+		}
 	}
-	go c.run()
-	return c
 }
 
-func (c *ChannelCounter) run() {
+func (c *OverflowCheckedCounter) IncrementBy(value int) {
+	c.add(int32(value))
+}
+
+func (c *OverflowCheckedCounter) DecrementBy(value int) {
+	c.add(int32(-value))
+}
+
+func (c *OverflowCheckedCounter) Value() int {
+	return int(c.count.Load())
+}
+
+func (c *OverflowCheckedCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+func (c *OverflowCheckedCounter) Reset() {
+	c.count.Store(0)
+	c.overflows.Store(0)
+}
+
+func (c *OverflowCheckedCounter) Report() string {
+	return fmt.Sprintf("int32 overflows: %d", c.overflows.Load())
+}
+
+// SpinLockCounter guards its count with a hand rolled spin lock, a goroutine that loses the compare and swap keeps
+// retrying on its CPU instead of being parked by the scheduler the way a sync.Mutex waiter eventually is
+// That's cheap when the lock is held briefly and rarely contended, and burns CPU doing nothing useful when it isn't
+type SpinLockCounter struct {
+	locked atomic.Bool
+	count  int
+}
+
+// spinsBeforeYield bounds how long a waiter spins before handing its CPU back, without it a waiter could spin
+// through its whole time slice while the lock holder sits descheduled
+const spinsBeforeYield = 64
+
+func (c *SpinLockCounter) lock() {
+	for spins := 1; !c.locked.CompareAndSwap(false, true); spins++ {
+		if spins%spinsBeforeYield == 0 {
+			runtime.Gosched()
+		}
+	}
+}
+
+func (c *SpinLockCounter) unlock() {
+	c.locked.Store(false)
+}
+
+func (c *SpinLockCounter) IncrementBy(value int) {
+	c.lock()
+	defer c.unlock()
+	c.count += value
+}
+
+func (c *SpinLockCounter) DecrementBy(value int) {
+	c.lock()
+	defer c.unlock()
+	c.count -= value
+}
+
+func (c *SpinLockCounter) Value() int {
+	c.lock()
+	defer c.unlock()
+	return c.count
+}
+
+func (c *SpinLockCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+func (c *SpinLockCounter) Reset() {
+	c.lock()
+	defer c.unlock()
+	c.count = 0
+}
+
+// CASCounter applies every change with an explicit load, compute, compare and swap loop, the same optimistic
+// strategy atomic.Add uses in hardware, but spelled out so the number of times a goroutine lost the race and had to
+// start over can be counted, under heavy contention most of the work done is retries
+type CASCounter struct {
+	count   atomic.Int64
+	retries atomic.Int64
+}
+
+func (c *CASCounter) add(delta int64) {
 	for {
-		select {
-		case v := <-c.increments:
-			c.count += v
-		case v := <-c.decrements:
-			c.count -= v
-		case reply := <-c.valueRetrieval:
-			reply <- c.count
-		case <-c.ctx.Done():
+		current := c.count.Load()
+		if c.count.CompareAndSwap(current, current+delta) {
 			return
 		}
+		c.retries.Add(1)
+	}
+}
+
+func (c *CASCounter) IncrementBy(value int) {
+	c.add(int64(value))
+}
+
+func (c *CASCounter) DecrementBy(value int) {
+	c.add(int64(-value))
+}
+
+func (c *CASCounter) Value() int {
+	return int(c.count.Load())
+}
+
+func (c *CASCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+func (c *CASCounter) Reset() {
+	c.count.Store(0)
+	c.retries.Store(0)
+}
+
+func (c *CASCounter) Report() string {
+	return fmt.Sprintf("compare and swap retries: %d", c.retries.Load())
+}
+
+// SemaphoreCounter admits at most a fixed number of operations into the counter at once, the rest queue on a weighted
+// semaphore until a slot frees up
+// With a limit of one it behaves like a mutex, raising the limit lets more operations through together, which is how
+// access to anything that only copes with so much concurrent load, a database or a remote API, is bounded in practice
+// The count itself is atomic since several operations can be inside at the same time
+type SemaphoreCounter struct {
+	sem      *semaphore.Weighted
+	limit    int64
+	count    atomic.Int64
+	inFlight atomic.Int64
+	peak     atomic.Int64
+	admitted atomic.Int64
+	waited   atomic.Int64
+}
+
+func NewSemaphoreCounter(maxInFlight int) *SemaphoreCounter {
+	return &SemaphoreCounter{
+		sem:   semaphore.NewWeighted(int64(maxInFlight)),
+		limit: int64(maxInFlight),
+	}
+}
+
+// enter takes a slot, counting the operations that found every slot taken and had to wait for one
+// Every holder releases its slot promptly, so the wait is never abandoned and needs no context
+func (c *SemaphoreCounter) enter() {
+	if !c.sem.TryAcquire(1) {
+		c.waited.Add(1)
+		c.sem.Acquire(context.Background(), 1)
+	}
+	c.admitted.Add(1)
+
+	inFlight := c.inFlight.Add(1)
+	for {
+		peak := c.peak.Load()
+		if inFlight <= peak || c.peak.CompareAndSwap(peak, inFlight) {
+			break
+		}
 	}
 }
 
-func (c *ChannelCounter) IncrementBy(value int) {
-	select {
-	case c.increments <- value:
-	case <-c.ctx.Done():
+func (c *SemaphoreCounter) leave() {
+	c.inFlight.Add(-1)
+	c.sem.Release(1)
+}
+
+func (c *SemaphoreCounter) IncrementBy(value int) {
+	c.enter()
+	defer c.leave()
+	c.count.Add(int64(value))
+}
+
+func (c *SemaphoreCounter) DecrementBy(value int) {
+	c.enter()
+	defer c.leave()
+	c.count.Add(int64(-value))
+}
+
+func (c *SemaphoreCounter) Value() int {
+	c.enter()
+	defer c.leave()
+	return int(c.count.Load())
+}
+
+func (c *SemaphoreCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+// Reset zeroes the count while holding a slot like any other operation, the admission statistics are cleared after
+// the slot is given back so the reset itself isn't counted
+func (c *SemaphoreCounter) Reset() {
+	c.enter()
+	c.count.Store(0)
+	c.leave()
+
+	c.peak.Store(0)
+	c.admitted.Store(0)
+	c.waited.Store(0)
+}
+
+func (c *SemaphoreCounter) Report() string {
+	admitted := c.admitted.Load()
+	waitedPercent := 0.0
+	if admitted > 0 {
+		waitedPercent = 100 * float64(c.waited.Load()) / float64(admitted)
 	}
+	return fmt.Sprintf("at most %d in flight, peak %d, %d of %d operations (%.1f%%) waited for a slot",
+		c.limit, c.peak.Load(), c.waited.Load(), admitted, waitedPercent)
+}
+
+// ShardedCounter splits the count across several independently locked shards, each operation picks a random
+// shard so concurrent writers rarely compete for the same lock, at the cost of Value() having to visit every shard
+type ShardedCounter struct {
+	shards []counterShard
 }
 
-func (c *ChannelCounter) DecrementBy(value int) {
-	select {
-	case c.decrements <- value:
-	case <-c.ctx.Done():
+type counterShard struct {
+	mu    sync.Mutex
+	count int
+}
+
+func NewShardedCounter(shards int) *ShardedCounter {
+	return &ShardedCounter{
+		shards: make([]counterShard, shards),
 	}
 }
 
-func (c *ChannelCounter) Value() int {
-	reply := make(chan int)
-	select {
-	case c.valueRetrieval <- reply:
-		return <-reply
-	case <-c.ctx.Done():
-		return c.count
+func (c *ShardedCounter) shard() *counterShard {
+	return &c.shards[rand.Intn(len(c.shards))]
+}
+
+func (c *ShardedCounter) IncrementBy(value int) {
+	s := c.shard()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count += value
+}
+
+func (c *ShardedCounter) DecrementBy(value int) {
+	s := c.shard()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count -= value
+}
+
+func (c *ShardedCounter) Value() int {
+	total := 0
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		total += s.count
+		s.mu.Unlock()
 	}
+	return total
 }
 
-func main() {
+func (c *ShardedCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
 
-	numRoutines := flag.Int("routines", 100, "the number of routines to run")
-	numLoopPerRoutine := flag.Int("loops", 10000, "the number of loops or iterations to run per routine")
+func (c *ShardedCounter) Reset() {
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		s.count = 0
+		s.mu.Unlock()
+	}
+}
 
-	flag.Parse()
+// AdjacentShardedCounter gives every goroutine a shard of its own, shards are plain atomics packed next to each other
+// Although no two goroutines ever touch the same shard, eight int64 shards share each 64 byte cache line, and CPUs keep
+// caches coherent a whole line at a time, so every write still invalidates the line in every other core's cache, this
+// is false sharing, the goroutines contend as if they were sharing a single counter
+type AdjacentShardedCounter struct {
+	shards []atomic.Int64
+}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer cancel()
+func NewAdjacentShardedCounter(shards int) *AdjacentShardedCounter {
+	return &AdjacentShardedCounter{
+		shards: make([]atomic.Int64, shards),
+	}
+}
 
-	counters := []*TimedCounter{}
-	counters = append(counters,
-		NewTimedCounter("Mutex", &MutexCounter{}),
-		NewTimedCounter("Unsafe", &ThreadUnsafeCounter{}),
-		NewTimedCounter("AtomicInt", &AtomicIntCounter{}),
-		NewTimedCounter("Channel and worker", CreateAndRunChannelCounter(ctx)))
+func (c *AdjacentShardedCounter) ForWorker(id int) Counter {
+	return &atomicShardHandle{shard: &c.shards[id%len(c.shards)], total: c.Value, reset: c.Reset}
+}
 
-	var wg sync.WaitGroup
+func (c *AdjacentShardedCounter) IncrementBy(value int) {
+	c.shards[0].Add(int64(value))
+}
 
-	// iterate through the number of configured go routines to spin up
-	for i := 0; i < *numRoutines; i++ {
+func (c *AdjacentShardedCounter) DecrementBy(value int) {
+	c.shards[0].Add(int64(-value))
+}
 
-		// place the async func into a wait group directly
-		wg.Go(func() {
+func (c *AdjacentShardedCounter) Value() int {
+	total := int64(0)
+	for i := range c.shards {
+		total += c.shards[i].Load()
+	}
+	return int(total)
+}
 
-			// iterate through the number of loops per routine
-			for i := 0; i < *numLoopPerRoutine; i++ {
+func (c *AdjacentShardedCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
 
-				// check for context cancellation
-				select {
-				case <-ctx.Done():
-					return
-				default:
-				}
+func (c *AdjacentShardedCounter) Reset() {
+	for i := range c.shards {
+		c.shards[i].Store(0)
+	}
+}
 
-				// randomly select an operation
-				switch rand.Intn(2) + 1 {
-				case 1:
-					randValue := rand.Intn(5)
-					for _, counter := range counters {
-						counter.DecrementBy(randValue)
-					}
-				case 2:
-					randValue := rand.Intn(5)
-					for _, counter := range counters {
-						counter.IncrementBy(randValue)
-					}
-				}
-			}
+// PaddedShardedCounter is AdjacentShardedCounter with every shard padded out to fill its own cache line, the only
+// difference between the two is memory layout, so any difference in their results is the cost of false sharing
+type PaddedShardedCounter struct {
+	shards []paddedInt64
+}
 
-		})
+// paddedInt64 fills a 64 byte cache line, the most common size on x86-64 and arm64
+type paddedInt64 struct {
+	atomic.Int64
+	_ [56]byte
+}
+
+func NewPaddedShardedCounter(shards int) *PaddedShardedCounter {
+	return &PaddedShardedCounter{
+		shards: make([]paddedInt64, shards),
 	}
+}
 
-	// block the main thread until all routines complete
-	// if we don't do this, the main thread may exit before any of the routines start, honestly, and definitely before they complete
-	wg.Wait()
+func (c *PaddedShardedCounter) ForWorker(id int) Counter {
+	return &atomicShardHandle{shard: &c.shards[id%len(c.shards)].Int64, total: c.Value, reset: c.Reset}
+}
 
-	// range through the counters and get their final values and stats
-	for _, counter := range counters {
-		fmt.Printf("%s value is %d with a collective operation count of %v and processing time of %v\n", counter.Name(), counter.Value(), counter.TotalOps(), counter.TotalTime())
+func (c *PaddedShardedCounter) IncrementBy(value int) {
+	c.shards[0].Add(int64(value))
+}
+
+func (c *PaddedShardedCounter) DecrementBy(value int) {
+	c.shards[0].Add(int64(-value))
+}
+
+func (c *PaddedShardedCounter) Value() int {
+	total := int64(0)
+	for i := range c.shards {
+		total += c.shards[i].Load()
+	}
+	return int(total)
+}
+
+func (c *PaddedShardedCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+func (c *PaddedShardedCounter) Reset() {
+	for i := range c.shards {
+		c.shards[i].Store(0)
+	}
+}
+
+// atomicShardHandle is a goroutine's view of a single shard, several goroutines may share a shard when there are more
+// of them than shards, so it is still updated atomically
+type atomicShardHandle struct {
+	shard *atomic.Int64
+	total func() int
+	reset func()
+}
+
+func (h *atomicShardHandle) IncrementBy(value int) {
+	h.shard.Add(int64(value))
+}
+
+func (h *atomicShardHandle) DecrementBy(value int) {
+	h.shard.Add(int64(-value))
+}
+
+func (h *atomicShardHandle) Value() int {
+	return h.total()
+}
+
+func (h *atomicShardHandle) Snapshot() Snapshot {
+	return newSnapshot(h.Value())
+}
+
+func (h *atomicShardHandle) Reset() {
+	h.reset()
+}
+
+// LocalCounter gives every goroutine its own slot to accumulate into with no synchronization at all, the slots are
+// only reduced into a single total when Value() is called, so it is only correct to read once every worker has finished
+// This is the fastest possible strategy whenever intermediate reads aren't needed
+type LocalCounter struct {
+	slots  []localSlot
+	shared atomic.Int64
+}
+
+// localSlot is padded out to a full cache line so neighbouring workers don't invalidate each other's caches
+type localSlot struct {
+	count int
+	_     [56]byte
+}
+
+func NewLocalCounter(workers int) *LocalCounter {
+	return &LocalCounter{
+		slots: make([]localSlot, workers),
+	}
+}
+
+func (c *LocalCounter) ForWorker(id int) Counter {
+	return &c.slots[id]
+}
+
+// IncrementBy is only used by callers that don't have a worker slot, it falls back to a shared atomic
+func (c *LocalCounter) IncrementBy(value int) {
+	c.shared.Add(int64(value))
+}
+
+// DecrementBy is only used by callers that don't have a worker slot, it falls back to a shared atomic
+func (c *LocalCounter) DecrementBy(value int) {
+	c.shared.Add(int64(-value))
+}
+
+// FinalValueOnly marks the counter as unreadable mid-run, the slots are written without any synchronization
+func (c *LocalCounter) FinalValueOnly() {}
+
+// Value reduces every worker slot into the final total
+func (c *LocalCounter) Value() int {
+	total := int(c.shared.Load())
+	for i := range c.slots {
+		total += c.slots[i].count
+	}
+	return total
+}
+
+func (c *LocalCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+// Reset clears every worker slot, like Value it is only safe once every worker has finished
+func (c *LocalCounter) Reset() {
+	for i := range c.slots {
+		c.slots[i].count = 0
+	}
+	c.shared.Store(0)
+}
+
+func (s *localSlot) IncrementBy(value int) {
+	s.count += value
+}
+
+func (s *localSlot) DecrementBy(value int) {
+	s.count -= value
+}
+
+func (s *localSlot) Value() int {
+	return s.count
+}
+
+func (s *localSlot) Snapshot() Snapshot {
+	return newSnapshot(s.Value())
+}
+
+func (s *localSlot) Reset() {
+	s.count = 0
+}
+
+// KeyedCounter defines the common contract for counters that track many independent keys, the single key counters
+// above hide the design space where a map's own synchronization, not the count's, becomes the bottleneck
+type KeyedCounter interface {
+	IncrementBy(key string, value int)
+	DecrementBy(key string, value int)
+	Value(key string) int
+}
+
+// MutexMapCounter is the straightforward approach, one map and one lock for every key
+type MutexMapCounter struct {
+	mu     sync.RWMutex
+	counts map[string]int
+}
+
+func NewMutexMapCounter() *MutexMapCounter {
+	return &MutexMapCounter{
+		counts: map[string]int{},
+	}
+}
+
+func (c *MutexMapCounter) IncrementBy(key string, value int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[key] += value
+}
+
+func (c *MutexMapCounter) DecrementBy(key string, value int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[key] -= value
+}
+
+func (c *MutexMapCounter) Value(key string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.counts[key]
+}
+
+// SyncMapCounter stores an atomic per key in a sync.Map
+// sync.Map is built for keys that are written once and read many times, after the first LoadOrStore each key's
+// atomic is found with a lock free read, so goroutines working on different keys never contend at all
+type SyncMapCounter struct {
+	counts sync.Map
+}
+
+func (c *SyncMapCounter) counter(key string) *atomic.Int64 {
+	if existing, ok := c.counts.Load(key); ok {
+		return existing.(*atomic.Int64)
+	}
+	actual, _ := c.counts.LoadOrStore(key, &atomic.Int64{})
+	return actual.(*atomic.Int64)
+}
+
+func (c *SyncMapCounter) IncrementBy(key string, value int) {
+	c.counter(key).Add(int64(value))
+}
+
+func (c *SyncMapCounter) DecrementBy(key string, value int) {
+	c.counter(key).Add(int64(-value))
+}
+
+func (c *SyncMapCounter) Value(key string) int {
+	return int(c.counter(key).Load())
+}
+
+// ShardedMapCounter spreads keys over several independently locked maps by hash, so only goroutines whose keys
+// land in the same shard ever wait on each other
+type ShardedMapCounter struct {
+	shards []mapShard
+}
+
+type mapShard struct {
+	mu     sync.RWMutex
+	counts map[string]int
+}
+
+func NewShardedMapCounter(shards int) *ShardedMapCounter {
+	c := &ShardedMapCounter{
+		shards: make([]mapShard, shards),
+	}
+	for i := range c.shards {
+		c.shards[i].counts = map[string]int{}
+	}
+	return c
+}
+
+func (c *ShardedMapCounter) shard(key string) *mapShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &c.shards[h.Sum32()%uint32(len(c.shards))]
+}
+
+func (c *ShardedMapCounter) IncrementBy(key string, value int) {
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[key] += value
+}
+
+func (c *ShardedMapCounter) DecrementBy(key string, value int) {
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[key] -= value
+}
+
+func (c *ShardedMapCounter) Value(key string) int {
+	s := c.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.counts[key]
+}
+
+// TimedKeyedCounter is the TimedCounter decorator for keyed counters
+type TimedKeyedCounter struct {
+	name     string
+	delegate KeyedCounter
+	stats    *timingStats
+}
+
+func NewTimedKeyedCounter(name string, delegate KeyedCounter) *TimedKeyedCounter {
+	return &TimedKeyedCounter{
+		name:     name,
+		delegate: delegate,
+		stats:    newTimingStats(),
+	}
+}
+
+func (c *TimedKeyedCounter) IncrementBy(key string, value int) {
+	start := time.Now()
+	c.delegate.IncrementBy(key, value)
+	c.stats.increments.Record(time.Since(start))
+}
+
+func (c *TimedKeyedCounter) DecrementBy(key string, value int) {
+	start := time.Now()
+	c.delegate.DecrementBy(key, value)
+	c.stats.decrements.Record(time.Since(start))
+}
+
+func (c *TimedKeyedCounter) Value(key string) int {
+	start := time.Now()
+	val := c.delegate.Value(key)
+	c.stats.reads.Record(time.Since(start))
+	return val
+}
+
+// runKeyedBenchmark repeats the single counter workload with every operation aimed at a random key, then checks every
+// key of every implementation against a reference count
+func runKeyedBenchmark(ctx context.Context, numKeys, numShards int, workload Workload) {
+	keys := make([]string, numKeys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	counters := []*TimedKeyedCounter{
+		NewTimedKeyedCounter("Map and mutex", NewMutexMapCounter()),
+		NewTimedKeyedCounter("sync.Map", &SyncMapCounter{}),
+		NewTimedKeyedCounter("Sharded map", NewShardedMapCounter(numShards)),
+	}
+	reference := make([]atomic.Int64, numKeys)
+	schedule := workload.Schedule(numKeys)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for _, ops := range schedule {
+		wg.Go(func() {
+			for _, op := range ops {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+
+				key := keys[op.key]
+				switch op.kind {
+				case OpDecrement:
+					reference[op.key].Add(int64(-op.value))
+					for _, counter := range counters {
+						counter.DecrementBy(key, op.value)
+					}
+				case OpIncrement:
+					reference[op.key].Add(int64(op.value))
+					for _, counter := range counters {
+						counter.IncrementBy(key, op.value)
+					}
+				case OpValue:
+					for _, counter := range counters {
+						counter.Value(key)
+					}
+				}
+			}
+		})
+	}
+	wg.Wait()
+	wallClock := time.Since(start)
+
+	fmt.Printf("Ran %d routines across %d keys, picked %s, in %v\n", workload.Routines, numKeys,
+		cmp.Or(workload.KeyDistribution, "uniform"), wallClock)
+	for _, counter := range counters {
+		wrongKeys := 0
+		for i, key := range keys {
+			if counter.delegate.Value(key) != int(reference[i].Load()) {
+				wrongKeys++
+			}
+		}
+
+		result := "all keys correct"
+		if wrongKeys > 0 {
+			result = fmt.Sprintf("WRONG on %d keys", wrongKeys)
+		}
+		var ops int64
+		var total time.Duration
+		for _, op := range counter.stats.byType() {
+			ops += op.stats.Count()
+			total += op.stats.Total()
+		}
+		fmt.Printf("%s %s with a collective operation count of %v and processing time of %v\n", counter.name, result, ops, total)
+		printOpStats(counter.stats)
+	}
+}
+
+// Workload describes the operations each routine applies to the counters
+type Workload struct {
+	Routines        int
+	LoopsPerRoutine int
+
+	// ReadRatio is the fraction of operations that read the value rather than change it
+	ReadRatio float64
+
+	// IncrementRatio is the fraction of the remaining, writing, operations that increment rather than decrement
+	IncrementRatio float64
+
+	// ValueRange bounds the amount of each increment or decrement, values are drawn from [0, ValueRange)
+	ValueRange int
+
+	// Seed makes the generated schedule, and so the whole run, reproducible
+	Seed int64
+
+	// Orchestration picks how the routines are run and waited for, "waitgroup" or "errgroup"
+	Orchestration string
+
+	// FailAfter makes the first routine fail after this many operations, zero never fails, it shows how each
+	// orchestration reacts to one routine going wrong
+	FailAfter int
+
+	// ThinkTime is how long each routine rests between operations, modelling work done away from the counter, with
+	// Jitter adding up to that much random extra to each rest so the routines drift out of step
+	ThinkTime time.Duration
+	Jitter    time.Duration
+
+	// Burst is how many operations a routine performs back to back before each rest, arrivals bunched up in bursts
+	// contend far more than the same number spread out evenly
+	Burst int
+
+	// KeyDistribution is how the multi-key benchmark picks each operation's key, a datagen distribution such as
+	// zipfian, where a few hot keys take most of the operations, uniform when empty
+	KeyDistribution string
+
+	// Warmup is how many operations each routine runs against the counters before the measured run, their effect on
+	// the counters and their stats is reset away, what remains is warm caches, a settled scheduler and grown buffers
+	Warmup int
+
+	// Events, when set, records every operation the run applies so its interleaving can be drawn afterwards
+	Events *EventLog
+
+	// replay, when set, is a recorded or already generated schedule to run instead of generating one from Seed
+	replay [][]scheduledOp
+}
+
+// scheduledOp is one pre-generated step of a routine's schedule, key is only used by the multi-key benchmark
+type scheduledOp struct {
+	kind  OperationKind
+	value int
+	key   int
+}
+
+// nextOperation picks the next operation at random according to the workload's mix
+func (w Workload) nextOperation(rng *rand.Rand) OperationKind {
+	if rng.Float64() < w.ReadRatio {
+		return OpValue
+	}
+	if rng.Float64() < w.IncrementRatio {
+		return OpIncrement
+	}
+	return OpDecrement
+}
+
+// Schedule pre-generates every operation each routine will perform before any timing starts
+// Drawing the operations up front means every counter is handed exactly the same sequence, random number generation
+// stays out of the measurements, and the same seed replays the same run
+// Each routine gets its own generator seeded from the master one so its schedule doesn't depend on the others
+// A workload replaying a recording hands back the recorded schedule unchanged
+func (w Workload) Schedule(numKeys int) [][]scheduledOp {
+	if w.replay != nil {
+		return w.replay
+	}
+
+	// the key distribution was checked when the flags were, and a uniform source draws just as rng.Intn did, so
+	// seeds recorded before the distributions existed still produce the same schedule
+	values := datagen.MustParse("uniform", w.ValueRange)
+	var keys datagen.Distribution
+	if numKeys > 0 {
+		keys = datagen.MustParse(w.KeyDistribution, numKeys)
+	}
+
+	master := rand.New(rand.NewSource(w.Seed))
+	schedule := make([][]scheduledOp, w.Routines)
+	for i := range schedule {
+		rng := rand.New(rand.NewSource(master.Int63()))
+		valueSource, keySource := values.Source(rng), keys.Source(rng)
+		ops := make([]scheduledOp, w.LoopsPerRoutine)
+		for j := range ops {
+			ops[j].kind = w.nextOperation(rng)
+			if ops[j].kind != OpValue {
+				ops[j].value = valueSource.Next()
+			}
+			if numKeys > 0 {
+				ops[j].key = keySource.Next()
+			}
+		}
+		schedule[i] = ops
+	}
+	return schedule
+}
+
+// CounterConfig carries the run settings a counter implementation may need when it is constructed
+type CounterConfig struct {
+	Ctx       context.Context
+	Routines  int
+	Shards    int
+	BatchSize int
+	// MaxInFlight bounds how many operations the semaphore counter lets in at once
+	MaxInFlight int
+	// ChannelBuffer is the size of the channel counter's increment and decrement buffers, and the worker pool's queue
+	ChannelBuffer int
+	// PoolSize is the number of workers in the worker pool counter
+	PoolSize int
+	// Stripes is the number of locks the striped counter spreads goroutines over
+	Stripes int
+}
+
+// CounterFactory builds a fresh, zeroed counter for a run
+type CounterFactory func(cfg CounterConfig) Counter
+
+// registeredCounter pairs a counter's name with the factory that builds it
+type registeredCounter struct {
+	name    string
+	factory CounterFactory
+}
+
+// counterRegistry lists every counter implementation in registration order, which is also report order
+var counterRegistry []registeredCounter
+
+// RegisterCounter makes a counter implementation available to the benchmark and the report under the given name,
+// which is also what -counters selects it by
+// It is meant to be called from an init function, so a new implementation, a student's submission for example, only
+// needs its own file in this package to be picked up, like database/sql drivers registering themselves
+// Registering the same name twice panics, as that is always a programming mistake
+func RegisterCounter(name string, factory CounterFactory) {
+	for _, registered := range counterRegistry {
+		if strings.EqualFold(registered.name, name) {
+			panic(fmt.Sprintf("counter %q registered twice", name))
+		}
+	}
+	counterRegistry = append(counterRegistry, registeredCounter{name: name, factory: factory})
+}
+
+func init() {
+	RegisterCounter("Mutex", func(cfg CounterConfig) Counter { return &MutexCounter{} })
+	RegisterCounter("RWMutex", func(cfg CounterConfig) Counter { return &RWMutexCounter{} })
+	RegisterCounter("Unsafe", func(cfg CounterConfig) Counter { return &ThreadUnsafeCounter{} })
+	RegisterCounter("AtomicInt", func(cfg CounterConfig) Counter { return &AtomicIntCounter{} })
+	RegisterCounter("AtomicInt64", func(cfg CounterConfig) Counter { return &AtomicInt64Counter{} })
+	RegisterCounter("OverflowChecked", func(cfg CounterConfig) Counter { return &OverflowCheckedCounter{} })
+	RegisterCounter("SpinLock", func(cfg CounterConfig) Counter { return &SpinLockCounter{} })
+	RegisterCounter("CAS", func(cfg CounterConfig) Counter { return &CASCounter{} })
+	RegisterCounter("GenericMutex", func(cfg CounterConfig) Counter { return intCounter[int64]{&MutexCounterOf[int64]{}} })
+	RegisterCounter("AtomicFloat64", func(cfg CounterConfig) Counter { return intCounter[float64]{&AtomicCounterOf[float64]{}} })
+	RegisterCounter("Cond", func(cfg CounterConfig) Counter { return NewCondCounter() })
+	RegisterCounter("Semaphore", func(cfg CounterConfig) Counter { return NewSemaphoreCounter(cfg.MaxInFlight) })
+	RegisterCounter("Sharded", func(cfg CounterConfig) Counter { return NewShardedCounter(cfg.Shards) })
+	RegisterCounter("Striped", func(cfg CounterConfig) Counter { return NewStripedCounter(cfg.Stripes) })
+	RegisterCounter("AdjacentShards", func(cfg CounterConfig) Counter { return NewAdjacentShardedCounter(cfg.Shards) })
+	RegisterCounter("PaddedShards", func(cfg CounterConfig) Counter { return NewPaddedShardedCounter(cfg.Shards) })
+	RegisterCounter("Local", func(cfg CounterConfig) Counter { return NewLocalCounter(cfg.Routines) })
+	RegisterCounter("Channel", func(cfg CounterConfig) Counter { return CreateAndRunChannelCounter(cfg.Ctx, cfg.ChannelBuffer) })
+	RegisterCounter("WorkerPool", func(cfg CounterConfig) Counter {
+		return CreateAndRunWorkerPoolCounter(cfg.Ctx, cfg.PoolSize, cfg.ChannelBuffer)
+	})
+	RegisterCounter("UnboundedChannel", func(cfg CounterConfig) Counter { return CreateAndRunUnboundedChannelCounter(cfg.Ctx) })
+	RegisterCounter("Actor", func(cfg CounterConfig) Counter { return CreateAndRunActorCounter(cfg.Ctx) })
+	RegisterCounter("BatchedChannel", func(cfg CounterConfig) Counter {
+		return CreateAndRunBatchedChannelCounter(cfg.Ctx, cfg.BatchSize)
+	})
+	RegisterCounter("FlatCombining", func(cfg CounterConfig) Counter { return NewFlatCombiningCounter(cfg.Routines) })
+}
+
+// counterNames lists the names of every registered counter
+func counterNames() []string {
+	names := []string{}
+	for _, registered := range counterRegistry {
+		names = append(names, registered.name)
+	}
+	return names
+}
+
+// parseCounterSelection turns the comma separated -counters value into names, an empty value selects every counter
+// Names are matched case-insensitively and an unknown name is an error listing the valid ones
+func parseCounterSelection(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return counterNames(), nil
+	}
+
+	selected := []string{}
+	for _, requested := range strings.Split(value, ",") {
+		requested = strings.TrimSpace(requested)
+		found := false
+		for _, name := range counterNames() {
+			if strings.EqualFold(requested, name) {
+				selected = append(selected, name)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown counter %q, available counters are %s", requested, strings.Join(counterNames(), ", "))
+		}
+	}
+	return selected, nil
+}
+
+// newCounters builds a fresh, zeroed set of the selected counter implementations wrapped for timing
+func newCounters(cfg CounterConfig, selected []string) []*TimedCounter {
+	counters := []*TimedCounter{}
+	for _, registered := range counterRegistry {
+		if slices.Contains(selected, registered.name) {
+			counters = append(counters, NewTimedCounter(registered.name, registered.factory(cfg)))
+		}
+	}
+	return counters
+}
+
+// warmUp runs the workload's warm-up operations, a fresh schedule of its own that is neither failed nor logged, then
+// resets every counter, TimedCounter.Reset clears its stats along with the count so none of the warm-up is measured
+func warmUp(ctx context.Context, counters []*TimedCounter, workload Workload) error {
+	warmup := workload
+	warmup.LoopsPerRoutine = workload.Warmup
+	warmup.Warmup = 0
+	warmup.FailAfter = 0
+	warmup.Events = nil
+	warmup.replay = nil
+	if _, _, err := runWorkload(ctx, counters, warmup); err != nil {
+		return fmt.Errorf("warm-up: %w", err)
+	}
+
+	for _, counter := range counters {
+		counter.Reset()
+	}
+	return nil
+}
+
+// errWorkerFailed is the failure injected by Workload.FailAfter
+var errWorkerFailed = errors.New("worker failed")
+
+// errCancelAfter is the cause recorded when -cancel-after cancels the run
+var errCancelAfter = errors.New("canceled by -cancel-after")
+
+// applyOperation applies a single scheduled operation to a counter
+func applyOperation(counter *TimedCounter, op scheduledOp) {
+	switch op.kind {
+	case OpDecrement:
+		counter.DecrementBy(op.value)
+	case OpIncrement:
+		counter.IncrementBy(op.value)
+	case OpValue:
+		counter.Value()
+	}
+}
+
+// runWorkload spins up the routines, has each apply a random mix of increments and decrements to every counter and
+// blocks until they all finish, returning the wall clock time of the run and the value every counter should hold
+// The error is only ever set when the routines are orchestrated with errgroup, a WaitGroup has no way to carry one
+func runWorkload(ctx context.Context, counters []*TimedCounter, workload Workload) (time.Duration, int, error) {
+	if workload.Warmup > 0 {
+		if err := warmUp(ctx, counters, workload); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	schedule := workload.Schedule(0)
+	start := time.Now()
+	if workload.Events != nil {
+		workload.Events.begin(workload.Routines, start)
+	}
+
+	// the reference tracks the net of every operation handed out, independent of any counter under test
+	var reference atomic.Int64
+
+	// work is the body of routine i, it stops early when ctx is done or when it is the routine chosen to fail
+	work := func(ctx context.Context, i int, workerCounters []*TimedCounter) error {
+
+		// the rests are drawn as the routine goes rather than scheduled, they happen outside any timing
+		rng := rand.New(rand.NewSource(workload.Seed + int64(i)))
+
+		// push through anything the counters are still buffering once this routine is done, however it ends
+		defer func() {
+			for _, counter := range workerCounters {
+				counter.Flush()
+			}
+		}()
+
+		// iterate through the routine's schedule
+		for n, op := range schedule[i] {
+
+			// check for context cancellation
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+
+			if i == 0 && workload.FailAfter > 0 && n == workload.FailAfter {
+				return fmt.Errorf("routine %d after %d operations: %w", i, n, errWorkerFailed)
+			}
+
+			// apply the scheduled operation
+			switch op.kind {
+			case OpDecrement:
+				reference.Add(int64(-op.value))
+			case OpIncrement:
+				reference.Add(int64(op.value))
+			}
+			for _, counter := range workerCounters {
+				if workload.Events == nil {
+					applyOperation(counter, op)
+					continue
+				}
+				began := time.Now()
+				applyOperation(counter, op)
+				workload.Events.record(i, counter.Name(), op, began, time.Now())
+			}
+
+			if err := think(ctx, workload.pauseAfter(n, rng)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// each routine works against its own view of the counters so worker-local counters can hand out private slots
+	workerViews := make([][]*TimedCounter, workload.Routines)
+	for i := range workerViews {
+		workerViews[i] = make([]*TimedCounter, len(counters))
+		for j, counter := range counters {
+			workerViews[i][j] = counter.ForWorker(i)
+		}
+	}
+
+	var err error
+	switch workload.Orchestration {
+	case "errgroup":
+		// errgroup derives a context that is canceled the moment any routine returns an error, every sibling watches
+		// that context and stops, and Wait hands back the first error, structured error propagation in a few lines
+		group, groupCtx := errgroup.WithContext(ctx)
+		for i := 0; i < workload.Routines; i++ {
+			group.Go(func() error {
+				return work(groupCtx, i, workerViews[i])
+			})
+		}
+		err = group.Wait()
+
+		// the routines stopping because the caller canceled isn't a failure of the workload
+		if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+			err = nil
+		}
+	default:
+		var wg sync.WaitGroup
+
+		// iterate through the number of configured go routines to spin up
+		for i := 0; i < workload.Routines; i++ {
+
+			// place the async func into a wait group directly
+			// a WaitGroup only counts routines, so a failure has nowhere to go but a log line while its siblings carry on
+			wg.Go(func() {
+				if err := work(ctx, i, workerViews[i]); err != nil && ctx.Err() == nil {
+					slog.Warn("routine stopped", "routine", i, "err", err)
+				}
+			})
+		}
+
+		// block the main thread until all routines complete
+		// if we don't do this, the main thread may exit before any of the routines start, honestly, and definitely before they complete
+		wg.Wait()
+	}
+
+	// settle the counters that still have operations queued up, otherwise they'd be read before catching up
+	for _, counter := range counters {
+		counter.Drain()
+	}
+
+	return time.Since(start), int(reference.Load()), err
+}
+
+// reportProgress logs the progress of the run every interval until the returned stop function is called, each
+// counter's live value is its own attribute so a JSON log can be followed counter by counter
+// The ticker fires on its own schedule while the select also watches for the stop signal, so a slow status line never
+// holds up shutdown and shutdown never waits for the next tick
+func reportProgress(logger *slog.Logger, counters []*TimedCounter, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	start := time.Now()
+
+	go func() {
+		defer close(finished)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ops := int64(0)
+				values := []any{}
+				for _, counter := range counters {
+					ops += counter.TotalOps()
+					if value, ok := counter.LiveValue(); ok {
+						values = append(values, slog.Int(counter.Name(), value))
+					}
+				}
+				elapsed := time.Since(start)
+				logger.Info("progress", "elapsed", elapsed.Round(time.Second), "ops", ops,
+					"ops_per_sec", math.Round(float64(ops)/elapsed.Seconds()), slog.Group("values", values...))
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// dashboardBarWidth is the width of the longest throughput bar drawn by the dashboard
+const dashboardBarWidth = 40
+
+// runDashboard redraws a table of every counter's live value and throughput over itself every interval, until the
+// returned stop function is called, which draws one last frame with the final numbers
+// Drawing in place only needs two ANSI escape codes, one moving the cursor back up over the previous frame and one
+// clearing each line before it is rewritten
+func runDashboard(w io.Writer, counters []*TimedCounter, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	start := time.Now()
+	previousOps := make([]int64, len(counters))
+	previousDraw := start
+	linesDrawn := 0
+
+	draw := func(elapsed time.Duration) {
+		sinceLastDraw := time.Since(previousDraw).Seconds()
+		previousDraw = time.Now()
+
+		rates := make([]float64, len(counters))
+		fastest := 0.0
+		for i, counter := range counters {
+			ops := counter.TotalOps()
+			rates[i] = float64(ops-previousOps[i]) / sinceLastDraw
+			previousOps[i] = ops
+			fastest = max(fastest, rates[i])
+		}
+
+		if linesDrawn > 0 {
+			fmt.Fprintf(w, "\033[%dA", linesDrawn)
+		}
+		fmt.Fprintf(w, "\033[KElapsed %v\n", elapsed.Round(100*time.Millisecond))
+		fmt.Fprintf(w, "\033[K%-18s %14s %12s %12s\n", "Counter", "Value", "Ops", "Ops/s")
+		for i, counter := range counters {
+			value := "-"
+			if v, ok := counter.LiveValue(); ok {
+				value = strconv.Itoa(v)
+			}
+			bar := 0
+			if fastest > 0 {
+				bar = int(rates[i] / fastest * dashboardBarWidth)
+			}
+			fmt.Fprintf(w, "\033[K%-18s %14s %12d %12s %s\n", counter.Name(), value, counter.TotalOps(),
+				bench.FormatRate(rates[i]), strings.Repeat("#", bar))
+		}
+		linesDrawn = len(counters) + 2
+	}
+
+	go func() {
+		defer close(finished)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		draw(0)
+		for {
+			select {
+			case <-ticker.C:
+				draw(time.Since(start))
+			case <-done:
+				draw(time.Since(start))
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// LiveOutput selects what is shown while a workload is still running
+type LiveOutput struct {
+	Quiet   bool
+	TUI     bool
+	Metrics *metricsSource
+}
+
+// startProgress starts whichever live output was asked for, the dashboard, the status line, or nothing at all, and
+// exposes the counters on the metrics endpoint when one is running, a dump signal prints them whatever was chosen
+func startProgress(counters []*TimedCounter, live LiveOutput) (stop func()) {
+	if live.Metrics != nil {
+		live.Metrics.track(counters)
+	}
+	stopDump := watchDumpSignal(os.Stderr, counters)
+
+	var stopOutput func()
+	switch {
+	case live.TUI:
+		stopOutput = runDashboard(os.Stderr, counters, 250*time.Millisecond)
+	case live.Quiet:
+		stopOutput = func() {}
+	default:
+		stopOutput = reportProgress(slog.Default(), counters, time.Second)
+	}
+	return func() {
+		stopOutput()
+		stopDump()
+	}
+}
+
+// parallelism is how many routines can actually be inside a counter at the same instant
+func parallelism(numRoutines int) int {
+	return max(min(numRoutines, runtime.GOMAXPROCS(0)), 1)
+}
+
+// Throughput estimates operations per second for this counter alone
+// Every counter shares the same routines, so wall clock can't be split between them, instead the summed time spent
+// inside this counter is spread across the routines that could have been running in parallel
+func (c *TimedCounter) Throughput(parallelism int) float64 {
+	elapsed := c.TotalTime() / time.Duration(parallelism)
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(c.TotalOps()) / elapsed.Seconds()
+}
+
+// verdict compares a counter's final value against the reference so lost updates can't hide behind a plausible number
+func verdict(value, expected int) string {
+	if value == expected {
+		return "correct"
+	}
+	return fmt.Sprintf("WRONG by %d", value-expected)
+}
+
+// printOpStats prints the latency breakdown of each operation type on its own indented line, reads are left out when
+// the workload made none
+func printOpStats(stats *timingStats) {
+	for _, op := range stats.byType() {
+		if op.name == "read" && op.stats.Count() == 0 {
+			continue
+		}
+		fmt.Printf("    %-9s %8d ops  min %-10v mean %-10v p50 %-10v p90 %-10v p99 %-10v max %v\n",
+			op.name, op.stats.Count(), op.stats.Min(), op.stats.Mean(),
+			op.stats.Percentile(0.50), op.stats.Percentile(0.90), op.stats.Percentile(0.99), op.stats.Max())
+	}
+}
+
+func printReport(counters []*TimedCounter, workload Workload, wallClock time.Duration, expected int) {
+	numRoutines := workload.Routines
+	fmt.Printf("Ran %d routines with seed %d in %v, expected final value is %d\n", numRoutines, workload.Seed, wallClock, expected)
+	if workload.Warmup > 0 {
+		fmt.Printf("Each routine first ran %d warm-up operations, left out of every value and statistic below\n", workload.Warmup)
+	}
+	if workload.thinking() {
+		fmt.Printf("Each routine rested %v plus up to %v of jitter after every %d operations, rests aren't counted in the throughput\n",
+			workload.ThinkTime, workload.Jitter, max(workload.Burst, 1))
+	}
+
+	// range through the counters and get their final values and stats
+	for _, counter := range counters {
+		value := counter.FinalValue()
+		fmt.Printf("%s value is %d (%s) with a collective operation count of %v, processing time of %v and throughput of %s\n",
+			counter.Name(), value, verdict(value, expected), counter.TotalOps(), counter.TotalTime(), bench.FormatRate(counter.Throughput(parallelism(numRoutines))))
+		printOpStats(counter.stats)
+		if report := counter.Report(); report != "" {
+			fmt.Printf("    %s\n", report)
+		}
+	}
+}
+
+// runSeparately runs the full workload against one counter at a time, each seeing the identical schedule, so a slow
+// counter can't throttle the routines exercising a fast one and no counter shares caches with the others mid-run
+// The wall clock of each run is then a fair, directly comparable measure of the counter on its own
+// Progress goes to stderr so it never mixes with machine readable results on stdout
+func runSeparately(ctx context.Context, cfg CounterConfig, selected []string, workload Workload, live LiveOutput) ([]*TimedCounter, map[string]time.Duration, int) {
+	counters := []*TimedCounter{}
+	wallClocks := map[string]time.Duration{}
+	expected := 0
+
+	for _, name := range selected {
+		slog.Info("running a counter on its own", "counter", name)
+
+		// each run gets its own context so any worker goroutine a counter starts exits once its run is done
+		runCtx, runCancel := context.WithCancel(ctx)
+		runCfg := cfg
+		runCfg.Ctx = runCtx
+		counter := newCounters(runCfg, []string{name})
+		stopProgress := startProgress(counter, live)
+		wallClock, runExpected, err := runWorkload(runCtx, counter, workload)
+		if err != nil {
+			slog.Warn("run stopped early", "counter", name, "err", err)
+		}
+		stopProgress()
+		runCancel()
+
+		counters = append(counters, counter...)
+		wallClocks[name] = wallClock
+		expected = runExpected
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return counters, wallClocks, expected
+}
+
+// totalWallClock sums the wall clock of every separate run
+func totalWallClock(wallClocks map[string]time.Duration) time.Duration {
+	var total time.Duration
+	for _, wallClock := range wallClocks {
+		total += wallClock
+	}
+	return total
+}
+
+// meanOpNs is the average time a counter took per operation in nanoseconds, callers check it recorded some first
+func meanOpNs(counter *TimedCounter) float64 {
+	return float64(counter.TotalTime()) / float64(counter.TotalOps())
+}
+
+// printFalseSharingComparison contrasts the adjacent and padded sharded counters when both took part in the run
+func printFalseSharingComparison(counters []*TimedCounter) {
+	var adjacent, padded *TimedCounter
+	for _, counter := range counters {
+		switch counter.Name() {
+		case "AdjacentShards":
+			adjacent = counter
+		case "PaddedShards":
+			padded = counter
+		}
+	}
+	if adjacent == nil || padded == nil || adjacent.TotalOps() == 0 || padded.TotalOps() == 0 {
+		return
+	}
+
+	adjacentMean := meanOpNs(adjacent)
+	paddedMean := meanOpNs(padded)
+	fmt.Printf("\nFalse sharing: padded shards averaged %.1fns per operation against %.1fns for adjacent shards, %.2fx faster\n",
+		paddedMean, adjacentMean, adjacentMean/paddedMean)
+}
+
+// printOpTypeComparison tabulates the mean and p99 latency of each operation type for every counter side by side, which
+// is where the price of a read shows, cheap on an atomic and a full round trip through the goroutine on a channel
+func printOpTypeComparison(counters []*TimedCounter) {
+	if len(counters) == 0 {
+		return
+	}
+
+	fmt.Printf("\n%-20s", "Counter")
+	for _, op := range counters[0].stats.byType() {
+		fmt.Printf(" %21s", op.name+" mean/p99")
+	}
+	fmt.Println()
+	for _, counter := range counters {
+		fmt.Printf("%-20s", counter.Name())
+		for _, op := range counter.stats.byType() {
+			if op.stats.Count() == 0 {
+				fmt.Printf(" %21s", "-")
+				continue
+			}
+			fmt.Printf(" %21s", fmt.Sprintf("%v / %v", op.stats.Mean().Round(time.Nanosecond), op.stats.Percentile(0.99)))
+		}
+		fmt.Println()
+	}
+}
+
+// printCancellationSummary explains a run that was cut short, how far each counter got and whether it applied every
+// operation it accepted before it stopped
+// The expected value only covers operations handed out before the routines noticed the cancellation, a counter matching
+// it lost nothing on the way out, one that doesn't dropped operations it had already accepted, typically ones still
+// sitting in a buffer when its worker gave up, which is exactly what draining is there to prevent
+func printCancellationSummary(ctx context.Context, counters []*TimedCounter, workload Workload, wallClock time.Duration, expected int) {
+	scheduled := int64(workload.Routines * workload.LoopsPerRoutine)
+	fmt.Printf("\nThe run was canceled after %v: %v\n", wallClock.Round(time.Microsecond), context.Cause(ctx))
+	for _, counter := range counters {
+		ops := counter.TotalOps()
+		value := counter.FinalValue()
+		fmt.Printf("%-20s completed %d of %d operations (%.1f%%), final value %d (%s%s)\n", counter.Name(), ops, scheduled,
+			100*float64(ops)/float64(scheduled), value, verdict(value, expected), drainNote(counter, value, expected))
+	}
+}
+
+// drainNote says what draining did for a counter that buffers operations, only claiming it saved them when the value
+// shows nothing was lost
+func drainNote(counter *TimedCounter, value, expected int) string {
+	if _, ok := counter.delegate.(Drainer); !ok {
+		return ""
+	}
+	if value != expected {
+		return ", lost operations despite draining"
+	}
+	return ", drained every buffered operation"
+}
+
+// printSeparateComparison prints the wall clock of each separate run, fastest first, relative to the fastest
+func printSeparateComparison(counters []*TimedCounter, wallClocks map[string]time.Duration) {
+	if len(counters) == 0 {
+		return
+	}
+
+	counters = slices.Clone(counters)
+	slices.SortFunc(counters, func(a, b *TimedCounter) int {
+		return cmp.Compare(wallClocks[a.Name()], wallClocks[b.Name()])
+	})
+	fmt.Printf("\n%-20s %14s %14s %10s\n", "Counter", "Wall clock", "Throughput", "Relative")
+	fastest := wallClocks[counters[0].Name()]
+	for _, counter := range counters {
+		wallClock := wallClocks[counter.Name()]
+		fmt.Printf("%-20s %14v %14s %9.2fx\n", counter.Name(), wallClock.Round(time.Microsecond),
+			bench.FormatRate(float64(counter.TotalOps())/wallClock.Seconds()), float64(wallClock)/float64(fastest))
+	}
+}
+
+// RunConfig records the settings a run was made with, so results gathered on different machines or days can be
+// grouped and compared
+type RunConfig struct {
+	Mode            string  `json:"mode"`
+	Routines        int     `json:"routines"`
+	LoopsPerRoutine int     `json:"loops_per_routine"`
+	ReadRatio       float64 `json:"read_ratio"`
+	IncrementRatio  float64 `json:"increment_ratio"`
+	ValueRange      int     `json:"value_range"`
+	Seed            int64   `json:"seed"`
+	Shards          int     `json:"shards"`
+	BatchSize       int     `json:"batch_size"`
+	MaxInFlight     int     `json:"max_in_flight"`
+	ChannelBuffer   int     `json:"channel_buffer"`
+	PoolSize        int     `json:"pool_size"`
+	Stripes         int     `json:"stripes"`
+	Warmup          int     `json:"warmup"`
+	GOMAXPROCS      int     `json:"gomaxprocs"`
+}
+
+// CounterResult is the outcome of a run for a single counter
+type CounterResult struct {
+	Name                string  `json:"name"`
+	Value               int     `json:"value"`
+	Expected            int     `json:"expected"`
+	Correct             bool    `json:"correct"`
+	Ops                 int64   `json:"ops"`
+	TotalTimeNs         int64   `json:"total_time_ns"`
+	ThroughputOpsPerSec float64 `json:"throughput_ops_per_sec"`
+	P50Ns               int64   `json:"p50_ns"`
+	P90Ns               int64   `json:"p90_ns"`
+	P99Ns               int64   `json:"p99_ns"`
+	WallClockNs         int64   `json:"wall_clock_ns,omitempty"`
+}
+
+// RunResult is everything a run produced, in a form that can be written as JSON or CSV
+type RunResult struct {
+	Config      RunConfig       `json:"config"`
+	Env         bench.Env       `json:"env"`
+	WallClockNs int64           `json:"wall_clock_ns"`
+	Counters    []CounterResult `json:"counters"`
+}
+
+// buildResult gathers the counters' final state into a RunResult, wallClocks is only set when each counter ran on its
+// own, in which case throughput is measured against its own wall clock rather than estimated
+func buildResult(config RunConfig, counters []*TimedCounter, wallClock time.Duration, wallClocks map[string]time.Duration, expected int) RunResult {
+	result := RunResult{
+		Config:      config,
+		Env:         bench.CaptureEnv(),
+		WallClockNs: wallClock.Nanoseconds(),
+	}
+	for _, counter := range counters {
+		value := counter.FinalValue()
+		counterResult := CounterResult{
+			Name:                counter.Name(),
+			Value:               value,
+			Expected:            expected,
+			Correct:             value == expected,
+			Ops:                 counter.TotalOps(),
+			TotalTimeNs:         counter.TotalTime().Nanoseconds(),
+			ThroughputOpsPerSec: counter.Throughput(parallelism(config.Routines)),
+			P50Ns:               counter.Percentile(0.50).Nanoseconds(),
+			P90Ns:               counter.Percentile(0.90).Nanoseconds(),
+			P99Ns:               counter.Percentile(0.99).Nanoseconds(),
+		}
+		if counterWallClock, ok := wallClocks[counter.Name()]; ok {
+			counterResult.WallClockNs = counterWallClock.Nanoseconds()
+			counterResult.ThroughputOpsPerSec = float64(counter.TotalOps()) / counterWallClock.Seconds()
+		}
+		result.Counters = append(result.Counters, counterResult)
+	}
+	return result
+}
+
+// writeJSON writes the result as a single indented JSON document
+func writeJSON(w io.Writer, result RunResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// writeCSV writes one row per counter, repeating the run configuration on every row so files from many runs can
+// simply be concatenated and loaded into a spreadsheet
+func writeCSV(w io.Writer, result RunResult) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{
+		"mode", "routines", "loops_per_routine", "read_ratio", "increment_ratio", "value_range", "seed", "shards",
+		"batch_size", "max_in_flight", "channel_buffer", "pool_size", "stripes", "warmup", "gomaxprocs", "goos", "goarch", "cpu", "num_cpu", "go_version", "name", "value", "expected", "correct", "ops", "total_time_ns",
+		"throughput_ops_per_sec", "p50_ns", "p90_ns", "p99_ns", "wall_clock_ns",
+	})
+
+	config, env := result.Config, result.Env
+	for _, counter := range result.Counters {
+		wallClock := counter.WallClockNs
+		if wallClock == 0 {
+			wallClock = result.WallClockNs
+		}
+		writer.Write([]string{
+			config.Mode,
+			strconv.Itoa(config.Routines),
+			strconv.Itoa(config.LoopsPerRoutine),
+			strconv.FormatFloat(config.ReadRatio, 'f', -1, 64),
+			strconv.FormatFloat(config.IncrementRatio, 'f', -1, 64),
+			strconv.Itoa(config.ValueRange),
+			strconv.FormatInt(config.Seed, 10),
+			strconv.Itoa(config.Shards),
+			strconv.Itoa(config.BatchSize),
+			strconv.Itoa(config.MaxInFlight),
+			strconv.Itoa(config.ChannelBuffer),
+			strconv.Itoa(config.PoolSize),
+			strconv.Itoa(config.Stripes),
+			strconv.Itoa(config.Warmup),
+			strconv.Itoa(config.GOMAXPROCS),
+			env.GOOS,
+			env.GOARCH,
+			env.CPU,
+			strconv.Itoa(env.NumCPU),
+			env.GoVersion,
+			counter.Name,
+			strconv.Itoa(counter.Value),
+			strconv.Itoa(counter.Expected),
+			strconv.FormatBool(counter.Correct),
+			strconv.FormatInt(counter.Ops, 10),
+			strconv.FormatInt(counter.TotalTimeNs, 10),
+			strconv.FormatFloat(counter.ThroughputOpsPerSec, 'f', 0, 64),
+			strconv.FormatInt(counter.P50Ns, 10),
+			strconv.FormatInt(counter.P90Ns, 10),
+			strconv.FormatInt(counter.P99Ns, 10),
+			strconv.FormatInt(wallClock, 10),
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// doublingSteps doubles from 1 up to limit, always finishing on limit itself
+func doublingSteps(limit int) []int {
+	steps := []int{}
+	for n := 1; n < limit; n *= 2 {
+		steps = append(steps, n)
+	}
+	return append(steps, limit)
+}
+
+// scalingTable collects each counter's throughput at every step of a sweep and prints them side by side
+type scalingTable struct {
+	label   string
+	steps   []int
+	headers []string
+	names   []string
+	results map[string][]float64
+}
+
+func newScalingTable(label string, steps []int) *scalingTable {
+	headers := []string{}
+	for _, step := range steps {
+		headers = append(headers, strconv.Itoa(step))
+	}
+	return &scalingTable{
+		label:   label,
+		steps:   steps,
+		headers: headers,
+		results: map[string][]float64{},
+	}
+}
+
+// newLabelledTable is a table whose columns are named rather than numbered steps
+func newLabelledTable(label string, headers []string) *scalingTable {
+	return &scalingTable{
+		label:   label,
+		headers: headers,
+		results: map[string][]float64{},
+	}
+}
+
+func (t *scalingTable) add(name string, opsPerSec float64) {
+	if _, ok := t.results[name]; !ok {
+		t.names = append(t.names, name)
+	}
+	t.results[name] = append(t.results[name], opsPerSec)
+}
+
+func (t *scalingTable) print() {
+	fmt.Printf("\n%-20s", t.label)
+	for _, header := range t.headers {
+		fmt.Printf(" %12s", header)
+	}
+	fmt.Println()
+	for _, name := range t.names {
+		fmt.Printf("%-20s", name)
+		for _, rate := range t.results[name] {
+			fmt.Printf(" %12s", bench.FormatRate(rate))
+		}
+		fmt.Println()
+	}
+}
+
+// runStep runs the workload once against fresh counters and records each counter's throughput in the table
+func runStep(ctx context.Context, cfg CounterConfig, selected []string, workload Workload, table *scalingTable) {
+	// each step gets its own context so the channel counter's worker exits once the step is done
+	stepCtx, stepCancel := context.WithCancel(ctx)
+	defer stepCancel()
+
+	cfg.Ctx = stepCtx
+	cfg.Routines = workload.Routines
+	counters := newCounters(cfg, selected)
+	runWorkload(stepCtx, counters, workload)
+
+	for _, counter := range counters {
+		table.add(counter.Name(), counter.Throughput(parallelism(workload.Routines)))
+	}
+}
+
+// runSweep reruns the workload with fresh counters at each routine count and prints a table of throughput,
+// showing which implementations scale as routines are added and which collapse under contention
+func runSweep(ctx context.Context, cfg CounterConfig, selected []string, workload Workload) {
+	table := newScalingTable("Routines", doublingSteps(workload.Routines))
+
+	for _, numRoutines := range table.steps {
+		slog.Info("running sweep step", "routines", numRoutines)
+
+		step := workload
+		step.Routines = numRoutines
+		runStep(ctx, cfg, selected, step, table)
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+
+	table.print()
+}
+
+// runProcsSweep reruns the workload with fresh counters under each GOMAXPROCS value, from a single processor up to
+// every CPU, so the table shows how each counter responds to the parallelism actually available to it rather than to
+// the number of routines, which the scheduler multiplexes onto however many processors it is given
+func runProcsSweep(ctx context.Context, cfg CounterConfig, selected []string, workload Workload) {
+	table := newScalingTable("GOMAXPROCS", doublingSteps(runtime.NumCPU()))
+
+	original := runtime.GOMAXPROCS(0)
+	defer runtime.GOMAXPROCS(original)
+
+	for _, procs := range table.steps {
+		slog.Info("running sweep step", "gomaxprocs", procs)
+
+		runtime.GOMAXPROCS(procs)
+		runStep(ctx, cfg, selected, workload, table)
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+
+	table.print()
+}
+
+// readScalingCounters are compared by the read scaling benchmark, an exclusive lock, a reader/writer lock and no lock
+var readScalingCounters = []string{"Mutex", "RWMutex", "AtomicInt64"}
+
+// measureReads runs readers calling Value() alongside writers incrementing the counter, each routine for the given
+// number of loops, and returns the read throughput achieved while the writers were competing for the same counter
+func measureReads(counter Counter, readers, writers, loops int) float64 {
+	var readersWG, writersWG sync.WaitGroup
+	start := time.Now()
+
+	for range writers {
+		writersWG.Go(func() {
+			for range loops {
+				counter.IncrementBy(1)
+			}
+		})
+	}
+	for range readers {
+		readersWG.Go(func() {
+			for range loops {
+				counter.Value()
+			}
+		})
+	}
+
+	readersWG.Wait()
+	elapsed := time.Since(start)
+	writersWG.Wait()
+
+	return float64(readers*loops) / elapsed.Seconds()
+}
+
+// runReadScaling measures read throughput for each counter with a growing number of readers and a fixed, small
+// number of writers
+// A plain mutex serializes readers, so adding them adds nothing, a RWMutex lets them proceed together so its reads
+// scale with readers, and an atomic needs no lock at all, showing what a RWMutex's reader bookkeeping costs
+func runReadScaling(ctx context.Context, maxReaders, writers, loops int) {
+	table := newScalingTable("Readers (reads/s)", doublingSteps(maxReaders))
+
+	for _, readers := range table.steps {
+		slog.Info("running sweep step", "readers", readers, "writers", writers)
+
+		for _, name := range readScalingCounters {
+			counter := newCounters(CounterConfig{Ctx: ctx, Routines: readers + writers}, []string{name})[0]
+			table.add(name, measureReads(counter, readers, writers, loops))
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+
+	table.print()
+}
+
+// Description is the lesson's help text, shown by teachgo counters -h, its first line is the summary teachgo help lists
+const Description = `Two dozen concurrent counter implementations raced for correctness, throughput and latency
+
+Races a mix of increments, decrements and reads from many goroutines against counters from a plain mutex to sharded
+atomics, channels and flat combining, and reports each one's correctness, throughput and latency, along with
+demonstrations of the concurrency pitfalls in between.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "counters",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"goroutines", "mutexes", "atomics", "channels", "sharding", "false sharing", "data races", "context cancellation"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo counters
+func Main(args []string) {
+	fs := bench.NewFlagSet("counters", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("seed").Usage = "the random seed used to generate the operation schedule, reuse it to repeat a run"
+	fs.Lookup("format").Usage = "the output format of the results, one of text, json, csv or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "repeat the workload this many times and report each counter's mean throughput with a confidence interval"
+	seed, format, trials := &globals.Seed, &globals.Format, &globals.Trials
+
+	numRoutines := fs.Int("routines", 100, "the number of routines to run")
+	numLoopPerRoutine := fs.Int("loops", 10000, "the number of loops or iterations to run per routine")
+	numShards := fs.Int("shards", runtime.GOMAXPROCS(0), "the number of shards used by the sharded counters")
+	batchSize := fs.Int("batch", 32, "the number of operations each routine sends at once to the batched channel counter")
+	channelBuffer := fs.Int("channel-buffer", 64, "the buffer size of the channel counter's increment and decrement channels, 0 for unbuffered")
+	poolSize := fs.Int("pool-size", runtime.GOMAXPROCS(0), "the number of workers the worker pool counter runs, sharing one job channel")
+	stripes := fs.Int("stripes", 4*runtime.GOMAXPROCS(0), "the number of locks the striped counter spreads goroutines over")
+	maxInFlight := fs.Int("maxinflight", runtime.GOMAXPROCS(0), "the number of operations the semaphore counter lets in at once")
+	readRatio := fs.Float64("read-ratio", 0, "the fraction of operations, from 0 to 1, that read the counter's value instead of changing it")
+	incrementRatio := fs.Float64("increment-ratio", 0.5, "the fraction of writing operations, from 0 to 1, that increment rather than decrement")
+	valueRange := fs.Int("value-range", 5, "increments and decrements are by a random amount in [0, value-range)")
+	orchestration := fs.String("orchestration", "waitgroup", "how routines are run and waited for, waitgroup or errgroup")
+	failAfter := fs.Int("fail-after", 0, "make the first routine fail after this many operations to compare how each orchestration reacts")
+	recordPath := fs.String("record", "", "write the generated operation schedule to this file, e.g. ops.bin, so the run can be replayed")
+	replayPath := fs.String("replay", "", "rerun the operation schedule recorded in this file instead of generating one")
+	eventsPath := fs.String("events", "", "log every operation of a small run to this CSV file, e.g. events.csv, to draw with -timeline")
+	timelinePath := fs.String("timeline", "", "draw the interleaving of the operations in an event log written by -events and exit")
+	numKeys := fs.Int("keys", 0, "when set, run the multi-key benchmark over this many keys (e.g. 1000) instead of the single counter one")
+	keyDistribution := fs.String("key-dist", "uniform", "how the multi-key benchmark picks each operation's key: "+datagen.Usage)
+	counterSelection := fs.String("counters", "", "comma separated counters to run, all of them when empty, available: "+strings.Join(counterNames(), ","))
+	separate := fs.Bool("separate", false, "run the workload against each counter on its own, one after another, and compare wall clock")
+	tui := fs.Bool("tui", false, "show a live dashboard of every counter's value and throughput while the workload runs")
+	metricsAddr := fs.String("metrics", "", "serve counter metrics over HTTP on this address, e.g. :8080, expvar at /debug/vars and Prometheus at /metrics")
+	cpuProfile := fs.String("cpuprofile", "", "write a CPU profile of the run to this file")
+	memProfile := fs.String("memprofile", "", "write a memory allocation profile to this file at the end of the run")
+	mutexProfile := fs.String("mutexprofile", "", "write a mutex contention profile to this file at the end of the run")
+	pprofAddr := fs.String("pprof", "", "serve net/http/pprof on this address, e.g. localhost:6060, to profile the run live")
+	contention := fs.Bool("contention", false, "record every mutex contention and blocking event during the run and print a per-counter summary")
+	timeout := fs.Duration("timeout", 0, "give the run a deadline, e.g. 50ms, after which it is canceled mid-flight")
+	cancelAfter := fs.Duration("cancel-after", 0, "explicitly cancel the run after this long, e.g. 50ms, as a caller giving up would")
+	quiet := fs.Bool("quiet", false, "don't print a status line every second while the workload runs")
+	htmlPath := fs.String("html", "", "also write the results as a self-contained HTML page of charts to this file, e.g. report.html")
+	sweep := fs.Bool("sweep", false, "rerun the workload at 1, 2, 4, ... up to -routines routines and print a scalability table")
+	readScaling := fs.Bool("read-scaling", false, "compare read throughput of mutex, RWMutex and atomic counters as readers grow from 1 up to -routines")
+	numWriters := fs.Int("writers", 1, "the number of writer routines competing with the readers during -read-scaling")
+	procs := fs.Int("procs", 0, "the GOMAXPROCS to run with, the runtime default when zero")
+	procsSweep := fs.Bool("procs-sweep", false, "rerun the workload at GOMAXPROCS 1, 2, 4, ... up to the number of CPUs and print a scalability table")
+	phases := fs.Int("phases", 1, "run the workload this many times against the same counters, resetting them in between")
+	demonstrate := fs.String("demonstrate", "", "instead of benchmarking, demonstrate a concurrency primitive or pitfall, one of: cond, deadlock, race, float")
+	warmup := fs.Int("warmup", 0, "the number of untimed operations each routine runs first, to warm caches, the scheduler and buffers")
+	thinkTime := fs.Duration("think", 0, "how long each routine rests between operations, e.g. 10µs, to model work done away from the counter")
+	jitter := fs.Duration("jitter", 0, "add a random extra of up to this long to each rest, so routines drift out of step")
+	burst := fs.Int("burst", 1, "the number of operations each routine performs back to back before resting for -think")
+	regimes := fs.Bool("regimes", false, "rerun the workload under saturated, bursty and sparse contention and compare the counters in each")
+
+	fs.Parse(args)
+
+	// every problem with the flags is reported at once, the modes that only make sense on their own are listed
+	// together so each combination rule below reads as one sentence
+	repeatable := !(*sweep || *procsSweep || *readScaling || *numKeys > 0 || *phases > 1 || *regimes)
+	singleRun := repeatable && *trials <= 1
+	v := bench.NewValidator(fs)
+	selected, err := parseCounterSelection(*counterSelection)
+	v.Add(err)
+	v.Add(globals.Check("text", "json", "csv", "bench"))
+	v.OneOf("orchestration", *orchestration, "waitgroup", "errgroup")
+	v.AtLeast("routines", *numRoutines, 1)
+	v.AtLeast("loops", *numLoopPerRoutine, 0)
+	v.AtLeast("shards", *numShards, 1)
+	v.AtLeast("batch", *batchSize, 1)
+	v.AtLeast("channel-buffer", *channelBuffer, 0)
+	v.AtLeast("pool-size", *poolSize, 1)
+	v.AtLeast("stripes", *stripes, 1)
+	v.AtLeast("maxinflight", *maxInFlight, 1)
+	v.Fraction("read-ratio", *readRatio)
+	v.Fraction("increment-ratio", *incrementRatio)
+	v.AtLeast("value-range", *valueRange, 1)
+	v.AtLeast("fail-after", *failAfter, 0)
+	v.AtLeast("keys", *numKeys, 0)
+	if *numKeys > 0 {
+		_, err := datagen.Parse(*keyDistribution, *numKeys)
+		v.Add(err)
+	}
+	v.AtLeast("writers", *numWriters, 0)
+	v.AtLeast("procs", *procs, 0)
+	v.AtLeast("phases", *phases, 1)
+	v.AtLeast("warmup", *warmup, 0)
+	v.AtLeast("burst", *burst, 1)
+	v.NotNegative("timeout", *timeout)
+	v.NotNegative("cancel-after", *cancelAfter)
+	v.NotNegative("think", *thinkTime)
+	v.NotNegative("jitter", *jitter)
+	v.Check(*format == "text" || *format == "bench" || singleRun,
+		"-format json and csv are only supported by the single counter benchmark, with or without -separate")
+	v.Check(*format != "bench" || (repeatable && !*separate && *htmlPath == "" && *eventsPath == ""),
+		"-format bench only writes the single counter benchmark, repeated with -trials, without -separate, -html or -events")
+	v.Check(*htmlPath == "" || singleRun, "-html is only supported by the single counter benchmark, with or without -separate")
+	v.Check(*eventsPath == "" || (singleRun && !*separate), "-events only logs a single run of the counters together")
+	if *demonstrate != "" {
+		v.OneOf("demonstrate", *demonstrate, "cond", "deadlock", "race", "float")
+	}
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	if *timelinePath != "" {
+		events, err := ReadEvents(*timelinePath)
+		if err != nil {
+			slog.Error("failed to read event log", "path", *timelinePath, "err", err)
+			os.Exit(1)
+		}
+		renderTimeline(os.Stdout, events, 100)
+		return
+	}
+
+	if *procs > 0 {
+		runtime.GOMAXPROCS(*procs)
+	}
+	// every text report starts with the machine it ran on, JSON and CSV results carry the same in their env fields
+	if *format == "text" {
+		fmt.Printf("Machine: %s\n\n", bench.CaptureEnv())
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	workload := Workload{
+		Routines:        *numRoutines,
+		LoopsPerRoutine: *numLoopPerRoutine,
+		ReadRatio:       *readRatio,
+		IncrementRatio:  *incrementRatio,
+		ValueRange:      *valueRange,
+		Seed:            *seed,
+		Orchestration:   *orchestration,
+		FailAfter:       *failAfter,
+		ThinkTime:       *thinkTime,
+		Jitter:          *jitter,
+		Burst:           *burst,
+		Warmup:          *warmup,
+		KeyDistribution: *keyDistribution,
+	}
+	if *eventsPath != "" {
+		workload.Events = &EventLog{}
+	}
+
+	// a replay takes its routines, loops and seed from the recording, so the run matches it exactly
+	if *replayPath != "" {
+		recording, err := ReadRecording(*replayPath)
+		if err != nil {
+			slog.Error("failed to read recording", "path", *replayPath, "err", err)
+			os.Exit(1)
+		}
+		if *numKeys > 0 && recording.maxKey() >= *numKeys {
+			fmt.Fprintf(os.Stderr, "the recording uses keys up to %d, run it with -keys %d or more\n", recording.maxKey(), recording.maxKey()+1)
+			os.Exit(2)
+		}
+		workload.replay = recording.Schedule
+		workload.Routines = len(recording.Schedule)
+		workload.LoopsPerRoutine = recording.loopsPerRoutine()
+		workload.Seed = recording.Seed
+		slog.Info("replaying a recording", "path", *replayPath, "routines", workload.Routines)
+	}
+
+	if *recordPath != "" {
+		if err := WriteRecording(*recordPath, Recording{Seed: workload.Seed, Schedule: workload.Schedule(*numKeys)}); err != nil {
+			slog.Error("failed to write recording", "path", *recordPath, "err", err)
+			os.Exit(1)
+		}
+		slog.Info("recorded the operation schedule", "path", *recordPath)
+	}
+
+	// with a deadline the schedule is generated before the clock starts, so it only runs while the counters are being
+	// exercised, a sweep varies the routines and still generates a schedule for each step as it goes
+	if (*timeout > 0 || *cancelAfter > 0) && workload.replay == nil && !*sweep {
+		workload.replay = workload.Schedule(*numKeys)
+	}
+
+	// a deadline cancels the run on its own, the timer stands in for a caller deciding to give up, context.Cause tells
+	// the two apart afterwards
+	if *timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, *timeout)
+		defer cancelTimeout()
+	}
+	if *cancelAfter > 0 {
+		var cancelRun context.CancelCauseFunc
+		ctx, cancelRun = context.WithCancelCause(ctx)
+		defer cancelRun(nil)
+		stopTimer := time.AfterFunc(*cancelAfter, func() { cancelRun(errCancelAfter) })
+		defer stopTimer.Stop()
+	}
+
+	// profiling starts once nothing is left that can fail on its way in, from here on every failure is returned to
+	// the deferred exit, os.Exit would skip writing the profiles
+	stopProfiling, err := startProfiling(Profiles{CPU: *cpuProfile, Mem: *memProfile, Mutex: *mutexProfile}, *pprofAddr)
+	if err != nil {
+		slog.Error("failed to start profiling", "err", err)
+		os.Exit(1)
+	}
+	var failure error
+	defer func() {
+		if err := stopProfiling(); err != nil {
+			slog.Error("failed to write profiles", "err", err)
+		}
+		if failure != nil {
+			slog.Error("run failed", "err", failure)
+			os.Exit(1)
+		}
+	}()
+	if *contention {
+		enableContentionProfiling()
+	}
+	if *pprofAddr != "" {
+		slog.Info("serving pprof", "url", "http://"+*pprofAddr+"/debug/pprof/")
+	}
+
+	switch *demonstrate {
+	case "cond":
+		runCondDemo(ctx, workload.Routines, workload.LoopsPerRoutine)
+		return
+	case "deadlock":
+		runDeadlockDemo(ctx)
+		return
+	case "race":
+		runRaceDemo(ctx, min(workload.Routines, 4), min(workload.LoopsPerRoutine, 1000))
+		return
+	case "float":
+		runFloatDemo(ctx, workload.Routines, workload.LoopsPerRoutine)
+		return
+	}
+
+	if *readScaling {
+		runReadScaling(ctx, *numRoutines, *numWriters, *numLoopPerRoutine)
+		return
+	}
+
+	if *numKeys > 0 {
+		runKeyedBenchmark(ctx, *numKeys, *numShards, workload)
+		return
+	}
+
+	cfg := CounterConfig{
+		Ctx:           ctx,
+		Routines:      workload.Routines,
+		Shards:        *numShards,
+		BatchSize:     *batchSize,
+		MaxInFlight:   *maxInFlight,
+		ChannelBuffer: *channelBuffer,
+		PoolSize:      *poolSize,
+		Stripes:       *stripes,
+	}
+
+	if *format == "bench" {
+		if err := runBenchmarks(ctx, os.Stdout, cfg, selected, workload, *trials); err != nil {
+			failure = fmt.Errorf("failed to write benchmarks: %w", err)
+		}
+		return
+	}
+
+	if *sweep {
+		runSweep(ctx, cfg, selected, workload)
+		return
+	}
+
+	if *procsSweep {
+		runProcsSweep(ctx, cfg, selected, workload)
+		return
+	}
+
+	if *phases > 1 {
+		runPhases(ctx, cfg, selected, workload, *phases)
+		return
+	}
+
+	if *trials > 1 {
+		runTrials(ctx, cfg, selected, workload, *trials)
+		return
+	}
+
+	if *regimes {
+		runRegimes(ctx, cfg, selected, workload)
+		return
+	}
+
+	config := RunConfig{
+		Mode:            "combined",
+		Routines:        workload.Routines,
+		LoopsPerRoutine: workload.LoopsPerRoutine,
+		ReadRatio:       workload.ReadRatio,
+		IncrementRatio:  workload.IncrementRatio,
+		ValueRange:      workload.ValueRange,
+		Seed:            workload.Seed,
+		Shards:          cfg.Shards,
+		BatchSize:       cfg.BatchSize,
+		MaxInFlight:     cfg.MaxInFlight,
+		ChannelBuffer:   cfg.ChannelBuffer,
+		PoolSize:        cfg.PoolSize,
+		Stripes:         cfg.Stripes,
+		Warmup:          workload.Warmup,
+		GOMAXPROCS:      runtime.GOMAXPROCS(0),
+	}
+
+	live := LiveOutput{Quiet: *quiet, TUI: *tui}
+	if *metricsAddr != "" {
+		live.Metrics, err = serveMetrics(*metricsAddr)
+		if err != nil {
+			failure = fmt.Errorf("failed to start metrics server on %s: %w", *metricsAddr, err)
+			return
+		}
+		slog.Info("serving metrics", "addr", *metricsAddr, "prometheus", "/metrics", "expvar", "/debug/vars")
+	}
+	if len(dumpSignals) > 0 && !*quiet && *format == "text" {
+		slog.Info("dump every counter without stopping the run", "command", fmt.Sprintf("kill -USR1 %d", os.Getpid()))
+	}
+
+	var counters []*TimedCounter
+	var wallClocks map[string]time.Duration
+	var wallClock time.Duration
+	var expected int
+	if *separate {
+		config.Mode = "separate"
+		counters, wallClocks, expected = runSeparately(ctx, cfg, selected, workload, live)
+		wallClock = totalWallClock(wallClocks)
+	} else {
+		counters = newCounters(cfg, selected)
+		stopProgress := startProgress(counters, live)
+		// a routine failing, -fail-after's doing, stops the run early but isn't a failure of the lesson, the results
+		// of the run so far are reported and written in every format as they are for separate runs
+		var runErr error
+		wallClock, expected, runErr = runWorkload(ctx, counters, workload)
+		stopProgress()
+		if runErr != nil {
+			slog.Warn("run stopped early", "err", runErr)
+		}
+	}
+
+	switch *format {
+	case "json":
+		err = writeJSON(os.Stdout, buildResult(config, counters, wallClock, wallClocks, expected))
+	case "csv":
+		err = writeCSV(os.Stdout, buildResult(config, counters, wallClock, wallClocks, expected))
+	default:
+		printReport(counters, workload, wallClock, expected)
+		printFalseSharingComparison(counters)
+		printCombiningComparison(counters)
+		printPoolComparison(counters)
+		printOpTypeComparison(counters)
+		if ctx.Err() != nil {
+			printCancellationSummary(ctx, counters, workload, wallClock, expected)
+		}
+		if *contention {
+			printContentionSummary(counters)
+		}
+		if *separate {
+			printSeparateComparison(counters, wallClocks)
+		}
+	}
+	if err != nil {
+		failure = fmt.Errorf("failed to write results: %w", err)
+		return
+	}
+
+	if *htmlPath != "" {
+		if err := report.Write(*htmlPath, htmlReport(buildResult(config, counters, wallClock, wallClocks, expected))); err != nil {
+			failure = fmt.Errorf("failed to write HTML report %s: %w", *htmlPath, err)
+			return
+		}
+		slog.Info("wrote the HTML report", "path", *htmlPath)
+	}
+
+	if workload.Events != nil {
+		events := workload.Events.Events()
+		if err := WriteEvents(*eventsPath, events); err != nil {
+			failure = fmt.Errorf("failed to write event log %s: %w", *eventsPath, err)
+			return
+		}
+		slog.Info("logged every operation, draw them with -timeline", "path", *eventsPath, "operations", len(events))
+	}
+
+	// keep the final numbers available to scrape until the user is done with them
+	if live.Metrics != nil && ctx.Err() == nil {
+		slog.Info("run complete, still serving metrics, press Ctrl+C to exit", "addr", *metricsAddr)
+		<-ctx.Done()
 	}
 }
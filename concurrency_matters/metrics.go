@@ -0,0 +1,94 @@
+package concurrencymatters
+
+import (
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// metricsSource holds the counters the metrics endpoint reports on, counters from every run so far are kept so a
+// scrape after a -separate run still sees all of them
+type metricsSource struct {
+	mu       sync.Mutex
+	counters []*TimedCounter
+}
+
+func (m *metricsSource) track(counters []*TimedCounter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters = append(m.counters, counters...)
+}
+
+func (m *metricsSource) tracked() []*TimedCounter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.counters)
+}
+
+// expvarSnapshot is published through expvar, which serializes whatever it returns as JSON under /debug/vars
+func (m *metricsSource) expvarSnapshot() any {
+	snapshot := map[string]any{}
+	for _, counter := range m.tracked() {
+		entry := map[string]any{
+			"ops":            counter.TotalOps(),
+			"total_time_ns":  counter.TotalTime().Nanoseconds(),
+			"p50_latency_ns": counter.Percentile(0.50).Nanoseconds(),
+			"p90_latency_ns": counter.Percentile(0.90).Nanoseconds(),
+			"p99_latency_ns": counter.Percentile(0.99).Nanoseconds(),
+		}
+		if value, ok := counter.LiveValue(); ok {
+			entry["value"] = value
+		}
+		snapshot[counter.Name()] = entry
+	}
+	return snapshot
+}
+
+// servePrometheus writes the counters in the Prometheus text exposition format, which is simple enough to produce
+// by hand: a HELP and TYPE line per metric followed by one line per labelled sample
+func (m *metricsSource) servePrometheus(w http.ResponseWriter, r *http.Request) {
+	counters := m.tracked()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP counter_value The current value of the counter.")
+	fmt.Fprintln(w, "# TYPE counter_value gauge")
+	for _, counter := range counters {
+		if value, ok := counter.LiveValue(); ok {
+			fmt.Fprintf(w, "counter_value{counter=%q} %d\n", counter.Name(), value)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP counter_operation_duration_seconds The time taken by each counter operation.")
+	fmt.Fprintln(w, "# TYPE counter_operation_duration_seconds summary")
+	for _, counter := range counters {
+		for _, op := range counter.stats.byType() {
+			labels := fmt.Sprintf("counter=%q,op=%q", counter.Name(), op.name)
+			for _, q := range []float64{0.5, 0.9, 0.99} {
+				fmt.Fprintf(w, "counter_operation_duration_seconds{%s,quantile=\"%g\"} %g\n", labels, q, op.stats.Percentile(q).Seconds())
+			}
+			fmt.Fprintf(w, "counter_operation_duration_seconds_sum{%s} %g\n", labels, op.stats.Total().Seconds())
+			fmt.Fprintf(w, "counter_operation_duration_seconds_count{%s} %d\n", labels, op.stats.Count())
+		}
+	}
+}
+
+// serveMetrics starts an HTTP server exposing the counters through expvar at /debug/vars and in Prometheus format
+// at /metrics
+func serveMetrics(addr string) (*metricsSource, error) {
+	source := &metricsSource{}
+	expvar.Publish("counters", expvar.Func(source.expvarSnapshot))
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/metrics", source.servePrometheus)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	go http.Serve(listener, mux)
+	return source, nil
+}
@@ -0,0 +1,48 @@
+package concurrencymatters
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// runPhases runs the workload several times against the same counters, resetting them between phases rather than
+// building new ones, so any difference between phases comes from the counters having already been used, warm caches,
+// grown buffers and running worker goroutines, rather than from fresh allocations
+func runPhases(ctx context.Context, cfg CounterConfig, selected []string, workload Workload, phases int) {
+	steps := make([]int, phases)
+	for i := range steps {
+		steps[i] = i + 1
+	}
+	table := newScalingTable("Phase", steps)
+	counters := newCounters(cfg, selected)
+
+	for _, phase := range table.steps {
+		if phase > 1 {
+			for _, counter := range counters {
+				counter.Reset()
+			}
+		}
+
+		fmt.Printf("Running phase %d of %d...\n", phase, phases)
+		start := time.Now()
+		_, expected, err := runWorkload(ctx, counters, workload)
+		if err != nil {
+			slog.Warn("phase stopped early", "phase", phase, "err", err)
+		}
+
+		for _, counter := range counters {
+			snapshot := counter.Snapshot()
+			fmt.Printf("    %-20s %d (%s) at +%v\n", counter.Name(), snapshot.Value, verdict(snapshot.Value, expected),
+				snapshot.At.Sub(start).Round(time.Microsecond))
+			table.add(counter.Name(), counter.Throughput(parallelism(workload.Routines)))
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+
+	table.print()
+}
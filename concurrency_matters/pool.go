@@ -0,0 +1,207 @@
+package concurrencymatters
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// WorkerPoolCounter spreads the work of a single actor across a fixed pool of worker goroutines all pulling
+// operations from one shared job channel
+// Each worker keeps a partial count of its own, so no two of them ever touch the same count and they need no locking
+// between them, the price is a Value that has to ask every worker for its share, and a shared queue every sender and
+// every worker still contends on
+type WorkerPoolCounter struct {
+	ctx      context.Context
+	buffer   int
+	jobs     chan int
+	workers  []poolWorker
+	finished chan struct{}
+
+	// senders hold sendMu for reading while they send, just as for ChannelCounter, so jobs is never closed under them
+	sendMu    sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+}
+
+// poolWorker is one goroutine of the pool, count is only touched by that goroutine until the pool has finished
+type poolWorker struct {
+	count          int
+	applied        atomic.Int64
+	valueRetrieval chan chan int
+}
+
+// CreateAndRunWorkerPoolCounter starts the given number of workers on a job channel with the given buffer size
+func CreateAndRunWorkerPoolCounter(ctx context.Context, workers, buffer int) *WorkerPoolCounter {
+	c := &WorkerPoolCounter{
+		ctx:     ctx,
+		buffer:  buffer,
+		workers: make([]poolWorker, workers),
+	}
+	c.start()
+	return c
+}
+
+func (c *WorkerPoolCounter) start() {
+	c.jobs = make(chan int, c.buffer)
+	c.finished = make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := range c.workers {
+		w := &c.workers[i]
+		w.valueRetrieval = make(chan chan int)
+		wg.Go(func() {
+			c.run(w)
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(c.finished)
+	}()
+}
+
+// run applies jobs until the job channel is closed and empty, whichever worker is free takes the next job
+func (c *WorkerPoolCounter) run(w *poolWorker) {
+	ctxDone := c.ctx.Done()
+	for {
+		select {
+		case v, ok := <-c.jobs:
+			if !ok {
+				return
+			}
+			w.count += v
+			w.applied.Add(1)
+		case reply := <-w.valueRetrieval:
+			reply <- w.count
+		case <-ctxDone:
+			ctxDone = nil
+			go c.Close()
+		}
+	}
+}
+
+// send queues the job for the pool, once the pool has closed it is added to the first worker's count instead, for the
+// reason ChannelCounter's applyLate gives
+func (c *WorkerPoolCounter) send(value int) {
+	c.sendMu.RLock()
+	if !c.closed {
+		defer c.sendMu.RUnlock()
+		c.jobs <- value
+		return
+	}
+	c.sendMu.RUnlock()
+
+	<-c.finished
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.workers[0].count += value
+}
+
+func (c *WorkerPoolCounter) IncrementBy(value int) {
+	c.send(value)
+}
+
+func (c *WorkerPoolCounter) DecrementBy(value int) {
+	c.send(-value)
+}
+
+// Value collects every worker's share, jobs still queued haven't been applied by anyone yet so aren't included
+func (c *WorkerPoolCounter) Value() int {
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+
+	total := 0
+	for i := range c.workers {
+		reply := make(chan int)
+		select {
+		case c.workers[i].valueRetrieval <- reply:
+			total += <-reply
+		case <-c.finished:
+			return c.settled()
+		}
+	}
+	return total
+}
+
+// settled sums the workers' counts directly, only safe once every worker has finished
+func (c *WorkerPoolCounter) settled() int {
+	total := 0
+	for i := range c.workers {
+		total += c.workers[i].count
+	}
+	return total
+}
+
+func (c *WorkerPoolCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+// Close stops accepting jobs and waits for the pool to work through everything already queued
+func (c *WorkerPoolCounter) Close() {
+	c.closeOnce.Do(func() {
+		c.sendMu.Lock()
+		defer c.sendMu.Unlock()
+		c.closed = true
+		close(c.jobs)
+	})
+	<-c.finished
+}
+
+func (c *WorkerPoolCounter) Drain() int {
+	c.Close()
+
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+	return c.settled()
+}
+
+// Reset closes the pool and starts a fresh one from zero, like ChannelCounter it must not run alongside Close or Drain
+func (c *WorkerPoolCounter) Reset() {
+	c.Close()
+
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	for i := range c.workers {
+		c.workers[i].count = 0
+		c.workers[i].applied.Store(0)
+	}
+	c.closed = false
+	c.closeOnce = sync.Once{}
+	c.start()
+}
+
+// Report shows how evenly the shared queue spread the jobs across the pool
+func (c *WorkerPoolCounter) Report() string {
+	least, most := c.workers[0].applied.Load(), c.workers[0].applied.Load()
+	for i := range c.workers {
+		applied := c.workers[i].applied.Load()
+		least = min(least, applied)
+		most = max(most, applied)
+	}
+	return fmt.Sprintf("%d workers applied between %d and %d jobs each", len(c.workers), least, most)
+}
+
+// printPoolComparison sets the pooled design's mean cost per operation against the single goroutine ones, the actor
+// with its single queue and the channel counter with a queue per operation type
+func printPoolComparison(counters []*TimedCounter) {
+	byName := map[string]*TimedCounter{}
+	for _, counter := range counters {
+		if counter.TotalOps() > 0 {
+			byName[counter.Name()] = counter
+		}
+	}
+	pool, ok := byName["WorkerPool"]
+	if !ok {
+		return
+	}
+
+	poolMean := meanOpNs(pool)
+	fmt.Printf("\nThe worker pool averaged %.1fns per operation", poolMean)
+	for _, name := range []string{"Actor", "Channel"} {
+		if single, ok := byName[name]; ok {
+			fmt.Printf(", the single goroutine %s %.1fns (%.2fx)", name, meanOpNs(single), meanOpNs(single)/poolMean)
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,180 @@
+package concurrencymatters
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Profiles names the profile files to write, an empty name skips that profile
+type Profiles struct {
+	CPU   string
+	Mem   string
+	Mutex string
+}
+
+// startProfiling starts CPU profiling and enables mutex contention sampling as needed, the returned function stops
+// them and writes every requested profile, ready for go tool pprof
+func startProfiling(profiles Profiles, pprofAddr string) (stop func() error, err error) {
+	// every contention event is sampled, it slows contended locks slightly but the counters here are the subject
+	if profiles.Mutex != "" || pprofAddr != "" {
+		runtime.SetMutexProfileFraction(1)
+	}
+
+	// importing net/http/pprof registers its handlers on the default mux, serving that is all the live endpoint needs
+	if pprofAddr != "" {
+		listener, err := net.Listen("tcp", pprofAddr)
+		if err != nil {
+			return nil, err
+		}
+		go http.Serve(listener, http.DefaultServeMux)
+	}
+
+	var cpuFile *os.File
+	if profiles.CPU != "" {
+		cpuFile, err = os.Create(profiles.CPU)
+		if err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(cpuFile); err != nil {
+			cpuFile.Close()
+			return nil, err
+		}
+	}
+
+	return func() error {
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			if err := cpuFile.Close(); err != nil {
+				return err
+			}
+		}
+		if profiles.Mem != "" {
+			// a GC first means the heap profile reflects live memory as of the end of the run
+			runtime.GC()
+			if err := writeProfile("allocs", profiles.Mem); err != nil {
+				return err
+			}
+		}
+		if profiles.Mutex != "" {
+			if err := writeProfile("mutex", profiles.Mutex); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// contentionStats is the lock contention and blocking attributed to one counter implementation
+type contentionStats struct {
+	mutexEvents int64
+	mutexWait   time.Duration
+	blockEvents int64
+	blocked     time.Duration
+}
+
+// enableContentionProfiling has the runtime record every mutex contention and every blocking event from here on
+func enableContentionProfiling() {
+	runtime.SetMutexProfileFraction(1)
+	runtime.SetBlockProfileRate(1)
+}
+
+// cyclesPerSecond reads the tick rate the runtime uses for profile delays from the header of the text form of the
+// profile, which is the only place it is exposed
+func cyclesPerSecond(profile string) float64 {
+	var text strings.Builder
+	pprof.Lookup(profile).WriteTo(&text, 1)
+	for _, line := range strings.Split(text.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, "cycles/second="); ok {
+			cycles, err := strconv.ParseFloat(value, 64)
+			if err == nil && cycles > 0 {
+				return cycles
+			}
+		}
+	}
+	return 1e9
+}
+
+// profileRecords returns every record of the mutex or block profile, growing the slice until the runtime fits
+func profileRecords(read func([]runtime.BlockProfileRecord) (int, bool)) []runtime.BlockProfileRecord {
+	n, _ := read(nil)
+	for {
+		records := make([]runtime.BlockProfileRecord, n+50)
+		count, ok := read(records)
+		if ok {
+			return records[:count]
+		}
+		n = count
+	}
+}
+
+// attributeContention charges each profile record to the counter whose methods appear in its stack, matching on the
+// delegate's type name the way it appears in symbolized frames, main.(*MutexCounter).IncrementBy for example
+func attributeContention(counters []*TimedCounter, records []runtime.BlockProfileRecord, cyclesPerSecond float64, charge func(*contentionStats, int64, time.Duration), stats map[string]*contentionStats) {
+	for _, record := range records {
+		frames := runtime.CallersFrames(record.Stack())
+		for {
+			frame, more := frames.Next()
+			name := ""
+			for _, counter := range counters {
+				if strings.Contains(frame.Function, "(*"+counter.DelegateType()+")") {
+					name = counter.Name()
+					break
+				}
+			}
+			if name != "" {
+				if stats[name] == nil {
+					stats[name] = &contentionStats{}
+				}
+				charge(stats[name], record.Count, time.Duration(float64(record.Cycles)/cyclesPerSecond*float64(time.Second)))
+				break
+			}
+			if !more {
+				break
+			}
+		}
+	}
+}
+
+// printContentionSummary reads the mutex and block profiles gathered during the run and prints, per counter, how
+// often goroutines had to wait and for how long in total, lock contention made visible without any external tooling
+func printContentionSummary(counters []*TimedCounter) {
+	stats := map[string]*contentionStats{}
+	attributeContention(counters, profileRecords(runtime.MutexProfile), cyclesPerSecond("mutex"),
+		func(s *contentionStats, events int64, wait time.Duration) {
+			s.mutexEvents += events
+			s.mutexWait += wait
+		}, stats)
+	attributeContention(counters, profileRecords(runtime.BlockProfile), cyclesPerSecond("block"),
+		func(s *contentionStats, events int64, wait time.Duration) {
+			s.blockEvents += events
+			s.blocked += wait
+		}, stats)
+
+	fmt.Printf("\n%-18s %16s %16s %16s %16s\n", "Contention", "Mutex events", "Mutex wait", "Block events", "Blocked")
+	for _, counter := range counters {
+		s := stats[counter.Name()]
+		if s == nil {
+			s = &contentionStats{}
+		}
+		fmt.Printf("%-18s %16d %16v %16d %16v\n", counter.Name(), s.mutexEvents, s.mutexWait.Round(time.Microsecond),
+			s.blockEvents, s.blocked.Round(time.Microsecond))
+	}
+}
+
+// writeProfile writes one of the runtime's named profiles to a file
+func writeProfile(name, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return pprof.Lookup(name).WriteTo(f, 0)
+}
@@ -0,0 +1,74 @@
+package concurrencymatters
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz counters, it starts from the lost updates of the unsafe counter and works through what
+// atomics, sharding, padding and worker goroutines each trade for throughput
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why did the Unsafe counter's value differ from the expected value?",
+		Choices: []string{
+			"Its int overflowed part way through the run and wrapped around to a negative value",
+			"The workload handed it fewer operations than the other counters, which ran first",
+			"Two goroutines read the same value, each added to it and wrote back, losing one update",
+			"The compiler reordered the additions, and integer addition depends on its order",
+		},
+		Answer:      2,
+		Explanation: "count += n is a read, an add and a write, goroutines interleaving between the read and the write overwrite each other's updates, a data race",
+	},
+	{
+		Prompt: "Why can an atomic counter beat a mutex counter under heavy contention?",
+		Choices: []string{
+			"An atomic add is one instruction, a contended mutex parks the goroutines that lose",
+			"Atomics bypass the CPU cache and write straight to memory, so cores never wait on each other",
+			"Atomic adds are batched by the runtime and applied together at the next preemption point",
+			"The mutex counter is instrumented by the race detector in every build, the atomic one isn't",
+		},
+		Answer:      0,
+		Explanation: "both serialize on one cache line, but the atomic never puts a goroutine to sleep, a contended mutex goes to the scheduler",
+	},
+	{
+		Prompt: "What does the Sharded counter trade for its throughput?",
+		Choices: []string{
+			"Exactness, a shard's update can be lost when two goroutines land on it at once",
+			"Memory and a slower Value, which has to visit and sum every shard",
+			"Ordering, reads can see increments applied out of the order they were made",
+			"Fairness, goroutines on the busiest shard are starved by the others",
+		},
+		Answer:      1,
+		Explanation: "writers spread over the shards so they rarely meet, reads pay for it by visiting every shard",
+	},
+	{
+		Prompt: "Why is PaddedShards faster than AdjacentShards though they do the same work?",
+		Choices: []string{
+			"Padding aligns each shard's mutex, so the lock and unlock can use a cheaper instruction",
+			"The padding keeps each shard on its own cache line, so cores don't invalidate each other's",
+			"Padded shards are big enough that the allocator places them on separate memory pages",
+			"AdjacentShards has fewer shards, since the padding is what decides how many fit",
+		},
+		Answer:      1,
+		Explanation: "that is false sharing, independent variables on one 64 byte cache line bounce it between cores as if they were shared",
+	},
+	{
+		Prompt: "What happens to the channel based counters' goroutines once a run is canceled?",
+		Choices: []string{
+			"They leak until the program exits, there's no way to stop a goroutine from outside it",
+			"The garbage collector finds them blocked on unreachable channels and reclaims them",
+			"They keep serving forever, since a goroutine blocked in select can't see the context",
+			"They watch the context, close the counter, apply what's queued and return, so nothing leaks",
+		},
+		Answer:      3,
+		Explanation: "a goroutine can only be asked to stop, the counters select on ctx.Done() alongside their channels and drain before they return",
+	},
+	{
+		Prompt: "You raise -routines from 8 to 800 and the Mutex counter's throughput barely changes. Why?",
+		Choices: []string{
+			"The lesson caps the number of routines at GOMAXPROCS, so the extra ones never start",
+			"The runtime only runs as many goroutines at once as there are cores, the rest wait to start",
+			"Only one goroutine can hold the lock at a time, more goroutines only lengthen the queue",
+			"Mutex throughput is reported per routine, so the total is divided by the routine count",
+		},
+		Answer:      2,
+		Explanation: "a single lock serializes every update, adding goroutines adds waiting, not work done",
+	},
+}
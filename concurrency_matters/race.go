@@ -0,0 +1,49 @@
+package concurrencymatters
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// runRaceDemo hammers a ThreadUnsafeCounter from a few routines, just enough for the race detector to catch the
+// unsynchronized read-modify-write in IncrementBy, and explains the report it prints
+// Without the detector the only evidence is a wrong total, and only when the scheduler happens to interleave the
+// routines badly, the detector instead flags the missing synchronization every time, whatever the total turns out to be
+func runRaceDemo(ctx context.Context, numRoutines, loops int) {
+	if raceEnabled {
+		fmt.Println("The race detector is on, watch stderr for a report that starts with WARNING: DATA RACE")
+	} else {
+		fmt.Println("The race detector is off, so the only sign of trouble is a total that may come out wrong")
+		fmt.Println("Rerun with: go run -race ./cmd/teachgo counters -demonstrate race")
+	}
+	fmt.Printf("%d routines each increment a ThreadUnsafeCounter by one %d times\n\n", numRoutines, loops)
+
+	counter := &ThreadUnsafeCounter{}
+	var wg sync.WaitGroup
+	for range numRoutines {
+		wg.Go(func() {
+			for range loops {
+				if ctx.Err() != nil {
+					return
+				}
+				counter.IncrementBy(1)
+			}
+		})
+	}
+	wg.Wait()
+
+	expected := numRoutines * loops
+	fmt.Printf("\nThe counter reached %d of an expected %d (%s)\n", counter.Value(), expected, verdict(counter.Value(), expected))
+	if !raceEnabled {
+		return
+	}
+	fmt.Println(`
+Reading the report:
+  - "Read at" or "Write at" is the access that was caught, here count += value in ThreadUnsafeCounter.IncrementBy
+  - "Previous write at" is the earlier access from another goroutine it conflicts with, nothing ordered the two
+  - "Goroutine N created at" shows where each of the two routines was started
+The detector only reports each racing pair of source lines once, however many times they race, and the process exits
+with status 66 once it's done so a test or CI run under -race fails
+A correct total doesn't make the code safe, the race is there whether or not this run happened to lose an update`)
+}
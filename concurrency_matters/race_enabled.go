@@ -0,0 +1,6 @@
+//go:build race
+
+package concurrencymatters
+
+// raceEnabled reports whether the binary was built with the race detector, go run -race or go build -race
+const raceEnabled = true
@@ -0,0 +1,129 @@
+package concurrencymatters
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// recordingMagic starts every recording so replaying some other file fails clearly instead of producing garbage
+const recordingMagic = "TGOPS1"
+
+// Recording is an operation schedule saved to disk together with the seed that generated it
+// The file is the magic string, the seed, the routine count and then, for each routine, its operation count followed
+// by each operation as a kind byte and varint encoded value and key, compact enough to attach to a bug report
+type Recording struct {
+	Seed     int64
+	Schedule [][]scheduledOp
+}
+
+// WriteRecording saves a schedule to path, replacing any existing file
+func WriteRecording(path string, recording Recording) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	w.WriteString(recordingMagic)
+	binary.Write(w, binary.LittleEndian, recording.Seed)
+	w.Write(binary.AppendUvarint(nil, uint64(len(recording.Schedule))))
+
+	buf := []byte{}
+	for _, ops := range recording.Schedule {
+		buf = binary.AppendUvarint(buf[:0], uint64(len(ops)))
+		w.Write(buf)
+		for _, op := range ops {
+			buf = append(buf[:0], byte(op.kind))
+			buf = binary.AppendVarint(buf, int64(op.value))
+			buf = binary.AppendUvarint(buf, uint64(op.key))
+			w.Write(buf)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// ReadRecording loads a schedule saved by WriteRecording
+func ReadRecording(path string) (Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Recording{}, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	magic := make([]byte, len(recordingMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != recordingMagic {
+		return Recording{}, fmt.Errorf("%s is not an operation recording", path)
+	}
+
+	var recording Recording
+	if err := binary.Read(r, binary.LittleEndian, &recording.Seed); err != nil {
+		return Recording{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+	routines, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Recording{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	recording.Schedule = make([][]scheduledOp, routines)
+	for i := range recording.Schedule {
+		count, err := binary.ReadUvarint(r)
+		if err != nil {
+			return Recording{}, fmt.Errorf("reading %s routine %d: %w", path, i, err)
+		}
+		ops := make([]scheduledOp, count)
+		for j := range ops {
+			kind, err := r.ReadByte()
+			if err != nil {
+				return Recording{}, fmt.Errorf("reading %s routine %d: %w", path, i, err)
+			}
+			value, err := binary.ReadVarint(r)
+			if err != nil {
+				return Recording{}, fmt.Errorf("reading %s routine %d: %w", path, i, err)
+			}
+			key, err := binary.ReadUvarint(r)
+			if err != nil {
+				return Recording{}, fmt.Errorf("reading %s routine %d: %w", path, i, err)
+			}
+			if OperationKind(kind) > OpValue {
+				return Recording{}, fmt.Errorf("reading %s routine %d: unknown operation kind %d", path, i, kind)
+			}
+			ops[j] = scheduledOp{kind: OperationKind(kind), value: int(value), key: int(key)}
+		}
+		recording.Schedule[i] = ops
+	}
+
+	if _, err := r.ReadByte(); !errors.Is(err, io.EOF) {
+		return Recording{}, fmt.Errorf("reading %s: unexpected data after the last routine", path)
+	}
+	return recording, nil
+}
+
+// maxKey is the largest key any operation in the recording uses
+func (r Recording) maxKey() int {
+	largest := 0
+	for _, ops := range r.Schedule {
+		for _, op := range ops {
+			largest = max(largest, op.key)
+		}
+	}
+	return largest
+}
+
+// loopsPerRoutine is the longest routine's operation count
+func (r Recording) loopsPerRoutine() int {
+	longest := 0
+	for _, ops := range r.Schedule {
+		longest = max(longest, len(ops))
+	}
+	return longest
+}
@@ -0,0 +1,42 @@
+package concurrencymatters
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRecordingRoundTrip(t *testing.T) {
+	workload := Workload{
+		Routines:        3,
+		LoopsPerRoutine: 50,
+		ReadRatio:       0.2,
+		IncrementRatio:  0.5,
+		ValueRange:      1000,
+		Seed:            42,
+	}
+	recording := Recording{Seed: workload.Seed, Schedule: workload.Schedule(10)}
+
+	path := filepath.Join(t.TempDir(), "ops.bin")
+	if err := WriteRecording(path, recording); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	replayed, err := ReadRecording(path)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if !reflect.DeepEqual(replayed, recording) {
+		t.Error("the replayed recording differs from the one written")
+	}
+}
+
+func TestReadRecordingRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "not-a-recording")
+	if err := os.WriteFile(path, []byte("hello, world"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadRecording(path); err == nil {
+		t.Error("reading a file that isn't a recording returned no error")
+	}
+}
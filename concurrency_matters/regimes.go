@@ -0,0 +1,120 @@
+package concurrencymatters
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+)
+
+// thinking reports whether the workload pauses between operations at all
+func (w Workload) thinking() bool {
+	return w.ThinkTime > 0 || w.Jitter > 0
+}
+
+// pauseAfter is how long a routine rests after its nth operation, nothing inside a burst and the think time plus up
+// to a jitter's worth of random extra once the burst is over
+func (w Workload) pauseAfter(n int, rng *rand.Rand) time.Duration {
+	if !w.thinking() || (n+1)%max(w.Burst, 1) != 0 {
+		return 0
+	}
+	pause := w.ThinkTime
+	if w.Jitter > 0 {
+		pause += time.Duration(rng.Int63n(int64(w.Jitter)))
+	}
+	return pause
+}
+
+// think sleeps for the pause, waking early if ctx is done
+func think(ctx context.Context, pause time.Duration) error {
+	if pause <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(pause)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// regime is one level of contention, set by how long routines rest between operations and how they bunch them up
+type regime struct {
+	name        string
+	description string
+	think       time.Duration
+	jitter      time.Duration
+	burst       int
+}
+
+// contentionRegimes run from every routine hammering the counters to the routines only occasionally touching them
+var contentionRegimes = []regime{
+	{"saturated", "no pauses, every routine operates back to back", 0, 0, 1},
+	{"bursty", "bursts of 100 operations back to back, then a rest of 1ms to 2ms", time.Millisecond, time.Millisecond, 100},
+	{"sparse", "a rest of 20µs to 60µs after every operation", 20 * time.Microsecond, 40 * time.Microsecond, 1},
+}
+
+// runRegimes reruns the workload with fresh counters under each contention regime and prints their throughput side
+// by side, followed by the ranking within each regime
+// Throughput only counts the time spent inside a counter, so the rests don't dilute it, what changes between regimes
+// is how often a routine finds another one already in the counter
+func runRegimes(ctx context.Context, cfg CounterConfig, selected []string, workload Workload) {
+	headers := []string{}
+	for _, r := range contentionRegimes {
+		headers = append(headers, r.name)
+	}
+	table := newLabelledTable("Regime", headers)
+
+	for _, r := range contentionRegimes {
+		slog.Info("running regime", "regime", r.name, "description", r.description)
+
+		step := workload
+		step.ThinkTime, step.Jitter, step.Burst = r.think, r.jitter, r.burst
+		runStep(ctx, cfg, selected, step, table)
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+
+	table.print()
+	printRegimeComparison(table)
+}
+
+// printRegimeComparison ranks the counters within each regime and points out when the fastest one changes, the
+// counter that wins under heavy contention is rarely the one that wins when routines seldom meet
+func printRegimeComparison(table *scalingTable) {
+	if len(table.names) == 0 {
+		return
+	}
+
+	fmt.Println()
+	winners := []string{}
+	for i, header := range table.headers {
+		ranked := slices.Clone(table.names)
+		slices.SortStableFunc(ranked, func(a, b string) int {
+			return cmp.Compare(table.results[b][i], table.results[a][i])
+		})
+		winners = append(winners, ranked[0])
+
+		podium := []string{}
+		for place, name := range ranked[:min(len(ranked), 3)] {
+			podium = append(podium, fmt.Sprintf("%d. %s %s", place+1, name, bench.FormatRate(table.results[name][i])))
+		}
+		fmt.Printf("%-20s %s\n", header, strings.Join(podium, "  "))
+	}
+
+	if slices.ContainsFunc(winners, func(name string) bool { return name != winners[0] }) {
+		fmt.Println("\nThe fastest counter depends on the contention, there is no best counter, only a best one for a workload")
+	} else {
+		fmt.Printf("\n%s was fastest in every regime at these settings, try more routines or -procs to raise the contention\n", winners[0])
+	}
+}
@@ -0,0 +1,110 @@
+package concurrencymatters
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// StripedCounter is lock striping, a fixed array of mutex guarded counts with each goroutine always landing on the
+// same stripe, it sits between MutexCounter's one lock for everyone and LocalCounter's slot per goroutine
+// Unlike ShardedCounter's random pick a goroutine keeps hitting a stripe whose cache line is already in its core's
+// cache, and unlike the per goroutine counters it needs no handle or registration, any goroutine can call it, two
+// goroutines only ever contend when they hash to the same stripe
+type StripedCounter struct {
+	stripes []counterStripe
+}
+
+// counterStripe is padded out to a full cache line so neighbouring stripes don't falsely share one
+type counterStripe struct {
+	mu    sync.Mutex
+	count int
+	ops   int
+	_     [40]byte
+}
+
+func NewStripedCounter(stripes int) *StripedCounter {
+	return &StripedCounter{
+		stripes: make([]counterStripe, max(stripes, 1)),
+	}
+}
+
+// goroutineHash is a cheap stand in for the goroutine id Go deliberately doesn't expose
+// Every goroutine runs on a stack of its own, at least 2KB, so the address of a local variable tells goroutines apart,
+// the low 11 bits vary with call depth and are dropped, the rest are run through splitmix64's finalizer so stacks
+// allocated next to each other land on unrelated stripes
+// A stack can be moved when it grows, the goroutine then simply lands on another stripe, which is still correct, and a
+// new goroutine may be handed the stack of one that has exited, sharing its stripe with a goroutine that is gone
+func goroutineHash() uint64 {
+	var marker byte
+	h := uint64(uintptr(unsafe.Pointer(&marker)) >> 11)
+	h = (h ^ h>>30) * 0xBF58476D1CE4E5B9
+	h = (h ^ h>>27) * 0x94D049BB133111EB
+	return h ^ h>>31
+}
+
+func (c *StripedCounter) stripe() *counterStripe {
+	return &c.stripes[goroutineHash()%uint64(len(c.stripes))]
+}
+
+func (c *StripedCounter) IncrementBy(value int) {
+	s := c.stripe()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count += value
+	s.ops++
+}
+
+func (c *StripedCounter) DecrementBy(value int) {
+	s := c.stripe()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count -= value
+	s.ops++
+}
+
+func (c *StripedCounter) Value() int {
+	total := 0
+	for i := range c.stripes {
+		s := &c.stripes[i]
+		s.mu.Lock()
+		total += s.count
+		s.mu.Unlock()
+	}
+	return total
+}
+
+func (c *StripedCounter) Snapshot() Snapshot {
+	return newSnapshot(c.Value())
+}
+
+func (c *StripedCounter) Reset() {
+	for i := range c.stripes {
+		s := &c.stripes[i]
+		s.mu.Lock()
+		s.count = 0
+		s.ops = 0
+		s.mu.Unlock()
+	}
+}
+
+// Report shows how evenly the goroutines spread over the stripes, a stripe taking far more than its share is where
+// the hash put several busy goroutines together
+func (c *StripedCounter) Report() string {
+	used, busiest, total := 0, 0, 0
+	for i := range c.stripes {
+		s := &c.stripes[i]
+		s.mu.Lock()
+		if s.ops > 0 {
+			used++
+		}
+		busiest = max(busiest, s.ops)
+		total += s.ops
+		s.mu.Unlock()
+	}
+	if total == 0 {
+		return fmt.Sprintf("%d stripes, none used yet", len(c.stripes))
+	}
+	return fmt.Sprintf("%d of %d stripes used, the busiest took %.1f%% of the writes against an even share of %.1f%%",
+		used, len(c.stripes), 100*float64(busiest)/float64(total), 100/float64(len(c.stripes)))
+}
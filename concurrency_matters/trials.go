@@ -0,0 +1,116 @@
+package concurrencymatters
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+)
+
+// tCritical95 holds the two-sided 95% critical values of Student's t distribution for 1 to 30 degrees of freedom,
+// beyond which the normal distribution's 1.96 is close enough
+var tCritical95 = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// trialStats summarises one counter's throughput across repeated trials
+type trialStats struct {
+	name   string
+	mean   float64
+	stddev float64
+	// margin is the half width of the 95% confidence interval around the mean
+	margin float64
+}
+
+func (s trialStats) low() float64 {
+	return s.mean - s.margin
+}
+
+func (s trialStats) high() float64 {
+	return s.mean + s.margin
+}
+
+// summariseTrials computes the mean, sample standard deviation and 95% confidence interval of a set of measurements
+// The interval uses the t distribution as a handful of trials is far too few to assume the normal one
+func summariseTrials(name string, samples []float64) trialStats {
+	n := float64(len(samples))
+	var sum float64
+	for _, sample := range samples {
+		sum += sample
+	}
+	mean := sum / n
+
+	var squares float64
+	for _, sample := range samples {
+		squares += (sample - mean) * (sample - mean)
+	}
+	stddev := math.Sqrt(squares / (n - 1))
+
+	critical := 1.96
+	if df := len(samples) - 1; df <= len(tCritical95) {
+		critical = tCritical95[df-1]
+	}
+	return trialStats{
+		name:   name,
+		mean:   mean,
+		stddev: stddev,
+		margin: critical * stddev / math.Sqrt(n),
+	}
+}
+
+// runTrials repeats the identical workload against fresh counters the given number of times and prints each counter's
+// mean throughput with its spread, so a difference that only shows up in one lucky run can be told from a real one
+func runTrials(ctx context.Context, cfg CounterConfig, selected []string, workload Workload, trials int) {
+	steps := make([]int, trials)
+	for i := range steps {
+		steps[i] = i + 1
+	}
+	table := newScalingTable("Trial", steps)
+
+	for _, trial := range table.steps {
+		slog.Info("running trial", "trial", trial, "of", trials)
+		runStep(ctx, cfg, selected, workload, table)
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+
+	table.print()
+	printTrialComparison(table)
+}
+
+// printTrialComparison prints the statistics of every counter, fastest mean first, marking with an asterisk each one
+// whose confidence interval doesn't overlap the fastest's
+// Non-overlapping intervals are a conservative test, a difference without the marker may still be real but these runs
+// aren't enough to tell it from noise
+func printTrialComparison(table *scalingTable) {
+	stats := []trialStats{}
+	for _, name := range table.names {
+		stats = append(stats, summariseTrials(name, table.results[name]))
+	}
+	if len(stats) == 0 {
+		return
+	}
+	slices.SortFunc(stats, func(a, b trialStats) int {
+		return cmp.Compare(b.mean, a.mean)
+	})
+
+	fastest := stats[0]
+	fmt.Printf("\n%-20s %12s %12s %8s %27s %10s\n", "Counter", "Mean", "Stddev", "CV", "95% confidence interval", "Relative")
+	for _, s := range stats {
+		marker := ""
+		if s.name != fastest.name && s.high() < fastest.low() {
+			marker = " *"
+		}
+		fmt.Printf("%-20s %12s %12s %7.1f%% %27s %9.2fx%s\n", s.name, bench.FormatRate(s.mean), bench.FormatRate(s.stddev),
+			100*s.stddev/s.mean, fmt.Sprintf("%s - %s", bench.FormatRate(s.low()), bench.FormatRate(s.high())), fastest.mean/s.mean, marker)
+	}
+	fmt.Printf("\n* significantly slower than %s, the 95%% confidence intervals don't overlap\n", fastest.name)
+}
@@ -0,0 +1,336 @@
+// Package consistenthashing is the consistent hashing lesson, teachgo hashring, a hash ring with virtual nodes, how
+// evenly it spreads keys and how few of them move when a node joins or leaves, against hashing modulo the node count
+package consistenthashing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo hashring -h, its first line is the summary teachgo help
+// lists
+const Description = `Consistent hashing, a hash ring with virtual nodes
+
+Spreads keys over a set of nodes with a consistent hash ring, each node hashed onto a circle at many points, its
+virtual nodes, and each key owned by the next point round from its own hash. Reports how evenly the keys land at
+several numbers of virtual nodes per node, then adds a node and removes one, counting the keys that move, and
+compares both with the naive hash mod n, which spreads keys evenly but moves almost all of them whenever n changes.
+The ring is how distributed caches and databases split their data between servers that come and go.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "hashring",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"hashing", "consistent hashing", "virtual nodes", "partitioning", "distributed systems"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// vnodeSweep is the numbers of virtual nodes per node the distribution is measured at, along with -vnodes
+var vnodeSweep = []int{1, 10, 100, 1000}
+
+// placement is what the lesson needs of the ring and of mod n hashing, which node a key belongs to as nodes come
+// and go
+type placement interface {
+	Add(node string)
+	Remove(node string)
+	Get(key string) string
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Nodes  int   `json:"nodes"`
+	Keys   int   `json:"keys"`
+	VNodes int   `json:"vnodes"`
+	Seed   int64 `json:"seed"`
+}
+
+// Distribution is how evenly one placement spread the keys, the counts are keys per node, StdDev is relative to the
+// mean, 0.1 when a typical node is 10% off the even share
+type Distribution struct {
+	Name   string  `json:"name"`
+	VNodes int     `json:"vnodes,omitempty"`
+	Min    int     `json:"min"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std_dev"`
+}
+
+// Change is the keys that moved when a node joined or left, Ideal is the fewest that could have, the new node's
+// even share when one joins and the departing node's keys when one leaves
+// RingLocal is whether every key the ring moved went to the node that joined or came from the node that left
+type Change struct {
+	Event     string `json:"event"`
+	Ideal     int    `json:"ideal"`
+	RingMoved int    `json:"ring_moved"`
+	ModMoved  int    `json:"mod_moved"`
+	RingLocal bool   `json:"ring_local"`
+}
+
+// Lookup is how long finding a key's node took, per key
+type Lookup struct {
+	Name   string  `json:"name"`
+	VNodes int     `json:"vnodes,omitempty"`
+	Ns     float64 `json:"ns"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config        RunConfig      `json:"config"`
+	Env           bench.Env      `json:"env"`
+	Distributions []Distribution `json:"distributions"`
+	Changes       []Change       `json:"changes"`
+	Lookups       []Lookup       `json:"lookups"`
+}
+
+func nodeName(i int) string {
+	return "node-" + strconv.Itoa(i)
+}
+
+func makeKeys(rng *rand.Rand, n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("user:%016x", rng.Uint64())
+	}
+	return keys
+}
+
+// assign returns the node each key belongs to
+func assign(p placement, keys []string) []string {
+	owners := make([]string, len(keys))
+	for i, key := range keys {
+		owners[i] = p.Get(key)
+	}
+	return owners
+}
+
+// distribution counts the keys each of the nodes was given
+func distribution(name string, vnodes int, nodes []string, owners []string) Distribution {
+	counts := map[string]int{}
+	for _, owner := range owners {
+		counts[owner]++
+	}
+	d := Distribution{Name: name, VNodes: vnodes, Min: math.MaxInt, Mean: float64(len(owners)) / float64(len(nodes))}
+	variance := 0.0
+	for _, node := range nodes {
+		c := counts[node]
+		d.Min, d.Max = min(d.Min, c), max(d.Max, c)
+		variance += (float64(c) - d.Mean) * (float64(c) - d.Mean)
+	}
+	d.StdDev = math.Sqrt(variance/float64(len(nodes))) / d.Mean
+	return d
+}
+
+// change applies a node joining or leaving, changed, to both placements and counts the keys each moved
+func change(event, changed string, ring *Ring, mod *ModHash, keys []string, apply func(placement)) Change {
+	ringBefore, modBefore := assign(ring, keys), assign(mod, keys)
+	apply(ring)
+	apply(mod)
+	ringAfter, modAfter := assign(ring, keys), assign(mod, keys)
+	c := Change{Event: event, RingLocal: true}
+	for i := range keys {
+		if ringBefore[i] != ringAfter[i] {
+			c.RingMoved++
+			c.RingLocal = c.RingLocal && (ringBefore[i] == changed || ringAfter[i] == changed)
+		}
+		if modBefore[i] != modAfter[i] {
+			c.ModMoved++
+		}
+	}
+	return c
+}
+
+// timeLookups finds the node of every key once
+func timeLookups(ctx context.Context, name string, vnodes int, p placement, keys []string) Lookup {
+	elapsed := bench.Phase(ctx, "lookup "+name, func() {
+		for _, key := range keys {
+			p.Get(key)
+		}
+	})
+	return Lookup{Name: name, VNodes: vnodes, Ns: perOp(elapsed, len(keys))}
+}
+
+func perOp(d time.Duration, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(d.Nanoseconds()) / float64(n)
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Consistent Hashing")
+	fmt.Fprintln(w, "==================")
+	fmt.Fprintf(w, "Nodes: %d\nKeys: %d\nVirtual nodes per node: %d\n", config.Nodes, config.Keys, config.VNodes)
+	fmt.Fprintf(w, "Machine: %s\n", result.Env)
+
+	fmt.Fprintf(w, "\n=====Keys per node, an even share is %.0f=====\n", float64(config.Keys)/float64(config.Nodes))
+	fmt.Fprintf(w, "%-10s %8s %10s %10s %10s %10s\n", "Placement", "Vnodes", "Min", "Max", "Std dev", "Max/mean")
+	for _, d := range result.Distributions {
+		vnodes := "-"
+		if d.VNodes > 0 {
+			vnodes = strconv.Itoa(d.VNodes)
+		}
+		fmt.Fprintf(w, "%-10s %8s %10d %10d %9.1f%% %10.2f\n", d.Name, vnodes, d.Min, d.Max, 100*d.StdDev, float64(d.Max)/d.Mean)
+	}
+	fmt.Fprintln(w, "\nWith one point each, a node's share is the arc before its point, and arcs vary wildly, the spread shrinks")
+	fmt.Fprintln(w, "with the square root of the virtual nodes, each node's share the sum of many small arcs")
+
+	fmt.Fprintf(w, "\n=====Keys moved, ring with %d virtual nodes per node=====\n", config.VNodes)
+	fmt.Fprintf(w, "%-16s %10s %12s %12s %12s\n", "Change", "Ideal", "Ring moved", "Mod moved", "Ring local")
+	for _, c := range result.Changes {
+		fmt.Fprintf(w, "%-16s %10d %12d %12d %12v\n", c.Event, c.Ideal, c.RingMoved, c.ModMoved, c.RingLocal)
+	}
+	fmt.Fprintln(w, "\nThe ring moves only the keys the new node takes or the old node held, close to the fewest possible, mod n")
+	fmt.Fprintln(w, "moves a key whenever its hash mod n changes with n, which is almost always")
+
+	fmt.Fprintln(w, "\n=====Lookups=====")
+	fmt.Fprintf(w, "%-10s %8s %12s\n", "Placement", "Vnodes", "Per key")
+	for _, l := range result.Lookups {
+		vnodes := "-"
+		if l.VNodes > 0 {
+			vnodes = strconv.Itoa(l.VNodes)
+		}
+		fmt.Fprintf(w, "%-10s %8s %12v\n", l.Name, vnodes, time.Duration(l.Ns))
+	}
+	fmt.Fprintln(w, "\nThe ring pays a binary search over every node's points per lookup, mod n a single division")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo hashring
+func Main(args []string) {
+	fs := bench.NewFlagSet("hashring", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the consistent hashing lesson measures a single run"
+	nodes := fs.Int("nodes", 10, "the number of nodes the keys are spread over before one is added and one removed")
+	keys := fs.Int("keys", 500_000, "the number of keys spread over the nodes")
+	vnodes := fs.Int("vnodes", 100, "the number of virtual nodes per node on the ring that's timed and has nodes added and removed")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "hashring measures a single run, -trials isn't supported")
+	v.AtLeast("nodes", *nodes, 2)
+	v.AtLeast("keys", *keys, 1)
+	v.AtLeast("vnodes", *vnodes, 1)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	result := RunResult{
+		Config: RunConfig{Nodes: *nodes, Keys: *keys, VNodes: *vnodes, Seed: globals.Seed},
+		Env:    bench.CaptureEnv(),
+	}
+	rng := rand.New(rand.NewSource(globals.Seed))
+	ctx := context.Background()
+	slog.Info("generating keys", "keys", *keys)
+	keyList := makeKeys(rng, *keys)
+	names := make([]string, *nodes)
+	for i := range names {
+		names[i] = nodeName(i)
+	}
+
+	sweep := vnodeSweep
+	if !slices.Contains(sweep, *vnodes) {
+		sweep = append(slices.Clone(sweep), *vnodes)
+		slices.Sort(sweep)
+	}
+	for _, vn := range sweep {
+		slog.Info("measuring distribution", "vnodes", vn)
+		ring := NewRing(vn)
+		for _, name := range names {
+			ring.Add(name)
+		}
+		result.Distributions = append(result.Distributions, distribution("ring", vn, names, assign(ring, keyList)))
+	}
+	mod := &ModHash{}
+	for _, name := range names {
+		mod.Add(name)
+	}
+	result.Distributions = append(result.Distributions, distribution("mod n", 0, names, assign(mod, keyList)))
+
+	ring := NewRing(*vnodes)
+	for _, name := range names {
+		ring.Add(name)
+	}
+	result.Lookups = []Lookup{
+		timeLookups(ctx, "ring", *vnodes, ring, keyList),
+		timeLookups(ctx, "mod n", 0, mod, keyList),
+	}
+
+	slog.Info("adding and removing nodes")
+	added, removed := nodeName(*nodes), names[0]
+	joined := change("add "+added, added, ring, mod, keyList, func(p placement) { p.Add(added) })
+	joined.Ideal = *keys / (*nodes + 1)
+	// the ideal for a departure is what the departing node holds, counted before it goes
+	held := 0
+	for _, owner := range assign(ring, keyList) {
+		if owner == removed {
+			held++
+		}
+	}
+	left := change("remove "+removed, removed, ring, mod, keyList, func(p placement) { p.Remove(removed) })
+	left.Ideal = held
+	result.Changes = []Change{joined, left}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per placement timing its lookups, and a line per change with the fraction of the
+// keys each placement moved, moved/key, which benchstat can compare between runs like any other unit
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/consistent_hashing"); err != nil {
+		return err
+	}
+	config := result.Config
+	benchmarks := []bench.Benchmark{}
+	for _, l := range result.Lookups {
+		name := fmt.Sprintf("Lookup/%s/nodes=%d", l.Name, config.Nodes)
+		if l.VNodes > 0 {
+			name += fmt.Sprintf("/vnodes=%d", l.VNodes)
+		}
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name:    name,
+			N:       int64(config.Keys),
+			Metrics: []bench.Metric{{Value: l.Ns, Unit: "ns/op"}},
+		})
+	}
+	for _, c := range result.Changes {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: fmt.Sprintf("Change/%s/nodes=%d/vnodes=%d", c.Event, config.Nodes, config.VNodes),
+			N:    int64(config.Keys),
+			Metrics: []bench.Metric{
+				{Value: float64(c.RingMoved) / float64(config.Keys), Unit: "ring-moved/key"},
+				{Value: float64(c.ModMoved) / float64(config.Keys), Unit: "mod-moved/key"},
+			},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
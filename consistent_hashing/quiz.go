@@ -0,0 +1,63 @@
+package consistenthashing
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz hashring, it compares how many keys move under mod n and on the ring when nodes join
+// and leave, and what virtual nodes do for the spread of keys and cost a lookup
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does adding an eleventh node to ten move about nine keys in ten with mod n hashing?",
+		Choices: []string{
+			"A key stays only if its hash mod 10 and mod 11 name the same node, about one key in eleven",
+			"The hash is seeded with the node count, so every key hashes to a new value once it changes",
+			"Every key is first moved to the new node and then rebalanced back out across the others",
+			"Mod n spreads keys unevenly, so the new node takes most of the keys from the busiest ones",
+		},
+		Answer:      0,
+		Explanation: "only the new node's share of keys had to move, a tenth or so, mod n moves nearly all of them, a cache would lose almost every entry",
+	},
+	{
+		Prompt: "When a node joins the ring, where do the keys that move come from?",
+		Choices: []string{
+			"Evenly from every node, each giving up a tenth of its keys whatever its number of points",
+			"Only from the node holding the most keys, which the ring splits in two",
+			"From the nodes whose points follow each new point, the keys between it and the point before",
+			"From whichever node was added last, since its points are the newest on the ring",
+		},
+		Answer:      2,
+		Explanation: "every key that moves goes to the new node, which is what the Ring local column checks, no key moves between two old nodes",
+	},
+	{
+		Prompt: "Why is the spread of keys so uneven with one virtual node per node?",
+		Choices: []string{
+			"The hash function clusters similar node names together, so their points sit side by side",
+			"The binary search favours the first point on the ring when a key's hash falls between two",
+			"The keys are drawn from a skewed distribution, so some arcs are much busier than others",
+			"Each node owns the arc before its one point, and the gaps between a few random points vary widely",
+		},
+		Answer:      3,
+		Explanation: "with many points per node each node owns the sum of many small arcs, which averages out, the spread shrinking with the square root of the points",
+	},
+	{
+		Prompt: "What does a ring lookup cost compared with mod n?",
+		Choices: []string{
+			"The same, both are one hash and one division",
+			"A hash and a binary search over all the points",
+			"A hash and a linear scan of every point",
+			"A hash per node, keeping the largest of them",
+		},
+		Answer:      1,
+		Explanation: "a binary search over every node's points is O(log(nodes x vnodes)), more virtual nodes even out the keys and cost a few more steps of it, a small price for moving few keys",
+	},
+	{
+		Prompt: "When a node leaves the ring, which keys move?",
+		Choices: []string{
+			"About half of all keys, as the remaining nodes split the ring again between them",
+			"Just the keys the node held, each to the node whose point follows the removed one",
+			"None at first, the removed node's keys are lost until they are written again",
+			"Every key, the ring's points are recomputed from the new list of nodes",
+		},
+		Answer:      1,
+		Explanation: "the keys moved match the Ideal column exactly, and with many virtual nodes they scatter over all the remaining nodes instead of landing on one",
+	},
+}
@@ -0,0 +1,109 @@
+package consistenthashing
+
+import (
+	"cmp"
+	"hash/fnv"
+	"slices"
+	"strconv"
+)
+
+// Hash maps a string to 64 bits, FNV-1a followed by splitmix64's finalizer, FNV alone leaves strings that differ only
+// in their last characters, node-1#7 and node-1#8, close together, and points close together on the ring bunch up
+func Hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// point is one of a node's virtual nodes, its position on the ring
+type point struct {
+	hash uint64
+	node string
+}
+
+// Ring is a consistent hash ring, every node is hashed onto a circle of 2^64 positions at vnodes points, its virtual
+// nodes, and a key belongs to the first point clockwise from the key's own hash
+// Adding a node only takes the keys that fall just before its new points, and removing one only hands its keys to the
+// points that follow, every other key stays where it was
+type Ring struct {
+	vnodes int
+	// points is sorted by hash, so the point owning a key is found by binary search
+	points []point
+	nodes  []string
+}
+
+// NewRing creates an empty ring placing each node at vnodes points
+func NewRing(vnodes int) *Ring {
+	return &Ring{vnodes: vnodes}
+}
+
+// Add places a node on the ring, adding a node already on it does nothing
+func (r *Ring) Add(node string) {
+	if slices.Contains(r.nodes, node) {
+		return
+	}
+	r.nodes = append(r.nodes, node)
+	for i := range r.vnodes {
+		r.points = append(r.points, point{Hash(node + "#" + strconv.Itoa(i)), node})
+	}
+	slices.SortFunc(r.points, func(a, b point) int { return cmp.Compare(a.hash, b.hash) })
+}
+
+// Remove takes a node and all its points off the ring
+func (r *Ring) Remove(node string) {
+	r.nodes = slices.DeleteFunc(r.nodes, func(n string) bool { return n == node })
+	r.points = slices.DeleteFunc(r.points, func(p point) bool { return p.node == node })
+}
+
+// Get returns the node a key belongs to, the empty string when the ring has no nodes
+func (r *Ring) Get(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := Hash(key)
+	i, _ := slices.BinarySearchFunc(r.points, h, func(p point, h uint64) int { return cmp.Compare(p.hash, h) })
+	// past the last point the circle wraps around to the first
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].node
+}
+
+// Nodes returns the nodes on the ring in the order they were added
+func (r *Ring) Nodes() []string {
+	return slices.Clone(r.nodes)
+}
+
+// Points is the number of virtual nodes on the ring
+func (r *Ring) Points() int {
+	return len(r.points)
+}
+
+// ModHash is the naive way to spread keys, a key belongs to node hash mod n, even as the ring it costs a single hash,
+// but changing n changes almost every key's remainder, adding a tenth node to nine moves nine keys in ten
+type ModHash struct {
+	nodes []string
+}
+
+func (m *ModHash) Add(node string) {
+	if !slices.Contains(m.nodes, node) {
+		m.nodes = append(m.nodes, node)
+	}
+}
+
+func (m *ModHash) Remove(node string) {
+	m.nodes = slices.DeleteFunc(m.nodes, func(n string) bool { return n == node })
+}
+
+func (m *ModHash) Get(key string) string {
+	if len(m.nodes) == 0 {
+		return ""
+	}
+	return m.nodes[Hash(key)%uint64(len(m.nodes))]
+}
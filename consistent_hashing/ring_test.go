@@ -0,0 +1,86 @@
+package consistenthashing
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEmptyRingOwnsNothing(t *testing.T) {
+	if got := NewRing(10).Get("key"); got != "" {
+		t.Errorf("Get on an empty ring = %q, want \"\"", got)
+	}
+	if got := (&ModHash{}).Get("key"); got != "" {
+		t.Errorf("Get with no nodes = %q, want \"\"", got)
+	}
+}
+
+func TestRingAddAndRemove(t *testing.T) {
+	r := NewRing(50)
+	for i := range 5 {
+		r.Add(nodeName(i))
+	}
+	r.Add(nodeName(0))
+	if r.Points() != 250 || len(r.Nodes()) != 5 {
+		t.Fatalf("after adding 5 nodes, one twice, Points() = %d and %d nodes, want 250 and 5", r.Points(), len(r.Nodes()))
+	}
+	r.Remove(nodeName(2))
+	if r.Points() != 200 || len(r.Nodes()) != 4 {
+		t.Fatalf("after removing a node, Points() = %d and %d nodes, want 200 and 4", r.Points(), len(r.Nodes()))
+	}
+	for i := range 1000 {
+		if r.Get(fmt.Sprint(i)) == nodeName(2) {
+			t.Fatalf("key %d belongs to a removed node", i)
+		}
+	}
+}
+
+func TestRingMovesOnlyTheChangedNodesKeys(t *testing.T) {
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	r, m := NewRing(100), &ModHash{}
+	for i := range 8 {
+		r.Add(nodeName(i))
+		m.Add(nodeName(i))
+	}
+	added := change("add", nodeName(8), r, m, keys, func(p placement) { p.Add(nodeName(8)) })
+	if !added.RingLocal {
+		t.Error("adding a node moved keys between the old nodes")
+	}
+	// the new node's fair share is a ninth, mod n keeps only about a ninth in place
+	if added.RingMoved > len(keys)/5 || added.ModMoved < len(keys)*3/4 {
+		t.Errorf("adding a ninth node moved %d keys on the ring and %d by mod n, of %d", added.RingMoved, added.ModMoved, len(keys))
+	}
+	held := 0
+	for _, owner := range assign(r, keys) {
+		if owner == nodeName(3) {
+			held++
+		}
+	}
+	removed := change("remove", nodeName(3), r, m, keys, func(p placement) { p.Remove(nodeName(3)) })
+	if !removed.RingLocal || removed.RingMoved != held {
+		t.Errorf("removing a node holding %d keys moved %d, local %v", held, removed.RingMoved, removed.RingLocal)
+	}
+}
+
+func TestVirtualNodesEvenOutTheKeys(t *testing.T) {
+	keys := make([]string, 20000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	nodes := []string{}
+	for i := range 10 {
+		nodes = append(nodes, nodeName(i))
+	}
+	spread := func(vnodes int) float64 {
+		r := NewRing(vnodes)
+		for _, node := range nodes {
+			r.Add(node)
+		}
+		return distribution("ring", vnodes, nodes, assign(r, keys)).StdDev
+	}
+	if one, many := spread(1), spread(500); many >= one || many > 0.1 {
+		t.Errorf("relative std dev is %.3f with 1 vnode and %.3f with 500, want 500 far more even", one, many)
+	}
+}
@@ -0,0 +1,274 @@
+// Package countminsketch is the count-min sketch lesson, teachgo countmin, a count-min sketch counting a skewed
+// stream in fixed memory against an exact map, and the heavy hitters each finds
+package countminsketch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/datagen"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo countmin -h, its first line is the summary teachgo help
+// lists
+const Description = `Count-min sketch, approximate counts and heavy hitters in fixed memory
+
+Counts how often each key occurs in a zipfian stream, a few keys very common and most rare, three ways, exactly
+with a map, with a count-min sketch, rows of counters that every key bumps one of per row, and with a sketch that
+updates conservatively. Reports the memory each takes and how far the sketches' estimates are above the true
+counts, against the bound theory gives, and lists the top keys each found, showing the sketch getting the heavy
+hitters right in a fraction of the memory while overcounting the rare keys that share counters with them.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "countmin",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"probabilistic data structures", "streaming algorithms", "hashing", "heavy hitters"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// mapBytesPerKey is the least an exact count costs in a map[int]int, the key and the count, the map's own control
+// bytes and spare slots come on top
+const mapBytesPerKey = 16
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Stream       int     `json:"stream"`
+	Universe     int     `json:"universe"`
+	Distribution string  `json:"distribution"`
+	Epsilon      float64 `json:"epsilon"`
+	Delta        float64 `json:"delta"`
+	Width        int     `json:"width"`
+	Depth        int     `json:"depth"`
+	K            int     `json:"k"`
+	Seed         int64   `json:"seed"`
+}
+
+// CounterResult is what a run measured of one way of counting, the errors are estimate minus true count over every
+// distinct key, WithinBound the fraction of keys overestimated by no more than epsilon times the stream's length,
+// and Recall how many of the true top K it also put in its top K, left zero for the exact map
+type CounterResult struct {
+	Name        string    `json:"name"`
+	Exact       bool      `json:"exact"`
+	Ns          float64   `json:"ns"`
+	Bytes       int       `json:"bytes"`
+	MeanError   float64   `json:"mean_error"`
+	MaxError    int       `json:"max_error"`
+	WithinBound float64   `json:"within_bound"`
+	Recall      int       `json:"recall"`
+	Top         []Counted `json:"top"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config   RunConfig       `json:"config"`
+	Env      bench.Env       `json:"env"`
+	Distinct int             `json:"distinct"`
+	Bound    int             `json:"bound"`
+	Counters []CounterResult `json:"counters"`
+}
+
+// countExactly counts the stream in a map, its top K found once the stream has ended
+func countExactly(ctx context.Context, stream []int, k int) (map[int]int, CounterResult) {
+	counts := map[int]int{}
+	elapsed := bench.Phase(ctx, "exact", func() {
+		for _, key := range stream {
+			counts[key]++
+		}
+	})
+	return counts, CounterResult{
+		Name:  "Exact map",
+		Exact: true,
+		Ns:    perOp(elapsed, len(stream)),
+		Bytes: mapBytesPerKey * len(counts),
+		Top:   TopK(counts, k),
+	}
+}
+
+// countWithSketch counts the stream in the sketch, offering each key's new estimate to a heavy hitter tracker, then
+// compares every distinct key's estimate with its exact count
+func countWithSketch(ctx context.Context, name string, s *Sketch, stream []int, k int, exact map[int]int, exactTop []Counted, bound int) CounterResult {
+	hitters := NewHeavyHitters(k)
+	elapsed := bench.Phase(ctx, name, func() {
+		for _, key := range stream {
+			hitters.Offer(key, s.Add(key))
+		}
+	})
+	result := CounterResult{Name: name, Ns: perOp(elapsed, len(stream)), Bytes: s.Bytes(), Top: hitters.Top()}
+	within, total := 0, 0
+	for key, count := range exact {
+		overcount := s.Count(key) - count
+		total += overcount
+		result.MaxError = max(result.MaxError, overcount)
+		if overcount <= bound {
+			within++
+		}
+	}
+	result.MeanError = float64(total) / float64(len(exact))
+	result.WithinBound = float64(within) / float64(len(exact))
+	for _, want := range exactTop {
+		for _, got := range result.Top {
+			if got.Key == want.Key {
+				result.Recall++
+			}
+		}
+	}
+	return result
+}
+
+func perOp(d time.Duration, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(d.Nanoseconds()) / float64(n)
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Count-Min Sketch")
+	fmt.Fprintln(w, "================")
+	fmt.Fprintf(w, "Stream: %d keys drawn %s from [0, %d)\nEpsilon: %g\nDelta: %g\nSketch: %d counters wide, %d rows deep\n",
+		config.Stream, config.Distribution, config.Universe, config.Epsilon, config.Delta, config.Width, config.Depth)
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%d distinct keys, estimates should be at most epsilon x %d = %d over, with probability %g\n",
+		result.Distinct, config.Stream, result.Bound, 1-config.Delta)
+	fmt.Fprintf(w, "%-14s %12s %12s %12s %12s %14s %10s\n", "Counter", "Add/op", "Memory", "Mean over", "Max over", "Within bound", "Top recall")
+	for _, c := range result.Counters {
+		memory := fmt.Sprintf("%d KiB", c.Bytes/1024)
+		within, recall := fmt.Sprintf("%.2f%%", 100*c.WithinBound), fmt.Sprintf("%d/%d", c.Recall, config.K)
+		if c.Exact {
+			memory, within, recall = ">"+memory, "-", "-"
+		}
+		fmt.Fprintf(w, "%-14s %12v %12s %12.1f %12d %14s %10s\n", c.Name, time.Duration(c.Ns), memory,
+			c.MeanError, c.MaxError, within, recall)
+	}
+
+	fmt.Fprintf(w, "\n=====Top %d, key (count)=====\n", config.K)
+	fmt.Fprintf(w, "%-6s", "Rank")
+	for _, c := range result.Counters {
+		fmt.Fprintf(w, " %24s", c.Name)
+	}
+	fmt.Fprintln(w)
+	for rank := range config.K {
+		fmt.Fprintf(w, "%-6d", rank+1)
+		for _, c := range result.Counters {
+			cell := ""
+			if rank < len(c.Top) {
+				cell = fmt.Sprintf("%d (%d)", c.Top[rank].Key, c.Top[rank].Count)
+			}
+			fmt.Fprintf(w, " %24s", cell)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "\nEvery estimate is the true count plus the other keys sharing its least crowded counter, never under, a")
+	fmt.Fprintln(w, "heavy hitter's few extra are lost in its count, a rare key's can be many times it, conservative updates")
+	fmt.Fprintln(w, "only raise the counters at the key's estimate, so the crowded counters grow slower")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo countmin
+func Main(args []string) {
+	fs := bench.NewFlagSet("countmin", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the count-min sketch lesson measures a single run"
+	streamLength := fs.Int("stream", 5_000_000, "the number of keys in the stream")
+	universe := fs.Int("universe", 1_000_000, "the number of different keys the stream is drawn from")
+	distribution := fs.String("dist", "zipfian", "how the stream's keys are drawn from the universe: "+datagen.Usage)
+	epsilon := fs.Float64("epsilon", 0.0005, "the error bound as a fraction of the stream's length, the sketch is e/epsilon counters wide")
+	delta := fs.Float64("delta", 0.01, "the chance an estimate exceeds the bound, the sketch is ln(1/delta) rows deep")
+	k := fs.Int("k", 10, "the number of heavy hitters to find")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "countmin measures a single run, -trials isn't supported")
+	v.AtLeast("stream", *streamLength, 1)
+	v.AtLeast("universe", *universe, 1)
+	v.Check(*epsilon > 0 && *epsilon < 1, "-epsilon must be between 0 and 1, got %g", *epsilon)
+	v.Check(*delta > 0 && *delta < 1, "-delta must be between 0 and 1, got %g", *delta)
+	v.AtLeast("k", *k, 1)
+	if *universe >= 1 {
+		_, err := datagen.Parse(*distribution, *universe)
+		v.Add(err)
+	}
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	dist := datagen.MustParse(*distribution, *universe)
+	width, depth := Dimensions(*epsilon, *delta)
+	rng := rand.New(rand.NewSource(globals.Seed))
+	slog.Info("generating stream", "stream", *streamLength, "distribution", dist)
+	stream := dist.Fill(rng, *streamLength)
+
+	result := RunResult{
+		Config: RunConfig{Stream: *streamLength, Universe: *universe, Distribution: dist.String(), Epsilon: *epsilon,
+			Delta: *delta, Width: width, Depth: depth, K: *k, Seed: globals.Seed},
+		Env:   bench.CaptureEnv(),
+		Bound: int(*epsilon * float64(*streamLength)),
+	}
+	ctx := context.Background()
+	slog.Info("counting exactly")
+	exact, exactResult := countExactly(ctx, stream, *k)
+	result.Distinct = len(exact)
+	result.Counters = append(result.Counters, exactResult)
+	for _, s := range []struct {
+		name   string
+		sketch *Sketch
+	}{
+		{"Sketch", NewSketch(width, depth)},
+		{"Conservative", NewConservativeSketch(width, depth)},
+	} {
+		slog.Info("counting with a sketch", "sketch", s.name)
+		result.Counters = append(result.Counters, countWithSketch(ctx, s.name, s.sketch, stream, *k, exact, exactResult.Top, result.Bound))
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per counter with the time to count one key, its memory, and how far over its
+// estimates were on average, over/key, zero for the exact map
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/count_min_sketch"); err != nil {
+		return err
+	}
+	config := result.Config
+	benchmarks := []bench.Benchmark{}
+	for _, c := range result.Counters {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: fmt.Sprintf("Count/%s/stream=%d/width=%d/depth=%d", c.Name, config.Stream, config.Width, config.Depth),
+			N:    int64(config.Stream),
+			Metrics: []bench.Metric{
+				{Value: c.Ns, Unit: "ns/op"},
+				{Value: float64(c.Bytes), Unit: "B"},
+				{Value: c.MeanError, Unit: "over/key"},
+			},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
@@ -0,0 +1,63 @@
+package countminsketch
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz countmin, it asks why the sketch only ever overestimates, how width and depth shape
+// the error, and what tracking heavy hitters and conservative updates add on top of the counters
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why is a count-min sketch's estimate never below the true count?",
+		Choices: []string{
+			"Every counter is rounded up to the next power of two when it's read",
+			"Each of the key's counters got every one of its adds, plus maybe other keys', so the smallest is enough",
+			"The counters are saturating, so a counter that would wrap stays at its maximum instead",
+			"The sketch keeps the exact count of the most frequent keys and estimates only the rest",
+		},
+		Answer:      1,
+		Explanation: "collisions only ever add, so the smallest of the key's counters is the best estimate, the one with the least other traffic",
+	},
+	{
+		Prompt: "Why are the rare keys overestimated so much more, relative to their counts, than the heavy hitters?",
+		Choices: []string{
+			"Rare keys are hashed into a smaller region of each row, so they collide with each other more",
+			"The heavy hitters get counters of their own once they pass a threshold, leaving the rest to share",
+			"The overcount is about the same for every key, a handful extra is small to a big count and large to a tiny one",
+			"The sketch halves every counter periodically, which rounds the small counts up proportionally more",
+		},
+		Answer:      2,
+		Explanation: "the bound is epsilon times the stream's length, an absolute error, which is why sketches suit finding heavy hitters and not counting rare keys",
+	},
+	{
+		Prompt: "What does making the sketch wider do, and what does making it deeper do?",
+		Choices: []string{
+			"Wider lowers the chance of exceeding the bound, deeper shrinks the bound itself",
+			"Wider makes each update faster, deeper makes each estimate more accurate",
+			"Both shrink the bound, wider by more counters a row, deeper by averaging the rows",
+			"Wider shrinks the typical overcount, deeper makes it rarer every counter is crowded",
+		},
+		Answer:      3,
+		Explanation: "the width is e/epsilon and sets the bound, the depth is ln(1/delta) and sets how often an estimate may exceed it",
+	},
+	{
+		Prompt: "Why does the lesson keep a separate list of candidate heavy hitters beside the sketch?",
+		Choices: []string{
+			"The sketch can estimate any key but doesn't remember which keys it has seen",
+			"The sketch's counters are too small to hold the heavy hitters' counts exactly",
+			"The candidates' exact counts are used to correct the sketch's estimates",
+			"The sketch is rebuilt from the candidates whenever its counters fill up",
+		},
+		Answer:      0,
+		Explanation: "each add returns the key's new estimate, and a key whose estimate beats the smallest candidate's takes its place, k keys of memory on top of the counters",
+	},
+	{
+		Prompt: "Why does the conservative sketch overestimate less?",
+		Choices: []string{
+			"It subtracts an estimate of the collisions from each counter when it's read",
+			"It raises a key's counters only up to its new estimate, leaving already higher ones alone",
+			"It uses twice as many counters for the same memory by packing them into smaller integers",
+			"It ignores keys seen only once, so the rare keys never crowd the counters",
+		},
+		Answer:      1,
+		Explanation: "it gives up removals, a key's counters no longer each hold its full count, so a count can't be taken back out",
+	},
+}
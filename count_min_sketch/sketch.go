@@ -0,0 +1,175 @@
+package countminsketch
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// Sketch is a count-min sketch, depth rows of width counters, counting how often keys occur in a stream in memory
+// fixed by the width and depth, however many distinct keys there are
+// Adding a key bumps one counter per row, picked by hashing it, and the estimate is the smallest of those counters,
+// every counter holds the key's own count plus whatever other keys landed on it, so the estimate is never low, and
+// the row where the key collided with the least traffic is the closest
+type Sketch struct {
+	counters     []uint32
+	width, depth uint64
+	total        int
+	// conservative only raises the counters that are at the key's current estimate, see NewConservativeSketch
+	conservative bool
+}
+
+// NewSketch creates a sketch of depth rows of width counters
+func NewSketch(width, depth int) *Sketch {
+	width, depth = max(width, 1), max(depth, 1)
+	return &Sketch{counters: make([]uint32, width*depth), width: uint64(width), depth: uint64(depth)}
+}
+
+// NewConservativeSketch creates a sketch that updates conservatively, an add only raises the key's counters that are
+// below its new estimate, leaving any counter already above it, inflated by other keys, alone
+// It overestimates less, but a key's counters no longer hold its full count, so counts can't be subtracted
+func NewConservativeSketch(width, depth int) *Sketch {
+	s := NewSketch(width, depth)
+	s.conservative = true
+	return s
+}
+
+// Dimensions returns the width and depth that give an estimate within epsilon of the stream's length above the
+// true count with probability 1 - delta, e/epsilon counters per row and ln(1/delta) rows
+func Dimensions(epsilon, delta float64) (width, depth int) {
+	return int(math.Ceil(math.E / epsilon)), int(math.Ceil(math.Log(1 / delta)))
+}
+
+// mix is splitmix64's finalizer, it scatters keys that differ in a bit or two, like 1, 2, 3, across all 64 bits
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// columns returns the counter the key hashes to in each row, h1 + row*h2 modulo the width, double hashing, one hash
+// standing in for a hash per row
+func (s *Sketch) columns(key int, into []uint64) []uint64 {
+	h := mix(uint64(key))
+	h1, h2 := h&math.MaxUint32, h>>32|1
+	for row := range s.depth {
+		into = append(into, row*s.width+(h1+row*h2)%s.width)
+	}
+	return into
+}
+
+// Add counts one occurrence of the key and returns its new estimate
+func (s *Sketch) Add(key int) int {
+	var buf [16]uint64
+	cols := s.columns(key, buf[:0])
+	s.total++
+	if s.conservative {
+		estimate := s.min(cols) + 1
+		for _, c := range cols {
+			s.counters[c] = max(s.counters[c], estimate)
+		}
+		return int(estimate)
+	}
+	for _, c := range cols {
+		s.counters[c]++
+	}
+	return int(s.min(cols))
+}
+
+// Count estimates how many times the key was added, never less than the truth
+func (s *Sketch) Count(key int) int {
+	var buf [16]uint64
+	return int(s.min(s.columns(key, buf[:0])))
+}
+
+func (s *Sketch) min(cols []uint64) uint32 {
+	smallest := uint32(math.MaxUint32)
+	for _, c := range cols {
+		smallest = min(smallest, s.counters[c])
+	}
+	return smallest
+}
+
+// Total is the number of adds, the stream's length
+func (s *Sketch) Total() int {
+	return s.total
+}
+
+// Bytes is the memory the counters take
+func (s *Sketch) Bytes() int {
+	return 4 * len(s.counters)
+}
+
+// Counted is a key and its count, exact or estimated
+type Counted struct {
+	Key   int `json:"key"`
+	Count int `json:"count"`
+}
+
+// HeavyHitters keeps the k keys with the biggest counts seen so far, fed each key's running count as the stream
+// goes by, the sketch doesn't remember which keys it has seen, so the candidates have to be tracked alongside it
+// The candidates are a map of at most k keys, a key not among them replaces the smallest once its count passes it
+type HeavyHitters struct {
+	k      int
+	counts map[int]int
+	// floor is at most the smallest candidate's count, the smallest only grows, so the floor can lag behind and only
+	// costs a scan that finds nothing to replace
+	floor int
+}
+
+func NewHeavyHitters(k int) *HeavyHitters {
+	return &HeavyHitters{k: k, counts: make(map[int]int, k)}
+}
+
+// Offer tells the tracker the key's count is now count
+func (h *HeavyHitters) Offer(key, count int) {
+	if _, ok := h.counts[key]; ok || len(h.counts) < h.k {
+		h.counts[key] = count
+		return
+	}
+	if count <= h.floor {
+		return
+	}
+	smallest, smallestCount := 0, math.MaxInt
+	for candidate, c := range h.counts {
+		if c < smallestCount {
+			smallest, smallestCount = candidate, c
+		}
+	}
+	if count > smallestCount {
+		delete(h.counts, smallest)
+		h.counts[key] = count
+	}
+	h.floor = smallestCount
+}
+
+// Top returns the candidates, biggest count first
+func (h *HeavyHitters) Top() []Counted {
+	top := make([]Counted, 0, len(h.counts))
+	for key, count := range h.counts {
+		top = append(top, Counted{key, count})
+	}
+	sortCounted(top)
+	return top
+}
+
+// TopK returns the k keys with the biggest exact counts, biggest first
+func TopK(counts map[int]int, k int) []Counted {
+	all := make([]Counted, 0, len(counts))
+	for key, count := range counts {
+		all = append(all, Counted{key, count})
+	}
+	sortCounted(all)
+	return all[:min(k, len(all))]
+}
+
+// sortCounted orders by count, biggest first, and by key between equal counts so the order doesn't depend on a
+// map's iteration
+func sortCounted(c []Counted) {
+	slices.SortFunc(c, func(a, b Counted) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Key, b.Key))
+	})
+}
@@ -0,0 +1,76 @@
+package countminsketch
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSketchNeverUnderestimates(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, s := range []*Sketch{NewSketch(64, 4), NewConservativeSketch(64, 4)} {
+		exact := map[int]int{}
+		for range 20000 {
+			key := rng.Intn(1000)
+			exact[key]++
+			if got := s.Add(key); got < exact[key] {
+				t.Fatalf("Add(%d) estimated %d, below the true count %d", key, got, exact[key])
+			}
+		}
+		for key, count := range exact {
+			if got := s.Count(key); got < count {
+				t.Errorf("Count(%d) = %d, below the true count %d", key, got, count)
+			}
+		}
+		if s.Total() != 20000 {
+			t.Errorf("Total() = %d, want 20000", s.Total())
+		}
+	}
+}
+
+func TestSketchIsExactWithoutCollisions(t *testing.T) {
+	s := NewSketch(1<<16, 4)
+	for key := range 10 {
+		for range key + 1 {
+			s.Add(key)
+		}
+	}
+	for key := range 10 {
+		if got := s.Count(key); got != key+1 {
+			t.Errorf("Count(%d) = %d, want %d", key, got, key+1)
+		}
+	}
+	if got := s.Count(99); got != 0 {
+		t.Errorf("Count of a key never added = %d, want 0", got)
+	}
+}
+
+func TestDimensions(t *testing.T) {
+	width, depth := Dimensions(0.01, 0.01)
+	if width != 272 || depth != 5 {
+		t.Errorf("Dimensions(0.01, 0.01) = %d, %d, want 272, 5", width, depth)
+	}
+}
+
+func TestHeavyHittersKeepTheBiggest(t *testing.T) {
+	h := NewHeavyHitters(3)
+	counts := map[int]int{}
+	// key i is offered i times, interleaved, so the biggest keys only overtake the early ones late in the stream
+	for round := range 10 {
+		for key := range 10 {
+			if key > round {
+				counts[key]++
+				h.Offer(key, counts[key])
+			}
+		}
+	}
+	top := h.Top()
+	want := TopK(counts, 3)
+	if len(top) != 3 {
+		t.Fatalf("Top() has %d keys, want 3", len(top))
+	}
+	for i := range want {
+		if top[i] != want[i] {
+			t.Errorf("Top()[%d] = %v, want %v", i, top[i], want[i])
+		}
+	}
+}
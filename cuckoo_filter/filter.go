@@ -0,0 +1,256 @@
+package cuckoofilter
+
+import "math"
+
+const (
+	// SlotsPerBucket is the fingerprints a bucket holds, with four the table can be filled to about 95% before an
+	// insert has nowhere left to go
+	SlotsPerBucket = 4
+	// MinFingerprint and MaxFingerprint bound the fingerprint's bits, a bucket of four has to fit in 64 bits
+	MinFingerprint = 2
+	MaxFingerprint = 16
+	// maxKicks is how many fingerprints an insert moves before it gives up and calls the filter full
+	maxKicks = 500
+)
+
+// Filter is a cuckoo filter over uint64 keys, like a Bloom filter it answers "definitely not in it" or "probably in
+// it", but it stores a short fingerprint of each key rather than setting bits, so a key can be deleted again
+// Each key has two buckets it can go in, one from its hash and the other the first xored with the hash of its
+// fingerprint, so either bucket and the fingerprint are enough to find the other, a lookup checks the two buckets for
+// the fingerprint, and an insert finding both full moves a fingerprint already there to its other bucket, which may
+// move another, cuckoo hashing, until one lands in a free slot
+// The table is packed, bucket after bucket of four fingerprints of f bits each
+type Filter struct {
+	table   []uint64
+	buckets uint64
+	f       uint
+	length  int
+	// victim holds the fingerprint left over when an insert gives up, so the key it belongs to is still found, and
+	// makes every later insert fail
+	victim       uint64
+	victimBucket uint64
+	// rng picks which fingerprint an insert kicks out, seeded so a run can be repeated
+	rng uint64
+}
+
+// NewFilter creates a filter with room for capacity fingerprints of f bits, clamped to MinFingerprint and
+// MaxFingerprint, the number of buckets is a power of two
+func NewFilter(capacity, f int) *Filter {
+	f = min(max(f, MinFingerprint), MaxFingerprint)
+	buckets := uint64(1)
+	for buckets*SlotsPerBucket < uint64(capacity) {
+		buckets <<= 1
+	}
+	bucketBits := uint64(SlotsPerBucket * f)
+	return &Filter{
+		table:   make([]uint64, (buckets*bucketBits+63)/64+1),
+		buckets: buckets,
+		f:       uint(f),
+		rng:     1,
+	}
+}
+
+// mix is splitmix64's finalizer, it scatters keys that differ in a bit or two across all 64 bits
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// locate returns the key's fingerprint, never zero as zero marks an empty slot, and its first bucket
+func (c *Filter) locate(key uint64) (fp, i1 uint64) {
+	h := mix(key)
+	fp = (h>>32)%(1<<c.f-1) + 1
+	return fp, h & (c.buckets - 1)
+}
+
+// alternate is the fingerprint's other bucket, the same operation takes either bucket to the other
+func (c *Filter) alternate(i, fp uint64) uint64 {
+	return (i ^ mix(fp)) & (c.buckets - 1)
+}
+
+// bucket reads bucket i, its four fingerprints f bits apart from the bottom
+func (c *Filter) bucket(i uint64) uint64 {
+	width := uint64(SlotsPerBucket * c.f)
+	bit := i * width
+	word, offset := bit/64, bit%64
+	v := c.table[word] >> offset
+	if offset+width > 64 {
+		v |= c.table[word+1] << (64 - offset)
+	}
+	return v & (1<<width - 1)
+}
+
+// setBucket writes bucket i
+func (c *Filter) setBucket(i, v uint64) {
+	width := uint64(SlotsPerBucket * c.f)
+	bit := i * width
+	word, offset := bit/64, bit%64
+	mask := uint64(1)<<width - 1
+	c.table[word] = c.table[word]&^(mask<<offset) | v<<offset
+	if offset+width > 64 {
+		spill := 64 - offset
+		c.table[word+1] = c.table[word+1]&^(mask>>spill) | v>>spill
+	}
+}
+
+func (c *Filter) slot(b uint64, j int) uint64 {
+	return b >> (uint(j) * c.f) & (1<<c.f - 1)
+}
+
+func (c *Filter) withSlot(b uint64, j int, fp uint64) uint64 {
+	shift := uint(j) * c.f
+	return b&^((1<<c.f-1)<<shift) | fp<<shift
+}
+
+// put puts fp in a free slot of bucket i, false when there's none
+func (c *Filter) put(i, fp uint64) bool {
+	b := c.bucket(i)
+	for j := range SlotsPerBucket {
+		if c.slot(b, j) == 0 {
+			c.setBucket(i, c.withSlot(b, j, fp))
+			return true
+		}
+	}
+	return false
+}
+
+// Insert adds the key, false when the filter is too full to take it
+func (c *Filter) Insert(key uint64) bool {
+	if c.victim != 0 {
+		return false
+	}
+	fp, i := c.locate(key)
+	if c.put(i, fp) {
+		c.length++
+		return true
+	}
+	i = c.alternate(i, fp)
+	if c.put(i, fp) {
+		c.length++
+		return true
+	}
+	for range maxKicks {
+		// kick a fingerprint out of one of the buckets at random and take its slot, it goes to its other bucket
+		c.rng ^= c.rng << 13
+		c.rng ^= c.rng >> 7
+		c.rng ^= c.rng << 17
+		j := int(c.rng % SlotsPerBucket)
+		b := c.bucket(i)
+		kicked := c.slot(b, j)
+		c.setBucket(i, c.withSlot(b, j, fp))
+		fp, i = kicked, c.alternate(i, kicked)
+		if c.put(i, fp) {
+			c.length++
+			return true
+		}
+	}
+	// the key went in, but the last fingerprint kicked out has nowhere to go
+	c.victim, c.victimBucket = fp, i
+	c.length++
+	return true
+}
+
+// has reports whether bucket i holds fp
+func (c *Filter) has(i, fp uint64) bool {
+	b := c.bucket(i)
+	for j := range SlotsPerBucket {
+		if c.slot(b, j) == fp {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether the key might have been added, false is certain, true is wrong when another key's
+// fingerprint in one of its buckets happens to match
+func (c *Filter) Contains(key uint64) bool {
+	fp, i1 := c.locate(key)
+	i2 := c.alternate(i1, fp)
+	if c.has(i1, fp) || c.has(i2, fp) {
+		return true
+	}
+	return c.victim == fp && (c.victimBucket == i1 || c.victimBucket == i2)
+}
+
+// Delete removes one copy of the key's fingerprint, false when there's none
+// Only keys that were added may be deleted, deleting one that wasn't can remove another key's matching fingerprint,
+// and that key is then missed
+func (c *Filter) Delete(key uint64) bool {
+	fp, i1 := c.locate(key)
+	i2 := c.alternate(i1, fp)
+	for _, i := range []uint64{i1, i2} {
+		b := c.bucket(i)
+		for j := range SlotsPerBucket {
+			if c.slot(b, j) == fp {
+				c.setBucket(i, c.withSlot(b, j, 0))
+				c.length--
+				c.reinsertVictim()
+				return true
+			}
+		}
+	}
+	if c.victim == fp && (c.victimBucket == i1 || c.victimBucket == i2) {
+		c.victim = 0
+		c.length--
+		return true
+	}
+	return false
+}
+
+// reinsertVictim gives the victim a slot once a delete may have freed one
+func (c *Filter) reinsertVictim() {
+	if c.victim == 0 {
+		return
+	}
+	if c.put(c.victimBucket, c.victim) || c.put(c.alternate(c.victimBucket, c.victim), c.victim) {
+		c.victim = 0
+	}
+}
+
+// Len is the number of fingerprints held
+func (c *Filter) Len() int {
+	return c.length
+}
+
+// Slots is the number of fingerprints the table has room for
+func (c *Filter) Slots() int {
+	return int(c.buckets) * SlotsPerBucket
+}
+
+// Bits is the size of the table in bits
+func (c *Filter) Bits() int {
+	return c.Slots() * int(c.f)
+}
+
+// LoadFactor is the fraction of the slots that are full
+func (c *Filter) LoadFactor() float64 {
+	return float64(c.length) / float64(c.Slots())
+}
+
+// FalsePositiveRate is the rate theory predicts for fingerprints of f bits at a load factor, a lookup compares the
+// key's fingerprint with up to 2 x 4 others, each matching with probability 1/(2^f - 1)
+func FalsePositiveRate(f int, load float64) float64 {
+	compared := 2 * SlotsPerBucket * load
+	return 1 - math.Pow(1-1/float64(uint64(1)<<f-1), compared)
+}
+
+// occupied counts the full slots, a check on Len
+func (c *Filter) occupied() int {
+	n := 0
+	for i := range c.buckets {
+		b := c.bucket(i)
+		for j := range SlotsPerBucket {
+			if c.slot(b, j) != 0 {
+				n++
+			}
+		}
+	}
+	if c.victim != 0 {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,89 @@
+package cuckoofilter
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestFilterHasNoFalseNegatives(t *testing.T) {
+	for _, f := range []int{4, 7, 12, 16} {
+		rng := rand.New(rand.NewSource(1))
+		filter := NewFilter(10000, f)
+		keys := make([]uint64, 9000)
+		for i := range keys {
+			keys[i] = rng.Uint64()
+			if !filter.Insert(keys[i]) {
+				t.Fatalf("f=%d: insert %d failed at load %.2f", f, i, filter.LoadFactor())
+			}
+		}
+		for _, key := range keys {
+			if !filter.Contains(key) {
+				t.Fatalf("f=%d: added key %d isn't found", f, key)
+			}
+		}
+		if got := filter.occupied(); got != filter.Len() {
+			t.Errorf("f=%d: %d slots are full, Len() = %d", f, got, filter.Len())
+		}
+	}
+}
+
+func TestFilterDeletes(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	filter := NewFilter(4096, 12)
+	keys := make([]uint64, 3500)
+	for i := range keys {
+		keys[i] = rng.Uint64()
+		filter.Insert(keys[i])
+	}
+	for _, key := range keys[:1000] {
+		if !filter.Delete(key) {
+			t.Fatalf("deleting added key %d failed", key)
+		}
+	}
+	for _, key := range keys[1000:] {
+		if !filter.Contains(key) {
+			t.Fatalf("key %d wasn't deleted but isn't found", key)
+		}
+	}
+	if filter.Len() != 2500 || filter.occupied() != 2500 {
+		t.Errorf("Len() = %d with %d slots full, want 2500", filter.Len(), filter.occupied())
+	}
+	stillFound := 0
+	for _, key := range keys[:1000] {
+		if filter.Contains(key) {
+			stillFound++
+		}
+	}
+	if stillFound > 20 {
+		t.Errorf("%d of 1000 deleted keys are still found, want no more than false positives", stillFound)
+	}
+}
+
+func TestFalsePositivesMatchTheory(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	filter := NewFilter(1<<16, 8)
+	for range 60000 {
+		filter.Insert(rng.Uint64() &^ 1)
+	}
+	falsePositives := 0
+	const queries = 200000
+	for range queries {
+		if filter.Contains(rng.Uint64() | 1) {
+			falsePositives++
+		}
+	}
+	measured, want := float64(falsePositives)/queries, FalsePositiveRate(8, filter.LoadFactor())
+	if measured < want*0.8 || measured > want*1.2 {
+		t.Errorf("false positive rate %.4f, theory says %.4f", measured, want)
+	}
+}
+
+func TestFilterFillsUpToItsCapacity(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	filter := NewFilter(1<<14, 12)
+	for filter.Insert(rng.Uint64()) {
+	}
+	if load := filter.LoadFactor(); load < 0.9 {
+		t.Errorf("the filter only filled to %.2f before an insert failed, want above 0.9", load)
+	}
+}
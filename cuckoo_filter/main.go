@@ -0,0 +1,353 @@
+// Package cuckoofilter is the cuckoo filter lesson, teachgo cuckoo, a cuckoo filter built from scratch, set against
+// the Bloom filter lesson's filter on memory, speed and false positives, and deleting keys the Bloom filter can't
+package cuckoofilter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"slices"
+	"time"
+
+	bloomfilter "github.com/joshdurbin/teaching-go/bloom_filter"
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo cuckoo -h, its first line is the summary teachgo help lists
+const Description = `Cuckoo filters, a Bloom filter's rival that can delete
+
+Builds a cuckoo filter, a table of short fingerprints where each key has two buckets it can go in, and an insert
+that finds both full moves a fingerprint already there to its other bucket to make room, and times it against a
+Bloom filter given the same bits per key and against Go's map. Deletes half the keys again, which a Bloom filter
+can't do, checking the rest are all still found, then compares the false positive rates of the two filters across
+a range of fingerprint sizes, and fills a filter until an insert finds no room, showing how full four slots a bucket
+lets the table get.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "cuckoo",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"probabilistic data structures", "cuckoo hashing", "fingerprints", "deletion"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// fingerprintSweep is the fingerprint sizes the false positive rates are compared at, along with -fingerprint
+var fingerprintSweep = []int{4, 6, 8, 10, 12, 14, 16}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Keys        int   `json:"keys"`
+	Queries     int   `json:"queries"`
+	Fingerprint int   `json:"fingerprint"`
+	Seed        int64 `json:"seed"`
+}
+
+// SetResult is what a run measured of one set, the times are per operation, FalsePositives counts the absent keys
+// the set claimed to hold, BitsPerKey is zero for the map, which holds the keys themselves
+type SetResult struct {
+	Name           string  `json:"name"`
+	InsertNs       float64 `json:"insert_ns"`
+	HitNs          float64 `json:"hit_ns"`
+	MissNs         float64 `json:"miss_ns"`
+	Found          int     `json:"found"`
+	FalsePositives int     `json:"false_positives"`
+	BitsPerKey     float64 `json:"bits_per_key,omitempty"`
+}
+
+// DeleteResult is half the keys deleted from the cuckoo filter, Kept counts the keys not deleted that are still found,
+// all of them, and StillFound the deleted ones still found, as false positives
+type DeleteResult struct {
+	Deleted    int     `json:"deleted"`
+	DeleteNs   float64 `json:"delete_ns"`
+	Kept       int     `json:"kept"`
+	KeptFound  int     `json:"kept_found"`
+	StillFound int     `json:"still_found"`
+}
+
+// Experiment is the two filters' false positive rates at the bits per key a fingerprint size gives the cuckoo filter
+type Experiment struct {
+	Fingerprint int     `json:"fingerprint"`
+	BitsPerKey  float64 `json:"bits_per_key"`
+	Cuckoo      float64 `json:"cuckoo"`
+	Theoretical float64 `json:"theoretical"`
+	Bloom       float64 `json:"bloom"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+// Load is the cuckoo filter's load factor with every key in, MaxLoad how full a filter got before an insert failed
+type RunResult struct {
+	Config      RunConfig    `json:"config"`
+	Env         bench.Env    `json:"env"`
+	Load        float64      `json:"load"`
+	Sets        []SetResult  `json:"sets"`
+	Delete      DeleteResult `json:"delete"`
+	Experiments []Experiment `json:"experiments"`
+	MaxLoad     float64      `json:"max_load"`
+}
+
+// makeKeys draws the keys to add and keys certain to be absent, the added keys are even and the absent ones odd
+func makeKeys(rng *rand.Rand, keys, queries int) (present, absent []uint64) {
+	present = make([]uint64, keys)
+	for i := range present {
+		present[i] = rng.Uint64() &^ 1
+	}
+	absent = make([]uint64, queries)
+	for i := range absent {
+		absent[i] = rng.Uint64() | 1
+	}
+	return present, absent
+}
+
+// measure adds every present key, then looks up as many present keys as there are queries and every absent key
+func measure(ctx context.Context, name string, add func(uint64), contains func(uint64) bool, present, absent []uint64) SetResult {
+	result := SetResult{Name: name}
+	insert := bench.Phase(ctx, "insert "+name, func() {
+		for _, key := range present {
+			add(key)
+		}
+	})
+	hit := bench.Phase(ctx, "lookup present "+name, func() {
+		for i := range absent {
+			if contains(present[i%len(present)]) {
+				result.Found++
+			}
+		}
+	})
+	miss := bench.Phase(ctx, "lookup absent "+name, func() {
+		for _, key := range absent {
+			if contains(key) {
+				result.FalsePositives++
+			}
+		}
+	})
+	result.InsertNs = perOp(insert, len(present))
+	result.HitNs = perOp(hit, len(absent))
+	result.MissNs = perOp(miss, len(absent))
+	return result
+}
+
+// deleteHalf deletes every other present key from the filter and looks all of them up again
+func deleteHalf(ctx context.Context, filter *Filter, present []uint64) DeleteResult {
+	result := DeleteResult{}
+	elapsed := bench.Phase(ctx, "delete", func() {
+		for i := 0; i < len(present); i += 2 {
+			if filter.Delete(present[i]) {
+				result.Deleted++
+			}
+		}
+	})
+	result.DeleteNs = perOp(elapsed, result.Deleted)
+	for i, key := range present {
+		switch found := filter.Contains(key); {
+		case i%2 == 1:
+			result.Kept++
+			if found {
+				result.KeptFound++
+			}
+		case found:
+			result.StillFound++
+		}
+	}
+	return result
+}
+
+// experiment fills a cuckoo filter of fingerprints of f bits and a Bloom filter of the same size with the present
+// keys, then counts the absent keys each claims
+func experiment(f int, present, absent []uint64) Experiment {
+	cuckoo := NewFilter(len(present), f)
+	for _, key := range present {
+		cuckoo.Insert(key)
+	}
+	bitsPerKey := float64(cuckoo.Bits()) / float64(len(present))
+	bloom := bloomfilter.NewFilter(cuckoo.Bits(), bloomfilter.OptimalHashes(bitsPerKey))
+	for _, key := range present {
+		bloom.Add(key)
+	}
+	cuckooFalse, bloomFalse := 0, 0
+	for _, key := range absent {
+		if cuckoo.Contains(key) {
+			cuckooFalse++
+		}
+		if bloom.Contains(key) {
+			bloomFalse++
+		}
+	}
+	return Experiment{
+		Fingerprint: f,
+		BitsPerKey:  bitsPerKey,
+		Cuckoo:      float64(cuckooFalse) / float64(len(absent)),
+		Theoretical: FalsePositiveRate(f, cuckoo.LoadFactor()),
+		Bloom:       float64(bloomFalse) / float64(len(absent)),
+	}
+}
+
+// fillUntilFull inserts fresh keys into a filter the size of the present keys until one doesn't fit, and returns how
+// full it got
+func fillUntilFull(rng *rand.Rand, keys, f int) float64 {
+	filter := NewFilter(keys, f)
+	for filter.Insert(rng.Uint64()) {
+	}
+	return filter.LoadFactor()
+}
+
+func perOp(d time.Duration, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(d.Nanoseconds()) / float64(n)
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Cuckoo Filter")
+	fmt.Fprintln(w, "=============")
+	fmt.Fprintf(w, "Keys: %d\nQueries: %d\nFingerprint: %d bits\nLoad: %.1f%%\n", config.Keys, config.Queries, config.Fingerprint, 100*result.Load)
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%-14s %12s %12s %12s %8s %16s %10s\n", "Set", "Insert/op", "Hit/op", "Miss/op", "Found", "False positives", "Bits/key")
+	for _, s := range result.Sets {
+		bitsPerKey := ">64"
+		if s.BitsPerKey > 0 {
+			bitsPerKey = fmt.Sprintf("%.1f", s.BitsPerKey)
+		}
+		fmt.Fprintf(w, "%-14s %12v %12v %12v %8d %16d %10s\n", s.Name,
+			time.Duration(s.InsertNs), time.Duration(s.HitNs), time.Duration(s.MissNs), s.Found, s.FalsePositives, bitsPerKey)
+	}
+	fmt.Fprintln(w, "\nA cuckoo lookup reads two buckets, a Bloom lookup a bit for each of its hashes, each likely a cache miss")
+
+	d := result.Delete
+	fmt.Fprintf(w, "\n=====Deleting every other key=====\n")
+	fmt.Fprintf(w, "Deleted %d keys at %v each, %d of the %d kept are still found, and %d of those deleted, as false positives\n",
+		d.Deleted, time.Duration(d.DeleteNs), d.KeptFound, d.Kept, d.StillFound)
+	fmt.Fprintln(w, "A Bloom filter can't delete, clearing a key's bits would clear bits other keys share")
+
+	fmt.Fprintln(w, "\n=====False positives at the same bits per key=====")
+	fmt.Fprintf(w, "%12s %10s %12s %12s %12s\n", "Fingerprint", "Bits/key", "Cuckoo", "Theory", "Bloom")
+	for _, e := range result.Experiments {
+		fmt.Fprintf(w, "%12d %10.1f %11.3f%% %11.3f%% %11.3f%%\n", e.Fingerprint, e.BitsPerKey, 100*e.Cuckoo, 100*e.Theoretical, 100*e.Bloom)
+	}
+	fmt.Fprintln(w, "\nEach bit of fingerprint halves the cuckoo filter's rate, the Bloom filter's falls more slowly with")
+	fmt.Fprintln(w, "each bit per key, so the Bloom filter wins with few bits and the cuckoo filter once the rate is low,")
+	fmt.Fprintln(w, "the cuckoo filter's bits per key count its empty slots too, so the fuller its table the sooner it wins")
+
+	fmt.Fprintf(w, "\nFilled with fresh keys, a filter took them until it was %.1f%% full before an insert found no room\n", 100*result.MaxLoad)
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo cuckoo
+func Main(args []string) {
+	fs := bench.NewFlagSet("cuckoo", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the cuckoo filter lesson measures a single run"
+	numKeys := fs.Int("keys", 900_000, "the number of keys to add, the table has a power of two buckets, so this sets how full it is")
+	numQueries := fs.Int("queries", 1_000_000, "the number of lookups of keys that were added, and of keys that weren't")
+	fingerprint := fs.Int("fingerprint", 12, fmt.Sprintf("the bits of each key's fingerprint, from %d to %d", MinFingerprint, MaxFingerprint))
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "cuckoo measures a single run, -trials isn't supported")
+	v.AtLeast("keys", *numKeys, 1)
+	v.AtLeast("queries", *numQueries, 1)
+	v.Check(*fingerprint >= MinFingerprint && *fingerprint <= MaxFingerprint, "-fingerprint must be from %d to %d, got %d",
+		MinFingerprint, MaxFingerprint, *fingerprint)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	rng := rand.New(rand.NewSource(globals.Seed))
+	slog.Info("generating keys", "keys", *numKeys, "queries", *numQueries)
+	present, absent := makeKeys(rng, *numKeys, *numQueries)
+	result := RunResult{
+		Config: RunConfig{Keys: *numKeys, Queries: *numQueries, Fingerprint: *fingerprint, Seed: globals.Seed},
+		Env:    bench.CaptureEnv(),
+	}
+
+	ctx := context.Background()
+	cuckoo := NewFilter(*numKeys, *fingerprint)
+	failed := 0
+	cuckooSet := measure(ctx, "Cuckoo filter", func(key uint64) {
+		if !cuckoo.Insert(key) {
+			failed++
+		}
+	}, cuckoo.Contains, present, absent)
+	if failed > 0 {
+		slog.Warn("the cuckoo filter was too full for some keys", "failed", failed)
+	}
+	result.Load = cuckoo.LoadFactor()
+	bitsPerKey := float64(cuckoo.Bits()) / float64(*numKeys)
+	cuckooSet.BitsPerKey = bitsPerKey
+	bloom := bloomfilter.NewFilter(cuckoo.Bits(), bloomfilter.OptimalHashes(bitsPerKey))
+	bloomSet := measure(ctx, "Bloom filter", bloom.Add, bloom.Contains, present, absent)
+	bloomSet.BitsPerKey = bitsPerKey
+	goMap := map[uint64]struct{}{}
+	mapSet := measure(ctx, "Map", func(key uint64) { goMap[key] = struct{}{} }, func(key uint64) bool {
+		_, ok := goMap[key]
+		return ok
+	}, present, absent)
+	result.Sets = []SetResult{cuckooSet, bloomSet, mapSet}
+
+	slog.Info("deleting keys", "keys", *numKeys/2)
+	result.Delete = deleteHalf(ctx, cuckoo, present)
+
+	slog.Info("comparing false positives", "fingerprints", fingerprintSweep)
+	sweep := fingerprintSweep
+	if !slices.Contains(sweep, *fingerprint) {
+		sweep = append(slices.Clone(sweep), *fingerprint)
+		slices.Sort(sweep)
+	}
+	for _, f := range sweep {
+		result.Experiments = append(result.Experiments, experiment(f, present, absent))
+	}
+	result.MaxLoad = fillUntilFull(rng, *numKeys, *fingerprint)
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a benchmark line per operation and set, the miss lines carry the false positive rate as
+// well, and a line for the cuckoo filter's deletes
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/cuckoo_filter"); err != nil {
+		return err
+	}
+	config := result.Config
+	keys := fmt.Sprintf("keys=%d", config.Keys)
+	benchmarks := []bench.Benchmark{}
+	for _, s := range result.Sets {
+		benchmarks = append(benchmarks,
+			bench.Benchmark{Name: "Insert/" + s.Name + "/" + keys, N: int64(config.Keys), Metrics: []bench.Metric{{Value: s.InsertNs, Unit: "ns/op"}}},
+			bench.Benchmark{Name: "Hit/" + s.Name + "/" + keys, N: int64(config.Queries), Metrics: []bench.Metric{{Value: s.HitNs, Unit: "ns/op"}}},
+			bench.Benchmark{Name: "Miss/" + s.Name + "/" + keys, N: int64(config.Queries), Metrics: []bench.Metric{
+				{Value: s.MissNs, Unit: "ns/op"},
+				{Value: float64(s.FalsePositives) / float64(config.Queries), Unit: "fp/op"},
+			}},
+		)
+	}
+	benchmarks = append(benchmarks, bench.Benchmark{
+		Name:    "Delete/Cuckoo filter/" + keys,
+		N:       int64(result.Delete.Deleted),
+		Metrics: []bench.Metric{{Value: result.Delete.DeleteNs, Unit: "ns/op"}},
+	})
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
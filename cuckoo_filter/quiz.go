@@ -0,0 +1,63 @@
+package cuckoofilter
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz cuckoo, it sets the cuckoo filter against the Bloom filter, deletes, the xor trick for
+// finding a fingerprint's other bucket, false positive rates by fingerprint size and how full the table gets
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why can the cuckoo filter delete a key when the Bloom filter can't?",
+		Choices: []string{
+			"It keeps a hash of the whole key beside the fingerprint, so it knows which slot was that key's",
+			"Each key is one fingerprint in one slot, removing it leaves the others, a Bloom filter's bits are shared",
+			"It marks deleted slots with a tombstone that lookups skip, where a Bloom filter has no spare bits",
+			"It rebuilds itself from the remaining fingerprints after every delete, which a Bloom filter can't",
+		},
+		Answer:      1,
+		Explanation: "clearing a Bloom filter's bits for one key would clear bits other keys set too, and those keys would then be missed",
+	},
+	{
+		Prompt: "How does an insert find a key's second bucket once it's only holding the fingerprint, not the key?",
+		Choices: []string{
+			"It stores both bucket numbers alongside the fingerprint",
+			"It takes the next bucket along, wrapping at the end",
+			"It rehashes the fingerprint with a second seed",
+			"It xors this bucket with a hash of the fingerprint",
+		},
+		Answer:      3,
+		Explanation: "either bucket xored with a hash of the fingerprint gives the other, that's what lets a fingerprint be kicked out to its other bucket without the key, partial-key cuckoo hashing",
+	},
+	{
+		Prompt: "Why do the deleted keys still found make up only a fraction of a percent?",
+		Choices: []string{
+			"Deleting only clears the fingerprint in the first bucket, so a copy in the second is sometimes left behind",
+			"A deleted key is found only when another key's fingerprint in its buckets happens to match",
+			"Some deletes fail when both buckets are full and are quietly skipped",
+			"The filter keeps a small list of recently deleted keys and reports those as found",
+		},
+		Answer:      1,
+		Explanation: "once its own fingerprint is gone a deleted key is no different from a key that was never added",
+	},
+	{
+		Prompt: "Why does the Bloom filter have fewer false positives than the cuckoo filter at small fingerprints, and more at large ones?",
+		Choices: []string{
+			"Each fingerprint bit halves the cuckoo rate, but from eight slots compared, so it overtakes as bits grow",
+			"The cuckoo filter fills up as fingerprints grow, and a fuller table has more false positives",
+			"The Bloom filter's hashes get worse as it grows, so its rate stops falling at large sizes",
+			"At small sizes the Bloom filter is given more bits per key than the cuckoo filter",
+		},
+		Answer:      0,
+		Explanation: "the cuckoo filter's rate is about 8/2^f times how full it is, the Bloom filter's about 0.62^bits per key, and the empty slots count against the cuckoo filter's bits per key, so the fuller the table the sooner it overtakes",
+	},
+	{
+		Prompt: "Why does a filter fill to about 95% before an insert fails?",
+		Choices: []string{
+			"The last 5% of slots is held back so deletes always have somewhere to move fingerprints",
+			"The fingerprints are padded to a byte, and the padding takes the other 5% of the table",
+			"The insert deliberately refuses past 95% so lookups don't slow down on a nearly full table",
+			"With four slots a bucket and two buckets a key, kicks almost always find a free slot",
+		},
+		Answer:      3,
+		Explanation: "with one slot a bucket the table only fills to about half before kicks go round in circles, more slots a bucket fill it further",
+	},
+}
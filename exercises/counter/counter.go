@@ -0,0 +1,33 @@
+// Package counter is the starting point of the counter exercises, the types the exercises ask for are declared with
+// their methods left as TODOs
+//
+// Run teachgo exercises sharded-counter for the instructions and teachgo verify sharded-counter to check your work
+package counter
+
+// ShardedCounter spreads its count over several shards, each with its own lock, so goroutines updating it at the
+// same time mostly update different shards rather than queuing on one lock
+//
+// TODO: add the shards, a slice of structs each holding a sync.Mutex and a count is enough to start with
+type ShardedCounter struct {
+}
+
+// NewShardedCounter creates a counter of the given number of shards, at least 1
+//
+// TODO: allocate the shards
+func NewShardedCounter(shards int) *ShardedCounter {
+	panic("TODO: implement NewShardedCounter")
+}
+
+// Add adds delta, which may be negative, to the count, it is safe to call from many goroutines at once
+//
+// TODO: pick a shard, lock it and add to its count, picking shards round robin with an atomic index is a fine start
+func (c *ShardedCounter) Add(delta int) {
+	panic("TODO: implement ShardedCounter.Add")
+}
+
+// Value is the count, the sum of every shard, it is safe to call while other goroutines are adding
+//
+// TODO: lock each shard in turn and sum their counts
+func (c *ShardedCounter) Value() int {
+	panic("TODO: implement ShardedCounter.Value")
+}
@@ -0,0 +1,107 @@
+// Package skiplist is the starting point of the skip list exercises, a working skip list like the one the skiplist
+// lesson benchmarks with the operations the exercises ask for left as TODOs
+//
+// Run teachgo exercises skiplist-delete for the instructions and teachgo verify skiplist-delete to check your work
+package skiplist
+
+import "math/rand"
+
+// levelProbability is the chance a node reaching one level also reaches the next
+const levelProbability = 0.5
+
+type node struct {
+	value   int
+	forward []*node
+}
+
+// SkipList is a sorted list of ints with express lanes, each level skipping over about half the nodes of the level
+// below it
+type SkipList struct {
+	head     *node
+	maxLevel int
+	// level is the highest level any node reaches, searches start there
+	level int
+	size  int
+	rng   *rand.Rand
+}
+
+// New creates an empty skip list of up to maxLevel levels, the seed makes the levels nodes are given repeatable
+func New(maxLevel int, seed int64) *SkipList {
+	return &SkipList{
+		head:     &node{forward: make([]*node, maxLevel)},
+		maxLevel: maxLevel,
+		rng:      rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (sl *SkipList) randomLevel() int {
+	level := 0
+	for level < sl.maxLevel-1 && sl.rng.Float64() < levelProbability {
+		level++
+	}
+	return level
+}
+
+// Insert adds a value, duplicates are kept
+func (sl *SkipList) Insert(value int) {
+	update := make([]*node, sl.maxLevel)
+	current := sl.head
+	for i := sl.level; i >= 0; i-- {
+		for current.forward[i] != nil && current.forward[i].value < value {
+			current = current.forward[i]
+		}
+		update[i] = current
+	}
+
+	newLevel := sl.randomLevel()
+	if newLevel > sl.level {
+		for i := sl.level + 1; i <= newLevel; i++ {
+			update[i] = sl.head
+		}
+		sl.level = newLevel
+	}
+
+	n := &node{value: value, forward: make([]*node, newLevel+1)}
+	for i := 0; i <= newLevel; i++ {
+		n.forward[i] = update[i].forward[i]
+		update[i].forward[i] = n
+	}
+	sl.size++
+}
+
+// Contains reports whether the value is in the list
+func (sl *SkipList) Contains(value int) bool {
+	current := sl.head
+	for i := sl.level; i >= 0; i-- {
+		for current.forward[i] != nil && current.forward[i].value < value {
+			current = current.forward[i]
+		}
+	}
+	current = current.forward[0]
+	return current != nil && current.value == value
+}
+
+// Len is the number of values in the list
+func (sl *SkipList) Len() int {
+	return sl.size
+}
+
+// Levels lists the values linked on each level, level 0 holding every value, up to the highest level in use
+func (sl *SkipList) Levels() [][]int {
+	levels := make([][]int, sl.level+1)
+	for i := range levels {
+		levels[i] = []int{}
+		for current := sl.head.forward[i]; current != nil; current = current.forward[i] {
+			levels[i] = append(levels[i], current.value)
+		}
+	}
+	return levels
+}
+
+// Delete removes one occurrence of the value, reporting whether there was one to remove
+//
+// TODO: find the node the way Insert finds where a node goes, remembering the last node before it on every level,
+// unlink it from every level it reaches, and lower sl.level while the top level is left empty
+func (sl *SkipList) Delete(value int) bool {
+	panic("TODO: implement SkipList.Delete")
+}
@@ -0,0 +1,226 @@
+package externalsort
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+
+	binaryheap "github.com/joshdurbin/teaching-go/binary_heap"
+)
+
+// ValueSize is the bytes each integer takes in a file, little endian
+const ValueSize = 8
+
+// minBuffer is the smallest buffer a reader or writer gets, however many runs share the memory
+const minBuffer = 4096
+
+// WriteRandom writes n seeded random integers to w
+func WriteRandom(w io.Writer, rng *rand.Rand, n int) error {
+	bw := bufio.NewWriterSize(w, 1<<20)
+	var buf [ValueSize]byte
+	for range n {
+		binary.LittleEndian.PutUint64(buf[:], rng.Uint64())
+		if _, err := bw.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// valueReader reads integers one at a time through a buffer
+type valueReader struct {
+	br  *bufio.Reader
+	buf [ValueSize]byte
+}
+
+func newValueReader(r io.Reader, size int) *valueReader {
+	return &valueReader{br: bufio.NewReaderSize(r, max(size, minBuffer))}
+}
+
+// next returns the next integer, false at the end
+func (v *valueReader) next() (uint64, bool, error) {
+	if _, err := io.ReadFull(v.br, v.buf[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return binary.LittleEndian.Uint64(v.buf[:]), true, nil
+}
+
+// Sorter sorts files of integers too big for memory, it never holds more than Memory bytes of them at once
+// Sorting happens in two phases, the input is read a memory's worth at a time, each chunk sorted in memory and
+// written out as a run, then the runs are merged, reading a little of each and always writing out the smallest of
+// their next integers, which a heap of one integer from each run finds in O(log runs)
+// With more runs than FanIn they're merged FanIn at a time into longer runs, pass after pass, every pass reading and
+// writing the whole file once more, which is what a bigger fan-in saves
+type Sorter struct {
+	Memory int
+	FanIn  int
+	// Dir is where the runs are written
+	Dir string
+	// Passes counts the merge passes made so far, the last one writing the output
+	Passes int
+}
+
+// SortRuns reads r a chunk at a time, sorts each chunk and writes it to a file of its own in Dir, returning the runs'
+// paths in order
+// A chunk is half the Memory, the other half holds its bytes as they're read and written
+func (s *Sorter) SortRuns(r io.Reader) ([]string, error) {
+	chunk := make([]uint64, max(s.Memory/(2*ValueSize), 1))
+	buf := make([]byte, len(chunk)*ValueSize)
+	runs := []string{}
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n%ValueSize != 0 {
+			return runs, fmt.Errorf("the input's length isn't a multiple of %d bytes", ValueSize)
+		}
+		if n > 0 {
+			values := chunk[:n/ValueSize]
+			for i := range values {
+				values[i] = binary.LittleEndian.Uint64(buf[i*ValueSize:])
+			}
+			slices.Sort(values)
+			path, werr := s.writeRun(len(runs), values, buf[:n])
+			if werr != nil {
+				return runs, werr
+			}
+			runs = append(runs, path)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return runs, nil
+		}
+		if err != nil {
+			return runs, err
+		}
+	}
+}
+
+// writeRun encodes the sorted values into buf and writes them to run i's file
+func (s *Sorter) writeRun(i int, values []uint64, buf []byte) (string, error) {
+	for j, v := range values {
+		binary.LittleEndian.PutUint64(buf[j*ValueSize:], v)
+	}
+	path := filepath.Join(s.Dir, fmt.Sprintf("run-%d.bin", i))
+	return path, os.WriteFile(path, buf, 0o600)
+}
+
+// head is the next integer of a run, what the merge's heap orders
+type head struct {
+	value uint64
+	run   int
+}
+
+// Merge merges the sorted runs into w, the Memory split between a buffer for each run and one for w, merging FanIn
+// at a time into intermediate runs in Dir first while there are more than FanIn, 0 merges every run at once
+// The runs given are left in place, the intermediate ones are removed once merged
+func (s *Sorter) Merge(runs []string, w io.Writer) error {
+	fanIn := s.FanIn
+	if fanIn < 2 {
+		fanIn = max(len(runs), 2)
+	}
+	// intermediate is the runs the last pass wrote, removed once the next has merged them
+	var intermediate []string
+	defer func() {
+		for _, run := range intermediate {
+			os.Remove(run)
+		}
+	}()
+	for pass := 0; len(runs) > fanIn; pass++ {
+		merged := intermediate
+		intermediate = nil
+		for i := 0; i < len(runs); i += fanIn {
+			path := filepath.Join(s.Dir, fmt.Sprintf("pass-%d-%d.bin", pass, len(intermediate)))
+			intermediate = append(intermediate, path)
+			if err := s.mergeToFile(runs[i:min(i+fanIn, len(runs))], path); err != nil {
+				return err
+			}
+		}
+		for _, run := range merged {
+			os.Remove(run)
+		}
+		runs = intermediate
+		s.Passes++
+	}
+	s.Passes++
+	return s.merge(runs, w)
+}
+
+func (s *Sorter) mergeToFile(runs []string, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.merge(runs, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// merge makes one pass, merging the runs into w
+func (s *Sorter) merge(runs []string, w io.Writer) error {
+	buffer := s.Memory / (len(runs) + 1)
+	readers := make([]*valueReader, len(runs))
+	for i, run := range runs {
+		f, err := os.Open(run)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		readers[i] = newValueReader(f, buffer)
+	}
+	bw := bufio.NewWriterSize(w, max(buffer, minBuffer))
+	heads := binaryheap.NewHeap(func(a, b head) bool { return a.value < b.value })
+	for i, r := range readers {
+		v, ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heads.Push(head{value: v, run: i})
+		}
+	}
+	var buf [ValueSize]byte
+	for heads.Len() > 0 {
+		h := heads.Pop()
+		binary.LittleEndian.PutUint64(buf[:], h.value)
+		if _, err := bw.Write(buf[:]); err != nil {
+			return err
+		}
+		v, ok, err := readers[h.run].next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heads.Push(head{value: v, run: h.run})
+		}
+	}
+	return bw.Flush()
+}
+
+// Check reads the integers in r and returns how many there are, their sum, wrapping, and whether they were in order,
+// the sum is the same for a file and its sorted copy
+func Check(r io.Reader) (count int, sum uint64, sorted bool, err error) {
+	vr := newValueReader(r, 1<<20)
+	sorted = true
+	var last uint64
+	for {
+		v, ok, err := vr.next()
+		if err != nil || !ok {
+			return count, sum, sorted, err
+		}
+		if count > 0 && v < last {
+			sorted = false
+		}
+		count++
+		sum += v
+		last = v
+	}
+}
@@ -0,0 +1,72 @@
+package externalsort
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"slices"
+	"testing"
+)
+
+func decode(data []byte) []uint64 {
+	values := make([]uint64, len(data)/ValueSize)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint64(data[i*ValueSize:])
+	}
+	return values
+}
+
+func TestSorterSortsAtEveryFanIn(t *testing.T) {
+	var input bytes.Buffer
+	if err := WriteRandom(&input, rand.New(rand.NewSource(1)), 10000); err != nil {
+		t.Fatal(err)
+	}
+	want := decode(input.Bytes())
+	slices.Sort(want)
+
+	for _, fanIn := range []int{0, 2, 3, 16} {
+		s := &Sorter{Memory: 8192, FanIn: fanIn, Dir: t.TempDir()}
+		runs, err := s.SortRuns(bytes.NewReader(input.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 10000 integers, 512 a run
+		if len(runs) != 20 {
+			t.Fatalf("fan-in %d: %d runs, want 20", fanIn, len(runs))
+		}
+		var output bytes.Buffer
+		if err := s.Merge(runs, &output); err != nil {
+			t.Fatal(err)
+		}
+		if got := decode(output.Bytes()); !slices.Equal(got, want) {
+			t.Fatalf("fan-in %d: the output isn't the input sorted", fanIn)
+		}
+		wantPasses := map[int]int{0: 1, 2: 5, 3: 3, 16: 2}[fanIn]
+		if s.Passes != wantPasses {
+			t.Errorf("fan-in %d: %d passes, want %d", fanIn, s.Passes, wantPasses)
+		}
+		entries, err := os.ReadDir(s.Dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != len(runs) {
+			t.Errorf("fan-in %d: %d files left in the directory, want just the %d runs", fanIn, len(entries), len(runs))
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var buf bytes.Buffer
+	for _, v := range []uint64{3, 1, 2} {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	count, sum, sorted, err := Check(bytes.NewReader(buf.Bytes()))
+	if err != nil || count != 3 || sum != 6 || sorted {
+		t.Errorf("Check(3, 1, 2) = %d, %d, %v, %v, want 3, 6, false, nil", count, sum, sorted, err)
+	}
+	_, _, sorted, _ = Check(bytes.NewReader(buf.Bytes()[ValueSize:]))
+	if !sorted {
+		t.Errorf("Check(1, 2) says the integers are out of order")
+	}
+}
@@ -0,0 +1,342 @@
+// Package externalsort is the external merge sort lesson, teachgo extsort, sorting a file of integers bigger than
+// the memory it's allowed, in sorted runs merged through the heap lesson's priority queue
+package externalsort
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo extsort -h, its first line is the summary teachgo help
+// lists
+const Description = `External merge sort, sorting a file bigger than memory
+
+Writes a file of random integers, then sorts it while holding no more than -memory of it at once. The file is read
+a chunk at a time, each chunk sorted in memory and written out as a run, then the runs are merged, a buffer of each
+read at a time and a heap, the heap lesson's priority queue, picking the smallest next integer among them. Reports
+the time and throughput of each phase against sorting the whole file in memory, checks the output, then merges the
+runs again at a range of fan-ins, showing how each extra pass over the data reads and writes the whole file once
+more. The file defaults to a few tens of MiB, -size takes it to gigabytes, with -in-memory=false to leave out the
+in-memory sort that would need that much memory.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "extsort",
+		Description: Description,
+		Difficulty:  lesson.Advanced,
+		Topics:      []string{"external sorting", "k-way merge", "priority queues", "I/O"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// fanInSweep is the fan-ins the runs are merged again at, those below the number of runs, and every run at once
+var fanInSweep = []int{2, 4, 16}
+
+// RunConfig records the settings a run was made with, FanIn 0 merges every run at once
+type RunConfig struct {
+	SizeMB   int   `json:"size_mb"`
+	MemoryMB int   `json:"memory_mb"`
+	FanIn    int   `json:"fan_in"`
+	InMemory bool  `json:"in_memory"`
+	Seed     int64 `json:"seed"`
+}
+
+// FanInResult is the runs merged at one fan-in, BytesMoved counts the bytes read and written by every pass
+type FanInResult struct {
+	FanIn      int     `json:"fan_in"`
+	Passes     int     `json:"passes"`
+	BytesMoved int64   `json:"bytes_moved"`
+	Ns         float64 `json:"ns"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+// Sorted and Matches say the output is in order and holds the same integers as the input, by count and sum
+type RunResult struct {
+	Config     RunConfig     `json:"config"`
+	Env        bench.Env     `json:"env"`
+	Bytes      int64         `json:"bytes"`
+	Values     int           `json:"values"`
+	Runs       int           `json:"runs"`
+	GenerateNs float64       `json:"generate_ns"`
+	RunsNs     float64       `json:"runs_ns"`
+	MergeNs    float64       `json:"merge_ns"`
+	Passes     int           `json:"passes"`
+	Sorted     bool          `json:"sorted"`
+	Matches    bool          `json:"matches"`
+	InMemoryNs float64       `json:"in_memory_ns,omitempty"`
+	FanIns     []FanInResult `json:"fan_ins"`
+}
+
+// generate writes values random integers to path
+func generate(rng *rand.Rand, path string, values int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteRandom(f, rng, values); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// sortRuns writes the sorted runs of input to the sorter's directory
+func sortRuns(s *Sorter, input string) ([]string, error) {
+	f, err := os.Open(input)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return s.SortRuns(f)
+}
+
+// merge merges the runs into path
+func merge(s *Sorter, runs []string, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.Merge(runs, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// check reads the integers in path
+func check(path string) (count int, sum uint64, sorted bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	defer f.Close()
+	return Check(f)
+}
+
+// sortInMemory reads the whole file, sorts it and writes it to output, what the external sort does without the
+// memory limit
+func sortInMemory(input, output string) error {
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	values := make([]uint64, len(data)/ValueSize)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint64(data[i*ValueSize:])
+	}
+	slices.Sort(values)
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(data[i*ValueSize:], v)
+	}
+	return os.WriteFile(output, data, 0o600)
+}
+
+func rate(bytes int64, ns float64) string {
+	return bench.FormatRate(float64(bytes) / time.Duration(ns).Seconds())
+}
+
+func mergeLabel(passes int) string {
+	if passes == 1 {
+		return "Merge in 1 pass"
+	}
+	return fmt.Sprintf("Merge in %d passes", passes)
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "External Merge Sort")
+	fmt.Fprintln(w, "===================")
+	fmt.Fprintf(w, "File: %d MiB, %d integers\nMemory: %d MiB\n", config.SizeMB, result.Values, config.MemoryMB)
+	if config.FanIn == 0 {
+		fmt.Fprintln(w, "Fan-in: every run at once")
+	} else {
+		fmt.Fprintf(w, "Fan-in: %d\n", config.FanIn)
+	}
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%-22s %14s %14s\n", "Phase", "Time", "Bytes/s")
+	fmt.Fprintf(w, "%-22s %14v %14s\n", "Generate", time.Duration(result.GenerateNs), rate(result.Bytes, result.GenerateNs))
+	fmt.Fprintf(w, "%-22s %14v %14s\n", fmt.Sprintf("Sort %d runs", result.Runs), time.Duration(result.RunsNs), rate(result.Bytes, result.RunsNs))
+	fmt.Fprintf(w, "%-22s %14v %14s\n", mergeLabel(result.Passes), time.Duration(result.MergeNs), rate(result.Bytes, result.MergeNs))
+	total := result.RunsNs + result.MergeNs
+	fmt.Fprintf(w, "%-22s %14v %14s\n", "External sort", time.Duration(total), rate(result.Bytes, total))
+	if config.InMemory {
+		fmt.Fprintf(w, "%-22s %14v %14s\n", "In memory", time.Duration(result.InMemoryNs), rate(result.Bytes, result.InMemoryNs))
+	} else {
+		fmt.Fprintf(w, "%-22s %14s %14s\n", "In memory", "-", "-")
+	}
+	verdict := "no"
+	if result.Sorted && result.Matches {
+		verdict = "yes"
+	}
+	fmt.Fprintf(w, "\nThe output is sorted and holds the same %d integers as the input: %s\n", result.Values, verdict)
+	fmt.Fprintln(w, "The external sort reads and writes the file once to make the runs and once more for every merge pass,")
+	fmt.Fprintln(w, "sorting in memory reads and writes it once, but needs memory for all of it")
+
+	fmt.Fprintf(w, "\n=====Merging the %d runs at a range of fan-ins=====\n", result.Runs)
+	fmt.Fprintf(w, "%8s %8s %18s %14s %14s\n", "Fan-in", "Passes", "Bytes moved", "Time", "Bytes/s")
+	for _, f := range result.FanIns {
+		fmt.Fprintf(w, "%8d %8d %18d %14v %14s\n", f.FanIn, f.Passes, f.BytesMoved, time.Duration(f.Ns), rate(result.Bytes, f.Ns))
+	}
+	fmt.Fprintln(w, "\nEach pass reads and writes every byte, a fan-in of k needs log_k(runs) passes, but splits the memory")
+	fmt.Fprintln(w, "into k+1 buffers, so a bigger fan-in reads each run in smaller pieces")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo extsort
+func Main(args []string) {
+	fs := bench.NewFlagSet("extsort", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the external merge sort lesson measures a single run"
+	sizeMB := fs.Int("size", 32, "the size in MiB of the file of random integers sorted")
+	memoryMB := fs.Int("memory", 4, "the memory in MiB the sort may hold integers in, split between sorting a run and the merge's buffers")
+	fanIn := fs.Int("fan-in", 0, "the number of runs merged at a time, 0 merges every run at once")
+	dir := fs.String("dir", "", "the directory the file and runs are written to, a temporary directory when empty")
+	inMemory := fs.Bool("in-memory", true, "sort the file in memory as well, leave it out for files bigger than memory")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "extsort measures a single run, -trials isn't supported")
+	v.AtLeast("size", *sizeMB, 1)
+	v.AtLeast("memory", *memoryMB, 1)
+	v.Check(*fanIn == 0 || *fanIn >= 2, "-fan-in must be 0 or at least 2, got %d", *fanIn)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	work, err := os.MkdirTemp(*dir, "teachgo-extsort-*")
+	if err != nil {
+		slog.Error("failed to create the working directory", "err", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(work)
+	// Main's deferred remove doesn't run past os.Exit, so failures from here on go through fail
+	fail := func(msg string, err error) {
+		slog.Error(msg, "err", err)
+		os.RemoveAll(work)
+		os.Exit(1)
+	}
+
+	rng := rand.New(rand.NewSource(globals.Seed))
+	bytes := int64(*sizeMB) << 20
+	result := RunResult{
+		Config: RunConfig{SizeMB: *sizeMB, MemoryMB: *memoryMB, FanIn: *fanIn, InMemory: *inMemory, Seed: globals.Seed},
+		Env:    bench.CaptureEnv(),
+		Bytes:  bytes,
+		Values: int(bytes / ValueSize),
+	}
+	ctx := context.Background()
+	input, output := filepath.Join(work, "input.bin"), filepath.Join(work, "sorted.bin")
+	slog.Info("writing random integers", "mib", *sizeMB, "dir", work)
+	result.GenerateNs = float64(bench.Phase(ctx, "generate", func() { err = generate(rng, input, result.Values) }).Nanoseconds())
+	if err != nil {
+		fail("failed to write the input", err)
+	}
+	inputCount, inputSum, _, err := check(input)
+	if err != nil {
+		fail("failed to read the input", err)
+	}
+
+	sorter := &Sorter{Memory: *memoryMB << 20, FanIn: *fanIn, Dir: work}
+	var runs []string
+	slog.Info("sorting runs", "memory_mib", *memoryMB)
+	result.RunsNs = float64(bench.Phase(ctx, "sort runs", func() { runs, err = sortRuns(sorter, input) }).Nanoseconds())
+	if err != nil {
+		fail("failed to sort the runs", err)
+	}
+	result.Runs = len(runs)
+	slog.Info("merging runs", "runs", len(runs))
+	result.MergeNs = float64(bench.Phase(ctx, "merge", func() { err = merge(sorter, runs, output) }).Nanoseconds())
+	if err != nil {
+		fail("failed to merge the runs", err)
+	}
+	result.Passes = sorter.Passes
+	count, sum, sorted, err := check(output)
+	if err != nil {
+		fail("failed to read the output", err)
+	}
+	result.Sorted, result.Matches = sorted, count == inputCount && sum == inputSum
+	os.Remove(output)
+
+	if *inMemory {
+		slog.Info("sorting in memory")
+		result.InMemoryNs = float64(bench.Phase(ctx, "in memory", func() { err = sortInMemory(input, output) }).Nanoseconds())
+		if err != nil {
+			fail("failed to sort in memory", err)
+		}
+		os.Remove(output)
+	}
+
+	fanIns := []int{}
+	for _, f := range fanInSweep {
+		if f < len(runs) {
+			fanIns = append(fanIns, f)
+		}
+	}
+	fanIns = append(fanIns, max(len(runs), 2))
+	for _, f := range fanIns {
+		slog.Info("merging runs", "fan_in", f)
+		s := &Sorter{Memory: *memoryMB << 20, FanIn: f, Dir: work}
+		elapsed := bench.Phase(ctx, fmt.Sprintf("merge fan-in %d", f), func() { err = merge(s, runs, output) })
+		if err != nil {
+			fail("failed to merge the runs", err)
+		}
+		os.Remove(output)
+		result.FanIns = append(result.FanIns, FanInResult{FanIn: f, Passes: s.Passes, BytesMoved: 2 * bytes * int64(s.Passes), Ns: float64(elapsed.Nanoseconds())})
+	}
+
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		fail("failed to write results", err)
+	}
+}
+
+// writeBenchmarks writes a line per phase and per fan-in, each with its throughput in MB/s
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/external_sort"); err != nil {
+		return err
+	}
+	config := result.Config
+	size := fmt.Sprintf("size=%dMiB/memory=%dMiB", config.SizeMB, config.MemoryMB)
+	throughput := func(name string, ns float64) bench.Benchmark {
+		return bench.Benchmark{Name: name + "/" + size, N: 1, Metrics: []bench.Metric{
+			{Value: ns, Unit: "ns/op"},
+			{Value: float64(result.Bytes) / time.Duration(ns).Seconds() / 1e6, Unit: "MB/s"},
+		}}
+	}
+	benchmarks := []bench.Benchmark{
+		throughput("Generate", result.GenerateNs),
+		throughput("SortRuns", result.RunsNs),
+		throughput("Merge", result.MergeNs),
+		throughput("External", result.RunsNs+result.MergeNs),
+	}
+	if config.InMemory {
+		benchmarks = append(benchmarks, throughput("InMemory", result.InMemoryNs))
+	}
+	for _, f := range result.FanIns {
+		benchmarks = append(benchmarks, throughput(fmt.Sprintf("Merge/fan-in=%d", f.FanIn), f.Ns))
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
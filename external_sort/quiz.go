@@ -0,0 +1,63 @@
+package externalsort
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz extsort, it follows a file through the runs and the heap merge, and asks what the
+// fan-in trades between passes over the data and the size of each read
+var quiz = []lesson.Question{
+	{
+		Prompt: "How many runs does sorting a file in chunks make?",
+		Choices: []string{
+			"The file's size over the chunk size, a chunk being what memory holds at once",
+			"As many as the fan-in, so the merge can take them all in one pass",
+			"One per CPU, each sorting its share of the file in parallel",
+			"log2 of the file's size, halving the unsorted part each time",
+		},
+		Answer:      0,
+		Explanation: "each run is a chunk sorted in memory and written out, the more memory the fewer and longer the runs",
+	},
+	{
+		Prompt: "Why does the merge use a heap of one integer from each run?",
+		Choices: []string{
+			"The heap re-sorts each run as it is read, in case a run was written out of order",
+			"The heap needs less memory than a slice of the runs' heads, so more runs fit in a pass",
+			"The next output is the smallest of the runs' heads, a heap finds it in O(log runs)",
+			"The heap holds a buffer of integers from each run so reads can be made in large blocks",
+		},
+		Answer:      2,
+		Explanation: "the merge pops the smallest head, writes it, and pushes the next integer from the same run, so the heap never holds more than one integer a run",
+	},
+	{
+		Prompt: "Why does merging at a fan-in of 2 move several times more bytes than merging every run at once?",
+		Choices: []string{
+			"A fan-in of 2 writes every integer twice, once for each of the runs it's merging",
+			"It takes log2(runs) passes, and every pass reads and writes the whole file",
+			"A small fan-in needs bigger buffers, which are flushed to disk before they're full",
+			"Each pass re-sorts the runs it produced, since a two way merge isn't stable",
+		},
+		Answer:      1,
+		Explanation: "the cost of an external sort is counted in passes over the data, a bigger fan-in means fewer of them",
+	},
+	{
+		Prompt: "What stops the fan-in from being made as big as possible?",
+		Choices: []string{
+			"The heap slows to O(runs) per pop once it holds more than a few dozen items",
+			"Go limits a process to 16 open files, so no more runs can be read at once",
+			"Merging many runs at once can lose integers when two runs have equal heads",
+			"Memory is split into a buffer per run, so huge fan-ins read in tiny pieces",
+		},
+		Answer:      3,
+		Explanation: "the best fan-in balances fewer passes against reading in pieces big enough to keep the disk streaming, on a disk every small read pays for a seek",
+	},
+	{
+		Prompt: "Why is the in-memory sort faster when the file fits in memory?",
+		Choices: []string{
+			"It uses a radix sort, which beats the comparison sort used on each run",
+			"It reads and writes the file once, and pays for no runs and no heap",
+			"It checks fewer integers, since duplicates are dropped as it reads",
+			"It runs on every core, where the external sort merges on one",
+		},
+		Answer:      1,
+		Explanation: "the external sort writes and reads the file again for the runs and pays for the heap on every integer it merges, it is for when that isn't an option, memory bounded by -memory however big the file grows",
+	},
+}
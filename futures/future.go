@@ -0,0 +1,147 @@
+package futures
+
+import (
+	"context"
+	"sync"
+)
+
+// Future is a result that's still being worked out, read with Await, which waits for it, as often and from as many
+// goroutines as like
+// done is closed once value and err are set, and the close is what publishes them, a goroutine that sees done
+// closed is guaranteed to see them too
+type Future[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// Promise is the end of a Future that sets its result, only the first Resolve counts
+type Promise[T any] struct {
+	future *Future[T]
+	once   sync.Once
+}
+
+// NewPromise creates a future and the promise that resolves it
+func NewPromise[T any]() (*Promise[T], *Future[T]) {
+	f := &Future[T]{done: make(chan struct{})}
+	return &Promise[T]{future: f}, f
+}
+
+// Resolve sets the future's result and wakes everything awaiting it, reporting whether it was the call that did
+func (p *Promise[T]) Resolve(value T, err error) bool {
+	resolved := false
+	p.once.Do(func() {
+		p.future.value, p.future.err = value, err
+		close(p.future.done)
+		resolved = true
+	})
+	return resolved
+}
+
+// Go calls fn on a goroutine of its own, returning the future of its result, fn is passed ctx and should give up
+// when it's done, a future can't stop the work behind it
+func Go[T any](ctx context.Context, fn func(context.Context) (T, error)) *Future[T] {
+	p, f := NewPromise[T]()
+	go func() { p.Resolve(fn(ctx)) }()
+	return f
+}
+
+// Resolved is a future whose result is already v
+func Resolved[T any](v T) *Future[T] {
+	p, f := NewPromise[T]()
+	p.Resolve(v, nil)
+	return f
+}
+
+// Done is closed once the result is set, for a select across futures and other channels
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Await waits for the result, or for ctx to be done, in which case it returns ctx's error, the work carries on and
+// the result can still be awaited later
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Then is the future of fn called with f's value once it's ready, without anything having to wait on f, if f fails
+// fn isn't called and its error is passed on
+func Then[T, U any](ctx context.Context, f *Future[T], fn func(context.Context, T) (U, error)) *Future[U] {
+	return Go(ctx, func(ctx context.Context) (U, error) {
+		v, err := f.Await(ctx)
+		if err != nil {
+			var zero U
+			return zero, err
+		}
+		return fn(ctx, v)
+	})
+}
+
+// OrElse is f with fallback in place of an error, for a result that's nice to have but not worth failing over
+func OrElse[T any](f *Future[T], fallback T) *Future[T] {
+	return Go(context.Background(), func(ctx context.Context) (T, error) {
+		if v, err := f.Await(ctx); err == nil {
+			return v, nil
+		}
+		return fallback, nil
+	})
+}
+
+// All is the future of every one of fs's values, in order, or of the first error among them, which it reports as
+// soon as it happens rather than waiting for the rest
+func All[T any](ctx context.Context, fs ...*Future[T]) *Future[[]T] {
+	p, all := NewPromise[[]T]()
+	values := make([]T, len(fs))
+	var wg sync.WaitGroup
+	for i, f := range fs {
+		wg.Go(func() {
+			v, err := f.Await(ctx)
+			if err != nil {
+				p.Resolve(nil, err)
+				return
+			}
+			values[i] = v
+		})
+	}
+	go func() {
+		wg.Wait()
+		p.Resolve(values, nil)
+	}()
+	return all
+}
+
+// First is the future of whichever of fs succeeds first, or of the last error if none do, the rest carry on, it's
+// up to whoever started them to cancel them
+func First[T any](ctx context.Context, fs ...*Future[T]) *Future[T] {
+	p, first := NewPromise[T]()
+	errs := make(chan error, len(fs))
+	for _, f := range fs {
+		go func() {
+			v, err := f.Await(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			p.Resolve(v, nil)
+		}()
+	}
+	go func() {
+		var err error
+		for range fs {
+			select {
+			case err = <-errs:
+			case <-first.done:
+				return
+			}
+		}
+		var zero T
+		p.Resolve(zero, err)
+	}()
+	return first
+}
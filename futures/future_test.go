@@ -0,0 +1,83 @@
+package futures
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+var errTest = errors.New("test")
+
+func TestPromiseResolvesOnce(t *testing.T) {
+	p, f := NewPromise[int]()
+	if !p.Resolve(1, nil) || p.Resolve(2, errTest) {
+		t.Fatal("want only the first resolve to count")
+	}
+	for range 2 {
+		if v, err := f.Await(context.Background()); v != 1 || err != nil {
+			t.Fatalf("awaited %d, %v, want 1, nil", v, err)
+		}
+	}
+}
+
+func TestAwaitGivesUpWithItsContext(t *testing.T) {
+	p, f := NewPromise[int]()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := f.Await(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("awaited an unresolved future with %v, want the deadline", err)
+	}
+	p.Resolve(3, nil)
+	if v, _ := f.Await(context.Background()); v != 3 {
+		t.Fatalf("awaited %d once it was resolved, want 3", v)
+	}
+}
+
+func TestCombinators(t *testing.T) {
+	ctx := context.Background()
+	failed := Go(ctx, func(context.Context) (int, error) { return 0, errTest })
+	called := false
+	then := Then(ctx, failed, func(context.Context, int) (int, error) { called = true; return 1, nil })
+	if _, err := then.Await(ctx); !errors.Is(err, errTest) || called {
+		t.Errorf("Then of a failure gave %v and called fn %v, want the failure and no call", err, called)
+	}
+	if v, _ := OrElse(failed, 7).Await(ctx); v != 7 {
+		t.Errorf("OrElse of a failure gave %d, want the fallback 7", v)
+	}
+
+	all, err := All(ctx, Resolved(1), Then(ctx, Resolved(1), func(_ context.Context, v int) (int, error) { return v + 1, nil })).Await(ctx)
+	if err != nil || len(all) != 2 || all[0] != 1 || all[1] != 2 {
+		t.Errorf("All gave %v, %v, want [1 2]", all, err)
+	}
+	never, _ := NewPromise[int]()
+	if _, err := All(ctx, never.future, failed).Await(ctx); !errors.Is(err, errTest) {
+		t.Errorf("All with a failure gave %v, want the failure without waiting for the rest", err)
+	}
+
+	slow := Go(ctx, func(context.Context) (int, error) { time.Sleep(50 * time.Millisecond); return 2, nil })
+	if v, err := First(ctx, failed, slow, Resolved(3)).Await(ctx); v != 3 || err != nil {
+		t.Errorf("First gave %d, %v, want the resolved 3", v, err)
+	}
+	if _, err := First(ctx, failed, failed).Await(ctx); !errors.Is(err, errTest) {
+		t.Errorf("First of failures gave %v, want the failure", err)
+	}
+}
+
+func TestEveryApproachBuildsTheSamePages(t *testing.T) {
+	latencies := newServices(rand.New(rand.NewSource(1)), 20, time.Millisecond, 20*time.Millisecond, 0.15)
+	var want Approach
+	for i, buildPage := range []func(context.Context, *services, int) (Page, error){sequentialPage, waitGroupPage, futurePage} {
+		a := build(context.Background(), "", latencies, 20*time.Millisecond, buildPage)
+		a.Calls, a.P50Ns, a.P99Ns, a.ElapsedNs = 0, 0, 0, 0
+		if i == 0 {
+			want = a
+			if a.Failed == 0 || a.Partial == 0 {
+				t.Fatalf("pages came out %+v, want some failed and some partial for the test to mean anything", a)
+			}
+		} else if a != want {
+			t.Errorf("approach %d built %+v, want %+v as one call at a time did", i, a, want)
+		}
+	}
+}
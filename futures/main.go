@@ -0,0 +1,380 @@
+// Package futures is the futures lesson, teachgo futures, a Future type built on a channel, with Then, All and First
+// to compose them, used to build a page from several simulated services, each call under a timeout, against the
+// same page built with goroutines and a WaitGroup, and built one call at a time
+package futures
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo futures -h, its first line is the summary teachgo help lists
+const Description = `Futures and promises, composing concurrent calls with timeouts, against goroutines and a WaitGroup
+
+Builds -pages pages, each from five calls to simulated services, a user, that user's orders and recommendations,
+both of which need the user first, and a price quote asked of two replicas, the first answer taken. A call takes
+around -latency, except for a -slow fraction that hang, and each is cut off by a -timeout of its own. A page fails
+without its user or orders, and is partial without recommendations or a price. Every page is built three ways from
+the same latencies, one call at a time, with goroutines and a WaitGroup, and with futures, where Then chains the
+calls that need the user onto it, First races the replicas and OrElse falls back for what's optional. Reports how
+many pages were complete, partial and failed, which is the same all three ways, and how long they took.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "futures",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"futures", "promises", "channels", "timeouts", "sync.WaitGroup", "composition"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// the calls a page makes, indexing a page's latencies
+const (
+	callUser = iota
+	callOrders
+	callRecommendations
+	callQuoteA
+	callQuoteB
+	callCount
+)
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Pages   int           `json:"pages"`
+	Latency time.Duration `json:"latency"`
+	Timeout time.Duration `json:"timeout"`
+	Slow    float64       `json:"slow"`
+	Seed    int64         `json:"seed"`
+}
+
+// Approach is how the pages came out built one way
+type Approach struct {
+	Name      string  `json:"name"`
+	Complete  int     `json:"complete"`
+	Partial   int     `json:"partial"`
+	Failed    int     `json:"failed"`
+	Calls     int64   `json:"calls"`
+	P50Ns     float64 `json:"p50_ns"`
+	P99Ns     float64 `json:"p99_ns"`
+	ElapsedNs float64 `json:"elapsed_ns"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config     RunConfig  `json:"config"`
+	Env        bench.Env  `json:"env"`
+	Approaches []Approach `json:"approaches"`
+}
+
+// Page is what a page is built from, Recommendations is nil and Price negative when they couldn't be had
+type Page struct {
+	User            int
+	Orders          []string
+	Recommendations []string
+	Price           float64
+}
+
+// complete is whether the page has everything, not just what it can't do without
+func (p Page) complete() bool {
+	return p.Recommendations != nil && p.Price >= 0
+}
+
+// services simulates the services the pages call, every call to one taking the latency drawn for it up front, so
+// each approach meets the same slow calls
+type services struct {
+	latencies [][callCount]time.Duration
+	timeout   time.Duration
+	calls     atomic.Int64
+}
+
+// newServices draws every call's latency, within half of latency either way, or for a slow fraction, twice the
+// timeout, long enough that the timeout always cuts it off
+func newServices(rng *rand.Rand, pages int, latency, timeout time.Duration, slow float64) [][callCount]time.Duration {
+	latencies := make([][callCount]time.Duration, pages)
+	for i := range latencies {
+		for c := range callCount {
+			latencies[i][c] = time.Duration((0.5 + rng.Float64()) * float64(latency))
+			if rng.Float64() < slow {
+				latencies[i][c] = 2 * timeout
+			}
+		}
+	}
+	return latencies
+}
+
+// call waits out a call's latency under the call's own timeout, returning the timeout's error if it runs out, or
+// ctx's if the caller gives up first
+func (s *services) call(ctx context.Context, page, c int) error {
+	s.calls.Add(1)
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	t := time.NewTimer(s.latencies[page][c])
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *services) user(ctx context.Context, page int) (int, error) {
+	if err := s.call(ctx, page, callUser); err != nil {
+		return 0, fmt.Errorf("user: %w", err)
+	}
+	return 1000 + page, nil
+}
+
+func (s *services) orders(ctx context.Context, page, user int) ([]string, error) {
+	if err := s.call(ctx, page, callOrders); err != nil {
+		return nil, fmt.Errorf("orders: %w", err)
+	}
+	return []string{fmt.Sprintf("order %d-1", user), fmt.Sprintf("order %d-2", user)}, nil
+}
+
+func (s *services) recommendations(ctx context.Context, page, user int) ([]string, error) {
+	if err := s.call(ctx, page, callRecommendations); err != nil {
+		return nil, fmt.Errorf("recommendations: %w", err)
+	}
+	return []string{fmt.Sprintf("item %d", user%7)}, nil
+}
+
+// quote asks replica, callQuoteA or callQuoteB, for a price
+func (s *services) quote(ctx context.Context, page, replica int) (float64, error) {
+	if err := s.call(ctx, page, replica); err != nil {
+		return 0, fmt.Errorf("quote: %w", err)
+	}
+	return 9.99, nil
+}
+
+// sequentialPage makes one call at a time, trying the second replica only if the first fails, so a page takes the
+// sum of its calls
+func sequentialPage(ctx context.Context, s *services, page int) (Page, error) {
+	user, err := s.user(ctx, page)
+	if err != nil {
+		return Page{}, err
+	}
+	orders, err := s.orders(ctx, page, user)
+	if err != nil {
+		return Page{}, err
+	}
+	p := Page{User: user, Orders: orders, Price: -1}
+	if recommendations, err := s.recommendations(ctx, page, user); err == nil {
+		p.Recommendations = recommendations
+	}
+	for _, replica := range []int{callQuoteA, callQuoteB} {
+		if price, err := s.quote(ctx, page, replica); err == nil {
+			p.Price = price
+			break
+		}
+	}
+	return p, nil
+}
+
+// waitGroupPage fetches the user, then everything else at once, each goroutine writing its own variables, read
+// once Wait says they've all finished, it waits for every one of them even when the orders have already failed
+func waitGroupPage(ctx context.Context, s *services, page int) (Page, error) {
+	user, err := s.user(ctx, page)
+	if err != nil {
+		return Page{}, err
+	}
+	p := Page{User: user, Price: -1}
+	var ordersErr error
+	var wg sync.WaitGroup
+	wg.Go(func() { p.Orders, ordersErr = s.orders(ctx, page, user) })
+	wg.Go(func() {
+		if recommendations, err := s.recommendations(ctx, page, user); err == nil {
+			p.Recommendations = recommendations
+		}
+	})
+	wg.Go(func() {
+		// racing the replicas needs a channel of its own, buffered so the loser's send doesn't block it forever,
+		// and a cancel to stop the loser once there's a winner
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		prices := make(chan float64, 2)
+		for _, replica := range []int{callQuoteA, callQuoteB} {
+			go func() {
+				price, err := s.quote(ctx, page, replica)
+				if err != nil {
+					price = -1
+				}
+				prices <- price
+			}()
+		}
+		for range 2 {
+			if price := <-prices; price >= 0 {
+				p.Price = price
+				return
+			}
+		}
+	})
+	wg.Wait()
+	if ordersErr != nil {
+		return Page{}, ordersErr
+	}
+	return p, nil
+}
+
+// futurePage starts every call as soon as what it needs is ready, the quotes straight away alongside the user, then
+// awaits the results in any order, they're all running already, cancelling what's still running once the page is
+// built or has failed
+func futurePage(ctx context.Context, s *services, page int) (Page, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	user := Go(ctx, func(ctx context.Context) (int, error) { return s.user(ctx, page) })
+	orders := Then(ctx, user, func(ctx context.Context, user int) ([]string, error) { return s.orders(ctx, page, user) })
+	recommendations := OrElse(Then(ctx, user, func(ctx context.Context, user int) ([]string, error) {
+		return s.recommendations(ctx, page, user)
+	}), nil)
+	price := OrElse(First(ctx,
+		Go(ctx, func(ctx context.Context) (float64, error) { return s.quote(ctx, page, callQuoteA) }),
+		Go(ctx, func(ctx context.Context) (float64, error) { return s.quote(ctx, page, callQuoteB) }),
+	), -1)
+
+	// orders passes on the user's error, so a page that's failed fails as soon as either has
+	p := Page{}
+	var err error
+	if p.Orders, err = orders.Await(ctx); err != nil {
+		return Page{}, err
+	}
+	p.User, _ = user.Await(ctx)
+	p.Recommendations, _ = recommendations.Await(ctx)
+	p.Price, _ = price.Await(ctx)
+	return p, nil
+}
+
+// build builds every page one way, timing each
+func build(ctx context.Context, name string, latencies [][callCount]time.Duration, timeout time.Duration,
+	buildPage func(context.Context, *services, int) (Page, error)) Approach {
+	s := &services{latencies: latencies, timeout: timeout}
+	a := Approach{Name: name}
+	stats := bench.NewStats()
+	elapsed := bench.Phase(ctx, name, func() {
+		for page := range latencies {
+			start := time.Now()
+			p, err := buildPage(ctx, s, page)
+			stats.Record(time.Since(start))
+			switch {
+			case err != nil:
+				slog.Debug("page failed", "approach", name, "page", page, "err", err)
+				a.Failed++
+			case p.complete():
+				a.Complete++
+			default:
+				a.Partial++
+			}
+		}
+	})
+	a.Calls = s.calls.Load()
+	a.P50Ns = float64(stats.Percentile(0.5))
+	a.P99Ns = float64(stats.Percentile(0.99))
+	a.ElapsedNs = float64(elapsed)
+	return a
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Futures")
+	fmt.Fprintln(w, "=======")
+	fmt.Fprintf(w, "Pages: %d, calls taking around %v, %.0f%% of them hanging, each cut off after %v\n", config.Pages,
+		config.Latency, config.Slow*100, config.Timeout)
+	fmt.Fprintf(w, "Machine: %s\n", result.Env)
+
+	fmt.Fprintf(w, "\n=====%s=====\n", "Building every page three ways")
+	fmt.Fprintf(w, "%-18s %9s %9s %9s %7s %12s %12s %12s\n", "Approach", "Complete", "Partial", "Failed", "Calls", "Page p50", "Page p99", "Elapsed")
+	for _, a := range result.Approaches {
+		fmt.Fprintf(w, "%-18s %9d %9d %9d %7d %12v %12v %12v\n", a.Name, a.Complete, a.Partial, a.Failed, a.Calls,
+			time.Duration(a.P50Ns), time.Duration(a.P99Ns), time.Duration(a.ElapsedNs))
+	}
+	fmt.Fprintln(w, "\nEvery approach meets the same slow calls, so the pages come out the same, only the time differs. One")
+	fmt.Fprintln(w, "call at a time a page takes the sum of its calls, and the second replica is only asked once the first")
+	fmt.Fprintln(w, "has timed out. The WaitGroup page makes the calls that need the user at once, but a race of the")
+	fmt.Fprintln(w, "replicas takes a channel and a cancel of its own, and it waits for every call even once the orders")
+	fmt.Fprintln(w, "have failed. The futures page says what each call needs and nothing more, so the quotes start")
+	fmt.Fprintln(w, "alongside the user, a failure is returned as soon as it happens, and Then, First and OrElse stand in")
+	fmt.Fprintln(w, "for the bookkeeping, the same channels and goroutines underneath")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo futures
+func Main(args []string) {
+	fs := bench.NewFlagSet("futures", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the futures lesson measures a single run"
+	pages := fs.Int("pages", 30, "the pages to build each way")
+	latency := fs.Duration("latency", 10*time.Millisecond, "how long a call takes, give or take half")
+	timeout := fs.Duration("timeout", 30*time.Millisecond, "how long a call is given before it's cut off")
+	slow := fs.Float64("slow", 0.05, "the fraction of calls that hang until their timeout")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "futures measures a single run, -trials isn't supported")
+	v.AtLeast("pages", *pages, 1)
+	v.NotNegative("latency", *latency)
+	v.Check(*timeout > 0, "-timeout must be positive, got %v", *timeout)
+	v.Fraction("slow", *slow)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	config := RunConfig{Pages: *pages, Latency: *latency, Timeout: *timeout, Slow: *slow, Seed: globals.Seed}
+	result := RunResult{Config: config, Env: bench.CaptureEnv()}
+	latencies := newServices(rand.New(rand.NewSource(globals.Seed)), *pages, *latency, *timeout, *slow)
+	ctx := context.Background()
+	result.Approaches = []Approach{
+		build(ctx, "one at a time", latencies, *timeout, sequentialPage),
+		build(ctx, "WaitGroup", latencies, *timeout, waitGroupPage),
+		build(ctx, "futures", latencies, *timeout, futurePage),
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per approach, an op is one page
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/futures"); err != nil {
+		return err
+	}
+	benchmarks := []bench.Benchmark{}
+	n := int64(result.Config.Pages)
+	for _, a := range result.Approaches {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: a.Name,
+			N:    n,
+			Metrics: []bench.Metric{
+				bench.NsPerOp(a.ElapsedNs, n),
+				{Value: a.P99Ns, Unit: "p99-ns"},
+				{Value: float64(a.Calls) / float64(n), Unit: "calls/op"},
+			},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
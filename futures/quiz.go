@@ -0,0 +1,63 @@
+package futures
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz futures, it sets one call at a time, a WaitGroup and futures against each other on the
+// same page build and asks what each costs in calls, time and goroutines left waiting
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why do the three approaches come out with the same complete, partial and failed pages?",
+		Choices: []string{
+			"They run on one shared pool of goroutines, so a call that fails for one fails for all three",
+			"The futures and WaitGroup versions retry a failed call until it agrees with the sequential one",
+			"Each call's latency is drawn up front, so every approach meets the same slow calls",
+			"Pages are built once and the result is counted against each of the three approaches",
+		},
+		Answer:      2,
+		Explanation: "concurrency changes how long a page takes, a call that hangs past its timeout fails whichever way it's made",
+	},
+	{
+		Prompt: "Why does one call at a time make the fewest calls but take the longest?",
+		Choices: []string{
+			"It asks a replica only after the other fails, but a page takes the sum of its calls",
+			"Its calls are given a longer timeout, so each slow call holds it up for longer",
+			"It has no timeouts, so a hung call holds up the page until the call returns",
+			"It builds the page twice, once to find the calls and once to make them",
+		},
+		Answer:      0,
+		Explanation: "the concurrent versions ask both replicas every time, trading calls that turn out not to be needed for a page that takes as long as its slowest path",
+	},
+	{
+		Prompt: "Why does the WaitGroup race of the replicas send on a channel with room for both answers?",
+		Choices: []string{
+			"So both answers are kept and the faster one can be checked against the slower",
+			"A buffered channel delivers its values in the order they were sent, the unbuffered one doesn't",
+			"Channels used from inside a WaitGroup's goroutines have to be buffered or wg.Wait deadlocks",
+			"Nothing reads the second answer, so on an unbuffered channel the loser would block forever",
+		},
+		Answer:      3,
+		Explanation: "First needs the same care, its futures are resolved at most once and awaiting one never blocks the goroutine that resolves it",
+	},
+	{
+		Prompt: "What does Await with a context that's done give up on?",
+		Choices: []string{
+			"The work behind the future, Await cancels the goroutine computing it",
+			"Only the waiting, the goroutine working out the result carries on",
+			"Every future started from the same context, which all fail at once",
+			"Nothing, Await returns the result whenever it arrives",
+		},
+		Answer:      1,
+		Explanation: "the goroutine carries on until its own context tells it to stop, which is why futurePage cancels its context once it has its page, a future is just the result, whoever started the work owns stopping it",
+	},
+	{
+		Prompt: "How can a page built with futures await its results in any order without slowing down?",
+		Choices: []string{
+			"Await looks at every pending future and returns whichever one resolves first",
+			"The futures are resolved in the order they're created, which matches the order awaited",
+			"Every call was started as soon as its inputs were ready, so they're all running already",
+			"It can't, awaiting a slow future first holds up the ones behind it",
+		},
+		Answer:      2,
+		Explanation: "the page takes as long as the slowest whatever order it waits in, awaiting the orders first lets a failed page return as soon as the orders or the user behind them have failed",
+	},
+}
@@ -0,0 +1,9 @@
+module github.com/joshdurbin/teaching-go
+
+go 1.26.0
+
+require (
+	golang.org/x/sync v0.23.0
+	golang.org/x/time v0.16.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,145 @@
+package goroutineleaks
+
+import (
+	"cmp"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Detector finds goroutines started since it was created that are still around, the way a leak check at the end of
+// a test does, it samples runtime.NumGoroutine until the count stops changing, then reads every goroutine's stack
+// from runtime.Stack and groups the new ones by where they're blocked
+type Detector struct {
+	baseline int
+	existing map[int]bool
+}
+
+// NewDetector records the goroutines running now, which are never reported as leaks
+func NewDetector() *Detector {
+	d := &Detector{baseline: runtime.NumGoroutine(), existing: map[int]bool{}}
+	for _, g := range goroutines() {
+		d.existing[g.id] = true
+	}
+	return d
+}
+
+// Settle samples the goroutine count every interval until it's back to the baseline or hasn't changed for quiet,
+// returning how many goroutines over the baseline are left, a goroutine that's still finishing its work isn't a
+// leak, one that's still there once the count has held steady for a while most likely is
+func (d *Detector) Settle(interval, quiet time.Duration) int {
+	last, since := runtime.NumGoroutine(), time.Now()
+	for last > d.baseline && time.Since(since) < quiet {
+		time.Sleep(interval)
+		if n := runtime.NumGoroutine(); n != last {
+			last, since = n, time.Now()
+		}
+	}
+	return last - d.baseline
+}
+
+// Leak is a group of goroutines blocked in the same place, Function is the first frame of their stacks in this
+// package, Location its file and line, and CreatedBy the function that started them
+type Leak struct {
+	Count     int    `json:"count"`
+	State     string `json:"state"`
+	Function  string `json:"function"`
+	Location  string `json:"location"`
+	CreatedBy string `json:"created_by"`
+}
+
+// Leaks groups the goroutines started since the detector was created, most first
+func (d *Detector) Leaks() []Leak {
+	groups := map[Leak]int{}
+	for _, g := range goroutines() {
+		if !d.existing[g.id] {
+			groups[g.leak]++
+		}
+	}
+	leaks := []Leak{}
+	for leak, n := range groups {
+		leak.Count = n
+		leaks = append(leaks, leak)
+	}
+	slices.SortFunc(leaks, func(a, b Leak) int {
+		return cmp.Or(b.Count-a.Count, strings.Compare(a.Function, b.Function))
+	})
+	return leaks
+}
+
+// goroutine is one goroutine's stack, parsed
+type goroutine struct {
+	id   int
+	leak Leak
+}
+
+// goroutines reads every goroutine's stack, growing the buffer until it holds them all, and parses the text, a
+// block per goroutine that looks like
+//
+//	goroutine 7 [chan send]:
+//	github.com/joshdurbin/teaching-go/goroutine_leaks.fetchLeaky.func1()
+//		/path/to/goroutine_leaks/leaks.go:71 +0x2d
+//	created by github.com/joshdurbin/teaching-go/goroutine_leaks.fetchLeaky in goroutine 1
+//		/path/to/goroutine_leaks/leaks.go:71 +0x9a
+func goroutines() []goroutine {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	gs := []goroutine{}
+	for block := range strings.SplitSeq(string(buf), "\n\n") {
+		if g, ok := parseGoroutine(block); ok {
+			gs = append(gs, g)
+		}
+	}
+	return gs
+}
+
+// pkgPrefix is how this package's functions start in a stack
+const pkgPrefix = "github.com/joshdurbin/teaching-go/goroutine_leaks."
+
+func parseGoroutine(block string) (goroutine, bool) {
+	lines := strings.Split(strings.TrimSpace(block), "\n")
+	header, ok := strings.CutPrefix(lines[0], "goroutine ")
+	if !ok {
+		return goroutine{}, false
+	}
+	idText, state, ok := strings.Cut(header, " [")
+	id, err := strconv.Atoi(idText)
+	if !ok || err != nil {
+		return goroutine{}, false
+	}
+	// the state is followed by how long it's been waiting once that's a minute or more, which would split a group
+	state, _, _ = strings.Cut(strings.TrimSuffix(state, "]:"), ",")
+	g := goroutine{id: id, leak: Leak{State: state}}
+	for i := 1; i+1 < len(lines); i += 2 {
+		frame, location := lines[i], strings.TrimSpace(lines[i+1])
+		if createdBy, ok := strings.CutPrefix(frame, "created by "); ok {
+			createdBy, _, _ = strings.Cut(createdBy, " in goroutine")
+			g.leak.CreatedBy = shortName(createdBy)
+			continue
+		}
+		if g.leak.Function == "" && strings.HasPrefix(frame, pkgPrefix) {
+			if args := strings.LastIndex(frame, "("); args >= 0 {
+				frame = frame[:args]
+			}
+			g.leak.Function = shortName(frame)
+			location, _, _ = strings.Cut(location, " +")
+			g.leak.Location = filepath.Base(location)
+		}
+	}
+	return g, true
+}
+
+// shortName trims a function's import path to its package's name
+func shortName(function string) string {
+	return function[strings.LastIndex(function, "/")+1:]
+}
@@ -0,0 +1,39 @@
+package goroutineleaks
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPatterns(t *testing.T) {
+	config := RunConfig{Calls: 5, Work: 5 * time.Millisecond, Timeout: time.Millisecond, Settle: 30 * time.Millisecond}
+	for _, p := range patterns {
+		left, d := leave(config, p.Leaky)
+		if left != config.Calls {
+			t.Errorf("%s: leaky calls left %d goroutines, want %d", p.Name, left, config.Calls)
+		}
+		leaks := d.Leaks()
+		if len(leaks) != 1 || leaks[0].Count != config.Calls || !strings.Contains(leaks[0].Function, "Leaky") &&
+			!strings.Contains(leaks[0].Function, "startHeartbeat") {
+			t.Errorf("%s: detector found %+v, want %d goroutines in the leaky code", p.Name, leaks, config.Calls)
+		}
+		if left, _ := leave(config, p.Fixed); left != 0 {
+			t.Errorf("%s: fixed calls left %d goroutines", p.Name, left)
+		}
+	}
+}
+
+func TestParseGoroutine(t *testing.T) {
+	block := `goroutine 42 [chan send, 3 minutes]:
+github.com/joshdurbin/teaching-go/goroutine_leaks.fetchLeaky.func1()
+	/src/goroutine_leaks/leaks.go:68 +0x2d
+created by github.com/joshdurbin/teaching-go/goroutine_leaks.fetchLeaky in goroutine 1
+	/src/goroutine_leaks/leaks.go:68 +0x9a`
+	g, ok := parseGoroutine(block)
+	want := Leak{State: "chan send", Function: "goroutine_leaks.fetchLeaky.func1", Location: "leaks.go:68",
+		CreatedBy: "goroutine_leaks.fetchLeaky"}
+	if !ok || g.id != 42 || g.leak != want {
+		t.Fatalf("parsed %+v, %v, want goroutine 42 %+v", g, ok, want)
+	}
+}
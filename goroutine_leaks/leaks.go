@@ -0,0 +1,199 @@
+package goroutineleaks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// errTimeout is what fetch returns when the slow call takes longer than it's willing to wait
+var errTimeout = errors.New("timed out")
+
+// Pattern is a way of leaking goroutines, Leaky makes one call that leaks and Fixed the same call with the leak
+// fixed, work is how long a slow call takes and timeout how long a caller waits for one
+type Pattern struct {
+	Name  string
+	Cause string
+	Fix   string
+	Leaky func(work, timeout time.Duration)
+	Fixed func(work, timeout time.Duration)
+}
+
+// patterns is the gallery, in the order the lesson runs them
+var patterns = []Pattern{
+	{
+		Name: "abandoned result",
+		Cause: "fetch starts the slow call on a goroutine that sends its result on an unbuffered channel, then gives up " +
+			"waiting, once nothing will ever receive, the send blocks forever",
+		Fix:   "give the channel room for the one result, so the send never waits for a receiver",
+		Leaky: func(work, timeout time.Duration) { fetchLeaky(work, timeout) },
+		Fixed: func(work, timeout time.Duration) { fetchFixed(work, timeout) },
+	},
+	{
+		Name: "abandoned stream",
+		Cause: "generate sends numbers until they're no longer wanted, but has no way to be told, so once first has " +
+			"taken the few it needs the generator blocks on its next send forever",
+		Fix:   "pass the generator a context and select on its Done alongside the send, cancelled once first returns",
+		Leaky: func(time.Duration, time.Duration) { firstLeaky(3) },
+		Fixed: func(time.Duration, time.Duration) { firstFixed(3) },
+	},
+	{
+		Name: "forgotten receiver",
+		Cause: "write hands its lines to a goroutine that ranges over a channel, and returns without closing it, so " +
+			"the range never ends and the goroutine waits on a receive forever",
+		Fix:   "close the channel once every line is sent, and wait for the goroutine to finish the last of them",
+		Leaky: func(time.Duration, time.Duration) { writeLeaky(io.Discard, []string{"a", "b", "c"}) },
+		Fixed: func(time.Duration, time.Duration) { writeFixed(io.Discard, []string{"a", "b", "c"}) },
+	},
+	{
+		Name: "missing cancel",
+		Cause: "handle starts a heartbeat that beats until its context is cancelled, and never calls Stop, its context " +
+			"is the background, which nothing ever cancels, so it beats forever",
+		Fix:   "defer Stop as soon as the heartbeat starts, as defer cancel() follows every context.WithCancel",
+		Leaky: func(time.Duration, time.Duration) { handleLeaky(context.Background()) },
+		Fixed: func(time.Duration, time.Duration) { handleFixed(context.Background()) },
+	},
+}
+
+// slowCall is a call that takes work to answer
+func slowCall(work time.Duration) int {
+	time.Sleep(work)
+	return 42
+}
+
+func fetchLeaky(work, timeout time.Duration) (int, error) {
+	results := make(chan int)
+	go func() { results <- slowCall(work) }()
+	select {
+	case v := <-results:
+		return v, nil
+	case <-time.After(timeout):
+		return 0, errTimeout
+	}
+}
+
+func fetchFixed(work, timeout time.Duration) (int, error) {
+	results := make(chan int, 1)
+	go func() { results <- slowCall(work) }()
+	select {
+	case v := <-results:
+		return v, nil
+	case <-time.After(timeout):
+		return 0, errTimeout
+	}
+}
+
+func generateLeaky() <-chan int {
+	numbers := make(chan int)
+	go func() {
+		for i := 0; ; i++ {
+			numbers <- i
+		}
+	}()
+	return numbers
+}
+
+func firstLeaky(n int) []int {
+	numbers := generateLeaky()
+	first := []int{}
+	for range n {
+		first = append(first, <-numbers)
+	}
+	return first
+}
+
+func generateFixed(ctx context.Context) <-chan int {
+	numbers := make(chan int)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case numbers <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return numbers
+}
+
+func firstFixed(n int) []int {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	numbers := generateFixed(ctx)
+	first := []int{}
+	for range n {
+		first = append(first, <-numbers)
+	}
+	return first
+}
+
+func writeLeaky(w io.Writer, lines []string) {
+	queue := make(chan string)
+	go func() {
+		for line := range queue {
+			fmt.Fprintln(w, line)
+		}
+	}()
+	for _, line := range lines {
+		queue <- line
+	}
+}
+
+func writeFixed(w io.Writer, lines []string) {
+	queue := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for line := range queue {
+			fmt.Fprintln(w, line)
+		}
+	}()
+	for _, line := range lines {
+		queue <- line
+	}
+	close(queue)
+	<-done
+}
+
+// heartbeat beats every interval until it's stopped or its context is cancelled
+type heartbeat struct {
+	cancel context.CancelFunc
+	beats  int
+}
+
+// heartbeatInterval is long enough that a leaked heartbeat costs nothing but its goroutine during a run
+const heartbeatInterval = time.Minute
+
+func startHeartbeat(parent context.Context) *heartbeat {
+	ctx, cancel := context.WithCancel(parent)
+	h := &heartbeat{cancel: cancel}
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				h.beats++
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return h
+}
+
+func (h *heartbeat) Stop() {
+	h.cancel()
+}
+
+func handleLeaky(ctx context.Context) {
+	startHeartbeat(ctx)
+	slowCall(0)
+}
+
+func handleFixed(ctx context.Context) {
+	defer startHeartbeat(ctx).Stop()
+	slowCall(0)
+}
@@ -0,0 +1,197 @@
+// Package goroutineleaks is the goroutine leaks lesson, teachgo leaks, a gallery of ways to leak goroutines, each run
+// leaky and fixed, with a detector that samples the goroutine count until it settles and groups the stacks of the
+// goroutines left over by where they're stuck
+package goroutineleaks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo leaks -h, its first line is the summary teachgo help lists
+const Description = `Goroutine leaks, a gallery of blocked sends, forgotten receivers and missing cancels, and finding them
+
+Runs each of a gallery of leaks -calls times, a result sent on an unbuffered channel after its caller has given up
+waiting -timeout for a call taking -work, a generator no one can tell to stop, a goroutine ranging over a channel
+no one closes, and a heartbeat no one stops. A detector samples runtime.NumGoroutine until the count holds steady
+for -settle, then reads every goroutine's stack with runtime.Stack and groups the ones started since it began by
+where they're blocked and what started them, which is how a leak is tracked to its line. Then runs each fixed,
+which leaves nothing behind. A leaked goroutine never comes back, every one found stays until the process exits.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "leaks",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"goroutine leaks", "channels", "context cancellation", "runtime.Stack", "debugging"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// sampleInterval is how often the detector samples the goroutine count
+const sampleInterval = time.Millisecond
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Calls   int           `json:"calls"`
+	Work    time.Duration `json:"work"`
+	Timeout time.Duration `json:"timeout"`
+	Settle  time.Duration `json:"settle"`
+}
+
+// Gallery is how one pattern came out, the goroutines left over once the count settled after the leaky calls and
+// after the fixed ones, and what the detector found after the leaky ones
+type Gallery struct {
+	Pattern   string  `json:"pattern"`
+	Cause     string  `json:"cause"`
+	Fix       string  `json:"fix"`
+	LeakyLeft int     `json:"leaky_left"`
+	FixedLeft int     `json:"fixed_left"`
+	Leaks     []Leak  `json:"leaks"`
+	ElapsedNs float64 `json:"elapsed_ns"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config    RunConfig `json:"config"`
+	Env       bench.Env `json:"env"`
+	Galleries []Gallery `json:"galleries"`
+}
+
+// leave runs calls calls of fn, returning how many goroutines they left behind, and the detector that found them
+func leave(config RunConfig, fn func(work, timeout time.Duration)) (int, *Detector) {
+	d := NewDetector()
+	for range config.Calls {
+		fn(config.Work, config.Timeout)
+	}
+	return d.Settle(sampleInterval, config.Settle), d
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Goroutine Leaks")
+	fmt.Fprintln(w, "===============")
+	fmt.Fprintf(w, "Calls: %d of each, slow calls taking %v waited on for %v, counts settled over %v\n", config.Calls,
+		config.Work, config.Timeout, config.Settle)
+	fmt.Fprintf(w, "Machine: %s\n", result.Env)
+
+	fmt.Fprintf(w, "\n=====%s=====\n", "Goroutines left over")
+	fmt.Fprintf(w, "%-20s %12s %12s\n", "Pattern", "Leaky", "Fixed")
+	for _, g := range result.Galleries {
+		fmt.Fprintf(w, "%-20s %12d %12d\n", g.Pattern, g.LeakyLeft, g.FixedLeft)
+	}
+	for _, g := range result.Galleries {
+		fmt.Fprintf(w, "\n=====%s=====\n", g.Pattern)
+		for _, leak := range g.Leaks {
+			fmt.Fprintf(w, "%6d [%s] in %s at %s, started by %s\n", leak.Count, leak.State, leak.Function,
+				leak.Location, leak.CreatedBy)
+		}
+		printWrapped(w, "Cause: "+g.Cause)
+		printWrapped(w, "Fix: "+g.Fix)
+	}
+	fmt.Fprintln(w, "\nEach leaky call leaves a goroutine behind for good, blocked on a channel or a select nothing will ever")
+	fmt.Fprintln(w, "complete, and the count only ever grows. Right after the calls a count can't tell a leak from a")
+	fmt.Fprintln(w, "goroutine still finishing its work, the fixed slow calls are still running too, so the detector waits")
+	fmt.Fprintln(w, "for it to settle, then the stacks say where each is stuck and the created by line who started it")
+}
+
+// printWrapped prints text a line at a time, breaking it between words before lines run past the notes' width
+func printWrapped(w io.Writer, text string) {
+	const width = 105
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			fmt.Fprintln(w, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	fmt.Fprintln(w, line)
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo leaks
+func Main(args []string) {
+	fs := bench.NewFlagSet("leaks", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the leaks lesson measures a single run"
+	calls := fs.Int("calls", 100, "the calls made of each pattern, leaky and fixed")
+	work := fs.Duration("work", 5*time.Millisecond, "how long a slow call takes to answer")
+	timeout := fs.Duration("timeout", time.Millisecond, "how long a caller waits for a slow call")
+	settle := fs.Duration("settle", 50*time.Millisecond, "how long the goroutine count has to hold steady to be taken as settled")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "leaks measures a single run, -trials isn't supported")
+	v.AtLeast("calls", *calls, 1)
+	v.NotNegative("work", *work)
+	v.NotNegative("timeout", *timeout)
+	v.Check(*timeout < *work, "-timeout must be shorter than -work for the caller to give up, got %v and %v", *timeout, *work)
+	v.Check(*settle > *work, "-settle must be longer than -work, or a slow call still running looks like a leak, got %v", *settle)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	config := RunConfig{Calls: *calls, Work: *work, Timeout: *timeout, Settle: *settle}
+	result := RunResult{Config: config, Env: bench.CaptureEnv()}
+	for _, p := range patterns {
+		slog.Info("leaking", "pattern", p.Name, "calls", *calls)
+		g := Gallery{Pattern: p.Name, Cause: p.Cause, Fix: p.Fix}
+		start := time.Now()
+		var d *Detector
+		g.LeakyLeft, d = leave(config, p.Leaky)
+		g.Leaks = d.Leaks()
+		g.FixedLeft, _ = leave(config, p.Fixed)
+		g.ElapsedNs = float64(time.Since(start))
+		result.Galleries = append(result.Galleries, g)
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per pattern, an op is one leaky and one fixed call
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/goroutine_leaks"); err != nil {
+		return err
+	}
+	benchmarks := []bench.Benchmark{}
+	n := int64(result.Config.Calls)
+	for _, g := range result.Galleries {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: g.Pattern,
+			N:    n,
+			Metrics: []bench.Metric{
+				bench.NsPerOp(g.ElapsedNs, n),
+				{Value: float64(g.LeakyLeft) / float64(n), Unit: "leaked/op"},
+				{Value: float64(g.FixedLeft) / float64(n), Unit: "fixed-leaked/op"},
+			},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
@@ -0,0 +1,63 @@
+package goroutineleaks
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz leaks, it asks why each leaky pattern blocks forever, how its fix lets the goroutine
+// finish, and how the detector tells a stuck goroutine from one that is just slow
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does a buffer of one fix the abandoned result?",
+		Choices: []string{
+			"A buffered channel is closed by the runtime once its value has been received",
+			"The send completes into the buffer whether or not anyone is waiting, so the goroutine ends",
+			"The caller's select now waits for the result as well as the timeout, so it's always read",
+			"The buffer makes the send non-blocking, so the goroutine drops the result if it's late",
+		},
+		Answer:      1,
+		Explanation: "the channel and its value are collected once nothing refers to them, one slot is enough because there's only ever one send, a goroutine that sends several values needs a way to be told to stop instead",
+	},
+	{
+		Prompt: "Why does the detector wait for the goroutine count to settle before calling anything a leak?",
+		Choices: []string{
+			"Goroutines still doing legitimate work count the same as leaked ones until they finish",
+			"runtime.NumGoroutine is only refreshed at each garbage collection, so it lags behind",
+			"Goroutine stacks can't be read while any of them is running, only once all are parked",
+			"It gives the garbage collector time to free the leaked goroutines that have no references",
+		},
+		Answer:      0,
+		Explanation: "only a goroutine that's still there once the count holds steady is likely stuck, the settle time has to be longer than the slowest legitimate work, which is why -settle must be longer than -work",
+	},
+	{
+		Prompt: "What does the garbage collector do with a leaked goroutine's channel?",
+		Choices: []string{
+			"Collects it once nothing but the blocked goroutine refers to it, which unblocks the goroutine",
+			"Closes it when the function that made it returns, waking the goroutine with a zero value",
+			"Collects it and the goroutine together at the next cycle, since neither is reachable",
+			"Nothing, the blocked goroutine still refers to it and goroutines are never collected",
+		},
+		Answer:      3,
+		Explanation: "both stay until the process exits, this is why every leaky call adds one to the count for good, and why a leak in a long running server shows as memory that only grows",
+	},
+	{
+		Prompt: "How does the detector tell the goroutines each pattern leaked from the ones the earlier patterns did?",
+		Choices: []string{
+			"It stops every goroutine the earlier patterns leaked before it runs the next pattern",
+			"It records the ids already running when it's created and only reports new ones",
+			"It compares each goroutine's start time with when the pattern began running",
+			"It can't, the counts it reports are cumulative over every pattern so far",
+		},
+		Answer:      1,
+		Explanation: "the new ones are grouped by where they're blocked, leak checks in tests work the same way, ignoring whatever was running before the test began",
+	},
+	{
+		Prompt: "Why does the missing cancel leak show up in a select rather than on a channel send or receive?",
+		Choices: []string{
+			"Its stack is read while the goroutine is running, so it shows the last statement it passed",
+			"The context was cancelled, and a cancelled context parks its goroutine inside a select",
+			"The heartbeat waits on its ticker and Done at once, the ticker fires but Done never closes",
+			"Every goroutine blocked on a channel is reported as in a select by the stack dump",
+		},
+		Answer:      2,
+		Explanation: "it loops on the select forever, a goroutine that's busy on a ticker rather than blocked still leaks, the stack shows the select it returns to between beats",
+	},
+}
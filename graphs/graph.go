@@ -0,0 +1,190 @@
+package graphs
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+)
+
+// Unreachable is the distance to a node no path leads to
+const Unreachable = math.MaxInt
+
+// Edge is one end of an undirected edge, the node it leads to and what it costs to cross
+type Edge struct {
+	To     int
+	Weight int
+}
+
+// Graph is an undirected graph stored as adjacency lists, each node's slice of the edges leaving it, O(nodes + edges)
+// memory where a matrix would take O(nodes^2), and visiting a node's neighbours costs only as many steps as it has
+type Graph struct {
+	adj   [][]Edge
+	edges int
+}
+
+// NewGraph creates a graph of n nodes, 0 to n-1, and no edges
+func NewGraph(n int) *Graph {
+	return &Graph{adj: make([][]Edge, n)}
+}
+
+// AddEdge joins u and v both ways with an edge of the given weight
+func (g *Graph) AddEdge(u, v, weight int) {
+	g.adj[u] = append(g.adj[u], Edge{v, weight})
+	g.adj[v] = append(g.adj[v], Edge{u, weight})
+	g.edges++
+}
+
+// Neighbours returns the edges leaving u
+func (g *Graph) Neighbours(u int) []Edge {
+	return g.adj[u]
+}
+
+func (g *Graph) Nodes() int {
+	return len(g.adj)
+}
+
+func (g *Graph) Edges() int {
+	return g.edges
+}
+
+// RandomGraph joins random pairs of n nodes until the nodes average degree edges each, with weights from 1 to
+// maxWeight, a node can end up with no edges at all, and the graph in several pieces, when the degree is low
+func RandomGraph(rng *rand.Rand, n int, degree float64, maxWeight int) *Graph {
+	g := NewGraph(n)
+	for range int(degree * float64(n) / 2) {
+		u, v := rng.Intn(n), rng.Intn(n)
+		if u != v {
+			g.AddEdge(u, v, 1+rng.Intn(maxWeight))
+		}
+	}
+	return g
+}
+
+// Search is what a traversal from a source found, Dist is each node's distance from the source, in edges for BFS
+// and DFS, in total weight for Dijkstra, Unreachable for nodes it never reached, and Prev the node each was reached
+// from, -1 for the source and the unreached, following Prev back from a node spells its path
+type Search struct {
+	Dist    []int
+	Prev    []int
+	Reached int
+}
+
+func newSearch(n, source int) Search {
+	s := Search{Dist: make([]int, n), Prev: make([]int, n), Reached: 1}
+	for i := range s.Dist {
+		s.Dist[i], s.Prev[i] = Unreachable, -1
+	}
+	s.Dist[source] = 0
+	return s
+}
+
+// Path returns the nodes from the source to target, empty if target wasn't reached
+func (s Search) Path(target int) []int {
+	if s.Dist[target] == Unreachable {
+		return nil
+	}
+	path := []int{}
+	for n := target; n != -1; n = s.Prev[n] {
+		path = append(path, n)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// BFS visits nodes breadth first, everything one edge from the source, then two, and so on, a FIFO queue holding the
+// frontier, so each node is first reached by a path with the fewest edges, the shortest path when every edge costs
+// the same
+func BFS(g *Graph, source int) Search {
+	s := newSearch(g.Nodes(), source)
+	queue := []int{source}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		for _, e := range g.adj[u] {
+			if s.Dist[e.To] == Unreachable {
+				s.Dist[e.To], s.Prev[e.To] = s.Dist[u]+1, u
+				s.Reached++
+				queue = append(queue, e.To)
+			}
+		}
+	}
+	return s
+}
+
+// DFS visits nodes depth first, following one path as far as it goes before backing up, a stack in place of BFS's
+// queue, it reaches the same nodes but by whatever path it happened to take, Dist is that path's length and not
+// the shortest
+// The stack is a slice rather than recursion, a graph of a million nodes can make a path a million calls deep
+func DFS(g *Graph, source int) Search {
+	s := newSearch(g.Nodes(), source)
+	visited := make([]bool, g.Nodes())
+	stack := []int{source}
+	for len(stack) > 0 {
+		u := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[u] {
+			continue
+		}
+		visited[u] = true
+		// pushed in reverse so the first neighbour is the next one explored, the order recursion would take
+		for i := len(g.adj[u]) - 1; i >= 0; i-- {
+			v := g.adj[u][i].To
+			if !visited[v] {
+				if s.Dist[v] == Unreachable {
+					s.Reached++
+				}
+				// a node pushed twice is reached by whichever push is popped first, the later one, deeper
+				s.Dist[v], s.Prev[v] = s.Dist[u]+1, u
+				stack = append(stack, v)
+			}
+		}
+	}
+	return s
+}
+
+// item is a node waiting in Dijkstra's queue with the distance it was queued at
+type item struct {
+	node, dist int
+}
+
+type queue []item
+
+func (q queue) Len() int           { return len(q) }
+func (q queue) Less(i, j int) bool { return q[i].dist < q[j].dist }
+func (q queue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *queue) Push(x any)        { *q = append(*q, x.(item)) }
+
+func (q *queue) Pop() any {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
+
+// Dijkstra finds the least total weight path from the source to every node, always settling the closest unsettled
+// node next, a min-heap of the nodes reached so far ordered by distance, O((nodes + edges) log nodes)
+// A node whose distance improves is pushed again rather than updated in place, the stale entry is skipped when it
+// surfaces, simpler than a heap that can decrease keys and no slower in practice
+// Weights must not be negative, a settled node is only final if no later edge can make its path shorter
+func Dijkstra(g *Graph, source int) Search {
+	s := newSearch(g.Nodes(), source)
+	q := &queue{{source, 0}}
+	for q.Len() > 0 {
+		it := heap.Pop(q).(item)
+		if it.dist > s.Dist[it.node] {
+			continue
+		}
+		for _, e := range g.adj[it.node] {
+			if d := it.dist + e.Weight; d < s.Dist[e.To] {
+				if s.Dist[e.To] == Unreachable {
+					s.Reached++
+				}
+				s.Dist[e.To], s.Prev[e.To] = d, it.node
+				heap.Push(q, item{e.To, d})
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,70 @@
+package graphs
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// a square with a cheap way round, 0-1-2 costs 2 by two hops, 0-2 directly costs 10 in one
+func square() *Graph {
+	g := NewGraph(5)
+	g.AddEdge(0, 1, 1)
+	g.AddEdge(1, 2, 1)
+	g.AddEdge(0, 2, 10)
+	g.AddEdge(2, 3, 1)
+	return g
+}
+
+func TestSearches(t *testing.T) {
+	g := square()
+	bfs, dijkstra := BFS(g, 0), Dijkstra(g, 0)
+	if !slices.Equal(bfs.Path(2), []int{0, 2}) {
+		t.Errorf("BFS path to 2 = %v, want the single hop [0 2]", bfs.Path(2))
+	}
+	if !slices.Equal(dijkstra.Path(3), []int{0, 1, 2, 3}) || dijkstra.Dist[3] != 3 {
+		t.Errorf("Dijkstra path to 3 = %v costing %d, want [0 1 2 3] costing 3", dijkstra.Path(3), dijkstra.Dist[3])
+	}
+	for name, s := range map[string]Search{"BFS": bfs, "DFS": DFS(g, 0), "Dijkstra": dijkstra} {
+		if s.Reached != 4 || s.Dist[4] != Unreachable || s.Path(4) != nil {
+			t.Errorf("%s reached %d nodes, want 4, the isolated node 4 unreached", name, s.Reached)
+		}
+	}
+}
+
+// on a random graph every search reaches the same nodes, and Dijkstra's distances are never beaten by another route
+func TestSearchesAgreeOnRandomGraph(t *testing.T) {
+	g := RandomGraph(rand.New(rand.NewSource(1)), 2000, 3, 20)
+	bfs, dfs, dijkstra := BFS(g, 0), DFS(g, 0), Dijkstra(g, 0)
+	if bfs.Reached != dfs.Reached || bfs.Reached != dijkstra.Reached {
+		t.Fatalf("reached BFS %d, DFS %d, Dijkstra %d", bfs.Reached, dfs.Reached, dijkstra.Reached)
+	}
+	for n := range g.Nodes() {
+		if dijkstra.Dist[n] == Unreachable {
+			continue
+		}
+		if got := routeCost(g, dijkstra.Path(n)); got != dijkstra.Dist[n] {
+			t.Fatalf("Dijkstra's route to %d costs %d, its distance says %d", n, got, dijkstra.Dist[n])
+		}
+		if routeCost(g, bfs.Path(n)) < dijkstra.Dist[n] || len(bfs.Path(n)) > len(dfs.Path(n)) {
+			t.Fatalf("node %d: BFS's route beats Dijkstra's cost or DFS's hops", n)
+		}
+	}
+}
+
+func TestPerfectMazeHasOneRoute(t *testing.T) {
+	m := NewMaze(rand.New(rand.NewSource(2)), 20, 10, 0, 0)
+	g := m.Graph()
+	// a perfect maze is a spanning tree of its cells
+	if g.Edges() != m.Cells()-1 {
+		t.Errorf("a perfect maze of %d cells has %d openings, want %d", m.Cells(), g.Edges(), m.Cells()-1)
+	}
+	finish := m.Cells() - 1
+	if bfs, dfs := BFS(g, 0), DFS(g, 0); !slices.Equal(bfs.Path(finish), dfs.Path(finish)) {
+		t.Error("BFS and DFS took different routes through a maze with only one")
+	}
+	lines := m.Render(BFS(g, 0).Path(finish))
+	if len(lines) != 21 || len(lines[0]) != 41 || lines[1][1] != 'S' || lines[19][39] != 'F' {
+		t.Errorf("Render drew %d lines of %d, want 21 of 41 with S and F in the corners", len(lines), len(lines[0]))
+	}
+}
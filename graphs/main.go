@@ -0,0 +1,246 @@
+// Package graphs is the graph lesson, teachgo graph, breadth first search, depth first search and Dijkstra's
+// shortest paths over adjacency lists, timed on a random graph or solving a generated maze
+package graphs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo graph -h, its first line is the summary teachgo help lists
+const Description = `Graph search, BFS, DFS and Dijkstra's shortest paths
+
+Builds a random weighted graph as adjacency lists and searches it from one node three ways, breadth first, depth
+first and with Dijkstra's algorithm, timing each and reporting how many nodes they reached and how far away they
+found them. With -maze, carves a maze on a grid instead, with a few loops and patches of mud that cost more to
+cross, solves it all three ways and prints it with the cheapest route marked, BFS taking the fewest steps, DFS
+whichever route it stumbled on, and Dijkstra the least total cost.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "graph",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"graphs", "adjacency lists", "breadth first search", "depth first search", "Dijkstra's algorithm"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// RunConfig records the settings a run was made with, the maze settings only when it solved a maze
+type RunConfig struct {
+	Nodes      int     `json:"nodes,omitempty"`
+	Degree     float64 `json:"degree,omitempty"`
+	MaxWeight  int     `json:"max_weight,omitempty"`
+	MazeWidth  int     `json:"maze_width,omitempty"`
+	MazeHeight int     `json:"maze_height,omitempty"`
+	Loops      float64 `json:"loops,omitempty"`
+	Mud        float64 `json:"mud,omitempty"`
+	Seed       int64   `json:"seed"`
+}
+
+// SearchResult is what one algorithm found from the source, Hops and Cost describe the route to the target, for a
+// maze the finish, for a random graph the node farthest from the source by Dijkstra's reckoning
+type SearchResult struct {
+	Name    string  `json:"name"`
+	Ns      float64 `json:"ns"`
+	Reached int     `json:"reached"`
+	Hops    int     `json:"hops"`
+	Cost    int     `json:"cost"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config   RunConfig      `json:"config"`
+	Env      bench.Env      `json:"env"`
+	Nodes    int            `json:"nodes"`
+	Edges    int            `json:"edges"`
+	Target   int            `json:"target"`
+	Searches []SearchResult `json:"searches"`
+	// Maze is the maze drawn with Dijkstra's route through it, only when solving a maze
+	Maze []string `json:"maze,omitempty"`
+}
+
+var algorithms = []struct {
+	name   string
+	search func(*Graph, int) Search
+}{
+	{"BFS", BFS},
+	{"DFS", DFS},
+	{"Dijkstra", Dijkstra},
+}
+
+// routeCost adds up the weights along a path, BFS and DFS count edges, what their routes cost has to be added up
+// A random graph can join two nodes more than once, a route crosses by the lightest of the edges
+func routeCost(g *Graph, path []int) int {
+	cost := 0
+	for i := 1; i < len(path); i++ {
+		lightest := Unreachable
+		for _, e := range g.Neighbours(path[i-1]) {
+			if e.To == path[i] {
+				lightest = min(lightest, e.Weight)
+			}
+		}
+		cost += lightest
+	}
+	return cost
+}
+
+// farthest is the reached node with the greatest distance, the target whose route says the most about a search
+func farthest(s Search) int {
+	target := 0
+	for n, d := range s.Dist {
+		if d != Unreachable && d > s.Dist[target] {
+			target = n
+		}
+	}
+	return target
+}
+
+// solve runs every algorithm from node 0, returning their results and Dijkstra's route to the target, the farthest
+// node Dijkstra found when target is -1
+func solve(ctx context.Context, g *Graph, target int) ([]SearchResult, int, []int) {
+	searches := make([]Search, len(algorithms))
+	results := make([]SearchResult, len(algorithms))
+	for i, a := range algorithms {
+		slog.Info("searching", "algorithm", a.name)
+		elapsed := bench.Phase(ctx, a.name, func() { searches[i] = a.search(g, 0) })
+		results[i] = SearchResult{Name: a.name, Ns: float64(elapsed.Nanoseconds()), Reached: searches[i].Reached}
+	}
+	dijkstra := searches[len(searches)-1]
+	if target < 0 {
+		target = farthest(dijkstra)
+	}
+	for i, s := range searches {
+		path := s.Path(target)
+		results[i].Hops = max(len(path)-1, 0)
+		results[i].Cost = routeCost(g, path)
+	}
+	return results, target, dijkstra.Path(target)
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Graph Search")
+	fmt.Fprintln(w, "============")
+	if result.Maze != nil {
+		fmt.Fprintf(w, "Maze: %d x %d cells, %.0f%% extra openings, %.0f%% mud costing %d a step\n",
+			config.MazeWidth, config.MazeHeight, 100*config.Loops, 100*config.Mud, mudCost)
+	} else {
+		fmt.Fprintf(w, "Nodes: %d\nAverage degree: %g\nWeights: 1 to %d\n", config.Nodes, config.Degree, config.MaxWeight)
+	}
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%d nodes, %d edges, routes from node 0 to node %d\n", result.Nodes, result.Edges, result.Target)
+	fmt.Fprintf(w, "%-10s %12s %10s %8s %8s\n", "Algorithm", "Time", "Reached", "Hops", "Cost")
+	for _, s := range result.Searches {
+		fmt.Fprintf(w, "%-10s %12v %10d %8d %8d\n", s.Name, time.Duration(s.Ns), s.Reached, s.Hops, s.Cost)
+	}
+
+	if result.Maze != nil {
+		fmt.Fprintln(w, "\nDijkstra's route, S to F, ~ is mud:")
+		for _, line := range result.Maze {
+			fmt.Fprintln(w, line)
+		}
+	}
+	fmt.Fprintln(w, "\nAll three reach the same nodes, BFS by the fewest hops, DFS by whatever route it followed first, and")
+	fmt.Fprintln(w, "Dijkstra by the least total weight, paying a heap operation per edge for it where BFS pays a queue append")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo graph
+func Main(args []string) {
+	fs := bench.NewFlagSet("graph", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the graph lesson measures a single run"
+	nodes := fs.Int("nodes", 200000, "the number of nodes in the random graph")
+	degree := fs.Float64("degree", 8, "the average number of edges per node in the random graph")
+	maxWeight := fs.Int("max-weight", 100, "the heaviest edge in the random graph, weights are drawn from 1 up to it")
+	maze := fs.Bool("maze", false, "solve a generated maze and print it, instead of searching a random graph")
+	width := fs.Int("maze-width", 30, "the width of the maze in cells")
+	height := fs.Int("maze-height", 12, "the height of the maze in cells")
+	loops := fs.Float64("loops", 0.1, "the fraction of the maze's remaining walls knocked through, making more than one route")
+	mud := fs.Float64("mud", 0.2, "the fraction of the maze's cells that are mud, costing more to cross")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "graph measures a single run, -trials isn't supported")
+	v.AtLeast("nodes", *nodes, 1)
+	v.Check(*degree >= 0, "-degree can't be negative, got %g", *degree)
+	v.AtLeast("max-weight", *maxWeight, 1)
+	v.AtLeast("maze-width", *width, 1)
+	v.AtLeast("maze-height", *height, 1)
+	v.Fraction("loops", *loops)
+	v.Fraction("mud", *mud)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	rng := rand.New(rand.NewSource(globals.Seed))
+	result := RunResult{Env: bench.CaptureEnv()}
+	ctx := context.Background()
+	if *maze {
+		result.Config = RunConfig{MazeWidth: *width, MazeHeight: *height, Loops: *loops, Mud: *mud, Seed: globals.Seed}
+		m := NewMaze(rng, *width, *height, *loops, *mud)
+		g := m.Graph()
+		result.Nodes, result.Edges = g.Nodes(), g.Edges()
+		var route []int
+		result.Searches, result.Target, route = solve(ctx, g, m.Cells()-1)
+		result.Maze = m.Render(route)
+	} else {
+		result.Config = RunConfig{Nodes: *nodes, Degree: *degree, MaxWeight: *maxWeight, Seed: globals.Seed}
+		slog.Info("generating graph", "nodes", *nodes, "degree", *degree)
+		g := RandomGraph(rng, *nodes, *degree, *maxWeight)
+		result.Nodes, result.Edges = g.Nodes(), g.Edges()
+		result.Searches, result.Target, _ = solve(ctx, g, -1)
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per algorithm timing one whole search, with the time per node reached so searches of
+// graphs of different sizes can be compared
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/graphs"); err != nil {
+		return err
+	}
+	graph := fmt.Sprintf("nodes=%d/edges=%d", result.Nodes, result.Edges)
+	if result.Maze != nil {
+		graph = fmt.Sprintf("maze=%dx%d", result.Config.MazeWidth, result.Config.MazeHeight)
+	}
+	benchmarks := []bench.Benchmark{}
+	for _, s := range result.Searches {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: "Search/" + s.Name + "/" + graph,
+			N:    1,
+			Metrics: []bench.Metric{
+				{Value: s.Ns, Unit: "ns/op"},
+				{Value: s.Ns / float64(max(s.Reached, 1)), Unit: "ns/node"},
+			},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
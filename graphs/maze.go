@@ -0,0 +1,156 @@
+package graphs
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// mudCost is what stepping into or out of a mud cell costs, an ordinary step costs 1
+const mudCost = 5
+
+// Maze is a grid of cells with walls between neighbours, cell (x, y) is node y*width + x of its graph
+type Maze struct {
+	width, height int
+	// right[c] and down[c] hold whether the walls to the right of and below cell c have been knocked through
+	right, down []bool
+	mud         []bool
+}
+
+// NewMaze carves a maze of width by height cells with a randomized depth first search, from the top left cell it
+// knocks through to a random unvisited neighbour, backing up when there are none, which makes a perfect maze, exactly
+// one path between any two cells
+// Then loops of the remaining inner walls are knocked through at random, giving some cells more than one path, and
+// mud of the cells are made mud, each a fraction from 0 to 1
+func NewMaze(rng *rand.Rand, width, height int, loops, mud float64) *Maze {
+	n := width * height
+	m := &Maze{width: width, height: height, right: make([]bool, n), down: make([]bool, n), mud: make([]bool, n)}
+	visited := make([]bool, n)
+	visited[0] = true
+	stack := []int{0}
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		next := []int{}
+		for _, nb := range m.neighbours(c) {
+			if !visited[nb] {
+				next = append(next, nb)
+			}
+		}
+		if len(next) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		nb := next[rng.Intn(len(next))]
+		m.open(c, nb)
+		visited[nb] = true
+		stack = append(stack, nb)
+	}
+
+	for c := range n {
+		if c%width < width-1 && !m.right[c] && rng.Float64() < loops {
+			m.right[c] = true
+		}
+		if c/width < height-1 && !m.down[c] && rng.Float64() < loops {
+			m.down[c] = true
+		}
+		// the start and the finish stay dry so every route starts and ends the same
+		m.mud[c] = c != 0 && c != n-1 && rng.Float64() < mud
+	}
+	return m
+}
+
+// neighbours returns the cells next to c, above, left, right and below, whether or not there's a wall between
+func (m *Maze) neighbours(c int) []int {
+	x, y := c%m.width, c/m.width
+	cells := []int{}
+	if y > 0 {
+		cells = append(cells, c-m.width)
+	}
+	if x > 0 {
+		cells = append(cells, c-1)
+	}
+	if x < m.width-1 {
+		cells = append(cells, c+1)
+	}
+	if y < m.height-1 {
+		cells = append(cells, c+m.width)
+	}
+	return cells
+}
+
+// open knocks through the wall between neighbouring cells a and b
+func (m *Maze) open(a, b int) {
+	a, b = min(a, b), max(a, b)
+	if b == a+1 {
+		m.right[a] = true
+	} else {
+		m.down[a] = true
+	}
+}
+
+// Cells is the number of cells, the start is cell 0, top left, and the finish the last, bottom right
+func (m *Maze) Cells() int {
+	return m.width * m.height
+}
+
+// Graph turns the maze into a graph, a node per cell and an edge through every open wall, costing 1 or mudCost when
+// either cell is mud
+func (m *Maze) Graph() *Graph {
+	g := NewGraph(m.Cells())
+	for c := range m.Cells() {
+		if m.right[c] {
+			g.AddEdge(c, c+1, m.cost(c, c+1))
+		}
+		if m.down[c] {
+			g.AddEdge(c, c+m.width, m.cost(c, c+m.width))
+		}
+	}
+	return g
+}
+
+// cost is what crossing between neighbouring cells a and b costs
+func (m *Maze) cost(a, b int) int {
+	if m.mud[a] || m.mud[b] {
+		return mudCost
+	}
+	return 1
+}
+
+// Render draws the maze in text, # for walls, ~ for mud, S and F for the start and finish, and the path's cells and
+// the gaps between them as dots
+func (m *Maze) Render(path []int) []string {
+	rows := make([][]byte, 2*m.height+1)
+	for y := range rows {
+		rows[y] = []byte(strings.Repeat("#", 2*m.width+1))
+	}
+	// cell (x, y) is drawn at (2x+1, 2y+1) and the wall to its right at (2x+2, 2y+1), below it at (2x+1, 2y+2)
+	for c := range m.Cells() {
+		x, y := 2*(c%m.width)+1, 2*(c/m.width)+1
+		rows[y][x] = ' '
+		if m.mud[c] {
+			rows[y][x] = '~'
+		}
+		if m.right[c] {
+			rows[y][x+1] = ' '
+		}
+		if m.down[c] {
+			rows[y+1][x] = ' '
+		}
+	}
+	for i, c := range path {
+		x, y := 2*(c%m.width)+1, 2*(c/m.width)+1
+		rows[y][x] = '.'
+		if i > 0 {
+			px, py := 2*(path[i-1]%m.width)+1, 2*(path[i-1]/m.width)+1
+			rows[(y+py)/2][(x+px)/2] = '.'
+		}
+	}
+	rows[1][1] = 'S'
+	last := m.Cells() - 1
+	rows[2*(last/m.width)+1][2*(last%m.width)+1] = 'F'
+
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		lines[i] = string(row)
+	}
+	return lines
+}
@@ -0,0 +1,63 @@
+package graphs
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz graph, it covers the adjacency list representation and what BFS, DFS and Dijkstra
+// each promise about the route they find through the maze
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does the lesson store the graph as adjacency lists rather than an adjacency matrix?",
+		Choices: []string{
+			"A matrix can only hold a yes or no for each pair, so the edges' weights would need a second one",
+			"A matrix takes nodes^2 memory and a search scans whole rows to find a node's few edges",
+			"Looking up a single edge is faster in a list than in a matrix, whatever the graph's density",
+			"A matrix has to be rebuilt from scratch whenever a node is added to the graph",
+		},
+		Answer:      1,
+		Explanation: "a matrix for a million nodes is a trillion cells, a sparse graph's lists take O(nodes + edges), a matrix only pays off when most pairs of nodes are joined",
+	},
+	{
+		Prompt: "Why does BFS find the route with the fewest hops?",
+		Choices: []string{
+			"It explores every route to the finish and keeps the one with the fewest edges",
+			"It always follows the neighbour closest to the finish, which keeps the route short",
+			"It visits the nodes in the order of their numbers, which follow the grid's rows",
+			"Its FIFO queue visits all nodes one edge away before any two away",
+		},
+		Answer:      3,
+		Explanation: "so each node is first reached by a shortest route, BFS is Dijkstra with every edge costing the same, and a plain queue in place of the heap",
+	},
+	{
+		Prompt: "Why is DFS's route through the maze often longer than BFS's?",
+		Choices: []string{
+			"DFS reaches each cell by whatever route it was following, and loops give it long ones",
+			"DFS ignores the mud's weights and so takes routes through the heaviest cells",
+			"DFS searches from the finish back to the start, and the maze isn't symmetric",
+			"DFS gives up on a branch after a fixed depth and has to take a detour instead",
+		},
+		Answer:      0,
+		Explanation: "DFS's first route to a cell isn't the shortest one, in a perfect maze, with no loops, there's only one route so all three agree, try -loops 0",
+	},
+	{
+		Prompt: "Why can't Dijkstra's algorithm handle negative weights?",
+		Choices: []string{
+			"The heap orders by distance and can't hold a negative priority",
+			"Adding a negative weight to a distance can overflow the int",
+			"It treats the closest unsettled node as final, which a later edge could undercut",
+			"It can, as long as the graph has no loops for it to follow",
+		},
+		Answer:      2,
+		Explanation: "with a negative edge, a longer route could end up cheaper after a node was settled, Bellman-Ford handles them at O(nodes x edges)",
+	},
+	{
+		Prompt: "Why does DFS here use a slice as a stack rather than recursion?",
+		Choices: []string{
+			"Recursion would visit the nodes in a different order and find a different route",
+			"The visited marks have to live in a slice, which recursion can't share between calls",
+			"Recursive calls can't be inlined, so each visit would cost a function call",
+			"A long path would recurse a million calls deep, where a slice grows on the heap",
+		},
+		Answer:      3,
+		Explanation: "Go's goroutine stacks grow, but to a limit of 1GB by default, deep recursion is a crash waiting for a big enough input",
+	},
+}
@@ -0,0 +1,243 @@
+// Package hashtables is the hash table lesson, teachgo hashtable, a chaining and an open addressing hash table built
+// from scratch and benchmarked against Go's own map
+package hashtables
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/datagen"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo hashtable -h, its first line is the summary teachgo help lists
+const Description = `Hash tables from scratch, chaining and open addressing against Go's map
+
+Builds a hash table that chains colliding keys in per-bucket lists and one that probes for the next free slot,
+both doubling as they fill, then times inserts, lookups and deletes against Go's built-in map. Reports how many
+resizes each table needed and how many entries they rehashed, showing growth is amortized constant time, and how
+far lookups probe at the load factor the tables reached.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "hashtable",
+		Description: Description,
+		Difficulty:  lesson.Beginner,
+		Topics:      []string{"hashing", "collisions", "load factor", "open addressing", "amortized growth"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Keys         int     `json:"keys"`
+	Lookups      int     `json:"lookups"`
+	MaxLoad      float64 `json:"max_load"`
+	Distribution string  `json:"distribution"`
+	Seed         int64   `json:"seed"`
+}
+
+// TableResult is what a run measured of one table, the times are per operation, the growth and probe lengths are
+// only known for the tables built here
+type TableResult struct {
+	Name         string  `json:"name"`
+	InsertNs     float64 `json:"insert_ns"`
+	LookupNs     float64 `json:"lookup_ns"`
+	DeleteNs     float64 `json:"delete_ns"`
+	Found        int     `json:"found"`
+	Distinct     int     `json:"distinct"`
+	Remaining    int     `json:"remaining"`
+	Resizes      int     `json:"resizes,omitempty"`
+	Moved        int     `json:"moved,omitempty"`
+	Load         float64 `json:"load,omitempty"`
+	MeanProbe    float64 `json:"mean_probe,omitempty"`
+	LongestProbe int     `json:"longest_probe,omitempty"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config RunConfig     `json:"config"`
+	Env    bench.Env     `json:"env"`
+	Tables []TableResult `json:"tables"`
+}
+
+// candidate is a table under test, new builds an empty one so every table starts from the same point
+type candidate struct {
+	name string
+	new  func() Table
+}
+
+func candidates(maxLoad float64, keys int) []candidate {
+	return []candidate{
+		{"Chaining", func() Table { return NewChainingTable(maxLoad) }},
+		{"Open addressing", func() Table { return NewOpenAddressingTable(maxLoad) }},
+		{"Built-in map", func() Table { return builtinMap{} }},
+		// sized up front the map never grows, the difference from the one above is the cost of growing
+		{"Built-in map, presized", func() Table { return make(builtinMap, keys) }},
+	}
+}
+
+// measure inserts every key, looks up every query and deletes the first half of the keys, timing each phase
+func measure(ctx context.Context, c candidate, keys, queries []int) TableResult {
+	result := TableResult{Name: c.name}
+	table := c.new()
+
+	insert := bench.Phase(ctx, "insert "+c.name, func() {
+		for i, key := range keys {
+			table.Put(key, i)
+		}
+	})
+	lookup := bench.Phase(ctx, "lookup "+c.name, func() {
+		for _, query := range queries {
+			if _, ok := table.Get(query); ok {
+				result.Found++
+			}
+		}
+	})
+
+	// the table's shape is measured before the deletes shrink it, at the load its inserts left it
+	result.Distinct = table.Len()
+	if grower, ok := table.(interface {
+		Growth() Growth
+		Load() float64
+		Probes() Probes
+	}); ok {
+		growth, probes := grower.Growth(), grower.Probes()
+		result.Resizes, result.Moved = growth.Resizes, growth.Moved
+		result.Load = grower.Load()
+		result.MeanProbe, result.LongestProbe = probes.Mean, probes.Longest
+	}
+
+	deletes := keys[:len(keys)/2]
+	remove := bench.Phase(ctx, "delete "+c.name, func() {
+		for _, key := range deletes {
+			table.Delete(key)
+		}
+	})
+	result.Remaining = table.Len()
+
+	result.InsertNs = perOp(insert, len(keys))
+	result.LookupNs = perOp(lookup, len(queries))
+	result.DeleteNs = perOp(remove, len(deletes))
+	return result
+}
+
+func perOp(d time.Duration, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(d.Nanoseconds()) / float64(n)
+}
+
+// printResults writes the comparison table and what the growth and probe lengths show
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Hash Table Comparison")
+	fmt.Fprintln(w, "=====================")
+	fmt.Fprintf(w, "Keys: %d\nLookups: %d\nMax load factor: %g\nDistribution: %s\n", config.Keys, config.Lookups, config.MaxLoad, config.Distribution)
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%-24s %12s %12s %12s %8s %10s\n", "Table", "Insert/op", "Lookup/op", "Delete/op", "Found", "Remaining")
+	for _, t := range result.Tables {
+		fmt.Fprintf(w, "%-24s %12v %12v %12v %8d %10d\n", t.Name,
+			time.Duration(t.InsertNs), time.Duration(t.LookupNs), time.Duration(t.DeleteNs), t.Found, t.Remaining)
+	}
+
+	fmt.Fprintln(w, "\n=====Growth=====")
+	fmt.Fprintf(w, "%-24s %8s %10s %14s %10s %12s %14s\n", "Table", "Resizes", "Rehashed", "Rehashed/key", "Load", "Mean probe", "Longest probe")
+	for _, t := range result.Tables {
+		if t.Resizes == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%-24s %8d %10d %14.2f %10.3f %12.2f %14d\n", t.Name,
+			t.Resizes, t.Moved, float64(t.Moved)/float64(t.Distinct), t.Load, t.MeanProbe, t.LongestProbe)
+	}
+	fmt.Fprintln(w, "\nEach resize doubles the table, so the entries rehashed across every resize add up to fewer than twice the")
+	fmt.Fprintln(w, "keys inserted, under two rehashes per key however many resizes there were, growth is amortized O(1)")
+	fmt.Fprintln(w, "Chaining's probe is the position in a chain, open addressing's the slots walked from a key's home slot,")
+	fmt.Fprintln(w, "linear probing's runs lengthen quickly as the load factor nears 1, try -max-load 0.95")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo hashtable
+func Main(args []string) {
+	fs := bench.NewFlagSet("hashtable", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the hash table lesson measures a single run"
+	numKeys := fs.Int("keys", 1000000, "the number of keys to insert, drawn from [0, 10 x keys)")
+	numLookups := fs.Int("lookups", 1000000, "the number of lookups, drawn from the same distribution as the keys")
+	maxLoad := fs.Float64("max-load", 0.75, "the load factor, entries per slot or bucket, past which the tables double in size")
+	distribution := fs.String("dist", "uniform", "how the keys and lookups are drawn: "+datagen.Usage)
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "hashtable measures a single run, -trials isn't supported")
+	v.AtLeast("keys", *numKeys, 1)
+	v.AtLeast("lookups", *numLookups, 0)
+	v.Check(*maxLoad > 0 && *maxLoad < 1, "-max-load must be above 0 and below 1, open addressing needs a free slot to end every probe, got %g", *maxLoad)
+	if *numKeys >= 1 {
+		_, err := datagen.Parse(*distribution, 10**numKeys)
+		v.Add(err)
+	}
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	dist := datagen.MustParse(*distribution, 10**numKeys)
+	rng := rand.New(rand.NewSource(globals.Seed))
+	slog.Info("generating keys", "keys", *numKeys, "lookups", *numLookups, "distribution", dist)
+	keys := dist.Fill(rng, *numKeys)
+	queries := dist.Fill(rng, *numLookups)
+
+	result := RunResult{
+		Config: RunConfig{Keys: *numKeys, Lookups: *numLookups, MaxLoad: *maxLoad, Distribution: dist.String(), Seed: globals.Seed},
+		Env:    bench.CaptureEnv(),
+	}
+	ctx := context.Background()
+	for _, c := range candidates(*maxLoad, *numKeys) {
+		slog.Info("measuring", "table", c.name)
+		result.Tables = append(result.Tables, measure(ctx, c, keys, queries))
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a benchmark line per operation and table, the lesson measures one run at a time so
+// benchstat needs several runs appended to one file
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/hash_tables"); err != nil {
+		return err
+	}
+	keys := fmt.Sprintf("keys=%d", result.Config.Keys)
+	benchmarks := []bench.Benchmark{}
+	for _, t := range result.Tables {
+		benchmarks = append(benchmarks,
+			bench.Benchmark{Name: "Insert/" + t.Name + "/" + keys, N: int64(result.Config.Keys), Metrics: []bench.Metric{{Value: t.InsertNs, Unit: "ns/op"}}},
+			bench.Benchmark{Name: "Lookup/" + t.Name + "/" + keys, N: int64(result.Config.Lookups), Metrics: []bench.Metric{{Value: t.LookupNs, Unit: "ns/op"}}},
+			bench.Benchmark{Name: "Delete/" + t.Name + "/" + keys, N: int64(result.Config.Keys / 2), Metrics: []bench.Metric{{Value: t.DeleteNs, Unit: "ns/op"}}},
+		)
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
@@ -0,0 +1,63 @@
+package hashtables
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz hashtable, it is about what growth costs amortized, how open addressing degrades as
+// it fills and deletes, and why presizing and Fibonacci hashing help
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why do the tables rehash fewer than two entries per key inserted, however many times they resize?",
+		Choices: []string{
+			"Only the entries whose slot changes in the bigger table are rehashed, about half of them",
+			"Resizing copies the slots with one memmove, only the collided entries are rehashed",
+			"Most keys are inserted after the last resize, so they never have to be moved at all",
+			"Each resize doubles the table, so the entries moved form a halving series under 2n",
+		},
+		Answer:      3,
+		Explanation: "a resize at n entries moves n, the one before moved n/2, then n/4 and so on, under 2n in all, so each insert pays a constant amount for growth on average, amortized O(1)",
+	},
+	{
+		Prompt: "What happens to open addressing's probe lengths as -max-load approaches 1?",
+		Choices: []string{
+			"They stay about the same, a good hash spreads the keys however full the table gets",
+			"They grow slowly and in proportion to the load, twice as full meaning twice the probes",
+			"They grow sharply, runs of taken slots merge into long clusters a lookup has to walk",
+			"They fall, since a fuller table means a lookup is more likely to hit a key early",
+		},
+		Answer:      2,
+		Explanation: "linear probing's expected probes grow with 1/(1-load)^2 for a miss, fine at 0.75 and dreadful at 0.95, which is why open addressing tables resize well before they're full",
+	},
+	{
+		Prompt: "Why can't open addressing simply empty a deleted key's slot?",
+		Choices: []string{
+			"A lookup stops at the first empty slot, so keys that probed past it would be lost",
+			"An emptied slot can't be reused until the next resize, so the table would leak slots",
+			"The load factor counts deleted slots, so emptying one would trigger a resize",
+			"The slot's key has to stay for the garbage collector until the table is rebuilt",
+		},
+		Answer:      0,
+		Explanation: "the lesson's table shifts the rest of the probe sequence back into the gap, the other common fix is a tombstone marking the slot as deleted",
+	},
+	{
+		Prompt: "Why does the presized built-in map insert faster than the one that starts empty?",
+		Choices: []string{
+			"A presized map uses a cheaper hash function chosen for its size",
+			"It never has to grow, so no entries are rehashed into a bigger table",
+			"It skips the duplicate check, since it expects exactly n distinct keys",
+			"It keeps its keys in insertion order, so each insert is an append",
+		},
+		Answer:      1,
+		Explanation: "make(map[K]V, n) allocates room for n entries up front, when the size is known in advance it saves every resize",
+	},
+	{
+		Prompt: "What does the Fibonacci hash, multiplying the key by 2^64 divided by the golden ratio and keeping the top bits, protect against?",
+		Choices: []string{
+			"Attackers choosing keys that collide, since the multiplier is secret",
+			"Negative keys, which would index before the start of the table",
+			"Keys that differ only in low bits, or are multiples of the size, crowding a few slots",
+			"Keys larger than the table, which mod would otherwise wrap around",
+		},
+		Answer:      2,
+		Explanation: "taking key mod table size would put every multiple of the size in slot 0, the multiplication mixes every bit of the key into the top bits used as the index",
+	},
+}
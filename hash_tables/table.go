@@ -0,0 +1,299 @@
+package hashtables
+
+// Table is what the benchmark needs of a hash table from int keys to int values
+type Table interface {
+	// Put sets the value of a key, adding the key if it's new
+	Put(key, value int)
+	Get(key int) (int, bool)
+	// Delete removes a key, reporting whether it was there
+	Delete(key int) bool
+	Len() int
+}
+
+// Growth is how much work a table's resizes have done, Moved counts every entry rehashed into a bigger table
+type Growth struct {
+	Resizes int
+	Moved   int
+}
+
+// Probes measures how far a successful lookup searches, Mean is the average number of entries compared to find a
+// key and Longest the most any key needs
+type Probes struct {
+	Mean    float64
+	Longest int
+}
+
+// minCapacity is the number of slots or buckets a table starts with, always a power of two
+const minCapacity = 8
+
+// fibonacci is 2^64 divided by the golden ratio, multiplying by it scatters keys that differ only slightly, like
+// 1, 2, 3, across the whole 64 bits, the top bits of the product are the index into the table
+const fibonacci = 11400714819323198485
+
+// index hashes a key to a slot of a table of 2^bits slots, Fibonacci hashing, a multiplication and a shift, cheap
+// and good enough for integer keys
+func index(key int, bits uint) int {
+	return int((uint64(key) * fibonacci) >> (64 - bits))
+}
+
+// bitsFor is the number of index bits for a power of two capacity
+func bitsFor(capacity int) uint {
+	bits := uint(0)
+	for 1<<bits < capacity {
+		bits++
+	}
+	return bits
+}
+
+type entry struct {
+	key, value int
+}
+
+// ChainingTable keeps a list of entries in each bucket, every key hashing to a bucket joins its chain, so a lookup
+// scans one chain and the table never fills, it just slows as the chains grow
+type ChainingTable struct {
+	buckets [][]entry
+	bits    uint
+	size    int
+	maxLoad float64
+	growth  Growth
+}
+
+// NewChainingTable creates a table that doubles its buckets whenever the entries per bucket would pass maxLoad
+func NewChainingTable(maxLoad float64) *ChainingTable {
+	return &ChainingTable{buckets: make([][]entry, minCapacity), bits: bitsFor(minCapacity), maxLoad: maxLoad}
+}
+
+func (t *ChainingTable) Put(key, value int) {
+	bucket := index(key, t.bits)
+	for i := range t.buckets[bucket] {
+		if t.buckets[bucket][i].key == key {
+			t.buckets[bucket][i].value = value
+			return
+		}
+	}
+	if float64(t.size+1) > t.maxLoad*float64(len(t.buckets)) {
+		t.resize(2 * len(t.buckets))
+		bucket = index(key, t.bits)
+	}
+	t.buckets[bucket] = append(t.buckets[bucket], entry{key, value})
+	t.size++
+}
+
+func (t *ChainingTable) Get(key int) (int, bool) {
+	for _, e := range t.buckets[index(key, t.bits)] {
+		if e.key == key {
+			return e.value, true
+		}
+	}
+	return 0, false
+}
+
+func (t *ChainingTable) Delete(key int) bool {
+	bucket := index(key, t.bits)
+	chain := t.buckets[bucket]
+	for i, e := range chain {
+		if e.key == key {
+			// order within a chain doesn't matter, the last entry fills the gap
+			chain[i] = chain[len(chain)-1]
+			t.buckets[bucket] = chain[:len(chain)-1]
+			t.size--
+			return true
+		}
+	}
+	return false
+}
+
+func (t *ChainingTable) Len() int {
+	return t.size
+}
+
+// resize rehashes every entry into a table of capacity buckets, a key's bucket depends on the number of buckets so
+// nothing can simply be copied across
+func (t *ChainingTable) resize(capacity int) {
+	old := t.buckets
+	t.buckets = make([][]entry, capacity)
+	t.bits = bitsFor(capacity)
+	for _, chain := range old {
+		for _, e := range chain {
+			bucket := index(e.key, t.bits)
+			t.buckets[bucket] = append(t.buckets[bucket], e)
+		}
+	}
+	t.growth.Resizes++
+	t.growth.Moved += t.size
+}
+
+// Growth reports the work the table's resizes have done
+func (t *ChainingTable) Growth() Growth {
+	return t.growth
+}
+
+// Load is the number of entries per bucket
+func (t *ChainingTable) Load() float64 {
+	return float64(t.size) / float64(len(t.buckets))
+}
+
+// Probes measures the chains, a key's probe length is its position in its chain
+func (t *ChainingTable) Probes() Probes {
+	var p Probes
+	total := 0
+	for _, chain := range t.buckets {
+		for i := range chain {
+			total += i + 1
+		}
+		p.Longest = max(p.Longest, len(chain))
+	}
+	if t.size > 0 {
+		p.Mean = float64(total) / float64(t.size)
+	}
+	return p
+}
+
+type slot struct {
+	key, value int
+	used       bool
+}
+
+// OpenAddressingTable keeps every entry in one array, a key whose slot is taken goes in the next free slot along,
+// linear probing, so lookups walk neighbouring slots that share cache lines, but the runs of taken slots lengthen
+// quickly as the table fills
+type OpenAddressingTable struct {
+	slots   []slot
+	bits    uint
+	size    int
+	maxLoad float64
+	growth  Growth
+}
+
+// NewOpenAddressingTable creates a table that doubles its slots whenever the fraction taken would pass maxLoad,
+// which has to be below 1 as a full table has nowhere left to probe
+func NewOpenAddressingTable(maxLoad float64) *OpenAddressingTable {
+	return &OpenAddressingTable{slots: make([]slot, minCapacity), bits: bitsFor(minCapacity), maxLoad: maxLoad}
+}
+
+// find returns the slot holding key, or the free slot ending its probe sequence when it isn't there
+func (t *OpenAddressingTable) find(key int) (int, bool) {
+	mask := len(t.slots) - 1
+	for i := index(key, t.bits); ; i = (i + 1) & mask {
+		if !t.slots[i].used {
+			return i, false
+		}
+		if t.slots[i].key == key {
+			return i, true
+		}
+	}
+}
+
+func (t *OpenAddressingTable) Put(key, value int) {
+	i, found := t.find(key)
+	if found {
+		t.slots[i].value = value
+		return
+	}
+	if float64(t.size+1) > t.maxLoad*float64(len(t.slots)) {
+		t.resize(2 * len(t.slots))
+		i, _ = t.find(key)
+	}
+	t.slots[i] = slot{key: key, value: value, used: true}
+	t.size++
+}
+
+func (t *OpenAddressingTable) Get(key int) (int, bool) {
+	i, found := t.find(key)
+	if !found {
+		return 0, false
+	}
+	return t.slots[i].value, true
+}
+
+// Delete empties the key's slot and shifts later entries of the probe sequence back into the gap, without that a
+// lookup would stop at the empty slot and miss keys placed beyond it
+// Shifting back avoids tombstones, markers for deleted slots that lookups skip over but that pile up and slow them
+func (t *OpenAddressingTable) Delete(key int) bool {
+	i, found := t.find(key)
+	if !found {
+		return false
+	}
+	mask := len(t.slots) - 1
+	for j := (i + 1) & mask; t.slots[j].used; j = (j + 1) & mask {
+		// the entry at j can move back to i unless its home slot lies after i, cyclically, up to j
+		home := index(t.slots[j].key, t.bits)
+		if (j-home)&mask >= (j-i)&mask {
+			t.slots[i] = t.slots[j]
+			i = j
+		}
+	}
+	t.slots[i] = slot{}
+	t.size--
+	return true
+}
+
+func (t *OpenAddressingTable) Len() int {
+	return t.size
+}
+
+func (t *OpenAddressingTable) resize(capacity int) {
+	old := t.slots
+	t.slots = make([]slot, capacity)
+	t.bits = bitsFor(capacity)
+	for _, s := range old {
+		if s.used {
+			i, _ := t.find(s.key)
+			t.slots[i] = s
+		}
+	}
+	t.growth.Resizes++
+	t.growth.Moved += t.size
+}
+
+// Growth reports the work the table's resizes have done
+func (t *OpenAddressingTable) Growth() Growth {
+	return t.growth
+}
+
+// Load is the fraction of slots taken
+func (t *OpenAddressingTable) Load() float64 {
+	return float64(t.size) / float64(len(t.slots))
+}
+
+// Probes measures the probe sequences, a key's probe length is the number of slots from its home slot to where it
+// sits, counting both
+func (t *OpenAddressingTable) Probes() Probes {
+	var p Probes
+	mask := len(t.slots) - 1
+	total := 0
+	for i, s := range t.slots {
+		if s.used {
+			length := (i-index(s.key, t.bits))&mask + 1
+			total += length
+			p.Longest = max(p.Longest, length)
+		}
+	}
+	if t.size > 0 {
+		p.Mean = float64(total) / float64(t.size)
+	}
+	return p
+}
+
+// builtinMap wraps Go's map so it can be benchmarked alongside the tables built here
+type builtinMap map[int]int
+
+func (m builtinMap) Put(key, value int) {
+	m[key] = value
+}
+
+func (m builtinMap) Get(key int) (int, bool) {
+	value, ok := m[key]
+	return value, ok
+}
+
+func (m builtinMap) Delete(key int) bool {
+	_, ok := m[key]
+	delete(m, key)
+	return ok
+}
+
+func (m builtinMap) Len() int {
+	return len(m)
+}
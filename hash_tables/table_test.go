@@ -0,0 +1,84 @@
+package hashtables
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func newTables() map[string]Table {
+	return map[string]Table{
+		"chaining":                     NewChainingTable(0.75),
+		"open addressing":              NewOpenAddressingTable(0.75),
+		"open addressing, nearly full": NewOpenAddressingTable(0.95),
+	}
+}
+
+// every table has to agree with Go's map over a long run of random puts, gets and deletes, a small key range makes
+// collisions, overwrites and deletes of long probe sequences common
+func TestTablesMatchMap(t *testing.T) {
+	for name, table := range newTables() {
+		t.Run(name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			want := map[int]int{}
+			for i := range 100000 {
+				key := rng.Intn(2000)
+				switch rng.Intn(3) {
+				case 0:
+					table.Put(key, i)
+					want[key] = i
+				case 1:
+					_, inWant := want[key]
+					if deleted := table.Delete(key); deleted != inWant {
+						t.Fatalf("op %d: Delete(%d) = %v, want %v", i, key, deleted, inWant)
+					}
+					delete(want, key)
+				default:
+					got, ok := table.Get(key)
+					if wantValue, inWant := want[key]; ok != inWant || got != wantValue {
+						t.Fatalf("op %d: Get(%d) = %d, %v, want %d, %v", i, key, got, ok, wantValue, inWant)
+					}
+				}
+				if table.Len() != len(want) {
+					t.Fatalf("op %d: Len() = %d, want %d", i, table.Len(), len(want))
+				}
+			}
+		})
+	}
+}
+
+func TestGrowthIsAmortized(t *testing.T) {
+	for _, table := range []interface {
+		Table
+		Growth() Growth
+		Load() float64
+	}{NewChainingTable(0.75), NewOpenAddressingTable(0.75)} {
+		const n = 100000
+		for i := range n {
+			table.Put(i, i)
+		}
+		growth := table.Growth()
+		if growth.Resizes < 10 {
+			t.Errorf("%T resized %d times for %d keys, want it to have grown from 8 slots", table, growth.Resizes, n)
+		}
+		if growth.Moved >= 2*n {
+			t.Errorf("%T rehashed %d entries for %d keys, doubling should move fewer than twice as many as were inserted", table, growth.Moved, n)
+		}
+		if load := table.Load(); load > 0.75 || load < 0.75/2 {
+			t.Errorf("%T has load %.3f, want it between half the max load and the max load", table, load)
+		}
+	}
+}
+
+func TestProbes(t *testing.T) {
+	table := NewOpenAddressingTable(0.75)
+	if p := table.Probes(); p.Mean != 0 || p.Longest != 0 {
+		t.Errorf("empty table Probes() = %+v, want zero", p)
+	}
+	for i := range 1000 {
+		table.Put(i*7919, i)
+	}
+	p := table.Probes()
+	if p.Mean < 1 || float64(p.Longest) < p.Mean {
+		t.Errorf("Probes() = %+v, want a mean of at least 1 and a longest at least the mean", p)
+	}
+}
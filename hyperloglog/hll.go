@@ -0,0 +1,103 @@
+package hyperloglog
+
+import (
+	"math"
+	"math/bits"
+)
+
+const (
+	// MinPrecision and MaxPrecision bound the precision, below 16 registers the estimate is too coarse to be of use
+	// and above 2^18 the registers outgrow the exact counts of all but the biggest streams
+	MinPrecision = 4
+	MaxPrecision = 18
+)
+
+// Sketch is a HyperLogLog sketch, an estimate of how many distinct items a stream holds from 2^precision small
+// registers, however long the stream and however many distinct items are in it
+// Each item is hashed, the first precision bits of the hash pick a register and the register keeps the longest run
+// of leading zeros it has seen in the rest, plus one, a run of r zeros turns up about once in 2^r distinct hashes, so
+// the longest run says roughly how many there have been, and averaging over the registers smooths out the luck
+// Seeing an item again hashes it to the same register and the same run, so repeats change nothing
+type Sketch struct {
+	registers []uint8
+	precision uint
+}
+
+// NewSketch creates a sketch of 2^precision registers, clamped to MinPrecision and MaxPrecision
+func NewSketch(precision int) *Sketch {
+	precision = min(max(precision, MinPrecision), MaxPrecision)
+	return &Sketch{registers: make([]uint8, 1<<precision), precision: uint(precision)}
+}
+
+// mix is splitmix64's finalizer, it scatters items that differ in a bit or two, like 1, 2, 3, across all 64 bits,
+// the sketch needs every bit of the hash to look random
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Add counts the item
+func (s *Sketch) Add(item uint64) {
+	h := mix(item)
+	register := h >> (64 - s.precision)
+	// the bit set below the remaining 64 - precision bits caps the run, a hash of all zeros can't run off the end
+	rest := h<<s.precision | 1<<(s.precision-1)
+	rank := uint8(bits.LeadingZeros64(rest) + 1)
+	if rank > s.registers[register] {
+		s.registers[register] = rank
+	}
+}
+
+// Estimate is the number of distinct items added, the harmonic mean of 2^register over the registers, scaled by the
+// number of registers and a correction for the bias of that mean
+// While many registers are still empty the estimate is poor, so small counts are estimated from how many are empty
+// instead, linear counting, the way the number of empty buckets says how many balls were thrown
+func (s *Sketch) Estimate() float64 {
+	m := float64(len(s.registers))
+	sum, empty := 0.0, 0
+	for _, r := range s.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			empty++
+		}
+	}
+	estimate := alpha(len(s.registers)) * m * m / sum
+	if estimate <= 2.5*m && empty > 0 {
+		return m * math.Log(m/float64(empty))
+	}
+	return estimate
+}
+
+// alpha corrects the bias of the raw estimate, the constants are from Flajolet et al.'s paper
+func alpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	default:
+		return 0.7213 / (1 + 1.079/float64(m))
+	}
+}
+
+// Precision is the number of hash bits that pick a register
+func (s *Sketch) Precision() int {
+	return int(s.precision)
+}
+
+// Bytes is the memory the registers take, a byte each, though six bits would hold any run a 64 bit hash can have
+func (s *Sketch) Bytes() int {
+	return len(s.registers)
+}
+
+// StdError is the typical relative error of a sketch of 2^precision registers, 1.04 / sqrt(registers), four times
+// the memory halves it
+func StdError(precision int) float64 {
+	return 1.04 / math.Sqrt(float64(int(1)<<precision))
+}
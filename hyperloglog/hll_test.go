@@ -0,0 +1,59 @@
+package hyperloglog
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSketchIgnoresRepeats(t *testing.T) {
+	s := NewSketch(10)
+	for range 100 {
+		for item := range uint64(500) {
+			s.Add(item)
+		}
+	}
+	if got := s.Estimate(); math.Abs(got-500) > 25 {
+		t.Errorf("Estimate() = %.0f after 500 items added 100 times each, want about 500", got)
+	}
+}
+
+func TestSketchEstimatesWithinAFewStandardErrors(t *testing.T) {
+	for _, precision := range []int{8, 12, 14} {
+		for _, n := range []int{1000, 100000} {
+			s := NewSketch(precision)
+			for i := range n {
+				s.Add(uint64(i))
+			}
+			err := math.Abs(relativeError(s.Estimate(), n))
+			if err > 4*StdError(precision) {
+				t.Errorf("precision %d, %d items: error %.2f%%, more than four standard errors of %.2f%%", precision, n, 100*err, 100*StdError(precision))
+			}
+		}
+	}
+}
+
+func TestEmptySketchEstimatesZero(t *testing.T) {
+	if got := NewSketch(12).Estimate(); got != 0 {
+		t.Errorf("Estimate() of an empty sketch = %g, want 0", got)
+	}
+}
+
+func TestNewSketchClampsPrecision(t *testing.T) {
+	if got := NewSketch(1).Precision(); got != MinPrecision {
+		t.Errorf("NewSketch(1).Precision() = %d, want %d", got, MinPrecision)
+	}
+	if got := NewSketch(30).Bytes(); got != 1<<MaxPrecision {
+		t.Errorf("NewSketch(30).Bytes() = %d, want %d", got, 1<<MaxPrecision)
+	}
+}
+
+func TestStreamKnowsItsTrueCount(t *testing.T) {
+	s := stream{length: 5000, distinct: 1200, salt: 7}
+	seen := map[uint64]bool{}
+	for i := range s.length {
+		seen[s.item(i)] = true
+	}
+	if len(seen) != s.trueCount(s.length) {
+		t.Errorf("the stream has %d distinct items, trueCount says %d", len(seen), s.trueCount(s.length))
+	}
+}
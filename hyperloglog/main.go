@@ -0,0 +1,314 @@
+// Package hyperloglog is the HyperLogLog lesson, teachgo hll, estimating how many distinct items a stream holds from
+// a few kilobytes of registers, against counting them exactly in a map
+package hyperloglog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo hll -h, its first line is the summary teachgo help lists
+const Description = `HyperLogLog, counting distinct items in a few kilobytes
+
+Streams items through a HyperLogLog sketch, registers that each remember the longest run of leading zeros among the
+hashes they're given, and through a map holding every distinct item, and compares the count each gives, the time
+they take per item and the memory they need. Then repeats the stream at a range of precisions, showing the error
+halving for every fourfold increase in memory, and follows the estimate as the stream goes by. The stream can run to
+hundreds of millions of items, with -exact=false to leave out the map that would need gigabytes to keep up.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "hll",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"probabilistic data structures", "streaming algorithms", "cardinality estimation", "hashing"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// precisionSweep is the precisions the stream is repeated at, along with -precision
+var precisionSweep = []int{4, 6, 8, 10, 12, 14, 16}
+
+// mapBytesPerItem is the least a distinct item costs in a map[uint64]struct{}, the item itself, the map's own control
+// bytes and spare slots come on top
+const mapBytesPerItem = 8
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Stream    int   `json:"stream"`
+	Distinct  int   `json:"distinct"`
+	Precision int   `json:"precision"`
+	Exact     bool  `json:"exact"`
+	Seed      int64 `json:"seed"`
+}
+
+// CounterResult is one way of counting the whole stream, Error is the estimate's error relative to the true count
+type CounterResult struct {
+	Name     string  `json:"name"`
+	Exact    bool    `json:"exact"`
+	Ns       float64 `json:"ns"`
+	Bytes    int     `json:"bytes"`
+	Estimate float64 `json:"estimate"`
+	Error    float64 `json:"error"`
+}
+
+// PrecisionResult is a sketch of one precision's estimate of the whole stream, StdError the error theory expects
+type PrecisionResult struct {
+	Precision int     `json:"precision"`
+	Bytes     int     `json:"bytes"`
+	Estimate  float64 `json:"estimate"`
+	Error     float64 `json:"error"`
+	StdError  float64 `json:"std_error"`
+}
+
+// Checkpoint is the -precision sketch's estimate partway through the stream
+type Checkpoint struct {
+	Items    int     `json:"items"`
+	True     int     `json:"true"`
+	Estimate float64 `json:"estimate"`
+	Error    float64 `json:"error"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config      RunConfig         `json:"config"`
+	Env         bench.Env         `json:"env"`
+	True        int               `json:"true"`
+	Counters    []CounterResult   `json:"counters"`
+	Precisions  []PrecisionResult `json:"precisions"`
+	Checkpoints []Checkpoint      `json:"checkpoints"`
+}
+
+// stream is the items, distinct of them cycled over and over, each scattered by a hash from a seeded salt, as mix is
+// a bijection the first distinct items are all different and the true count is known without counting
+// The items are made on the fly, a stream of hundreds of millions would take gigabytes held in a slice
+type stream struct {
+	length, distinct int
+	salt             uint64
+}
+
+func (s stream) item(i int) uint64 {
+	return mix(s.salt + uint64(i%s.distinct))
+}
+
+// trueCount is the number of distinct items among the first n
+func (s stream) trueCount(n int) int {
+	return min(n, s.distinct)
+}
+
+func relativeError(estimate float64, truth int) float64 {
+	return (estimate - float64(truth)) / float64(truth)
+}
+
+// countExactly puts every item in a map
+func countExactly(ctx context.Context, s stream) CounterResult {
+	seen := map[uint64]struct{}{}
+	elapsed := bench.Phase(ctx, "exact", func() {
+		for i := range s.length {
+			seen[s.item(i)] = struct{}{}
+		}
+	})
+	return CounterResult{
+		Name:     "Exact map",
+		Exact:    true,
+		Ns:       perOp(elapsed, s.length),
+		Bytes:    mapBytesPerItem * len(seen),
+		Estimate: float64(len(seen)),
+		Error:    relativeError(float64(len(seen)), s.trueCount(s.length)),
+	}
+}
+
+// countWithSketch adds every item to a sketch of the given precision, taking its estimate at each checkpoint
+func countWithSketch(ctx context.Context, s stream, precision int, checkpoints []int) (CounterResult, []Checkpoint) {
+	sketch := NewSketch(precision)
+	taken := []Checkpoint{}
+	next := 0
+	elapsed := bench.Phase(ctx, fmt.Sprintf("hll p=%d", precision), func() {
+		for i := range s.length {
+			sketch.Add(s.item(i))
+			if next < len(checkpoints) && i+1 == checkpoints[next] {
+				estimate, truth := sketch.Estimate(), s.trueCount(i+1)
+				taken = append(taken, Checkpoint{Items: i + 1, True: truth, Estimate: estimate, Error: relativeError(estimate, truth)})
+				next++
+			}
+		}
+	})
+	estimate := sketch.Estimate()
+	return CounterResult{
+		Name:     fmt.Sprintf("HyperLogLog p=%d", precision),
+		Ns:       perOp(elapsed, s.length),
+		Bytes:    sketch.Bytes(),
+		Estimate: estimate,
+		Error:    relativeError(estimate, s.trueCount(s.length)),
+	}, taken
+}
+
+// checkpointsFor is the powers of ten below the stream's length, and the distinct count where every item has been
+// seen once
+func checkpointsFor(s stream) []int {
+	points := []int{}
+	for n := 10; n < s.length; n *= 10 {
+		points = append(points, n)
+	}
+	if s.distinct < s.length && !slices.Contains(points, s.distinct) {
+		points = append(points, s.distinct)
+		slices.Sort(points)
+	}
+	return points
+}
+
+func perOp(d time.Duration, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(d.Nanoseconds()) / float64(n)
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "HyperLogLog")
+	fmt.Fprintln(w, "===========")
+	fmt.Fprintf(w, "Stream: %d items, %d distinct\nPrecision: %d, %d registers\n", config.Stream, result.True, config.Precision, 1<<config.Precision)
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%-18s %12s %12s %14s %10s\n", "Counter", "Add/op", "Memory", "Distinct", "Error")
+	for _, c := range result.Counters {
+		memory := fmt.Sprintf("%d B", c.Bytes)
+		if c.Exact {
+			memory = fmt.Sprintf(">%d KiB", c.Bytes/1024)
+		}
+		fmt.Fprintf(w, "%-18s %12v %12s %14.0f %+9.2f%%\n", c.Name, time.Duration(c.Ns), memory, c.Estimate, 100*c.Error)
+	}
+	if !config.Exact {
+		fmt.Fprintln(w, "The exact map was left out, -exact=false")
+	}
+
+	fmt.Fprintln(w, "\n=====Error against memory=====")
+	fmt.Fprintf(w, "%10s %12s %14s %10s %12s\n", "Precision", "Memory", "Estimate", "Error", "Std error")
+	for _, p := range result.Precisions {
+		fmt.Fprintf(w, "%10d %10d B %14.0f %+9.2f%% %11.2f%%\n", p.Precision, p.Bytes, p.Estimate, 100*p.Error, 100*p.StdError)
+	}
+	fmt.Fprintln(w, "\nEach added bit of precision doubles the registers, and two of them halve the expected error, a single")
+	fmt.Fprintln(w, "estimate can land a few standard errors either side")
+
+	fmt.Fprintf(w, "\n=====Estimate as the stream goes by, precision %d=====\n", config.Precision)
+	fmt.Fprintf(w, "%14s %14s %14s %10s\n", "Items", "Distinct", "Estimate", "Error")
+	for _, c := range result.Checkpoints {
+		fmt.Fprintf(w, "%14d %14d %14.0f %+9.2f%%\n", c.Items, c.True, c.Estimate, 100*c.Error)
+	}
+	fmt.Fprintln(w, "\nWhile most registers are empty the count comes from how many are, linear counting, close to exact, once")
+	fmt.Fprintln(w, "every distinct item has been seen the repeats change no register and the estimate stops moving")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo hll
+func Main(args []string) {
+	fs := bench.NewFlagSet("hll", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the HyperLogLog lesson measures a single run"
+	streamLength := fs.Int("stream", 10_000_000, "the number of items in the stream")
+	distinct := fs.Int("distinct", 1_000_000, "the number of distinct items, cycled through until the stream ends")
+	precision := fs.Int("precision", 14, fmt.Sprintf("the bits of hash that pick a register, 2^precision registers, from %d to %d", MinPrecision, MaxPrecision))
+	exact := fs.Bool("exact", true, "count exactly with a map as well, leave it out for streams with more distinct items than memory holds")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "hll measures a single run, -trials isn't supported")
+	v.AtLeast("stream", *streamLength, 1)
+	v.AtLeast("distinct", *distinct, 1)
+	v.Check(*precision >= MinPrecision && *precision <= MaxPrecision, "-precision must be from %d to %d, got %d", MinPrecision, MaxPrecision, *precision)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	rng := rand.New(rand.NewSource(globals.Seed))
+	s := stream{length: *streamLength, distinct: *distinct, salt: rng.Uint64()}
+	result := RunResult{
+		Config: RunConfig{Stream: *streamLength, Distinct: *distinct, Precision: *precision, Exact: *exact, Seed: globals.Seed},
+		Env:    bench.CaptureEnv(),
+		True:   s.trueCount(s.length),
+	}
+	ctx := context.Background()
+	if *exact {
+		slog.Info("counting exactly", "stream", *streamLength)
+		result.Counters = append(result.Counters, countExactly(ctx, s))
+	}
+	slog.Info("counting with a sketch", "precision", *precision)
+	counter, checkpoints := countWithSketch(ctx, s, *precision, checkpointsFor(s))
+	result.Counters = append(result.Counters, counter)
+	result.Checkpoints = checkpoints
+
+	sweep := precisionSweep
+	if !slices.Contains(sweep, *precision) {
+		sweep = append(slices.Clone(sweep), *precision)
+		slices.Sort(sweep)
+	}
+	for _, p := range sweep {
+		c := counter
+		if p != *precision {
+			slog.Info("counting with a sketch", "precision", p)
+			c, _ = countWithSketch(ctx, s, p, nil)
+		}
+		result.Precisions = append(result.Precisions, PrecisionResult{
+			Precision: p, Bytes: c.Bytes, Estimate: c.Estimate, Error: c.Error, StdError: StdError(p),
+		})
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per counter timing an add, with its memory and the size of its error, and a line per
+// precision with its memory and error, |error| as a fraction of the true count, smaller is better
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/hyperloglog"); err != nil {
+		return err
+	}
+	config := result.Config
+	stream := fmt.Sprintf("stream=%d/distinct=%d", config.Stream, result.True)
+	benchmarks := []bench.Benchmark{}
+	for _, c := range result.Counters {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: "Count/" + c.Name + "/" + stream,
+			N:    int64(config.Stream),
+			Metrics: []bench.Metric{
+				{Value: c.Ns, Unit: "ns/op"},
+				{Value: float64(c.Bytes), Unit: "B"},
+				{Value: math.Abs(c.Error), Unit: "|error|"},
+			},
+		})
+	}
+	for _, p := range result.Precisions {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name:    fmt.Sprintf("Precision/p=%d/%s", p.Precision, stream),
+			N:       1,
+			Metrics: []bench.Metric{{Value: float64(p.Bytes), Unit: "B"}, {Value: math.Abs(p.Error), Unit: "|error|"}},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
@@ -0,0 +1,63 @@
+package hyperloglog
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz hll, it asks how runs of leading zeros count distinct items, why repeats and the
+// stream's length don't matter, and how registers trade memory for error
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does the longest run of leading zeros in the hashes say how many distinct items there were?",
+		Choices: []string{
+			"Each distinct item shifts another zero into the register, so the run is a count of them",
+			"Items seen more often hash to fewer leading zeros, so a long run means many rare items",
+			"A run of r zeros has probability 2^-r, so it's only likely after about 2^r distinct hashes",
+			"Hashes of longer streams are computed with more bits, so their runs are longer",
+		},
+		Answer:      2,
+		Explanation: "one register's longest run is a very rough guess, off by a factor of two on a lucky hash, thousands of registers averaged make it close",
+	},
+	{
+		Prompt: "Why doesn't the estimate change once every distinct item has been seen, however long the stream runs on?",
+		Choices: []string{
+			"A repeat hashes to the same register and run of zeros, which it already holds",
+			"The sketch keeps a Bloom filter of the items it has seen and skips the repeats",
+			"The registers saturate at their maximum once every item has been seen once",
+			"It does change, slowly, as repeats add small amounts to the harmonic mean",
+		},
+		Answer:      0,
+		Explanation: "the sketch is a function of the set of items, not of how many times each appears, which is what makes it count distinct items",
+	},
+	{
+		Prompt: "How much more memory does halving the sketch's typical error take?",
+		Choices: []string{
+			"Twice as much",
+			"Eight times as much",
+			"Four times as much",
+			"None, the error depends only on the stream",
+		},
+		Answer:      2,
+		Explanation: "the standard error is 1.04 over the square root of the registers, each extra bit of precision doubles the registers and cuts the error by a factor of the square root of two",
+	},
+	{
+		Prompt: "Why is the sketch's memory the same for a thousand distinct items and for a billion?",
+		Choices: []string{
+			"It samples a fixed number of items from the stream and scales its estimate up from them",
+			"It keeps a fixed number of small registers, each the length of a run, never the items",
+			"It stores the items compressed, and a billion similar items compress as well as a thousand",
+			"It isn't, each register widens by a bit every time the count of items doubles",
+		},
+		Answer:      1,
+		Explanation: "the map has to hold every distinct item to know whether the next is new, the sketch only the longest run per register",
+	},
+	{
+		Prompt: "Why is the estimate so accurate early in the stream, while most registers are still empty?",
+		Choices: []string{
+			"The sketch keeps the first few thousand items exactly before it starts estimating",
+			"The harmonic mean is exact whenever fewer than half of the registers are set",
+			"The early items are each given a register of their own until the registers run out",
+			"It switches to linear counting, estimating from how many registers are still empty",
+		},
+		Answer:      3,
+		Explanation: "linear counting is precise while few registers have been hit, the raw HyperLogLog estimate is biased high for small counts, the switch at 2.5 times the registers avoids it",
+	},
+}
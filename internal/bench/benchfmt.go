@@ -0,0 +1,75 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Benchmark is one result line in the format go test -bench prints, which benchstat reads, so results from many runs
+// of a lesson can be summarised and compared just like a package's benchmarks
+type Benchmark struct {
+	// Name follows Benchmark in the line, sub-benchmarks separated by slashes, e.g. Counter/Mutex or
+	// SkipListSearch/elements=1000, benchstat groups and compares lines by it
+	Name string
+	// N is the number of operations measured
+	N       int64
+	Metrics []Metric
+}
+
+// Metric is one value of a benchmark and its unit, ns/op and the like
+type Metric struct {
+	Value float64
+	Unit  string
+}
+
+// NsPerOp is the ns/op metric every benchmark line starts with, total spread over n operations
+func NsPerOp(totalNs float64, n int64) Metric {
+	if n == 0 {
+		return Metric{Unit: "ns/op"}
+	}
+	return Metric{Value: totalNs / float64(n), Unit: "ns/op"}
+}
+
+// WriteBenchHeader writes the configuration lines go test prints ahead of its benchmarks, benchstat shows them above
+// its tables and warns when two files disagree
+func WriteBenchHeader(w io.Writer, env Env, pkg string) error {
+	_, err := fmt.Fprintf(w, "goos: %s\ngoarch: %s\npkg: %s\ncpu: %s\n", env.GOOS, env.GOARCH, pkg, env.CPU)
+	return err
+}
+
+// WriteBenchmarks writes each benchmark as a line, with go test's -N suffix for GOMAXPROCS when it isn't one
+// Pipe several runs into a file and run benchstat on it, or on a file from before a change and one from after
+func WriteBenchmarks(w io.Writer, env Env, benchmarks []Benchmark) error {
+	suffix := ""
+	if env.GOMAXPROCS > 1 {
+		suffix = "-" + strconv.Itoa(env.GOMAXPROCS)
+	}
+	for _, b := range benchmarks {
+		line := fmt.Sprintf("Benchmark%s%s\t%d", benchName(b.Name), suffix, b.N)
+		for _, m := range b.Metrics {
+			line += "\t" + benchValue(m.Value) + " " + m.Unit
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// benchValue rounds a value to about four significant figures, as go test does, 1234567 ns/op but 12.35 ns/op
+func benchValue(v float64) string {
+	decimals := 0
+	for limit := 999.95; decimals < 4 && v != 0 && math.Abs(v) < limit; limit /= 10 {
+		decimals++
+	}
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+// benchName makes a name safe for the benchmark format, which ends a name at whitespace, the way go test replaces
+// spaces in sub-test names with underscores
+func benchName(name string) string {
+	return strings.Join(strings.Fields(name), "_")
+}
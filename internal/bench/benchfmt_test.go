@@ -0,0 +1,42 @@
+package bench
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteBenchmarks(t *testing.T) {
+	var b strings.Builder
+	env := Env{GOOS: "linux", GOARCH: "amd64", CPU: "Example CPU", GOMAXPROCS: 8}
+	if err := WriteBenchHeader(&b, env, "example.com/lesson"); err != nil {
+		t.Fatal(err)
+	}
+	err := WriteBenchmarks(&b, env, []Benchmark{
+		{Name: "Counter/Sharded map/routines=4", N: 4000, Metrics: []Metric{NsPerOp(246913, 4000), {Value: 16200000, Unit: "ops/s"}}},
+		{Name: "Clone", N: 1, Metrics: []Metric{NsPerOp(1234567, 1)}},
+		{Name: "Nothing", N: 0, Metrics: []Metric{NsPerOp(0, 0)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `goos: linux
+goarch: amd64
+pkg: example.com/lesson
+cpu: Example CPU
+BenchmarkCounter/Sharded_map/routines=4-8	4000	61.73 ns/op	16200000 ops/s
+BenchmarkClone-8	1	1234567 ns/op
+BenchmarkNothing-8	0	0 ns/op
+`
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestBenchValue(t *testing.T) {
+	for v, want := range map[float64]string{0: "0", 0.123456: "0.1235", 3.14159: "3.142", 12.345: "12.35", 602.14: "602.1", 20068.4: "20068"} {
+		if got := benchValue(v); got != want {
+			t.Errorf("benchValue(%v) = %s, want %s", v, got, want)
+		}
+	}
+}
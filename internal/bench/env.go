@@ -0,0 +1,75 @@
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Env is the machine a result was measured on, recorded alongside every result so numbers shared between students
+// can be told apart, four cores against sixteen explains more than any change to the code
+type Env struct {
+	GOOS       string `json:"goos"`
+	GOARCH     string `json:"goarch"`
+	CPU        string `json:"cpu"`
+	NumCPU     int    `json:"num_cpu"`
+	GoVersion  string `json:"go_version"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+}
+
+// CaptureEnv describes the machine the program is running on, call it after any change to GOMAXPROCS
+func CaptureEnv() Env {
+	return Env{
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+		CPU:        cpuModel(),
+		NumCPU:     runtime.NumCPU(),
+		GoVersion:  runtime.Version(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+	}
+}
+
+// String is the one line summary printed above a lesson's results
+func (e Env) String() string {
+	return fmt.Sprintf("%s/%s, %s, %d CPUs, GOMAXPROCS=%d, %s", e.GOOS, e.GOARCH, e.CPU, e.NumCPU, e.GOMAXPROCS, e.GoVersion)
+}
+
+// cpuModel names the processor, from /proc/cpuinfo on Linux and sysctl on macOS, or "unknown" anywhere else
+func cpuModel() string {
+	switch runtime.GOOS {
+	case "linux":
+		f, err := os.Open("/proc/cpuinfo")
+		if err != nil {
+			return "unknown"
+		}
+		defer f.Close()
+		return parseCPUInfo(bufio.NewScanner(f))
+	case "darwin":
+		out, err := exec.Command("sysctl", "-n", "machdep.cpu.brand_string").Output()
+		if model := strings.TrimSpace(string(out)); err == nil && model != "" {
+			return model
+		}
+	}
+	return "unknown"
+}
+
+// parseCPUInfo finds the processor's model in /proc/cpuinfo, ARM machines name it on a Model or Hardware line
+// rather than model name
+func parseCPUInfo(scanner *bufio.Scanner) string {
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "model name", "Model", "Hardware":
+			if value = strings.TrimSpace(value); value != "" {
+				return value
+			}
+		}
+	}
+	return "unknown"
+}
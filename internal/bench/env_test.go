@@ -0,0 +1,34 @@
+package bench
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestParseCPUInfo(t *testing.T) {
+	tests := []struct {
+		name, cpuinfo, want string
+	}{
+		{"x86", "processor\t: 0\nvendor_id\t: GenuineIntel\nmodel name\t: Intel(R) Xeon(R) CPU @ 2.20GHz\n", "Intel(R) Xeon(R) CPU @ 2.20GHz"},
+		{"raspberry pi", "processor\t: 0\nBogoMIPS\t: 108.00\nCPU part\t: 0xd08\n\nModel\t\t: Raspberry Pi 4 Model B Rev 1.4\n", "Raspberry Pi 4 Model B Rev 1.4"},
+		{"nothing", "processor\t: 0\n", "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCPUInfo(bufio.NewScanner(strings.NewReader(tt.cpuinfo))); got != tt.want {
+				t.Errorf("parseCPUInfo() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaptureEnv(t *testing.T) {
+	env := CaptureEnv()
+	if env.GOOS == "" || env.GOARCH == "" || env.GoVersion == "" || env.NumCPU < 1 || env.GOMAXPROCS < 1 {
+		t.Errorf("CaptureEnv() = %+v, want every field set", env)
+	}
+	if s := env.String(); !strings.Contains(s, env.GOOS+"/"+env.GOARCH) {
+		t.Errorf("String() = %q, want it to start with %s/%s", s, env.GOOS, env.GOARCH)
+	}
+}
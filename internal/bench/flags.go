@@ -0,0 +1,84 @@
+package bench
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FlagsJSONEnv makes a lesson's -h print its flags as JSON rather than help text, for tools such as teachgo serve
+// that build a form from them
+const FlagsJSONEnv = "TEACHGO_FLAGS_JSON"
+
+// FlagInfo describes one flag of a lesson
+type FlagInfo struct {
+	Name    string `json:"name"`
+	Usage   string `json:"usage"`
+	Default string `json:"default"`
+	// Type is the kind of value the flag takes, e.g. int, duration or string, bool for a flag that is set or not
+	Type string `json:"type"`
+}
+
+// DescribeFlags lists every flag of a flag set in alphabetical order
+func DescribeFlags(fs *flag.FlagSet) []FlagInfo {
+	flags := []FlagInfo{}
+	fs.VisitAll(func(f *flag.Flag) {
+		typ, usage := flag.UnquoteUsage(f)
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			typ = "bool"
+		}
+		flags = append(flags, FlagInfo{Name: f.Name, Usage: usage, Default: f.DefValue, Type: typ})
+	})
+	return flags
+}
+
+// NewFlagSet builds the flag set of a lesson run as teachgo <name>, its help starts with the lesson's description
+func NewFlagSet(name, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet("teachgo "+name, flag.ExitOnError)
+	fs.Usage = func() {
+		if os.Getenv(FlagsJSONEnv) != "" {
+			json.NewEncoder(fs.Output()).Encode(DescribeFlags(fs))
+			return
+		}
+		fmt.Fprintf(fs.Output(), "Usage: teachgo [global flags] %s [flags]\n\n%s\n\nFlags:\n", name, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// Globals are the flags every lesson takes, given before the lesson's name on the teachgo command line they apply to
+// it just as if they had been given after it
+type Globals struct {
+	Format    string
+	Seed      int64
+	Trials    int
+	LogFormat string
+	LogLevel  string
+}
+
+// RegisterGlobals adds the shared flags to a flag set, a lesson can reword a flag's help with fs.Lookup afterwards
+// The seed defaults to the current time so each run differs, passing a run's seed back in repeats it
+func RegisterGlobals(fs *flag.FlagSet) *Globals {
+	g := &Globals{}
+	fs.StringVar(&g.Format, "format", "text", "the output format of the results, text, json or csv, where the lesson supports it")
+	fs.Int64Var(&g.Seed, "seed", time.Now().UnixNano(), "the random seed, reuse a run's seed to repeat it")
+	fs.IntVar(&g.Trials, "trials", 1, "repeat the measurement this many times, where the lesson supports it")
+	fs.StringVar(&g.LogFormat, "log-format", "text", "the format of the progress and status messages written to stderr, text or json")
+	fs.StringVar(&g.LogLevel, "log-level", "info", "the least severe progress and status messages shown, debug, info, warn or error")
+	return g
+}
+
+// Check rejects a format the lesson can't produce, a trial count below one and unknown logging settings
+func (g *Globals) Check(formats ...string) error {
+	if !slices.Contains(formats, g.Format) {
+		return fmt.Errorf("unknown format %q, use one of %s", g.Format, strings.Join(formats, ", "))
+	}
+	if g.Trials < 1 {
+		return fmt.Errorf("-trials must be at least 1")
+	}
+	return g.checkLogging()
+}
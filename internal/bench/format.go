@@ -0,0 +1,33 @@
+package bench
+
+import (
+	"fmt"
+	"time"
+)
+
+// FormatRate formats a throughput with a unit prefix, 12.34M/s rather than 12340000
+func FormatRate(opsPerSec float64) string {
+	switch {
+	case opsPerSec >= 1e9:
+		return fmt.Sprintf("%.2fG/s", opsPerSec/1e9)
+	case opsPerSec >= 1e6:
+		return fmt.Sprintf("%.2fM/s", opsPerSec/1e6)
+	case opsPerSec >= 1e3:
+		return fmt.Sprintf("%.2fK/s", opsPerSec/1e3)
+	default:
+		return fmt.Sprintf("%.0f/s", opsPerSec)
+	}
+}
+
+// Speedup is how many times faster the second duration is than the first
+func Speedup(baseline, improved time.Duration) float64 {
+	return float64(baseline) / float64(improved)
+}
+
+// PerOp spreads a duration evenly over a number of operations, zero when there were none
+func PerOp(elapsed time.Duration, ops int) time.Duration {
+	if ops == 0 {
+		return 0
+	}
+	return elapsed / time.Duration(ops)
+}
@@ -0,0 +1,38 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// logLevels maps the -log-level names to slog levels, least severe first
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// checkLogging rejects a log format or level the lesson can't set up
+func (g *Globals) checkLogging() error {
+	if g.LogFormat != "text" && g.LogFormat != "json" {
+		return fmt.Errorf("unknown -log-format %q, use one of text, json", g.LogFormat)
+	}
+	if _, ok := logLevels[g.LogLevel]; !ok {
+		return fmt.Errorf("unknown -log-level %q, use one of debug, info, warn, error", g.LogLevel)
+	}
+	return nil
+}
+
+// Logger builds the logger for a lesson's progress and status messages, which go to w, usually stderr, leaving
+// stdout to the results
+// With -log-format json every message is one JSON object per line a script can follow a run's progress through,
+// a lesson makes it the default with slog.SetDefault so every package logs through it
+func (g *Globals) Logger(w io.Writer) *slog.Logger {
+	options := &slog.HandlerOptions{Level: logLevels[g.LogLevel]}
+	if g.LogFormat == "json" {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+	return slog.New(slog.NewTextHandler(w, options))
+}
@@ -0,0 +1,37 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	g := &Globals{Format: "text", Trials: 1, LogFormat: "json", LogLevel: "warn"}
+	if err := g.Check("text"); err != nil {
+		t.Fatalf("valid logging settings reported %v", err)
+	}
+
+	var buf bytes.Buffer
+	logger := g.Logger(&buf)
+	logger.Info("dropped below the level")
+	logger.Warn("run stopped early", "routine", 3)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("logged %d lines, want 1: %q", len(lines), buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("log line isn't JSON: %v", err)
+	}
+	if entry["msg"] != "run stopped early" || entry["routine"] != 3.0 {
+		t.Errorf("logged %v, want the warning with its routine", entry)
+	}
+
+	g.LogLevel = "loud"
+	if err := g.Check("text"); err == nil {
+		t.Error("an unknown log level wasn't reported")
+	}
+}
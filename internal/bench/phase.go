@@ -0,0 +1,37 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime/trace"
+	"time"
+)
+
+// Phase runs fn as a named step of a benchmark and returns how long it took, while a runtime/trace is being recorded
+// the step also shows up in it as a user region under that name, and with -log-level debug it is logged as it ends
+func Phase(ctx context.Context, name string, fn func()) time.Duration {
+	start := time.Now()
+	trace.WithRegion(ctx, name, fn)
+	elapsed := time.Since(start)
+	slog.DebugContext(ctx, "phase finished", "phase", name, "duration", elapsed)
+	return elapsed
+}
+
+// StartTrace records a runtime/trace of the program to path until the returned stop function is called, view it with
+// go tool trace
+func StartTrace(path string) (stop func() error, err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace file: %w", err)
+	}
+	if err := trace.Start(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to start trace: %w", err)
+	}
+	return func() error {
+		trace.Stop()
+		return f.Close()
+	}, nil
+}
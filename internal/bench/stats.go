@@ -0,0 +1,179 @@
+// Package bench is the measurement harness the lessons share, timing phases of a run, accumulating latencies into
+// lock free statistics and formatting the results, so every program measures and reports the same way
+package bench
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// Stats accumulates the latency of a single kind of operation, every field is atomic so many goroutines can record
+// into it at once
+// The zero value isn't ready for use, Reset it first, or build it with NewStats
+type Stats struct {
+	count     atomic.Int64
+	totalNs   atomic.Int64
+	minNs     atomic.Int64
+	maxNs     atomic.Int64
+	histogram Histogram
+}
+
+func NewStats() *Stats {
+	stats := &Stats{}
+	stats.Reset()
+	return stats
+}
+
+// Reset zeroes every field, min starts at the largest possible value so the first recorded latency replaces it
+func (s *Stats) Reset() {
+	s.count.Store(0)
+	s.totalNs.Store(0)
+	s.minNs.Store(math.MaxInt64)
+	s.maxNs.Store(0)
+	s.histogram.Reset()
+}
+
+func (s *Stats) Record(elapsed time.Duration) {
+	ns := elapsed.Nanoseconds()
+	s.count.Add(1)
+	s.totalNs.Add(ns)
+	s.histogram.Record(ns)
+
+	// min and max can't be expressed as a single atomic add, so retry until our compare and swap wins or is unnecessary
+	for current := s.minNs.Load(); ns < current; current = s.minNs.Load() {
+		if s.minNs.CompareAndSwap(current, ns) {
+			break
+		}
+	}
+	for current := s.maxNs.Load(); ns > current; current = s.maxNs.Load() {
+		if s.maxNs.CompareAndSwap(current, ns) {
+			break
+		}
+	}
+}
+
+func (s *Stats) Count() int64 {
+	return s.count.Load()
+}
+
+func (s *Stats) Total() time.Duration {
+	return time.Duration(s.totalNs.Load())
+}
+
+func (s *Stats) Min() time.Duration {
+	if s.Count() == 0 {
+		return 0
+	}
+	return time.Duration(s.minNs.Load())
+}
+
+func (s *Stats) Max() time.Duration {
+	return time.Duration(s.maxNs.Load())
+}
+
+func (s *Stats) Mean() time.Duration {
+	count := s.Count()
+	if count == 0 {
+		return 0
+	}
+	return s.Total() / time.Duration(count)
+}
+
+// Percentile returns the latency below which the given fraction (0 to 1) of operations completed, the histogram
+// reports bucket upper bounds so it is clamped to the largest latency actually observed
+func (s *Stats) Percentile(q float64) time.Duration {
+	return MergedPercentile(q, s)
+}
+
+// MergedPercentile computes a percentile across several Stats as if their samples had been recorded into one
+func MergedPercentile(q float64, stats ...*Stats) time.Duration {
+	histograms := []*Histogram{}
+	largest := time.Duration(0)
+	for _, s := range stats {
+		histograms = append(histograms, &s.histogram)
+		largest = max(largest, s.Max())
+	}
+	return min(mergedHistogramPercentile(q, histograms...), largest)
+}
+
+// HistogramPrecisionBits sets how many sub-buckets each power of two is split into, 3 bits gives 8 sub-buckets
+// and keeps every reported percentile within 12.5% of the true value
+const HistogramPrecisionBits = 3
+
+// histogramBuckets covers every non-negative int64 nanosecond value
+const histogramBuckets = (64 - HistogramPrecisionBits + 1) << HistogramPrecisionBits
+
+// Histogram is a small HDR-style histogram, bucket widths grow with the value so relative precision stays constant
+// from nanoseconds to seconds while memory stays fixed, and every bucket is an atomic so recording is lock free
+type Histogram struct {
+	buckets [histogramBuckets]atomic.Int64
+}
+
+// histogramBucket maps a value onto its bucket, values below 2^precision get exact buckets, larger values are
+// bucketed by their exponent plus the next few most significant bits
+func histogramBucket(ns int64) int {
+	if ns < 1<<HistogramPrecisionBits {
+		return int(max(ns, 0))
+	}
+	exponent := bits.Len64(uint64(ns)) - 1
+	sub := int(ns>>(exponent-HistogramPrecisionBits)) & (1<<HistogramPrecisionBits - 1)
+	return (exponent-HistogramPrecisionBits+1)<<HistogramPrecisionBits + sub
+}
+
+// histogramBucketLowerBound is the inverse of histogramBucket, returning the smallest value stored in a bucket
+func histogramBucketLowerBound(bucket int) int64 {
+	if bucket < 1<<HistogramPrecisionBits {
+		return int64(bucket)
+	}
+	exponent := bucket>>HistogramPrecisionBits + HistogramPrecisionBits - 1
+	sub := int64(bucket & (1<<HistogramPrecisionBits - 1))
+	return (1<<HistogramPrecisionBits + sub) << (exponent - HistogramPrecisionBits)
+}
+
+func (h *Histogram) Record(ns int64) {
+	h.buckets[histogramBucket(ns)].Add(1)
+}
+
+func (h *Histogram) Reset() {
+	for i := range h.buckets {
+		h.buckets[i].Store(0)
+	}
+}
+
+// Percentile walks the buckets until the requested share of samples is covered and reports that bucket's upper bound
+func (h *Histogram) Percentile(q float64) time.Duration {
+	return mergedHistogramPercentile(q, h)
+}
+
+func mergedHistogramPercentile(q float64, histograms ...*Histogram) time.Duration {
+	bucketCount := func(i int) int64 {
+		count := int64(0)
+		for _, h := range histograms {
+			count += h.buckets[i].Load()
+		}
+		return count
+	}
+
+	total := int64(0)
+	for i := range histogramBuckets {
+		total += bucketCount(i)
+	}
+	if total == 0 {
+		return 0
+	}
+
+	target := int64(math.Ceil(q * float64(total)))
+	seen := int64(0)
+	for i := range histogramBuckets {
+		seen += bucketCount(i)
+		if seen >= target {
+			if i == histogramBuckets-1 {
+				return time.Duration(math.MaxInt64)
+			}
+			return time.Duration(histogramBucketLowerBound(i+1) - 1)
+		}
+	}
+	return time.Duration(math.MaxInt64)
+}
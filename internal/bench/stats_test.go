@@ -0,0 +1,39 @@
+package bench
+
+import (
+	"testing"
+	"time"
+)
+
+// TestHistogramBuckets checks every value lands in a bucket whose lower bound is within the histogram's precision
+func TestHistogramBuckets(t *testing.T) {
+	for _, ns := range []int64{0, 1, 7, 8, 9, 15, 16, 100, 1000, 123456, 1 << 40, 1<<62 + 12345} {
+		lower := histogramBucketLowerBound(histogramBucket(ns))
+		if lower > ns {
+			t.Errorf("%d landed in a bucket starting at %d, above it", ns, lower)
+		}
+		if float64(ns-lower) > float64(ns)/(1<<HistogramPrecisionBits) {
+			t.Errorf("%d landed in a bucket starting at %d, outside the histogram's precision", ns, lower)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	stats := NewStats()
+	for _, latency := range []time.Duration{30, 10, 20} {
+		stats.Record(latency)
+	}
+	if stats.Count() != 3 || stats.Min() != 10 || stats.Max() != 30 || stats.Mean() != 20 {
+		t.Errorf("count %d, min %v, max %v, mean %v, want 3, 10ns, 30ns and 20ns",
+			stats.Count(), stats.Min(), stats.Max(), stats.Mean())
+	}
+	// the histogram is exact below 2^precision nanoseconds, above it the percentile is clamped to the max
+	if got := stats.Percentile(1); got != 30 {
+		t.Errorf("p100 is %v, want 30ns", got)
+	}
+
+	stats.Reset()
+	if stats.Count() != 0 || stats.Min() != 0 || stats.Percentile(0.5) != 0 {
+		t.Error("reset left samples behind")
+	}
+}
@@ -0,0 +1,89 @@
+package bench
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Validator collects every problem with a lesson's flags so they can all be reported at once, rather than the student
+// fixing one flag per run, or the lesson panicking or hanging on a value it can't use
+//
+//	v := bench.NewValidator(fs)
+//	v.AtLeast("routines", *routines, 1)
+//	v.Check(*html == "" || !*sweep, "-html can't be combined with -sweep")
+//	v.Exit()
+type Validator struct {
+	fs       *flag.FlagSet
+	problems []string
+}
+
+// NewValidator starts validating the parsed flags of a flag set built by NewFlagSet
+func NewValidator(fs *flag.FlagSet) *Validator {
+	return &Validator{fs: fs}
+}
+
+// Check records the problem when the condition doesn't hold, the message should say how to fix it
+func (v *Validator) Check(ok bool, format string, args ...any) {
+	if !ok {
+		v.problems = append(v.problems, fmt.Sprintf(format, args...))
+	}
+}
+
+// Add records an error returned by another check, such as Globals.Check, nil is ignored
+func (v *Validator) Add(err error) {
+	if err != nil {
+		v.problems = append(v.problems, err.Error())
+	}
+}
+
+// AtLeast requires an integer flag to be at least min
+func (v *Validator) AtLeast(name string, value, min int) {
+	v.Check(value >= min, "-%s must be at least %d, got %d", name, min, value)
+}
+
+// Fraction requires a float flag to lie between 0 and 1
+func (v *Validator) Fraction(name string, value float64) {
+	v.Check(value >= 0 && value <= 1, "-%s is a fraction from 0 to 1, got %g", name, value)
+}
+
+// NotNegative requires a duration flag to be zero or more, zero usually meaning the feature is off
+func (v *Validator) NotNegative(name string, value time.Duration) {
+	v.Check(value >= 0, "-%s can't be negative, got %v, use 0 to turn it off", name, value)
+}
+
+// OneOf requires a string flag to be one of the choices
+func (v *Validator) OneOf(name, value string, choices ...string) {
+	for _, choice := range choices {
+		if value == choice {
+			return
+		}
+	}
+	v.Check(false, "unknown -%s %q, use one of %s", name, value, strings.Join(choices, ", "))
+}
+
+// Err joins the problems found into one error, nil when the flags are fine
+func (v *Validator) Err() error {
+	errs := []error{}
+	for _, problem := range v.problems {
+		errs = append(errs, errors.New(problem))
+	}
+	return errors.Join(errs...)
+}
+
+// Exit prints every problem found and exits with status 2, the status for a usage error, it returns when the flags
+// are fine
+func (v *Validator) Exit() {
+	if len(v.problems) == 0 {
+		return
+	}
+	for _, problem := range v.problems {
+		fmt.Fprintln(os.Stderr, problem)
+	}
+	// the flag set is named teachgo <lesson>
+	fmt.Fprintf(os.Stderr, "\nRun %s -h to see every flag and its default\n", v.fs.Name())
+	os.Exit(2)
+}
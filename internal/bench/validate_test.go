@@ -0,0 +1,36 @@
+package bench
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidator(t *testing.T) {
+	v := NewValidator(flag.NewFlagSet("teachgo test", flag.ContinueOnError))
+	v.AtLeast("routines", 4, 1)
+	v.Fraction("read-ratio", 0.5)
+	v.NotNegative("think", 0)
+	v.OneOf("format", "json", "text", "json")
+	v.Add(nil)
+	if err := v.Err(); err != nil {
+		t.Fatalf("valid flags reported %v", err)
+	}
+
+	v.AtLeast("routines", 0, 1)
+	v.Fraction("read-ratio", 1.5)
+	v.NotNegative("think", -time.Second)
+	v.OneOf("format", "xml", "text", "json")
+	v.Check(false, "-html can't be combined with -sweep")
+	err := v.Err()
+	if err == nil {
+		t.Fatal("invalid flags weren't reported")
+	}
+	// every problem is reported, each naming its flag
+	for _, flag := range []string{"-routines", "-read-ratio", "-think", "-format", "-html"} {
+		if !strings.Contains(err.Error(), flag) {
+			t.Errorf("%s isn't mentioned in %q", flag, err)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Package datagen draws the keys and values the lessons work on, from one of a few distributions, so every lesson
+// can ask the same question of its data structure under uniform access, a few hot keys, a cluster around the middle,
+// values in order, or a student's own data read from a file
+package datagen
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Kinds lists the distributions Parse accepts, in the order a flag's usage mentions them
+var Kinds = []string{"uniform", "zipfian", "gaussian", "sequential", "file"}
+
+// Usage describes the distribution syntax, for a flag's usage text
+const Usage = "uniform, zipfian[:skew], gaussian[:stddev as a fraction of the range], sequential or file:path"
+
+const (
+	// defaultSkew is the zipfian exponent when none is given, math/rand's Zipf needs it above 1, the higher it is the
+	// more of the draws land on the first few values
+	defaultSkew = 1.1
+	// defaultSpread is the gaussian standard deviation when none is given, a sixth of the range puts three standard
+	// deviations either side of the middle inside it
+	defaultSpread = 1.0 / 6
+)
+
+// Distribution is how values in [0, n) are drawn, built by Parse from a flag's value
+type Distribution struct {
+	kind   string
+	n      int
+	param  float64
+	values []int
+}
+
+// Parse reads a distribution of values in [0, n) from its name and optional parameter, e.g. zipfian:1.5, a file
+// distribution reads the file, one integer per line, each of which has to lie in the range, an empty spec is uniform
+func Parse(spec string, n int) (Distribution, error) {
+	if n < 1 {
+		return Distribution{}, fmt.Errorf("a distribution needs a range of at least 1 value, got %d", n)
+	}
+	kind, param, hasParam := strings.Cut(cmp.Or(spec, "uniform"), ":")
+	d := Distribution{kind: kind, n: n}
+
+	switch kind {
+	case "uniform", "sequential":
+		if hasParam {
+			return Distribution{}, fmt.Errorf("the %s distribution takes no parameter, got %q", kind, spec)
+		}
+	case "zipfian", "gaussian":
+		d.param = defaultSkew
+		if kind == "gaussian" {
+			d.param = defaultSpread
+		}
+		if hasParam {
+			value, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return Distribution{}, fmt.Errorf("the %s parameter %q isn't a number", kind, param)
+			}
+			d.param = value
+		}
+		if kind == "zipfian" && d.param <= 1 {
+			return Distribution{}, fmt.Errorf("the zipfian skew has to be above 1, got %g", d.param)
+		}
+		if kind == "gaussian" && d.param <= 0 {
+			return Distribution{}, fmt.Errorf("the gaussian standard deviation has to be above 0, got %g", d.param)
+		}
+	case "file":
+		if param == "" {
+			return Distribution{}, fmt.Errorf("the file distribution needs a path, e.g. file:keys.txt")
+		}
+		values, err := readValues(param, n)
+		if err != nil {
+			return Distribution{}, err
+		}
+		d.values = values
+	default:
+		return Distribution{}, fmt.Errorf("unknown distribution %q, use one of %s", kind, strings.Join(Kinds, ", "))
+	}
+	return d, nil
+}
+
+// MustParse is Parse for distributions known to be valid, like a flag's default, it panics on an error
+func MustParse(spec string, n int) Distribution {
+	d, err := Parse(spec, n)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// readValues loads a file distribution's values, blank lines and lines starting with # are skipped
+func readValues(path string, n int) ([]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := []int{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		value, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %q isn't an integer", path, line, text)
+		}
+		if value < 0 || value >= n {
+			return nil, fmt.Errorf("%s:%d: %d is outside the range [0, %d)", path, line, value, n)
+		}
+		values = append(values, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%s has no values in it", path)
+	}
+	return values, nil
+}
+
+// String is the distribution as Parse reads it, the file's path left out as it means nothing on another machine
+func (d Distribution) String() string {
+	switch d.kind {
+	case "zipfian", "gaussian":
+		return d.kind + ":" + strconv.FormatFloat(d.param, 'g', -1, 64)
+	default:
+		return d.kind
+	}
+}
+
+// Source is a stream of values from a distribution
+type Source interface {
+	Next() int
+}
+
+// Source starts a stream of values drawn with rng, streams given generators seeded alike produce the same values
+// A uniform source draws exactly as rng.Intn(n) would, so switching code over to it leaves earlier seeds' data as it was
+func (d Distribution) Source(rng *rand.Rand) Source {
+	switch d.kind {
+	case "zipfian":
+		return zipfian{rand.NewZipf(rng, d.param, 1, uint64(d.n-1))}
+	case "gaussian":
+		return gaussian{rng: rng, n: d.n, stddev: d.param * float64(d.n)}
+	case "sequential":
+		return &sequential{n: d.n}
+	case "file":
+		return &sequential{values: d.values}
+	default:
+		return uniform{rng: rng, n: d.n}
+	}
+}
+
+// Fill draws count values from a fresh source
+func (d Distribution) Fill(rng *rand.Rand, count int) []int {
+	source := d.Source(rng)
+	values := make([]int, count)
+	for i := range values {
+		values[i] = source.Next()
+	}
+	return values
+}
+
+type uniform struct {
+	rng *rand.Rand
+	n   int
+}
+
+func (u uniform) Next() int {
+	return u.rng.Intn(u.n)
+}
+
+// zipfian draws 0 most often, 1 next most and so on, the shape of real traffic where a few keys are hot
+type zipfian struct {
+	zipf *rand.Zipf
+}
+
+func (z zipfian) Next() int {
+	return int(z.zipf.Uint64())
+}
+
+// gaussian clusters values around the middle of the range, draws falling outside it are clamped to its ends
+type gaussian struct {
+	rng    *rand.Rand
+	n      int
+	stddev float64
+}
+
+func (g gaussian) Next() int {
+	value := math.Round(float64(g.n)/2 + g.rng.NormFloat64()*g.stddev)
+	return int(max(0, min(value, float64(g.n-1))))
+}
+
+// sequential counts up through the range, or through a file's values, starting over once it reaches the end
+type sequential struct {
+	n      int
+	values []int
+	next   int
+}
+
+func (s *sequential) Next() int {
+	if s.values != nil {
+		value := s.values[s.next]
+		s.next = (s.next + 1) % len(s.values)
+		return value
+	}
+	value := s.next
+	s.next = (s.next + 1) % s.n
+	return value
+}
@@ -0,0 +1,119 @@
+package datagen
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{spec: "", want: "uniform"},
+		{spec: "uniform", want: "uniform"},
+		{spec: "zipfian", want: "zipfian:1.1"},
+		{spec: "zipfian:2", want: "zipfian:2"},
+		{spec: "gaussian:0.1", want: "gaussian:0.1"},
+		{spec: "sequential", want: "sequential"},
+		{spec: "zipfian:1", wantErr: true},
+		{spec: "gaussian:-1", wantErr: true},
+		{spec: "gaussian:wide", wantErr: true},
+		{spec: "uniform:3", wantErr: true},
+		{spec: "file", wantErr: true},
+		{spec: "pareto", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			d, err := Parse(tt.spec, 100)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Parse(%q) = %v, want an error", tt.spec, d)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.spec, err)
+			}
+			if d.String() != tt.want {
+				t.Errorf("Parse(%q) = %v, want %s", tt.spec, d, tt.want)
+			}
+		})
+	}
+}
+
+func TestSourcesStayInRange(t *testing.T) {
+	for _, spec := range []string{"uniform", "zipfian", "gaussian", "gaussian:5", "sequential"} {
+		values := MustParse(spec, 50).Fill(rand.New(rand.NewSource(1)), 10000)
+		for _, v := range values {
+			if v < 0 || v >= 50 {
+				t.Fatalf("%s drew %d, outside [0, 50)", spec, v)
+			}
+		}
+	}
+}
+
+// a uniform source has to draw exactly what rng.Intn did, or recorded seeds would generate different data
+func TestUniformMatchesIntn(t *testing.T) {
+	got := MustParse("uniform", 1000).Fill(rand.New(rand.NewSource(42)), 100)
+	rng := rand.New(rand.NewSource(42))
+	for i, v := range got {
+		if want := rng.Intn(1000); v != want {
+			t.Fatalf("value %d = %d, want %d", i, v, want)
+		}
+	}
+}
+
+func TestShapes(t *testing.T) {
+	count := func(values []int, keep func(int) bool) int {
+		n := 0
+		for _, v := range values {
+			if keep(v) {
+				n++
+			}
+		}
+		return n
+	}
+	rng := rand.New(rand.NewSource(1))
+
+	// under a uniform distribution the lowest tenth of the range would get a tenth of the draws
+	zipf := MustParse("zipfian", 1000).Fill(rng, 10000)
+	if low := count(zipf, func(v int) bool { return v < 100 }); low < 5000 {
+		t.Errorf("zipfian put %d of 10000 draws in the lowest tenth, want most of them", low)
+	}
+
+	// within one standard deviation of the middle holds about 68% of a normal distribution's draws
+	gauss := MustParse("gaussian", 600).Fill(rng, 10000)
+	if middle := count(gauss, func(v int) bool { return v >= 200 && v < 400 }); middle < 6000 || middle > 7500 {
+		t.Errorf("gaussian put %d of 10000 draws within a standard deviation of the middle, want about 6800", middle)
+	}
+
+	if got := MustParse("sequential", 3).Fill(rng, 7); !slices.Equal(got, []int{0, 1, 2, 0, 1, 2, 0}) {
+		t.Errorf("sequential drew %v, want 0, 1, 2 repeating", got)
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.txt")
+	if err := os.WriteFile(path, []byte("# hot keys\n7\n\n3\n9\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Parse("file:"+path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Fill(nil, 5); !slices.Equal(got, []int{7, 3, 9, 7, 3}) {
+		t.Errorf("file drew %v, want 7, 3, 9 repeating", got)
+	}
+
+	if _, err := Parse("file:"+path, 5); err == nil {
+		t.Error("Parse accepted a file with values outside the range")
+	}
+	if _, err := Parse("file:"+filepath.Join(t.TempDir(), "missing.txt"), 10); err == nil {
+		t.Error("Parse accepted a file that doesn't exist")
+	}
+}
@@ -0,0 +1,107 @@
+package exercise
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/joshdurbin/teaching-go/exercises/counter"
+)
+
+func init() {
+	Register(Exercise{
+		Name:   "sharded-counter",
+		Lesson: "counters",
+		Instructions: `Implement a sharded counter
+
+The counters lesson shows a single mutex becoming the bottleneck once many goroutines share it. Implement
+ShardedCounter in exercises/counter, which spreads the count over several shards each guarded by its own lock, so
+concurrent updates mostly land on different shards. Value sums the shards. Compare yours with the lesson's Sharded
+counter, then run the checks with go run -race ./cmd/teachgo verify sharded-counter to have the race detector
+watch them too.`,
+		Stub: "exercises/counter/counter.go",
+		Checks: []Check{
+			{"counting from one goroutine", checkCounterSequential},
+			{"a single shard", checkCounterOneShard},
+			{"counting from many goroutines at once", checkCounterConcurrent},
+			{"reading while others count", checkCounterConcurrentReads},
+		},
+	})
+}
+
+func checkCounterSequential() error {
+	c := counter.NewShardedCounter(8)
+	if v := c.Value(); v != 0 {
+		return fmt.Errorf("a new counter's Value() is %d, want 0", v)
+	}
+	for i := range 100 {
+		c.Add(i)
+	}
+	c.Add(-50)
+	if v := c.Value(); v != 4900 {
+		return fmt.Errorf("after adding 0 to 99 and -50 Value() is %d, want 4900", v)
+	}
+	return nil
+}
+
+func checkCounterOneShard() error {
+	c := counter.NewShardedCounter(1)
+	for range 10 {
+		c.Add(3)
+	}
+	if v := c.Value(); v != 30 {
+		return fmt.Errorf("a one shard counter's Value() is %d after ten Add(3), want 30", v)
+	}
+	return nil
+}
+
+func checkCounterConcurrent() error {
+	const routines, adds = 16, 10000
+	c := counter.NewShardedCounter(4)
+	var wg sync.WaitGroup
+	for i := range routines {
+		wg.Go(func() {
+			for range adds {
+				// half the routines count down, so a lost update shows whichever way it goes
+				if i%2 == 0 {
+					c.Add(2)
+				} else {
+					c.Add(-1)
+				}
+			}
+		})
+	}
+	wg.Wait()
+	if want := routines / 2 * adds; c.Value() != want {
+		return fmt.Errorf("Value() is %d after %d concurrent routines finished, want %d, updates were lost", c.Value(), routines, want)
+	}
+	return nil
+}
+
+func checkCounterConcurrentReads() error {
+	const routines, adds = 8, 10000
+	c := counter.NewShardedCounter(4)
+	var wg sync.WaitGroup
+	for range routines {
+		wg.Go(func() {
+			for range adds {
+				c.Add(1)
+			}
+		})
+	}
+
+	// every routine only adds, so the value a reader sees can never go down
+	last := 0
+	for range 1000 {
+		v := c.Value()
+		if v < last || v > routines*adds {
+			wg.Wait()
+			return fmt.Errorf("Value() read %d after %d while routines were only adding, a read saw a half updated count", v, last)
+		}
+		last = v
+	}
+	wg.Wait()
+	if v := c.Value(); v != routines*adds {
+		return fmt.Errorf("Value() is %d once every routine finished, want %d", v, routines*adds)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+// Package exercise turns the lessons into a course, each exercise is a stubbed implementation under exercises/ for a
+// student to fill in, and a set of checks kept here, out of the student's way, that teachgo verify runs against it
+package exercise
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Exercise is one task for a student, tied to the lesson it follows on from
+type Exercise struct {
+	// Name is how teachgo refers to it, e.g. skiplist-delete
+	Name string
+	// Lesson is the name of the lesson to run first
+	Lesson string
+	// Instructions explain the task, their first line is the summary listed alongside the other exercises
+	Instructions string
+	// Stub is the file, relative to the repository root, the student edits
+	Stub string
+	// Checks verify the student's implementation, in order
+	Checks []Check
+}
+
+// Summary is the first line of the instructions
+func (e Exercise) Summary() string {
+	summary, _, _ := strings.Cut(e.Instructions, "\n")
+	return summary
+}
+
+// Check is one verification of an exercise, Run returns an error saying what it expected and what it found
+type Check struct {
+	Name string
+	Run  func() error
+}
+
+var registry []Exercise
+
+// Register adds an exercise, it panics on a duplicate name
+func Register(e Exercise) {
+	if _, ok := Find(e.Name); ok {
+		panic(fmt.Sprintf("exercise %q registered twice", e.Name))
+	}
+	registry = append(registry, e)
+}
+
+// All returns every exercise in the order they were registered
+func All() []Exercise {
+	return append([]Exercise{}, registry...)
+}
+
+// Find looks an exercise up by name
+func Find(name string) (Exercise, bool) {
+	for _, e := range registry {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Exercise{}, false
+}
+
+// ForLesson returns the exercises that follow on from a lesson
+func ForLesson(lesson string) []Exercise {
+	exercises := []Exercise{}
+	for _, e := range registry {
+		if e.Lesson == lesson {
+			exercises = append(exercises, e)
+		}
+	}
+	return exercises
+}
+
+// Outcome is how a check went
+type Outcome int
+
+const (
+	Passed Outcome = iota
+	Failed
+	// NotImplemented means the check reached a stub's TODO panic, the student hasn't got that far yet
+	NotImplemented
+	// TimedOut means the check didn't finish in time, usually a deadlock or an endless loop
+	TimedOut
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Passed:
+		return "PASS"
+	case Failed:
+		return "FAIL"
+	case NotImplemented:
+		return "TODO"
+	case TimedOut:
+		return "TIMEOUT"
+	default:
+		return fmt.Sprintf("Outcome(%d)", int(o))
+	}
+}
+
+// Result is the outcome of one check, with the reason when it didn't pass
+type Result struct {
+	Check   string
+	Outcome Outcome
+	Err     error
+}
+
+// Verify runs every check of an exercise, each in its own goroutine so a panic in the student's code fails only that
+// check and a check stuck past the timeout is abandoned rather than hanging the run
+func Verify(e Exercise, timeout time.Duration) []Result {
+	results := []Result{}
+	for _, check := range e.Checks {
+		results = append(results, run(check, timeout))
+	}
+	return results
+}
+
+func run(check Check, timeout time.Duration) Result {
+	done := make(chan Result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				if message, ok := r.(string); ok && strings.HasPrefix(message, "TODO") {
+					done <- Result{Check: check.Name, Outcome: NotImplemented, Err: fmt.Errorf("%s", message)}
+					return
+				}
+				done <- Result{Check: check.Name, Outcome: Failed, Err: fmt.Errorf("panicked: %v", r)}
+			}
+		}()
+		if err := check.Run(); err != nil {
+			done <- Result{Check: check.Name, Outcome: Failed, Err: err}
+			return
+		}
+		done <- Result{Check: check.Name, Outcome: Passed}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case result := <-done:
+		return result
+	case <-timer.C:
+		return Result{Check: check.Name, Outcome: TimedOut, Err: fmt.Errorf("didn't finish within %v, is something deadlocked or looping forever?", timeout)}
+	}
+}
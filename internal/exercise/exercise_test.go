@@ -0,0 +1,38 @@
+package exercise
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerifyOutcomes(t *testing.T) {
+	e := Exercise{
+		Name: "test",
+		Checks: []Check{
+			{"passes", func() error { return nil }},
+			{"fails", func() error { return errors.New("wrong") }},
+			{"reaches a stub", func() error { panic("TODO: implement it") }},
+			{"crashes", func() error {
+				var m map[string]int
+				m["x"] = 1
+				return nil
+			}},
+			{"hangs", func() error { select {} }},
+		},
+	}
+
+	want := []Outcome{Passed, Failed, NotImplemented, Failed, TimedOut}
+	results := Verify(e, 50*time.Millisecond)
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, result := range results {
+		if result.Outcome != want[i] {
+			t.Errorf("check %q was %v, want %v", result.Check, result.Outcome, want[i])
+		}
+		if (result.Outcome == Passed) != (result.Err == nil) {
+			t.Errorf("check %q was %v with error %v", result.Check, result.Outcome, result.Err)
+		}
+	}
+}
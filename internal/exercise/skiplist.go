@@ -0,0 +1,141 @@
+package exercise
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+
+	"github.com/joshdurbin/teaching-go/exercises/skiplist"
+)
+
+func init() {
+	Register(Exercise{
+		Name:   "skiplist-delete",
+		Lesson: "skiplist",
+		Instructions: `Implement Delete for the skip list
+
+The skip list in exercises/skiplist can insert and search but not delete. Delete finds the node the same way Insert
+finds where a node goes, remembering the last node before it on every level, then unlinks it from each level it
+reaches. Once the node is gone the top levels may be empty, lower the list's level so searches don't start on them.`,
+		Stub: "exercises/skiplist/skiplist.go",
+		Checks: []Check{
+			{"deleting from an empty list", checkDeleteEmpty},
+			{"deleting the only value", checkDeleteOnly},
+			{"deleting every other value", checkDeleteHalf},
+			{"deleting one of several duplicates", checkDeleteDuplicate},
+			{"every level stays linked and sorted", checkDeleteLevels},
+			{"deleting everything lowers the level", checkDeleteAll},
+		},
+	})
+}
+
+func checkDeleteEmpty() error {
+	sl := skiplist.New(16, 1)
+	if sl.Delete(5) {
+		return fmt.Errorf("Delete(5) on an empty list returned true, want false")
+	}
+	return nil
+}
+
+func checkDeleteOnly() error {
+	sl := skiplist.New(16, 1)
+	sl.Insert(5)
+	if !sl.Delete(5) {
+		return fmt.Errorf("Delete(5) returned false after Insert(5), want true")
+	}
+	if sl.Contains(5) || sl.Len() != 0 {
+		return fmt.Errorf("after deleting the only value Contains(5) is %v and Len() %d, want false and 0", sl.Contains(5), sl.Len())
+	}
+	if sl.Delete(5) {
+		return fmt.Errorf("deleting 5 a second time returned true, want false")
+	}
+	return nil
+}
+
+// filledList inserts 0 to n-1 in a random order
+func filledList(n int) *skiplist.SkipList {
+	sl := skiplist.New(16, 42)
+	for _, v := range rand.New(rand.NewSource(42)).Perm(n) {
+		sl.Insert(v)
+	}
+	return sl
+}
+
+func checkDeleteHalf() error {
+	const n = 1000
+	sl := filledList(n)
+	for v := 0; v < n; v += 2 {
+		if !sl.Delete(v) {
+			return fmt.Errorf("Delete(%d) returned false, want true", v)
+		}
+	}
+	for v := range n {
+		if want := v%2 == 1; sl.Contains(v) != want {
+			return fmt.Errorf("after deleting the even values Contains(%d) is %v, want %v", v, !want, want)
+		}
+	}
+	if sl.Len() != n/2 {
+		return fmt.Errorf("after deleting %d of %d values Len() is %d, want %d", n/2, n, sl.Len(), n/2)
+	}
+	return nil
+}
+
+func checkDeleteDuplicate() error {
+	sl := skiplist.New(16, 7)
+	for range 3 {
+		sl.Insert(9)
+	}
+	sl.Insert(4)
+	if !sl.Delete(9) {
+		return fmt.Errorf("Delete(9) returned false with three 9s in the list, want true")
+	}
+	if got := sl.Levels()[0]; !slices.Equal(got, []int{4, 9, 9}) {
+		return fmt.Errorf("after deleting one of three 9s the list holds %v, want [4 9 9]", got)
+	}
+	return nil
+}
+
+// checkDeleteLevels looks inside the list, a node unlinked from level 0 but not the levels above it is still found by
+// searches that skip past it, so every level must stay sorted and only hold values of the level below
+func checkDeleteLevels() error {
+	const n = 2000
+	sl := filledList(n)
+	deleted := map[int]bool{}
+	for _, v := range rand.New(rand.NewSource(7)).Perm(n)[:n/3] {
+		sl.Delete(v)
+		deleted[v] = true
+	}
+
+	levels := sl.Levels()
+	for i, level := range levels {
+		if !slices.IsSorted(level) {
+			return fmt.Errorf("level %d isn't sorted after deleting", i)
+		}
+		for _, v := range level {
+			if deleted[v] {
+				return fmt.Errorf("%d is still linked on level %d after it was deleted", v, i)
+			}
+			if i > 0 {
+				if _, found := slices.BinarySearch(levels[i-1], v); !found {
+					return fmt.Errorf("%d is on level %d but not on level %d below it", v, i, i-1)
+				}
+			}
+		}
+	}
+	if len(levels[0]) != sl.Len() {
+		return fmt.Errorf("level 0 holds %d values but Len() is %d", len(levels[0]), sl.Len())
+	}
+	return nil
+}
+
+func checkDeleteAll() error {
+	const n = 500
+	sl := filledList(n)
+	for v := range n {
+		sl.Delete(v)
+	}
+	if levels := sl.Levels(); len(levels) != 1 || len(levels[0]) != 0 {
+		return fmt.Errorf("after deleting everything the list still has %d levels, want just the empty level 0", len(levels))
+	}
+	return nil
+}
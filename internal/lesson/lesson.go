@@ -0,0 +1,83 @@
+// Package lesson is the curriculum, every lesson registers itself here from an init function and teachgo finds,
+// lists and runs them through it, so adding a lesson never means editing the command
+package lesson
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Difficulty orders the lessons from the first one a student should run to the last
+type Difficulty int
+
+const (
+	Beginner Difficulty = iota
+	Intermediate
+	Advanced
+)
+
+func (d Difficulty) String() string {
+	switch d {
+	case Beginner:
+		return "beginner"
+	case Intermediate:
+		return "intermediate"
+	case Advanced:
+		return "advanced"
+	default:
+		return fmt.Sprintf("Difficulty(%d)", int(d))
+	}
+}
+
+// Lesson is one subcommand of teachgo
+type Lesson struct {
+	// Name is the subcommand, teachgo <name>
+	Name string
+	// Description is the lesson's help text, its first line is the summary listed alongside the other lessons
+	Description string
+	Difficulty  Difficulty
+	// Topics are what the lesson teaches, a few words each, e.g. "mutexes" or "false sharing"
+	Topics []string
+	// Main runs the lesson with the arguments following its name
+	Main func(args []string)
+	// Quiz checks the student understood what the lesson showed them, teachgo quiz asks it
+	Quiz []Question
+}
+
+// Summary is the first line of the description
+func (l Lesson) Summary() string {
+	summary, _, _ := strings.Cut(l.Description, "\n")
+	return summary
+}
+
+var registry []Lesson
+
+// Register adds a lesson to the curriculum, it panics on a duplicate name as two lessons can't share a subcommand
+func Register(l Lesson) {
+	if _, ok := Find(l.Name); ok {
+		panic(fmt.Sprintf("lesson %q registered twice", l.Name))
+	}
+	registry = append(registry, l)
+}
+
+// All returns every registered lesson, easiest first and alphabetically within a difficulty, the order a student
+// would work through them in
+func All() []Lesson {
+	lessons := slices.Clone(registry)
+	slices.SortFunc(lessons, func(a, b Lesson) int {
+		return cmp.Or(cmp.Compare(a.Difficulty, b.Difficulty), strings.Compare(a.Name, b.Name))
+	})
+	return lessons
+}
+
+// Find looks a lesson up by name
+func Find(name string) (Lesson, bool) {
+	for _, l := range registry {
+		if l.Name == name {
+			return l, true
+		}
+	}
+	return Lesson{}, false
+}
@@ -0,0 +1,27 @@
+package lesson
+
+import "testing"
+
+func TestAllOrdersByDifficultyThenName(t *testing.T) {
+	Register(Lesson{Name: "test-heap", Difficulty: Advanced})
+	Register(Lesson{Name: "test-list", Difficulty: Beginner})
+	Register(Lesson{Name: "test-array", Difficulty: Advanced})
+
+	names := []string{}
+	for _, l := range All() {
+		names = append(names, l.Name)
+	}
+	want := []string{"test-list", "test-array", "test-heap"}
+	for i, name := range want {
+		if names[i] != name {
+			t.Fatalf("lessons are ordered %v, want %v", names, want)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a lesson name twice didn't panic")
+		}
+	}()
+	Register(Lesson{Name: "test-list"})
+}
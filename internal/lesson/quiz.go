@@ -0,0 +1,11 @@
+package lesson
+
+// Question is one multiple choice question of a lesson's quiz
+type Question struct {
+	Prompt  string
+	Choices []string
+	// Answer is the index of the correct choice
+	Answer int
+	// Explanation is shown after the student answers, right or wrong, it should say why the answer is right
+	Explanation string
+}
@@ -0,0 +1,242 @@
+// Package plot draws benchmark results as standalone SVG charts, line charts for a measurement against a parameter,
+// like search time against the number of elements, and bar charts for a single run
+package plot
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// Series is one line of a line chart, or one bar of a bar chart from its first point
+type Series struct {
+	Name string
+	X, Y []float64
+}
+
+// Chart is everything drawn, the axes switch to a log scale by themselves when their values span two orders of
+// magnitude or more, so O(n) against O(log n) reads as two lines rather than one line and the floor
+type Chart struct {
+	Title  string
+	XLabel string
+	YLabel string
+	Series []Series
+	// FormatY formats the values on the y axis and on the bars, strconv's shortest form when nil
+	FormatY func(float64) string
+}
+
+// palette colours the series in order, the same colours as internal/report's charts
+var palette = []string{"#4e79a7", "#f28e2b", "#59a14f", "#e15759", "#76b7b2", "#edc948", "#b07aa1"}
+
+const (
+	width, height            = 800, 500
+	left, right, top, bottom = 90, 180, 50, 60
+	plotWidth, plotHeight    = width - left - right, height - top - bottom
+)
+
+// axis maps values onto a length of the chart, linearly or by their logarithm
+type axis struct {
+	min, max float64
+	log      bool
+	length   float64
+}
+
+func newAxis(values []float64, length float64, fromZero bool) axis {
+	a := axis{min: slices.Min(values), max: slices.Max(values), length: length}
+	if a.min > 0 && a.max/a.min >= 100 {
+		a.log = true
+		a.min = math.Pow(10, math.Floor(math.Log10(a.min)))
+		a.max = math.Pow(10, math.Ceil(math.Log10(a.max)))
+		return a
+	}
+	if fromZero {
+		a.min = min(a.min, 0)
+	}
+	if a.max == a.min {
+		a.max = a.min + 1
+	}
+	return a
+}
+
+// position is how far along the axis a value lies
+func (a axis) position(v float64) float64 {
+	if a.log {
+		return (math.Log10(v) - math.Log10(a.min)) / (math.Log10(a.max) - math.Log10(a.min)) * a.length
+	}
+	return (v - a.min) / (a.max - a.min) * a.length
+}
+
+// ticks are the values labelled along the axis, powers of ten on a log scale and round numbers otherwise
+func (a axis) ticks() []float64 {
+	ticks := []float64{}
+	if a.log {
+		for v := a.min; v <= a.max*1.0001; v *= 10 {
+			ticks = append(ticks, v)
+		}
+		return ticks
+	}
+	step := math.Pow(10, math.Floor(math.Log10((a.max-a.min)/5)))
+	for _, multiple := range []float64{1, 2, 5, 10} {
+		if (a.max-a.min)/(step*multiple) <= 6 {
+			step *= multiple
+			break
+		}
+	}
+	for v := math.Ceil(a.min/step) * step; v <= a.max+step/1e6; v += step {
+		ticks = append(ticks, v)
+	}
+	return ticks
+}
+
+func shortest(v float64) string {
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+func (c Chart) formatY() func(float64) string {
+	if c.FormatY == nil {
+		return shortest
+	}
+	return c.FormatY
+}
+
+// svgWriter collects the first write error so drawing code needn't check each one
+type svgWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (s *svgWriter) printf(format string, args ...any) {
+	if s.err == nil {
+		_, s.err = fmt.Fprintf(s.w, format, args...)
+	}
+}
+
+func (s *svgWriter) header(c Chart) {
+	s.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif" font-size="12">`+"\n",
+		width, height, width, height)
+	s.printf(`<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+	s.printf(`<text x="%d" y="28" font-size="16" font-weight="bold">%s</text>`+"\n", left, html.EscapeString(c.Title))
+	s.printf(`<text x="%d" y="%d" text-anchor="middle">%s</text>`+"\n", left+plotWidth/2, height-15, html.EscapeString(c.XLabel))
+	s.printf(`<text transform="translate(20 %d) rotate(-90)" text-anchor="middle">%s</text>`+"\n", top+plotHeight/2, html.EscapeString(c.YLabel))
+}
+
+func (s *svgWriter) yAxis(y axis, format func(float64) string) {
+	for _, tick := range y.ticks() {
+		py := float64(top+plotHeight) - y.position(tick)
+		s.printf(`<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#e5e5e5"/>`+"\n", left, py, left+plotWidth, py)
+		s.printf(`<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%s</text>`+"\n", left-6, py, html.EscapeString(format(tick)))
+	}
+	s.printf(`<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#333"/>`+"\n", left, top, left, top+plotHeight)
+	s.printf(`<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#333"/>`+"\n", left, top+plotHeight, left+plotWidth, top+plotHeight)
+}
+
+func (s *svgWriter) legend(i int, name string) {
+	y := top + 10 + i*20
+	s.printf(`<rect x="%d" y="%d" width="12" height="12" fill="%s"/>`+"\n", left+plotWidth+20, y-6, palette[i%len(palette)])
+	s.printf(`<text x="%d" y="%d" dominant-baseline="middle">%s</text>`+"\n", left+plotWidth+38, y, html.EscapeString(name))
+}
+
+// Line draws each series as a line through its points, in order of x
+func Line(w io.Writer, c Chart) error {
+	xs, ys := []float64{}, []float64{}
+	for _, series := range c.Series {
+		xs, ys = append(xs, series.X...), append(ys, series.Y...)
+	}
+	if len(xs) == 0 {
+		return fmt.Errorf("nothing to plot")
+	}
+	format := c.formatY()
+	x := newAxis(xs, plotWidth, false)
+	y := newAxis(ys, plotHeight, true)
+
+	s := &svgWriter{w: bufio.NewWriter(w)}
+	s.header(c)
+	s.yAxis(y, format)
+	for _, tick := range x.ticks() {
+		px := float64(left) + x.position(tick)
+		s.printf(`<text x="%.1f" y="%d" text-anchor="middle">%s</text>`+"\n", px, top+plotHeight+18, shortest(tick))
+	}
+
+	for i, series := range c.Series {
+		order := make([]int, len(series.X))
+		for j := range order {
+			order[j] = j
+		}
+		slices.SortFunc(order, func(a, b int) int { return cmp.Compare(series.X[a], series.X[b]) })
+
+		color := palette[i%len(palette)]
+		points := ""
+		for _, j := range order {
+			px, py := float64(left)+x.position(series.X[j]), float64(top+plotHeight)-y.position(series.Y[j])
+			points += fmt.Sprintf("%.1f,%.1f ", px, py)
+			s.printf(`<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s at %s: %s</title></circle>`+"\n",
+				px, py, color, html.EscapeString(series.Name), shortest(series.X[j]), html.EscapeString(format(series.Y[j])))
+		}
+		s.printf(`<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`+"\n", points, color)
+		s.legend(i, series.Name)
+	}
+	s.printf("</svg>\n")
+	if s.err != nil {
+		return s.err
+	}
+	return s.w.Flush()
+}
+
+// Bar draws a bar for the first point of each series, for results of a single run
+func Bar(w io.Writer, c Chart) error {
+	values := []float64{}
+	for _, series := range c.Series {
+		if len(series.Y) == 0 {
+			return fmt.Errorf("%s has no value to plot", series.Name)
+		}
+		values = append(values, series.Y[0])
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("nothing to plot")
+	}
+	format := c.formatY()
+	y := newAxis(values, plotHeight, true)
+
+	s := &svgWriter{w: bufio.NewWriter(w)}
+	s.header(c)
+	s.yAxis(y, format)
+
+	slot := float64(plotWidth) / float64(len(values))
+	for i, v := range values {
+		color := palette[i%len(palette)]
+		h := y.position(v)
+		x := float64(left) + slot*float64(i) + slot*0.15
+		s.printf(`<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %s</title></rect>`+"\n",
+			x, float64(top+plotHeight)-h, slot*0.7, h, color, html.EscapeString(c.Series[i].Name), html.EscapeString(format(v)))
+		s.legend(i, c.Series[i].Name)
+	}
+	s.printf("</svg>\n")
+	if s.err != nil {
+		return s.err
+	}
+	return s.w.Flush()
+}
+
+// WriteFile draws the chart to a new file at path, as a line chart when any series has more than one point and as a
+// bar chart otherwise
+func WriteFile(path string, c Chart) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	draw := Bar
+	if slices.ContainsFunc(c.Series, func(s Series) bool { return len(s.X) > 1 }) {
+		draw = Line
+	}
+	if err := draw(f, c); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,56 @@
+package plot
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAxis(t *testing.T) {
+	linear := newAxis([]float64{3, 47}, 100, true)
+	if linear.log || linear.min != 0 {
+		t.Errorf("values 3 to 47 gave a log scale %v from %v, want linear from 0", linear.log, linear.min)
+	}
+	if ticks := linear.ticks(); !slices.Equal(ticks, []float64{0, 10, 20, 30, 40}) {
+		t.Errorf("linear ticks are %v", ticks)
+	}
+
+	log := newAxis([]float64{1000, 1e6}, 100, true)
+	if !log.log {
+		t.Fatal("values three orders of magnitude apart didn't switch to a log scale")
+	}
+	if ticks := log.ticks(); !slices.Equal(ticks, []float64{1e3, 1e4, 1e5, 1e6}) {
+		t.Errorf("log ticks are %v", ticks)
+	}
+	if p := log.position(1e6); p != 100 {
+		t.Errorf("the largest value sits at %v, want the end of the axis", p)
+	}
+}
+
+func TestLine(t *testing.T) {
+	var buf bytes.Buffer
+	chart := Chart{Title: "a < b", Series: []Series{
+		{Name: "linear", X: []float64{4, 1, 2}, Y: []float64{4, 1, 2}},
+		{Name: "flat", X: []float64{1, 2, 4}, Y: []float64{1, 1, 1}},
+	}}
+	if err := Line(&buf, chart); err != nil {
+		t.Fatalf("drawing failed: %v", err)
+	}
+	if n := strings.Count(buf.String(), "<polyline"); n != 2 {
+		t.Errorf("drew %d lines, want 2", n)
+	}
+	// the title is escaped, so the chart stays well formed XML
+	decoder := xml.NewDecoder(&buf)
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Errorf("the chart isn't well formed: %v", err)
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Package progress remembers what each student has done, kept in a JSON file in the user's config directory so it
+// survives between runs of teachgo
+package progress
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+)
+
+// PathEnv overrides where the progress file is kept, e.g. to keep a class's progress on a shared drive
+const PathEnv = "TEACHGO_PROGRESS"
+
+// Path is where the progress file is kept, $TEACHGO_PROGRESS or teachgo/progress.json in the user's config directory
+func Path() (string, error) {
+	if path := os.Getenv(PathEnv); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("no config directory to keep progress in, set %s: %w", PathEnv, err)
+	}
+	return filepath.Join(dir, "teachgo", "progress.json"), nil
+}
+
+// StudentEnv names the student progress is recorded for, students sharing a machine and its user account each set it
+const StudentEnv = "TEACHGO_STUDENT"
+
+// DefaultStudent is the name progress is recorded under when the student doesn't give one, $TEACHGO_STUDENT or else
+// their user name
+func DefaultStudent() string {
+	if name := os.Getenv(StudentEnv); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "student"
+}
+
+// File is everything in the progress file, one record per student so a shared machine keeps them apart
+type File struct {
+	Students map[string]*Student `json:"students"`
+}
+
+// Student is one student's progress
+type Student struct {
+	// LastLesson is the lesson the student ran most recently, the one teachgo quiz asks about by default
+	LastLesson string                    `json:"last_lesson,omitempty"`
+	Lessons    map[string]LessonRecord   `json:"lessons,omitempty"`
+	Quizzes    map[string]QuizResult     `json:"quizzes,omitempty"`
+	Exercises  map[string]ExerciseRecord `json:"exercises,omitempty"`
+}
+
+// LessonRecord is how often a student has run a lesson
+type LessonRecord struct {
+	Runs  int       `json:"runs"`
+	First time.Time `json:"first"`
+	Last  time.Time `json:"last"`
+}
+
+// ExerciseRecord is how a student has got on with an exercise, Passed stays set once every check has passed
+type ExerciseRecord struct {
+	Attempts int       `json:"attempts"`
+	Checks   int       `json:"checks"`
+	Best     int       `json:"best"`
+	Passed   bool      `json:"passed"`
+	At       time.Time `json:"at"`
+}
+
+// QuizResult is how a student has done on a lesson's quiz, over every attempt
+type QuizResult struct {
+	Best     int       `json:"best"`
+	Last     int       `json:"last"`
+	Total    int       `json:"total"`
+	Attempts int       `json:"attempts"`
+	At       time.Time `json:"at"`
+}
+
+// Load reads the progress file, a file that doesn't exist yet is empty progress
+func Load(path string) (*File, error) {
+	f := &File{Students: map[string]*Student{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("%s is corrupt, move it aside to start over: %w", path, err)
+	}
+	if f.Students == nil {
+		f.Students = map[string]*Student{}
+	}
+	return f, nil
+}
+
+// Save writes the progress file, through a temporary file renamed over it so an interrupted save never leaves half
+// a file behind
+func (f *File) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Student returns a student's record, creating it the first time they're seen
+func (f *File) Student(name string) *Student {
+	s, ok := f.Students[name]
+	if !ok {
+		s = &Student{}
+		f.Students[name] = s
+	}
+	return s
+}
+
+// RecordLesson adds a run of a lesson
+func (s *Student) RecordLesson(lesson string, at time.Time) {
+	if s.Lessons == nil {
+		s.Lessons = map[string]LessonRecord{}
+	}
+	record := s.Lessons[lesson]
+	if record.Runs == 0 {
+		record.First = at
+	}
+	record.Runs++
+	record.Last = at
+	s.Lessons[lesson] = record
+	s.LastLesson = lesson
+}
+
+// RecordExercise adds an attempt at an exercise, passing checks out of total
+func (s *Student) RecordExercise(exercise string, passed, total int, at time.Time) {
+	if s.Exercises == nil {
+		s.Exercises = map[string]ExerciseRecord{}
+	}
+	record := s.Exercises[exercise]
+	record.Attempts++
+	record.Checks = total
+	record.Best = max(record.Best, passed)
+	record.Passed = record.Passed || passed == total
+	record.At = at
+	s.Exercises[exercise] = record
+}
+
+// QuizPassMark is the fraction of a quiz a student has to answer correctly to pass it
+const QuizPassMark = 0.8
+
+// PassedQuiz reports whether the student's best attempt at a lesson's quiz reached the pass mark
+func (s *Student) PassedQuiz(lesson string) bool {
+	result, ok := s.Quizzes[lesson]
+	return ok && result.Total > 0 && float64(result.Best) >= QuizPassMark*float64(result.Total)
+}
+
+// RecordQuiz adds an attempt at a lesson's quiz, scoring correct out of total
+func (s *Student) RecordQuiz(lesson string, correct, total int, at time.Time) {
+	if s.Quizzes == nil {
+		s.Quizzes = map[string]QuizResult{}
+	}
+	result := s.Quizzes[lesson]
+	result.Best = max(result.Best, correct)
+	result.Last = correct
+	result.Total = total
+	result.Attempts++
+	result.At = at
+	s.Quizzes[lesson] = result
+}
+
+// Update loads the progress file, applies fn to the student's record and saves it again
+func Update(student string, fn func(*Student)) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	f, err := Load(path)
+	if err != nil {
+		return err
+	}
+	fn(f.Student(student))
+	return f.Save(path)
+}
@@ -0,0 +1,50 @@
+package progress
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teachgo", "progress.json")
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("loading a missing file failed: %v", err)
+	}
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := f.Student("ada")
+	s.RecordLesson("counters", at)
+	s.RecordExercise("sharded-counter", 2, 4, at)
+	s.RecordExercise("sharded-counter", 4, 4, at)
+	s.RecordExercise("sharded-counter", 1, 4, at)
+	s.RecordQuiz("counters", 4, 6, at)
+	s.RecordQuiz("counters", 3, 6, at)
+	if err := f.Save(path); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	f, err = Load(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	got := f.Student("ada")
+	want := QuizResult{Best: 4, Last: 3, Total: 6, Attempts: 2, At: at}
+	if got.LastLesson != "counters" || got.Quizzes["counters"] != want {
+		t.Errorf("loaded %+v, want the last lesson counters and quiz result %+v", got, want)
+	}
+	if !got.Exercises["sharded-counter"].Passed || got.Exercises["sharded-counter"].Attempts != 3 {
+		t.Errorf("loaded exercise %+v, want it passed over 3 attempts", got.Exercises["sharded-counter"])
+	}
+	if got.PassedQuiz("counters") {
+		t.Error("a best of 4 of 6 passed the quiz, want it short of the pass mark")
+	}
+	got.RecordQuiz("counters", 5, 6, at)
+	if !got.PassedQuiz("counters") {
+		t.Error("a best of 5 of 6 didn't pass the quiz")
+	}
+	if len(f.Students) != 1 {
+		t.Errorf("loaded %d students, want 1", len(f.Students))
+	}
+}
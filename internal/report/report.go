@@ -0,0 +1,210 @@
+// Package report renders benchmark results as a single self-contained HTML file of bar charts, the charts are inline
+// SVG and the styling inline CSS, so the file opens in any browser, offline, and can be mailed or projected as is
+package report
+
+import (
+	"html/template"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Report is one page, the settings the run was made with followed by its charts in order
+type Report struct {
+	Title    string
+	Settings []Setting
+	Charts   []Chart
+	// Notes are free text paragraphs printed under the charts
+	Notes []string
+}
+
+// Setting is one name and value from the run's configuration
+type Setting struct {
+	Name  string
+	Value string
+}
+
+// Chart is a horizontal bar chart, a bar per group, or a cluster of bars per group when there are several series
+type Chart struct {
+	Title string
+	// Series names the values within each group, a chart of one series needs none
+	Series []string
+	Groups []Group
+	// Format labels each bar with its value, plain numbers when nil
+	Format func(float64) string
+}
+
+// Group is one labelled row of the chart holding a value for each series
+type Group struct {
+	Label  string
+	Values []float64
+}
+
+// Bars is the common case of a chart with a single series, one bar per label
+func Bars(title string, format func(float64) string, labels []string, values []float64) Chart {
+	chart := Chart{Title: title, Format: format}
+	for i, label := range labels {
+		chart.Groups = append(chart.Groups, Group{Label: label, Values: []float64{values[i]}})
+	}
+	return chart
+}
+
+// Nanoseconds formats a value in nanoseconds as a duration, for charts of timings
+func Nanoseconds(ns float64) string {
+	return time.Duration(ns).String()
+}
+
+// Write renders the report to a new file at path
+func Write(path string, r Report) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := Render(f, r); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Render writes the report as HTML
+func Render(w io.Writer, r Report) error {
+	page := pageView{Title: r.Title, Settings: r.Settings, Notes: r.Notes}
+	for _, chart := range r.Charts {
+		page.Charts = append(page.Charts, layout(chart))
+	}
+	return pageTemplate.Execute(w, page)
+}
+
+// the chart geometry, in SVG user units
+const (
+	labelWidth = 200
+	barsWidth  = 560
+	valueWidth = 120
+	barHeight  = 18
+	groupGap   = 10
+	legendRow  = 24
+)
+
+// palette colours the series in order, repeating when there are more series than colours
+var palette = []string{"#4e79a7", "#f28e2b", "#59a14f", "#e15759", "#76b7b2", "#edc948", "#b07aa1"}
+
+type pageView struct {
+	Title    string
+	Settings []Setting
+	Charts   []chartView
+	Notes    []string
+}
+
+type chartView struct {
+	Title  string
+	Width  int
+	Height int
+	Legend []legendView
+	Groups []groupView
+}
+
+type legendView struct {
+	Name  string
+	Color string
+	X     int
+}
+
+type groupView struct {
+	Label  string
+	Y      int
+	LabelY int
+	Bars   []barView
+}
+
+type barView struct {
+	Y      int
+	Width  int
+	Color  string
+	Value  string
+	ValueX int
+	TextY  int
+}
+
+// layout works out the position of every bar, all of them scaled against the largest value in the chart
+func layout(chart Chart) chartView {
+	format := chart.Format
+	if format == nil {
+		format = func(v float64) string { return strconv.FormatFloat(v, 'g', 4, 64) }
+	}
+
+	largest := 0.0
+	for _, group := range chart.Groups {
+		for _, value := range group.Values {
+			largest = max(largest, value)
+		}
+	}
+
+	view := chartView{Title: chart.Title, Width: labelWidth + barsWidth + valueWidth}
+	y := 0
+	if len(chart.Series) > 1 {
+		for i, name := range chart.Series {
+			view.Legend = append(view.Legend, legendView{Name: name, Color: palette[i%len(palette)], X: labelWidth + i*110})
+		}
+		y = legendRow
+	}
+
+	for _, group := range chart.Groups {
+		g := groupView{Label: group.Label, Y: y, LabelY: y + len(group.Values)*barHeight/2 + 5}
+		for i, value := range group.Values {
+			width := 0
+			if largest > 0 {
+				width = max(int(value/largest*barsWidth), 1)
+			}
+			g.Bars = append(g.Bars, barView{
+				Y:      y,
+				Width:  width,
+				Color:  palette[i%len(palette)],
+				Value:  format(value),
+				ValueX: labelWidth + width + 6,
+				TextY:  y + barHeight - 5,
+			})
+			y += barHeight
+		}
+		view.Groups = append(view.Groups, g)
+		y += groupGap
+	}
+	view.Height = y
+	return view
+}
+
+var pageTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"labelWidth": func() int { return labelWidth },
+	"barHeight":  func() int { return barHeight - 2 },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 920px; color: #222; }
+h1 { font-size: 1.6em; }
+h2 { font-size: 1.15em; margin-top: 2em; }
+table { border-collapse: collapse; }
+td { padding: 2px 16px 2px 0; }
+td:first-child { color: #666; }
+svg text { font-size: 12px; fill: #222; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Settings}}<table>
+{{range .Settings}}<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>
+{{end}}{{range .Charts}}<h2>{{.Title}}</h2>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}">
+{{range .Legend}}<rect x="{{.X}}" y="2" width="12" height="12" fill="{{.Color}}"/><text x="{{.X}}" dx="16" y="13">{{.Name}}</text>
+{{end}}{{range .Groups}}<text x="{{labelWidth}}" dx="-8" y="{{.LabelY}}" text-anchor="end">{{.Label}}</text>
+{{range .Bars}}<rect x="{{labelWidth}}" y="{{.Y}}" width="{{.Width}}" height="{{barHeight}}" fill="{{.Color}}"/><text x="{{.ValueX}}" y="{{.TextY}}">{{.Value}}</text>
+{{end}}{{end}}</svg>
+{{end}}{{range .Notes}}<p>{{.}}</p>
+{{end}}</body>
+</html>
+`))
@@ -0,0 +1,41 @@
+package report
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	r := Report{
+		Title: "Counters <compared>",
+		Charts: []Chart{
+			Bars("Throughput", nil, []string{"Mutex", "Atomic"}, []float64{50, 100}),
+			{
+				Title:  "Percentiles",
+				Series: []string{"p50", "p99"},
+				Groups: []Group{{Label: "Mutex", Values: []float64{10, 40}}},
+				Format: Nanoseconds,
+			},
+		},
+	}
+
+	var out strings.Builder
+	if err := Render(&out, r); err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	page := out.String()
+
+	for _, want := range []string{
+		"Counters &lt;compared&gt;",
+		// the largest value spans the whole bar area and the others are scaled against it
+		`width="560"`,
+		`width="280"`,
+		// a chart with several series gets a legend and labels values with its formatter
+		">p99</text>",
+		">40ns</text>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("the report is missing %s", want)
+		}
+	}
+}
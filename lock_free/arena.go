@@ -0,0 +1,150 @@
+package lockfree
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrArenaFull is returned by ArenaStack.Push when every node is in use
+var ErrArenaFull = errors.New("every node in the arena is in use")
+
+// arenaNode is a node of an ArenaStack, its fields are atomic because a pop may read a node another goroutine has
+// just been given back and is filling in, the read is stale and the pop's swap is meant to fail
+type arenaNode struct {
+	value atomic.Int64
+	next  atomic.Uint32
+}
+
+// ArenaStack is a Treiber stack over a fixed arena of nodes that it reuses, as a stack has to in a language without
+// a garbage collector, or one avoiding the garbage, nodes are numbered from 1, 0 is the end of the stack
+// head packs the top node's number into its low 32 bits, and when the stack is tagged, a count of the swaps that
+// have changed it into the high 32, untagged the count stays 0
+// Untagged it's open to ABA, a pop reads head A and the node after it B, then before it swaps, other goroutines pop
+// A and B and push A again, reusing its node, head is A once more, so the swap from A to B succeeds, putting B,
+// which is free, back on top, with a tag the head is A with a different count and the swap fails as it should
+// The free list is a queue under a mutex, only the stack is lock free, reusing nodes in the order they were freed
+// is what lets a pop's node come back while the one after it is still free
+type ArenaStack struct {
+	head   atomic.Uint64
+	nodes  []arenaNode
+	tagged bool
+
+	mu   sync.Mutex
+	free []uint32
+}
+
+// NewArenaStack creates a stack of up to size items, tagged or not
+func NewArenaStack(size int, tagged bool) *ArenaStack {
+	s := &ArenaStack{nodes: make([]arenaNode, size+1), tagged: tagged, free: make([]uint32, 0, size)}
+	for i := 1; i <= size; i++ {
+		s.free = append(s.free, uint32(i))
+	}
+	return s
+}
+
+func pack(index uint32, tag uint32) uint64 {
+	return uint64(tag)<<32 | uint64(index)
+}
+
+func unpack(head uint64) (index uint32, tag uint32) {
+	return uint32(head), uint32(head >> 32)
+}
+
+// swap is the head after old with index on top, counting the change if the stack is tagged
+func (s *ArenaStack) swap(old uint64, index uint32) uint64 {
+	_, tag := unpack(old)
+	if s.tagged {
+		tag++
+	}
+	return pack(index, tag)
+}
+
+func (s *ArenaStack) alloc() (uint32, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.free) == 0 {
+		return 0, false
+	}
+	i := s.free[0]
+	s.free = s.free[1:]
+	return i, true
+}
+
+func (s *ArenaStack) release(i uint32) {
+	s.mu.Lock()
+	s.free = append(s.free, i)
+	s.mu.Unlock()
+}
+
+// Push puts v on top, in a node from the free list
+func (s *ArenaStack) Push(v int64) error {
+	i, ok := s.alloc()
+	if !ok {
+		return ErrArenaFull
+	}
+	n := &s.nodes[i]
+	n.value.Store(v)
+	for {
+		old := s.head.Load()
+		top, _ := unpack(old)
+		n.next.Store(top)
+		if s.head.CompareAndSwap(old, s.swap(old, i)) {
+			return nil
+		}
+	}
+}
+
+// Pop takes the top value off, giving its node back to the free list
+func (s *ArenaStack) Pop() (int64, bool) {
+	for {
+		p, ok := s.beginPop()
+		if !ok {
+			return 0, false
+		}
+		if s.commitPop(p) {
+			return p.value, true
+		}
+	}
+}
+
+// pendingPop is a pop between reading the head and swapping it, split out so the lesson can interleave another
+// goroutine's operations between the two, the only way ABA can happen
+type pendingPop struct {
+	head  uint64
+	next  uint32
+	value int64
+}
+
+// beginPop reads the head and what a pop would need to take it off, the value has to be read now, once the swap
+// succeeds the node may already be reused
+func (s *ArenaStack) beginPop() (pendingPop, bool) {
+	old := s.head.Load()
+	top, _ := unpack(old)
+	if top == 0 {
+		return pendingPop{}, false
+	}
+	n := &s.nodes[top]
+	return pendingPop{head: old, next: n.next.Load(), value: n.value.Load()}, true
+}
+
+// commitPop swaps the head to the node after the one read, reporting whether the head was still what was read
+func (s *ArenaStack) commitPop(p pendingPop) bool {
+	if !s.head.CompareAndSwap(p.head, s.swap(p.head, p.next)) {
+		return false
+	}
+	top, _ := unpack(p.head)
+	s.release(top)
+	return true
+}
+
+// Values is everything on the stack from the top, stopping at size values in case ABA has linked it into a cycle,
+// it's only meant for a stack no one is changing
+func (s *ArenaStack) Values() []int64 {
+	values := []int64{}
+	top, _ := unpack(s.head.Load())
+	for i := top; i != 0 && len(values) < len(s.nodes)-1; i = s.nodes[i].next.Load() {
+		values = append(values, s.nodes[i].value.Load())
+	}
+	return values
+}
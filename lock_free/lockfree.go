@@ -0,0 +1,163 @@
+package lockfree
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// node is one item of a stack or a queue, next is atomic because another goroutine may be reading it as it's set
+type node[T any] struct {
+	value T
+	next  atomic.Pointer[node[T]]
+}
+
+// TreiberStack is a lock free stack, head is the only shared word, and both Push and Pop read it, work out the new
+// head and compare and swap it in, starting over if another goroutine changed it in between
+// Nothing ever blocks, a goroutine that fails its swap only does so because another's succeeded, so the stack as a
+// whole always makes progress
+// The garbage collector is what makes it this simple, a popped node isn't reused while any goroutine still holds a
+// pointer to it, so the head can't change and change back under a swap, see ArenaStack for what happens without it
+type TreiberStack[T any] struct {
+	head atomic.Pointer[node[T]]
+}
+
+func (s *TreiberStack[T]) Push(v T) {
+	n := &node[T]{value: v}
+	for {
+		old := s.head.Load()
+		n.next.Store(old)
+		if s.head.CompareAndSwap(old, n) {
+			return
+		}
+	}
+}
+
+func (s *TreiberStack[T]) Pop() (T, bool) {
+	for {
+		old := s.head.Load()
+		if old == nil {
+			var zero T
+			return zero, false
+		}
+		if s.head.CompareAndSwap(old, old.next.Load()) {
+			return old.value, true
+		}
+	}
+}
+
+// MSQueue is Michael and Scott's lock free queue, a linked list that always starts with a dummy node, the one most
+// recently taken, so head and tail never need to change together
+// Enqueue links its node after the last with a swap on the last node's next, then swings tail to it, Dequeue swings
+// head to the node after the dummy, which becomes the new dummy, and its value is the one dequeued
+// Tail can lag a node behind, when an enqueue has linked its node but not yet swung tail, and any goroutine that
+// finds it lagging swings it on itself rather than waiting, which is what keeps the queue lock free
+type MSQueue[T any] struct {
+	head atomic.Pointer[node[T]]
+	tail atomic.Pointer[node[T]]
+}
+
+// NewMSQueue creates an empty queue, its head and tail both the dummy node
+func NewMSQueue[T any]() *MSQueue[T] {
+	q := &MSQueue[T]{}
+	dummy := &node[T]{}
+	q.head.Store(dummy)
+	q.tail.Store(dummy)
+	return q
+}
+
+func (q *MSQueue[T]) Enqueue(v T) {
+	n := &node[T]{value: v}
+	for {
+		tail := q.tail.Load()
+		next := tail.next.Load()
+		if tail != q.tail.Load() {
+			continue
+		}
+		if next != nil {
+			// tail is lagging, help the enqueue that linked next finish
+			q.tail.CompareAndSwap(tail, next)
+			continue
+		}
+		if tail.next.CompareAndSwap(nil, n) {
+			// if this swing fails another goroutine has already helped
+			q.tail.CompareAndSwap(tail, n)
+			return
+		}
+	}
+}
+
+func (q *MSQueue[T]) Dequeue() (T, bool) {
+	for {
+		head := q.head.Load()
+		tail := q.tail.Load()
+		next := head.next.Load()
+		if head != q.head.Load() {
+			continue
+		}
+		if next == nil {
+			var zero T
+			return zero, false
+		}
+		if head == tail {
+			// the queue isn't empty but tail still points at the dummy, swing it on before head passes it
+			q.tail.CompareAndSwap(tail, next)
+			continue
+		}
+		// the value has to be read before the swap, after it another dequeue may already have passed this node, and
+		// it can't be cleared after either, a dequeue that's about to lose its swap may be reading it
+		v := next.value
+		if q.head.CompareAndSwap(head, next) {
+			return v, true
+		}
+	}
+}
+
+// MutexStack is a slice guarded by a mutex, the stack the lock free one is measured against
+type MutexStack[T any] struct {
+	mu    sync.Mutex
+	items []T
+}
+
+func (s *MutexStack[T]) Push(v T) {
+	s.mu.Lock()
+	s.items = append(s.items, v)
+	s.mu.Unlock()
+}
+
+func (s *MutexStack[T]) Pop() (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var zero T
+	if len(s.items) == 0 {
+		return zero, false
+	}
+	v := s.items[len(s.items)-1]
+	s.items[len(s.items)-1] = zero
+	s.items = s.items[:len(s.items)-1]
+	return v, true
+}
+
+// MutexQueue is a slice guarded by a mutex, the queue the lock free one is measured against
+type MutexQueue[T any] struct {
+	mu    sync.Mutex
+	items []T
+}
+
+func (q *MutexQueue[T]) Enqueue(v T) {
+	q.mu.Lock()
+	q.items = append(q.items, v)
+	q.mu.Unlock()
+}
+
+func (q *MutexQueue[T]) Dequeue() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var zero T
+	if len(q.items) == 0 {
+		return zero, false
+	}
+	v := q.items[0]
+	q.items[0] = zero
+	q.items = q.items[1:]
+	return v, true
+}
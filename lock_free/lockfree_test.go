@@ -0,0 +1,102 @@
+package lockfree
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestTreiberStackIsLastInFirstOut(t *testing.T) {
+	s := &TreiberStack[int]{}
+	for i := range 5 {
+		s.Push(i)
+	}
+	for want := 4; want >= 0; want-- {
+		if got, ok := s.Pop(); !ok || got != want {
+			t.Fatalf("popped %d, %v, want %d", got, ok, want)
+		}
+	}
+	if _, ok := s.Pop(); ok {
+		t.Fatal("popped from an empty stack")
+	}
+}
+
+func TestMSQueueIsFirstInFirstOut(t *testing.T) {
+	q := NewMSQueue[int]()
+	for i := range 5 {
+		q.Enqueue(i)
+	}
+	for want := range 5 {
+		if got, ok := q.Dequeue(); !ok || got != want {
+			t.Fatalf("dequeued %d, %v, want %d", got, ok, want)
+		}
+	}
+	if _, ok := q.Dequeue(); ok {
+		t.Fatal("dequeued from an empty queue")
+	}
+}
+
+// TestConcurrentStress is the lesson's stress, meant to be run with -race, every structure has to give every value
+// out exactly once, and the queues each goroutine's values in order
+func TestConcurrentStress(t *testing.T) {
+	const goroutines, values = 8, 2000
+	for _, c := range containers(goroutines * values) {
+		r := stress(c, goroutines, values)
+		if r.Lost != 0 || r.Duplicated != 0 || r.OutOfOrder != 0 {
+			t.Errorf("%s %s lost %d, duplicated %d and reordered %d", c.name, c.kind, r.Lost, r.Duplicated, r.OutOfOrder)
+		}
+	}
+}
+
+func TestArenaStackReusesNodes(t *testing.T) {
+	s := NewArenaStack(2, true)
+	for round := range 3 {
+		if s.Push(int64(round)) != nil || s.Push(int64(round+10)) != nil {
+			t.Fatalf("round %d: push into an arena with room failed", round)
+		}
+		if err := s.Push(99); err != ErrArenaFull {
+			t.Fatalf("round %d: push into a full arena returned %v", round, err)
+		}
+		if got := s.Values(); !slices.Equal(got, []int64{int64(round + 10), int64(round)}) {
+			t.Fatalf("round %d: stack is %v", round, got)
+		}
+		s.Pop()
+		s.Pop()
+	}
+}
+
+func TestABA(t *testing.T) {
+	untagged := aba(false)
+	if !untagged.Swapped || !slices.Equal(untagged.Twice, []int64{2, 3}) || !slices.Equal(untagged.Lost, []int64{7}) {
+		t.Errorf("untagged: swapped %v, out twice %v, lost %v, want the swap to succeed, 2 and 3 twice and 7 lost",
+			untagged.Swapped, untagged.Twice, untagged.Lost)
+	}
+	tagged := aba(true)
+	if tagged.Swapped || len(tagged.Twice) != 0 || len(tagged.Lost) != 0 || !slices.Equal(tagged.Popped, []int64{3, 2, 7}) {
+		t.Errorf("tagged: swapped %v, popped %v, out twice %v, lost %v, want the swap to fail and 3, 2 and 7 popped",
+			tagged.Swapped, tagged.Popped, tagged.Twice, tagged.Lost)
+	}
+}
+
+func TestTaggedArenaStackUnderContention(t *testing.T) {
+	s := NewArenaStack(64, true)
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Go(func() {
+			for i := range 5000 {
+				if err := s.Push(int64(g*5000 + i)); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, ok := s.Pop(); !ok {
+					t.Error("popped from an empty stack after a push")
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+	if got := s.Values(); len(got) != 0 {
+		t.Fatalf("%d values left after every push was popped", len(got))
+	}
+}
@@ -0,0 +1,397 @@
+// Package lockfree is the lock free lesson, teachgo lockfree, a Treiber stack and a Michael-Scott queue built on
+// compare and swap, checked under concurrent load and timed against the same structures behind a mutex, and the ABA
+// problem played out step by step on a stack that reuses its nodes, with and without a tag
+package lockfree
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo lockfree -h, its first line is the summary teachgo help
+// lists
+const Description = `Lock free structures, a Treiber stack and a Michael-Scott queue, and the ABA problem
+
+Builds a stack and a queue with no locks, every change a compare and swap on an atomic.Pointer that starts over if
+another goroutine got in first, and the same stack and queue as slices behind a mutex. Stresses each with
+-stress-goroutines goroutines pushing and popping at once, checking that every value comes out exactly once, and
+that the queues keep each goroutine's values in order. Times each with every number of -goroutines doing -ops
+pushes and pops between them, counting the allocations. Then plays ABA out step by step on a stack that reuses its
+nodes from an arena, as one without a garbage collector must, a pop is interrupted between reading the head and
+swapping it while another goroutine pops two values and pushes a third into the first one's node, and shows the
+swap wrongly succeeding untagged, and failing as it should once the head carries a count of its changes.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "lockfree",
+		Description: Description,
+		Difficulty:  lesson.Advanced,
+		Topics:      []string{"lock free", "compare and swap", "atomics", "ABA problem", "stacks", "queues"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// container is a stack or a queue as the stress and the timing use it, put adds a value and take removes one
+type container struct {
+	name string
+	kind string
+	new  func() (put func(int), take func() (int, bool))
+}
+
+// containers are the structures compared, the arena stack with room for size values
+func containers(size int) []container {
+	return []container{
+		{"Treiber", "stack", func() (func(int), func() (int, bool)) {
+			s := &TreiberStack[int]{}
+			return s.Push, s.Pop
+		}},
+		{"Mutex", "stack", func() (func(int), func() (int, bool)) {
+			s := &MutexStack[int]{}
+			return s.Push, s.Pop
+		}},
+		{"Michael-Scott", "queue", func() (func(int), func() (int, bool)) {
+			q := NewMSQueue[int]()
+			return q.Enqueue, q.Dequeue
+		}},
+		{"Mutex", "queue", func() (func(int), func() (int, bool)) {
+			q := &MutexQueue[int]{}
+			return q.Enqueue, q.Dequeue
+		}},
+		{"Arena tagged", "stack", func() (func(int), func() (int, bool)) {
+			s := NewArenaStack(size, true)
+			return func(v int) {
+					if err := s.Push(int64(v)); err != nil {
+						panic(err)
+					}
+				}, func() (int, bool) {
+					v, ok := s.Pop()
+					return int(v), ok
+				}
+		}},
+	}
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Ops              int   `json:"ops"`
+	Goroutines       []int `json:"goroutines"`
+	StressGoroutines int   `json:"stress_goroutines"`
+	StressValues     int   `json:"stress_values"`
+}
+
+// StressResult is a structure pushed and popped by many goroutines at once, Lost and Duplicated count the values
+// that came out other than once, OutOfOrder the values a queue gave out ahead of an earlier one from the same
+// goroutine
+type StressResult struct {
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	Values     int    `json:"values"`
+	Lost       int    `json:"lost"`
+	Duplicated int    `json:"duplicated"`
+	OutOfOrder int    `json:"out_of_order"`
+}
+
+// TimingResult is ops pushes and pops shared among goroutines, Allocs is the heap allocations made
+type TimingResult struct {
+	Name       string  `json:"name"`
+	Kind       string  `json:"kind"`
+	Goroutines int     `json:"goroutines"`
+	ElapsedNs  float64 `json:"elapsed_ns"`
+	Allocs     uint64  `json:"allocs"`
+}
+
+// ABAResult is the interrupted pop played out on an arena stack, Popped is every value popped in order, Left what's
+// on the stack after, and AfterPush what's on it once one more value is pushed
+type ABAResult struct {
+	Tagged    bool    `json:"tagged"`
+	Swapped   bool    `json:"swapped"`
+	Popped    []int64 `json:"popped"`
+	Left      []int64 `json:"left"`
+	AfterPush []int64 `json:"after_push"`
+	Lost      []int64 `json:"lost"`
+	Twice     []int64 `json:"twice"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config RunConfig      `json:"config"`
+	Env    bench.Env      `json:"env"`
+	Stress []StressResult `json:"stress"`
+	Timing []TimingResult `json:"timing"`
+	ABA    []ABAResult    `json:"aba"`
+}
+
+// stress has goroutines each put values of their own, taking one out after every second, then drains what's left,
+// checking every value came out once, and for a queue, that each taker saw every goroutine's values in order
+func stress(c container, goroutines, values int) StressResult {
+	put, take := c.new()
+	result := StressResult{Name: c.name, Kind: c.kind, Values: goroutines * values}
+	taken := make([][]int, goroutines+1)
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Go(func() {
+			for i := range values {
+				put(g*values + i)
+				if i%2 == 1 {
+					if v, ok := take(); ok {
+						taken[g] = append(taken[g], v)
+					}
+				}
+			}
+		})
+	}
+	wg.Wait()
+	for v, ok := take(); ok; v, ok = take() {
+		taken[goroutines] = append(taken[goroutines], v)
+	}
+
+	seen := make([]int, goroutines*values)
+	for _, vs := range taken {
+		last := make([]int, goroutines)
+		for i := range last {
+			last[i] = -1
+		}
+		for _, v := range vs {
+			seen[v]++
+			if from := v / values; c.kind == "queue" && v < last[from] {
+				result.OutOfOrder++
+			} else {
+				last[from] = v
+			}
+		}
+	}
+	for _, n := range seen {
+		switch {
+		case n == 0:
+			result.Lost++
+		case n > 1:
+			result.Duplicated += n - 1
+		}
+	}
+	return result
+}
+
+// timing has goroutines share ops operations, each putting a value then taking one, so the structure stays small
+// and every operation is on the shared word or lock
+func timing(ctx context.Context, c container, goroutines, ops int) TimingResult {
+	put, take := c.new()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	elapsed := bench.Phase(ctx, fmt.Sprintf("%s %s with %d goroutines", c.name, c.kind, goroutines), func() {
+		var wg sync.WaitGroup
+		for g := range goroutines {
+			wg.Go(func() {
+				for i := g; i < ops/2; i += goroutines {
+					put(i)
+					take()
+				}
+			})
+		}
+		wg.Wait()
+	})
+	runtime.ReadMemStats(&after)
+	return TimingResult{Name: c.name, Kind: c.kind, Goroutines: goroutines, ElapsedNs: float64(elapsed.Nanoseconds()),
+		Allocs: after.Mallocs - before.Mallocs}
+}
+
+// aba plays the interrupted pop out, A reads the head of 3 on 2 on 1, B pops 3 and 2 and pushes 7, which takes the
+// node 3 was in, the first freed, then A swaps
+func aba(tagged bool) ABAResult {
+	result := ABAResult{Tagged: tagged}
+	s := NewArenaStack(3, tagged)
+	for _, v := range []int64{1, 2, 3} {
+		s.Push(v)
+	}
+	a, _ := s.beginPop()
+	for range 2 {
+		v, _ := s.Pop()
+		result.Popped = append(result.Popped, v)
+	}
+	s.Push(7)
+	result.Swapped = s.commitPop(a)
+	if result.Swapped {
+		result.Popped = append(result.Popped, a.value)
+	} else if v, ok := s.Pop(); ok {
+		result.Popped = append(result.Popped, v)
+	}
+	result.Left = s.Values()
+
+	counts := map[int64]int{}
+	for _, v := range append(slices.Clone(result.Popped), result.Left...) {
+		counts[v]++
+	}
+	for _, v := range []int64{1, 2, 3, 7} {
+		switch {
+		case counts[v] == 0:
+			result.Lost = append(result.Lost, v)
+		case counts[v] > 1:
+			result.Twice = append(result.Twice, v)
+		}
+	}
+	s.Push(8)
+	result.AfterPush = s.Values()
+	return result
+}
+
+// values formats a list of values, - for none
+func values(vs []int64) string {
+	if len(vs) == 0 {
+		return "-"
+	}
+	fields := make([]string, len(vs))
+	for i, v := range vs {
+		fields[i] = strconv.FormatInt(v, 10)
+	}
+	return strings.Join(fields, " ")
+}
+
+// perOp is elapsed divided over n operations
+func perOp(elapsedNs float64, n int) time.Duration {
+	if n == 0 {
+		return 0
+	}
+	return time.Duration(elapsedNs / float64(n))
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Lock Free Stacks and Queues")
+	fmt.Fprintln(w, "===========================")
+	fmt.Fprintf(w, "Ops: %d\n", config.Ops)
+	fmt.Fprintf(w, "Machine: %s\n", result.Env)
+
+	fmt.Fprintf(w, "\n=====%d goroutines putting %d values each and taking half as they go=====\n", config.StressGoroutines, config.StressValues)
+	fmt.Fprintf(w, "%-14s %-6s %10s %6s %11s %13s\n", "Structure", "Kind", "Values", "Lost", "Duplicated", "Out of order")
+	for _, r := range result.Stress {
+		fmt.Fprintf(w, "%-14s %-6s %10d %6d %11d %13d\n", r.Name, r.Kind, r.Values, r.Lost, r.Duplicated, r.OutOfOrder)
+	}
+
+	fmt.Fprintln(w, "\n=====Timing=====")
+	fmt.Fprintf(w, "%-14s %-6s %11s %10s %14s %10s\n", "Structure", "Kind", "Goroutines", "Time/op", "Throughput", "Allocs/op")
+	for _, r := range result.Timing {
+		fmt.Fprintf(w, "%-14s %-6s %11d %10v %14s %10.2f\n", r.Name, r.Kind, r.Goroutines, perOp(r.ElapsedNs, config.Ops),
+			bench.FormatRate(float64(config.Ops)/(r.ElapsedNs/1e9)), float64(r.Allocs)/float64(config.Ops))
+	}
+	fmt.Fprintln(w, "\nAn op is a push or a pop. The lock free structures allocate a node for every push and the arena stack")
+	fmt.Fprintln(w, "reuses its nodes, the mutex stack reuses its slice while the mutex queue slices its front off, so")
+	fmt.Fprintln(w, "appends keep needing a new array. Lock free isn't faster in itself, a swap that loses starts over just")
+	fmt.Fprintln(w, "as a goroutine that loses a lock waits, what it buys is that no goroutine descheduled halfway through an")
+	fmt.Fprintln(w, "operation can hold everyone else up")
+
+	fmt.Fprintln(w, "\n=====ABA, a pop interrupted on an arena stack of 3 on 2 on 1=====")
+	fmt.Fprintln(w, "A reads the head, 3, and the node under it, 2, and is interrupted before its swap")
+	fmt.Fprintln(w, "B pops 3 and 2, then pushes 7, which gets the node 3 was in, the first freed, so the head is that node again")
+	for _, r := range result.ABA {
+		name := "Untagged"
+		if r.Tagged {
+			name = "Tagged"
+		}
+		fmt.Fprintf(w, "\n%s:\n", name)
+		if r.Swapped {
+			fmt.Fprintln(w, "  A swaps the head from the node it read to 2's node, and it succeeds, the head is the same node")
+		} else {
+			fmt.Fprintln(w, "  A's swap fails, the head is the same node but its count has changed, so A starts over")
+		}
+		fmt.Fprintf(w, "  Popped: %s\n", values(r.Popped))
+		fmt.Fprintf(w, "  Left on the stack: %s\n", values(r.Left))
+		fmt.Fprintf(w, "  Out twice, popped or left: %s, lost: %s\n", values(r.Twice), values(r.Lost))
+		fmt.Fprintf(w, "  After pushing 8: %s\n", values(r.AfterPush))
+	}
+	fmt.Fprintln(w, "\nUntagged, A's swap put 2's node back on top though B had popped it and it was on the free list, so 8")
+	fmt.Fprintln(w, "was pushed into the node already on top, which now links to itself, the stack lists it until the arena")
+	fmt.Fprintln(w, "runs out. The Treiber stack can't do this, the garbage collector won't reuse a node A still points to")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo lockfree
+func Main(args []string) {
+	fs := bench.NewFlagSet("lockfree", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the lock free lesson measures a single run"
+	ops := fs.Int("ops", 1000000, "the pushes and pops timed on each structure with each number of goroutines")
+	goroutineList := fs.String("goroutines", "1,4,16", "comma separated numbers of goroutines to share the timed ops")
+	stressGoroutines := fs.Int("stress-goroutines", 8, "the goroutines pushing and popping at once in the stress")
+	stressValues := fs.Int("stress-values", 20000, "the values each goroutine pushes in the stress")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "lockfree measures a single run, -trials isn't supported")
+	v.AtLeast("ops", *ops, 2)
+	v.AtLeast("stress-goroutines", *stressGoroutines, 1)
+	v.AtLeast("stress-values", *stressValues, 1)
+	goroutines := []int{}
+	for _, field := range strings.Split(*goroutineList, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		v.Check(err == nil && n >= 1, "-goroutines must be whole numbers of at least 1, got %q", field)
+		goroutines = append(goroutines, n)
+	}
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	config := RunConfig{Ops: *ops, Goroutines: goroutines, StressGoroutines: *stressGoroutines, StressValues: *stressValues}
+	result := RunResult{Config: config, Env: bench.CaptureEnv()}
+	ctx := context.Background()
+	size := max(*stressGoroutines**stressValues, len(goroutines))
+	for _, c := range containers(size) {
+		slog.Info("stressing", "structure", c.name, "kind", c.kind)
+		result.Stress = append(result.Stress, stress(c, *stressGoroutines, *stressValues))
+	}
+	for _, c := range containers(size) {
+		for _, n := range goroutines {
+			slog.Info("timing", "structure", c.name, "kind", c.kind, "goroutines", n)
+			result.Timing = append(result.Timing, timing(ctx, c, n, *ops))
+		}
+	}
+	result.ABA = []ABAResult{aba(false), aba(true)}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per structure and number of goroutines, an op is one push or pop
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/lock_free"); err != nil {
+		return err
+	}
+	n := int64(result.Config.Ops)
+	benchmarks := []bench.Benchmark{}
+	for _, r := range result.Timing {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: fmt.Sprintf("%s/%s/goroutines=%d", strings.ReplaceAll(r.Name, " ", "-"), r.Kind, r.Goroutines),
+			N:    n,
+			Metrics: []bench.Metric{
+				bench.NsPerOp(r.ElapsedNs, n),
+				{Value: float64(r.Allocs) / float64(n), Unit: "allocs/op"},
+			},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
@@ -0,0 +1,63 @@
+package lockfree
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz lockfree, it replays the ABA interleaving against the arena, tagged and Treiber stacks
+// and asks what lock freedom does and doesn't promise, helping included
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does the untagged arena stack's swap succeed after B has popped two values and pushed a third?",
+		Choices: []string{
+			"B's pops and push happened on a copy of the head, so A's view of the stack is still current",
+			"The third value reused the first's node, so the head holds the node number A read",
+			"A compare and swap on a stack of three nodes always succeeds, whatever happened in between",
+			"A retried its swap in a loop until the head came back to the value it expected",
+		},
+		Answer:      1,
+		Explanation: "that's ABA, the head went from A to something else and back to A, and a compare and swap can't tell it ever changed",
+	},
+	{
+		Prompt: "Why does the tagged stack's swap fail in the same interleaving?",
+		Choices: []string{
+			"The head counts the swaps that changed it, so B's three leave it not matching A's copy",
+			"The tagged stack never reuses a node's slot, so the third value lands in a new node",
+			"The tag is a lock bit, and B's push holds it while A's swap is interrupted",
+			"The tag makes the swap compare the node's value as well as its number",
+		},
+		Answer:      0,
+		Explanation: "a 32 bit count could in principle wrap round to the same value while a pop is interrupted, but only after four billion changes",
+	},
+	{
+		Prompt: "Why can't the Treiber stack fall into the same trap?",
+		Choices: []string{
+			"Its pops take a mutex, so no other goroutine can change the head during one",
+			"Its swaps compare the values stored in the nodes rather than the pointers to them",
+			"Its head holds a generation count alongside the pointer, like the tagged stack",
+			"A new node per push can't be reused while A still holds a pointer to the old one",
+		},
+		Answer:      3,
+		Explanation: "the garbage collector won't reuse a node A still refers to, so the head can't come back to it, the collector does the job that hazard pointers or epochs do in languages without one, at the price of an allocation per push",
+	},
+	{
+		Prompt: "Why isn't the lock free stack faster than the mutex one in the timings?",
+		Choices: []string{
+			"Atomic operations are always slower than taking and releasing an uncontended mutex",
+			"The lesson runs the lock free stack with more goroutines, so it sees more contention",
+			"Both do a few atomic operations on one shared word, and Treiber also allocates per push",
+			"The lock free stack retries every swap at least once before it can succeed",
+		},
+		Answer:      2,
+		Explanation: "lock freedom is a progress guarantee, a goroutine descheduled mid operation can't hold the others up, not a promise of speed",
+	},
+	{
+		Prompt: "Why does a Michael-Scott dequeue help swing the tail on when it finds it lagging?",
+		Choices: []string{
+			"An enqueue links then swings the tail in two swaps, and waiting for it would be blocking",
+			"A lagging tail means the dummy node has to be removed before anything can be dequeued",
+			"The tail is guarded by a lock that only a dequeue is allowed to release",
+			"Swinging the tail in the dequeue saves the enqueue a swap, so the queue is faster",
+		},
+		Answer:      0,
+		Explanation: "helping is how lock free structures finish what a descheduled goroutine started, so no one has to wait for it",
+	},
+}
@@ -0,0 +1,228 @@
+package lrucache
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Cache is a fixed size cache, Put evicts an entry once the cache is full, each implementation choosing which
+type Cache[K comparable, V any] interface {
+	// Get returns a key's value, counting a hit or a miss
+	Get(key K) (V, bool)
+	Put(key K, value V)
+	Len() int
+	Stats() Stats
+}
+
+// Stats counts how a cache has been used, the hit rate is what a cache is judged by
+type Stats struct {
+	Hits      int
+	Misses    int
+	Evictions int
+}
+
+// HitRate is the fraction of lookups that found their key
+func (s Stats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// node is an entry of the LRU's recency list
+type node[K comparable, V any] struct {
+	key        K
+	value      V
+	prev, next *node[K, V]
+}
+
+// LRU evicts the least recently used entry, a map finds a key's node in O(1) and a doubly linked list keeps the
+// nodes in order of use, so moving a node to the front on every Get and dropping the one at the back on a full Put
+// are O(1) too
+type LRU[K comparable, V any] struct {
+	capacity int
+	items    map[K]*node[K, V]
+	// root is a sentinel, root.next is the most recently used node and root.prev the least, with it an empty list
+	// needs no special cases
+	root  node[K, V]
+	stats Stats
+}
+
+// NewLRU creates an LRU cache holding up to capacity entries
+func NewLRU[K comparable, V any](capacity int) *LRU[K, V] {
+	c := &LRU[K, V]{capacity: capacity, items: make(map[K]*node[K, V], capacity)}
+	c.root.next, c.root.prev = &c.root, &c.root
+	return c
+}
+
+func (c *LRU[K, V]) unlink(n *node[K, V]) {
+	n.prev.next, n.next.prev = n.next, n.prev
+}
+
+func (c *LRU[K, V]) pushFront(n *node[K, V]) {
+	n.prev, n.next = &c.root, c.root.next
+	c.root.next.prev = n
+	c.root.next = n
+}
+
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	n, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	c.unlink(n)
+	c.pushFront(n)
+	return n.value, true
+}
+
+func (c *LRU[K, V]) Put(key K, value V) {
+	if n, ok := c.items[key]; ok {
+		n.value = value
+		c.unlink(n)
+		c.pushFront(n)
+		return
+	}
+	if len(c.items) >= c.capacity {
+		oldest := c.root.prev
+		c.unlink(oldest)
+		delete(c.items, oldest.key)
+		c.stats.Evictions++
+	}
+	n := &node[K, V]{key: key, value: value}
+	c.items[key] = n
+	c.pushFront(n)
+}
+
+func (c *LRU[K, V]) Len() int {
+	return len(c.items)
+}
+
+func (c *LRU[K, V]) Stats() Stats {
+	return c.stats
+}
+
+// Keys lists the cached keys from the most recently used to the least
+func (c *LRU[K, V]) Keys() []K {
+	keys := make([]K, 0, len(c.items))
+	for n := c.root.next; n != &c.root; n = n.next {
+		keys = append(keys, n.key)
+	}
+	return keys
+}
+
+type stamped[V any] struct {
+	value V
+	used  uint64
+}
+
+// ScanCache is the naive way to evict the least recently used entry, a plain map stamping each entry with a tick
+// when it's used, and a scan of the whole map for the oldest stamp when something has to go
+// It evicts exactly what LRU does, so its hit rate is the same, but every eviction costs O(capacity)
+type ScanCache[K comparable, V any] struct {
+	capacity int
+	items    map[K]stamped[V]
+	tick     uint64
+	stats    Stats
+}
+
+// NewScanCache creates a scanning cache holding up to capacity entries
+func NewScanCache[K comparable, V any](capacity int) *ScanCache[K, V] {
+	return &ScanCache[K, V]{capacity: capacity, items: make(map[K]stamped[V], capacity)}
+}
+
+func (c *ScanCache[K, V]) Get(key K) (V, bool) {
+	e, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	c.tick++
+	e.used = c.tick
+	c.items[key] = e
+	return e.value, true
+}
+
+func (c *ScanCache[K, V]) Put(key K, value V) {
+	if _, ok := c.items[key]; !ok && len(c.items) >= c.capacity {
+		var oldest K
+		oldestUsed := uint64(math.MaxUint64)
+		for k, e := range c.items {
+			if e.used < oldestUsed {
+				oldest, oldestUsed = k, e.used
+			}
+		}
+		delete(c.items, oldest)
+		c.stats.Evictions++
+	}
+	c.tick++
+	c.items[key] = stamped[V]{value: value, used: c.tick}
+}
+
+func (c *ScanCache[K, V]) Len() int {
+	return len(c.items)
+}
+
+func (c *ScanCache[K, V]) Stats() Stats {
+	return c.stats
+}
+
+// RandomCache evicts an entry at random, as cheap as eviction gets and with no bookkeeping on a hit, it keeps the
+// keys in a slice as well as the map so one can be picked in O(1)
+type RandomCache[K comparable, V any] struct {
+	capacity int
+	items    map[K]int
+	keys     []K
+	values   []V
+	rng      *rand.Rand
+	stats    Stats
+}
+
+// NewRandomCache creates a randomly evicting cache holding up to capacity entries, the evictions drawn from seed
+func NewRandomCache[K comparable, V any](capacity int, seed int64) *RandomCache[K, V] {
+	return &RandomCache[K, V]{capacity: capacity, items: make(map[K]int, capacity), rng: rand.New(rand.NewSource(seed))}
+}
+
+func (c *RandomCache[K, V]) Get(key K) (V, bool) {
+	i, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	return c.values[i], true
+}
+
+func (c *RandomCache[K, V]) Put(key K, value V) {
+	if i, ok := c.items[key]; ok {
+		c.values[i] = value
+		return
+	}
+	if len(c.keys) >= c.capacity {
+		// the last entry moves into the victim's place so the slices stay packed
+		victim, last := c.rng.Intn(len(c.keys)), len(c.keys)-1
+		delete(c.items, c.keys[victim])
+		c.keys[victim], c.values[victim] = c.keys[last], c.values[last]
+		if victim != last {
+			c.items[c.keys[victim]] = victim
+		}
+		c.keys, c.values = c.keys[:last], c.values[:last]
+		c.stats.Evictions++
+	}
+	c.items[key] = len(c.keys)
+	c.keys = append(c.keys, key)
+	c.values = append(c.values, value)
+}
+
+func (c *RandomCache[K, V]) Len() int {
+	return len(c.keys)
+}
+
+func (c *RandomCache[K, V]) Stats() Stats {
+	return c.stats
+}
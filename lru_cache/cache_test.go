@@ -0,0 +1,73 @@
+package lrucache
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[string, int](3)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("c", 3)
+	c.Get("a")    // a is now the most recently used, b the least
+	c.Put("d", 4) // evicts b
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b is still cached, want it evicted as the least recently used")
+	}
+	if got := c.Keys(); !slices.Equal(got, []string{"d", "a", "c"}) {
+		t.Errorf("Keys() = %v, want [d a c], most recently used first", got)
+	}
+	c.Put("c", 30)
+	if v, ok := c.Get("c"); !ok || v != 30 {
+		t.Errorf("Get(c) = %d, %v, want the updated 30", v, ok)
+	}
+	if want := (Stats{Hits: 2, Misses: 1, Evictions: 1}); c.Stats() != want {
+		t.Errorf("Stats() = %+v, want %+v", c.Stats(), want)
+	}
+}
+
+func TestCachesStayWithinCapacity(t *testing.T) {
+	caches := map[string]Cache[int, int]{
+		"lru":    NewLRU[int, int](50),
+		"scan":   NewScanCache[int, int](50),
+		"random": NewRandomCache[int, int](50, 1),
+	}
+	for name, c := range caches {
+		rng := rand.New(rand.NewSource(1))
+		for range 10000 {
+			key := rng.Intn(200)
+			if v, ok := c.Get(key); ok && v != key {
+				t.Fatalf("%s: Get(%d) = %d, want the value put for it", name, key, v)
+			} else if !ok {
+				c.Put(key, key)
+			}
+			if c.Len() > 50 {
+				t.Fatalf("%s holds %d entries, over its capacity of 50", name, c.Len())
+			}
+		}
+		if stats := c.Stats(); stats.Hits+stats.Misses != 10000 || stats.Evictions != stats.Misses-50 {
+			t.Errorf("%s: Stats() = %+v, want 10000 lookups and an eviction for every miss after the first 50", name, stats)
+		}
+	}
+}
+
+// the scanning cache follows the same policy as the LRU, only more slowly, so it must hit on exactly the same lookups
+func TestScanCacheMatchesLRU(t *testing.T) {
+	lru, scan := NewLRU[int, int](100), NewScanCache[int, int](100)
+	rng := rand.New(rand.NewSource(2))
+	for i := range 20000 {
+		key := int(rng.ExpFloat64() * 100)
+		_, lruHit := lru.Get(key)
+		_, scanHit := scan.Get(key)
+		if lruHit != scanHit {
+			t.Fatalf("lookup %d of %d: LRU hit %v, scan hit %v", i, key, lruHit, scanHit)
+		}
+		if !lruHit {
+			lru.Put(key, key)
+			scan.Put(key, key)
+		}
+	}
+}
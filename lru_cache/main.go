@@ -0,0 +1,214 @@
+// Package lrucache is the LRU cache lesson, teachgo lru, a generic least recently used cache built from a map and a
+// doubly linked list, compared on hit rate and speed against naive caches under different access patterns
+package lrucache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/datagen"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo lru -h, its first line is the summary teachgo help lists
+const Description = `LRU cache with generics, hit rates under different access patterns
+
+Builds a generic least recently used cache from a map and a doubly linked list, then replays streams of lookups
+drawn from several distributions through it, filling the cache on each miss, alongside a map that scans for its
+oldest entry to evict and a map that evicts at random. Reports each cache's hit rate and time per lookup, showing
+when recency pays off, skewed traffic, and when it can't, a sequential scan bigger than the cache.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "lru",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"generics", "doubly linked lists", "caching", "eviction policies", "hit rate"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Capacity      int      `json:"capacity"`
+	Keys          int      `json:"keys"`
+	Ops           int      `json:"ops"`
+	Distributions []string `json:"distributions"`
+	Seed          int64    `json:"seed"`
+}
+
+// CacheResult is how one cache did on one stream of lookups
+type CacheResult struct {
+	Name      string  `json:"name"`
+	Hits      int     `json:"hits"`
+	Misses    int     `json:"misses"`
+	Evictions int     `json:"evictions"`
+	HitRate   float64 `json:"hit_rate"`
+	OpNs      float64 `json:"op_ns"`
+}
+
+// DistributionResult is every cache's result on the stream drawn from one distribution
+type DistributionResult struct {
+	Name   string        `json:"name"`
+	Caches []CacheResult `json:"caches"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config        RunConfig            `json:"config"`
+	Env           bench.Env            `json:"env"`
+	Distributions []DistributionResult `json:"distributions"`
+}
+
+// candidate is a cache under test, new builds an empty one so every cache sees the stream from the start
+type candidate struct {
+	name string
+	new  func() Cache[int, int]
+}
+
+func candidates(capacity int, seed int64) []candidate {
+	return []candidate{
+		{"LRU", func() Cache[int, int] { return NewLRU[int, int](capacity) }},
+		{"Map, evict oldest by scan", func() Cache[int, int] { return NewScanCache[int, int](capacity) }},
+		{"Map, evict at random", func() Cache[int, int] { return NewRandomCache[int, int](capacity, seed) }},
+	}
+}
+
+// replay looks every key up in the cache, putting it in on a miss as a read-through cache in front of something
+// slow would
+func replay(ctx context.Context, name string, cache Cache[int, int], stream []int) CacheResult {
+	elapsed := bench.Phase(ctx, name, func() {
+		for _, key := range stream {
+			if _, ok := cache.Get(key); !ok {
+				cache.Put(key, key)
+			}
+		}
+	})
+	stats := cache.Stats()
+	return CacheResult{
+		Hits:      stats.Hits,
+		Misses:    stats.Misses,
+		Evictions: stats.Evictions,
+		HitRate:   stats.HitRate(),
+		OpNs:      float64(elapsed.Nanoseconds()) / float64(max(len(stream), 1)),
+	}
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "LRU Cache Comparison")
+	fmt.Fprintln(w, "====================")
+	fmt.Fprintf(w, "Capacity: %d\nKeys: %d\nLookups per distribution: %d\n", config.Capacity, config.Keys, config.Ops)
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%-16s %-28s %9s %12s %10s\n", "Distribution", "Cache", "Hit rate", "Evictions", "Time/op")
+	for _, d := range result.Distributions {
+		for i, c := range d.Caches {
+			label := d.Name
+			if i > 0 {
+				label = ""
+			}
+			fmt.Fprintf(w, "%-16s %-28s %8.1f%% %12d %10v\n", label, c.Name, 100*c.HitRate, c.Evictions, time.Duration(c.OpNs))
+		}
+	}
+
+	fmt.Fprintf(w, "\nThe cache holds %.1f%% of the keys, about the hit rate any policy gets when every key is equally likely\n",
+		100*float64(config.Capacity)/float64(config.Keys))
+	fmt.Fprintln(w, "Skewed traffic, zipfian or gaussian, keeps its hot keys cached under LRU, random eviction throws them out")
+	fmt.Fprintln(w, "A sequential scan over more keys than fit evicts every key just before it comes round again, LRU's worst case")
+	fmt.Fprintln(w, "The scanning map evicts just what LRU does, the same hit rate, but searches every entry to find the oldest")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo lru
+func Main(args []string) {
+	fs := bench.NewFlagSet("lru", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the LRU lesson measures a single run"
+	capacity := fs.Int("capacity", 1000, "the number of entries each cache holds")
+	numKeys := fs.Int("keys", 10000, "the number of distinct keys the lookups are drawn from")
+	numOps := fs.Int("ops", 100000, "the number of lookups drawn from each distribution")
+	distributions := fs.String("dists", "uniform,zipfian,gaussian:0.05,sequential", "comma separated distributions to draw lookups from, each one of: "+datagen.Usage)
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "lru measures a single run, -trials isn't supported")
+	v.AtLeast("capacity", *capacity, 1)
+	v.AtLeast("keys", *numKeys, 1)
+	v.AtLeast("ops", *numOps, 1)
+	dists := []datagen.Distribution{}
+	if *numKeys >= 1 {
+		for _, spec := range strings.Split(*distributions, ",") {
+			d, err := datagen.Parse(strings.TrimSpace(spec), *numKeys)
+			v.Add(err)
+			dists = append(dists, d)
+		}
+	}
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	result := RunResult{
+		Config: RunConfig{Capacity: *capacity, Keys: *numKeys, Ops: *numOps, Seed: globals.Seed},
+		Env:    bench.CaptureEnv(),
+	}
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(globals.Seed))
+	for _, d := range dists {
+		result.Config.Distributions = append(result.Config.Distributions, d.String())
+		slog.Info("replaying lookups", "distribution", d, "ops", *numOps)
+		stream := d.Fill(rng, *numOps)
+
+		distResult := DistributionResult{Name: d.String()}
+		for _, c := range candidates(*capacity, globals.Seed) {
+			cacheResult := replay(ctx, c.name+" "+d.String(), c.new(), stream)
+			cacheResult.Name = c.name
+			distResult.Caches = append(distResult.Caches, cacheResult)
+		}
+		result.Distributions = append(result.Distributions, distResult)
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per cache and distribution, the hit rate alongside the time per lookup so
+// benchstat compares both
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/lru_cache"); err != nil {
+		return err
+	}
+	benchmarks := []bench.Benchmark{}
+	for _, d := range result.Distributions {
+		for _, c := range d.Caches {
+			benchmarks = append(benchmarks, bench.Benchmark{
+				Name:    fmt.Sprintf("Lookup/%s/dist=%s/capacity=%d", c.Name, d.Name, result.Config.Capacity),
+				N:       int64(result.Config.Ops),
+				Metrics: []bench.Metric{{Value: c.OpNs, Unit: "ns/op"}, {Value: c.HitRate, Unit: "hits/op"}},
+			})
+		}
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
@@ -0,0 +1,63 @@
+package lrucache
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz lru, it checks the map plus list design behind O(1) LRU, how the key distribution
+// decides whether any eviction policy helps, and what generics give the cache
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does the LRU cache need both a map and a doubly linked list?",
+		Choices: []string{
+			"The map holds the values and the list holds the keys, so each can be sized on its own",
+			"The list is a backup of the entries in case the map drops one while it is growing",
+			"The map holds the recent entries and the list the older ones, which are cheaper to keep",
+			"The map finds a key's node in O(1), the list keeps use order so moves and drops are O(1)",
+		},
+		Answer:      3,
+		Explanation: "either alone makes some operation O(n), a map has no order and a list has to be walked to find a key",
+	},
+	{
+		Prompt: "Why do all three caches hit about as often as the cache's share of the keys under the uniform distribution?",
+		Choices: []string{
+			"The uniform stream is too short to warm the caches, so most lookups are cold misses",
+			"When every key is equally likely, which keys are kept makes no difference, only how many",
+			"Uniform keys all hash into the same few buckets, so the caches keep evicting each other's",
+			"All three end up evicting at random, since uniform keys have no recency to go by",
+		},
+		Answer:      1,
+		Explanation: "an eviction policy only helps when some keys are more likely than others, a capacity of 10% of the keys hits about 10% of uniform lookups whatever it evicts",
+	},
+	{
+		Prompt: "Why does LRU hit nothing on the sequential stream when the keys outnumber the capacity?",
+		Choices: []string{
+			"Each key is evicted as least recently used just before the scan comes back to it",
+			"Sequential keys collide in the map, so each new one overwrites the one before",
+			"The cache treats a key it has never seen before as a one off and doesn't keep it",
+			"The stream clears the cache between passes so each pass starts from empty",
+		},
+		Answer:      0,
+		Explanation: "a loop over more data than fits is LRU's worst case, random eviction at least keeps the odd key by chance",
+	},
+	{
+		Prompt: "The scanning map evicts exactly what LRU evicts, so why is it slower?",
+		Choices: []string{
+			"It allocates a node for every entry it inserts, which LRU reuses",
+			"It hits less often, so more of its lookups fall through to the source",
+			"Each eviction searches every entry for the oldest stamp, O(capacity)",
+			"It has to rehash the whole map after each eviction to keep it compact",
+		},
+		Answer:      2,
+		Explanation: "the policy decides the hit rate, the data structure decides the cost, and a miss-heavy stream evicts on nearly every lookup",
+	},
+	{
+		Prompt: "What do the type parameters in LRU[K comparable, V any] buy over a cache of interface{} values?",
+		Choices: []string{
+			"Keys of different types can be stored side by side in one cache",
+			"Faster hashing, since the compiler picks a hash function for each key type",
+			"Type safety with no assertions or boxing, and K comparable lets keys index a map",
+			"Nothing at run time, they are checked by vet but erased before compiling",
+		},
+		Answer:      2,
+		Explanation: "the compiler checks every Get and Put, comparable is the constraint a map key needs, any accepts every value type, LRU[int, int] and LRU[string, []byte] share the one implementation",
+	},
+}
@@ -0,0 +1,298 @@
+package memorymodel
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// plainMailbox hands a message over with a plain bool, a data race, nothing orders the reader's loads after the
+// writer's stores, so the memory model lets the reader spin forever on a ready it loaded once, or see ready before
+// message
+type plainMailbox struct {
+	message int
+	ready   bool
+}
+
+// atomicMailbox hands a message over with an atomic.Bool, a load that sees the store synchronizes with it, so
+// everything written before the store, the message, is visible after the load
+type atomicMailbox struct {
+	message int
+	ready   atomic.Bool
+}
+
+// channelMailbox hands a message over by closing a channel, a receive that sees the close is ordered after it
+type channelMailbox struct {
+	message int
+	ready   chan struct{}
+}
+
+// Handover is how a spinning reader got on waiting for a message, Saw is whether it saw the flag within the
+// patience it was given, After how long from the reader starting, and Message what it read once it had
+type Handover struct {
+	Version string  `json:"version"`
+	Saw     bool    `json:"saw"`
+	AfterNs float64 `json:"after_ns"`
+	Message int     `json:"message"`
+}
+
+// handover starts reader spinning on a goroutine, sets the message a millisecond later, and waits patience for the
+// reader to see it, a reader that never does spins until the process exits, nothing can stop it
+func handover(version string, reader func() int, publish func(), patience time.Duration) Handover {
+	h := Handover{Version: version}
+	seen := make(chan int, 1)
+	start := time.Now()
+	go func() { seen <- reader() }()
+	time.Sleep(time.Millisecond)
+	publish()
+	select {
+	case h.Message = <-seen:
+		h.Saw, h.AfterNs = true, float64(time.Since(start))
+	case <-time.After(patience):
+	}
+	return h
+}
+
+// message is what every mailbox hands over
+const message = 42
+
+// handovers runs the three mailboxes
+func handovers(patience time.Duration) []Handover {
+	plain := &plainMailbox{}
+	atomicBox := &atomicMailbox{}
+	channelBox := &channelMailbox{ready: make(chan struct{})}
+	return []Handover{
+		handover("plain bool", func() int {
+			for !plain.ready {
+			}
+			return plain.message
+		}, func() {
+			plain.message = message
+			plain.ready = true
+		}, patience),
+		handover("atomic.Bool", func() int {
+			for !atomicBox.ready.Load() {
+			}
+			return atomicBox.message
+		}, func() {
+			atomicBox.message = message
+			atomicBox.ready.Store(true)
+		}, patience),
+		handover("closed channel", func() int {
+			<-channelBox.ready
+			return channelBox.message
+		}, func() {
+			channelBox.message = message
+			close(channelBox.ready)
+		}, patience),
+	}
+}
+
+// cells are the two variables of a store buffering test, reached through load and store so one test runs plain and
+// atomic
+type cells interface {
+	store(i int, v int32)
+	load(i int) int32
+	reset()
+}
+
+// plainCells are plain int32s, racing
+type plainCells [2]int32
+
+func (c *plainCells) store(i int, v int32) { c[i] = v }
+func (c *plainCells) load(i int) int32     { return c[i] }
+func (c *plainCells) reset()               { *c = plainCells{} }
+
+// atomicCells are atomic.Int32s, Go's atomics are sequentially consistent, every goroutine sees every atomic
+// operation in one order that agrees with each goroutine's program order
+type atomicCells [2]atomic.Int32
+
+func (c *atomicCells) store(i int, v int32) { c[i].Store(v) }
+func (c *atomicCells) load(i int) int32     { return c[i].Load() }
+func (c *atomicCells) reset() {
+	c[0].Store(0)
+	c[1].Store(0)
+}
+
+// storeBuffering runs the store buffering litmus test iterations times, x and y start at 0, one goroutine stores
+// x = 1 then loads y, the other stores y = 1 then loads x, and counts how often both loads saw 0
+// Interleaving the four operations in any order, one of the stores always comes before both loads, so at least one
+// load sees a 1, both seeing 0 means a store was still on its way, in a CPU's store buffer, while the load after it
+// went ahead, which x86 and ARM both do
+// Each iteration the goroutines wait for round to reach it and the main goroutine for both to be done, they yield
+// while they wait, with fewer CPUs than goroutines a spin would only hold up the goroutine it's waiting for
+func storeBuffering(c cells, iterations int) int {
+	var round, done atomic.Int64
+	results := [2][]int32{make([]int32, iterations), make([]int32, iterations)}
+	var wg sync.WaitGroup
+	for g := range 2 {
+		wg.Go(func() {
+			for i := range iterations {
+				for round.Load() <= int64(i) {
+					runtime.Gosched()
+				}
+				c.store(g, 1)
+				results[g][i] = c.load(1 - g)
+				done.Add(1)
+			}
+		})
+	}
+	both := 0
+	for i := range iterations {
+		c.reset()
+		round.Store(int64(i) + 1)
+		for done.Load() < 2*(int64(i)+1) {
+			runtime.Gosched()
+		}
+		if results[0][i] == 0 && results[1][i] == 0 {
+			both++
+		}
+	}
+	wg.Wait()
+	return both
+}
+
+// Config is what a lazy initializer builds, all three fields are set before it's published
+type Config struct {
+	Name    string
+	Retries int
+	Timeout time.Duration
+}
+
+func newConfig() *Config {
+	return &Config{Name: "default", Retries: 3, Timeout: time.Second}
+}
+
+// complete is whether every field has the value newConfig gives it, a config published before its fields are
+// visible reads partly zero
+func (c *Config) complete() bool {
+	return c.Name == "default" && c.Retries == 3 && c.Timeout == time.Second
+}
+
+// Lazy builds a Config the first time it's asked for one, and hands every caller the same one
+type Lazy interface {
+	Get() *Config
+}
+
+// uncheckedLazy is double-checked locking as it's often written, the first check of config is a plain load outside
+// the mutex, a data race, a goroutine can see the pointer without the stores to the fields behind it, the compiler or
+// the CPU are free to make them visible in either order
+type uncheckedLazy struct {
+	mu     sync.Mutex
+	config *Config
+}
+
+func (l *uncheckedLazy) Get() *Config {
+	if l.config == nil {
+		l.mu.Lock()
+		if l.config == nil {
+			l.config = newConfig()
+		}
+		l.mu.Unlock()
+	}
+	return l.config
+}
+
+// mutexLazy takes the mutex every time, correct, and paying for a lock on every call long after the config is built
+type mutexLazy struct {
+	mu     sync.Mutex
+	config *Config
+}
+
+func (l *mutexLazy) Get() *Config {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.config == nil {
+		l.config = newConfig()
+	}
+	return l.config
+}
+
+// atomicLazy is double-checked locking done right, the first check is an atomic load, which synchronizes with the
+// store that published the pointer, so the fields written before it are visible
+type atomicLazy struct {
+	mu     sync.Mutex
+	config atomic.Pointer[Config]
+}
+
+func (l *atomicLazy) Get() *Config {
+	if c := l.config.Load(); c != nil {
+		return c
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c := l.config.Load(); c != nil {
+		return c
+	}
+	c := newConfig()
+	l.config.Store(c)
+	return c
+}
+
+// onceLazy leaves it to sync.OnceValue, which is double-checked locking with an atomic inside
+type onceLazy struct {
+	get func() *Config
+}
+
+func newOnceLazy() *onceLazy {
+	return &onceLazy{get: sync.OnceValue(newConfig)}
+}
+
+func (l *onceLazy) Get() *Config {
+	return l.get()
+}
+
+// lazies are the lazy initializers compared, by name
+var lazies = []struct {
+	name    string
+	newLazy func() Lazy
+}{
+	{"unchecked double check", func() Lazy { return &uncheckedLazy{} }},
+	{"mutex every time", func() Lazy { return &mutexLazy{} }},
+	{"atomic double check", func() Lazy { return &atomicLazy{} }},
+	{"sync.OnceValue", func() Lazy { return newOnceLazy() }},
+}
+
+// halfBuilt races goroutines goroutines to a fresh initializer's first Get, rounds times, counting the calls that
+// got a config whose fields weren't all visible, or a different config from the others
+func halfBuilt(newLazy func() Lazy, rounds, goroutines int) int {
+	var bad atomic.Int64
+	for range rounds {
+		l := newLazy()
+		configs := make([]*Config, goroutines)
+		var start sync.WaitGroup
+		start.Add(1)
+		var wg sync.WaitGroup
+		for g := range goroutines {
+			wg.Go(func() {
+				start.Wait()
+				configs[g] = l.Get()
+				if !configs[g].complete() {
+					bad.Add(1)
+				}
+			})
+		}
+		start.Done()
+		wg.Wait()
+		for _, c := range configs {
+			if c != configs[0] {
+				bad.Add(1)
+			}
+		}
+	}
+	return int(bad.Load())
+}
+
+// getCost is what a Get costs in nanoseconds once the config is built, the path every call but the first takes
+func getCost(l Lazy, calls int) float64 {
+	l.Get()
+	var sink *Config
+	start := time.Now()
+	for range calls {
+		sink = l.Get()
+	}
+	elapsed := time.Since(start)
+	runtime.KeepAlive(sink)
+	return float64(elapsed) / float64(calls)
+}
@@ -0,0 +1,47 @@
+package memorymodel
+
+import (
+	"testing"
+	"time"
+)
+
+// racy is whether a version races by design, and so is left out of a run under the race detector
+func racy(version string) bool {
+	return version == "plain bool" || version == "plain int32" || version == "unchecked double check"
+}
+
+func TestHandovers(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the plain bool races by design")
+	}
+	for _, h := range handovers(time.Second) {
+		if !h.Saw || h.Message != message {
+			t.Errorf("%s: saw %v, message %d, want the flag seen and message %d", h.Version, h.Saw, h.Message, message)
+		}
+	}
+}
+
+func TestAtomicsNeverReadBothZero(t *testing.T) {
+	if n := storeBuffering(&atomicCells{}, 2000); n != 0 {
+		t.Fatalf("both atomic loads read 0 %d times, sequential consistency forbids it", n)
+	}
+	if !raceEnabled {
+		// plain ints may read both 0 on a machine with CPUs to spare, all that's checked is that the test runs
+		storeBuffering(&plainCells{}, 2000)
+	}
+}
+
+func TestLazies(t *testing.T) {
+	for _, l := range lazies {
+		if raceEnabled && racy(l.name) {
+			continue
+		}
+		if n := halfBuilt(l.newLazy, 200, 4); n != 0 {
+			t.Errorf("%s: %d Gets saw a half built or different config", l.name, n)
+		}
+		lazy := l.newLazy()
+		if a, b := lazy.Get(), lazy.Get(); a != b || !a.complete() {
+			t.Errorf("%s: Get gave %p then %p, want the same complete config", l.name, a, b)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// Package memorymodel is the memory model lesson, teachgo memorymodel, experiments with what Go's memory model does
+// and doesn't promise, a flag spun on plain and atomically, the store buffering litmus test, and double-checked
+// locking, each racing version run beside its correct counterparts
+package memorymodel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo memorymodel -h, its first line is the summary teachgo help
+// lists
+const Description = `The Go memory model, what a data race lets a goroutine see, against atomics, channels and mutexes
+
+Runs experiments on what one goroutine is promised to see of another's writes, -demo picks one or all of them.
+flag spins a goroutine on a flag until another sets it, with a message written before it, as a plain bool, an
+atomic.Bool and a closed channel, waiting up to -patience for the spinner to notice. reorder runs the store
+buffering litmus test -iterations times, two goroutines each storing to one variable and loading the other, with
+plain and atomic ints, counting the runs where both loads saw the old value, which no interleaving of the four
+operations explains. dcl races goroutines to the first Get of a lazily built config, -rounds times, with
+double-checked locking unchecked, a mutex every time, an atomic double check and sync.OnceValue, counting configs
+seen half built, and times a Get once it's built. The experiments run with -procs as GOMAXPROCS. Each explains
+what the memory model allows, what this machine showed and why a race that came out right is still a bug.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "memorymodel",
+		Description: Description,
+		Difficulty:  lesson.Advanced,
+		Topics:      []string{"memory model", "data races", "happens before", "atomics", "double-checked locking", "visibility"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// demos are the experiments -demo can pick, in the order all runs them
+var demos = []string{"flag", "reorder", "dcl"}
+
+// getCalls is how many calls a Get is timed over
+const getCalls = 1000000
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Demo       string        `json:"demo"`
+	Procs      int           `json:"procs"`
+	CPUs       int           `json:"cpus"`
+	Iterations int           `json:"iterations"`
+	Rounds     int           `json:"rounds"`
+	Patience   time.Duration `json:"patience"`
+	Race       bool          `json:"race"`
+}
+
+// Litmus is how often one version of the store buffering test saw both loads read 0
+type Litmus struct {
+	Version  string `json:"version"`
+	BothZero int    `json:"both_zero"`
+}
+
+// Init is how one lazy initializer came through its races, HalfBuilt counts the Gets that saw a config not fully
+// built, or not the one the others got, and GetNs is a Get once it's built
+type Init struct {
+	Version   string  `json:"version"`
+	HalfBuilt int     `json:"half_built"`
+	GetNs     float64 `json:"get_ns"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config    RunConfig  `json:"config"`
+	Env       bench.Env  `json:"env"`
+	Handovers []Handover `json:"handovers,omitempty"`
+	Litmus    []Litmus   `json:"litmus,omitempty"`
+	Inits     []Init     `json:"inits,omitempty"`
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Memory Model")
+	fmt.Fprintln(w, "============")
+	fmt.Fprintf(w, "GOMAXPROCS: %d, race detector %s\n", config.Procs, map[bool]string{false: "off", true: "on"}[config.Race])
+	fmt.Fprintf(w, "Machine: %s\n", result.Env)
+	if config.Race {
+		fmt.Fprintln(w, "The plain versions race by design, watch stderr for the reports that start with WARNING: DATA RACE")
+	}
+
+	if result.Handovers != nil {
+		fmt.Fprintf(w, "\n=====%s=====\n", "Flag, spinning until another goroutine says a message is ready")
+		fmt.Fprintf(w, "%-16s %14s %12s %8s\n", "Flag", "Saw it", "After", "Message")
+		for _, h := range result.Handovers {
+			after := "-"
+			if h.Saw {
+				after = time.Duration(h.AfterNs).String()
+			}
+			fmt.Fprintf(w, "%-16s %14v %12s %8d\n", h.Version, h.Saw, after, h.Message)
+		}
+		fmt.Fprintln(w, "\nThe memory model only promises a goroutine sees another's write when something orders the two, an")
+		fmt.Fprintln(w, "atomic load that sees an atomic store, a receive that sees a close. The plain bool has nothing, so the")
+		fmt.Fprintln(w, "compiler may load it once and spin on that forever, or the reader may see ready before the message.")
+		fmt.Fprintln(w, "Go's compiler happens to load it again every time round the loop, and amd64 keeps stores in order,")
+		fmt.Fprintln(w, "so here it likely came out right, which is luck the next compiler or an arm64 CPU needn't share. The")
+		fmt.Fprintln(w, "flag is set a millisecond after the spinner starts, with no CPU to spare the setter has to wait for")
+		fmt.Fprintln(w, "the scheduler to preempt the spinner first, which takes ten milliseconds or so")
+	}
+
+	if result.Litmus != nil {
+		fmt.Fprintf(w, "\n=====Store buffering, x = 1 then load y against y = 1 then load x, %d times=====\n", config.Iterations)
+		fmt.Fprintf(w, "%-16s %14s\n", "Variables", "Both read 0")
+		for _, l := range result.Litmus {
+			fmt.Fprintf(w, "%-16s %14d\n", l.Version, l.BothZero)
+		}
+		fmt.Fprintln(w, "\nIn any interleaving of the four operations one store comes before both loads, so one load at least")
+		fmt.Fprintln(w, "should read 1. Both reading 0 happens because a CPU lets a load go ahead while its own earlier store")
+		fmt.Fprintln(w, "waits in a store buffer, and x86 does, so plain ints show it once the goroutines really run at the")
+		fmt.Fprintln(w, "same time. With one CPU, or -procs 1, they take turns and it can't happen. Go's atomics are")
+		fmt.Fprintln(w, "sequentially consistent, every goroutine agrees on one order for them, so the atomic ints never do")
+	}
+
+	if result.Inits != nil {
+		fmt.Fprintf(w, "\n=====Double-checked locking, %d lazy builds raced by %d goroutines=====\n", config.Rounds, max(config.Procs, 2))
+		fmt.Fprintf(w, "%-24s %12s %12s\n", "Initializer", "Half built", "Get")
+		for _, i := range result.Inits {
+			fmt.Fprintf(w, "%-24s %12d %12s\n", i.Version, i.HalfBuilt, fmt.Sprintf("%.2fns", i.GetNs))
+		}
+		fmt.Fprintln(w, "\nThe unchecked double check reads the pointer outside the mutex with nothing ordering that read after")
+		fmt.Fprintln(w, "the writes to the fields behind it, so it's allowed to see the pointer and a config only partly")
+		fmt.Fprintln(w, "built. amd64 makes stores visible in order, so a half built config is rare to never here, that's the")
+		fmt.Fprintln(w, "hardware being kind, not the code being right, and the race detector reports it every time. An")
+		fmt.Fprintln(w, "atomic double check or sync.OnceValue is as cheap as the broken version once the config is built,")
+		fmt.Fprintln(w, "only taking the mutex every time costs more, so there's no speed to be had from leaving the race in")
+	}
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo memorymodel
+func Main(args []string) {
+	fs := bench.NewFlagSet("memorymodel", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the memory model lesson measures a single run, see -iterations and -rounds"
+	demo := fs.String("demo", "all", "the experiment to run, flag, reorder, dcl or all")
+	procs := fs.Int("procs", runtime.GOMAXPROCS(0), "the GOMAXPROCS the experiments run with")
+	iterations := fs.Int("iterations", 100000, "the runs of the store buffering test")
+	rounds := fs.Int("rounds", 2000, "the lazy builds raced for each initializer")
+	patience := fs.Duration("patience", time.Second, "how long to wait for a spinning goroutine to see its flag")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "memorymodel measures a single run, -trials isn't supported")
+	v.OneOf("demo", *demo, append(demos, "all")...)
+	v.AtLeast("procs", *procs, 1)
+	v.AtLeast("iterations", *iterations, 1)
+	v.AtLeast("rounds", *rounds, 1)
+	v.Check(*patience > 0, "-patience must be positive, got %v", *patience)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+	runtime.GOMAXPROCS(*procs)
+
+	config := RunConfig{Demo: *demo, Procs: *procs, CPUs: runtime.NumCPU(), Iterations: *iterations, Rounds: *rounds,
+		Patience: *patience, Race: raceEnabled}
+	result := RunResult{Config: config, Env: bench.CaptureEnv()}
+	runs := func(name string) bool { return *demo == "all" || *demo == name }
+	if runs("flag") {
+		slog.Info("spinning on flags", "patience", *patience)
+		result.Handovers = handovers(*patience)
+	}
+	if runs("reorder") {
+		slog.Info("running the store buffering test", "iterations", *iterations)
+		result.Litmus = []Litmus{
+			{Version: "plain int32", BothZero: storeBuffering(&plainCells{}, *iterations)},
+			{Version: "atomic.Int32", BothZero: storeBuffering(&atomicCells{}, *iterations)},
+		}
+	}
+	if runs("dcl") {
+		for _, l := range lazies {
+			slog.Info("racing lazy builds", "initializer", l.name, "rounds", *rounds)
+			result.Inits = append(result.Inits, Init{Version: l.name, HalfBuilt: halfBuilt(l.newLazy, *rounds, max(*procs, 2)),
+				GetNs: getCost(l.newLazy(), getCalls)})
+		}
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per litmus version, an op is one run of the test, and per initializer, an op is one
+// Get once the config is built
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/memory_model"); err != nil {
+		return err
+	}
+	benchmarks := []bench.Benchmark{}
+	for _, l := range result.Litmus {
+		n := int64(result.Config.Iterations)
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name:    fmt.Sprintf("reorder/%s", l.Version),
+			N:       n,
+			Metrics: []bench.Metric{{Value: float64(l.BothZero) / float64(n), Unit: "both-zero/op"}},
+		})
+	}
+	for _, i := range result.Inits {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: fmt.Sprintf("dcl/%s", i.Version),
+			N:    getCalls,
+			Metrics: []bench.Metric{
+				{Value: i.GetNs, Unit: "ns/op"},
+				{Value: float64(i.HalfBuilt), Unit: "half-built"},
+			},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
@@ -0,0 +1,63 @@
+package memorymodel
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz memorymodel, it asks what the Go memory model does and doesn't promise for the flag,
+// the store buffering litmus test and lazy initialization, and why the correct versions cost so little
+var quiz = []lesson.Question{
+	{
+		Prompt: "The plain bool flag came out right, why is it still a bug?",
+		Choices: []string{
+			"A plain bool is slower than an atomic one, so the reader spins longer than it needs to",
+			"Nothing orders the loads after the stores, so the compiler may load the flag once and spin forever",
+			"The message could be garbage collected between the writer's store and the reader's load",
+			"It isn't a bug on amd64, whose stores are never reordered with other stores",
+		},
+		Answer:      1,
+		Explanation: "it worked because of how this compiler and this CPU happen to behave, a program with a data race has no guaranteed behavior to test for, the race detector reports it whatever the result",
+	},
+	{
+		Prompt: "Why can both loads of the store buffering test read 0 with plain ints?",
+		Choices: []string{
+			"The compiler sees the stores are never read in the same goroutine and removes them",
+			"The goroutines happen to run one after the other, each before the other's store",
+			"A CPU can let a load go ahead while its own store still sits in its store buffer",
+			"The stores to plain ints are lost when both CPUs write the same cache line",
+		},
+		Answer:      2,
+		Explanation: "each goroutine's load can beat the other's store, no interleaving of the four operations in program order gives both 0, it takes the reordering, and truly parallel goroutines to see it",
+	},
+	{
+		Prompt: "Why do the atomic ints never show both loads reading 0?",
+		Choices: []string{
+			"Go's atomics are sequentially consistent, so one store comes before both loads",
+			"Atomics are slower, which shifts the timing so the stores always land first",
+			"An atomic turns off the store buffer for the rest of the program's run",
+			"The atomic version resets the ints between rounds in a different order",
+		},
+		Answer:      0,
+		Explanation: "all goroutines see every atomic operation in one order that keeps each goroutine's program order, on amd64 an atomic store is an XCHG, which waits for the store buffer to drain before the load after it can go ahead",
+	},
+	{
+		Prompt: "What's wrong with double-checked locking that reads the pointer outside the mutex without an atomic?",
+		Choices: []string{
+			"It can build the config twice, once for each goroutine that finds the pointer nil",
+			"The second check inside the mutex can deadlock with a goroutine doing the first",
+			"It's slower than just taking the mutex, because of the extra check on every call",
+			"The unlocked read isn't ordered after the field writes, so it may see zeroed fields",
+		},
+		Answer:      3,
+		Explanation: "an atomic.Pointer load synchronizes with the store that published it, making the fields written before it visible, sync.OnceValue does the same inside",
+	},
+	{
+		Prompt: "Why is taking the mutex on every Get the slowest correct initializer once the config is built?",
+		Choices: []string{
+			"It locks and unlocks every call, the others only do an atomic load on their fast path",
+			"It rebuilds the config under the lock each time to be sure it's up to date",
+			"It allocates a new lock on every call, which the garbage collector then frees",
+			"The race detector instruments the mutex, which slows every call down",
+		},
+		Answer:      0,
+		Explanation: "the atomic double check and sync.OnceValue's load is about as cheap as the racy plain read, the fast path of a correct lazy initializer costs about what the broken one does, there's nothing to gain from the race",
+	},
+}
@@ -0,0 +1,294 @@
+// Package merkletree is the Merkle tree lesson, teachgo merkle, a tree of hashes over a file's chunks that proves a
+// chunk belongs to the file and finds a corrupted chunk in a handful of comparisons
+package merkletree
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo merkle -h, its first line is the summary teachgo help lists
+const Description = `Merkle trees, proving and checking a large file chunk by chunk
+
+Splits a file into chunks, hashes each with SHA-256 and hashes the hashes together in pairs up to a single root.
+Flips one bit somewhere in the file, builds the tree again and walks the two trees down from their roots to the one
+chunk that differs, comparing a couple of hashes per level instead of every chunk's. Then proves a chunk belongs
+to the file with just the hashes along its path to the root, and shows the corrupted chunk failing the same check.
+The file is random data written to a temporary file unless -file names one of your own.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "merkle",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"hashing", "Merkle trees", "integrity verification", "binary trees"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// RunConfig records the settings a run was made with, File is empty when the lesson generated its own
+type RunConfig struct {
+	File   string `json:"file,omitempty"`
+	SizeMB int    `json:"size_mb,omitempty"`
+	Chunk  int    `json:"chunk"`
+	Proofs int    `json:"proofs"`
+	Seed   int64  `json:"seed"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+// Found is the chunks that differed between the trees, Comparisons the pairs of hashes compared to find them, and
+// Verified and CorruptVerified whether the chunk's original and corrupted contents pass its proof
+type RunResult struct {
+	Config          RunConfig `json:"config"`
+	Env             bench.Env `json:"env"`
+	Bytes           int64     `json:"bytes"`
+	Chunks          int       `json:"chunks"`
+	Height          int       `json:"height"`
+	Root            string    `json:"root"`
+	CorruptRoot     string    `json:"corrupt_root"`
+	Offset          int64     `json:"offset"`
+	Found           []int     `json:"found"`
+	Comparisons     int       `json:"comparisons"`
+	ProofHashes     int       `json:"proof_hashes"`
+	Verified        bool      `json:"verified"`
+	CorruptVerified bool      `json:"corrupt_verified"`
+	HashNs          float64   `json:"hash_ns"`
+	DiffNs          float64   `json:"diff_ns"`
+	ScanNs          float64   `json:"scan_ns"`
+	VerifyNs        float64   `json:"verify_ns"`
+}
+
+// corruptReader flips the lowest bit of the byte at offset as it's read, a corrupted copy of the file without
+// writing one
+type corruptReader struct {
+	r      io.Reader
+	offset int64
+	pos    int64
+}
+
+func (c *corruptReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if c.offset >= c.pos && c.offset < c.pos+int64(n) {
+		p[c.offset-c.pos] ^= 1
+	}
+	c.pos += int64(n)
+	return n, err
+}
+
+// writeRandomFile fills a temporary file with sizeMB mebibytes of seeded random data and returns its path
+func writeRandomFile(rng *rand.Rand, sizeMB int) (string, error) {
+	f, err := os.CreateTemp("", "teachgo-merkle-*.bin")
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, 1<<20)
+	for range sizeMB {
+		rng.Read(buf)
+		if _, err := f.Write(buf); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return "", err
+		}
+	}
+	return f.Name(), f.Close()
+}
+
+// buildTree hashes the file's chunks into a tree, corrupting the byte at offset first unless offset is negative
+func buildTree(path string, chunkSize int, offset int64) (*Tree, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if offset >= 0 {
+		r = &corruptReader{r: f, offset: offset}
+	}
+	leaves, err := HashChunks(r, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	return Build(leaves), nil
+}
+
+// scan compares the leaves one by one, what finding the corrupted chunk takes without the tree above them
+func scan(a, b *Tree) []int {
+	chunks := []int{}
+	for i, leaf := range a.levels[0] {
+		if leaf != b.levels[0][i] {
+			chunks = append(chunks, i)
+		}
+	}
+	return chunks
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Merkle Tree")
+	fmt.Fprintln(w, "===========")
+	if config.File != "" {
+		fmt.Fprintf(w, "File: %s\n", config.File)
+	} else {
+		fmt.Fprintf(w, "File: %d MiB of random data\n", config.SizeMB)
+	}
+	fmt.Fprintf(w, "Chunk size: %d bytes\n", config.Chunk)
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%d bytes in %d chunks, a tree %d levels high, hashed in %v, bytes at %s\n", result.Bytes, result.Chunks,
+		result.Height, time.Duration(result.HashNs), bench.FormatRate(float64(result.Bytes)/time.Duration(result.HashNs).Seconds()))
+	fmt.Fprintf(w, "Root:           %s\n", result.Root)
+	fmt.Fprintf(w, "Corrupted root: %s\n", result.CorruptRoot)
+	fmt.Fprintf(w, "\nFlipped a bit at byte %d, in chunk %d\n", result.Offset, result.Offset/int64(config.Chunk))
+	fmt.Fprintf(w, "%-22s %12s %12s %12s\n", "Search", "Found", "Compared", "Time")
+	found := strings.Trim(fmt.Sprint(result.Found), "[]")
+	fmt.Fprintf(w, "%-22s %12s %12d %12v\n", "Down from the roots", found, result.Comparisons, time.Duration(result.DiffNs))
+	fmt.Fprintf(w, "%-22s %12s %12d %12v\n", "Every leaf", found, result.Chunks, time.Duration(result.ScanNs))
+
+	fmt.Fprintf(w, "\nA proof of chunk %d is %d hashes, %d bytes, in place of the whole %d byte file\n",
+		result.Found[0], result.ProofHashes, result.ProofHashes*len(Hash{}), result.Bytes)
+	fmt.Fprintf(w, "The original chunk verifies against the root: %v\n", result.Verified)
+	fmt.Fprintf(w, "The corrupted chunk verifies against the root: %v\n", result.CorruptVerified)
+	fmt.Fprintf(w, "Checking a proof takes %v\n", time.Duration(result.VerifyNs))
+	fmt.Fprintln(w, "\nOne flipped bit changes its chunk's hash and every hash above it, and nothing beside that path, the two")
+	fmt.Fprintln(w, "trees agree everywhere else, so following the disagreement down leads straight to the chunk")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo merkle
+func Main(args []string) {
+	fs := bench.NewFlagSet("merkle", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the Merkle tree lesson measures a single run"
+	file := fs.String("file", "", "a file to build the tree over, instead of generating one")
+	sizeMB := fs.Int("size", 64, "the size in MiB of the random file generated when -file isn't given")
+	chunkSize := fs.Int("chunk", 4096, "the size in bytes of the chunks the file is split into, one leaf each")
+	proofs := fs.Int("proofs", 10000, "the number of proofs checked to time checking one")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "merkle measures a single run, -trials isn't supported")
+	v.AtLeast("size", *sizeMB, 1)
+	v.AtLeast("chunk", *chunkSize, 1)
+	v.AtLeast("proofs", *proofs, 1)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	rng := rand.New(rand.NewSource(globals.Seed))
+	config := RunConfig{File: *file, Chunk: *chunkSize, Proofs: *proofs, Seed: globals.Seed}
+	path := *file
+	if path == "" {
+		config.SizeMB = *sizeMB
+		slog.Info("writing random file", "mib", *sizeMB)
+		var err error
+		if path, err = writeRandomFile(rng, *sizeMB); err != nil {
+			slog.Error("failed to write the random file", "err", err)
+			os.Exit(1)
+		}
+		defer os.Remove(path)
+	}
+	// Main's deferred remove doesn't run past os.Exit, so failures from here on go through fail
+	fail := func(msg string, err error) {
+		slog.Error(msg, "err", err)
+		if *file == "" {
+			os.Remove(path)
+		}
+		os.Exit(1)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		fail("failed to read the file", err)
+	}
+	if info.Size() == 0 {
+		fail("failed to read the file", fmt.Errorf("%s is empty, there's nothing to hash", path))
+	}
+
+	result := RunResult{Config: config, Env: bench.CaptureEnv(), Bytes: info.Size()}
+	ctx := context.Background()
+	var original, corrupt *Tree
+	slog.Info("hashing file", "bytes", info.Size(), "chunk", *chunkSize)
+	result.HashNs = float64(bench.Phase(ctx, "hash", func() { original, err = buildTree(path, *chunkSize, -1) }).Nanoseconds())
+	if err != nil {
+		fail("failed to hash the file", err)
+	}
+	result.Offset = rng.Int63n(info.Size())
+	slog.Info("hashing corrupted file", "offset", result.Offset)
+	if corrupt, err = buildTree(path, *chunkSize, result.Offset); err != nil {
+		fail("failed to hash the file", err)
+	}
+	result.Chunks, result.Height = original.Leaves(), original.Height()
+	result.Root, result.CorruptRoot = original.Root().String(), corrupt.Root().String()
+
+	result.DiffNs = float64(bench.Phase(ctx, "diff", func() { result.Found, result.Comparisons = Diff(original, corrupt) }).Nanoseconds())
+	result.ScanNs = float64(bench.Phase(ctx, "scan", func() { scan(original, corrupt) }).Nanoseconds())
+
+	chunk := result.Found[0]
+	proof := original.Proof(chunk)
+	result.ProofHashes = len(proof)
+	result.Verified = Verify(original.Root(), original.levels[0][chunk], proof)
+	result.CorruptVerified = Verify(original.Root(), corrupt.levels[0][chunk], proof)
+	indexes := make([]int, *proofs)
+	for i := range indexes {
+		indexes[i] = rng.Intn(original.Leaves())
+	}
+	allProofs := make([][]ProofStep, len(indexes))
+	for i, index := range indexes {
+		allProofs[i] = original.Proof(index)
+	}
+	verify := bench.Phase(ctx, "verify", func() {
+		for i, index := range indexes {
+			Verify(original.Root(), original.levels[0][index], allProofs[i])
+		}
+	})
+	result.VerifyNs = float64(verify.Nanoseconds()) / float64(len(indexes))
+
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		fail("failed to write results", err)
+	}
+}
+
+// writeBenchmarks writes a line for hashing the file, with its throughput, for finding the corrupted chunk both ways,
+// and for checking a proof
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/merkle_tree"); err != nil {
+		return err
+	}
+	tree := fmt.Sprintf("chunks=%d/chunk=%d", result.Chunks, result.Config.Chunk)
+	benchmarks := []bench.Benchmark{
+		{Name: "Hash/" + tree, N: 1, Metrics: []bench.Metric{
+			{Value: result.HashNs, Unit: "ns/op"},
+			{Value: float64(result.Bytes) / time.Duration(result.HashNs).Seconds() / 1e6, Unit: "MB/s"},
+		}},
+		{Name: "Find/tree/" + tree, N: 1, Metrics: []bench.Metric{
+			{Value: result.DiffNs, Unit: "ns/op"},
+			{Value: float64(result.Comparisons), Unit: "compares/op"},
+		}},
+		{Name: "Find/scan/" + tree, N: 1, Metrics: []bench.Metric{
+			{Value: result.ScanNs, Unit: "ns/op"},
+			{Value: float64(result.Chunks), Unit: "compares/op"},
+		}},
+		{Name: "Verify/" + tree, N: int64(result.Config.Proofs), Metrics: []bench.Metric{{Value: result.VerifyNs, Unit: "ns/op"}}},
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
@@ -0,0 +1,63 @@
+package merkletree
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz merkle, it follows a flipped bit up to the root, down again to the corrupted chunk,
+// and through an inclusion proof, and asks why leaves and nodes hash with different prefixes
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does flipping a single bit change the root hash?",
+		Choices: []string{
+			"The root is a hash of the whole file read in one pass, so any change reaches it",
+			"The tree is rebuilt after the change, and the new tree has a different shape",
+			"It changes its chunk's hash, which changes its parent's, up every level to the root",
+			"It only does when the bit is in the first chunk, which the root hash covers",
+		},
+		Answer:      2,
+		Explanation: "every node hashes its children, so a change anywhere below reaches the root, and nothing off that path changes",
+	},
+	{
+		Prompt: "How does walking down from the roots find the corrupted chunk in so few comparisons?",
+		Choices: []string{
+			"Only a child whose hash differs can lead to the change, so it follows that one each level",
+			"It compares the contents of the chunks in pairs, halving the candidates each time",
+			"It derives the chunk's index from the difference between the two root hashes",
+			"It checks every leaf at once in parallel, so the work takes one comparison's time",
+		},
+		Answer:      0,
+		Explanation: "about 2 log2(n) comparisons against n for checking every leaf, two machines can find where their copies differ by swapping only those hashes",
+	},
+	{
+		Prompt: "What does someone need to check that a chunk belongs to a file whose root they trust?",
+		Choices: []string{
+			"The chunk and every other leaf hash",
+			"The chunk and its neighbouring chunks",
+			"The chunk alone, hashed with the root",
+			"The chunk and one sibling hash a level",
+		},
+		Answer:      3,
+		Explanation: "hashing the chunk and then each sibling on its path in turn has to arrive at the trusted root, a proof of a few hundred bytes for a file of gigabytes",
+	},
+	{
+		Prompt: "Why does the corrupted chunk fail its proof, even with the genuine sibling hashes?",
+		Choices: []string{
+			"The proof records the chunk's length and the corrupted chunk is a byte longer",
+			"Its different leaf hash changes every hash on the way up, so the last misses the root",
+			"The proof was made from the corrupted tree, so its sibling hashes are stale",
+			"A proof is tied to the time it was made, and the chunk was changed after that",
+		},
+		Answer:      1,
+		Explanation: "finding a chunk that did match would mean breaking SHA-256, this is how BitTorrent, Git and certificate transparency logs check pieces they're handed by someone they don't trust",
+	},
+	{
+		Prompt: "Why are leaves and inner nodes hashed with different prefixes?",
+		Choices: []string{
+			"The prefixes pad the input to SHA-256's block size, which makes hashing faster",
+			"A node's two child hashes joined would be a chunk with the same hash as the node",
+			"The prefixes record each node's depth, so the tree's shape is fixed by the root",
+			"SHA-256 requires a domain prefix on any input shorter than one block",
+		},
+		Answer:      1,
+		Explanation: "without them someone could pass a node off as a 64 byte chunk, a second preimage attack on the tree rather than on the hash, the prefixes keep leaf hashes and node hashes apart",
+	},
+}
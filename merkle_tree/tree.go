@@ -0,0 +1,158 @@
+package merkletree
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// Hash is a SHA-256 digest, of a chunk or of two hashes joined
+type Hash [sha256.Size]byte
+
+// String is the hash in hex
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// leafPrefix and nodePrefix are hashed in ahead of a chunk and of a pair of hashes, so a node can never be passed off
+// as a chunk, without them the 64 bytes of two child hashes would be a chunk whose leaf hash is their parent's
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// LeafHash is the hash of a chunk as it's stored in the tree
+func LeafHash(chunk []byte) Hash {
+	h := sha256.New()
+	h.Write([]byte{leafPrefix})
+	h.Write(chunk)
+	return Hash(h.Sum(nil))
+}
+
+func nodeHash(left, right Hash) Hash {
+	var buf [1 + 2*sha256.Size]byte
+	buf[0] = nodePrefix
+	copy(buf[1:], left[:])
+	copy(buf[1+sha256.Size:], right[:])
+	return sha256.Sum256(buf[:])
+}
+
+// HashChunks reads r to the end in chunks of chunkSize bytes, the last one shorter if the size doesn't divide it, and
+// returns each chunk's leaf hash
+func HashChunks(r io.Reader, chunkSize int) ([]Hash, error) {
+	br := bufio.NewReaderSize(r, chunkSize)
+	chunk := make([]byte, chunkSize)
+	leaves := []Hash{}
+	for {
+		n, err := io.ReadFull(br, chunk)
+		if n > 0 {
+			leaves = append(leaves, LeafHash(chunk[:n]))
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return leaves, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Tree is a Merkle tree, a binary tree of hashes whose leaves are the hashes of a file's chunks and whose every other
+// node is the hash of its two children, so the root depends on every byte of the file, and changing any one changes
+// the hashes on the path from its chunk's leaf to the root and nothing else
+// levels[0] is the leaves and the last level the root alone, a level of odd length carries its last hash up unpaired
+type Tree struct {
+	levels [][]Hash
+}
+
+// Build makes the tree over the leaf hashes, there has to be at least one
+func Build(leaves []Hash) *Tree {
+	t := &Tree{levels: [][]Hash{leaves}}
+	for level := leaves; len(level) > 1; {
+		next := make([]Hash, (len(level)+1)/2)
+		for i := range next {
+			if 2*i+1 < len(level) {
+				next[i] = nodeHash(level[2*i], level[2*i+1])
+			} else {
+				next[i] = level[2*i]
+			}
+		}
+		t.levels = append(t.levels, next)
+		level = next
+	}
+	return t
+}
+
+// Root is the hash at the top of the tree, the one hash that vouches for the whole file
+func (t *Tree) Root() Hash {
+	return t.levels[len(t.levels)-1][0]
+}
+
+// Leaves is the number of chunks
+func (t *Tree) Leaves() int {
+	return len(t.levels[0])
+}
+
+// Height is the number of levels above the leaves
+func (t *Tree) Height() int {
+	return len(t.levels) - 1
+}
+
+// ProofStep is a sibling on the path from a leaf to the root, Left when it's hashed in on the left
+type ProofStep struct {
+	Sibling Hash
+	Left    bool
+}
+
+// Proof returns the siblings on the path from leaf i to the root, all anyone holding the root needs to check chunk i
+// belongs to the file, O(log n) hashes where checking against the whole file would need all n
+func (t *Tree) Proof(i int) []ProofStep {
+	proof := []ProofStep{}
+	for _, level := range t.levels[:len(t.levels)-1] {
+		sibling := i ^ 1
+		// a node carried up unpaired has no sibling at this level
+		if sibling < len(level) {
+			proof = append(proof, ProofStep{Sibling: level[sibling], Left: sibling < i})
+		}
+		i /= 2
+	}
+	return proof
+}
+
+// Verify reports whether the chunk with the given leaf hash and proof hashes up to the root
+func Verify(root, leaf Hash, proof []ProofStep) bool {
+	h := leaf
+	for _, step := range proof {
+		if step.Left {
+			h = nodeHash(step.Sibling, h)
+		} else {
+			h = nodeHash(h, step.Sibling)
+		}
+	}
+	return h == root
+}
+
+// Diff returns the chunks whose leaves differ between two trees over the same number of chunks, and how many pairs
+// of hashes it compared to find them
+// It starts at the roots and only descends into children whose hashes differ, so a single changed chunk is found in
+// two comparisons per level, where comparing the leaves one by one takes n
+func Diff(a, b *Tree) (chunks []int, comparisons int) {
+	var descend func(level, i int)
+	descend = func(level, i int) {
+		comparisons++
+		if a.levels[level][i] == b.levels[level][i] {
+			return
+		}
+		if level == 0 {
+			chunks = append(chunks, i)
+			return
+		}
+		for child := 2 * i; child <= 2*i+1 && child < len(a.levels[level-1]); child++ {
+			descend(level-1, child)
+		}
+	}
+	descend(len(a.levels)-1, 0)
+	return chunks, comparisons
+}
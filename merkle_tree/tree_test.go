@@ -0,0 +1,79 @@
+package merkletree
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"testing"
+)
+
+func leavesOf(data []byte, chunkSize int) []Hash {
+	leaves, err := HashChunks(bytes.NewReader(data), chunkSize)
+	if err != nil {
+		panic(err)
+	}
+	return leaves
+}
+
+func TestHashChunksSplitsTheLastChunkShort(t *testing.T) {
+	data := bytes.Repeat([]byte("abcdefgh"), 10)
+	leaves := leavesOf(data, 32)
+	want := []Hash{LeafHash(data[:32]), LeafHash(data[32:64]), LeafHash(data[64:])}
+	if !slices.Equal(leaves, want) {
+		t.Errorf("HashChunks of 80 bytes in 32 byte chunks gave %d leaves, want the 3 chunks' hashes", len(leaves))
+	}
+}
+
+func TestEveryProofVerifies(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 7, 8, 13} {
+		leaves := make([]Hash, n)
+		for i := range leaves {
+			leaves[i] = LeafHash([]byte{byte(i)})
+		}
+		tree := Build(leaves)
+		for i := range n {
+			if !Verify(tree.Root(), leaves[i], tree.Proof(i)) {
+				t.Errorf("%d leaves: the proof of leaf %d doesn't verify", n, i)
+			}
+			if Verify(tree.Root(), LeafHash([]byte("other")), tree.Proof(i)) {
+				t.Errorf("%d leaves: a different chunk verifies with leaf %d's proof", n, i)
+			}
+		}
+	}
+}
+
+func TestDiffFindsTheCorruptedChunk(t *testing.T) {
+	data := make([]byte, 100*64+10)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	original := Build(leavesOf(data, 64))
+	corrupt, err := HashChunks(&corruptReader{r: bytes.NewReader(data), offset: 70*64 + 5}, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, comparisons := Diff(original, Build(corrupt))
+	if !slices.Equal(chunks, []int{70}) {
+		t.Errorf("Diff found chunks %v, want [70]", chunks)
+	}
+	if comparisons > 2*original.Height()+1 {
+		t.Errorf("Diff compared %d pairs, want at most two per level of a tree %d high", comparisons, original.Height())
+	}
+	same, _ := Diff(original, original)
+	if len(same) != 0 {
+		t.Errorf("Diff of a tree with itself found %v", same)
+	}
+}
+
+func TestCorruptReaderFlipsOneBit(t *testing.T) {
+	data := make([]byte, 100)
+	got, err := io.ReadAll(&corruptReader{r: bytes.NewReader(data), offset: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range got {
+		if want := byte(0); i == 42 && b != 1 || i != 42 && b != want {
+			t.Fatalf("byte %d = %d after corrupting byte 42", i, b)
+		}
+	}
+}
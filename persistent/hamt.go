@@ -0,0 +1,196 @@
+package persistent
+
+import (
+	"iter"
+	"math/bits"
+	"slices"
+)
+
+// Map is an immutable map from int keys, a hash array mapped trie, every Set and Delete returns a new map and leaves
+// the old one as it was, sharing all but the nodes on the path to the changed key
+// Each node covers 5 bits of the key's hash, up to 32 children, but only stores the ones that exist, a bitmap says
+// which of the 32 are present and a child's place in the slice is the number of set bits below its own, so a map of
+// a million keys is about 4 levels deep and a change copies 4 small nodes where copying the map would copy a million
+// entries
+// The hash is a bijection on 64 bit keys, two keys never hash alike, so a node never needs a list of collisions
+// The nil *Map is the empty map
+type Map[V any] struct {
+	root   *node[V]
+	length int
+	// copied counts the entries copied into new nodes by every change made on the way to this version
+	copied int64
+}
+
+type node[V any] struct {
+	bitmap  uint32
+	entries []entry[V]
+}
+
+// entry is a key and its value, or, when child is set, a node holding every key whose hash shares the bits so far
+type entry[V any] struct {
+	hash  uint64
+	key   int
+	value V
+	child *node[V]
+}
+
+const (
+	bitsPerLevel = 5
+	levelMask    = 1<<bitsPerLevel - 1
+)
+
+// mix is splitmix64's finalizer, a bijection that scatters keys that differ in a bit or two across all 64 bits
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// slot returns the bit for a hash at a level's shift, and where in the node's entries it is or would go
+func (n *node[V]) slot(hash uint64, shift uint) (bit uint32, pos int) {
+	bit = 1 << ((hash >> shift) & levelMask)
+	return bit, bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+// Len is the number of keys
+func (m *Map[V]) Len() int {
+	if m == nil {
+		return 0
+	}
+	return m.length
+}
+
+// Copied is the number of entries copied into new nodes by all the changes that made this version, what keeping
+// every version alive costs beyond the first
+func (m *Map[V]) Copied() int64 {
+	if m == nil {
+		return 0
+	}
+	return m.copied
+}
+
+// Get returns the key's value and whether the map holds it
+func (m *Map[V]) Get(key int) (V, bool) {
+	var zero V
+	if m == nil || m.root == nil {
+		return zero, false
+	}
+	hash := mix(uint64(key))
+	n := m.root
+	for shift := uint(0); ; shift += bitsPerLevel {
+		bit, pos := n.slot(hash, shift)
+		if n.bitmap&bit == 0 {
+			return zero, false
+		}
+		e := &n.entries[pos]
+		if e.child == nil {
+			if e.key == key {
+				return e.value, true
+			}
+			return zero, false
+		}
+		n = e.child
+	}
+}
+
+// Set returns a map with the key set to value
+func (m *Map[V]) Set(key int, value V) *Map[V] {
+	var root *node[V]
+	if m != nil {
+		root = m.root
+	}
+	copied := int64(0)
+	added := false
+	root = set(root, entry[V]{hash: mix(uint64(key)), key: key, value: value}, 0, &copied, &added)
+	length := m.Len()
+	if added {
+		length++
+	}
+	return &Map[V]{root: root, length: length, copied: m.Copied() + copied}
+}
+
+// set returns a copy of n with e in it, n itself untouched, counting the entries it copies
+func set[V any](n *node[V], e entry[V], shift uint, copied *int64, added *bool) *node[V] {
+	if n == nil {
+		*added = true
+		*copied++
+		return &node[V]{bitmap: 1 << ((e.hash >> shift) & levelMask), entries: []entry[V]{e}}
+	}
+	bit, pos := n.slot(e.hash, shift)
+	if n.bitmap&bit == 0 {
+		*added = true
+		*copied += int64(len(n.entries) + 1)
+		return &node[V]{bitmap: n.bitmap | bit, entries: slices.Insert(slices.Clone(n.entries), pos, e)}
+	}
+	entries := slices.Clone(n.entries)
+	*copied += int64(len(entries))
+	switch existing := entries[pos]; {
+	case existing.child != nil:
+		entries[pos] = entry[V]{child: set(existing.child, e, shift+bitsPerLevel, copied, added)}
+	case existing.key == e.key:
+		entries[pos] = e
+	default:
+		// two keys sharing the bits so far move down into a node of their own, where the next bits tell them apart
+		child := set(nil, existing, shift+bitsPerLevel, copied, added)
+		entries[pos] = entry[V]{child: set(child, e, shift+bitsPerLevel, copied, added)}
+	}
+	return &node[V]{bitmap: n.bitmap, entries: entries}
+}
+
+// Delete returns a map without the key, the same map when it wasn't there
+func (m *Map[V]) Delete(key int) *Map[V] {
+	if _, ok := m.Get(key); !ok {
+		return m
+	}
+	copied := int64(0)
+	root := remove(m.root, mix(uint64(key)), 0, &copied)
+	return &Map[V]{root: root, length: m.length - 1, copied: m.copied + copied}
+}
+
+// remove returns a copy of n without the hash, which has to be in it, nil when nothing is left
+// A node left holding a single key is folded into its parent, so the trie stays as shallow as the keys allow
+func remove[V any](n *node[V], hash uint64, shift uint, copied *int64) *node[V] {
+	bit, pos := n.slot(hash, shift)
+	if child := n.entries[pos].child; child != nil {
+		child = remove(child, hash, shift+bitsPerLevel, copied)
+		entries := slices.Clone(n.entries)
+		*copied += int64(len(entries))
+		if len(child.entries) == 1 && child.entries[0].child == nil {
+			entries[pos] = child.entries[0]
+		} else {
+			entries[pos] = entry[V]{child: child}
+		}
+		return &node[V]{bitmap: n.bitmap, entries: entries}
+	}
+	if len(n.entries) == 1 {
+		return nil
+	}
+	*copied += int64(len(n.entries) - 1)
+	return &node[V]{bitmap: n.bitmap &^ bit, entries: slices.Delete(slices.Clone(n.entries), pos, pos+1)}
+}
+
+// All yields every key and value, in the order of their hashes
+func (m *Map[V]) All() iter.Seq2[int, V] {
+	return func(yield func(int, V) bool) {
+		if m != nil && m.root != nil {
+			each(m.root, yield)
+		}
+	}
+}
+
+func each[V any](n *node[V], yield func(int, V) bool) bool {
+	for i := range n.entries {
+		e := &n.entries[i]
+		if e.child != nil {
+			if !each(e.child, yield) {
+				return false
+			}
+		} else if !yield(e.key, e.value) {
+			return false
+		}
+	}
+	return true
+}
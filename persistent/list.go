@@ -0,0 +1,53 @@
+package persistent
+
+import "iter"
+
+// List is an immutable singly linked list, Push returns a new list whose tail is the old one, shared, not copied, so
+// every version ever made stays valid and costs one node more than the version it was made from
+// The nil *List is the empty list
+type List[T any] struct {
+	head   T
+	tail   *List[T]
+	length int
+}
+
+// Push returns the list with x in front, the list it was called on is unchanged
+func (l *List[T]) Push(x T) *List[T] {
+	return &List[T]{head: x, tail: l, length: l.Len() + 1}
+}
+
+// Head returns the first element, false for the empty list
+func (l *List[T]) Head() (T, bool) {
+	if l == nil {
+		var zero T
+		return zero, false
+	}
+	return l.head, true
+}
+
+// Tail returns the list after the first element, shared with this one
+func (l *List[T]) Tail() *List[T] {
+	if l == nil {
+		return nil
+	}
+	return l.tail
+}
+
+// Len is the number of elements
+func (l *List[T]) Len() int {
+	if l == nil {
+		return 0
+	}
+	return l.length
+}
+
+// All yields the elements from the front
+func (l *List[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for ; l != nil; l = l.tail {
+			if !yield(l.head) {
+				return
+			}
+		}
+	}
+}
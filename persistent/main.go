@@ -0,0 +1,434 @@
+// Package persistent is the persistent data structures lesson, teachgo persistent, an immutable list and an immutable
+// hash array mapped trie that share structure between versions, against copying a slice or map for every version,
+// and how immutable snapshots let readers run alongside a writer without locks
+package persistent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"math/rand"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo persistent -h, its first line is the summary teachgo help
+// lists
+const Description = `Persistent data structures, immutable versions that share their structure
+
+Makes a series of versions of a list and of a map, each one change from the last, and keeps every version intact.
+An immutable linked list and a hash array mapped trie, a 32-way trie that copies only the few nodes on the path to
+a change, share everything else with the version before, against copying a slice or Go map whole for each version.
+Shows an old version reading exactly as it did after thousands of changes, then has readers sum a set of accounts
+while a writer moves money between them, once reading immutable snapshots published through an atomic pointer and
+once holding a read lock on a map the writer changes in place, for a whole sum, to keep the writer out.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "persistent",
+		Description: Description,
+		Difficulty:  lesson.Advanced,
+		Topics:      []string{"immutability", "structural sharing", "tries", "snapshots", "lock-free reads"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Keys      int   `json:"keys"`
+	Versions  int   `json:"versions"`
+	Lookups   int   `json:"lookups"`
+	Readers   int   `json:"readers"`
+	Accounts  int   `json:"accounts"`
+	Sums      int   `json:"sums"`
+	Transfers int   `json:"transfers"`
+	Seed      int64 `json:"seed"`
+}
+
+// VersionResult is the cost of making one new version, the time and the entries copied into it, which is the memory
+// every version kept alive costs
+type VersionResult struct {
+	Name   string  `json:"name"`
+	Ns     float64 `json:"ns"`
+	Copied float64 `json:"copied"`
+}
+
+// LookupResult is the time a lookup took
+type LookupResult struct {
+	Name string  `json:"name"`
+	Ns   float64 `json:"ns"`
+}
+
+// ConcurrentResult is readers summing the accounts while the writer transfers between them, WriterNs is how long
+// the writer took for all its transfers, SumNs how long a reader took over each sum and Inconsistent how many sums
+// saw a transfer half made
+type ConcurrentResult struct {
+	Name         string  `json:"name"`
+	WriterNs     float64 `json:"writer_ns"`
+	SumNs        float64 `json:"sum_ns"`
+	Inconsistent int     `json:"inconsistent"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+// SnapshotChanged counts the keys whose values differ between the first version of the map as read when it was made
+// and as read after every later version was made, zero as nothing can change it
+type RunResult struct {
+	Config          RunConfig          `json:"config"`
+	Env             bench.Env          `json:"env"`
+	Versions        []VersionResult    `json:"versions"`
+	Lookups         []LookupResult     `json:"lookups"`
+	SnapshotChanged int                `json:"snapshot_changed"`
+	LatestChanged   int                `json:"latest_changed"`
+	Concurrent      []ConcurrentResult `json:"concurrent"`
+}
+
+// measureVersions makes versions new versions of a list and a map of keys entries each way, one change a version,
+// and times them
+func measureVersions(ctx context.Context, rng *rand.Rand, keys, versions int) ([]VersionResult, *Map[int], *Map[int], map[int]int) {
+	var list *List[int]
+	slice := []int{}
+	var first *Map[int]
+	goMap := map[int]int{}
+	for k := range keys {
+		list = list.Push(k)
+		slice = append(slice, k)
+		first = first.Set(k, k)
+		goMap[k] = k
+	}
+	changes := make([][2]int, versions)
+	for i := range changes {
+		changes[i] = [2]int{rng.Intn(keys), rng.Int()}
+	}
+
+	// every version is kept, as a program keeping an undo history or old snapshots would
+	results := []VersionResult{}
+	lists := make([]*List[int], 0, versions)
+	elapsed := bench.Phase(ctx, "list push", func() {
+		for _, c := range changes {
+			list = list.Push(c[1])
+			lists = append(lists, list)
+		}
+	})
+	results = append(results, VersionResult{Name: "List push", Ns: perOp(elapsed, versions), Copied: 1})
+	// each copied slice is dropped once it's made, keeping every one would take versions x keys entries of memory
+	elapsed = bench.Phase(ctx, "slice copy", func() {
+		for _, c := range changes {
+			slice = append(slices.Clone(slice), c[1])
+		}
+	})
+	results = append(results, VersionResult{Name: "Slice copy", Ns: perOp(elapsed, versions), Copied: float64(keys) + float64(versions+1)/2})
+
+	latest := first
+	tries := make([]*Map[int], 0, versions)
+	elapsed = bench.Phase(ctx, "trie set", func() {
+		for _, c := range changes {
+			latest = latest.Set(c[0], c[1])
+			tries = append(tries, latest)
+		}
+	})
+	results = append(results, VersionResult{Name: "Trie set", Ns: perOp(elapsed, versions), Copied: float64(latest.Copied()-first.Copied()) / float64(versions)})
+	clone := goMap
+	elapsed = bench.Phase(ctx, "map copy", func() {
+		for _, c := range changes {
+			clone = maps.Clone(clone)
+			clone[c[0]] = c[1]
+		}
+	})
+	results = append(results, VersionResult{Name: "Map copy", Ns: perOp(elapsed, versions), Copied: float64(keys)})
+	return results, first, latest, clone
+}
+
+// measureLookups times looking keys up in the trie and in Go's map
+func measureLookups(ctx context.Context, rng *rand.Rand, trie *Map[int], goMap map[int]int, lookups int) []LookupResult {
+	keys := make([]int, lookups)
+	for i := range keys {
+		keys[i] = rng.Intn(len(goMap))
+	}
+	sum := 0
+	trieTime := bench.Phase(ctx, "trie get", func() {
+		for _, k := range keys {
+			v, _ := trie.Get(k)
+			sum += v
+		}
+	})
+	mapTime := bench.Phase(ctx, "map get", func() {
+		for _, k := range keys {
+			sum += goMap[k]
+		}
+	})
+	return []LookupResult{{"Trie", perOp(trieTime, lookups)}, {"Go map", perOp(mapTime, lookups)}}
+}
+
+// changed counts the keys 0 to keys-1 whose values differ between two versions
+func changed(a, b *Map[int], keys int) int {
+	n := 0
+	for k := range keys {
+		va, _ := a.Get(k)
+		vb, _ := b.Get(k)
+		if va != vb {
+			n++
+		}
+	}
+	return n
+}
+
+// balance is what every account starts with, the sum of all of them never changes, a transfer moves money from one
+// account to another in a single version
+const balance = 100
+
+// transfers returns the writer's moves, from, to and amount
+func transfers(rng *rand.Rand, accounts, count int) [][3]int {
+	moves := make([][3]int, count)
+	for i := range moves {
+		moves[i] = [3]int{rng.Intn(accounts), rng.Intn(accounts), 1 + rng.Intn(balance/10)}
+	}
+	return moves
+}
+
+// sharing is a way of sharing the accounts between the writer and the readers, sum is a reader's sum of them all and
+// transfer is the writer's move of amount from one account to another
+type sharing struct {
+	name     string
+	sum      func() int
+	transfer func(from, to, amount int)
+}
+
+// snapshots has the writer publish each transfer as a new version through an atomic pointer, readers load the latest
+// version and sum it without locking, the version they loaded can't change under them
+// There's only one writer, so it can load, change and store without a compare and swap
+func snapshots(accounts int) sharing {
+	var m *Map[int]
+	for a := range accounts {
+		m = m.Set(a, balance)
+	}
+	var current atomic.Pointer[Map[int]]
+	current.Store(m)
+	return sharing{
+		name: "Immutable snapshots",
+		sum: func() int {
+			total := 0
+			for _, v := range current.Load().All() {
+				total += v
+			}
+			return total
+		},
+		transfer: func(from, to, amount int) {
+			m := current.Load()
+			balance, _ := m.Get(from)
+			m = m.Set(from, balance-amount)
+			balance, _ = m.Get(to)
+			current.Store(m.Set(to, balance+amount))
+		},
+	}
+}
+
+// locked has the writer change a map in place under a write lock, readers hold the read lock for a whole sum, as
+// letting go partway through could see money taken from one account and not yet added to another
+func locked(accounts int) sharing {
+	m := map[int]int{}
+	for a := range accounts {
+		m[a] = balance
+	}
+	var mu sync.RWMutex
+	return sharing{
+		name: "Locked map",
+		sum: func() int {
+			mu.RLock()
+			defer mu.RUnlock()
+			total := 0
+			for _, v := range m {
+				total += v
+			}
+			return total
+		},
+		transfer: func(from, to, amount int) {
+			mu.Lock()
+			m[from] -= amount
+			m[to] += amount
+			mu.Unlock()
+		},
+	}
+}
+
+// runConcurrent starts readers each making sums sums of the accounts, and times the writer making its moves alongside
+// them
+func runConcurrent(ctx context.Context, s sharing, accounts, readers, sums int, moves [][3]int) ConcurrentResult {
+	result := ConcurrentResult{Name: s.name}
+	var inconsistent, readNs atomic.Int64
+	var wg sync.WaitGroup
+	for range readers {
+		wg.Go(func() {
+			start := time.Now()
+			for range sums {
+				if s.sum() != accounts*balance {
+					inconsistent.Add(1)
+				}
+			}
+			readNs.Add(time.Since(start).Nanoseconds())
+		})
+	}
+	result.WriterNs = float64(bench.Phase(ctx, s.name, func() {
+		for _, mv := range moves {
+			s.transfer(mv[0], mv[1], mv[2])
+		}
+	}).Nanoseconds())
+	wg.Wait()
+	result.SumNs = float64(readNs.Load()) / float64(readers*sums)
+	result.Inconsistent = int(inconsistent.Load())
+	return result
+}
+
+func perOp(d time.Duration, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(d.Nanoseconds()) / float64(n)
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Persistent Data Structures")
+	fmt.Fprintln(w, "==========================")
+	fmt.Fprintf(w, "Keys: %d\nVersions: %d\nReaders: %d\nAccounts: %d\nSums: %d\nTransfers: %d\n",
+		config.Keys, config.Versions, config.Readers, config.Accounts, config.Sums, config.Transfers)
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "Making %d versions of a list and a map of %d entries, one change each\n", config.Versions, config.Keys)
+	fmt.Fprintf(w, "%-12s %14s %18s\n", "Version", "Time/version", "Copied/version")
+	for _, v := range result.Versions {
+		fmt.Fprintf(w, "%-12s %14v %18.1f\n", v.Name, time.Duration(v.Ns), v.Copied)
+	}
+	fmt.Fprintf(w, "\n%-12s %14s\n", "Lookup", "Time/op")
+	for _, l := range result.Lookups {
+		fmt.Fprintf(w, "%-12s %14v\n", l.Name, time.Duration(l.Ns))
+	}
+	fmt.Fprintln(w, "\nThe trie copies the handful of nodes on the path to the change and shares the rest, the copies copy")
+	fmt.Fprintln(w, "everything, a trie lookup follows a few pointers where Go's map goes straight to a bucket")
+
+	fmt.Fprintf(w, "\nThe first version of the map, read again after all %d changes, has %d keys changed, the latest\n", config.Versions, result.SnapshotChanged)
+	fmt.Fprintf(w, "version differs from it in %d\n", result.LatestChanged)
+
+	fmt.Fprintf(w, "\n=====%d readers summing %d accounts %d times while a writer makes %d transfers=====\n",
+		config.Readers, config.Accounts, config.Sums, config.Transfers)
+	fmt.Fprintf(w, "%-20s %14s %14s %14s\n", "Sharing", "Writer time", "Time/sum", "Inconsistent")
+	for _, c := range result.Concurrent {
+		fmt.Fprintf(w, "%-20s %14v %14v %14d\n", c.Name, time.Duration(c.WriterNs), time.Duration(c.SumNs), c.Inconsistent)
+	}
+	fmt.Fprintln(w, "\nBoth see every transfer whole, a snapshot because it can't change once published, the map because")
+	fmt.Fprintln(w, "the read lock keeps the writer out for a whole sum, snapshot readers take no lock and never hold the")
+	fmt.Fprintln(w, "writer up, the writer pays instead, copying a path for every transfer where the map changes in place")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo persistent
+func Main(args []string) {
+	fs := bench.NewFlagSet("persistent", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the persistent data structures lesson measures a single run"
+	keys := fs.Int("keys", 50000, "the number of entries in the list and the map versions are made of")
+	versions := fs.Int("versions", 1000, "the number of versions made, one change each")
+	lookups := fs.Int("lookups", 1000000, "the number of lookups timed in the trie and in Go's map")
+	readers := fs.Int("readers", 4, "the number of goroutines summing the accounts while the writer transfers")
+	accounts := fs.Int("accounts", 1000, "the number of accounts the writer transfers between")
+	sums := fs.Int("sums", 200, "the number of times each reader sums the accounts")
+	numTransfers := fs.Int("transfers", 20000, "the number of transfers the writer makes")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "persistent measures a single run, -trials isn't supported")
+	v.AtLeast("keys", *keys, 1)
+	v.AtLeast("versions", *versions, 1)
+	v.AtLeast("lookups", *lookups, 1)
+	v.AtLeast("readers", *readers, 1)
+	v.AtLeast("accounts", *accounts, 2)
+	v.AtLeast("sums", *sums, 1)
+	v.AtLeast("transfers", *numTransfers, 1)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	rng := rand.New(rand.NewSource(globals.Seed))
+	result := RunResult{
+		Config: RunConfig{Keys: *keys, Versions: *versions, Lookups: *lookups, Readers: *readers, Accounts: *accounts,
+			Sums: *sums, Transfers: *numTransfers, Seed: globals.Seed},
+		Env: bench.CaptureEnv(),
+	}
+	ctx := context.Background()
+	slog.Info("making versions", "keys", *keys, "versions", *versions)
+	versionResults, first, latest, goMap := measureVersions(ctx, rng, *keys, *versions)
+	result.Versions = versionResults
+	// the first version mapped every key to itself, which a version built afresh the same way still does
+	var initial *Map[int]
+	for k := range *keys {
+		initial = initial.Set(k, k)
+	}
+	result.SnapshotChanged = changed(first, initial, *keys)
+	result.LatestChanged = changed(first, latest, *keys)
+	slog.Info("looking up keys", "lookups", *lookups)
+	result.Lookups = measureLookups(ctx, rng, latest, goMap, *lookups)
+
+	moves := transfers(rng, *accounts, *numTransfers)
+	slog.Info("transferring", "readers", *readers, "transfers", *numTransfers)
+	result.Concurrent = []ConcurrentResult{
+		runConcurrent(ctx, snapshots(*accounts), *accounts, *readers, *sums, moves),
+		runConcurrent(ctx, locked(*accounts), *accounts, *readers, *sums, moves),
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per way of making a version, with the entries it copied, a line per lookup, and a
+// line per way of sharing the accounts timing the writer's transfers
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/persistent"); err != nil {
+		return err
+	}
+	config := result.Config
+	benchmarks := []bench.Benchmark{}
+	for _, v := range result.Versions {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name:    fmt.Sprintf("Version/%s/keys=%d", v.Name, config.Keys),
+			N:       int64(config.Versions),
+			Metrics: []bench.Metric{{Value: v.Ns, Unit: "ns/op"}, {Value: v.Copied, Unit: "copied/op"}},
+		})
+	}
+	for _, l := range result.Lookups {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name:    fmt.Sprintf("Lookup/%s/keys=%d", l.Name, config.Keys),
+			N:       int64(config.Lookups),
+			Metrics: []bench.Metric{{Value: l.Ns, Unit: "ns/op"}},
+		})
+	}
+	for _, c := range result.Concurrent {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name:    fmt.Sprintf("Transfer/%s/readers=%d/accounts=%d", c.Name, config.Readers, config.Accounts),
+			N:       int64(config.Transfers),
+			Metrics: []bench.Metric{{Value: c.WriterNs / float64(config.Transfers), Unit: "ns/op"}, {Value: c.SumNs, Unit: "ns/sum"}},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
@@ -0,0 +1,94 @@
+package persistent
+
+import (
+	"maps"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestListVersionsShareTheirTails(t *testing.T) {
+	var empty *List[int]
+	one := empty.Push(1)
+	two := one.Push(2)
+	other := one.Push(3)
+	if two.Tail() != one || other.Tail() != one {
+		t.Fatalf("pushing onto a list didn't share it as the tail")
+	}
+	if got := slices.Collect(two.All()); !slices.Equal(got, []int{2, 1}) {
+		t.Errorf("two.All() = %v, want [2 1]", got)
+	}
+	if got := slices.Collect(other.All()); !slices.Equal(got, []int{3, 1}) {
+		t.Errorf("other.All() = %v, want [3 1]", got)
+	}
+	if one.Len() != 1 || two.Len() != 2 || empty.Len() != 0 {
+		t.Errorf("lengths = %d, %d, %d, want 1, 2, 0", one.Len(), two.Len(), empty.Len())
+	}
+	if _, ok := empty.Head(); ok {
+		t.Errorf("the empty list has a head")
+	}
+}
+
+func TestMapMatchesGoMap(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var m *Map[int]
+	want := map[int]int{}
+	for i := range 20000 {
+		key := rng.Intn(2000)
+		if rng.Intn(3) == 0 {
+			m = m.Delete(key)
+			delete(want, key)
+		} else {
+			m = m.Set(key, i)
+			want[key] = i
+		}
+		if m.Len() != len(want) {
+			t.Fatalf("after %d changes Len() = %d, want %d", i+1, m.Len(), len(want))
+		}
+	}
+	for key := range 2000 {
+		got, ok := m.Get(key)
+		if w, wok := want[key]; ok != wok || got != w {
+			t.Fatalf("Get(%d) = %d, %v, want %d, %v", key, got, ok, w, wok)
+		}
+	}
+	if got := maps.Collect(m.All()); !maps.Equal(got, want) {
+		t.Errorf("All() yielded %d keys, want the %d set", len(got), len(want))
+	}
+}
+
+func TestMapOldVersionsAreUnchanged(t *testing.T) {
+	var m *Map[string]
+	for k := range 1000 {
+		m = m.Set(k, "first")
+	}
+	first := m
+	for k := range 1000 {
+		if k%2 == 0 {
+			m = m.Set(k, "second")
+		} else {
+			m = m.Delete(k)
+		}
+	}
+	for k := range 1000 {
+		if v, ok := first.Get(k); !ok || v != "first" {
+			t.Fatalf("the first version's key %d = %q, %v after later changes, want \"first\"", k, v, ok)
+		}
+	}
+	if first.Len() != 1000 || m.Len() != 500 {
+		t.Errorf("lengths = %d, %d, want 1000, 500", first.Len(), m.Len())
+	}
+}
+
+func TestMapSetCopiesAPath(t *testing.T) {
+	var m *Map[int]
+	for k := range 100000 {
+		m = m.Set(k, k)
+	}
+	before := m.Copied()
+	m = m.Set(12345, 0)
+	// four levels of at most 32 entries
+	if copied := m.Copied() - before; copied > 4*32 {
+		t.Errorf("changing one of 100000 keys copied %d entries, want a path's worth", copied)
+	}
+}
@@ -0,0 +1,63 @@
+package persistent
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz persistent, it asks how path copying makes cheap versions of the trie, what that costs
+// a lookup, and why immutable snapshots let readers run without a lock while the writer keeps going
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does a new version of the trie copy only a handful of entries where a new copy of the map copies all of them?",
+		Choices: []string{
+			"The trie stores only the keys that changed, and reads fall back to the previous version",
+			"The trie copies lazily, a node is only copied the next time one of its keys is read",
+			"The trie packs many entries into each node, so the few nodes copied hold everything",
+			"Only the nodes on the path to the changed key are copied, the rest are shared",
+		},
+		Answer:      3,
+		Explanation: "a 32-way trie of tens of thousands of keys is three or four levels deep, so a change copies three or four small nodes and points the copies at the old, unchanged ones",
+	},
+	{
+		Prompt: "Why can the first version of the map still be read exactly as it was made after a thousand later versions?",
+		Choices: []string{
+			"Each version records its changes in a log, which is replayed backwards to undo them",
+			"No node changes once made, later versions build new ones, so the first root's nodes stay",
+			"Every version is copied in full when it is made, so the first is a copy nobody writes to",
+			"The garbage collector keeps the first version's nodes pinned and restores them on read",
+		},
+		Answer:      1,
+		Explanation: "sharing is only safe because nothing is changed in place, a node shared by two versions would otherwise change both",
+	},
+	{
+		Prompt: "Why is a trie lookup slower than a lookup in Go's map?",
+		Choices: []string{
+			"It follows a pointer to a new node every level, each likely a cache miss",
+			"It hashes the key again at every level to pick the next child",
+			"It takes a read lock on every node it passes so a writer can't change it",
+			"It searches every child of a node in turn to find the one for the key",
+		},
+		Answer:      0,
+		Explanation: "Go's map hashes straight to a bucket, cheap versions and snapshots are bought with a few more pointer hops on every read, a constant factor rather than a worse complexity",
+	},
+	{
+		Prompt: "Why can a reader sum an immutable snapshot without a lock while the writer keeps transferring?",
+		Choices: []string{
+			"Loading the atomic pointer locks the snapshot until the reader is done with it",
+			"The reader copies the whole map first and sums the copy, which the writer can't reach",
+			"The snapshot it loaded never changes, so it sees each transfer whole or not at all",
+			"With one goroutine per CPU, the reader and the writer never run at the same time",
+		},
+		Answer:      2,
+		Explanation: "the writer's transfers make new versions, a transfer takes from one account and adds to another in one new version, published by a single atomic store, so no reader can see the money in neither account",
+	},
+	{
+		Prompt: "Why does the writer with the locked map take longer than the writer publishing snapshots while readers sum?",
+		Choices: []string{
+			"A write to Go's map is slower than a write to the trie once the map is large",
+			"The RWMutex hands the lock to readers first, so the writer only gets it when none are left",
+			"The locked map is copied on every write so the readers can keep their old view",
+			"Each reader holds the read lock for a whole sum, and the writer has to wait for them all",
+		},
+		Answer:      3,
+		Explanation: "the lock is the only thing keeping a sum from seeing half a transfer, so it has to be held for the whole sum, with snapshots readers and the writer never wait on each other",
+	},
+}
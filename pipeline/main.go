@@ -0,0 +1,284 @@
+// Package pipeline is the pipeline lesson, teachgo pipeline, a generate, hash, look up and aggregate pipeline of
+// channels with its stages fanned out over goroutines and fanned back in, against one goroutine doing it all in turn
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo pipeline -h, its first line is the summary teachgo help
+// lists
+const Description = `Pipelines, stages of goroutines joined by channels, fanned out and back in
+
+Pushes -items integers through four stages joined by channels, a generator, a CPU bound hash of -rounds rounds,
+a lookup that waits -latency as a call to a database would, and an aggregate summing the answers, first with one
+goroutine doing every stage for each item in turn, then as a pipeline with the lookup fanned out over each of
+-fan-out workers and their results fanned back in to one channel. Measures each stage's capacity, the items a
+second it could handle with its workers always busy, showing the pipeline running at the pace of its slowest stage
+and fanning out the waiting stage lifting that pace. Then cancels a pipeline halfway through and checks every one
+of its goroutines returns, the context reaching every stage whether it was waiting to receive or to send.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "pipeline",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"channels", "pipelines", "fan-out", "fan-in", "cancellation"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Items       int           `json:"items"`
+	Rounds      int           `json:"rounds"`
+	Latency     time.Duration `json:"latency"`
+	HashWorkers int           `json:"hash_workers"`
+	FanOut      []int         `json:"fan_out"`
+}
+
+// StageResult is what one stage of a pipeline did
+type StageResult struct {
+	Name     string  `json:"name"`
+	Workers  int     `json:"workers"`
+	Items    int64   `json:"items"`
+	BusyNs   float64 `json:"busy_ns"`
+	Capacity float64 `json:"capacity"`
+}
+
+// Run is the items pushed through once, by one goroutine or a pipeline, Sum is the aggregate, the same for any order
+// the items arrive in
+type Run struct {
+	Name      string        `json:"name"`
+	ElapsedNs float64       `json:"elapsed_ns"`
+	Sum       uint64        `json:"sum"`
+	Stages    []StageResult `json:"stages,omitempty"`
+}
+
+// CancelResult is a pipeline cancelled partway, Goroutines is how many it was running, Leaked how many were left
+// once it had stopped
+type CancelResult struct {
+	After      int     `json:"after"`
+	Goroutines int     `json:"goroutines"`
+	StopNs     float64 `json:"stop_ns"`
+	Leaked     int     `json:"leaked"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config RunConfig    `json:"config"`
+	Env    bench.Env    `json:"env"`
+	Runs   []Run        `json:"runs"`
+	Cancel CancelResult `json:"cancel"`
+}
+
+// hash is the CPU bound stage, rounds of a 64 bit mixing function
+func hash(rounds int) func(uint64) uint64 {
+	return func(x uint64) uint64 {
+		for range rounds {
+			x ^= x >> 31
+			x *= 0x9e3779b97f4a7c15
+			x ^= x >> 29
+		}
+		return x
+	}
+}
+
+// lookup is the stage that waits, standing for a call to a database or another service, it sleeps for latency then
+// answers with a small number
+func lookup(latency time.Duration) func(uint64) uint64 {
+	return func(x uint64) uint64 {
+		time.Sleep(latency)
+		return x % 1000
+	}
+}
+
+// sequential does every stage for each item in turn on one goroutine
+func sequential(ctx context.Context, config RunConfig) Run {
+	hashOne, lookupOne := hash(config.Rounds), lookup(config.Latency)
+	run := Run{Name: "one goroutine"}
+	run.ElapsedNs = float64(bench.Phase(ctx, "one goroutine", func() {
+		for i := range config.Items {
+			run.Sum += lookupOne(hashOne(uint64(i)))
+		}
+	}).Nanoseconds())
+	return run
+}
+
+// stages builds the pipeline on ctx, returning the channel of looked up answers and the stages' stats
+func stages(ctx context.Context, config RunConfig, lookupWorkers int) (<-chan uint64, []*StageStats) {
+	hashStats := NewStageStats("hash", config.HashWorkers)
+	lookupStats := NewStageStats("lookup", lookupWorkers)
+	hashed := Stage(ctx, Generate(ctx, config.Items), hashStats, hash(config.Rounds))
+	answers := Stage(ctx, hashed, lookupStats, lookup(config.Latency))
+	return answers, []*StageStats{hashStats, lookupStats}
+}
+
+// pipelined runs the items through the pipeline, the aggregate stage summing the answers as they arrive
+func pipelined(ctx context.Context, config RunConfig, lookupWorkers int) Run {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	run := Run{Name: fmt.Sprintf("pipeline, %d lookup workers", lookupWorkers)}
+	var stats []*StageStats
+	run.ElapsedNs = float64(bench.Phase(ctx, run.Name, func() {
+		var answers <-chan uint64
+		answers, stats = stages(ctx, config, lookupWorkers)
+		for answer := range answers {
+			run.Sum += answer
+		}
+	}).Nanoseconds())
+	for _, s := range stats {
+		run.Stages = append(run.Stages, StageResult{Name: s.Name, Workers: s.Workers, Items: s.Items(),
+			BusyNs: float64(s.Busy().Nanoseconds()), Capacity: s.Capacity()})
+	}
+	return run
+}
+
+// cancelHalfway runs a pipeline until the aggregate has half the items, then cancels it and waits for its goroutines
+// to return, giving up on any after a second
+func cancelHalfway(config RunConfig, lookupWorkers int) CancelResult {
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	answers, _ := stages(ctx, config, lookupWorkers)
+	result := CancelResult{After: config.Items / 2, Goroutines: runtime.NumGoroutine() - before}
+	for range result.After {
+		<-answers
+	}
+	result.StopNs = float64(bench.Phase(context.Background(), "cancel", func() {
+		cancel()
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+			time.Sleep(100 * time.Microsecond)
+		}
+	}).Nanoseconds())
+	result.Leaked = max(runtime.NumGoroutine()-before, 0)
+	return result
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Pipelines")
+	fmt.Fprintln(w, "=========")
+	fmt.Fprintf(w, "Items: %d\nHash: %d rounds, %d workers\nLookup: %v\n", config.Items, config.Rounds, config.HashWorkers, config.Latency)
+	fmt.Fprintf(w, "Machine: %s\n", result.Env)
+
+	fmt.Fprintln(w, "\n=====Runs=====")
+	fmt.Fprintf(w, "%-30s %14s %12s %16s %16s %8s\n", "Run", "Elapsed", "Items/s", "Hash capacity", "Lookup capacity", "Sum")
+	first := result.Runs[0].Sum
+	agree := true
+	for _, run := range result.Runs {
+		capacities := []string{"-", "-"}
+		for i, s := range run.Stages {
+			capacities[i] = bench.FormatRate(s.Capacity)
+		}
+		agree = agree && run.Sum == first
+		fmt.Fprintf(w, "%-30s %14v %12s %16s %16s %8d\n", run.Name, time.Duration(run.ElapsedNs),
+			bench.FormatRate(float64(config.Items)/(run.ElapsedNs/1e9)), capacities[0], capacities[1], run.Sum)
+	}
+	fmt.Fprintf(w, "\nEvery run summed to the same answer: %v\n", agree)
+	fmt.Fprintln(w, "A stage's capacity is the items a second it could take with every worker busy, one goroutine waits on")
+	fmt.Fprintln(w, "each lookup in turn, a pipeline hashes the next item while the last one's lookup waits, and fanning")
+	fmt.Fprintln(w, "the lookup out lets its workers wait at once, the pipeline keeping pace with its slowest stage")
+
+	c := result.Cancel
+	fmt.Fprintln(w, "\n=====Cancelling halfway=====")
+	fmt.Fprintf(w, "Goroutines running the pipeline: %d\n", c.Goroutines)
+	fmt.Fprintf(w, "Cancelled after %d items reached the aggregate, the goroutines returned in %v\n", c.After, time.Duration(c.StopNs))
+	fmt.Fprintf(w, "Goroutines left over: %d\n", c.Leaked)
+	fmt.Fprintln(w, "Every send and receive also waits on the context, so each goroutine notices the cancel wherever it's")
+	fmt.Fprintln(w, "blocked, without the select on ctx.Done one blocked sending to a stage that stopped reading would leak")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo pipeline
+func Main(args []string) {
+	fs := bench.NewFlagSet("pipeline", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the pipeline lesson measures a single run"
+	items := fs.Int("items", 1000, "the number of integers pushed through")
+	rounds := fs.Int("rounds", 1000, "the rounds of mixing the hash stage does to each item")
+	latency := fs.Duration("latency", time.Millisecond, "how long the lookup stage waits for each item")
+	hashWorkers := fs.Int("hash-workers", 1, "the goroutines the hash stage is fanned out over")
+	fanOutList := fs.String("fan-out", "1,4,16,64", "comma separated numbers of goroutines to fan the lookup stage out over, a pipeline for each")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "pipeline measures a single run, -trials isn't supported")
+	v.AtLeast("items", *items, 2)
+	v.AtLeast("rounds", *rounds, 0)
+	v.NotNegative("latency", *latency)
+	v.AtLeast("hash-workers", *hashWorkers, 1)
+	fanOut := []int{}
+	for _, field := range strings.Split(*fanOutList, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		v.Check(err == nil && n >= 1, "-fan-out must be whole numbers of at least 1, got %q", field)
+		fanOut = append(fanOut, n)
+	}
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	config := RunConfig{Items: *items, Rounds: *rounds, Latency: *latency, HashWorkers: *hashWorkers, FanOut: fanOut}
+	result := RunResult{Config: config, Env: bench.CaptureEnv()}
+	ctx := context.Background()
+	slog.Info("running on one goroutine", "items", *items)
+	result.Runs = append(result.Runs, sequential(ctx, config))
+	for _, n := range fanOut {
+		slog.Info("running a pipeline", "lookup_workers", n)
+		result.Runs = append(result.Runs, pipelined(ctx, config, n))
+	}
+	slog.Info("cancelling a pipeline halfway", "lookup_workers", fanOut[len(fanOut)-1])
+	result.Cancel = cancelHalfway(config, fanOut[len(fanOut)-1])
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per run, an op is one item, with each stage's busy time per item for the pipelines
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/pipeline"); err != nil {
+		return err
+	}
+	config := result.Config
+	items := int64(config.Items)
+	benchmarks := []bench.Benchmark{}
+	for _, run := range result.Runs {
+		name := "Sequential"
+		metrics := []bench.Metric{bench.NsPerOp(run.ElapsedNs, items)}
+		if len(run.Stages) > 0 {
+			name = fmt.Sprintf("Pipeline/hash=%d/lookup=%d", run.Stages[0].Workers, run.Stages[1].Workers)
+			for _, s := range run.Stages {
+				metrics = append(metrics, bench.Metric{Value: s.BusyNs / float64(max(s.Items, 1)), Unit: s.Name + "-busy-ns/op"})
+			}
+		}
+		benchmarks = append(benchmarks, bench.Benchmark{Name: fmt.Sprintf("%s/items=%d", name, config.Items), N: items, Metrics: metrics})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
@@ -0,0 +1,121 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StageStats counts what a stage did, its workers record into it at once
+type StageStats struct {
+	Name    string
+	Workers int
+	items   atomic.Int64
+	busyNs  atomic.Int64
+}
+
+// NewStageStats is the stats of a stage run on workers goroutines
+func NewStageStats(name string, workers int) *StageStats {
+	return &StageStats{Name: name, Workers: workers}
+}
+
+func (s *StageStats) record(elapsed time.Duration) {
+	s.items.Add(1)
+	s.busyNs.Add(elapsed.Nanoseconds())
+}
+
+// Items is the number of values the stage has processed
+func (s *StageStats) Items() int64 {
+	return s.items.Load()
+}
+
+// Busy is the time the stage's workers spent processing, added up over all of them, not waiting to receive or send
+func (s *StageStats) Busy() time.Duration {
+	return time.Duration(s.busyNs.Load())
+}
+
+// Capacity is the values a second the stage could process with every worker always busy, the pipeline as a whole
+// goes no faster than its stage with the least
+func (s *StageStats) Capacity() float64 {
+	if s.busyNs.Load() == 0 {
+		return 0
+	}
+	return float64(s.items.Load()) * float64(s.Workers) / (float64(s.busyNs.Load()) / 1e9)
+}
+
+// Generate sends the integers from 0 to n-1, closing the channel after the last or once ctx is done
+func Generate(ctx context.Context, n int) <-chan uint64 {
+	out := make(chan uint64)
+	go func() {
+		defer close(out)
+		for i := range n {
+			select {
+			case out <- uint64(i):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Stage fans the values from in out over stats.Workers goroutines, each taking the next value, applying fn and
+// sending the result on a channel of its own, and fans their results back in to the one channel returned
+// Every send and receive also waits on ctx, so when it's done each goroutine returns, whether it was waiting on the
+// stage before or the one after, and the channels close behind them, cancellation reaching every stage at once
+// The results come out in whatever order the workers finish them
+func Stage[In, Out any](ctx context.Context, in <-chan In, stats *StageStats, fn func(In) Out) <-chan Out {
+	outs := make([]<-chan Out, stats.Workers)
+	for i := range outs {
+		out := make(chan Out)
+		outs[i] = out
+		go func() {
+			defer close(out)
+			for {
+				var v In
+				var ok bool
+				select {
+				case v, ok = <-in:
+					if !ok {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+				start := time.Now()
+				result := fn(v)
+				stats.record(time.Since(start))
+				select {
+				case out <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	return Merge(ctx, outs...)
+}
+
+// Merge sends everything received on the channels on the one returned, closing it once they're all closed, or ctx
+// is done
+func Merge[T any](ctx context.Context, channels ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	for _, c := range channels {
+		wg.Go(func() {
+			for v := range c {
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
@@ -0,0 +1,56 @@
+package pipeline
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestStageProcessesEveryValue(t *testing.T) {
+	for _, workers := range []int{1, 3, 8} {
+		stats := NewStageStats("double", workers)
+		out := Stage(context.Background(), Generate(context.Background(), 1000), stats, func(x uint64) uint64 { return 2 * x })
+		var sum uint64
+		count := 0
+		for v := range out {
+			sum += v
+			count++
+		}
+		if count != 1000 || sum != 999*1000 {
+			t.Errorf("%d workers: got %d values summing to %d, want 1000 summing to %d", workers, count, sum, 999*1000)
+		}
+		if stats.Items() != 1000 {
+			t.Errorf("%d workers: stats counted %d items, want 1000", workers, stats.Items())
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	ctx := context.Background()
+	var sum uint64
+	for v := range Merge(ctx, Generate(ctx, 10), Generate(ctx, 20)) {
+		sum += v
+	}
+	if sum != 45+190 {
+		t.Errorf("merged values sum to %d, want %d", sum, 45+190)
+	}
+}
+
+func TestCancelStopsEveryGoroutine(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	first := Stage(ctx, Generate(ctx, 1_000_000), NewStageStats("a", 4), func(x uint64) uint64 { return x })
+	second := Stage(ctx, first, NewStageStats("b", 4), func(x uint64) uint64 { return x })
+	for range 10 {
+		<-second
+	}
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines left after cancelling, want %d", n, before)
+	}
+}
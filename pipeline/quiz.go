@@ -0,0 +1,63 @@
+package pipeline
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz pipeline, it asks why the slowest stage sets the pace, when fanning a stage out helps
+// even on one CPU, what fan-in does to order, and how cancellation leaves no goroutine behind
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why is a pipeline with one lookup worker barely faster than one goroutine?",
+		Choices: []string{
+			"Every channel send costs more than the hashing it saves, so the stages can't gain",
+			"It only overlaps hashing with the previous lookup, and the lookup's wait is most of it",
+			"The aggregate stage reads one item at a time, so it holds up the stages before it",
+			"The pipeline hashes each item twice, once to route it and once to look it up",
+		},
+		Answer:      1,
+		Explanation: "the pipeline can go no faster than its slowest stage, pipelining helps most when the stages take similar time, here the lookup's capacity sets the pace",
+	},
+	{
+		Prompt: "Why does fanning the lookup out speed things up even with one CPU?",
+		Choices: []string{
+			"The lookups repeat, so the extra workers answer most of them from a cache",
+			"The runtime adds a thread for every blocked goroutine, giving the workers more CPUs",
+			"The workers share the hashing, so each item's hash is computed by several at once",
+			"A lookup waits rather than computes, and waiting goroutines take no CPU",
+		},
+		Answer:      3,
+		Explanation: "many workers can each wait on a lookup at once, fanning out the hash stage wouldn't help on one CPU, its work already keeps the CPU busy",
+	},
+	{
+		Prompt: "Why does every run sum to the same answer when fanned out workers finish items in any order?",
+		Choices: []string{
+			"Addition doesn't depend on order, an aggregate that did would have to reorder",
+			"The merge numbers the results and puts them back in order before summing",
+			"Each worker takes every nth item, so the results interleave in input order",
+			"The channels are first in, first out, so results leave in the order items arrive",
+		},
+		Answer:      0,
+		Explanation: "fan-in through one channel interleaves the workers' results as they finish, the input's order is lost, an order sensitive aggregate would need the items numbered",
+	},
+	{
+		Prompt: "What does a stage's capacity in the table measure?",
+		Choices: []string{
+			"The items it actually handled a second over the whole run",
+			"The size of the buffer on the channel feeding the stage",
+			"Its workers over its busy time per item, its rate if it never waited",
+			"How many goroutines it started and is still running",
+		},
+		Answer:      2,
+		Explanation: "the stage with the least capacity is the bottleneck, the others spend the difference blocked on channels",
+	},
+	{
+		Prompt: "Why are no goroutines left over after the pipeline is cancelled halfway?",
+		Choices: []string{
+			"The runtime stops every goroutine started with a context once it is cancelled",
+			"The aggregate keeps reading until every channel is empty, unblocking the senders",
+			"Each send and receive also selects on ctx.Done, so every goroutine can return",
+			"The unreachable channels are collected, and their blocked goroutines with them",
+		},
+		Answer:      2,
+		Explanation: "each goroutine returns wherever it's blocked and closes its channel behind it, a goroutine blocked sending to a stage that's stopped reading would wait forever without that select, a leak",
+	},
+}
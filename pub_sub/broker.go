@@ -0,0 +1,263 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed is returned by Subscribe and Publish once the broker is closed
+var ErrClosed = errors.New("the broker is closed")
+
+// Policy is what a publish does when a subscriber's channel is full, a subscriber that reads too slowly
+type Policy int
+
+const (
+	// Block waits for room, holding up the publisher, and every subscriber after this one, until the subscriber
+	// reads or the publish's context ends
+	Block Policy = iota
+	// Drop turns the message away, the subscriber misses it and the publisher carries straight on
+	Drop
+	// Buffer queues the message for the subscriber without limit, the publisher carries straight on and the
+	// subscriber gets everything, at the price of the memory its backlog takes
+	Buffer
+)
+
+func (p Policy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case Drop:
+		return "drop"
+	default:
+		return "buffer"
+	}
+}
+
+// Message is one publish as a subscriber receives it, Seq counts the broker's publishes from 1 and Sent is when the
+// publish began
+type Message struct {
+	Topic   string
+	Seq     uint64
+	Sent    time.Time
+	Payload []byte
+}
+
+// Subscription is one subscriber to a topic, it receives on C, which is closed once it unsubscribes or the broker
+// closes
+type Subscription struct {
+	C <-chan Message
+
+	broker  *Broker
+	topic   string
+	policy  Policy
+	ch      chan Message
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Int64
+
+	// the Buffer policy's backlog, moved onto ch by a goroutine of its own as the subscriber makes room
+	mu      sync.Mutex
+	queue   []Message
+	wake    chan struct{}
+	closing bool
+}
+
+// Dropped is the messages the subscription missed, turned away when it was full under Drop, or given up on when a
+// publish's context ended while waiting for it under Block
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Buffered is the messages waiting in a Buffer subscription's backlog, not yet on its channel
+func (s *Subscription) Buffered() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+// Unsubscribe stops delivering to the subscription and closes C, anything not yet received is lost
+// A publisher blocked waiting for room gives up on the subscription at once
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() { close(s.done) })
+	s.broker.remove(s)
+}
+
+// deliver hands m to the subscription under its policy, reporting whether it got it
+func (s *Subscription) deliver(ctx context.Context, m Message) bool {
+	switch s.policy {
+	case Drop:
+		select {
+		case s.ch <- m:
+			return true
+		default:
+		}
+	case Block:
+		// a send that can go at once shouldn't lose a race with a context that has already ended
+		select {
+		case s.ch <- m:
+			return true
+		default:
+		}
+		select {
+		case s.ch <- m:
+			return true
+		case <-s.done:
+			return false
+		case <-ctx.Done():
+		}
+	case Buffer:
+		s.mu.Lock()
+		if s.closing {
+			s.mu.Unlock()
+			return false
+		}
+		s.queue = append(s.queue, m)
+		s.mu.Unlock()
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+		return true
+	}
+	s.dropped.Add(1)
+	return false
+}
+
+// forward moves a Buffer subscription's backlog onto its channel, one message at a time as the subscriber makes
+// room, until it unsubscribes, or the broker closes and the backlog is empty
+func (s *Subscription) forward() {
+	defer close(s.ch)
+	for {
+		s.mu.Lock()
+		for len(s.queue) == 0 {
+			if s.closing {
+				s.mu.Unlock()
+				return
+			}
+			s.mu.Unlock()
+			select {
+			case <-s.wake:
+			case <-s.done:
+				return
+			}
+			s.mu.Lock()
+		}
+		m := s.queue[0]
+		s.queue[0] = Message{}
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+		select {
+		case s.ch <- m:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// finish ends delivery to a subscription the broker has let go of, a Buffer subscription's goroutine still empties
+// its backlog before closing the channel
+func (s *Subscription) finish() {
+	if s.policy != Buffer {
+		close(s.ch)
+		return
+	}
+	s.mu.Lock()
+	s.closing = true
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Broker delivers every message published to a topic to each of the topic's subscribers, a channel apiece
+// Publishes share a read lock, so many run at once, while subscribing, unsubscribing and closing take the write
+// lock, which is also what makes closing a channel safe, no publish can be sending on it
+type Broker struct {
+	mu     sync.RWMutex
+	topics map[string][]*Subscription
+	seq    atomic.Uint64
+	closed bool
+}
+
+// NewBroker creates a broker with no topics, a topic exists once something subscribes to it
+func NewBroker() *Broker {
+	return &Broker{topics: map[string][]*Subscription{}}
+}
+
+// Subscribe adds a subscriber to topic whose channel holds capacity messages, with policy deciding what a publish
+// does when it's full
+func (b *Broker) Subscribe(topic string, policy Policy, capacity int) (*Subscription, error) {
+	ch := make(chan Message, capacity)
+	s := &Subscription{C: ch, broker: b, topic: topic, policy: policy, ch: ch, done: make(chan struct{}),
+		wake: make(chan struct{}, 1)}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	b.topics[topic] = append(b.topics[topic], s)
+	if policy == Buffer {
+		go s.forward()
+	}
+	return s, nil
+}
+
+// Publish sends payload to every subscriber to topic, in the order they subscribed, returning how many got it
+// It returns ctx's error if a Block subscriber was still full when ctx ended, having carried on to the subscribers
+// after it
+func (b *Broker) Publish(ctx context.Context, topic string, payload []byte) (int, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return 0, ErrClosed
+	}
+	m := Message{Topic: topic, Seq: b.seq.Add(1), Sent: time.Now(), Payload: payload}
+	delivered := 0
+	var err error
+	for _, s := range b.topics[topic] {
+		if s.deliver(ctx, m) {
+			delivered++
+		} else if s.policy == Block && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+	}
+	return delivered, err
+}
+
+// Close stops the broker, closing every subscriber's channel once it has received what was already published
+// It waits for publishes in progress, so for any blocked on a full subscriber
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for _, subs := range b.topics {
+		for _, s := range subs {
+			s.finish()
+		}
+	}
+	clear(b.topics)
+}
+
+// remove lets go of an unsubscribed subscription, if the broker hasn't already
+func (b *Broker) remove(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subs := b.topics[s.topic]
+	i := slices.Index(subs, s)
+	if i < 0 {
+		return
+	}
+	b.topics[s.topic] = slices.Delete(subs, i, i+1)
+	if len(b.topics[s.topic]) == 0 {
+		delete(b.topics, s.topic)
+	}
+	s.finish()
+}
@@ -0,0 +1,116 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// drain receives everything left on sub's channel once it's closed
+func drain(sub *Subscription) []Message {
+	var got []Message
+	for m := range sub.C {
+		got = append(got, m)
+	}
+	return got
+}
+
+func TestOnlyTheTopicsSubscribersReceive(t *testing.T) {
+	b := NewBroker()
+	news, _ := b.Subscribe("news", Block, 4)
+	sport, _ := b.Subscribe("sport", Block, 4)
+	if n, err := b.Publish(context.Background(), "news", []byte("hello")); n != 1 || err != nil {
+		t.Fatalf("publish to news reached %d subscribers with %v, want 1", n, err)
+	}
+	b.Close()
+	if got := drain(news); len(got) != 1 || string(got[0].Payload) != "hello" || got[0].Seq != 1 {
+		t.Fatalf("news subscriber got %v", got)
+	}
+	if got := drain(sport); len(got) != 0 {
+		t.Fatalf("sport subscriber got %v, want nothing", got)
+	}
+}
+
+func TestDropMissesWhatDoesntFit(t *testing.T) {
+	b := NewBroker()
+	sub, _ := b.Subscribe("t", Drop, 2)
+	for range 5 {
+		b.Publish(context.Background(), "t", nil)
+	}
+	b.Close()
+	got := drain(sub)
+	if len(got) != 2 || got[0].Seq != 1 || got[1].Seq != 2 || sub.Dropped() != 3 {
+		t.Fatalf("got %d messages and dropped %d, want the first 2 and 3 dropped", len(got), sub.Dropped())
+	}
+}
+
+func TestBlockGivesUpWhenTheContextEnds(t *testing.T) {
+	b := NewBroker()
+	full, _ := b.Subscribe("t", Block, 1)
+	after, _ := b.Subscribe("t", Block, 4)
+	b.Publish(context.Background(), "t", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	n, err := b.Publish(ctx, "t", nil)
+	if !errors.Is(err, context.DeadlineExceeded) || n != 1 {
+		t.Fatalf("publish to a full subscriber reached %d with %v, want 1 and the deadline", n, err)
+	}
+	b.Close()
+	if got := drain(full); len(got) != 1 || full.Dropped() != 1 {
+		t.Fatalf("full subscriber got %d and dropped %d, want 1 and 1", len(got), full.Dropped())
+	}
+	if got := drain(after); len(got) != 2 {
+		t.Fatalf("the subscriber after the full one got %d, want 2", len(got))
+	}
+}
+
+func TestBufferKeepsEverythingInOrder(t *testing.T) {
+	b := NewBroker()
+	sub, _ := b.Subscribe("t", Buffer, 1)
+	for range 100 {
+		b.Publish(context.Background(), "t", nil)
+	}
+	b.Close()
+	got := drain(sub)
+	if len(got) != 100 || sub.Dropped() != 0 {
+		t.Fatalf("got %d messages and dropped %d, want 100 and none", len(got), sub.Dropped())
+	}
+	for i, m := range got {
+		if m.Seq != uint64(i+1) {
+			t.Fatalf("message %d has sequence %d", i, m.Seq)
+		}
+	}
+}
+
+func TestUnsubscribeReleasesABlockedPublisher(t *testing.T) {
+	b := NewBroker()
+	sub, _ := b.Subscribe("t", Block, 0)
+	published := make(chan error)
+	go func() {
+		_, err := b.Publish(context.Background(), "t", nil)
+		published <- err
+	}()
+	time.Sleep(time.Millisecond)
+	sub.Unsubscribe()
+	if err := <-published; err != nil {
+		t.Fatalf("publish after unsubscribing returned %v", err)
+	}
+	if _, ok := <-sub.C; ok {
+		t.Fatal("channel still open after unsubscribing")
+	}
+	if n, _ := b.Publish(context.Background(), "t", nil); n != 0 {
+		t.Fatalf("publish after unsubscribing reached %d subscribers", n)
+	}
+}
+
+func TestClosedBrokerRefuses(t *testing.T) {
+	b := NewBroker()
+	b.Close()
+	if _, err := b.Subscribe("t", Block, 1); !errors.Is(err, ErrClosed) {
+		t.Fatalf("subscribe to a closed broker returned %v", err)
+	}
+	if _, err := b.Publish(context.Background(), "t", nil); !errors.Is(err, ErrClosed) {
+		t.Fatalf("publish to a closed broker returned %v", err)
+	}
+}
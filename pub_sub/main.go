@@ -0,0 +1,320 @@
+// Package pubsub is the pub/sub lesson, teachgo pubsub, an in-memory broker delivering each topic's messages to its
+// subscribers' channels, what the block, drop and buffer policies do about a subscriber that stops reading, and how
+// publishing scales as one message fans out to thousands of subscribers
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo pubsub -h, its first line is the summary teachgo help
+// lists
+const Description = `Publish and subscribe, an in-memory broker, slow subscribers and fanning out to thousands
+
+Builds a broker that delivers every message published to a topic to each of its subscribers, a channel of
+-capacity messages apiece. Publishes -messages messages of -size bytes to -fast subscribers that keep up and one
+that stops reading until publishing is over, once for each policy the stalled one can have when its channel fills:
+block, where every publish waits up to -patience for it, holding up the subscribers after it, drop, where it misses
+what doesn't fit, and buffer, where the broker queues everything for it without limit. Then times -fanout-messages
+publishes to topics of each number of -subscribers, every one a goroutine reading its channel, measuring how long
+publishing takes and how long until the last subscriber has every message.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "pubsub",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"publish/subscribe", "channels", "backpressure", "fan-out", "slow consumers"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// topic is the one topic the lesson publishes to, a subscriber to another would see none of it
+const topic = "lesson"
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Messages       int           `json:"messages"`
+	Fast           int           `json:"fast"`
+	Capacity       int           `json:"capacity"`
+	Patience       time.Duration `json:"patience"`
+	Size           int           `json:"size"`
+	Subscribers    []int         `json:"subscribers"`
+	FanOutMessages int           `json:"fanout_messages"`
+}
+
+// StalledResult is the messages published past a stalled subscriber under one policy, what the subscribers that
+// kept up received and how long after each publish, and what the stalled one received once it read again
+type StalledResult struct {
+	Policy          string  `json:"policy"`
+	PublishNs       float64 `json:"publish_ns"`
+	Timeouts        int     `json:"timeouts"`
+	FastReceived    int64   `json:"fast_received"`
+	FastP50Ns       float64 `json:"fast_p50_ns"`
+	FastMaxNs       float64 `json:"fast_max_ns"`
+	StalledReceived int64   `json:"stalled_received"`
+	Dropped         int64   `json:"dropped"`
+}
+
+// FanOutResult is the messages published to a topic with a number of subscribers, Publish how long publishing took
+// and Delivered how long until every subscriber had received every message
+type FanOutResult struct {
+	Subscribers int     `json:"subscribers"`
+	Deliveries  int64   `json:"deliveries"`
+	PublishNs   float64 `json:"publish_ns"`
+	DeliveredNs float64 `json:"delivered_ns"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config  RunConfig       `json:"config"`
+	Env     bench.Env       `json:"env"`
+	Stalled []StalledResult `json:"stalled"`
+	FanOut  []FanOutResult  `json:"fanout"`
+}
+
+// consume reads sub until its channel closes, recording each message's latency into stats when it's set, and
+// returning how many it received
+func consume(sub *Subscription, stats *bench.Stats) int64 {
+	var n int64
+	for m := range sub.C {
+		if stats != nil {
+			stats.Record(time.Since(m.Sent))
+		}
+		n++
+	}
+	return n
+}
+
+// stalled publishes past a subscriber that doesn't read until publishing is over, subscribed first with policy, and
+// the fast subscribers after it, which block, keeping up so they rarely need to
+func stalled(config RunConfig, policy Policy, payload []byte) (StalledResult, error) {
+	result := StalledResult{Policy: policy.String()}
+	broker := NewBroker()
+	slow, err := broker.Subscribe(topic, policy, config.Capacity)
+	if err != nil {
+		return result, err
+	}
+	release := make(chan struct{})
+	var slowReceived, fastReceived atomic.Int64
+	latency := bench.NewStats()
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		<-release
+		slowReceived.Store(consume(slow, nil))
+	})
+	for range config.Fast {
+		sub, err := broker.Subscribe(topic, Block, config.Capacity)
+		if err != nil {
+			return result, err
+		}
+		wg.Go(func() { fastReceived.Add(consume(sub, latency)) })
+	}
+
+	ctx := context.Background()
+	elapsed := bench.Phase(ctx, "publishing past a stalled "+policy.String()+" subscriber", func() {
+		for range config.Messages {
+			publishCtx, cancel := context.WithTimeout(ctx, config.Patience)
+			if _, err := broker.Publish(publishCtx, topic, payload); err != nil {
+				result.Timeouts++
+			}
+			cancel()
+		}
+	})
+	close(release)
+	broker.Close()
+	wg.Wait()
+
+	result.PublishNs = float64(elapsed.Nanoseconds())
+	result.FastReceived = fastReceived.Load()
+	result.FastP50Ns = float64(latency.Percentile(0.5).Nanoseconds())
+	result.FastMaxNs = float64(latency.Max().Nanoseconds())
+	result.StalledReceived = slowReceived.Load()
+	result.Dropped = slow.Dropped()
+	return result, nil
+}
+
+// fanOut publishes to a topic with n subscribers, each a goroutine reading a blocking subscription
+func fanOut(config RunConfig, n int, payload []byte) (FanOutResult, error) {
+	result := FanOutResult{Subscribers: n}
+	broker := NewBroker()
+	var received atomic.Int64
+	var wg sync.WaitGroup
+	for range n {
+		sub, err := broker.Subscribe(topic, Block, config.Capacity)
+		if err != nil {
+			return result, err
+		}
+		wg.Go(func() { received.Add(consume(sub, nil)) })
+	}
+
+	var published time.Duration
+	ctx := context.Background()
+	delivered := bench.Phase(ctx, fmt.Sprintf("fanning out to %d subscribers", n), func() {
+		start := time.Now()
+		for range config.FanOutMessages {
+			broker.Publish(ctx, topic, payload)
+		}
+		published = time.Since(start)
+		broker.Close()
+		wg.Wait()
+	})
+	result.Deliveries = received.Load()
+	result.PublishNs = float64(published.Nanoseconds())
+	result.DeliveredNs = float64(delivered.Nanoseconds())
+	return result, nil
+}
+
+// perOp is elapsed divided over n operations
+func perOp(elapsedNs float64, n int64) time.Duration {
+	if n == 0 {
+		return 0
+	}
+	return time.Duration(elapsedNs / float64(n))
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Publish and Subscribe")
+	fmt.Fprintln(w, "=====================")
+	fmt.Fprintf(w, "Messages: %d of %d bytes, to %d subscribers that keep up and 1 that stalls\n", config.Messages, config.Size, config.Fast)
+	fmt.Fprintf(w, "Channels: %d messages each, blocking publishes wait up to %v\n", config.Capacity, config.Patience)
+	fmt.Fprintf(w, "Machine: %s\n", result.Env)
+
+	fmt.Fprintln(w, "\n=====A stalled subscriber=====")
+	fmt.Fprintf(w, "%-8s %12s %9s %10s %10s %10s %9s %8s\n", "Policy", "Publishing", "Timeouts", "Fast got", "Fast p50", "Fast max", "Stalled", "Dropped")
+	for _, r := range result.Stalled {
+		fmt.Fprintf(w, "%-8s %12v %9d %10d %10v %10v %9d %8d\n", r.Policy, time.Duration(r.PublishNs), r.Timeouts,
+			r.FastReceived, time.Duration(r.FastP50Ns), time.Duration(r.FastMaxNs), r.StalledReceived, r.Dropped)
+	}
+	fmt.Fprintln(w, "\nTimeouts is the publishes whose patience ran out, Fast got the messages the subscribers that kept up")
+	fmt.Fprintln(w, "received between them and Stalled what the stalled one received once it read again. Blocking waits out")
+	fmt.Fprintln(w, "the patience on every message once the stalled channel is full, and the subscribers after it wait with")
+	fmt.Fprintln(w, "it, dropping keeps everyone else quick and the stalled subscriber misses all but a channel's worth,")
+	fmt.Fprintln(w, "buffering loses nothing and holds up no one, but only because the broker holds every message it hasn't")
+	fmt.Fprintln(w, "read, a subscriber that never comes back is a leak")
+
+	fmt.Fprintf(w, "\n=====Fanning %d messages out=====\n", config.FanOutMessages)
+	fmt.Fprintf(w, "%12s %12s %12s %12s %14s %12s\n", "Subscribers", "Deliveries", "Publishing", "Delivered", "Deliveries/s", "Per delivery")
+	for _, r := range result.FanOut {
+		fmt.Fprintf(w, "%12d %12d %12v %12v %14s %12v\n", r.Subscribers, r.Deliveries, time.Duration(r.PublishNs),
+			time.Duration(r.DeliveredNs), bench.FormatRate(float64(r.Deliveries)/(r.DeliveredNs/1e9)), perOp(r.DeliveredNs, r.Deliveries))
+	}
+	fmt.Fprintln(w, "\nEvery publish sends on every subscriber's channel in turn, so its cost grows with the subscribers while")
+	fmt.Fprintln(w, "the cost of each delivery stays roughly flat, Delivered is until the last subscriber read the last")
+	fmt.Fprintln(w, "message, hardly longer than publishing as each subscriber reads while the publisher moves on to the next")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo pubsub
+func Main(args []string) {
+	fs := bench.NewFlagSet("pubsub", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the pub/sub lesson measures a single run"
+	messages := fs.Int("messages", 1000, "the messages published past the stalled subscriber under each policy")
+	fast := fs.Int("fast", 3, "the subscribers that keep up, alongside the stalled one")
+	capacity := fs.Int("capacity", 16, "the messages each subscriber's channel holds")
+	patience := fs.Duration("patience", time.Millisecond, "how long a publish waits for a full blocking subscriber")
+	size := fs.Int("size", 64, "the bytes in each message")
+	subscriberList := fs.String("subscribers", "1,10,100,1000,10000", "comma separated numbers of subscribers to fan messages out to")
+	fanOutMessages := fs.Int("fanout-messages", 100, "the messages published to each number of subscribers")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "pubsub measures a single run, -trials isn't supported")
+	v.AtLeast("messages", *messages, 1)
+	v.AtLeast("fast", *fast, 0)
+	v.AtLeast("capacity", *capacity, 0)
+	v.NotNegative("patience", *patience)
+	v.AtLeast("size", *size, 0)
+	v.AtLeast("fanout-messages", *fanOutMessages, 1)
+	subscribers := []int{}
+	for _, field := range strings.Split(*subscriberList, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		v.Check(err == nil && n >= 1, "-subscribers must be whole numbers of at least 1, got %q", field)
+		subscribers = append(subscribers, n)
+	}
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	config := RunConfig{Messages: *messages, Fast: *fast, Capacity: *capacity, Patience: *patience, Size: *size,
+		Subscribers: subscribers, FanOutMessages: *fanOutMessages}
+	result := RunResult{Config: config, Env: bench.CaptureEnv()}
+	payload := make([]byte, *size)
+	for _, policy := range []Policy{Block, Drop, Buffer} {
+		r, err := stalled(config, policy, payload)
+		if err != nil {
+			slog.Error("failed to subscribe", "err", err)
+			os.Exit(1)
+		}
+		result.Stalled = append(result.Stalled, r)
+	}
+	for _, n := range subscribers {
+		r, err := fanOut(config, n, payload)
+		if err != nil {
+			slog.Error("failed to subscribe", "err", err)
+			os.Exit(1)
+		}
+		result.FanOut = append(result.FanOut, r)
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per run, an op is one publish past the stalled subscriber, and one delivery fanning
+// out
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/pub_sub"); err != nil {
+		return err
+	}
+	config := result.Config
+	benchmarks := []bench.Benchmark{}
+	for _, r := range result.Stalled {
+		n := int64(config.Messages)
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: "Stalled/" + r.Policy,
+			N:    n,
+			Metrics: []bench.Metric{
+				bench.NsPerOp(r.PublishNs, n),
+				{Value: float64(r.Dropped) / float64(n), Unit: "dropped/op"},
+			},
+		})
+	}
+	for _, r := range result.FanOut {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name:    fmt.Sprintf("FanOut/subscribers=%d", r.Subscribers),
+			N:       r.Deliveries,
+			Metrics: []bench.Metric{bench.NsPerOp(r.DeliveredNs, r.Deliveries)},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
@@ -0,0 +1,63 @@
+package pubsub
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz pubsub, it compares the block, drop and buffer policies for a stalled subscriber and
+// asks what each costs the publisher, the other subscribers and the broker
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does publishing take so much longer when the stalled subscriber blocks?",
+		Choices: []string{
+			"The broker retries every message to the stalled subscriber until it's read",
+			"Once its channel is full, every publish waits out the whole patience for it",
+			"The fast subscribers switch to blocking too once any subscriber blocks",
+			"Blocking subscribers are sent to over an unbuffered channel, which is slower",
+		},
+		Answer:      1,
+		Explanation: "one subscriber that stops reading sets the pace for the publisher, which is exactly the backpressure Block is for, and why it suits only subscribers that must not miss anything",
+	},
+	{
+		Prompt: "Why do the fast subscribers' latencies rise under the block policy, when they keep up?",
+		Choices: []string{
+			"Their channels are given less room so the stalled subscriber can have more",
+			"Their latency includes the time the broker spends dropping the stalled one's messages",
+			"They share a goroutine with the stalled subscriber, which is blocked most of the time",
+			"A publish delivers in turn, so they only get a message after the wait on the stalled one",
+		},
+		Answer:      3,
+		Explanation: "head of line blocking, a slow subscriber delays everyone after it, a broker that needs both would deliver to each subscriber from its own goroutine",
+	},
+	{
+		Prompt: "Why does the stalled subscriber receive exactly a channel's worth under the drop policy?",
+		Choices: []string{
+			"The first messages fill its channel and every later one finds it full and is dropped",
+			"Drop keeps the latest messages, overwriting the oldest ones as new ones arrive",
+			"The broker samples the stream down to the subscriber's capacity before sending",
+			"Its capacity is counted in bytes, and that many bytes is one channel's worth",
+		},
+		Answer:      0,
+		Explanation: "dropping suits subscribers that only care about recent state, like a dashboard, a subscriber that needs every message can't use it",
+	},
+	{
+		Prompt: "Why is the buffer policy not free, when it loses nothing and holds up no one?",
+		Choices: []string{
+			"It delivers messages out of order once the buffer has wrapped around",
+			"It makes the fast subscribers drop messages when the buffer is busy",
+			"The broker keeps every unread message without limit, so a stalled one grows it for good",
+			"It needs a goroutine per message to hold each one until it's read",
+		},
+		Answer:      2,
+		Explanation: "an unbounded buffer only moves the problem, real brokers cap it and fall back to dropping or disconnecting the subscriber",
+	},
+	{
+		Prompt: "Why does publishing to ten thousand subscribers take so much longer than to one?",
+		Choices: []string{
+			"The topic lookup slows down as the map of subscribers grows",
+			"Each message is copied into a buffer sized for every subscriber",
+			"The subscribers hold the broker's lock for longer as they multiply",
+			"A publish sends on every subscriber's channel in turn, one send each",
+		},
+		Answer:      3,
+		Explanation: "the cost per delivery stays roughly flat, the cost per publish grows with the subscribers, which is why brokers shard topics and fan out from many goroutines",
+	},
+}
@@ -0,0 +1,99 @@
+package rangetrees
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// Interval is the closed range of points from Start to End, both included
+type Interval struct {
+	Start, End int
+}
+
+// Contains reports whether the point x lies in the interval
+func (iv Interval) Contains(x int) bool {
+	return iv.Start <= x && x <= iv.End
+}
+
+// IntervalTree finds every interval containing a point, a stabbing query, in O(log n + k) for k intervals found,
+// where a scan checks all n
+// The intervals are sorted by start and the tree is implicit in the sorted slice, each subrange's root is its middle
+// interval and its children the middles of the halves either side, as binary search would visit them, and every node
+// also records the greatest end in its subtree
+// A subtree whose greatest end is before the point holds nothing containing it, and neither does a right subtree
+// when the node itself starts after the point, those two checks prune everything but the paths to the answers
+type IntervalTree struct {
+	intervals []Interval
+	maxEnd    []int
+	queries   int64
+	steps     int64
+}
+
+// NewIntervalTree builds a tree over a copy of the intervals, O(n log n) for the sort
+func NewIntervalTree(intervals []Interval) *IntervalTree {
+	t := &IntervalTree{intervals: slices.Clone(intervals), maxEnd: make([]int, len(intervals))}
+	slices.SortFunc(t.intervals, func(a, b Interval) int {
+		return cmp.Or(cmp.Compare(a.Start, b.Start), cmp.Compare(a.End, b.End))
+	})
+	t.build(0, len(t.intervals))
+	return t
+}
+
+// build fills in maxEnd for the subtree over intervals[lo:hi] and returns it
+func (t *IntervalTree) build(lo, hi int) int {
+	if lo >= hi {
+		return math.MinInt
+	}
+	mid := (lo + hi) / 2
+	t.maxEnd[mid] = max(t.intervals[mid].End, t.build(lo, mid), t.build(mid+1, hi))
+	return t.maxEnd[mid]
+}
+
+// Len is the number of intervals
+func (t *IntervalTree) Len() int {
+	return len(t.intervals)
+}
+
+// Stab appends every interval containing x to into, in order of their starts, and returns it
+func (t *IntervalTree) Stab(x int, into []Interval) []Interval {
+	t.queries++
+	return t.stab(0, len(t.intervals), x, into)
+}
+
+func (t *IntervalTree) stab(lo, hi, x int, into []Interval) []Interval {
+	if lo >= hi {
+		return into
+	}
+	mid := (lo + hi) / 2
+	t.steps++
+	if t.maxEnd[mid] < x {
+		return into
+	}
+	into = t.stab(lo, mid, x, into)
+	if t.intervals[mid].Start > x {
+		return into
+	}
+	if t.intervals[mid].End >= x {
+		into = append(into, t.intervals[mid])
+	}
+	return t.stab(mid+1, hi, x, into)
+}
+
+// StepsPerQuery is the mean number of nodes each Stab visited
+func (t *IntervalTree) StepsPerQuery() float64 {
+	if t.queries == 0 {
+		return 0
+	}
+	return float64(t.steps) / float64(t.queries)
+}
+
+// ScanStab answers the same query by checking every interval
+func ScanStab(intervals []Interval, x int, into []Interval) []Interval {
+	for _, iv := range intervals {
+		if iv.Contains(x) {
+			into = append(into, iv)
+		}
+	}
+	return into
+}
@@ -0,0 +1,298 @@
+// Package rangetrees is the range tree lesson, teachgo rangetree, a segment tree answering range sums and minimums
+// and an interval tree answering stabbing queries, each against a scan of the plain slice
+package rangetrees
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo rangetree -h, its first line is the summary teachgo help
+// lists
+const Description = `Segment trees and interval trees, range queries in O(log n)
+
+Builds a segment tree over an array of numbers and times range sums, range minimums and point updates against a
+plain slice scanned for each query, then builds an interval tree over a set of intervals and times finding every
+interval that contains a point, a stabbing query, against checking each interval in turn. Counts the nodes each
+tree visits per query beside the elements a scan reads, showing the trees touching a few dozen where a scan reads
+thousands, and paying for it with an O(log n) update where the slice's is O(1).`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "rangetree",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"segment trees", "interval trees", "range queries", "augmented trees"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Elements  int   `json:"elements"`
+	Intervals int   `json:"intervals"`
+	MaxLength int   `json:"max_length"`
+	Queries   int   `json:"queries"`
+	Seed      int64 `json:"seed"`
+}
+
+// OperationResult is one operation timed on a tree and done by scanning, the times are per operation and the steps
+// are the nodes the tree visited and the elements the scan read, per operation, Agree is whether both gave the same
+// answers
+type OperationResult struct {
+	Name      string  `json:"name"`
+	TreeNs    float64 `json:"tree_ns"`
+	ScanNs    float64 `json:"scan_ns"`
+	TreeSteps float64 `json:"tree_steps"`
+	ScanSteps float64 `json:"scan_steps"`
+	Agree     bool    `json:"agree"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON, Found is the mean number of
+// intervals a stabbing query found
+type RunResult struct {
+	Config     RunConfig         `json:"config"`
+	Env        bench.Env         `json:"env"`
+	Operations []OperationResult `json:"operations"`
+	Found      float64           `json:"found"`
+}
+
+// span is the number of points intervals are drawn over, for a number of intervals, ten points an interval
+func span(intervals int) int {
+	return 10 * intervals
+}
+
+func randomRanges(rng *rand.Rand, n, count int) [][2]int {
+	ranges := make([][2]int, count)
+	for i := range ranges {
+		l, r := rng.Intn(n), rng.Intn(n)
+		ranges[i] = [2]int{min(l, r), max(l, r) + 1}
+	}
+	return ranges
+}
+
+func randomIntervals(rng *rand.Rand, count, maxLength int) []Interval {
+	intervals := make([]Interval, count)
+	for i := range intervals {
+		start := rng.Intn(span(count))
+		intervals[i] = Interval{start, start + rng.Intn(maxLength)}
+	}
+	return intervals
+}
+
+// measureRanges times the range sums and minimums on the tree and by scanning, then the point updates, applied to
+// both so they agree afterwards
+func measureRanges(ctx context.Context, rng *rand.Rand, values []int, queries int) []OperationResult {
+	tree := NewSegmentTree(values)
+	ranges := randomRanges(rng, len(values), queries)
+	scanned := 0
+	for _, lr := range ranges {
+		scanned += lr[1] - lr[0]
+	}
+
+	var treeSum, scanSum int64
+	sum := OperationResult{Name: "Range sum", ScanSteps: float64(scanned) / float64(queries)}
+	sum.TreeNs = perOp(bench.Phase(ctx, "tree sum", func() {
+		for _, lr := range ranges {
+			treeSum += tree.Sum(lr[0], lr[1])
+		}
+	}), queries)
+	sum.TreeSteps = tree.StepsPerQuery()
+	sum.ScanNs = perOp(bench.Phase(ctx, "scan sum", func() {
+		for _, lr := range ranges {
+			scanSum += ScanSum(values, lr[0], lr[1])
+		}
+	}), queries)
+	sum.Agree = treeSum == scanSum
+
+	// a fresh tree so the steps counted are the minimums' alone
+	tree = NewSegmentTree(values)
+	var treeMin, scanMin int
+	minimum := OperationResult{Name: "Range min", ScanSteps: sum.ScanSteps}
+	minimum.TreeNs = perOp(bench.Phase(ctx, "tree min", func() {
+		for _, lr := range ranges {
+			treeMin += tree.Min(lr[0], lr[1])
+		}
+	}), queries)
+	minimum.TreeSteps = tree.StepsPerQuery()
+	minimum.ScanNs = perOp(bench.Phase(ctx, "scan min", func() {
+		for _, lr := range ranges {
+			scanMin += ScanMin(values, lr[0], lr[1])
+		}
+	}), queries)
+	minimum.Agree = treeMin == scanMin
+
+	updates := make([][2]int, queries)
+	for i := range updates {
+		updates[i] = [2]int{rng.Intn(len(values)), rng.Intn(1000)}
+	}
+	// a leaf and every node above it, log2(n) + 1 of them
+	update := OperationResult{Name: "Point update", ScanSteps: 1, TreeSteps: float64(height(len(values)) + 1)}
+	update.TreeNs = perOp(bench.Phase(ctx, "tree update", func() {
+		for _, u := range updates {
+			tree.Set(u[0], u[1])
+		}
+	}), queries)
+	update.ScanNs = perOp(bench.Phase(ctx, "slice update", func() {
+		for _, u := range updates {
+			values[u[0]] = u[1]
+		}
+	}), queries)
+	update.Agree = tree.Sum(0, len(values)) == ScanSum(values, 0, len(values)) && tree.Min(0, len(values)) == ScanMin(values, 0, len(values))
+	return []OperationResult{sum, minimum, update}
+}
+
+// height is the number of times n halves before reaching 1, the levels above a segment tree's leaves
+func height(n int) int {
+	h := 0
+	for ; n > 1; n = (n + 1) / 2 {
+		h++
+	}
+	return h
+}
+
+// measureStabs times the stabbing queries on the tree and by scanning, returning the mean intervals each found
+func measureStabs(ctx context.Context, rng *rand.Rand, intervals []Interval, queries int) (OperationResult, float64) {
+	tree := NewIntervalTree(intervals)
+	points := make([]int, queries)
+	for i := range points {
+		points[i] = rng.Intn(span(len(intervals)))
+	}
+	var buf []Interval
+	treeFound, scanFound := 0, 0
+	stab := OperationResult{Name: "Stab", ScanSteps: float64(len(intervals))}
+	stab.TreeNs = perOp(bench.Phase(ctx, "tree stab", func() {
+		for _, x := range points {
+			buf = tree.Stab(x, buf[:0])
+			treeFound += len(buf)
+		}
+	}), queries)
+	stab.TreeSteps = tree.StepsPerQuery()
+	stab.ScanNs = perOp(bench.Phase(ctx, "scan stab", func() {
+		for _, x := range points {
+			buf = ScanStab(intervals, x, buf[:0])
+			scanFound += len(buf)
+		}
+	}), queries)
+	stab.Agree = treeFound == scanFound
+	return stab, float64(treeFound) / float64(queries)
+}
+
+func perOp(d time.Duration, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(d.Nanoseconds()) / float64(n)
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Range Trees")
+	fmt.Fprintln(w, "===========")
+	fmt.Fprintf(w, "Elements: %d\nIntervals: %d, up to %d long over %d points\nQueries: %d\n",
+		config.Elements, config.Intervals, config.MaxLength, span(config.Intervals), config.Queries)
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%-14s %12s %12s %10s %12s %12s %8s\n", "Operation", "Tree/op", "Scan/op", "Speedup", "Tree steps", "Scan steps", "Agree")
+	for _, op := range result.Operations {
+		fmt.Fprintf(w, "%-14s %12v %12v %9.2fx %12.1f %12.1f %8v\n", op.Name, time.Duration(op.TreeNs), time.Duration(op.ScanNs),
+			op.ScanNs/op.TreeNs, op.TreeSteps, op.ScanSteps, op.Agree)
+	}
+	fmt.Fprintf(w, "\nA stabbing query found %.1f intervals on average\n", result.Found)
+	fmt.Fprintln(w, "\nThe segment tree covers any range with at most two nodes a level, the scan reads the whole range, in")
+	fmt.Fprintln(w, "return an update rewrites every node above the element, where the slice writes one")
+	fmt.Fprintln(w, "The interval tree only visits the nodes on the way to the intervals it finds, every subtree that ends")
+	fmt.Fprintln(w, "before the point or starts after it is skipped whole")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo rangetree
+func Main(args []string) {
+	fs := bench.NewFlagSet("rangetree", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the range tree lesson measures a single run"
+	elements := fs.Int("elements", 100000, "the number of elements in the array the segment tree is built over")
+	intervals := fs.Int("intervals", 50000, "the number of intervals in the interval tree, over ten times as many points")
+	maxLength := fs.Int("max-length", 1000, "the longest an interval can be")
+	queries := fs.Int("queries", 3000, "the number of queries and updates of each kind")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "rangetree measures a single run, -trials isn't supported")
+	v.AtLeast("elements", *elements, 1)
+	v.AtLeast("intervals", *intervals, 1)
+	v.AtLeast("max-length", *maxLength, 1)
+	v.AtLeast("queries", *queries, 1)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	rng := rand.New(rand.NewSource(globals.Seed))
+	result := RunResult{
+		Config: RunConfig{Elements: *elements, Intervals: *intervals, MaxLength: *maxLength, Queries: *queries, Seed: globals.Seed},
+		Env:    bench.CaptureEnv(),
+	}
+	ctx := context.Background()
+	values := make([]int, *elements)
+	for i := range values {
+		values[i] = rng.Intn(1000)
+	}
+	slog.Info("querying ranges", "elements", *elements, "queries", *queries)
+	result.Operations = measureRanges(ctx, rng, values, *queries)
+	slog.Info("stabbing intervals", "intervals", *intervals, "queries", *queries)
+	stab, found := measureStabs(ctx, rng, randomIntervals(rng, *intervals, *maxLength), *queries)
+	result.Operations = append(result.Operations, stab)
+	result.Found = found
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a tree and a scan line per operation, with the steps per operation alongside the time
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/range_trees"); err != nil {
+		return err
+	}
+	config := result.Config
+	benchmarks := []bench.Benchmark{}
+	for _, op := range result.Operations {
+		size := fmt.Sprintf("elements=%d", config.Elements)
+		if op.Name == "Stab" {
+			size = fmt.Sprintf("intervals=%d", config.Intervals)
+		}
+		for _, side := range []struct {
+			name      string
+			ns, steps float64
+		}{{"tree", op.TreeNs, op.TreeSteps}, {"scan", op.ScanNs, op.ScanSteps}} {
+			benchmarks = append(benchmarks, bench.Benchmark{
+				Name:    fmt.Sprintf("%s/%s/%s", op.Name, side.name, size),
+				N:       int64(config.Queries),
+				Metrics: []bench.Metric{{Value: side.ns, Unit: "ns/op"}, {Value: side.steps, Unit: "steps/op"}},
+			})
+		}
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
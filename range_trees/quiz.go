@@ -0,0 +1,63 @@
+package rangetrees
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz rangetree, it asks why segment tree queries and updates are logarithmic, which
+// operations the tree can hold, and how the interval tree's greatest end prunes a stabbing query
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does a segment tree range query combine only a few dozen nodes, however long the range?",
+		Choices: []string{
+			"It keeps the answers to earlier queries and reuses them for overlapping ranges",
+			"A range splits into at most two whole subtrees per level, O(log n) nodes in all",
+			"It samples a fixed number of elements from the range and scales the answer up",
+			"Long ranges are answered from the root and only short ones walk down the tree",
+		},
+		Answer:      1,
+		Explanation: "each node holds the answer for its subtree, the scan reads every element in the range, a third of the array on average for random ranges, the tree reads about 2 log2(n) nodes",
+	},
+	{
+		Prompt: "Why is a point update slower on the segment tree than on the slice?",
+		Choices: []string{
+			"The tree is rebuilt from the updated slice after every change",
+			"The tree copies the path it changes so that older queries still see the old values",
+			"Every node above the element includes it, so all log2(n) of them are recomputed",
+			"It isn't, both write a single element and are O(1)",
+		},
+		Answer:      2,
+		Explanation: "the slice writes one element, the tree trades an O(1) update for an O(log n) one to turn O(n) queries into O(log n) ones, worth it unless updates far outnumber queries",
+	},
+	{
+		Prompt: "What lets the segment tree answer range minimums as well as sums?",
+		Choices: []string{
+			"The minimum can be worked out from the sums and the range's length",
+			"A second tree of the sorted elements is built alongside the first",
+			"Minimums need their own kind of tree, built from the same slice",
+			"Any associative operation works, a range's minimum is the minimum of its pieces'",
+		},
+		Answer:      3,
+		Explanation: "as the sum is the sum of the pieces' sums, max, gcd, products and bitwise ors all fit the same tree, what doesn't is an operation like the median that can't be combined from pieces",
+	},
+	{
+		Prompt: "Why does the interval tree record the greatest end in each subtree?",
+		Choices: []string{
+			"A subtree whose greatest end is before the point can be skipped whole",
+			"It is the key the tree is sorted by, so the intervals stay in order",
+			"It tells the tree when a subtree is too tall and needs rebalancing",
+			"It lets the query count the intervals without visiting them",
+		},
+		Answer:      0,
+		Explanation: "no interval in such a subtree reaches the point, sorting by start lets it skip what starts after the point, the greatest end lets it skip what ends before, leaving the paths to the answers",
+	},
+	{
+		Prompt: "What makes a stabbing query on the interval tree O(log n + k)?",
+		Choices: []string{
+			"It checks k intervals picked at random and estimates the rest",
+			"Each node it visits holds an answer or is on a path down to one",
+			"It visits log n nodes, then scans the k intervals after them",
+			"It isn't, it is O(n) like the scan, only with a smaller constant",
+		},
+		Answer:      1,
+		Explanation: "k answers and the tree's height of nodes on the way, the more intervals overlap the point the more work the query has to do, reporting them, but it never checks the many that don't",
+	},
+}
@@ -0,0 +1,114 @@
+package rangetrees
+
+import "math"
+
+// SegmentTree answers range sums and range minimums over an array in O(log n) and updates an element in O(log n),
+// where a plain slice takes O(n) a query and O(1) an update
+// It's a complete binary tree stored in a slice the way a binary heap is, the n elements are the leaves at n to
+// 2n-1 and node i above them holds the sum and the minimum of its children 2i and 2i+1, a range is covered by at
+// most two nodes per level, found by walking up from both ends of the range at once
+type SegmentTree struct {
+	n       int
+	sum     []int64
+	min     []int
+	queries int64
+	steps   int64
+}
+
+// NewSegmentTree builds a tree over a copy of values, O(n)
+func NewSegmentTree(values []int) *SegmentTree {
+	n := len(values)
+	t := &SegmentTree{n: n, sum: make([]int64, 2*n), min: make([]int, 2*n)}
+	for i, v := range values {
+		t.sum[n+i], t.min[n+i] = int64(v), v
+	}
+	for i := n - 1; i > 0; i-- {
+		t.pull(i)
+	}
+	return t
+}
+
+// pull recomputes node i from its children
+func (t *SegmentTree) pull(i int) {
+	t.sum[i] = t.sum[2*i] + t.sum[2*i+1]
+	t.min[i] = min(t.min[2*i], t.min[2*i+1])
+}
+
+// Len is the number of elements
+func (t *SegmentTree) Len() int {
+	return t.n
+}
+
+// Set changes element i to v and recomputes the nodes on the path from its leaf to the root
+func (t *SegmentTree) Set(i, v int) {
+	i += t.n
+	t.sum[i], t.min[i] = int64(v), v
+	for i /= 2; i > 0; i /= 2 {
+		t.pull(i)
+	}
+}
+
+// Sum returns the sum of the elements from l up to but not including r
+func (t *SegmentTree) Sum(l, r int) int64 {
+	t.queries++
+	var total int64
+	// l and r close in on each other a level at a time, a left end that's a right child and a right end that's a
+	// left child are taken whole, as their parents would reach outside the range
+	for l, r = l+t.n, r+t.n; l < r; l, r = l/2, r/2 {
+		if l&1 == 1 {
+			total += t.sum[l]
+			l++
+			t.steps++
+		}
+		if r&1 == 1 {
+			r--
+			total += t.sum[r]
+			t.steps++
+		}
+	}
+	return total
+}
+
+// Min returns the smallest of the elements from l up to but not including r, math.MaxInt for an empty range
+func (t *SegmentTree) Min(l, r int) int {
+	t.queries++
+	smallest := math.MaxInt
+	for l, r = l+t.n, r+t.n; l < r; l, r = l/2, r/2 {
+		if l&1 == 1 {
+			smallest = min(smallest, t.min[l])
+			l++
+			t.steps++
+		}
+		if r&1 == 1 {
+			r--
+			smallest = min(smallest, t.min[r])
+			t.steps++
+		}
+	}
+	return smallest
+}
+
+// StepsPerQuery is the mean number of nodes each Sum and Min combined, where a scan reads every element in the range
+func (t *SegmentTree) StepsPerQuery() float64 {
+	if t.queries == 0 {
+		return 0
+	}
+	return float64(t.steps) / float64(t.queries)
+}
+
+// ScanSum and ScanMin answer the same queries by reading every element in the range
+func ScanSum(values []int, l, r int) int64 {
+	var total int64
+	for _, v := range values[l:r] {
+		total += int64(v)
+	}
+	return total
+}
+
+func ScanMin(values []int, l, r int) int {
+	smallest := math.MaxInt
+	for _, v := range values[l:r] {
+		smallest = min(smallest, v)
+	}
+	return smallest
+}
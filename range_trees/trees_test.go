@@ -0,0 +1,71 @@
+package rangetrees
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestSegmentTreeMatchesScans(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 2, 3, 10, 17, 64, 100} {
+		values := make([]int, n)
+		for i := range values {
+			values[i] = rng.Intn(100) - 50
+		}
+		tree := NewSegmentTree(values)
+		for range 200 {
+			if rng.Intn(3) == 0 {
+				i, v := rng.Intn(n), rng.Intn(100)-50
+				tree.Set(i, v)
+				values[i] = v
+			}
+			l := rng.Intn(n)
+			r := l + 1 + rng.Intn(n-l)
+			if got, want := tree.Sum(l, r), ScanSum(values, l, r); got != want {
+				t.Fatalf("%d elements: Sum(%d, %d) = %d, want %d", n, l, r, got, want)
+			}
+			if got, want := tree.Min(l, r), ScanMin(values, l, r); got != want {
+				t.Fatalf("%d elements: Min(%d, %d) = %d, want %d", n, l, r, got, want)
+			}
+		}
+	}
+}
+
+func TestSegmentTreeStepsAreLogarithmic(t *testing.T) {
+	tree := NewSegmentTree(make([]int, 1<<16))
+	tree.Sum(1, 1<<16-1)
+	if steps := tree.StepsPerQuery(); steps > 2*16 {
+		t.Errorf("a sum over almost all of 2^16 elements combined %g nodes, want at most two a level", steps)
+	}
+}
+
+func TestIntervalTreeMatchesScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for _, n := range []int{0, 1, 2, 5, 50, 500} {
+		intervals := randomIntervals(rng, n, 40)
+		tree := NewIntervalTree(intervals)
+		for x := -1; x <= span(n)+40; x++ {
+			got := tree.Stab(x, nil)
+			want := ScanStab(intervals, x, nil)
+			slices.SortFunc(want, func(a, b Interval) int {
+				if a.Start != b.Start {
+					return a.Start - b.Start
+				}
+				return a.End - b.End
+			})
+			if !slices.Equal(got, want) {
+				t.Fatalf("%d intervals: Stab(%d) = %v, want %v", n, x, got, want)
+			}
+		}
+	}
+}
+
+func TestIntervalContainsItsEnds(t *testing.T) {
+	iv := Interval{3, 7}
+	for x, want := range map[int]bool{2: false, 3: true, 5: true, 7: true, 8: false} {
+		if iv.Contains(x) != want {
+			t.Errorf("%v.Contains(%d) = %v, want %v", iv, x, !want, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter decides whether a request arriving at now goes ahead, and how long it waits before it does
+// Taking the time rather than reading the clock lets a run replay simulated arrivals as fast as it can decide them,
+// and makes every decision repeatable
+type Limiter interface {
+	Allow(now time.Time) (ok bool, wait time.Duration)
+}
+
+// TokenBucket allows rate requests a second on average and up to burst at once
+// The bucket holds up to burst tokens and fills at rate a second, each request takes one and is turned away when
+// there are none, an idle spell saves up a full bucket to spend on a burst straight away
+// Rather than a goroutine adding tokens, the tokens are worked out from the time since the last request allowed,
+// the same arithmetic as golang.org/x/time/rate, so the two make the same decisions
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a token bucket that starts full
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{rate: rate, burst: burst, tokens: float64(burst)}
+}
+
+// Allow takes a token if there is one, a request never waits, it's allowed or it isn't
+func (b *TokenBucket) Allow(now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	last := b.last
+	if now.Before(last) {
+		last = now
+	}
+	tokens := min(b.tokens+now.Sub(last).Seconds()*b.rate, float64(b.burst))
+	if tokens < 1 {
+		return false, 0
+	}
+	b.tokens, b.last = tokens-1, now
+	return true, 0
+}
+
+// LeakyBucket lets requests out at a steady rate a second, one every 1/rate, queueing up to capacity of them to wait
+// their turn and turning away the rest
+// Where a token bucket passes a burst straight through, a leaky bucket smooths it out, the requests it allows leave
+// evenly spaced however bunched they arrived, paying for it in waiting
+// The queue is kept as the time the next request would leave, each one allowed moves it on a slot
+type LeakyBucket struct {
+	mu       sync.Mutex
+	interval time.Duration
+	capacity int
+	next     time.Time
+}
+
+// NewLeakyBucket creates an empty leaky bucket
+func NewLeakyBucket(rate float64, capacity int) *LeakyBucket {
+	return &LeakyBucket{interval: time.Duration(float64(time.Second) / rate), capacity: capacity}
+}
+
+// Allow queues the request if fewer than capacity are waiting ahead of it, returning how long it waits to leave
+func (b *LeakyBucket) Allow(now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	next := b.next
+	if next.Before(now) {
+		next = now
+	}
+	wait := next.Sub(now)
+	if wait >= time.Duration(b.capacity)*b.interval {
+		return false, 0
+	}
+	b.next = next.Add(b.interval)
+	return true, wait
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestTokenBucketDecidesLikeXTimeRate(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	ours, theirs := NewTokenBucket(50, 5), rate.NewLimiter(50, 5)
+	now := time.Unix(0, 0)
+	for i := range 10000 {
+		now = now.Add(time.Duration(rng.ExpFloat64() * float64(10*time.Millisecond)))
+		ok, wait := ours.Allow(now)
+		if want := theirs.AllowN(now, 1); ok != want || wait != 0 {
+			t.Fatalf("request %d: token bucket gave %v after %v, x/time/rate %v", i, ok, wait, want)
+		}
+	}
+}
+
+func TestTokenBucketBurstsThenRefills(t *testing.T) {
+	b := NewTokenBucket(10, 3)
+	now := time.Unix(0, 0)
+	for i := range 3 {
+		if ok, _ := b.Allow(now); !ok {
+			t.Fatalf("request %d of a burst of 3 was turned away", i)
+		}
+	}
+	if ok, _ := b.Allow(now); ok {
+		t.Fatal("a fourth request at once was allowed with a burst of 3")
+	}
+	if ok, _ := b.Allow(now.Add(100 * time.Millisecond)); !ok {
+		t.Fatal("a request a tenth of a second later was turned away at 10 a second")
+	}
+}
+
+func TestLeakyBucketSpacesRequestsOut(t *testing.T) {
+	b := NewLeakyBucket(10, 4)
+	now := time.Unix(0, 0)
+	for i := range 4 {
+		ok, wait := b.Allow(now)
+		if want := time.Duration(i) * 100 * time.Millisecond; !ok || wait != want {
+			t.Fatalf("request %d: allowed %v waiting %v, want allowed waiting %v", i, ok, wait, want)
+		}
+	}
+	if ok, _ := b.Allow(now); ok {
+		t.Fatal("a fifth request was queued with room for 4")
+	}
+	if ok, wait := b.Allow(now.Add(time.Second)); !ok || wait != 0 {
+		t.Fatalf("a request once the queue had drained was allowed %v waiting %v, want allowed straight away", ok, wait)
+	}
+}
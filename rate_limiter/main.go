@@ -0,0 +1,332 @@
+// Package ratelimit is the rate limiter lesson, teachgo ratelimit, a token bucket and a leaky bucket built from
+// scratch, replayed against golang.org/x/time/rate on simulated load that rises, spikes and falls away
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+	"golang.org/x/time/rate"
+)
+
+// Description is the lesson's help text, shown by teachgo ratelimit -h, its first line is the summary teachgo help
+// lists
+const Description = `Rate limiters, token and leaky buckets against golang.org/x/time/rate
+
+Generates -duration of simulated requests, arriving at random around half the -rate limit, then above it, then in
+a spike of eight times it, a pause, and just under it, and replays them through a token bucket, which lets bursts
+of up to -burst through at once and turns away what's over, a leaky bucket, which queues up to -burst requests and
+lets them out evenly spaced, and x/time/rate's Limiter used both ways, Allow turning requests away and Reserve
+making them wait. Reports what each allowed, rejected and made wait, the most it let through in any 100ms, and
+how long each decision takes, and graphs allowed against rejected requests over time. The simulated clock makes
+every decision the same from run to run, the token bucket and x/time/rate's Allow making identical ones.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "ratelimit",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"rate limiting", "token bucket", "leaky bucket", "traffic shaping", "simulated time"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// profile is the load offered over a run, each phase lasting until a fraction of -duration with requests arriving
+// at a multiple of -rate
+var profile = []struct {
+	until float64
+	load  float64
+}{
+	{0.25, 0.5},
+	{0.5, 1.5},
+	{0.55, 8},
+	{0.6, 0},
+	{1, 0.9},
+}
+
+// peakWindow is the window the most requests any limiter let out at once is counted over
+const peakWindow = 100 * time.Millisecond
+
+// chartRows and chartWidth are the shape of the graph, a row for each slice of the run
+const (
+	chartRows  = 20
+	chartWidth = 60
+)
+
+// limiter is a rate limiter under test, built for the run's rate and burst
+type limiter struct {
+	name string
+	make func(r float64, burst int) Limiter
+}
+
+var limiters = []limiter{
+	{"Token bucket", func(r float64, burst int) Limiter { return NewTokenBucket(r, burst) }},
+	{"x/time/rate Allow", func(r float64, burst int) Limiter { return xrateAllow{rate.NewLimiter(rate.Limit(r), burst)} }},
+	{"Leaky bucket", func(r float64, burst int) Limiter { return NewLeakyBucket(r, burst) }},
+	{"x/time/rate Reserve", func(r float64, burst int) Limiter {
+		return xrateReserve{rate.NewLimiter(rate.Limit(r), burst), time.Duration(float64(burst) / r * float64(time.Second))}
+	}},
+}
+
+// xrateAllow is x/time/rate's Limiter turning away any request without a token
+type xrateAllow struct {
+	limiter *rate.Limiter
+}
+
+func (l xrateAllow) Allow(now time.Time) (bool, time.Duration) {
+	return l.limiter.AllowN(now, 1), 0
+}
+
+// xrateReserve is x/time/rate's Limiter reserving a token for each request, making it wait until the token's due,
+// turning it away and cancelling the reservation when that's longer than maxWait
+type xrateReserve struct {
+	limiter *rate.Limiter
+	maxWait time.Duration
+}
+
+func (l xrateReserve) Allow(now time.Time) (bool, time.Duration) {
+	r := l.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return false, 0
+	}
+	if wait := r.DelayFrom(now); wait <= l.maxWait {
+		return true, wait
+	}
+	r.CancelAt(now)
+	return false, 0
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Rate     float64       `json:"rate"`
+	Burst    int           `json:"burst"`
+	Duration time.Duration `json:"duration"`
+	Seed     int64         `json:"seed"`
+}
+
+// Slice is one row of a graph, the requests that arrived in a slice of the run and how many were allowed
+type Slice struct {
+	Offered int `json:"offered"`
+	Allowed int `json:"allowed"`
+}
+
+// LimiterResult is every request replayed through one limiter, Peak is the most it let out in any 100ms
+type LimiterResult struct {
+	Name          string  `json:"name"`
+	Allowed       int     `json:"allowed"`
+	Rejected      int     `json:"rejected"`
+	MeanWaitNs    float64 `json:"mean_wait_ns"`
+	MaxWaitNs     float64 `json:"max_wait_ns"`
+	Peak          int     `json:"peak"`
+	NsPerDecision float64 `json:"ns_per_decision"`
+	Slices        []Slice `json:"slices"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+// Disagreements counts the requests the token bucket and x/time/rate's Allow decided differently
+type RunResult struct {
+	Config        RunConfig       `json:"config"`
+	Env           bench.Env       `json:"env"`
+	Requests      int             `json:"requests"`
+	Limiters      []LimiterResult `json:"limiters"`
+	Disagreements int             `json:"disagreements"`
+}
+
+// arrivals draws the times requests arrive, as offsets from the start, random arrivals at the rate each phase of
+// the profile sets
+func arrivals(rng *rand.Rand, r float64, duration time.Duration) []time.Duration {
+	times := []time.Duration{}
+	t := 0.0
+	for _, phase := range profile {
+		end := phase.until * duration.Seconds()
+		if phase.load == 0 {
+			t = end
+			continue
+		}
+		for {
+			t += rng.ExpFloat64() / (phase.load * r)
+			if t >= end {
+				t = end
+				break
+			}
+			times = append(times, time.Duration(t*float64(time.Second)))
+		}
+	}
+	return times
+}
+
+// replay puts every request through a limiter, returning what it did and its decisions
+func replay(ctx context.Context, l limiter, config RunConfig, times []time.Duration) (LimiterResult, []bool) {
+	limit := l.make(config.Rate, config.Burst)
+	start := time.Unix(0, 0)
+	decisions := make([]bool, len(times))
+	waits := make([]time.Duration, len(times))
+	elapsed := bench.Phase(ctx, l.name, func() {
+		for i, t := range times {
+			decisions[i], waits[i] = limit.Allow(start.Add(t))
+		}
+	})
+
+	result := LimiterResult{Name: l.name, NsPerDecision: perOp(elapsed, len(times)), Slices: make([]Slice, chartRows)}
+	sliceLength := config.Duration / chartRows
+	out := map[time.Duration]int{}
+	var totalWait time.Duration
+	for i, t := range times {
+		slice := &result.Slices[min(int(t/sliceLength), chartRows-1)]
+		slice.Offered++
+		if !decisions[i] {
+			result.Rejected++
+			continue
+		}
+		result.Allowed++
+		slice.Allowed++
+		totalWait += waits[i]
+		result.MaxWaitNs = max(result.MaxWaitNs, float64(waits[i].Nanoseconds()))
+		window := (t + waits[i]) / peakWindow
+		out[window]++
+		result.Peak = max(result.Peak, out[window])
+	}
+	if result.Allowed > 0 {
+		result.MeanWaitNs = float64(totalWait.Nanoseconds()) / float64(result.Allowed)
+	}
+	return result, decisions
+}
+
+func perOp(d time.Duration, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(d.Nanoseconds()) / float64(n)
+}
+
+// bar draws a row of the graph, # for allowed requests and . for rejected, full is the count that fills the width,
+// a row over it is cut off with >
+func bar(s Slice, full float64) string {
+	allowed := int(float64(s.Allowed)*chartWidth/full + 0.5)
+	offered := int(float64(s.Offered)*chartWidth/full + 0.5)
+	if offered <= chartWidth {
+		return strings.Repeat("#", allowed) + strings.Repeat(".", offered-allowed)
+	}
+	allowed = min(allowed, chartWidth)
+	return strings.Repeat("#", allowed) + strings.Repeat(".", chartWidth-allowed) + ">"
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Rate Limiters")
+	fmt.Fprintln(w, "=============")
+	fmt.Fprintf(w, "Limit: %g a second, bursts of %d\n", config.Rate, config.Burst)
+	fmt.Fprintf(w, "Requests: %d over %v, at half the limit, one and a half times it, a spike of eight times, none, then 0.9 times\n",
+		result.Requests, config.Duration)
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%-20s %8s %9s %14s %14s %13s %13s\n", "Limiter", "Allowed", "Rejected", "Mean wait", "Max wait", "Peak a 100ms", "Per decision")
+	for _, l := range result.Limiters {
+		fmt.Fprintf(w, "%-20s %8d %9d %14v %14v %13d %13v\n", l.Name, l.Allowed, l.Rejected, time.Duration(l.MeanWaitNs),
+			time.Duration(l.MaxWaitNs), l.Peak, time.Duration(l.NsPerDecision))
+	}
+	fmt.Fprintf(w, "\nRequests the token bucket and x/time/rate's Allow decided differently: %d\n", result.Disagreements)
+	fmt.Fprintln(w, "The token buckets let a full bucket of requests straight through when the spike hits, the leaky bucket")
+	fmt.Fprintln(w, "queues them and lets them out a slot at a time, its peak never more than the limit allows in 100ms,")
+	fmt.Fprintln(w, "paying for the smoothness in waiting, Reserve makes requests wait for a token rather than turning them")
+	fmt.Fprintln(w, "away, but spends a full bucket at once just as Allow does")
+
+	sliceLength := config.Duration / chartRows
+	tokens, leaky := result.Limiters[0], result.Limiters[2]
+	fmt.Fprintf(w, "\n=====%s, allowed # and rejected . every %v, a full row twice the limit=====\n", tokens.Name, sliceLength)
+	fmt.Fprintf(w, "%10s %8s %8s %8s %8s\n", "From", "Offered", "Allowed", "Rejected", "Leaky")
+	full := 2 * config.Rate * sliceLength.Seconds()
+	for i, s := range tokens.Slices {
+		fmt.Fprintf(w, "%10v %8d %8d %8d %8d |%s\n", time.Duration(i)*sliceLength, s.Offered, s.Allowed, s.Offered-s.Allowed,
+			leaky.Slices[i].Allowed, bar(s, full))
+	}
+	fmt.Fprintln(w, "\nLeaky is what the leaky bucket allowed, a second at a time the two buckets allow much the same, the")
+	fmt.Fprintln(w, "difference is inside the second, the token bucket's allowed requests bunched up, the leaky bucket's spread")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo ratelimit
+func Main(args []string) {
+	fs := bench.NewFlagSet("ratelimit", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the rate limiter lesson measures a single run"
+	limit := fs.Float64("rate", 1000, "the requests a second the limiters allow")
+	burst := fs.Int("burst", 100, "the token buckets' size, and the most requests the leaky bucket queues")
+	duration := fs.Duration("duration", 20*time.Second, "the simulated time the requests arrive over")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "ratelimit measures a single run, -trials isn't supported")
+	v.Check(*limit > 0, "-rate must be more than 0, got %g", *limit)
+	v.AtLeast("burst", *burst, 1)
+	v.Check(*duration >= time.Second, "-duration must be at least 1s, got %v", *duration)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	config := RunConfig{Rate: *limit, Burst: *burst, Duration: *duration, Seed: globals.Seed}
+	result := RunResult{Config: config, Env: bench.CaptureEnv()}
+	rng := rand.New(rand.NewSource(globals.Seed))
+	ctx := context.Background()
+	times := arrivals(rng, *limit, *duration)
+	result.Requests = len(times)
+	decisions := map[string][]bool{}
+	for _, l := range limiters {
+		slog.Info("replaying", "limiter", l.name, "requests", len(times))
+		var r LimiterResult
+		r, decisions[l.name] = replay(ctx, l, config, times)
+		result.Limiters = append(result.Limiters, r)
+	}
+	for i, ours := range decisions["Token bucket"] {
+		if ours != decisions["x/time/rate Allow"][i] {
+			result.Disagreements++
+		}
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per limiter, an op is one decision, with the fraction allowed and the mean wait
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/rate_limiter"); err != nil {
+		return err
+	}
+	config := result.Config
+	benchmarks := []bench.Benchmark{}
+	for _, l := range result.Limiters {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: fmt.Sprintf("Limiter/%s/rate=%g/burst=%d", l.Name, config.Rate, config.Burst),
+			N:    int64(result.Requests),
+			Metrics: []bench.Metric{
+				{Value: l.NsPerDecision, Unit: "ns/op"},
+				{Value: float64(l.Allowed) / float64(max(result.Requests, 1)), Unit: "allowed/op"},
+				{Value: l.MeanWaitNs, Unit: "wait-ns"},
+			},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
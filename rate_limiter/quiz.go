@@ -0,0 +1,63 @@
+package ratelimit
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz ratelimit, it sets the token bucket, the leaky bucket and x/time/rate side by side on
+// bursts and waiting, and asks why a limiter that takes the time as an argument needs no refill goroutine
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why is the token bucket's peak in 100ms so much higher than the leaky bucket's?",
+		Choices: []string{
+			"The token bucket was given a higher rate, so it allows more in every window",
+			"The leaky bucket rejects the whole spike, so it lets nothing through at the peak",
+			"It saves a full bucket of tokens while load is light and spends them at once on the spike",
+			"The token bucket's peak is measured over a longer window than the leaky bucket's",
+		},
+		Answer:      2,
+		Explanation: "the leaky bucket lets requests out one slot at a time whatever, both allow the same rate on average, a token bucket allows bursts of up to its size on top, which is often what a caller wants",
+	},
+	{
+		Prompt: "Why do the leaky bucket's requests wait, when the token bucket's never do?",
+		Choices: []string{
+			"It queues requests arriving faster than its rate and lets them out evenly spaced",
+			"It waits for a background goroutine to refill its tokens before deciding",
+			"The simulated clock advances more slowly for it, so every request looks early",
+			"It takes longer to decide each request, since it keeps a history of them",
+		},
+		Answer:      0,
+		Explanation: "the token bucket decides at once, allowed or not, a queue smooths a burst out instead of turning it away, at the price of latency, its maximum is the queue's length over the rate",
+	},
+	{
+		Prompt: "Why do the token bucket and x/time/rate's Allow decide every request the same way?",
+		Choices: []string{
+			"The lesson's token bucket calls x/time/rate underneath",
+			"The requests never exceed the limit, so both allow every one",
+			"Both work out their tokens from the time since the last request the same way",
+			"They share one clock and one bucket between them",
+		},
+		Answer:      2,
+		Explanation: "both are token buckets with the same arithmetic, floating point arithmetic done in a different order could make them disagree on a request that lands exactly as a token fills",
+	},
+	{
+		Prompt: "Why can the lesson replay twenty seconds of requests in a fraction of a second, the same every run?",
+		Choices: []string{
+			"It skips most of the requests and scales the counts up at the end",
+			"It runs each limiter on its own goroutine, in parallel with the others",
+			"It sleeps for a thousandth of each gap, so the replay is sped up uniformly",
+			"The limiters are given each request's simulated time rather than reading the clock",
+		},
+		Answer:      3,
+		Explanation: "the requests are passed in as fast as they can be decided, x/time/rate's AllowN and ReserveN take the time for the same reason, which also makes limiters easy to test",
+	},
+	{
+		Prompt: "Why does nothing have to refill the token bucket between requests?",
+		Choices: []string{
+			"A ticker goroutine refills it, so the requests never have to",
+			"Each request adds the tokens the time since the last one would have, capped at the size",
+			"The bucket is refilled in full whenever it is found empty",
+			"The leaky bucket hands over the tokens it doesn't use",
+		},
+		Answer:      1,
+		Explanation: "working it out lazily costs a multiply and a min per request, a ticker would cost a goroutine and a wake up per token",
+	},
+}
@@ -0,0 +1,251 @@
+// Package ringbuffer is the ring buffer lesson, teachgo ring, a mutex guarded ring buffer built from scratch and a
+// buffered channel, compared as bounded queues between producers and consumers
+package ringbuffer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo ring -h, its first line is the summary teachgo help lists
+const Description = `Ring buffers against channels, bounded queues between producers and consumers
+
+Builds a ring buffer, a fixed slice used as a circle, guarded by a mutex with conditions for blocked pushers and
+poppers, and runs producers and consumers through it and through a buffered channel. Once blocking, each side
+sleeping while the queue is full or empty, and once without, TryPush and TryPop failing straight away and the
+caller yielding and trying again. Reports the time per item, throughput and how often the tries failed.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "ring",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"ring buffers", "channels", "sync.Cond", "backpressure", "non-blocking operations"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// modes are the ways the producers and consumers use the queues
+var modes = []string{"blocking", "nonblocking"}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Producers  int      `json:"producers"`
+	Consumers  int      `json:"consumers"`
+	Items      int      `json:"items"`
+	Capacity   int      `json:"capacity"`
+	Modes      []string `json:"modes"`
+	GOMAXPROCS int      `json:"gomaxprocs"`
+}
+
+// QueueResult is what one queue measured in one mode, FailedPushes and FailedPops count the tries that found the
+// queue full or empty, always zero when blocking
+type QueueResult struct {
+	Name         string  `json:"name"`
+	Mode         string  `json:"mode"`
+	ItemNs       float64 `json:"item_ns"`
+	ItemsPerSec  float64 `json:"items_per_sec"`
+	FailedPushes int64   `json:"failed_pushes"`
+	FailedPops   int64   `json:"failed_pops"`
+	// Correct is whether the consumers got every item exactly once, their values adding up to what was pushed
+	Correct bool `json:"correct"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config RunConfig     `json:"config"`
+	Env    bench.Env     `json:"env"`
+	Queues []QueueResult `json:"queues"`
+}
+
+// candidate is a queue under test, new builds an empty one for each mode
+type candidate struct {
+	name string
+	new  func(capacity int) Queue[int]
+}
+
+func candidates() []candidate {
+	return []candidate{
+		{"Ring buffer", func(capacity int) Queue[int] { return NewRingBuffer[int](capacity) }},
+		{"Channel", func(capacity int) Queue[int] { return NewChannelQueue[int](capacity) }},
+	}
+}
+
+// transfer has the producers push the values 1 to items between them and the consumers pop until they have them
+// all, blocking or trying and yielding to the scheduler when a try fails
+// Blocking consumers stop when Pop reports the queue closed and drained, trying consumers when the count of items
+// taken reaches the total, as a failed TryPop can't tell an empty queue from a finished one
+func transfer(ctx context.Context, name string, q Queue[int], producers, consumers, items int, blocking bool) QueueResult {
+	var failedPushes, failedPops, taken, sum atomic.Int64
+	elapsed := bench.Phase(ctx, name, func() {
+		var consumerGroup sync.WaitGroup
+		for range consumers {
+			consumerGroup.Go(func() {
+				var local, failed int64
+				for {
+					if blocking {
+						v, ok := q.Pop()
+						if !ok {
+							break
+						}
+						local += int64(v)
+						continue
+					}
+					if taken.Load() == int64(items) {
+						break
+					}
+					if v, ok := q.TryPop(); ok {
+						local += int64(v)
+						taken.Add(1)
+					} else {
+						failed++
+						runtime.Gosched()
+					}
+				}
+				sum.Add(local)
+				failedPops.Add(failed)
+			})
+		}
+
+		var producerGroup sync.WaitGroup
+		for p := range producers {
+			producerGroup.Go(func() {
+				var failed int64
+				// producer p pushes every value congruent to p+1 modulo producers, between them exactly 1 to items
+				for v := p + 1; v <= items; v += producers {
+					if blocking {
+						q.Push(v)
+						continue
+					}
+					for !q.TryPush(v) {
+						failed++
+						runtime.Gosched()
+					}
+				}
+				failedPushes.Add(failed)
+			})
+		}
+		producerGroup.Wait()
+		q.Close()
+		consumerGroup.Wait()
+	})
+
+	want := int64(items) * int64(items+1) / 2
+	return QueueResult{
+		ItemNs:       float64(elapsed.Nanoseconds()) / float64(items),
+		ItemsPerSec:  float64(items) / elapsed.Seconds(),
+		FailedPushes: failedPushes.Load(),
+		FailedPops:   failedPops.Load(),
+		Correct:      sum.Load() == want,
+	}
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Ring Buffer Against Channel")
+	fmt.Fprintln(w, "===========================")
+	fmt.Fprintf(w, "Producers: %d\nConsumers: %d\nItems: %d\nCapacity: %d\n", config.Producers, config.Consumers, config.Items, config.Capacity)
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%-12s %-12s %10s %14s %14s %14s %8s\n", "Queue", "Mode", "Time/item", "Throughput", "Failed pushes", "Failed pops", "Correct")
+	for _, q := range result.Queues {
+		fmt.Fprintf(w, "%-12s %-12s %10v %14s %14d %14d %8v\n", q.Name, q.Mode, time.Duration(q.ItemNs),
+			bench.FormatRate(q.ItemsPerSec), q.FailedPushes, q.FailedPops, q.Correct)
+	}
+	fmt.Fprintln(w, "\nBlocking, a full queue puts its pushers to sleep and an empty one its poppers, until the other side makes room")
+	fmt.Fprintln(w, "or adds an item, that's backpressure, fast producers are slowed to the pace of the consumers")
+	fmt.Fprintln(w, "Trying never sleeps, every failed try is a trip through the scheduler and back, try -capacity 1 to see them pile up")
+	fmt.Fprintln(w, "A channel is a ring buffer too, with its lock and queues of waiting goroutines built into the runtime")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo ring
+func Main(args []string) {
+	fs := bench.NewFlagSet("ring", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the ring buffer lesson measures a single run"
+	producers := fs.Int("producers", 4, "the number of goroutines pushing items")
+	consumers := fs.Int("consumers", 4, "the number of goroutines popping items")
+	numItems := fs.Int("items", 1000000, "the number of items passed through each queue in each mode")
+	capacity := fs.Int("capacity", 64, "the number of items each queue holds")
+	modeList := fs.String("modes", strings.Join(modes, ","), "comma separated modes to run, blocking, with Push and Pop, or nonblocking, with TryPush and TryPop")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "ring measures a single run, -trials isn't supported")
+	v.AtLeast("producers", *producers, 1)
+	v.AtLeast("consumers", *consumers, 1)
+	v.AtLeast("items", *numItems, 1)
+	v.AtLeast("capacity", *capacity, 1)
+	selected := strings.Split(*modeList, ",")
+	for _, mode := range selected {
+		v.OneOf("modes", mode, modes...)
+	}
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	result := RunResult{
+		Config: RunConfig{Producers: *producers, Consumers: *consumers, Items: *numItems, Capacity: *capacity, Modes: selected, GOMAXPROCS: runtime.GOMAXPROCS(0)},
+		Env:    bench.CaptureEnv(),
+	}
+	ctx := context.Background()
+	for _, mode := range selected {
+		for _, c := range candidates() {
+			slog.Info("transferring", "queue", c.name, "mode", mode, "items", *numItems)
+			queueResult := transfer(ctx, c.name+" "+mode, c.new(*capacity), *producers, *consumers, *numItems, mode == "blocking")
+			queueResult.Name, queueResult.Mode = c.name, mode
+			result.Queues = append(result.Queues, queueResult)
+		}
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per queue and mode, the failed tries per item alongside the time so benchstat shows
+// how much of the time went to spinning
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/ring_buffer"); err != nil {
+		return err
+	}
+	config := result.Config
+	benchmarks := []bench.Benchmark{}
+	for _, q := range result.Queues {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: fmt.Sprintf("Transfer/%s/%s/producers=%d/consumers=%d/capacity=%d", q.Name, q.Mode, config.Producers, config.Consumers, config.Capacity),
+			N:    int64(config.Items),
+			Metrics: []bench.Metric{
+				{Value: q.ItemNs, Unit: "ns/op"},
+				{Value: float64(q.FailedPushes+q.FailedPops) / float64(config.Items), Unit: "fails/op"},
+			},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
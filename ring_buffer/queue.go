@@ -0,0 +1,177 @@
+package ringbuffer
+
+import "sync"
+
+// Queue is a bounded first in first out queue that goroutines share, the interface a buffered channel already has
+// Push blocks while the queue is full and Pop while it's empty, TryPush and TryPop return straight away instead,
+// reporting whether they managed it
+// Once Close is called Pop drains what's left then reports false, and pushing panics, just as on a closed channel
+type Queue[T any] interface {
+	Push(v T)
+	Pop() (T, bool)
+	TryPush(v T) bool
+	TryPop() (T, bool)
+	Close()
+	Len() int
+	Cap() int
+}
+
+// RingBuffer is a bounded queue built from a fixed slice used as a circle, head is the oldest item and the items run
+// on from it for size slots, wrapping round from the end of the slice to its start, so neither end ever moves the
+// items along
+// A mutex guards it, and two conditions let a blocked Push sleep until there's room and a blocked Pop until there's
+// an item, each Signal wakes only one sleeper since one slot or one item can only satisfy one
+type RingBuffer[T any] struct {
+	mu       sync.Mutex
+	notEmpty sync.Cond
+	notFull  sync.Cond
+	items    []T
+	head     int
+	size     int
+	closed   bool
+}
+
+// NewRingBuffer creates a ring buffer holding up to capacity items
+func NewRingBuffer[T any](capacity int) *RingBuffer[T] {
+	r := &RingBuffer[T]{items: make([]T, max(capacity, 1))}
+	r.notEmpty.L = &r.mu
+	r.notFull.L = &r.mu
+	return r
+}
+
+// push and pop do the work once the lock is held and the buffer is known not to be full or empty
+func (r *RingBuffer[T]) push(v T) {
+	r.items[(r.head+r.size)%len(r.items)] = v
+	r.size++
+	r.notEmpty.Signal()
+}
+
+func (r *RingBuffer[T]) pop() T {
+	v := r.items[r.head]
+	var zero T
+	// clearing the slot lets the garbage collector have whatever the item pointed to
+	r.items[r.head] = zero
+	r.head = (r.head + 1) % len(r.items)
+	r.size--
+	r.notFull.Signal()
+	return v
+}
+
+func (r *RingBuffer[T]) Push(v T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for r.size == len(r.items) && !r.closed {
+		r.notFull.Wait()
+	}
+	if r.closed {
+		panic("push on closed ring buffer")
+	}
+	r.push(v)
+}
+
+func (r *RingBuffer[T]) Pop() (T, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for r.size == 0 && !r.closed {
+		r.notEmpty.Wait()
+	}
+	if r.size == 0 {
+		var zero T
+		return zero, false
+	}
+	return r.pop(), true
+}
+
+func (r *RingBuffer[T]) TryPush(v T) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		panic("push on closed ring buffer")
+	}
+	if r.size == len(r.items) {
+		return false
+	}
+	r.push(v)
+	return true
+}
+
+func (r *RingBuffer[T]) TryPop() (T, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size == 0 {
+		var zero T
+		return zero, false
+	}
+	return r.pop(), true
+}
+
+// Close wakes every sleeper, the poppers to drain what's left and stop, the pushers to panic as they would on a
+// closed channel
+func (r *RingBuffer[T]) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	r.notEmpty.Broadcast()
+	r.notFull.Broadcast()
+}
+
+func (r *RingBuffer[T]) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.size
+}
+
+func (r *RingBuffer[T]) Cap() int {
+	return len(r.items)
+}
+
+// ChannelQueue is a buffered channel dressed as a Queue, the runtime's own ring buffer, lock and queues of waiting
+// goroutines, select with a default case is how a channel tries without blocking
+type ChannelQueue[T any] struct {
+	ch chan T
+}
+
+// NewChannelQueue creates a channel buffering up to capacity items
+func NewChannelQueue[T any](capacity int) *ChannelQueue[T] {
+	return &ChannelQueue[T]{ch: make(chan T, max(capacity, 1))}
+}
+
+func (c *ChannelQueue[T]) Push(v T) {
+	c.ch <- v
+}
+
+func (c *ChannelQueue[T]) Pop() (T, bool) {
+	v, ok := <-c.ch
+	return v, ok
+}
+
+func (c *ChannelQueue[T]) TryPush(v T) bool {
+	select {
+	case c.ch <- v:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *ChannelQueue[T]) TryPop() (T, bool) {
+	select {
+	case v, ok := <-c.ch:
+		return v, ok
+	default:
+		var zero T
+		return zero, false
+	}
+}
+
+func (c *ChannelQueue[T]) Close() {
+	close(c.ch)
+}
+
+func (c *ChannelQueue[T]) Len() int {
+	return len(c.ch)
+}
+
+func (c *ChannelQueue[T]) Cap() int {
+	return cap(c.ch)
+}
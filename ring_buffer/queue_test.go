@@ -0,0 +1,66 @@
+package ringbuffer
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRingBufferWrapsAround(t *testing.T) {
+	r := NewRingBuffer[int](3)
+	for round := range 5 {
+		for i := range 3 {
+			if !r.TryPush(round*10 + i) {
+				t.Fatalf("round %d: TryPush %d failed with %d of 3 items", round, i, r.Len())
+			}
+		}
+		if r.TryPush(99) {
+			t.Fatalf("round %d: TryPush succeeded on a full buffer", round)
+		}
+		for i := range 3 {
+			if v, ok := r.TryPop(); !ok || v != round*10+i {
+				t.Fatalf("round %d: TryPop() = %d, %v, want %d", round, v, ok, round*10+i)
+			}
+		}
+		if _, ok := r.TryPop(); ok {
+			t.Fatalf("round %d: TryPop succeeded on an empty buffer", round)
+		}
+	}
+}
+
+func TestCloseDrainsThenStops(t *testing.T) {
+	for _, c := range candidates() {
+		q := c.new(4)
+		q.Push(1)
+		q.Push(2)
+		q.Close()
+		if v, ok := q.Pop(); !ok || v != 1 {
+			t.Errorf("%s: Pop() after Close = %d, %v, want 1, true", c.name, v, ok)
+		}
+		q.Pop()
+		if _, ok := q.Pop(); ok {
+			t.Errorf("%s: Pop() on a closed, drained queue succeeded", c.name)
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: Push after Close didn't panic", c.name)
+				}
+			}()
+			q.Push(3)
+		}()
+	}
+}
+
+func TestTransferDeliversEveryItem(t *testing.T) {
+	for _, c := range candidates() {
+		for _, blocking := range []bool{true, false} {
+			result := transfer(context.Background(), c.name, c.new(2), 3, 2, 10000, blocking)
+			if !result.Correct {
+				t.Errorf("%s, blocking %v: the consumers didn't get every item exactly once", c.name, blocking)
+			}
+			if blocking && result.FailedPushes+result.FailedPops != 0 {
+				t.Errorf("%s: blocking transfer counted %d failed tries", c.name, result.FailedPushes+result.FailedPops)
+			}
+		}
+	}
+}
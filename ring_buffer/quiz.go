@@ -0,0 +1,63 @@
+package ringbuffer
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz ring, it asks how the ring avoids moving items, why its waits sit in a loop, what
+// a full bounded queue does to producers, and when trying beats blocking
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does a ring buffer never move its items along as they're popped?",
+		Choices: []string{
+			"It copies the remaining items to a fresh slice once half of them have been popped",
+			"Popped items stay where they are and are only overwritten by later pushes",
+			"The head index moves instead, wrapping back to the start, so both ends are O(1)",
+			"It keeps the items as a linked list, so popping just moves a pointer",
+		},
+		Answer:      2,
+		Explanation: "popping from the front of a plain slice either shifts every item or leaks the space in front, the circle reuses it",
+	},
+	{
+		Prompt: "Why does the ring buffer wait on its conditions in a for loop rather than an if?",
+		Choices: []string{
+			"Wait can return before Signal is called, so the loop counts the spurious wakeups",
+			"A woken goroutine must recheck, another may have got there first, and Close wakes all",
+			"The loop re-acquires the lock each time round, which Wait on its own doesn't do",
+			"sync.Cond panics if Wait is called outside a loop, to catch exactly this mistake",
+		},
+		Answer:      1,
+		Explanation: "a condition's Signal only says something changed, by the time the sleeper holds the lock again the queue may be full or empty once more",
+	},
+	{
+		Prompt: "What happens to fast producers when a bounded queue between them and slow consumers is full?",
+		Choices: []string{
+			"Their pushes overwrite the oldest items so the newest are always kept",
+			"The queue doubles its slice to make room, like append does",
+			"Their pushes fail with an error that they retry after a short sleep",
+			"Push blocks, slowing them to the consumers' pace, backpressure",
+		},
+		Answer:      3,
+		Explanation: "an unbounded queue would let the backlog, and the memory, grow without limit, the bound pushes the slowdown back to its source",
+	},
+	{
+		Prompt: "How does a channel try to send without blocking?",
+		Choices: []string{
+			"A select with the send and a default case",
+			"Checking len(ch) < cap(ch) before sending",
+			"Sending from a new goroutine each time",
+			"A send with a zero timeout from time.After",
+		},
+		Answer:      0,
+		Explanation: "the default runs if the send would block, checking len first is a race, another sender can fill the slot between the check and the send, the select decides atomically",
+	},
+	{
+		Prompt: "When is TryPop a better fit than Pop?",
+		Choices: []string{
+			"When the queue has been closed and the remaining items need draining",
+			"When the caller has other work to do if the queue is empty",
+			"Always, since it never blocks it is always the faster of the two",
+			"When several consumers share the queue and must not block each other",
+		},
+		Answer:      1,
+		Explanation: "a failed try does no work, a caller that only tries again is spinning, the failed pops column, and a sleeping Pop would hand that CPU back",
+	},
+}
@@ -0,0 +1,212 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	xsingleflight "golang.org/x/sync/singleflight"
+)
+
+// errBackend is what the backend returns while it's failing
+var errBackend = errors.New("backend unavailable")
+
+// Backend is a slow backend, a load takes latency while no more than capacity loads are in flight, and slows in
+// proportion once there are more, the way a database does once it's past what it can serve at once
+type Backend struct {
+	latency  time.Duration
+	capacity int
+	failing  atomic.Bool
+
+	calls    atomic.Int64
+	inFlight atomic.Int64
+	peak     atomic.Int64
+}
+
+// NewBackend creates a backend serving loads in latency up to capacity at once
+func NewBackend(latency time.Duration, capacity int) *Backend {
+	return &Backend{latency: latency, capacity: max(capacity, 1)}
+}
+
+// Load is key's value, after a wait that grows with the loads in flight, or errBackend while the backend is failing
+func (b *Backend) Load(key string) (int, error) {
+	b.calls.Add(1)
+	n := b.inFlight.Add(1)
+	defer b.inFlight.Add(-1)
+	for old := b.peak.Load(); n > old && !b.peak.CompareAndSwap(old, n); old = b.peak.Load() {
+	}
+	time.Sleep(time.Duration(float64(b.latency) * max(1, float64(n)/float64(b.capacity))))
+	if b.failing.Load() {
+		return 0, errBackend
+	}
+	return len(key), nil
+}
+
+// Fail sets whether the backend fails every load
+func (b *Backend) Fail(failing bool) {
+	b.failing.Store(failing)
+}
+
+// Calls is how many loads the backend has been asked for and the most it has had in flight at once
+func (b *Backend) Calls() (calls, peak int64) {
+	return b.calls.Load(), b.peak.Load()
+}
+
+// Cache is a read-through cache in front of a Backend, Get returns a key's cached value, loading it on a miss, the
+// implementations differ in what concurrent misses for one key do, and Expire drops every value, as though they'd
+// all reached the end of their time to live at once
+type Cache interface {
+	Get(key string) (int, error)
+	Expire()
+}
+
+// values is the map of cached values the caches share, errors aren't cached, a failed load is tried again by the
+// next Get
+type values struct {
+	mu sync.RWMutex
+	m  map[string]int
+}
+
+func (v *values) get(key string) (int, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	value, ok := v.m[key]
+	return value, ok
+}
+
+func (v *values) set(key string, value int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.m == nil {
+		v.m = map[string]int{}
+	}
+	v.m[key] = value
+}
+
+func (v *values) Expire() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	clear(v.m)
+}
+
+// NaiveCache loads on every miss, so every Get that misses before the first load returns makes a load of its own,
+// the stampede
+type NaiveCache struct {
+	values
+	backend *Backend
+}
+
+func NewNaiveCache(backend *Backend) *NaiveCache {
+	return &NaiveCache{backend: backend}
+}
+
+func (c *NaiveCache) Get(key string) (int, error) {
+	if v, ok := c.get(key); ok {
+		return v, nil
+	}
+	v, err := c.backend.Load(key)
+	if err == nil {
+		c.set(key, v)
+	}
+	return v, err
+}
+
+// KeyedCache holds a mutex per key for the length of a load, a Get that misses takes its key's lock and looks again,
+// so only the first loads and the rest find its value, but a load that fails leaves nothing to find, and the next
+// Get in line makes one of its own, each waiting for the one before
+type KeyedCache struct {
+	backend *Backend
+	mu      sync.Mutex
+	entries map[string]*keyedEntry
+}
+
+type keyedEntry struct {
+	mu     sync.Mutex
+	value  int
+	loaded bool
+}
+
+func NewKeyedCache(backend *Backend) *KeyedCache {
+	return &KeyedCache{backend: backend, entries: map[string]*keyedEntry{}}
+}
+
+func (c *KeyedCache) Get(key string) (int, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &keyedEntry{}
+		c.entries[key] = e
+	}
+	c.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.loaded {
+		return e.value, nil
+	}
+	v, err := c.backend.Load(key)
+	if err == nil {
+		e.value, e.loaded = v, true
+	}
+	return v, err
+}
+
+func (c *KeyedCache) Expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// FlightCache loads a miss through a Group, so the misses while a load is in flight share it, its error included
+type FlightCache struct {
+	values
+	backend *Backend
+	group   Group[int]
+}
+
+func NewFlightCache(backend *Backend) *FlightCache {
+	return &FlightCache{backend: backend}
+}
+
+func (c *FlightCache) Get(key string) (int, error) {
+	if v, ok := c.get(key); ok {
+		return v, nil
+	}
+	v, _, err := c.group.Do(key, func() (int, error) {
+		v, err := c.backend.Load(key)
+		if err == nil {
+			c.set(key, v)
+		}
+		return v, err
+	})
+	return v, err
+}
+
+// XFlightCache is FlightCache with golang.org/x/sync/singleflight's Group in place of the one written here
+type XFlightCache struct {
+	values
+	backend *Backend
+	group   xsingleflight.Group
+}
+
+func NewXFlightCache(backend *Backend) *XFlightCache {
+	return &XFlightCache{backend: backend}
+}
+
+func (c *XFlightCache) Get(key string) (int, error) {
+	if v, ok := c.get(key); ok {
+		return v, nil
+	}
+	v, err, _ := c.group.Do(key, func() (any, error) {
+		v, err := c.backend.Load(key)
+		if err == nil {
+			c.set(key, v)
+		}
+		return v, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return v.(int), nil
+}
@@ -0,0 +1,46 @@
+package singleflight
+
+import "sync"
+
+// call is one call a Group is making, the goroutines that join it wait on wg for value and err
+type call[V any] struct {
+	wg    sync.WaitGroup
+	value V
+	err   error
+}
+
+// Group makes sure only one call for a key is in flight at once, a Do for a key that's already being called waits
+// for that call and shares its result, error and all, rather than making another
+// It's golang.org/x/sync/singleflight cut down to the idea, without its handling of a call that panics, it doesn't
+// cache anything either, once a call returns the next Do for its key makes a new one
+type Group[V any] struct {
+	mu    sync.Mutex
+	calls map[string]*call[V]
+}
+
+// Do calls fn for key unless a call for it is already in flight, in which case it waits for that one, shared
+// reports whether the result came from a call another goroutine made
+func (g *Group[V]) Do(key string, fn func() (V, error)) (value V, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*call[V]{}
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.value, true, c.err
+	}
+	c := &call[V]{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	c.value, c.err = fn()
+	// the call comes out of the map before the waiters are released, so a Do from here on makes a new call rather
+	// than sharing a result that may already be stale
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	c.wg.Done()
+	return c.value, false, c.err
+}
@@ -0,0 +1,75 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupSharesACallInFlight(t *testing.T) {
+	var g Group[int]
+	var calls, shared atomic.Int64
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			v, s, err := g.Do("key", func() (int, error) {
+				calls.Add(1)
+				<-release
+				return 42, nil
+			})
+			if v != 42 || err != nil {
+				t.Errorf("Do gave %d, %v, want 42, nil", v, err)
+			}
+			if s {
+				shared.Add(1)
+			}
+		})
+	}
+	// give every goroutine time to join the call before it returns
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 || shared.Load() != 9 {
+		t.Fatalf("made %d calls with %d results shared, want 1 call shared 9 times", calls.Load(), shared.Load())
+	}
+	if _, s, _ := g.Do("key", func() (int, error) { return 1, nil }); s {
+		t.Fatal("a Do after the call returned joined it rather than making a new one")
+	}
+}
+
+func TestStampedes(t *testing.T) {
+	for _, failing := range []bool{false, true} {
+		for _, a := range approaches {
+			backend := NewBackend(2*time.Millisecond, 100)
+			backend.Fail(failing)
+			_, errs, _ := stampede(a.newCache(backend), 20, 2, 2)
+			calls, _ := backend.Calls()
+			var want int64 = 4
+			if a.name == "naive" || a.name == "mutex per key" && failing {
+				want = 40
+			}
+			if calls != want {
+				t.Errorf("%s, failing %v: %d loads, want %d", a.name, failing, calls, want)
+			}
+			if failing && errs != 40 || !failing && errs != 0 {
+				t.Errorf("%s, failing %v: %d errors", a.name, failing, errs)
+			}
+		}
+	}
+}
+
+func TestErrorsAreNotCached(t *testing.T) {
+	backend := NewBackend(0, 1)
+	cache := NewFlightCache(backend)
+	backend.Fail(true)
+	if _, err := cache.Get("a"); !errors.Is(err, errBackend) {
+		t.Fatalf("got %v from a failing backend", err)
+	}
+	backend.Fail(false)
+	if v, err := cache.Get("a"); v != 1 || err != nil {
+		t.Fatalf("got %d, %v once the backend recovered, want 1, nil", v, err)
+	}
+}
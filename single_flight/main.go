@@ -0,0 +1,235 @@
+// Package singleflight is the singleflight lesson, teachgo singleflight, a slow backend behind a cache whose hot
+// keys all expire at once, stampeded by concurrent requests for them, with the misses loaded naively, one at a time
+// under a mutex per key, and deduplicated by a Group written here and golang.org/x/sync/singleflight
+package singleflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo singleflight -h, its first line is the summary teachgo
+// help lists
+const Description = `Singleflight, stopping a cache stampede by sharing one load between every request that misses
+
+Puts a cache in front of a slow backend, one that takes -latency a load while it has no more than -capacity in
+flight and slows in proportion past that. Expires every value at once and sends -requests concurrent requests for
+-keys hot keys, -rounds times over, a stampede. The misses are loaded four ways, naively, every miss loading for
+itself, under a mutex per key, the first miss loading while the rest wait to find its value, and with singleflight,
+every miss while a load is in flight sharing it, once with a Group written here and once with
+golang.org/x/sync/singleflight. Then the backend fails, and the stampede is run once more, where the mutex per key
+has each request waiting in line to fail in turn. Reports the loads the backend was asked for, the most it had in
+flight and how long the requests took.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "singleflight",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"singleflight", "cache stampede", "deduplication", "caching", "golang.org/x/sync"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// approaches are the ways of loading a miss compared, by name, each with the cache that loads that way
+var approaches = []struct {
+	name     string
+	newCache func(*Backend) Cache
+}{
+	{"naive", func(b *Backend) Cache { return NewNaiveCache(b) }},
+	{"mutex per key", func(b *Backend) Cache { return NewKeyedCache(b) }},
+	{"singleflight", func(b *Backend) Cache { return NewFlightCache(b) }},
+	{"x/sync singleflight", func(b *Backend) Cache { return NewXFlightCache(b) }},
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Requests int           `json:"requests"`
+	Keys     int           `json:"keys"`
+	Rounds   int           `json:"rounds"`
+	Latency  time.Duration `json:"latency"`
+	Capacity int           `json:"capacity"`
+}
+
+// Stampede is how one approach came through the stampedes, Calls is the loads the backend was asked for and Peak
+// the most it had in flight
+type Stampede struct {
+	Approach  string  `json:"approach"`
+	Failing   bool    `json:"failing"`
+	Requests  int     `json:"requests"`
+	Errors    int     `json:"errors"`
+	Calls     int64   `json:"calls"`
+	Peak      int64   `json:"peak"`
+	P50Ns     float64 `json:"p50_ns"`
+	P99Ns     float64 `json:"p99_ns"`
+	MaxNs     float64 `json:"max_ns"`
+	ElapsedNs float64 `json:"elapsed_ns"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config    RunConfig  `json:"config"`
+	Env       bench.Env  `json:"env"`
+	Stampedes []Stampede `json:"stampedes"`
+}
+
+// stampede expires everything in cache and sends requests concurrent Gets for keys hot keys at it, rounds times,
+// the requests wait on a gate so they all start together, the way they would when a popular value expires under
+// load
+func stampede(cache Cache, requests, keys, rounds int) (stats *bench.Stats, errors int, elapsed time.Duration) {
+	stats = bench.NewStats()
+	var mu sync.Mutex
+	start := time.Now()
+	for range rounds {
+		cache.Expire()
+		gate := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := range requests {
+			key := fmt.Sprintf("key-%d", i%keys)
+			wg.Go(func() {
+				<-gate
+				t := time.Now()
+				_, err := cache.Get(key)
+				stats.Record(time.Since(t))
+				if err != nil {
+					mu.Lock()
+					errors++
+					mu.Unlock()
+				}
+			})
+		}
+		close(gate)
+		wg.Wait()
+	}
+	return stats, errors, time.Since(start)
+}
+
+// run runs the stampedes with each approach against a backend of its own
+func run(config RunConfig, failing bool) []Stampede {
+	rounds := config.Rounds
+	if failing {
+		// every request fails whatever the round, once shows it
+		rounds = 1
+	}
+	stampedes := []Stampede{}
+	for _, a := range approaches {
+		slog.Info("stampeding", "approach", a.name, "failing", failing)
+		backend := NewBackend(config.Latency, config.Capacity)
+		backend.Fail(failing)
+		stats, errors, elapsed := stampede(a.newCache(backend), config.Requests, config.Keys, rounds)
+		calls, peak := backend.Calls()
+		stampedes = append(stampedes, Stampede{Approach: a.name, Failing: failing, Requests: config.Requests * rounds,
+			Errors: errors, Calls: calls, Peak: peak, P50Ns: float64(stats.Percentile(0.5)),
+			P99Ns: float64(stats.Percentile(0.99)), MaxNs: float64(stats.Max()), ElapsedNs: float64(elapsed)})
+	}
+	return stampedes
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Singleflight")
+	fmt.Fprintln(w, "============")
+	fmt.Fprintf(w, "Requests: %d over %d keys, backend loads take %v up to %d in flight\n", config.Requests, config.Keys,
+		config.Latency, config.Capacity)
+	fmt.Fprintf(w, "Machine: %s\n", result.Env)
+
+	titles := map[bool]string{
+		false: fmt.Sprintf("Stampedes, every key expired %d times", config.Rounds),
+		true:  "Backend failing, every key expired once",
+	}
+	for _, failing := range []bool{false, true} {
+		fmt.Fprintf(w, "\n=====%s=====\n", titles[failing])
+		fmt.Fprintf(w, "%-20s %8s %8s %8s %12s %12s %12s %12s\n", "Approach", "Errors", "Loads", "Peak", "p50", "p99", "Max", "Elapsed")
+		for _, s := range result.Stampedes {
+			if s.Failing != failing {
+				continue
+			}
+			fmt.Fprintf(w, "%-20s %8d %8d %8d %12v %12v %12v %12v\n", s.Approach, s.Errors, s.Calls, s.Peak,
+				time.Duration(s.P50Ns), time.Duration(s.P99Ns), time.Duration(s.MaxNs), time.Duration(s.ElapsedNs))
+		}
+	}
+	fmt.Fprintln(w, "\nNaively every request that misses loads for itself, the backend is asked for a load per request and,")
+	fmt.Fprintln(w, "far past its capacity, every one of them is slow. A mutex per key and singleflight both make one load")
+	fmt.Fprintln(w, "a key, until the backend fails. Then the mutex per key has nothing for the next request in line to")
+	fmt.Fprintln(w, "find, so it loads and fails in turn, a load per request one after another, while singleflight hands")
+	fmt.Fprintln(w, "the one failure to everyone waiting on it, as fast as a success")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo singleflight
+func Main(args []string) {
+	fs := bench.NewFlagSet("singleflight", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the singleflight lesson measures a single run"
+	requests := fs.Int("requests", 400, "the concurrent requests in a stampede")
+	keys := fs.Int("keys", 4, "the hot keys the requests are spread over")
+	rounds := fs.Int("rounds", 3, "the stampedes, every key expired before each")
+	latency := fs.Duration("latency", 10*time.Millisecond, "how long a backend load takes while it's within its capacity")
+	capacity := fs.Int("capacity", 50, "the loads the backend has in flight before it slows down")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "singleflight measures a single run, -trials isn't supported")
+	v.AtLeast("requests", *requests, 1)
+	v.AtLeast("keys", *keys, 1)
+	v.AtLeast("rounds", *rounds, 1)
+	v.NotNegative("latency", *latency)
+	v.AtLeast("capacity", *capacity, 1)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	config := RunConfig{Requests: *requests, Keys: *keys, Rounds: *rounds, Latency: *latency, Capacity: *capacity}
+	result := RunResult{Config: config, Env: bench.CaptureEnv()}
+	result.Stampedes = append(run(config, false), run(config, true)...)
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per approach and backend state, an op is one request
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/single_flight"); err != nil {
+		return err
+	}
+	benchmarks := []bench.Benchmark{}
+	for _, s := range result.Stampedes {
+		backend := "healthy"
+		if s.Failing {
+			backend = "failing"
+		}
+		n := int64(s.Requests)
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: fmt.Sprintf("%s/%s", backend, s.Approach),
+			N:    n,
+			Metrics: []bench.Metric{
+				bench.NsPerOp(s.ElapsedNs, n),
+				{Value: s.P99Ns, Unit: "p99-ns"},
+				{Value: float64(s.Calls) / float64(n), Unit: "loads/op"},
+			},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
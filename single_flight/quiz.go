@@ -0,0 +1,63 @@
+package singleflight
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz singleflight, it follows a stampede through the naive cache, a mutex per key and a
+// singleflight Group, healthy and failing, and asks why the Group forgets a call as soon as it returns
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does the naive cache ask the backend for a load per request in a stampede?",
+		Choices: []string{
+			"Every request misses before the first load fills the cache, and none knows a load is on its way",
+			"It only caches a value after the second request for its key, so the first wave always misses",
+			"Its map is sized for fewer keys than the stampede asks for, so values are evicted straight away",
+			"It expires each value as soon as it's read, so every request has to load it again",
+		},
+		Answer:      0,
+		Explanation: "the cache only helps once a value is in it, a stampede is every request arriving in the window before it is",
+	},
+	{
+		Prompt: "Why are the naive requests slower than one load, not just more of them?",
+		Choices: []string{
+			"The requests queue on the cache's single lock while each of them loads",
+			"Each request waits for the others' loads to finish before it starts its own",
+			"The lesson adds a delay to every naive request to show the cost of a miss",
+			"The backend is far past its capacity and slows down for every request in flight",
+		},
+		Answer:      3,
+		Explanation: "every request waits longer, not only the extra ones, this is what makes a stampede dangerous, the backend is hardest hit exactly when the cache has nothing to protect it with",
+	},
+	{
+		Prompt: "Why does a mutex per key load once a key while the backend's healthy, but once a request while it's failing?",
+		Choices: []string{
+			"The lock is released as soon as a load fails, letting every waiter in at once",
+			"Each waiter looks again once it has the lock, and a failure leaves nothing to find",
+			"Failed loads are retried on purpose, once for every request that was waiting",
+			"The failure is cached per request, so each waiter sees only its own error",
+		},
+		Answer:      1,
+		Explanation: "a success leaves a value to find, a failure leaves nothing so the next in line loads for itself, and they load one after another, holding the lock, so the last request waits for every failure ahead of it",
+	},
+	{
+		Prompt: "How does singleflight fail the whole stampede in the time of one load?",
+		Choices: []string{
+			"It caches the first error and returns it to every later request for the key",
+			"It cancels the backend call as soon as the first request for the key gives up",
+			"Every request arriving during the load waits on that call and gets its result",
+			"It returns an error straight away for any key whose last load failed",
+		},
+		Answer:      2,
+		Explanation: "nothing is loaded again until the call is over, a Group deduplicates calls in flight, it caches nothing, once the call returns the next Do for the key makes a new one",
+	},
+	{
+		Prompt: "Why does the Group take a finished call out of its map before releasing the goroutines waiting on it?",
+		Choices: []string{
+			"So the call's memory is freed before the waiters copy its result",
+			"Because the waiters look up their result in the map once they're released",
+			"It makes no difference, the two steps can happen in either order",
+			"So a later Do makes a new call rather than joining one already stale",
+		},
+		Answer:      3,
+		Explanation: "joining is only for calls still in flight, a result that's come back is for the cache to keep, if anything",
+	},
+}
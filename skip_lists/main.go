@@ -1,10 +1,22 @@
-package main
+// Package skiplists is the skip list lesson, run it with teachgo skiplist
+package skiplists
 
 import (
-	"flag"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"math"
 	"math/rand"
+	"os"
+	"runtime/trace"
 	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/datagen"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+	"github.com/joshdurbin/teaching-go/internal/report"
 )
 
 // LinkedListNode represents a node in a standard linked list
@@ -38,6 +50,9 @@ func (ll *LinkedList) Find(value int) bool {
 	return false
 }
 
+// levelProbability is the chance a node is promoted to the next level, making node levels geometrically distributed
+const levelProbability = 0.5
+
 // SkipListNode represents a node in a skip list with multiple forward pointers
 type SkipListNode struct {
 	value   int
@@ -46,27 +61,34 @@ type SkipListNode struct {
 
 // SkipList represents a probabilistic data structure for fast search
 type SkipList struct {
-	head      *SkipListNode
-	maxLevel  int
-	level     int
-	size      int
-	rng       *rand.Rand
+	head     *SkipListNode
+	maxLevel int
+	level    int
+	size     int
+	rng      *rand.Rand
+	shared   bool
 }
 
 // NewSkipList creates a new skip list with specified max levels
 func NewSkipList(maxLevel int) *SkipList {
+	return NewSeededSkipList(maxLevel, time.Now().UnixNano())
+}
+
+// NewSeededSkipList creates a new skip list whose node levels are drawn from seed, the same seed and inserts build
+// the same levels, so a run's level distribution can be reproduced
+func NewSeededSkipList(maxLevel int, seed int64) *SkipList {
 	return &SkipList{
 		head:     &SkipListNode{value: -1, forward: make([]*SkipListNode, maxLevel)},
 		maxLevel: maxLevel,
 		level:    0,
-		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		rng:      rand.New(rand.NewSource(seed)),
 	}
 }
 
 // randomLevel generates a random level for a new node
 func (sl *SkipList) randomLevel() int {
 	level := 0
-	for level < sl.maxLevel-1 && sl.rng.Float32() < 0.5 {
+	for level < sl.maxLevel-1 && sl.rng.Float32() < levelProbability {
 		level++
 	}
 	return level
@@ -74,6 +96,11 @@ func (sl *SkipList) randomLevel() int {
 
 // Insert adds a value to the skip list
 func (sl *SkipList) Insert(value int) {
+	// nodes still referenced by a snapshot must not be mutated, so take a private copy first
+	if sl.shared {
+		sl.detach()
+	}
+
 	update := make([]*SkipListNode, sl.maxLevel)
 	current := sl.head
 
@@ -122,94 +149,500 @@ func (sl *SkipList) Find(value int) bool {
 	return current != nil && current.value == value
 }
 
-func main() {
-	// Command line flags
-	numElements := flag.Int("elements", 1000000, "Number of elements to insert")
-	numSearches := flag.Int("searches", 10000, "Number of search operations to perform")
-	maxLevel := flag.Int("maxlevel", 16, "Maximum level for skip list")
-	seed := flag.Int64("seed", time.Now().UnixNano(), "Random seed for reproducibility")
-	flag.Parse()
+// cloneNodes deep copies the node chain starting at head, returning the new head
+// The walk follows level 0 and keeps the most recent copy seen on each level so forward pointers
+// can be stitched together in a single pass
+func cloneNodes(head *SkipListNode) *SkipListNode {
+	newHead := &SkipListNode{value: head.value, forward: make([]*SkipListNode, len(head.forward))}
+	last := make([]*SkipListNode, len(head.forward))
+	for i := range last {
+		last[i] = newHead
+	}
 
-	fmt.Printf("Data Structure Performance Comparison\n")
-	fmt.Printf("=====================================\n")
-	fmt.Printf("Elements: %d\n", *numElements)
-	fmt.Printf("Searches: %d\n", *numSearches)
-	fmt.Printf("Skip List Max Level: %d\n\n", *maxLevel)
+	for current := head.forward[0]; current != nil; current = current.forward[0] {
+		copied := &SkipListNode{value: current.value, forward: make([]*SkipListNode, len(current.forward))}
+		for i := range copied.forward {
+			last[i].forward[i] = copied
+			last[i] = copied
+		}
+	}
+	return newHead
+}
 
-	rng := rand.New(rand.NewSource(*seed))
+// Clone returns a deep copy of the skip list that shares no nodes with the original, an O(n) operation
+func (sl *SkipList) Clone() *SkipList {
+	return &SkipList{
+		head:     cloneNodes(sl.head),
+		maxLevel: sl.maxLevel,
+		level:    sl.level,
+		size:     sl.size,
+		rng:      rand.New(rand.NewSource(sl.rng.Int63())),
+	}
+}
+
+// SkipListSnapshot is a read-only, point-in-time view of a skip list
+type SkipListSnapshot struct {
+	head  *SkipListNode
+	level int
+	size  int
+}
+
+// Snapshot returns a copy-on-write view of the skip list in O(1)
+// The snapshot shares every node with the list, the list then copies its nodes on the next write so
+// the snapshot never observes later changes, the same trick MVCC databases use to give readers a stable version
+func (sl *SkipList) Snapshot() *SkipListSnapshot {
+	sl.shared = true
+	return &SkipListSnapshot{head: sl.head, level: sl.level, size: sl.size}
+}
+
+// detach gives the skip list its own copy of the nodes it currently shares with one or more snapshots
+func (sl *SkipList) detach() {
+	sl.head = cloneNodes(sl.head)
+	sl.shared = false
+}
+
+// Find searches for a value in the snapshot
+func (snap *SkipListSnapshot) Find(value int) bool {
+	current := snap.head
 
-	// Generate random data to insert
-	fmt.Println("Generating random data...")
-	data := make([]int, *numElements)
-	for i := 0; i < *numElements; i++ {
-		data[i] = rng.Intn(*numElements * 10)
+	for i := snap.level; i >= 0; i-- {
+		for current.forward[i] != nil && current.forward[i].value < value {
+			current = current.forward[i]
+		}
 	}
 
-	// Generate random search queries
-	searchQueries := make([]int, *numSearches)
-	for i := 0; i < *numSearches; i++ {
-		searchQueries[i] = rng.Intn(*numElements * 10)
+	current = current.forward[0]
+	return current != nil && current.value == value
+}
+
+// Size returns the number of elements captured by the snapshot
+func (snap *SkipListSnapshot) Size() int {
+	return snap.size
+}
+
+// LevelDistribution counts how many nodes were assigned each level, index 0 being the base level
+func (sl *SkipList) LevelDistribution() []int {
+	counts := make([]int, sl.maxLevel)
+	for current := sl.head.forward[0]; current != nil; current = current.forward[0] {
+		counts[len(current.forward)-1]++
+	}
+	return counts
+}
+
+// ExpectedLevelDistribution is how many nodes the geometric distribution randomLevel draws from puts on each level.
+// A node lands on level k with probability p^k * (1-p), except the top level which absorbs the remaining tail.
+func (sl *SkipList) ExpectedLevelDistribution() []float64 {
+	expected := make([]float64, sl.maxLevel)
+	for level := range expected {
+		probability := math.Pow(levelProbability, float64(level))
+		if level < sl.maxLevel-1 {
+			probability *= 1 - levelProbability
+		}
+		expected[level] = probability * float64(sl.size)
+	}
+	return expected
+}
+
+// minExpectedPerBin is the fewest nodes a chi-square bin should expect, below it the statistic no longer follows the
+// chi-square distribution, so the sparse top levels are pooled until every bin expects at least this many
+const minExpectedPerBin = 5
+
+// levelBin is one level, or a run of adjacent levels pooled together, as the chi-square test counts it
+type levelBin struct {
+	first, last int
+	observed    int
+	expected    float64
+}
+
+// poolLevels groups the levels into bins for the chi-square test, each level keeps a bin of its own while the bin
+// before it expects enough nodes, the sparse levels above join that bin, and a last bin still short of the minimum is
+// folded into the one below it
+func poolLevels(observed []int, expected []float64) []levelBin {
+	bins := []levelBin{}
+	for level, count := range observed {
+		if n := len(bins); n > 0 && bins[n-1].expected < minExpectedPerBin {
+			bins[n-1].last = level
+			bins[n-1].observed += count
+			bins[n-1].expected += expected[level]
+			continue
+		}
+		bins = append(bins, levelBin{first: level, last: level, observed: count, expected: expected[level]})
+	}
+	if n := len(bins); n > 1 && bins[n-1].expected < minExpectedPerBin {
+		bins[n-2].last = bins[n-1].last
+		bins[n-2].observed += bins[n-1].observed
+		bins[n-2].expected += bins[n-1].expected
+		bins = bins[:n-1]
 	}
+	return bins
+}
+
+// printLevelAnalysis compares the observed node levels against the geometric distribution randomLevel should produce.
+// The chi-square statistic summarizes how far the observation strays from theory, over the levels pooled into bins
+// that each expect enough nodes for the test to hold.
+func printLevelAnalysis(w io.Writer, sl *SkipList) {
+	observed := sl.LevelDistribution()
+	expectedLevels := sl.ExpectedLevelDistribution()
+
+	fmt.Fprintln(w, "\n=====Level Distribution=====")
+	fmt.Fprintf(w, "%-6s %12s %14s %10s\n", "Level", "Observed", "Expected", "Error")
+
+	for level, count := range observed {
+		expected := expectedLevels[level]
+
+		// levels where less than one node is expected and none landed carry no useful signal, skip them
+		if expected < 1 && count == 0 {
+			continue
+		}
+
+		percentError := 0.0
+		if expected > 0 {
+			percentError = (float64(count) - expected) / expected * 100
+		}
+		fmt.Fprintf(w, "%-6d %12d %14.1f %9.2f%%\n", level, count, expected, percentError)
+	}
+
+	bins := poolLevels(observed, expectedLevels)
+	if len(bins) < 2 {
+		fmt.Fprintf(w, "Chi-square: too few nodes for a test, it needs at least two bins expecting %d nodes each\n",
+			minExpectedPerBin)
+		return
+	}
+	chiSquare := 0.0
+	for _, bin := range bins {
+		chiSquare += math.Pow(float64(bin.observed)-bin.expected, 2) / bin.expected
+	}
+	pooled := ""
+	if tail := bins[len(bins)-1]; tail.last > tail.first {
+		pooled = fmt.Sprintf(", levels %d to %d pooled so each bin expects at least %d nodes", tail.first, tail.last,
+			minExpectedPerBin)
+	}
+	fmt.Fprintf(w, "Chi-square: %.2f with %d degrees of freedom%s (values near the degrees of freedom indicate a good fit)\n",
+		chiSquare, len(bins)-1, pooled)
+}
+
+// Description is the lesson's help text, shown by teachgo skiplist -h, its first line is the summary teachgo help lists
+const Description = `Skip list against linked list, insert and search times side by side
+
+Inserts the same random data into an unsorted linked list, each value at its head, and a skip list, then times
+searches against both, showing O(n) against O(log n) search, checks the skip list's level distribution against
+theory and compares a deep clone with a copy-on-write snapshot.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "skiplist",
+		Description: Description,
+		Difficulty:  lesson.Beginner,
+		Topics:      []string{"linked lists", "skip lists", "probabilistic data structures", "big O", "copy-on-write"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Elements     int    `json:"elements"`
+	Searches     int    `json:"searches"`
+	MaxLevel     int    `json:"max_level"`
+	Seed         int64  `json:"seed"`
+	Distribution string `json:"distribution"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config                    RunConfig `json:"config"`
+	Env                       bench.Env `json:"env"`
+	LinkedListInsertNs        int64     `json:"linked_list_insert_ns"`
+	SkipListInsertNs          int64     `json:"skip_list_insert_ns"`
+	LinkedListSearchNs        int64     `json:"linked_list_search_ns"`
+	SkipListSearchNs          int64     `json:"skip_list_search_ns"`
+	Found                     int       `json:"found"`
+	SkipListLevels            int       `json:"skip_list_levels"`
+	CloneNs                   int64     `json:"clone_ns"`
+	SnapshotNs                int64     `json:"snapshot_ns"`
+	FirstInsertAfterSnapshot  int64     `json:"first_insert_after_snapshot_ns"`
+	SecondInsertAfterSnapshot int64     `json:"second_insert_after_snapshot_ns"`
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo skiplist
+func Main(args []string) {
+	fs := bench.NewFlagSet("skiplist", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("seed").Usage = "Random seed for reproducibility"
+	fs.Lookup("format").Usage = "The output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "Not supported, the skip list lesson measures a single run"
+	seed := &globals.Seed
+
+	numElements := fs.Int("elements", 1000000, "Number of elements to insert")
+	numSearches := fs.Int("searches", 10000, "Number of search operations to perform")
+	maxLevel := fs.Int("maxlevel", 16, "Maximum level for skip list")
+	traceOut := fs.String("trace", "", "Write a runtime/trace of the run to this file (view with go tool trace)")
+	htmlOut := fs.String("html", "", "Write the results as a self-contained HTML page of charts to this file")
+	sweep := fs.Bool("sweep", false, "Measure search times over a range of element counts, from -sweep-min to -sweep-max, instead of a single run")
+	sweepMin := fs.Int("sweep-min", 1000, "The fewest elements a sweep measures")
+	sweepMax := fs.Int("sweep-max", 10000000, "The most elements a sweep measures, 10 million needs about 2GB of memory")
+	distribution := fs.String("dist", "uniform", "How the inserted values and search targets are drawn from [0, 10 x elements): "+datagen.Usage)
+	fs.Parse(args)
+
+	// Reject values the benchmark can't run with, no elements leaves nothing to draw search targets from and a skip
+	// list needs at least its base level
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "skiplist measures a single run, -trials isn't supported")
+	v.AtLeast("elements", *numElements, 1)
+	v.AtLeast("searches", *numSearches, 0)
+	v.AtLeast("maxlevel", *maxLevel, 1)
+	v.Check(*maxLevel <= 64, "-maxlevel can be at most 64, enough levels for 2^64 elements, got %d", *maxLevel)
+	// a sweep's first step draws from the smallest range, a file of values that fits it fits every step after it
+	smallest := *numElements
+	if *sweep {
+		smallest = *sweepMin
+	}
+	if smallest >= 1 {
+		_, err := datagen.Parse(*distribution, 10*smallest)
+		v.Add(err)
+	}
+	if *sweep {
+		v.AtLeast("sweep-min", *sweepMin, 1)
+		v.Check(*sweepMax >= *sweepMin, "-sweep-max %d is below -sweep-min %d", *sweepMax, *sweepMin)
+		v.Check(*htmlOut == "", "-html draws a single run, plot a sweep with teachgo plot instead")
+	}
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	// with JSON or benchmark output the progress text is dropped so stdout holds nothing but the results
+	out := io.Writer(os.Stdout)
+	if globals.Format != "text" {
+		out = io.Discard
+	}
+
+	// Start an execution trace if requested, each phase below is wrapped in a user region so the
+	// trace UI lays them out by name under the "benchmark" task
+	if *traceOut != "" {
+		stopTrace, err := bench.StartTrace(*traceOut)
+		if err != nil {
+			slog.Error("failed to start the trace", "path", *traceOut, "err", err)
+			os.Exit(1)
+		}
+		defer stopTrace()
+	}
+
+	ctx, task := trace.NewTask(context.Background(), "benchmark")
+	defer task.End()
+
+	if *sweep {
+		result := runSweep(ctx, SweepConfig{MinElements: *sweepMin, MaxElements: *sweepMax, Searches: *numSearches, MaxLevel: *maxLevel, Seed: *seed, Distribution: *distribution})
+		switch globals.Format {
+		case "json":
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(result); err != nil {
+				slog.Error("failed to write results", "err", err)
+				os.Exit(1)
+			}
+		case "bench":
+			if err := writeBenchmarks(os.Stdout, result.Env, sweepBenchmarks(result)); err != nil {
+				slog.Error("failed to write benchmarks", "err", err)
+				os.Exit(1)
+			}
+		default:
+			printSweep(out, result)
+		}
+		return
+	}
+
+	dist := datagen.MustParse(*distribution, *numElements*10)
+
+	fmt.Fprintf(out, "Data Structure Performance Comparison\n")
+	fmt.Fprintf(out, "=====================================\n")
+	fmt.Fprintf(out, "Elements: %d\n", *numElements)
+	fmt.Fprintf(out, "Searches: %d\n", *numSearches)
+	fmt.Fprintf(out, "Skip List Max Level: %d\n", *maxLevel)
+	fmt.Fprintf(out, "Distribution: %s\n", dist)
+	fmt.Fprintf(out, "Machine: %s\n\n", bench.CaptureEnv())
+
+	rng := rand.New(rand.NewSource(*seed))
+
+	// Generate random data to insert, then the search queries from the same distribution
+	slog.Info("generating random data", "elements", *numElements, "searches", *numSearches, "distribution", dist)
+	var data, searchQueries []int
+	bench.Phase(ctx, "generate data", func() {
+		data = dist.Fill(rng, *numElements)
+		searchQueries = dist.Fill(rng, *numSearches)
+	})
 
 	// Benchmark Linked List
-	fmt.Println("Building Linked List...")
+	slog.Info("building the linked list")
 	ll := &LinkedList{}
-	startInsert := time.Now()
-	for _, value := range data {
-		ll.Insert(value)
-	}
-	llInsertDuration := time.Since(startInsert)
+	llInsertDuration := bench.Phase(ctx, "build linked list", func() {
+		for _, value := range data {
+			ll.Insert(value)
+		}
+	})
 
-	fmt.Printf("Linked List insert time: %v\n", llInsertDuration)
-	fmt.Printf("Linked List size: %d\n", ll.size)
+	fmt.Fprintf(out, "Linked List insert time: %v\n", llInsertDuration)
+	fmt.Fprintf(out, "Linked List size: %d\n", ll.size)
 
 	// Benchmark Skip List
-	fmt.Println("\nBuilding Skip List...")
-	sl := NewSkipList(*maxLevel)
-	startInsert = time.Now()
-	for _, value := range data {
-		sl.Insert(value)
-	}
-	slInsertDuration := time.Since(startInsert)
+	fmt.Fprintln(out)
+	slog.Info("building the skip list")
+	sl := NewSeededSkipList(*maxLevel, *seed)
+	slInsertDuration := bench.Phase(ctx, "build skip list", func() {
+		for _, value := range data {
+			sl.Insert(value)
+		}
+	})
 
-	fmt.Printf("Skip List insert time: %v\n", slInsertDuration)
-	fmt.Printf("Skip List size: %d\n", sl.size)
-	fmt.Printf("Skip List actual levels: %d\n", sl.level+1)
+	fmt.Fprintf(out, "Skip List insert time: %v\n", slInsertDuration)
+	fmt.Fprintf(out, "Skip List size: %d\n", sl.size)
+	fmt.Fprintf(out, "Skip List actual levels: %d\n", sl.level+1)
 
 	// Benchmark Linked List Search
-	fmt.Println("\nSearching Linked List...")
+	fmt.Fprintln(out)
+	slog.Info("searching the linked list")
 	llFoundCount := 0
-	startSearch := time.Now()
-	for _, query := range searchQueries {
-		if ll.Find(query) {
-			llFoundCount++
+	llSearchDuration := bench.Phase(ctx, "search linked list", func() {
+		for _, query := range searchQueries {
+			if ll.Find(query) {
+				llFoundCount++
+			}
 		}
-	}
-	llSearchDuration := time.Since(startSearch)
+	})
 
-	fmt.Printf("Linked List search time: %v\n", llSearchDuration)
-	fmt.Printf("Linked List found: %d/%d\n", llFoundCount, *numSearches)
-	fmt.Printf("Linked List avg per search: %v\n", llSearchDuration/time.Duration(*numSearches))
+	fmt.Fprintf(out, "Linked List search time: %v\n", llSearchDuration)
+	fmt.Fprintf(out, "Linked List found: %d/%d\n", llFoundCount, *numSearches)
+	fmt.Fprintf(out, "Linked List avg per search: %v\n", bench.PerOp(llSearchDuration, *numSearches))
 
 	// Benchmark Skip List Search
-	fmt.Println("\nSearching Skip List...")
+	fmt.Fprintln(out)
+	slog.Info("searching the skip list")
 	slFoundCount := 0
-	startSearch = time.Now()
-	for _, query := range searchQueries {
-		if sl.Find(query) {
-			slFoundCount++
+	slSearchDuration := bench.Phase(ctx, "search skip list", func() {
+		for _, query := range searchQueries {
+			if sl.Find(query) {
+				slFoundCount++
+			}
 		}
-	}
-	slSearchDuration := time.Since(startSearch)
+	})
 
-	fmt.Printf("Skip List search time: %v\n", slSearchDuration)
-	fmt.Printf("Skip List found: %d/%d\n", slFoundCount, *numSearches)
-	fmt.Printf("Skip List avg per search: %v\n", slSearchDuration/time.Duration(*numSearches))
+	fmt.Fprintf(out, "Skip List search time: %v\n", slSearchDuration)
+	fmt.Fprintf(out, "Skip List found: %d/%d\n", slFoundCount, *numSearches)
+	fmt.Fprintf(out, "Skip List avg per search: %v\n", bench.PerOp(slSearchDuration, *numSearches))
 
 	// Summary
-	fmt.Println("\n" + "=====Summary=====")
-	fmt.Printf("Insert speedup (Skip List vs Linked List): %.2fx\n",
-		float64(llInsertDuration)/float64(slInsertDuration))
-	fmt.Printf("Search speedup (Skip List vs Linked List): %.2fx\n",
-		float64(llSearchDuration)/float64(slSearchDuration))
+	fmt.Fprintln(out, "\n"+"=====Summary=====")
+	fmt.Fprintf(out, "Insert speedup (Skip List vs Linked List): %.2fx\n",
+		bench.Speedup(llInsertDuration, slInsertDuration))
+	fmt.Fprintf(out, "Search speedup (Skip List vs Linked List): %.2fx\n",
+		bench.Speedup(llSearchDuration, slSearchDuration))
+
+	printLevelAnalysis(out, sl)
+
+	// Benchmark Clone and Snapshot
+	// A deep clone pays for every node up front, a copy-on-write snapshot is free to take and defers that
+	// cost to the first write against the list that follows it
+	fmt.Fprintln(out, "\n=====Snapshots=====")
+	var clone *SkipList
+	var snapshot *SkipListSnapshot
+	var cloneDuration, snapshotDuration, firstWriteDuration, secondWriteDuration time.Duration
+	trace.WithRegion(ctx, "snapshot", func() {
+		cloneDuration = bench.Phase(ctx, "clone", func() { clone = sl.Clone() })
+		snapshotDuration = bench.Phase(ctx, "take snapshot", func() { snapshot = sl.Snapshot() })
+		firstWriteDuration = bench.Phase(ctx, "first insert", func() { sl.Insert(rng.Intn(*numElements * 10)) })
+		secondWriteDuration = bench.Phase(ctx, "second insert", func() { sl.Insert(rng.Intn(*numElements * 10)) })
+	})
+
+	fmt.Fprintf(out, "Clone (deep copy) time: %v\n", cloneDuration)
+	fmt.Fprintf(out, "Snapshot (copy-on-write) time: %v\n", snapshotDuration)
+	fmt.Fprintf(out, "First insert after snapshot (pays the copy): %v\n", firstWriteDuration)
+	fmt.Fprintf(out, "Second insert after snapshot: %v\n", secondWriteDuration)
+	fmt.Fprintf(out, "Sizes after two inserts - list: %d, clone: %d, snapshot: %d\n", sl.size, clone.size, snapshot.Size())
+
+	if *htmlOut != "" {
+		structures := []string{"Linked List", "Skip List"}
+		levels := report.Chart{
+			Title:  "Nodes per level, observed against the geometric distribution",
+			Series: []string{"Observed", "Expected"},
+		}
+		expected := sl.ExpectedLevelDistribution()
+		for level, count := range sl.LevelDistribution() {
+			if count == 0 && expected[level] < 1 {
+				continue
+			}
+			levels.Groups = append(levels.Groups, report.Group{
+				Label:  fmt.Sprintf("Level %d", level),
+				Values: []float64{float64(count), expected[level]},
+			})
+		}
+
+		r := report.Report{
+			Title: "Data Structure Performance Comparison",
+			Settings: []report.Setting{
+				{Name: "Elements", Value: fmt.Sprint(*numElements)},
+				{Name: "Searches", Value: fmt.Sprint(*numSearches)},
+				{Name: "Skip List Max Level", Value: fmt.Sprint(*maxLevel)},
+				{Name: "Seed", Value: fmt.Sprint(*seed)},
+				{Name: "Distribution", Value: dist.String()},
+				{Name: "Machine", Value: bench.CaptureEnv().String()},
+			},
+			Charts: []report.Chart{
+				report.Bars("Insert time", report.Nanoseconds, structures,
+					[]float64{float64(llInsertDuration), float64(slInsertDuration)}),
+				report.Bars("Average time per search", report.Nanoseconds, structures,
+					[]float64{float64(bench.PerOp(llSearchDuration, *numSearches)), float64(bench.PerOp(slSearchDuration, *numSearches))}),
+				levels,
+				report.Bars("Clone against copy-on-write snapshot", report.Nanoseconds,
+					[]string{"Clone", "Snapshot", "First insert after", "Second insert after"},
+					[]float64{float64(cloneDuration), float64(snapshotDuration), float64(firstWriteDuration), float64(secondWriteDuration)}),
+			},
+			Notes: []string{
+				fmt.Sprintf("Insert speedup (Skip List vs Linked List): %.2fx", bench.Speedup(llInsertDuration, slInsertDuration)),
+				fmt.Sprintf("Search speedup (Skip List vs Linked List): %.2fx", bench.Speedup(llSearchDuration, slSearchDuration)),
+			},
+		}
+		if err := report.Write(*htmlOut, r); err != nil {
+			slog.Error("failed to write HTML report", "path", *htmlOut, "err", err)
+			os.Exit(1)
+		}
+		slog.Info("wrote the HTML report", "path", *htmlOut)
+	}
+
+	if globals.Format == "bench" {
+		elements := fmt.Sprintf("elements=%d", *numElements)
+		err := writeBenchmarks(os.Stdout, bench.CaptureEnv(), []bench.Benchmark{
+			{Name: "LinkedListInsert/" + elements, N: int64(*numElements), Metrics: []bench.Metric{bench.NsPerOp(float64(llInsertDuration), int64(*numElements))}},
+			{Name: "SkipListInsert/" + elements, N: int64(*numElements), Metrics: []bench.Metric{bench.NsPerOp(float64(slInsertDuration), int64(*numElements))}},
+			{Name: "LinkedListSearch/" + elements, N: int64(*numSearches), Metrics: []bench.Metric{bench.NsPerOp(float64(llSearchDuration), int64(*numSearches))}},
+			{Name: "SkipListSearch/" + elements, N: int64(*numSearches), Metrics: []bench.Metric{bench.NsPerOp(float64(slSearchDuration), int64(*numSearches))}},
+			{Name: "Clone/" + elements, N: 1, Metrics: []bench.Metric{bench.NsPerOp(float64(cloneDuration), 1)}},
+			{Name: "Snapshot/" + elements, N: 1, Metrics: []bench.Metric{bench.NsPerOp(float64(snapshotDuration), 1)}},
+		})
+		if err != nil {
+			slog.Error("failed to write benchmarks", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	if globals.Format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(RunResult{
+			Config:                    RunConfig{Elements: *numElements, Searches: *numSearches, MaxLevel: *maxLevel, Seed: *seed, Distribution: dist.String()},
+			Env:                       bench.CaptureEnv(),
+			LinkedListInsertNs:        llInsertDuration.Nanoseconds(),
+			SkipListInsertNs:          slInsertDuration.Nanoseconds(),
+			LinkedListSearchNs:        llSearchDuration.Nanoseconds(),
+			SkipListSearchNs:          slSearchDuration.Nanoseconds(),
+			Found:                     slFoundCount,
+			SkipListLevels:            sl.level + 1,
+			CloneNs:                   cloneDuration.Nanoseconds(),
+			SnapshotNs:                snapshotDuration.Nanoseconds(),
+			FirstInsertAfterSnapshot:  firstWriteDuration.Nanoseconds(),
+			SecondInsertAfterSnapshot: secondWriteDuration.Nanoseconds(),
+		})
+		if err != nil {
+			slog.Error("failed to write results", "err", err)
+			os.Exit(1)
+		}
+	}
 }
@@ -0,0 +1,63 @@
+package skiplists
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz skiplist, it asks why searches are logarithmic, how coin flips choose levels, what too
+// low a max level does, and what the copy-on-write snapshot and the unsorted linked list each defer
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does searching the skip list take so much less time than searching the linked list?",
+		Choices: []string{
+			"The skip list keeps its values in a contiguous array, so a search runs through the cache",
+			"The skip list remembers where recent searches ended and starts the next one from there",
+			"The linked list is searched from its tail, and most searched values sit near the head",
+			"The upper levels skip over runs of nodes, so a search visits about log n of them",
+		},
+		Answer:      3,
+		Explanation: "each level holds about half the nodes of the one below, so every step down a level halves what's left to search, O(log n) rather than O(n)",
+	},
+	{
+		Prompt: "How does the skip list decide how many levels a new node reaches?",
+		Choices: []string{
+			"It rebalances the levels after each insert so every level holds half the one below",
+			"It derives the level from the value, so larger values always reach higher levels",
+			"By flipping a coin, going up each next level with probability one half",
+			"It promotes every other node inserted, alternating between level 0 and level 1",
+		},
+		Answer:      2,
+		Explanation: "the levels are random, no rebalancing is needed and on average the list stays balanced, which is why the level distribution roughly halves per level",
+	},
+	{
+		Prompt: "What happens if -maxlevel is far too small for the number of elements?",
+		Choices: []string{
+			"The top level fills with nodes, and searches slow towards a linked list's",
+			"Inserts start failing once every level up to the maximum is full",
+			"Every node is given the maximum level, so the list uses far more memory",
+			"Nothing, the max level only caps memory and doesn't affect searches",
+		},
+		Answer:      0,
+		Explanation: "about log2(n) levels are needed, with fewer the top level holds too many nodes to skip over quickly",
+	},
+	{
+		Prompt: "Why is taking a copy-on-write snapshot so much faster than cloning the skip list?",
+		Choices: []string{
+			"The snapshot only copies the level 0 nodes and rebuilds the upper levels on demand",
+			"The snapshot shares the nodes and leaves the copy to the next write, which pays for it",
+			"The snapshot is taken by a background goroutine, so the caller doesn't wait for it",
+			"Cloning allocates every node separately and the garbage collector runs part way through",
+		},
+		Answer:      1,
+		Explanation: "that's why the first insert after a snapshot is slow and the second fast again, the copy happened once, on the first write",
+	},
+	{
+		Prompt: "Why is building the linked list faster than building the skip list?",
+		Choices: []string{
+			"The linked list allocates all its nodes in one block up front",
+			"The skip list is copied on every insert to keep its snapshots valid",
+			"The skip list builds its levels in a second pass after every insert",
+			"The linked list puts each value at its head without finding its place",
+		},
+		Answer:      3,
+		Explanation: "so it stays unsorted, a linked list insert is O(1) at the head, the skip list pays O(log n) per insert to keep its values sorted, and the linked list pays at search time by scanning every node",
+	},
+}
@@ -0,0 +1,164 @@
+package skiplists
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/datagen"
+)
+
+// linkedListSweepBudget caps the nodes the linked list searches visit at each size of a sweep, about a second's
+// worth, at 10 million elements a search walks the whole list so the full number of searches would take minutes
+const linkedListSweepBudget = 200_000_000
+
+// SweepConfig is the settings of a sweep
+type SweepConfig struct {
+	MinElements int   `json:"min_elements"`
+	MaxElements int   `json:"max_elements"`
+	Searches    int   `json:"searches"`
+	MaxLevel    int   `json:"max_level"`
+	Seed        int64 `json:"seed"`
+	// Distribution is the datagen distribution values are drawn from, over [0, 10 x elements) at each size
+	Distribution string `json:"distribution"`
+}
+
+// SweepPoint is the search times measured at one number of elements, averaged per search
+type SweepPoint struct {
+	Elements           int     `json:"elements"`
+	LinkedListSearches int     `json:"linked_list_searches"`
+	LinkedListSearchNs float64 `json:"linked_list_search_ns"`
+	SkipListSearches   int     `json:"skip_list_searches"`
+	SkipListSearchNs   float64 `json:"skip_list_search_ns"`
+	SkipListLevels     int     `json:"skip_list_levels"`
+}
+
+// SweepResult is a whole sweep, written by teachgo skiplist -sweep -format json, teachgo plot draws its points
+type SweepResult struct {
+	Config SweepConfig  `json:"config"`
+	Env    bench.Env    `json:"env"`
+	Points []SweepPoint `json:"points"`
+}
+
+// sweepSizes steps from min to max elements in a 1, 2, 5 sequence, 1000, 2000, 5000, 10000 and so on, evenly spaced
+// on a log scale so both curves get as many points at the small end as the large
+func sweepSizes(min, max int) []int {
+	sizes := []int{}
+	for decade := 1; decade <= max; decade *= 10 {
+		for _, step := range []int{1, 2, 5} {
+			if n := decade * step; n >= min && n <= max {
+				sizes = append(sizes, n)
+			}
+		}
+	}
+	if len(sizes) == 0 || sizes[len(sizes)-1] != max {
+		sizes = append(sizes, max)
+	}
+	return sizes
+}
+
+// runSweep measures the average search time of both structures at each size, building them afresh each time
+// A single run shows the skip list winning, a sweep shows how, the linked list's time per search grows in step with n
+// while the skip list's barely moves, O(n) against O(log n)
+func runSweep(ctx context.Context, cfg SweepConfig) SweepResult {
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	result := SweepResult{Config: cfg, Env: bench.CaptureEnv()}
+
+	for _, n := range sweepSizes(cfg.MinElements, cfg.MaxElements) {
+		slog.Info("running sweep step", "elements", n)
+		dist := datagen.MustParse(cfg.Distribution, n*10)
+		ll := &LinkedList{}
+		sl := NewSeededSkipList(cfg.MaxLevel, cfg.Seed+int64(n))
+		bench.Phase(ctx, fmt.Sprintf("build %d", n), func() {
+			source := dist.Source(rng)
+			for range n {
+				value := source.Next()
+				ll.Insert(value)
+				sl.Insert(value)
+			}
+		})
+
+		queries := dist.Fill(rng, cfg.Searches)
+		llQueries := queries[:max(min(len(queries), linkedListSweepBudget/n), min(len(queries), 10))]
+
+		llDuration := bench.Phase(ctx, fmt.Sprintf("search linked list %d", n), func() {
+			for _, query := range llQueries {
+				ll.Find(query)
+			}
+		})
+		slDuration := bench.Phase(ctx, fmt.Sprintf("search skip list %d", n), func() {
+			for _, query := range queries {
+				sl.Find(query)
+			}
+		})
+
+		result.Points = append(result.Points, SweepPoint{
+			Elements:           n,
+			LinkedListSearches: len(llQueries),
+			LinkedListSearchNs: perSearch(llDuration, len(llQueries)),
+			SkipListSearches:   len(queries),
+			SkipListSearchNs:   perSearch(slDuration, len(queries)),
+			SkipListLevels:     sl.level + 1,
+		})
+	}
+	return result
+}
+
+func perSearch(elapsed time.Duration, searches int) float64 {
+	if searches == 0 {
+		return 0
+	}
+	return float64(elapsed.Nanoseconds()) / float64(searches)
+}
+
+// printSweep tabulates the sweep, dividing each time per search by the growth its complexity predicts, a column
+// that stays roughly flat as n grows confirms the prediction
+func printSweep(w io.Writer, result SweepResult) {
+	fmt.Fprintln(w, "Search time against the number of elements")
+	fmt.Fprintln(w, "==========================================")
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+	fmt.Fprintf(w, "%-10s %16s %16s %9s %12s %16s\n", "Elements", "Linked List", "Skip List", "Speedup", "Linked / n", "Skip / log2(n)")
+	for _, p := range result.Points {
+		speedup := 0.0
+		if p.SkipListSearchNs > 0 {
+			speedup = p.LinkedListSearchNs / p.SkipListSearchNs
+		}
+		fmt.Fprintf(w, "%-10d %16v %16v %8.1fx %10.3fns %14.2fns\n", p.Elements,
+			time.Duration(p.LinkedListSearchNs), time.Duration(p.SkipListSearchNs), speedup,
+			p.LinkedListSearchNs/float64(p.Elements), p.SkipListSearchNs/math.Log2(float64(max(p.Elements, 2))))
+	}
+	fmt.Fprintln(w, "\nTimes are per search. Linked / n staying roughly flat means the linked list's search time grows in")
+	fmt.Fprintln(w, "proportion to n, O(n), Skip / log2(n) staying roughly flat means the skip list's grows with log n, O(log n)")
+	fmt.Fprintln(w, "Cache misses bend both curves upwards once the structures outgrow the CPU's caches")
+}
+
+// benchPackage is the package benchstat shows above the results, as go test would for a package's own benchmarks
+const benchPackage = "github.com/joshdurbin/teaching-go/skip_lists"
+
+// writeBenchmarks writes results in Go's benchmark format, the skip list lesson measures one run at a time so
+// benchstat needs several runs appended to one file, e.g. for i in $(seq 10); do teachgo skiplist -format bench; done
+func writeBenchmarks(w io.Writer, env bench.Env, benchmarks []bench.Benchmark) error {
+	if err := bench.WriteBenchHeader(w, env, benchPackage); err != nil {
+		return err
+	}
+	return bench.WriteBenchmarks(w, env, benchmarks)
+}
+
+// sweepBenchmarks is a benchmark line per structure at each size, named by the elements so benchstat tabulates the
+// sizes side by side
+func sweepBenchmarks(result SweepResult) []bench.Benchmark {
+	benchmarks := []bench.Benchmark{}
+	for _, p := range result.Points {
+		elements := fmt.Sprintf("elements=%d", p.Elements)
+		benchmarks = append(benchmarks,
+			bench.Benchmark{Name: "LinkedListSearch/" + elements, N: int64(p.LinkedListSearches), Metrics: []bench.Metric{{Value: p.LinkedListSearchNs, Unit: "ns/op"}}},
+			bench.Benchmark{Name: "SkipListSearch/" + elements, N: int64(p.SkipListSearches), Metrics: []bench.Metric{{Value: p.SkipListSearchNs, Unit: "ns/op"}}},
+		)
+	}
+	return benchmarks
+}
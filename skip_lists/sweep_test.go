@@ -0,0 +1,42 @@
+package skiplists
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSweepSizes(t *testing.T) {
+	tests := []struct {
+		min, max int
+		want     []int
+	}{
+		{1000, 10000, []int{1000, 2000, 5000, 10000}},
+		{1500, 30000, []int{2000, 5000, 10000, 20000, 30000}},
+		{1, 1, []int{1}},
+	}
+	for _, tt := range tests {
+		if got := sweepSizes(tt.min, tt.max); !slices.Equal(got, tt.want) {
+			t.Errorf("sweepSizes(%d, %d) = %v, want %v", tt.min, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestPoolLevels(t *testing.T) {
+	tests := []struct {
+		observed []int
+		expected []float64
+		want     []levelBin
+	}{
+		// the sparse top levels pool into one bin expecting at least 5
+		{[]int{50, 26, 12, 8, 3, 1, 0}, []float64{50, 25, 12.5, 6.25, 3.125, 1.5625, 1.5625},
+			[]levelBin{{0, 0, 50, 50}, {1, 1, 26, 25}, {2, 2, 12, 12.5}, {3, 3, 8, 6.25}, {4, 6, 4, 6.25}}},
+		// a pooled tail still short of 5 folds into the level below
+		{[]int{10, 6, 2}, []float64{10, 6, 2}, []levelBin{{0, 0, 10, 10}, {1, 2, 8, 8}}},
+		{[]int{2, 1}, []float64{2, 1}, []levelBin{{0, 1, 3, 3}}},
+	}
+	for _, tt := range tests {
+		if got := poolLevels(tt.observed, tt.expected); !slices.Equal(got, tt.want) {
+			t.Errorf("poolLevels(%v, %v) = %v, want %v", tt.observed, tt.expected, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+// Package sorting is the sorting lesson, teachgo sort, insertion sort, merge sort, quicksort and heapsort built from
+// scratch and raced against the standard library's sorts over input sizes and orders
+package sorting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo sort -h, its first line is the summary teachgo help lists
+const Description = `Sorting algorithms shoot-out, from scratch against sort.Slice and slices.Sort
+
+Implements insertion sort, merge sort, quicksort and heapsort, and times them alongside the standard library's
+sort.Slice and slices.Sort on inputs of several sizes and orders, random, already sorted, reversed, nearly sorted
+and with only a few distinct values. Shows how much the order of the input matters to some algorithms and not at
+all to others, insertion sort racing through nearly sorted input it takes quadratic time on otherwise, and how the
+standard library's pattern-defeating quicksort adapts to all of them.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "sort",
+		Description: Description,
+		Difficulty:  lesson.Beginner,
+		Topics:      []string{"sorting", "divide and conquer", "asymptotic complexity", "adaptive algorithms", "generics"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// insertionMaxSize is the largest input insertion sort is timed on when its order makes it quadratic, a million
+// random elements would take it minutes
+const insertionMaxSize = 20_000
+
+// order is a way of arranging the input, quadratic marks the orders insertion sort takes O(n^2) on
+type order struct {
+	name      string
+	quadratic bool
+	fill      func(rng *rand.Rand, s []int)
+}
+
+var orders = []order{
+	{"random", true, func(rng *rand.Rand, s []int) {
+		for i := range s {
+			s[i] = rng.Intn(10 * len(s))
+		}
+	}},
+	{"sorted", false, func(rng *rand.Rand, s []int) {
+		for i := range s {
+			s[i] = i
+		}
+	}},
+	{"reversed", true, func(rng *rand.Rand, s []int) {
+		for i := range s {
+			s[i] = len(s) - i
+		}
+	}},
+	// nearly sorted is sorted with one element in a hundred swapped with another at random
+	{"nearly", false, func(rng *rand.Rand, s []int) {
+		for i := range s {
+			s[i] = i
+		}
+		for range len(s) / 100 {
+			i, j := rng.Intn(len(s)), rng.Intn(len(s))
+			s[i], s[j] = s[j], s[i]
+		}
+	}},
+	{"few-unique", true, func(rng *rand.Rand, s []int) {
+		for i := range s {
+			s[i] = rng.Intn(10)
+		}
+	}},
+}
+
+// algorithm is a sort under test
+type algorithm struct {
+	name string
+	sort func([]int)
+}
+
+var algorithms = []algorithm{
+	{"Insertion sort", InsertionSort},
+	{"Merge sort", MergeSort},
+	{"Quicksort", QuickSort},
+	{"Heapsort", HeapSort},
+	// sort.Slice reaches the elements through a less closure and a swapper built with reflection
+	{"sort.Slice", func(s []int) { sort.Slice(s, func(i, j int) bool { return s[i] < s[j] }) }},
+	{"slices.Sort", slices.Sort[[]int]},
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Sizes  []int    `json:"sizes"`
+	Orders []string `json:"orders"`
+	Seed   int64    `json:"seed"`
+}
+
+// Timing is one algorithm's time to sort one input, Skipped when it would have taken too long
+type Timing struct {
+	Algorithm string  `json:"algorithm"`
+	Order     string  `json:"order"`
+	Size      int     `json:"size"`
+	Ns        float64 `json:"ns"`
+	Sorted    bool    `json:"sorted"`
+	Skipped   bool    `json:"skipped,omitempty"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config  RunConfig `json:"config"`
+	Env     bench.Env `json:"env"`
+	Timings []Timing  `json:"timings"`
+}
+
+// measure sorts a copy of the input with every algorithm
+func measure(ctx context.Context, o order, input []int) []Timing {
+	timings := []Timing{}
+	for _, a := range algorithms {
+		timing := Timing{Algorithm: a.name, Order: o.name, Size: len(input)}
+		if a.name == "Insertion sort" && o.quadratic && len(input) > insertionMaxSize {
+			timing.Skipped = true
+			timings = append(timings, timing)
+			continue
+		}
+		s := slices.Clone(input)
+		elapsed := bench.Phase(ctx, fmt.Sprintf("%s %s %d", a.name, o.name, len(input)), func() { a.sort(s) })
+		timing.Ns = float64(elapsed.Nanoseconds())
+		timing.Sorted = slices.IsSorted(s)
+		timings = append(timings, timing)
+	}
+	return timings
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Sorting Shoot-out")
+	fmt.Fprintln(w, "=================")
+	fmt.Fprintf(w, "Sizes: %s\nOrders: %s\n", strings.Trim(fmt.Sprint(config.Sizes), "[]"), strings.Join(config.Orders, ", "))
+	fmt.Fprintf(w, "Machine: %s\n", result.Env)
+
+	allSorted := true
+	for _, orderName := range config.Orders {
+		fmt.Fprintf(w, "\n=====%s=====\n", orderName)
+		fmt.Fprintf(w, "%-16s", "Algorithm")
+		for _, size := range config.Sizes {
+			fmt.Fprintf(w, " %14s", "n="+strconv.Itoa(size))
+		}
+		fmt.Fprintln(w)
+		for _, a := range algorithms {
+			fmt.Fprintf(w, "%-16s", a.name)
+			for _, t := range result.Timings {
+				if t.Algorithm != a.name || t.Order != orderName {
+					continue
+				}
+				cell := time.Duration(t.Ns).String()
+				if t.Skipped {
+					cell = "skipped"
+				}
+				allSorted = allSorted && (t.Sorted || t.Skipped)
+				fmt.Fprintf(w, " %14s", cell)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintf(w, "\nEvery sort left its input in order: %v\n", allSorted)
+	fmt.Fprintf(w, "Insertion sort is skipped above %d elements where it's quadratic, and is fastest of all where it isn't\n", insertionMaxSize)
+	fmt.Fprintln(w, "Merge sort and heapsort take O(n log n) whatever the order, quicksort's Lomuto partition slows on few unique")
+	fmt.Fprintln(w, "values, sending every element equal to the pivot the same way, slices.Sort's pdqsort spots all these patterns")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo sort
+func Main(args []string) {
+	fs := bench.NewFlagSet("sort", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the sorting lesson measures a single run"
+	sizeList := fs.String("sizes", "1000,10000,100000", "comma separated numbers of elements to sort")
+	names := []string{}
+	for _, o := range orders {
+		names = append(names, o.name)
+	}
+	orderList := fs.String("orders", strings.Join(names, ","), "comma separated orders of input, each one of "+strings.Join(names, ", "))
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "sort measures a single run, -trials isn't supported")
+	sizes := []int{}
+	for _, field := range strings.Split(*sizeList, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(field))
+		v.Check(err == nil && size >= 1, "-sizes must be whole numbers of at least 1, got %q", field)
+		sizes = append(sizes, size)
+	}
+	selected := strings.Split(*orderList, ",")
+	for _, name := range selected {
+		v.OneOf("orders", name, names...)
+	}
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	result := RunResult{
+		Config: RunConfig{Sizes: sizes, Orders: selected, Seed: globals.Seed},
+		Env:    bench.CaptureEnv(),
+	}
+	rng := rand.New(rand.NewSource(globals.Seed))
+	ctx := context.Background()
+	for _, name := range selected {
+		o := orders[slices.IndexFunc(orders, func(o order) bool { return o.name == name })]
+		for _, size := range sizes {
+			slog.Info("sorting", "order", o.name, "size", size)
+			input := make([]int, size)
+			o.fill(rng, input)
+			result.Timings = append(result.Timings, measure(ctx, o, input)...)
+		}
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per algorithm, order and size, an op is one whole sort and ns/elem the time shared
+// out over the elements, which stays flat with size for an O(n) sort and creeps up for O(n log n)
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/sorting"); err != nil {
+		return err
+	}
+	benchmarks := []bench.Benchmark{}
+	for _, t := range result.Timings {
+		if t.Skipped {
+			continue
+		}
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name:    fmt.Sprintf("Sort/%s/order=%s/n=%d", t.Algorithm, t.Order, t.Size),
+			N:       1,
+			Metrics: []bench.Metric{{Value: t.Ns, Unit: "ns/op"}, {Value: t.Ns / float64(t.Size), Unit: "ns/elem"}},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
@@ -0,0 +1,63 @@
+package sorting
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz sort, it asks why each algorithm wins or loses on sorted, few-unique and random
+// input, and what generics save slices.Sort over sort.Slice
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why is insertion sort so fast on the sorted and nearly sorted inputs?",
+		Choices: []string{
+			"It checks whether the input is already sorted and returns before doing anything",
+			"It finds each element's place with a binary search, so every insert is O(log n)",
+			"Each element only shifts past the few bigger ones before it, O(n) plus those",
+			"It sorts runs of the input in parallel and merges them, and sorted input is one run",
+		},
+		Answer:      2,
+		Explanation: "its cost is the number of inversions, pairs in the wrong order, n^2/4 for random input and close to none for nearly sorted",
+	},
+	{
+		Prompt: "Why does the quicksort here pick the median of the first, middle and last elements as its pivot?",
+		Choices: []string{
+			"On sorted input a first element pivot splits one element off at a time, O(n^2)",
+			"The median of three is the true median of the slice, so every split is even",
+			"Picking the pivot from three places makes the sort stable on equal elements",
+			"It means the pivot is never compared with itself during the partition",
+		},
+		Answer:      0,
+		Explanation: "the median of three splits sorted input evenly, it is cheap and defeats the common patterns, though inputs built to beat it still exist",
+	},
+	{
+		Prompt: "Why does the quicksort slow down on the few-unique input?",
+		Choices: []string{
+			"Equal elements make the median of three pick the smallest value every time",
+			"It switches to heapsort once it sees duplicates, which is slower on that input",
+			"Lomuto's partition sends every element equal to the pivot to one side",
+			"The few-unique input is smaller, so the fixed costs take a larger share",
+		},
+		Answer:      2,
+		Explanation: "a run of equal values splits off one element at a time, a three way partition, less, equal and greater, puts all the equal elements in their final place at once, pdqsort does something similar",
+	},
+	{
+		Prompt: "Why does heapsort tend to trail merge sort even though both are O(n log n)?",
+		Choices: []string{
+			"Heapsort does about twice the comparisons of merge sort on every input",
+			"Merge sort sorts its halves on separate cores, heapsort has to run on one",
+			"Heapsort's worst case is O(n^2), and random input hits it some of the time",
+			"Its sift downs jump far along the slice and miss the cache, merging reads in order",
+		},
+		Answer:      3,
+		Explanation: "heapsort's strength is being in place with no O(n) buffer and no O(n^2) worst case, pdqsort falls back to it when quicksort goes badly",
+	},
+	{
+		Prompt: "Why is slices.Sort usually faster than sort.Slice on the same ints?",
+		Choices: []string{
+			"slices.Sort is a radix sort for integers, sort.Slice is a comparison sort",
+			"It's compiled for []int with the comparison inlined, sort.Slice calls a closure",
+			"sort.Slice copies the slice into an interface slice before sorting it",
+			"slices.Sort splits the work across goroutines once the slice is large",
+		},
+		Answer:      1,
+		Explanation: "sort.Slice calls a less closure and a reflection built swapper for every step, both are pattern-defeating quicksort, the difference is the indirection on every comparison and swap",
+	},
+}
@@ -0,0 +1,124 @@
+package sorting
+
+// insertionCutoff is the length below which the divide and conquer sorts hand over to insertion sort, on a handful
+// of elements its tight loop beats the bookkeeping of dividing any further
+const insertionCutoff = 12
+
+// InsertionSort grows a sorted prefix one element at a time, shifting each new element left past every bigger one,
+// O(n^2) comparisons in general but O(n) when the input is already nearly sorted, as each element barely moves
+func InsertionSort(s []int) {
+	for i := 1; i < len(s); i++ {
+		x := s[i]
+		j := i
+		for ; j > 0 && s[j-1] > x; j-- {
+			s[j] = s[j-1]
+		}
+		s[j] = x
+	}
+}
+
+// MergeSort sorts each half then merges them, always O(n log n) whatever the input, at the cost of a buffer as big
+// as the slice, and stable, equal elements keep their order
+func MergeSort(s []int) {
+	mergeSort(s, make([]int, len(s)))
+}
+
+func mergeSort(s, buf []int) {
+	if len(s) <= insertionCutoff {
+		InsertionSort(s)
+		return
+	}
+	mid := len(s) / 2
+	mergeSort(s[:mid], buf[:mid])
+	mergeSort(s[mid:], buf[mid:])
+	// the halves already in order need no merge, which makes sorted input O(n)
+	if s[mid-1] <= s[mid] {
+		return
+	}
+	copy(buf, s)
+	i, j := 0, mid
+	for k := range s {
+		if j == len(s) || (i < mid && buf[i] <= buf[j]) {
+			s[k] = buf[i]
+			i++
+		} else {
+			s[k] = buf[j]
+			j++
+		}
+	}
+}
+
+// QuickSort partitions around a pivot, smaller elements to its left and bigger to its right, then sorts each side in
+// place, O(n log n) on average and O(n^2) when the pivots keep splitting off a single element
+// The pivot is the median of the first, middle and last elements, a first element pivot would hit the worst case on
+// input that's already sorted, and recursing into the smaller side first keeps the stack O(log n) deep
+func QuickSort(s []int) {
+	for len(s) > insertionCutoff {
+		p := partition(s)
+		if p < len(s)-p {
+			QuickSort(s[:p])
+			s = s[p+1:]
+		} else {
+			QuickSort(s[p+1:])
+			s = s[:p]
+		}
+	}
+	InsertionSort(s)
+}
+
+// partition moves the median of three pivot to the end, sweeps everything smaller than it to the front, and puts it
+// back between the two, returning where it ended up, Lomuto's scheme
+func partition(s []int) int {
+	last, mid := len(s)-1, len(s)/2
+	// order the three so the median is at mid
+	if s[mid] < s[0] {
+		s[mid], s[0] = s[0], s[mid]
+	}
+	if s[last] < s[0] {
+		s[last], s[0] = s[0], s[last]
+	}
+	if s[last] < s[mid] {
+		s[last], s[mid] = s[mid], s[last]
+	}
+	s[mid], s[last] = s[last], s[mid]
+	pivot := s[last]
+	i := 0
+	for j := range last {
+		if s[j] < pivot {
+			s[i], s[j] = s[j], s[i]
+			i++
+		}
+	}
+	s[i], s[last] = s[last], s[i]
+	return i
+}
+
+// HeapSort arranges the slice as a max-heap, then repeatedly swaps the biggest element to the end and restores the
+// heap in what's left, O(n log n) in every case and in place, but its jumps around the slice are unkind to the cache
+func HeapSort(s []int) {
+	for i := len(s)/2 - 1; i >= 0; i-- {
+		siftDown(s, i)
+	}
+	for end := len(s) - 1; end > 0; end-- {
+		s[0], s[end] = s[end], s[0]
+		siftDown(s[:end], 0)
+	}
+}
+
+// siftDown swaps the element at i down past its bigger child until neither child is bigger
+func siftDown(s []int, i int) {
+	for {
+		largest, left, right := i, 2*i+1, 2*i+2
+		if left < len(s) && s[left] > s[largest] {
+			largest = left
+		}
+		if right < len(s) && s[right] > s[largest] {
+			largest = right
+		}
+		if largest == i {
+			return
+		}
+		s[i], s[largest] = s[largest], s[i]
+		i = largest
+	}
+}
@@ -0,0 +1,38 @@
+package sorting
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestSortsSortEveryOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, o := range orders {
+		for _, size := range []int{0, 1, 2, 13, 100, 1000} {
+			input := make([]int, size)
+			o.fill(rng, input)
+			want := slices.Sorted(slices.Values(input))
+			for _, a := range algorithms {
+				s := slices.Clone(input)
+				a.sort(s)
+				if !slices.Equal(s, want) {
+					t.Errorf("%s on %d %s elements didn't sort them", a.name, size, o.name)
+				}
+			}
+		}
+	}
+}
+
+func TestMeasureSkipsQuadraticInsertionSort(t *testing.T) {
+	input := make([]int, insertionMaxSize+1)
+	orders[0].fill(rand.New(rand.NewSource(2)), input)
+	for _, timing := range measure(t.Context(), orders[0], input) {
+		if timing.Skipped != (timing.Algorithm == "Insertion sort") {
+			t.Errorf("%s: Skipped = %v", timing.Algorithm, timing.Skipped)
+		}
+		if !timing.Skipped && !timing.Sorted {
+			t.Errorf("%s didn't sort the input", timing.Algorithm)
+		}
+	}
+}
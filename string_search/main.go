@@ -0,0 +1,260 @@
+// Package stringsearch is the string search lesson, teachgo strsearch, naive search, Knuth-Morris-Pratt, Rabin-Karp and
+// Boyer-Moore built from scratch and raced against strings.Index, on text and on inputs built to be their worst case
+package stringsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+	"github.com/joshdurbin/teaching-go/tries"
+)
+
+// Description is the lesson's help text, shown by teachgo strsearch -h, its first line is the summary teachgo help
+// lists
+const Description = `String search, naive, KMP, Rabin-Karp and Boyer-Moore against strings.Index
+
+Implements four ways of finding every place a pattern occurs in a text, trying the pattern at every position,
+Knuth-Morris-Pratt never reading a byte of the text twice, Rabin-Karp comparing rolling hashes of the windows, and
+Boyer-Moore comparing right to left and skipping ahead on a mismatch, and times them alongside strings.Index over
+generated prose, a four letter DNA alphabet, and two texts built to be worst cases, a pattern that almost matches
+everywhere and one that matches everywhere. Counts the byte comparisons each makes as well as timing it, showing
+the naive search going quadratic on near misses, Boyer-Moore looking at a fraction of the text until its alphabet
+shrinks or everything matches, and KMP's steady two comparisons a byte at worst whatever the input.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "strsearch",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"string search", "rolling hashes", "preprocessing", "worst case inputs"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// input is a text and pattern to search, make builds them from the text's size and the pattern's length
+type input struct {
+	name  string
+	about string
+	make  func(rng *rand.Rand, size, length int) (text, pattern string)
+}
+
+var inputs = []input{
+	{"prose", "generated words, the pattern taken from the text", func(rng *rand.Rand, size, length int) (string, string) {
+		words := tries.GenerateWords(rng, 2000)
+		var b strings.Builder
+		for b.Len() < size {
+			b.WriteString(words[rng.Intn(len(words))])
+			b.WriteByte(' ')
+		}
+		return taken(rng, b.String()[:size], length)
+	}},
+	{"dna", "random bases from acgt, the pattern taken from the text", func(rng *rand.Rand, size, length int) (string, string) {
+		text := make([]byte, size)
+		for i := range text {
+			text[i] = "acgt"[rng.Intn(4)]
+		}
+		return taken(rng, string(text), length)
+	}},
+	// near miss matches all but the pattern's last byte at every position
+	{"near-miss", "a text of a, a pattern of a ending in b", func(rng *rand.Rand, size, length int) (string, string) {
+		return strings.Repeat("a", size), strings.Repeat("a", length-1) + "b"
+	}},
+	{"all-match", "a text of a, a pattern of a, matching at every position", func(rng *rand.Rand, size, length int) (string, string) {
+		return strings.Repeat("a", size), strings.Repeat("a", length)
+	}},
+}
+
+// taken returns the text and a pattern of length bytes from a random place in it
+func taken(rng *rand.Rand, text string, length int) (string, string) {
+	start := rng.Intn(len(text) - length + 1)
+	return text, text[start : start+length]
+}
+
+// algorithm is a search under test, strings.Index doesn't count its comparisons and gives -1
+type algorithm struct {
+	name   string
+	search func(text, pattern string) ([]int, int)
+}
+
+var algorithms = []algorithm{
+	{"Naive", Naive},
+	{"KMP", KMP},
+	{"Rabin-Karp", RabinKarp},
+	{"Boyer-Moore", BoyerMoore},
+	{"strings.Index", indexAll},
+}
+
+// indexAll finds every match with strings.Index, starting it again a byte past each match so overlapping matches are
+// found too
+func indexAll(text, pattern string) ([]int, int) {
+	var matches []int
+	for offset := 0; offset <= len(text); {
+		i := strings.Index(text[offset:], pattern)
+		if i < 0 {
+			break
+		}
+		matches = append(matches, offset+i)
+		offset += i + 1
+	}
+	return matches, -1
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Size   int   `json:"size"`
+	Length int   `json:"length"`
+	Seed   int64 `json:"seed"`
+}
+
+// Search is one algorithm's search of one input, Comparisons is -1 for strings.Index, Agree whether it found the
+// same matches as strings.Index
+type Search struct {
+	Algorithm   string  `json:"algorithm"`
+	Input       string  `json:"input"`
+	Ns          float64 `json:"ns"`
+	Comparisons int     `json:"comparisons"`
+	Matches     int     `json:"matches"`
+	Agree       bool    `json:"agree"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config   RunConfig `json:"config"`
+	Env      bench.Env `json:"env"`
+	Searches []Search  `json:"searches"`
+}
+
+// measure searches the text with every algorithm, checking each against strings.Index
+func measure(ctx context.Context, name, text, pattern string) []Search {
+	want, _ := indexAll(text, pattern)
+	searches := []Search{}
+	for _, a := range algorithms {
+		var matches []int
+		var comparisons int
+		elapsed := bench.Phase(ctx, a.name+" "+name, func() { matches, comparisons = a.search(text, pattern) })
+		searches = append(searches, Search{
+			Algorithm:   a.name,
+			Input:       name,
+			Ns:          float64(elapsed.Nanoseconds()),
+			Comparisons: comparisons,
+			Matches:     len(matches),
+			Agree:       slices.Equal(matches, want),
+		})
+	}
+	return searches
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "String Search")
+	fmt.Fprintln(w, "=============")
+	fmt.Fprintf(w, "Text: %d bytes\nPattern: %d bytes\n", config.Size, config.Length)
+	fmt.Fprintf(w, "Machine: %s\n", result.Env)
+
+	allAgree := true
+	for _, in := range inputs {
+		fmt.Fprintf(w, "\n=====%s, %s=====\n", in.name, in.about)
+		fmt.Fprintf(w, "%-14s %14s %12s %14s %10s\n", "Algorithm", "Time", "Bytes/s", "Compared/byte", "Matches")
+		for _, s := range result.Searches {
+			if s.Input != in.name {
+				continue
+			}
+			compared := "-"
+			if s.Comparisons >= 0 {
+				compared = fmt.Sprintf("%.3f", float64(s.Comparisons)/float64(config.Size))
+			}
+			rate := "-"
+			if s.Ns > 0 {
+				rate = bench.FormatRate(float64(config.Size) / (s.Ns / 1e9))
+			}
+			allAgree = allAgree && s.Agree
+			fmt.Fprintf(w, "%-14s %14v %12s %14s %10d\n", s.Algorithm, time.Duration(s.Ns), rate, compared, s.Matches)
+		}
+	}
+	fmt.Fprintf(w, "\nEvery search found the same matches as strings.Index: %v\n", allAgree)
+	fmt.Fprintln(w, "Compared/byte is the bytes of the pattern compared against each byte of the text, the naive search")
+	fmt.Fprintln(w, "compares nearly the whole pattern at every position of a near miss, KMP never more than two a byte,")
+	fmt.Fprintln(w, "Boyer-Moore skips most of the prose, less of the DNA's four letters, none of a text that matches")
+	fmt.Fprintln(w, "everywhere, and Rabin-Karp compares only where a window's hash agrees, every window when the pattern")
+	fmt.Fprintln(w, "matches everywhere, strings.Index scans for the pattern's first bytes with vector instructions")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo strsearch
+func Main(args []string) {
+	fs := bench.NewFlagSet("strsearch", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the string search lesson measures a single run"
+	size := fs.Int("size", 2<<20, "the bytes of text searched")
+	length := fs.Int("length", 32, "the bytes of the pattern searched for")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "strsearch measures a single run, -trials isn't supported")
+	v.AtLeast("length", *length, 1)
+	v.Check(*size >= *length, "-size must be at least -length, got %d and %d", *size, *length)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	result := RunResult{
+		Config: RunConfig{Size: *size, Length: *length, Seed: globals.Seed},
+		Env:    bench.CaptureEnv(),
+	}
+	rng := rand.New(rand.NewSource(globals.Seed))
+	ctx := context.Background()
+	for _, in := range inputs {
+		slog.Info("searching", "input", in.name, "size", *size, "length", *length)
+		text, pattern := in.make(rng, *size, *length)
+		result.Searches = append(result.Searches, measure(ctx, in.name, text, pattern)...)
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per algorithm and input, an op is one search of the whole text, comparisons/byte is
+// left out for strings.Index, which doesn't count them
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/string_search"); err != nil {
+		return err
+	}
+	config := result.Config
+	benchmarks := []bench.Benchmark{}
+	for _, s := range result.Searches {
+		metrics := []bench.Metric{{Value: s.Ns, Unit: "ns/op"}}
+		if s.Comparisons >= 0 {
+			metrics = append(metrics, bench.Metric{Value: float64(s.Comparisons) / float64(config.Size), Unit: "comparisons/byte"})
+		}
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name:    fmt.Sprintf("Search/%s/input=%s/size=%d/length=%d", s.Algorithm, s.Input, config.Size, config.Length),
+			N:       1,
+			Metrics: metrics,
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
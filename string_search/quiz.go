@@ -0,0 +1,63 @@
+package stringsearch
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz strsearch, it asks which inputs push the naive search, KMP, Boyer-Moore and
+// Rabin-Karp to their best and worst comparisons a byte, and why
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does the naive search make about as many comparisons a byte as the pattern is long on the near miss input?",
+		Choices: []string{
+			"Each position matches all but the last byte, so it compares the whole pattern, then moves one",
+			"It compares the pattern from right to left, so it always reaches the mismatch last",
+			"It hashes every window of the text before comparing, and a hash costs a pass over the pattern",
+			"It reads the text twice, once to find candidates and once to check them",
+		},
+		Answer:      0,
+		Explanation: "that's O(n*m), on prose the first or second byte usually differs and the naive search is close to one comparison a byte",
+	},
+	{
+		Prompt: "How does KMP keep to at most two comparisons a byte on every input?",
+		Choices: []string{
+			"It skips any byte that doesn't appear in the pattern, so most bytes are never compared",
+			"It compares a machine word of the text at a time against the pattern",
+			"It never moves back in the text, on a mismatch it falls back to the matched part's border",
+			"It compares only the pattern's first and last bytes before accepting a match",
+		},
+		Answer:      2,
+		Explanation: "the text is known to end with the longest border of what matched, every comparison either moves on a byte or falls back, and it can't fall back further than it's moved, so comparisons are at most twice the text",
+	},
+	{
+		Prompt: "Why does Boyer-Moore compare far less than one byte per byte of the prose, but more on the DNA?",
+		Choices: []string{
+			"The DNA text is longer, so the same number of shifts covers less of it",
+			"A byte not in the pattern lets it shift past it, and with four letters every byte is in it",
+			"It falls back to the naive search whenever the alphabet is smaller than the pattern",
+			"It hashes prose in words but has to hash DNA a byte at a time",
+		},
+		Answer:      1,
+		Explanation: "the bad character rule skips by where the mismatched byte last occurs in the pattern, and a small alphabet puts every byte near the pattern's end, so the shifts are short",
+	},
+	{
+		Prompt: "Why does Rabin-Karp make no comparisons at all on the near miss input?",
+		Choices: []string{
+			"It only counts comparisons made after a full match, and there are none",
+			"It stops at the first window whose hash differs, which is the first one",
+			"It skips ahead by the pattern's length whenever a window's hash differs",
+			"It only compares when the hashes agree, and no window of a's hashes like the pattern",
+		},
+		Answer:      3,
+		Explanation: "no window of a's hashes the same as a pattern ending in b, when the pattern matches everywhere every hash agrees and it verifies every window in full, O(n*m) like the naive search",
+	},
+	{
+		Prompt: "Why is Boyer-Moore as slow as the naive search on the all match input?",
+		Choices: []string{
+			"Its bad character table is built wrongly when the pattern repeats one letter",
+			"It switches to scanning left to right once it has found the first match",
+			"Every position matches, so it compares the whole pattern at each and shifts by one",
+			"It rehashes the pattern at every position when the text is all one letter",
+		},
+		Answer:      2,
+		Explanation: "it shifts by the pattern's period of one with nothing remembered of what already matched, Galil's rule fixes this by not comparing again the part of the pattern the last match already covered",
+	},
+}
@@ -0,0 +1,205 @@
+package stringsearch
+
+// Every search finds all the places pattern occurs in text, overlapping ones included, and counts the comparisons
+// it made, a byte of text against a byte of the pattern, the work its preprocessing of the pattern does isn't counted
+// An empty pattern occurs at every index from 0 to len(text), as strings.Index has it
+
+// everyIndex is where an empty pattern occurs
+func everyIndex(text string) []int {
+	matches := make([]int, len(text)+1)
+	for i := range matches {
+		matches[i] = i
+	}
+	return matches
+}
+
+// Naive tries the pattern at every position of the text, comparing left to right until a byte differs
+// A text and pattern that agree on all but their last byte make it compare the whole pattern at every position,
+// O(n*m)
+func Naive(text, pattern string) (matches []int, comparisons int) {
+	if pattern == "" {
+		return everyIndex(text), 0
+	}
+	for s := 0; s+len(pattern) <= len(text); s++ {
+		j := 0
+		for j < len(pattern) {
+			comparisons++
+			if text[s+j] != pattern[j] {
+				break
+			}
+			j++
+		}
+		if j == len(pattern) {
+			matches = append(matches, s)
+		}
+	}
+	return matches, comparisons
+}
+
+// borders returns, for each prefix of the pattern, the length of its longest border, a proper prefix that's also a
+// suffix, border[i] is for pattern[:i+1]
+func borders(pattern string) []int {
+	border := make([]int, len(pattern))
+	k := 0
+	for i := 1; i < len(pattern); i++ {
+		for k > 0 && pattern[i] != pattern[k] {
+			k = border[k-1]
+		}
+		if pattern[i] == pattern[k] {
+			k++
+		}
+		border[i] = k
+	}
+	return border
+}
+
+// KMP is Knuth-Morris-Pratt, it reads the text once, never moving back, and keeps how much of the pattern the text
+// read so far ends with
+// On a mismatch it falls back to the longest border of what matched, which the text still ends with, so each byte of
+// text is compared once plus once for every fall back, and the fall backs can't outnumber the bytes, at most 2n
+func KMP(text, pattern string) (matches []int, comparisons int) {
+	if pattern == "" {
+		return everyIndex(text), 0
+	}
+	border := borders(pattern)
+	j := 0
+	for i := 0; i < len(text); i++ {
+		for {
+			comparisons++
+			if text[i] == pattern[j] {
+				j++
+				break
+			}
+			if j == 0 {
+				break
+			}
+			j = border[j-1]
+		}
+		if j == len(pattern) {
+			matches = append(matches, i-len(pattern)+1)
+			j = border[j-1]
+		}
+	}
+	return matches, comparisons
+}
+
+// primeRK is the base of the rolling hash, the same one the strings package uses
+const primeRK = 16777619
+
+// RabinKarp compares a hash of each window of the text against the pattern's hash, only comparing the bytes when the
+// hashes agree
+// The hash is a polynomial in primeRK wrapping at 32 bits, so moving the window one byte along takes out the byte
+// leaving and adds the one arriving in constant time, where a match or a collision is verified byte by byte, a text
+// where every window matches is O(n*m) again
+func RabinKarp(text, pattern string) (matches []int, comparisons int) {
+	if pattern == "" {
+		return everyIndex(text), 0
+	}
+	m := len(pattern)
+	if m > len(text) {
+		return nil, 0
+	}
+	// pow is primeRK^m, the weight of the byte leaving the window once the window has been multiplied along
+	var patternHash, windowHash, pow uint32 = 0, 0, 1
+	for i := range m {
+		patternHash = patternHash*primeRK + uint32(pattern[i])
+		windowHash = windowHash*primeRK + uint32(text[i])
+		pow *= primeRK
+	}
+	for s := 0; ; s++ {
+		if windowHash == patternHash {
+			j := 0
+			for j < m {
+				comparisons++
+				if text[s+j] != pattern[j] {
+					break
+				}
+				j++
+			}
+			if j == m {
+				matches = append(matches, s)
+			}
+		}
+		if s+m == len(text) {
+			return matches, comparisons
+		}
+		windowHash = windowHash*primeRK + uint32(text[s+m]) - pow*uint32(text[s])
+	}
+}
+
+// BoyerMoore compares the pattern right to left, and on a mismatch shifts it as far as two rules allow
+// The bad character rule lines the mismatched text byte up with its last place in the pattern, or moves the pattern
+// past it if it's not in the pattern at all, so on a big alphabet most shifts are nearly the whole pattern and most
+// of the text is never looked at, the good suffix rule lines what matched up with its next place in the pattern
+// After a match the pattern shifts by its period, without Galil's rule to remember what's already matched a pattern
+// that occurs everywhere is compared in full at every position, O(n*m)
+func BoyerMoore(text, pattern string) (matches []int, comparisons int) {
+	if pattern == "" {
+		return everyIndex(text), 0
+	}
+	m := len(pattern)
+	last := m - 1
+	// badChar is how far the text index moves to line its byte up with the byte's last place in the pattern, before
+	// the pattern's last byte
+	var badChar [256]int
+	for i := range badChar {
+		badChar[i] = m
+	}
+	for i := range last {
+		badChar[pattern[i]] = last - i
+	}
+	goodSuffix := goodSuffixes(pattern)
+	period := m - borders(pattern)[last]
+
+	// i is the text index being compared, j the pattern index it's compared against
+	for i := last; i < len(text); {
+		j := last
+		for j >= 0 {
+			comparisons++
+			if text[i] != pattern[j] {
+				break
+			}
+			i--
+			j--
+		}
+		if j < 0 {
+			matches = append(matches, i+1)
+			i += 1 + last + period
+			continue
+		}
+		i += max(badChar[text[i]], goodSuffix[j])
+	}
+	return matches, comparisons
+}
+
+// goodSuffixes returns, for a mismatch at each index of the pattern, how far the text index moves so the suffix that
+// matched lines up with its next place in the pattern, or the longest prefix of the pattern that's a suffix of it
+func goodSuffixes(pattern string) []int {
+	last := len(pattern) - 1
+	skip := make([]int, len(pattern))
+	// where the suffix doesn't occur again, shift to the longest prefix that's also a suffix of what matched
+	lastPrefix := last
+	for i := last; i >= 0; i-- {
+		if pattern[:len(pattern)-(i+1)] == pattern[i+1:] {
+			lastPrefix = i + 1
+		}
+		skip[i] = lastPrefix + last - i
+	}
+	// where it does occur again, preceded by a different byte, shift to that place
+	for i := range last {
+		suffix := commonSuffix(pattern, pattern[1:i+1])
+		if pattern[i-suffix] != pattern[last-suffix] {
+			skip[last-suffix] = suffix + last - i
+		}
+	}
+	return skip
+}
+
+// commonSuffix is how many bytes a and b end with in common
+func commonSuffix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
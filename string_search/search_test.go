@@ -0,0 +1,66 @@
+package stringsearch
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var searches = []algorithm{{"Naive", Naive}, {"KMP", KMP}, {"Rabin-Karp", RabinKarp}, {"Boyer-Moore", BoyerMoore}}
+
+func TestSearchesFindEveryMatch(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func(n int, alphabet string) string {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return string(b)
+	}
+	cases := [][2]string{{"", ""}, {"abc", ""}, {"", "a"}, {"ab", "abc"}, {"aaaaa", "aa"}, {"abababab", "abab"}, {"xabcabcabx", "abcab"}}
+	for range 500 {
+		alphabet := "abcd"[:1+rng.Intn(4)]
+		cases = append(cases, [2]string{random(rng.Intn(60), alphabet), random(1+rng.Intn(6), alphabet)})
+	}
+	for _, c := range cases {
+		text, pattern := c[0], c[1]
+		want, _ := indexAll(text, pattern)
+		for _, s := range searches {
+			if got, _ := s.search(text, pattern); !slices.Equal(got, want) {
+				t.Fatalf("%s(%q, %q) = %v, want %v", s.name, text, pattern, got, want)
+			}
+		}
+	}
+}
+
+func TestComparisonsOnWorstCases(t *testing.T) {
+	n, m := 1000, 10
+	nearMiss := strings.Repeat("a", m-1) + "b"
+	text := strings.Repeat("a", n)
+	if _, got := Naive(text, nearMiss); got != (n-m+1)*m {
+		t.Errorf("Naive made %d comparisons on a near miss, want %d", got, (n-m+1)*m)
+	}
+	if _, got := KMP(text, nearMiss); got > 2*n {
+		t.Errorf("KMP made %d comparisons on a near miss, more than 2n = %d", got, 2*n)
+	}
+	if _, got := BoyerMoore(text, nearMiss); got != n-m+1 {
+		t.Errorf("Boyer-Moore made %d comparisons on a near miss, want one a position, %d", got, n-m+1)
+	}
+	if _, got := RabinKarp(text, nearMiss); got != 0 {
+		t.Errorf("Rabin-Karp made %d comparisons on a near miss, want none, no window's hash agrees", got)
+	}
+	allMatch := strings.Repeat("a", m)
+	for _, s := range []algorithm{{"Rabin-Karp", RabinKarp}, {"Boyer-Moore", BoyerMoore}} {
+		if _, got := s.search(text, allMatch); got != (n-m+1)*m {
+			t.Errorf("%s made %d comparisons when every position matches, want %d", s.name, got, (n-m+1)*m)
+		}
+	}
+}
+
+func TestBoyerMooreSkipsOnABigAlphabet(t *testing.T) {
+	text := strings.Repeat("the quick brown fox jumps over the lazy dog ", 100)
+	if _, got := BoyerMoore(text, "lazy cat"); got >= len(text)/2 {
+		t.Errorf("Boyer-Moore made %d comparisons over %d bytes, want well under one a byte", got, len(text))
+	}
+}
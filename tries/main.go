@@ -0,0 +1,283 @@
+// Package tries is the trie lesson, teachgo trie, a prefix tree built from scratch for autocomplete, timed against
+// binary searches of a sorted slice, with an interactive autocomplete to try it on
+package tries
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo trie -h, its first line is the summary teachgo help lists
+const Description = `Tries and autocomplete, a prefix tree against binary search of a sorted slice
+
+Builds a trie, a tree whose paths from the root spell out words so words with a common prefix share its nodes,
+from a word list or generated words, and a sorted slice of the same words. Times building each, looking up whole
+words and completing prefixes, the trie walking down the prefix then over the words below it, the slice binary
+searching for the range of words that start with it. With -interactive, completes the prefixes you type.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "trie",
+		Description: Description,
+		Difficulty:  lesson.Beginner,
+		Topics:      []string{"tries", "prefix search", "binary search", "trees"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	// Words is the word list read, empty when the words were generated
+	Words    string `json:"words,omitempty"`
+	Count    int    `json:"count"`
+	Queries  int    `json:"queries"`
+	Limit    int    `json:"limit"`
+	Seed     int64  `json:"seed"`
+	Distinct int    `json:"distinct"`
+}
+
+// IndexResult is what a run measured of one index of the words, the times are per word, per lookup and per prefix
+type IndexResult struct {
+	Name        string  `json:"name"`
+	BuildNs     float64 `json:"build_ns"`
+	LookupNs    float64 `json:"lookup_ns"`
+	CompleteNs  float64 `json:"complete_ns"`
+	Found       int     `json:"found"`
+	Completions int     `json:"completions"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config  RunConfig     `json:"config"`
+	Env     bench.Env     `json:"env"`
+	Nodes   int           `json:"nodes"`
+	Bytes   int           `json:"bytes"`
+	Indexes []IndexResult `json:"indexes"`
+	// Agree is whether the trie and the slice completed every prefix with the same words
+	Agree bool `json:"agree"`
+}
+
+// index is what the timing needs of the trie and the sorted slice
+type index interface {
+	Contains(word string) bool
+	Complete(prefix string, limit int) []string
+}
+
+// prefixes picks a prefix of a random word for each query, from its first letter to half of it, so some prefixes
+// match thousands of words and some only a few
+func prefixes(rng *rand.Rand, words []string, n int) []string {
+	queries := make([]string, n)
+	for i := range queries {
+		word := words[rng.Intn(len(words))]
+		queries[i] = word[:1+rng.Intn(max(len(word)/2, 1))]
+	}
+	return queries
+}
+
+// measure times looking up every word and completing every prefix, returning the completions so the indexes can be
+// compared
+func measure(ctx context.Context, name string, idx index, words, queries []string, limit int) (IndexResult, [][]string) {
+	result := IndexResult{Name: name}
+	lookup := bench.Phase(ctx, "lookup "+name, func() {
+		for _, word := range words {
+			if idx.Contains(word) {
+				result.Found++
+			}
+		}
+	})
+	completions := make([][]string, len(queries))
+	complete := bench.Phase(ctx, "complete "+name, func() {
+		for i, prefix := range queries {
+			completions[i] = idx.Complete(prefix, limit)
+			result.Completions += len(completions[i])
+		}
+	})
+	result.LookupNs = perOp(lookup, len(words))
+	result.CompleteNs = perOp(complete, len(queries))
+	return result, completions
+}
+
+func perOp(d time.Duration, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(d.Nanoseconds()) / float64(n)
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Trie Against Sorted Slice")
+	fmt.Fprintln(w, "=========================")
+	words := "generated"
+	if config.Words != "" {
+		words = config.Words
+	}
+	fmt.Fprintf(w, "Words: %d, %s\nPrefixes: %d\nCompletions per prefix: up to %d\n", config.Distinct, words, config.Queries, config.Limit)
+	fmt.Fprintf(w, "Machine: %s\n\n", result.Env)
+
+	fmt.Fprintf(w, "%-14s %12s %12s %12s %8s %12s\n", "Index", "Build/word", "Lookup/op", "Complete/op", "Found", "Completions")
+	for _, idx := range result.Indexes {
+		fmt.Fprintf(w, "%-14s %12v %12v %12v %8d %12d\n", idx.Name,
+			time.Duration(idx.BuildNs), time.Duration(idx.LookupNs), time.Duration(idx.CompleteNs), idx.Found, idx.Completions)
+	}
+	fmt.Fprintf(w, "\nBoth completed every prefix with the same words: %v\n", result.Agree)
+	fmt.Fprintf(w, "The trie has %d nodes for %d bytes of words, %.2f per byte, the words sharing a prefix share its nodes\n",
+		result.Nodes, result.Bytes, float64(result.Nodes)/float64(max(result.Bytes, 1)))
+	fmt.Fprintln(w, "A trie lookup costs one step per letter whatever the number of words, binary search one string compare per")
+	fmt.Fprintln(w, "halving of the words, but the slice's words sit together in memory while the trie chases a pointer per letter")
+}
+
+// autocomplete reads prefixes a line at a time and writes the completions of each, with how long each index took
+func autocomplete(r io.Reader, w io.Writer, trie *Trie, sorted SortedWords, limit int) error {
+	input := bufio.NewScanner(r)
+	fmt.Fprintf(w, "Type the start of a word to complete it, from %d words, an empty line or end of input to stop\n", trie.Len())
+	for {
+		fmt.Fprint(w, "> ")
+		if !input.Scan() {
+			fmt.Fprintln(w)
+			return input.Err()
+		}
+		prefix := strings.TrimSpace(input.Text())
+		if prefix == "" {
+			return nil
+		}
+		start := time.Now()
+		words := trie.Complete(prefix, limit)
+		trieTime := time.Since(start)
+		start = time.Now()
+		sorted.Complete(prefix, limit)
+		sliceTime := time.Since(start)
+
+		if len(words) == 0 {
+			fmt.Fprintf(w, "no words start with %q\n", prefix)
+		} else {
+			fmt.Fprintln(w, strings.Join(words, " "))
+		}
+		lo, hi := sorted.Range(prefix)
+		fmt.Fprintf(w, "%d words start with %q, trie %v, sorted slice %v\n", hi-lo, prefix, trieTime, sliceTime)
+	}
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo trie
+func Main(args []string) {
+	fs := bench.NewFlagSet("trie", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the trie lesson measures a single run"
+	wordList := fs.String("words", "", "a word list to index, one word per line, such as /usr/share/dict/words, generated words if empty")
+	count := fs.Int("generate", 200000, "the number of words to generate when -words isn't given")
+	numQueries := fs.Int("queries", 100000, "the number of prefixes to complete")
+	limit := fs.Int("limit", 10, "the most completions to return for a prefix, 0 for every word starting with it")
+	interactive := fs.Bool("interactive", false, "complete the prefixes typed on standard input instead of measuring")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "trie measures a single run, -trials isn't supported")
+	v.AtLeast("generate", *count, 1)
+	v.AtLeast("queries", *numQueries, 1)
+	v.AtLeast("limit", *limit, 0)
+	v.Check(!*interactive || globals.Format == "text", "-interactive writes completions as text, -format %s isn't supported with it", globals.Format)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	rng := rand.New(rand.NewSource(globals.Seed))
+	var words []string
+	if *wordList != "" {
+		var err error
+		if words, err = ReadWords(*wordList); err != nil {
+			slog.Error("failed to read the word list", "err", err)
+			os.Exit(1)
+		}
+	} else {
+		slog.Info("generating words", "count", *count)
+		words = GenerateWords(rng, *count)
+	}
+
+	ctx := context.Background()
+	trie := NewTrie()
+	trieBuild := bench.Phase(ctx, "build trie", func() {
+		for _, word := range words {
+			trie.Insert(word)
+		}
+	})
+	var sorted SortedWords
+	sliceBuild := bench.Phase(ctx, "build sorted slice", func() { sorted = NewSortedWords(words) })
+
+	if *interactive {
+		if err := autocomplete(os.Stdin, os.Stdout, trie, sorted, *limit); err != nil {
+			slog.Error("failed to read prefixes", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// the lookups go through the distinct words in random order, so neither index benefits from them arriving sorted
+	distinct := slices.Clone([]string(sorted))
+	rng.Shuffle(len(distinct), func(i, j int) { distinct[i], distinct[j] = distinct[j], distinct[i] })
+	queries := prefixes(rng, distinct, *numQueries)
+
+	result := RunResult{
+		Config: RunConfig{Words: *wordList, Count: len(words), Queries: *numQueries, Limit: *limit, Seed: globals.Seed, Distinct: len(sorted)},
+		Env:    bench.CaptureEnv(),
+		Nodes:  trie.Nodes(),
+	}
+	for _, word := range sorted {
+		result.Bytes += len(word)
+	}
+	slog.Info("measuring", "index", "trie")
+	trieResult, trieCompletions := measure(ctx, "Trie", trie, distinct, queries, *limit)
+	slog.Info("measuring", "index", "sorted slice")
+	sliceResult, sliceCompletions := measure(ctx, "Sorted slice", sorted, distinct, queries, *limit)
+	trieResult.BuildNs = perOp(trieBuild, len(words))
+	sliceResult.BuildNs = perOp(sliceBuild, len(words))
+	result.Indexes = []IndexResult{trieResult, sliceResult}
+	result.Agree = slices.EqualFunc(trieCompletions, sliceCompletions, slices.Equal)
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a build, a lookup and a complete line per index
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/tries"); err != nil {
+		return err
+	}
+	config := result.Config
+	words := fmt.Sprintf("words=%d", config.Distinct)
+	benchmarks := []bench.Benchmark{}
+	for _, idx := range result.Indexes {
+		benchmarks = append(benchmarks,
+			bench.Benchmark{Name: "Build/" + idx.Name + "/" + words, N: int64(config.Count), Metrics: []bench.Metric{{Value: idx.BuildNs, Unit: "ns/op"}}},
+			bench.Benchmark{Name: "Lookup/" + idx.Name + "/" + words, N: int64(config.Distinct), Metrics: []bench.Metric{{Value: idx.LookupNs, Unit: "ns/op"}}},
+			bench.Benchmark{Name: fmt.Sprintf("Complete/%s/%s/limit=%d", idx.Name, words, config.Limit), N: int64(config.Queries), Metrics: []bench.Metric{{Value: idx.CompleteNs, Unit: "ns/op"}}},
+		)
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
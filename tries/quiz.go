@@ -0,0 +1,63 @@
+package tries
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz trie, it sets the trie against a sorted slice on prefix search, asks where the trie's
+// nodes and ordering come from, and why the slice keeps up on lookups anyway
+var quiz = []lesson.Question{
+	{
+		Prompt: "How long does it take a trie to find the node for a prefix?",
+		Choices: []string{
+			"O(log n) in the number of words",
+			"Constant time, it hashes the prefix",
+			"O(n), it checks every word",
+			"One step per byte of the prefix",
+		},
+		Answer:      3,
+		Explanation: "however many words the trie holds, the prefix's bytes spell the path down from the root, the words that aren't on it are never looked at",
+	},
+	{
+		Prompt: "How does a sorted slice find every word starting with a prefix?",
+		Choices: []string{
+			"The matches are one contiguous run, two binary searches find its start and end",
+			"It scans from the start until it reaches the first word past the prefix",
+			"It keeps an index from each first letter to where its words begin",
+			"It can't on its own, it needs a trie built over it for prefixes",
+		},
+		Answer:      0,
+		Explanation: "every word starting with the prefix sorts at or after the prefix itself and before the first later word that doesn't start with it",
+	},
+	{
+		Prompt: "Why does the trie have fewer nodes than the words have bytes?",
+		Choices: []string{
+			"It compresses runs of single children into one node holding several bytes",
+			"Words with a common prefix share the nodes that spell it",
+			"It only stores each distinct byte once, however many words use it",
+			"The last byte of each word is kept as a flag rather than a node",
+		},
+		Answer:      1,
+		Explanation: "only the bytes after the shared part need new nodes, the more the words share prefixes the bigger the saving in nodes, though each node, with its child slice and pointers, is much bigger than a byte",
+	},
+	{
+		Prompt: "Why does each node keep its children sorted by byte?",
+		Choices: []string{
+			"A sorted slice takes less memory than the same children unsorted",
+			"It stops the same word being added twice under one node",
+			"Walking them in order gives the words in order, and a binary search finds one",
+			"Sorting lets the trie share child slices between nodes",
+		},
+		Answer:      2,
+		Explanation: "a map of children would find one in O(1) but visit them in random order, completions would then need sorting",
+	},
+	{
+		Prompt: "Why can the sorted slice keep up with the trie on lookups despite its O(log n) string compares?",
+		Choices: []string{
+			"The slice keeps the most recent lookups and answers repeats without a search",
+			"Its strings sit together and compares fail early, the trie chases a pointer per byte",
+			"Binary search over strings is O(1) once the slice is sorted",
+			"The trie has to lock every node it passes, the slice is read without locks",
+		},
+		Answer:      1,
+		Explanation: "asymptotics count steps, not what each costs, pointer chasing misses the CPU cache in a way scanning an array doesn't",
+	},
+}
@@ -0,0 +1,153 @@
+package tries
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// node is a trie node, the path of bytes from the root to it spells a prefix, and word marks the prefixes that are
+// whole words
+// children is kept sorted by byte so walking it in order visits the words below in lexicographic order, a 256 entry
+// array would find a child in one step but most nodes have one or two children and the array would waste the rest
+type node struct {
+	b        byte
+	word     bool
+	children []*node
+}
+
+// child returns the child for byte b, or nil
+func (n *node) child(b byte) *node {
+	i, found := slices.BinarySearchFunc(n.children, b, func(c *node, b byte) int { return int(c.b) - int(b) })
+	if !found {
+		return nil
+	}
+	return n.children[i]
+}
+
+// Trie is a prefix tree of words, the words sharing a prefix share the nodes that spell it, so finding every word
+// that starts with a prefix is a walk down the prefix's nodes and then over the subtree below, however many words
+// the trie holds
+type Trie struct {
+	root  node
+	words int
+	nodes int
+}
+
+// NewTrie creates an empty trie
+func NewTrie() *Trie {
+	return &Trie{}
+}
+
+// Insert adds a word, reporting whether it was new
+func (t *Trie) Insert(word string) bool {
+	n := &t.root
+	for i := 0; i < len(word); i++ {
+		b := word[i]
+		j, found := slices.BinarySearchFunc(n.children, b, func(c *node, b byte) int { return int(c.b) - int(b) })
+		if !found {
+			n.children = slices.Insert(n.children, j, &node{b: b})
+			t.nodes++
+		}
+		n = n.children[j]
+	}
+	if n.word {
+		return false
+	}
+	n.word = true
+	t.words++
+	return true
+}
+
+// find walks down the nodes spelling s, returning the last one or nil if the trie holds no word starting with s
+func (t *Trie) find(s string) *node {
+	n := &t.root
+	for i := 0; i < len(s) && n != nil; i++ {
+		n = n.child(s[i])
+	}
+	return n
+}
+
+// Contains reports whether the word was inserted, a prefix of a word isn't enough
+func (t *Trie) Contains(word string) bool {
+	n := t.find(word)
+	return n != nil && n.word
+}
+
+// Complete returns up to limit of the words starting with prefix, in lexicographic order, a limit of 0 or less
+// returns them all
+func (t *Trie) Complete(prefix string, limit int) []string {
+	n := t.find(prefix)
+	if n == nil {
+		return nil
+	}
+	words := []string{}
+	// buf holds the path from the root to the node being visited, each word is copied out of it as it's found
+	buf := []byte(prefix)
+	var walk func(n *node) bool
+	walk = func(n *node) bool {
+		if n.word {
+			words = append(words, string(buf))
+			if limit > 0 && len(words) == limit {
+				return false
+			}
+		}
+		for _, c := range n.children {
+			buf = append(buf, c.b)
+			more := walk(c)
+			buf = buf[:len(buf)-1]
+			if !more {
+				return false
+			}
+		}
+		return true
+	}
+	walk(n)
+	return words
+}
+
+// Len is the number of words in the trie
+func (t *Trie) Len() int {
+	return t.words
+}
+
+// Nodes is the number of nodes below the root, one per byte of every word that no earlier word shares
+func (t *Trie) Nodes() int {
+	return t.nodes
+}
+
+// SortedWords is the alternative to a trie, the words in a sorted slice, the words starting with a prefix are a
+// contiguous range and two binary searches find its ends
+type SortedWords []string
+
+// NewSortedWords sorts a copy of the words and drops duplicates
+func NewSortedWords(words []string) SortedWords {
+	sorted := slices.Clone(words)
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
+
+// Range returns the indexes of the first word starting with prefix and of the first word after them, equal when
+// there are none, every word starting with the prefix sorts at or after it and before any word that doesn't that
+// sorts after it
+func (s SortedWords) Range(prefix string) (lo, hi int) {
+	lo = sort.SearchStrings(s, prefix)
+	hi = lo + sort.Search(len(s)-lo, func(i int) bool { return !strings.HasPrefix(s[lo+i], prefix) })
+	return lo, hi
+}
+
+// Contains reports whether the word is in the slice
+func (s SortedWords) Contains(word string) bool {
+	_, found := slices.BinarySearch(s, word)
+	return found
+}
+
+// Complete returns up to limit of the words starting with prefix, in lexicographic order, a limit of 0 or less
+// returns them all
+func (s SortedWords) Complete(prefix string, limit int) []string {
+	lo, hi := s.Range(prefix)
+	if limit > 0 {
+		hi = min(hi, lo+limit)
+	}
+	return slices.Clone(s[lo:hi])
+}
@@ -0,0 +1,93 @@
+package tries
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTrie(t *testing.T) {
+	trie := NewTrie()
+	for _, word := range []string{"car", "cart", "care", "cat", "dog", "car"} {
+		trie.Insert(word)
+	}
+	if trie.Len() != 5 {
+		t.Errorf("Len() = %d, want 5, the second car isn't new", trie.Len())
+	}
+	// c a r t e, t, d o g
+	if trie.Nodes() != 9 {
+		t.Errorf("Nodes() = %d, want 9", trie.Nodes())
+	}
+	if !trie.Contains("cart") || trie.Contains("ca") || trie.Contains("cars") {
+		t.Error("Contains must match whole words only")
+	}
+	tests := []struct {
+		prefix string
+		limit  int
+		want   []string
+	}{
+		{"ca", 0, []string{"car", "care", "cart", "cat"}},
+		{"ca", 2, []string{"car", "care"}},
+		{"car", 0, []string{"car", "care", "cart"}},
+		{"", 0, []string{"car", "care", "cart", "cat", "dog"}},
+		{"x", 0, nil},
+	}
+	for _, tt := range tests {
+		if got := trie.Complete(tt.prefix, tt.limit); !slices.Equal(got, tt.want) {
+			t.Errorf("Complete(%q, %d) = %v, want %v", tt.prefix, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestSortedWordsRange(t *testing.T) {
+	sorted := NewSortedWords([]string{"cat", "car", "dog", "cart", "car", "ca"})
+	if !slices.Equal(sorted, SortedWords{"ca", "car", "cart", "cat", "dog"}) {
+		t.Fatalf("NewSortedWords = %v, want sorted without duplicates", sorted)
+	}
+	if lo, hi := sorted.Range("car"); lo != 1 || hi != 3 {
+		t.Errorf("Range(car) = %d, %d, want 1, 3", lo, hi)
+	}
+	if lo, hi := sorted.Range("cb"); lo != hi {
+		t.Errorf("Range(cb) = %d, %d, want an empty range", lo, hi)
+	}
+}
+
+// the trie and the sorted slice are two ways to answer the same question, on generated words they must agree
+func TestTrieMatchesSortedWords(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	words := GenerateWords(rng, 5000)
+	trie := NewTrie()
+	for _, word := range words {
+		trie.Insert(word)
+	}
+	sorted := NewSortedWords(words)
+	for _, prefix := range prefixes(rng, words, 1000) {
+		got, want := trie.Complete(prefix, 0), sorted.Complete(prefix, 0)
+		if !slices.Equal(got, want) {
+			t.Fatalf("Complete(%q): trie %d words, sorted slice %d", prefix, len(got), len(want))
+		}
+		for _, word := range got {
+			if !strings.HasPrefix(word, prefix) {
+				t.Fatalf("Complete(%q) returned %q", prefix, word)
+			}
+		}
+	}
+}
+
+func TestAutocomplete(t *testing.T) {
+	words := []string{"tea", "team", "ten", "to"}
+	trie := NewTrie()
+	for _, word := range words {
+		trie.Insert(word)
+	}
+	var out strings.Builder
+	if err := autocomplete(strings.NewReader("te\nz\n"), &out, trie, NewSortedWords(words), 2); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"tea team\n", "3 words start with \"te\"", "no words start with \"z\""} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("autocomplete output is missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package tries
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+)
+
+// syllables make up the generated words, real words share prefixes because they share syllables, and words built
+// from a few dozen syllables share them the same way
+var (
+	onsets = []string{"", "b", "c", "d", "f", "g", "h", "l", "m", "n", "p", "r", "s", "t", "v", "w", "br", "ch", "cl", "pr", "sh", "st", "tr"}
+	vowels = []string{"a", "e", "i", "o", "u", "ai", "ea", "ou"}
+	codas  = []string{"", "", "", "n", "r", "s", "t", "ck", "ng", "st"}
+)
+
+// GenerateWords makes up n distinct words of one to four syllables, the same rng state makes the same words
+func GenerateWords(rng *rand.Rand, n int) []string {
+	seen := make(map[string]bool, n)
+	words := make([]string, 0, n)
+	var b strings.Builder
+	for len(words) < n {
+		b.Reset()
+		for range 1 + rng.Intn(4) {
+			b.WriteString(onsets[rng.Intn(len(onsets))])
+			b.WriteString(vowels[rng.Intn(len(vowels))])
+			b.WriteString(codas[rng.Intn(len(codas))])
+		}
+		if word := b.String(); !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// ReadWords reads a word list, one word per line, such as /usr/share/dict/words, skipping blank lines
+func ReadWords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	words := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != "" {
+			words = append(words, word)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("%s has no words", path)
+	}
+	return words, nil
+}
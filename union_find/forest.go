@@ -0,0 +1,108 @@
+package unionfind
+
+// Forest is a disjoint set forest over the elements 0 to n-1, each set a tree whose root names it, Find follows the
+// parent links up to the root and Union hangs a's root under b's
+// Two optional tricks keep the trees flat, union by rank hangs the shorter tree under the taller, and path
+// compression points every element Find passes straight at the root, together they make any sequence of m
+// operations take O(m α(n)), α the inverse Ackermann function, at most 4 for any n that fits in a computer
+type Forest struct {
+	parent []int
+	// rank bounds the height of the tree below a root, only kept up to date, and only meaningful, with byRank
+	rank     []uint8
+	byRank   bool
+	compress bool
+	sets     int
+	finds    int64
+	steps    int64
+}
+
+// NewForest creates n singleton sets, byRank and compress turn on union by rank and path compression
+func NewForest(n int, byRank, compress bool) *Forest {
+	f := &Forest{parent: make([]int, n), rank: make([]uint8, n), byRank: byRank, compress: compress, sets: n}
+	for i := range f.parent {
+		f.parent[i] = i
+	}
+	return f
+}
+
+// Find returns the root of the set holding x
+func (f *Forest) Find(x int) int {
+	f.finds++
+	root := x
+	for f.parent[root] != root {
+		root = f.parent[root]
+		f.steps++
+	}
+	if f.compress {
+		// a second pass up the same path repoints each element at the root, the next Find of any of them is one step
+		for x != root {
+			x, f.parent[x] = f.parent[x], root
+		}
+	}
+	return root
+}
+
+// Union merges the sets holding a and b, reporting whether they were separate
+func (f *Forest) Union(a, b int) bool {
+	ra, rb := f.Find(a), f.Find(b)
+	if ra == rb {
+		return false
+	}
+	if f.byRank {
+		if f.rank[ra] > f.rank[rb] {
+			ra, rb = rb, ra
+		}
+		// two trees of the same rank make one a level taller, which is why a rank of r needs 2^r elements
+		if f.rank[ra] == f.rank[rb] {
+			f.rank[rb]++
+		}
+	}
+	f.parent[ra] = rb
+	f.sets--
+	return true
+}
+
+// Connected reports whether a and b are in the same set
+func (f *Forest) Connected(a, b int) bool {
+	return f.Find(a) == f.Find(b)
+}
+
+// Sets is the number of disjoint sets left
+func (f *Forest) Sets() int {
+	return f.sets
+}
+
+// StepsPerFind is the mean number of parent links each Find followed to reach its root
+func (f *Forest) StepsPerFind() float64 {
+	if f.finds == 0 {
+		return 0
+	}
+	return float64(f.steps) / float64(f.finds)
+}
+
+// Height is the most parent links any element is from its root, found without compressing anything so measuring it
+// leaves the forest as it was
+func (f *Forest) Height() int {
+	// depth[x] is x's distance from its root plus one, zero until known, each element's depth is worked out once from
+	// its parent's, so a forest that's one long chain costs O(n) rather than O(n^2)
+	depth := make([]int, len(f.parent))
+	height := 0
+	path := []int{}
+	for x := range f.parent {
+		for y := x; depth[y] == 0; y = f.parent[y] {
+			path = append(path, y)
+			if f.parent[y] == y {
+				depth[y] = 1
+				break
+			}
+		}
+		for i := len(path) - 1; i >= 0; i-- {
+			if y := path[i]; depth[y] == 0 {
+				depth[y] = depth[f.parent[y]] + 1
+			}
+		}
+		path = path[:0]
+		height = max(height, depth[x]-1)
+	}
+	return height
+}
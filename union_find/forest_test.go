@@ -0,0 +1,59 @@
+package unionfind
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+func TestForest(t *testing.T) {
+	for _, v := range variants {
+		f := NewForest(6, v.byRank, v.compress)
+		f.Union(0, 1)
+		f.Union(2, 3)
+		f.Union(1, 3)
+		if f.Union(0, 2) {
+			t.Errorf("%s: Union(0, 2) merged elements already in one set", v.name)
+		}
+		if !f.Connected(0, 3) || f.Connected(0, 4) || f.Connected(4, 5) {
+			t.Errorf("%s: Connected got the sets {0 1 2 3} {4} {5} wrong", v.name)
+		}
+		if f.Sets() != 3 {
+			t.Errorf("%s: Sets() = %d, want 3", v.name, f.Sets())
+		}
+	}
+}
+
+// the variants differ only in the shape of their trees, on the same operations they must give the same answers
+func TestVariantsAgree(t *testing.T) {
+	ops := randomOps(rand.New(rand.NewSource(1)), 1000, 5000, 0.3)
+	want := measure(context.Background(), variants[0], 1000, ops)
+	for _, v := range variants[1:] {
+		got := measure(context.Background(), v, 1000, ops)
+		if got.Sets != want.Sets || got.Connected != want.Connected {
+			t.Errorf("%s: %d sets and %d connected, want %d and %d", v.name, got.Sets, got.Connected, want.Sets, want.Connected)
+		}
+	}
+}
+
+func TestChainHeights(t *testing.T) {
+	const n = 1024
+	ops := chainOps(rand.New(rand.NewSource(2)), n)
+	for _, tt := range []struct {
+		variant   variant
+		maxHeight int
+	}{
+		{variants[0], n - 1},
+		{variants[1], 10},
+	} {
+		forest := NewForest(n, tt.variant.byRank, tt.variant.compress)
+		for _, o := range ops {
+			if o.union {
+				forest.Union(o.a, o.b)
+			}
+		}
+		if got := forest.Height(); got > tt.maxHeight || (tt.variant.name == "Plain" && got != n-1) {
+			t.Errorf("%s: Height() = %d after the chain, want at most %d", tt.variant.name, got, tt.maxHeight)
+		}
+	}
+}
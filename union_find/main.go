@@ -0,0 +1,257 @@
+// Package unionfind is the union-find lesson, teachgo unionfind, a disjoint set forest with and without union by
+// rank and path compression, timed on a random connectivity workload and on the worst case for plain trees
+package unionfind
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo unionfind -h, its first line is the summary teachgo help lists
+const Description = `Union-find, disjoint sets with union by rank and path compression
+
+Builds a disjoint set forest, each set a tree of parent links whose root names it, in four variants, plain, with
+union by rank, with path compression and with both. Times them on a random connectivity workload, unions of random
+pairs mixed with queries of whether two elements are connected, and on a chain of unions that makes the plain tree
+a long list. Reports the time per operation, the parent links each find followed and the tallest tree, showing
+both tricks together keep every operation close to constant time.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "unionfind",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"disjoint sets", "path compression", "union by rank", "amortized analysis"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Elements      int     `json:"elements"`
+	Ops           int     `json:"ops"`
+	UnionFraction float64 `json:"union_fraction"`
+	Chain         int     `json:"chain"`
+	Seed          int64   `json:"seed"`
+}
+
+// VariantResult is what one variant measured on one workload
+type VariantResult struct {
+	Name         string  `json:"name"`
+	OpNs         float64 `json:"op_ns"`
+	StepsPerFind float64 `json:"steps_per_find"`
+	Height       int     `json:"height"`
+	Sets         int     `json:"sets"`
+	// Connected counts the queries answered yes, every variant must give the same answers
+	Connected int `json:"connected"`
+}
+
+// WorkloadResult is every variant's result on one workload
+type WorkloadResult struct {
+	Name     string          `json:"name"`
+	Elements int             `json:"elements"`
+	Ops      int             `json:"ops"`
+	Variants []VariantResult `json:"variants"`
+	// Skipped lists the variants too slow to run on this many elements
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config    RunConfig        `json:"config"`
+	Env       bench.Env        `json:"env"`
+	Workloads []WorkloadResult `json:"workloads"`
+}
+
+// plainMaxElements is the most elements the plain forest is run on, its trees grow to around a tenth of the
+// elements tall on the random workload, so its time grows with the square of them, a second at this size and
+// minutes at a million
+const plainMaxElements = 50_000
+
+// variant is a forest under test
+type variant struct {
+	name     string
+	byRank   bool
+	compress bool
+}
+
+var variants = []variant{
+	{"Plain", false, false},
+	{"Union by rank", true, false},
+	{"Path compression", false, true},
+	{"Rank and compression", true, true},
+}
+
+// op is a union of A and B, or a query of whether they're connected
+type op struct {
+	union bool
+	a, b  int
+}
+
+// randomOps mixes unions of random pairs with queries of random pairs, a random graph's edges arriving one by one
+// with questions about which parts of it are joined up yet
+func randomOps(rng *rand.Rand, elements, n int, unionFraction float64) []op {
+	ops := make([]op, n)
+	for i := range ops {
+		ops[i] = op{union: rng.Float64() < unionFraction, a: rng.Intn(elements), b: rng.Intn(elements)}
+	}
+	return ops
+}
+
+// chainOps unions element 0 with each other element in turn, then queries random pairs
+// Without union by rank each union hangs 0's root under the new element, so the tree becomes a list with 0 at the
+// far end and every union's Find of 0 walks the whole of it, O(n^2) for the unions
+func chainOps(rng *rand.Rand, elements int) []op {
+	ops := make([]op, 0, 2*elements)
+	for i := 1; i < elements; i++ {
+		ops = append(ops, op{union: true, a: 0, b: i})
+	}
+	for range elements {
+		ops = append(ops, op{a: rng.Intn(elements), b: rng.Intn(elements)})
+	}
+	return ops
+}
+
+// measure runs the operations against a fresh forest of the variant
+func measure(ctx context.Context, v variant, elements int, ops []op) VariantResult {
+	result := VariantResult{Name: v.name}
+	forest := NewForest(elements, v.byRank, v.compress)
+	elapsed := bench.Phase(ctx, v.name, func() {
+		for _, o := range ops {
+			if o.union {
+				forest.Union(o.a, o.b)
+			} else if forest.Connected(o.a, o.b) {
+				result.Connected++
+			}
+		}
+	})
+	result.OpNs = float64(elapsed.Nanoseconds()) / float64(max(len(ops), 1))
+	result.StepsPerFind = forest.StepsPerFind()
+	result.Height = forest.Height()
+	result.Sets = forest.Sets()
+	return result
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Union-Find Comparison")
+	fmt.Fprintln(w, "=====================")
+	fmt.Fprintf(w, "Elements: %d\nOperations: %d, %.0f%% unions\nChain: %d\n", config.Elements, config.Ops, 100*config.UnionFraction, config.Chain)
+	fmt.Fprintf(w, "Machine: %s\n", result.Env)
+
+	for _, workload := range result.Workloads {
+		fmt.Fprintf(w, "\n=====%s, %d elements, %d operations=====\n", workload.Name, workload.Elements, workload.Ops)
+		fmt.Fprintf(w, "%-22s %10s %12s %8s %10s %10s\n", "Variant", "Time/op", "Steps/find", "Height", "Sets", "Connected")
+		for _, v := range workload.Variants {
+			fmt.Fprintf(w, "%-22s %10v %12.2f %8d %10d %10d\n", v.Name, time.Duration(v.OpNs), v.StepsPerFind, v.Height, v.Sets, v.Connected)
+		}
+		for _, name := range workload.Skipped {
+			fmt.Fprintf(w, "%-22s skipped above %d elements, its time grows with their square\n", name, plainMaxElements)
+		}
+	}
+	fmt.Fprintln(w, "\nEvery variant finds the same sets, they differ only in the shape of the trees and so the cost of a find")
+	fmt.Fprintln(w, "The chain makes the plain forest one long list, union by rank alone keeps every tree under log2(n) high,")
+	fmt.Fprintln(w, "path compression alone flattens whatever a find walks, and both together bound a find by α(n), at most 4")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo unionfind
+func Main(args []string) {
+	fs := bench.NewFlagSet("unionfind", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the union-find lesson measures a single run"
+	elements := fs.Int("elements", 1000000, "the number of elements in the random workload, the plain forest only runs on it up to 50000")
+	numOps := fs.Int("ops", 2000000, "the number of unions and queries in the random workload")
+	unionFraction := fs.Float64("union-fraction", 0.5, "the fraction of the random workload's operations that are unions, the rest are queries")
+	chain := fs.Int("chain", 10000, "the number of elements in the chain workload, the plain forest takes O(n^2) on it, 0 to skip it")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "unionfind measures a single run, -trials isn't supported")
+	v.AtLeast("elements", *elements, 1)
+	v.AtLeast("ops", *numOps, 1)
+	v.Fraction("union-fraction", *unionFraction)
+	v.AtLeast("chain", *chain, 0)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	result := RunResult{
+		Config: RunConfig{Elements: *elements, Ops: *numOps, UnionFraction: *unionFraction, Chain: *chain, Seed: globals.Seed},
+		Env:    bench.CaptureEnv(),
+	}
+	rng := rand.New(rand.NewSource(globals.Seed))
+	workloads := []struct {
+		name     string
+		elements int
+		ops      []op
+	}{
+		{"Random", *elements, randomOps(rng, *elements, *numOps, *unionFraction)},
+	}
+	if *chain > 0 {
+		workloads = append(workloads, struct {
+			name     string
+			elements int
+			ops      []op
+		}{"Chain", *chain, chainOps(rng, *chain)})
+	}
+
+	ctx := context.Background()
+	for _, workload := range workloads {
+		workloadResult := WorkloadResult{Name: workload.name, Elements: workload.elements, Ops: len(workload.ops)}
+		for _, v := range variants {
+			if !v.byRank && !v.compress && workload.elements > plainMaxElements {
+				workloadResult.Skipped = append(workloadResult.Skipped, v.name)
+				continue
+			}
+			slog.Info("measuring", "workload", workload.name, "variant", v.name)
+			workloadResult.Variants = append(workloadResult.Variants, measure(ctx, v, workload.elements, workload.ops))
+		}
+		result.Workloads = append(result.Workloads, workloadResult)
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per workload and variant, the steps per find alongside the time, the count that
+// doesn't depend on the machine
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/union_find"); err != nil {
+		return err
+	}
+	benchmarks := []bench.Benchmark{}
+	for _, workload := range result.Workloads {
+		for _, v := range workload.Variants {
+			benchmarks = append(benchmarks, bench.Benchmark{
+				Name:    fmt.Sprintf("%s/%s/elements=%d", workload.Name, v.Name, workload.Elements),
+				N:       int64(workload.Ops),
+				Metrics: []bench.Metric{{Value: v.OpNs, Unit: "ns/op"}, {Value: v.StepsPerFind, Unit: "steps/find"}},
+			})
+		}
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
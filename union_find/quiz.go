@@ -0,0 +1,63 @@
+package unionfind
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz unionfind, it asks how a disjoint set forest answers a find, why the chain workload
+// ruins the plain forest, and what union by rank and path compression each bring
+var quiz = []lesson.Question{
+	{
+		Prompt: "How does a disjoint set forest tell whether two elements are in the same set?",
+		Choices: []string{
+			"It compares the ranks of the two elements",
+			"It looks both up in a matrix of every pair",
+			"It follows both up to their roots and compares them",
+			"It searches each set in turn for both elements",
+		},
+		Answer:      2,
+		Explanation: "each set is a tree and its root names it, a union only has to hang one root under the other",
+	},
+	{
+		Prompt: "Why does the chain workload make the plain forest so slow?",
+		Choices: []string{
+			"Each union hangs 0's tree under a new root, so the tree becomes a list",
+			"It runs many more operations than the random workload in the same time",
+			"Its unions all name the same pair, so each one rebuilds that pair's tree",
+			"The chain's elements are too many to fit in the CPU cache at once",
+		},
+		Answer:      0,
+		Explanation: "every find of 0 walks the whole list, without a rule about which root goes under which, an unlucky order of unions builds the tallest tree possible, n-1 links",
+	},
+	{
+		Prompt: "Why does union by rank alone keep every tree under log2(n) high?",
+		Choices: []string{
+			"It rebalances the whole tree after every union, like a red-black tree",
+			"It refuses a union that would make a set bigger than log2(n) elements",
+			"It flattens the path it walks as a side effect of comparing the ranks",
+			"A tree only grows taller when two of equal rank merge, so rank r means 2^r elements",
+		},
+		Answer:      3,
+		Explanation: "hanging the shorter tree under the taller leaves the height unchanged, only a tie adds a level and doubles the smallest possible size",
+	},
+	{
+		Prompt: "What does path compression change?",
+		Choices: []string{
+			"It merges any two sets found to share an element",
+			"Every element a find passes is repointed straight at the root",
+			"It drops elements that haven't been queried for a while",
+			"It records each find's path so it can be replayed faster",
+		},
+		Answer:      1,
+		Explanation: "later finds from them take one step, the first find up a long path pays for the walk and leaves it flat behind it, which is why a single slow find doesn't repeat",
+	},
+	{
+		Prompt: "With both union by rank and path compression, what does each operation cost?",
+		Choices: []string{
+			"O(log n) worst case for every operation",
+			"O(α(n)) amortized, the inverse Ackermann function",
+			"O(1) worst case for every operation",
+			"O(log log n) amortized",
+		},
+		Answer:      1,
+		Explanation: "α(n) is no more than 4 for any n that could ever be stored, it isn't a constant in theory, but it's a constant in practice, the steps per find stay near 1 however many elements there are",
+	},
+}
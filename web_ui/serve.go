@@ -0,0 +1,338 @@
+// Package webui is the web UI lesson, run it with teachgo serve, it serves a page per lesson with a form for its
+// flags and runs the lesson from it, and its source is itself a small lesson in net/http and html/template
+package webui
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo serve -h, its first line is the summary teachgo help lists
+const Description = `A web UI for running every lesson, itself a lesson in net/http and html/template
+
+Serves a page per lesson with a form for its flags, runs the lesson with them and shows its output and charts. Read
+web_ui/serve.go alongside it, the server routes with the method and path patterns of http.ServeMux, renders pages
+with html/template, which escapes whatever a lesson prints, and runs each lesson as a child process bound to the
+request's context, so closing the page stops the run.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "serve",
+		Description: Description,
+		Difficulty:  lesson.Advanced,
+		Topics:      []string{"net/http", "html/template", "http.ServeMux patterns", "os/exec", "request contexts"},
+		Main:        Main,
+	})
+}
+
+// templates holds the pages, embedded in the binary so teachgo serve works from any directory
+//
+//go:embed templates/*.html
+var templates embed.FS
+
+// pages are parsed once at start up, each page is parsed together with the layout it fills in, as two pages both
+// defining "content" in one template set would overwrite each other
+var pages = map[string]*template.Template{}
+
+func init() {
+	for _, page := range []string{"index.html", "lesson.html", "run.html"} {
+		t := template.New(page).Funcs(template.FuncMap{"join": strings.Join})
+		pages[page] = template.Must(t.ParseFS(templates, "templates/layout.html", "templates/"+page))
+	}
+}
+
+// hiddenFlags aren't offered in the forms, and so never passed on from one, they take over the terminal, serve
+// forever, are set by the server itself, or read or write files wherever they're told to
+var hiddenFlags = []string{"cpuprofile", "dir", "events", "file", "format", "html", "log-format", "memprofile",
+	"metrics", "mutexprofile", "pprof", "record", "replay", "timeline", "trace", "tui", "words"}
+
+// run is one run of a lesson from the UI
+type run struct {
+	ID       int
+	Lesson   string
+	Args     []string
+	Started  time.Time
+	Duration time.Duration
+	Output   string
+	Err      string
+	// Report is the HTML page of charts the lesson wrote, empty when charts weren't asked for or it can't draw them
+	Report []byte
+}
+
+// server holds the state shared by the handlers, every request runs in its own goroutine so the runs are guarded by
+// a mutex
+type server struct {
+	executable string
+	timeout    time.Duration
+
+	mu    sync.Mutex
+	runs  []*run
+	flags map[string][]bench.FlagInfo
+}
+
+func Main(args []string) {
+	fs := bench.NewFlagSet("serve", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("seed").Usage = "not used, set each lesson's seed in its form"
+	fs.Lookup("trials").Usage = "not used, set each lesson's trials in its form"
+	addr := fs.String("addr", "localhost:8080", "the address to serve the UI on")
+	timeout := fs.Duration("timeout", 5*time.Minute, "stop a lesson run from the UI that takes longer than this")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text"))
+	v.Check(*timeout > 0, "-timeout must be positive, got %v", *timeout)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	// lessons run as child processes of this same binary, so a lesson that exits or panics can't take the server down
+	executable, err := os.Executable()
+	if err != nil {
+		slog.Error("failed to find the teachgo binary to run lessons with", "err", err)
+		os.Exit(1)
+	}
+	s := &server{executable: executable, timeout: *timeout, flags: map[string][]bench.FlagInfo{}}
+
+	slog.Info("serving the lessons", "url", "http://"+*addr+"/")
+	if err := http.ListenAndServe(*addr, s.routes()); err != nil {
+		slog.Error("server stopped", "err", err)
+		os.Exit(1)
+	}
+}
+
+// routes maps each method and path pattern to its handler, {name} and {id} are wildcards a handler reads with
+// r.PathValue, a request matching no pattern gets a 404 and the wrong method a 405 without any code of ours
+// http.CrossOriginProtection turns away a POST from another site, any page the student visits could otherwise
+// start runs on the server listening on localhost
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.index)
+	mux.HandleFunc("GET /lessons/{name}", s.lessonPage)
+	mux.HandleFunc("POST /lessons/{name}/runs", s.startRun)
+	mux.HandleFunc("GET /runs/{id}", s.runPage)
+	mux.HandleFunc("GET /runs/{id}/report", s.runReport)
+	return http.NewCrossOriginProtection().Handler(mux)
+}
+
+// render executes a page into a buffer first, so a template error becomes a clean 500 rather than half a page
+func render(w http.ResponseWriter, page string, data any) {
+	var buf bytes.Buffer
+	if err := pages[page].ExecuteTemplate(&buf, "layout", data); err != nil {
+		slog.Error("failed to render page", "page", page, "err", err)
+		http.Error(w, "failed to render the page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+func (s *server) index(w http.ResponseWriter, r *http.Request) {
+	lessons := slices.DeleteFunc(lesson.All(), func(l lesson.Lesson) bool { return l.Name == "serve" })
+	render(w, "index.html", map[string]any{"Lessons": lessons, "Runs": s.recentRuns("")})
+}
+
+// findLesson looks up the lesson a request names, the serve lesson can't run itself
+func findLesson(w http.ResponseWriter, r *http.Request) (lesson.Lesson, bool) {
+	l, ok := lesson.Find(r.PathValue("name"))
+	if !ok || l.Name == "serve" {
+		http.NotFound(w, r)
+		return lesson.Lesson{}, false
+	}
+	return l, true
+}
+
+func (s *server) lessonPage(w http.ResponseWriter, r *http.Request) {
+	l, ok := findLesson(w, r)
+	if !ok {
+		return
+	}
+	flags, err := s.lessonFlags(r.Context(), l.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	render(w, "lesson.html", map[string]any{
+		"Lesson":    l,
+		"Flags":     flags,
+		"HasCharts": slices.ContainsFunc(flags, func(f bench.FlagInfo) bool { return f.Name == "html" }),
+		"Runs":      s.recentRuns(l.Name),
+	})
+}
+
+// lessonFlags asks a lesson for its flags, through -h with bench.FlagsJSONEnv set, and keeps them for next time
+func (s *server) lessonFlags(ctx context.Context, name string) ([]bench.FlagInfo, error) {
+	s.mu.Lock()
+	flags, ok := s.flags[name]
+	s.mu.Unlock()
+	if ok {
+		return flags, nil
+	}
+
+	cmd := exec.CommandContext(ctx, s.executable, name, "-h")
+	cmd.Env = append(os.Environ(), bench.FlagsJSONEnv+"=1")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	// -h exits with status 0 after printing the flags, so an error here means the lesson failed
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to list the flags of %s: %w", name, err)
+	}
+	if err := json.Unmarshal(stderr.Bytes(), &flags); err != nil {
+		return nil, fmt.Errorf("failed to read the flags of %s: %w", name, err)
+	}
+	flags = slices.DeleteFunc(flags, func(f bench.FlagInfo) bool { return slices.Contains(hiddenFlags, f.Name) })
+
+	s.mu.Lock()
+	s.flags[name] = flags
+	s.mu.Unlock()
+	return flags, nil
+}
+
+// startRun runs a lesson with the flags submitted by its form, then redirects to the run's page, a browser refreshing
+// that page only fetches the result again rather than rerunning the lesson, the Post/Redirect/Get pattern
+func (s *server) startRun(w http.ResponseWriter, r *http.Request) {
+	l, ok := findLesson(w, r)
+	if !ok {
+		return
+	}
+	flags, err := s.lessonFlags(r.Context(), l.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// only flags changed from their defaults are passed, so the command shown reads like one a student would type
+	args := []string{}
+	for _, f := range flags {
+		value := strings.TrimSpace(r.PostForm.Get(f.Name))
+		if f.Type == "bool" {
+			value = strconv.FormatBool(value == "on")
+		}
+		if readsFile(value) {
+			http.Error(w, fmt.Sprintf("-%s can't read a file from the UI, pick a generated distribution", f.Name),
+				http.StatusBadRequest)
+			return
+		}
+		if value != f.Default {
+			args = append(args, fmt.Sprintf("-%s=%s", f.Name, value))
+		}
+	}
+
+	run := s.execute(r.Context(), l.Name, args, r.PostForm.Get("charts") == "on")
+	http.Redirect(w, r, fmt.Sprintf("/runs/%d", run.ID), http.StatusSeeOther)
+}
+
+// readsFile reports whether a flag's value names a datagen file distribution, alone or in a comma separated list,
+// which would have the lesson read whatever path the form was sent
+func readsFile(value string) bool {
+	for _, part := range strings.Split(value, ",") {
+		if kind, _, _ := strings.Cut(strings.TrimSpace(part), ":"); kind == "file" {
+			return true
+		}
+	}
+	return false
+}
+
+// execute runs the lesson as a child process and records the run, the child is killed if the request is canceled,
+// the browser gave up waiting, or the run passes the timeout
+func (s *server) execute(ctx context.Context, name string, args []string, charts bool) *run {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	run := &run{Lesson: name, Args: args, Started: time.Now()}
+	cmdArgs := append([]string{name}, args...)
+
+	var reportPath string
+	if charts {
+		dir, err := os.MkdirTemp("", "teachgo-serve")
+		if err == nil {
+			defer os.RemoveAll(dir)
+			reportPath = filepath.Join(dir, "report.html")
+			cmdArgs = append(cmdArgs, "-html="+reportPath)
+		}
+	}
+
+	output, err := exec.CommandContext(ctx, s.executable, cmdArgs...).CombinedOutput()
+	run.Duration = time.Since(run.Started)
+	run.Output = string(output)
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		run.Err = fmt.Sprintf("stopped after %v, raise -timeout or make the run smaller", s.timeout)
+	case err != nil:
+		run.Err = err.Error()
+	}
+	if reportPath != "" {
+		run.Report, _ = os.ReadFile(reportPath)
+	}
+
+	s.mu.Lock()
+	run.ID = len(s.runs) + 1
+	s.runs = append(s.runs, run)
+	s.mu.Unlock()
+	return run
+}
+
+// recentRuns returns the runs of a lesson, or of every lesson, newest first
+func (s *server) recentRuns(name string) []*run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runs := []*run{}
+	for _, run := range slices.Backward(s.runs) {
+		if name == "" || run.Lesson == name {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+// findRun looks up the run a request names by its id
+func (s *server) findRun(w http.ResponseWriter, r *http.Request) (*run, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil || id < 1 || id > len(s.runs) {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	return s.runs[id-1], true
+}
+
+func (s *server) runPage(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.findRun(w, r)
+	if !ok {
+		return
+	}
+	render(w, "run.html", run)
+}
+
+// runReport serves the lesson's own HTML report as it wrote it, the run page shows it in an iframe
+func (s *server) runReport(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.findRun(w, r)
+	if !ok || run.Report == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(run.Report)
+}
@@ -0,0 +1,68 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoutes(t *testing.T) {
+	s := &server{timeout: time.Second}
+	s.runs = append(s.runs, &run{ID: 1, Lesson: "skiplist", Args: []string{"-elements=10"}, Output: "<b>not markup</b>"})
+	handler := s.routes()
+
+	tests := []struct {
+		method, path string
+		status       int
+		contains     string
+	}{
+		{"GET", "/", http.StatusOK, "Recent runs"},
+		// html/template escapes whatever a lesson printed
+		{"GET", "/runs/1", http.StatusOK, "&lt;b&gt;not markup&lt;/b&gt;"},
+		{"GET", "/runs/2", http.StatusNotFound, ""},
+		{"GET", "/runs/1/report", http.StatusNotFound, ""},
+		{"GET", "/lessons/nope", http.StatusNotFound, ""},
+		{"GET", "/lessons/serve", http.StatusNotFound, ""},
+		{"DELETE", "/runs/1", http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s %s returned %d, want %d", tt.method, tt.path, w.Code, tt.status)
+		}
+		if !strings.Contains(w.Body.String(), tt.contains) {
+			t.Errorf("%s %s doesn't contain %q", tt.method, tt.path, tt.contains)
+		}
+	}
+
+	// a form posted from another site is turned away before a run is started
+	r := httptest.NewRequest("POST", "/lessons/skiplist/runs", strings.NewReader("elements=10"))
+	r.Header.Set("Sec-Fetch-Site", "cross-site")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden || len(s.runs) != 1 {
+		t.Errorf("a cross-origin POST returned %d with %d runs, want %d and no new run", w.Code, len(s.runs), http.StatusForbidden)
+	}
+}
+
+func TestReadsFile(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"uniform", false},
+		{"zipfian:1.5", false},
+		{"file:/etc/passwd", true},
+		{"file", true},
+		{"uniform, file:keys.txt", true},
+		{"profile", false},
+	}
+	for _, tt := range tests {
+		if got := readsFile(tt.value); got != tt.want {
+			t.Errorf("readsFile(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// Package workstealing is the work stealing lesson, teachgo workstealing, a pool of workers with a deque apiece that
+// steal from each other once their own runs dry, running recursive Fibonacci and quicksort tasks, against one queue
+// every worker shares and against deques with no stealing
+package workstealing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo workstealing -h, its first line is the summary teachgo
+// help lists
+const Description = `Work stealing, a deque per worker and stealing when one runs dry, against one shared queue
+
+Runs two recursive workloads on pools of -workers goroutines, Fibonacci of -fib, split into a task for each call
+down to -fib-cutoff, below which a task adds its number up in place, and a quicksort of -sort random integers, a
+task partitioning its range and spawning a task for each side, down to -sort-cutoff elements it sorts in place.
+Each runs three ways, every task on one queue all the workers take from, every task on a deque of the worker that
+spawned it with no stealing, and work stealing, where a worker whose deque is empty takes the oldest task from
+another's. Reports how long each took, how many tasks the busiest worker ran against an even share, the steals
+and the most tasks ever waiting on one queue, the shared queue's breadth first order against the deques' depth
+first.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "workstealing",
+		Description: Description,
+		Difficulty:  lesson.Advanced,
+		Topics:      []string{"work stealing", "deques", "schedulers", "load balancing", "fork-join"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// modes are the ways of sharing out tasks compared
+var modes = []Mode{Shared, Local, Stealing}
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Workers    int   `json:"workers"`
+	Fib        int   `json:"fib"`
+	FibCutoff  int   `json:"fib_cutoff"`
+	Sort       int   `json:"sort"`
+	SortCutoff int   `json:"sort_cutoff"`
+	Seed       int64 `json:"seed"`
+}
+
+// Run is one workload run by one pool, Busiest is the most tasks a worker ran over an even share of them
+type Run struct {
+	Workload   string  `json:"workload"`
+	Mode       string  `json:"mode"`
+	ElapsedNs  float64 `json:"elapsed_ns"`
+	Tasks      int64   `json:"tasks"`
+	Executed   []int64 `json:"executed"`
+	Busiest    float64 `json:"busiest"`
+	Steals     int64   `json:"steals"`
+	PeakQueued int64   `json:"peak_queued"`
+	Correct    bool    `json:"correct"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config RunConfig `json:"config"`
+	Env    bench.Env `json:"env"`
+	Runs   []Run     `json:"runs"`
+}
+
+// fib is Fibonacci computed the slow recursive way, the work a task below the cutoff does in place
+func fib(n int) int64 {
+	if n < 2 {
+		return int64(n)
+	}
+	return fib(n-1) + fib(n-2)
+}
+
+// fibTask splits fib(n) into fib(n-1) and fib(n-2) down to the cutoff, adding what the tasks at the bottom compute
+// into sum, the subtasks are as unequal as the calls, fib(n-1) is over half as big again as fib(n-2)
+func fibTask(n, cutoff int, sum *atomic.Int64) Task {
+	return func(w *Worker) {
+		if n <= cutoff {
+			sum.Add(fib(n))
+			return
+		}
+		w.Spawn(fibTask(n-1, cutoff, sum))
+		w.Spawn(fibTask(n-2, cutoff, sum))
+	}
+}
+
+// partition splits data round a median of three pivot, returning p where everything before p is at most the pivot
+// and everything from p on at least it, both sides non empty
+func partition(data []int) int {
+	mid := len(data) / 2
+	a, b, c := data[0], data[mid], data[len(data)-1]
+	pivot := max(min(a, b), min(max(a, b), c))
+	i, j := -1, len(data)
+	for {
+		for i++; data[i] < pivot; i++ {
+		}
+		for j--; data[j] > pivot; j-- {
+		}
+		if i >= j {
+			return j + 1
+		}
+		data[i], data[j] = data[j], data[i]
+	}
+}
+
+// sortTask partitions data and spawns a task for each side, sorting it in place once it's no more than cutoff, the
+// two sides touch separate parts of the slice so the tasks never race
+func sortTask(data []int, cutoff int) Task {
+	return func(w *Worker) {
+		if len(data) <= cutoff {
+			slices.Sort(data)
+			return
+		}
+		p := partition(data)
+		w.Spawn(sortTask(data[:p], cutoff))
+		w.Spawn(sortTask(data[p:], cutoff))
+	}
+}
+
+// run records a pool's run of a workload
+func run(workload string, mode Mode, stats Stats, correct bool) Run {
+	r := Run{Workload: workload, Mode: mode.String(), ElapsedNs: float64(stats.Elapsed.Nanoseconds()),
+		Executed: stats.Executed, Steals: stats.Steals, PeakQueued: stats.PeakQueued, Correct: correct}
+	var most int64
+	for _, n := range stats.Executed {
+		r.Tasks += n
+		most = max(most, n)
+	}
+	if r.Tasks > 0 {
+		r.Busiest = float64(most) / (float64(r.Tasks) / float64(len(stats.Executed)))
+	}
+	return r
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Work Stealing")
+	fmt.Fprintln(w, "=============")
+	fmt.Fprintf(w, "Workers: %d\n", config.Workers)
+	fmt.Fprintf(w, "Machine: %s\n", result.Env)
+
+	titles := map[string]string{
+		"fib":       fmt.Sprintf("Fibonacci of %d, split down to %d", config.Fib, config.FibCutoff),
+		"quicksort": fmt.Sprintf("Quicksort of %d integers, split down to %d", config.Sort, config.SortCutoff),
+	}
+	workload := ""
+	for _, r := range result.Runs {
+		if r.Workload != workload {
+			workload = r.Workload
+			fmt.Fprintf(w, "\n=====%s=====\n", titles[workload])
+			fmt.Fprintf(w, "%-14s %12s %9s %9s %8s %12s %8s\n", "Scheduler", "Elapsed", "Tasks", "Busiest", "Steals", "Peak queued", "Correct")
+		}
+		fmt.Fprintf(w, "%-14s %12v %9d %8.2fx %8d %12d %8v\n", r.Mode, time.Duration(r.ElapsedNs), r.Tasks, r.Busiest,
+			r.Steals, r.PeakQueued, r.Correct)
+	}
+	fmt.Fprintln(w, "\nBusiest is the most tasks one worker ran over an even share, 1x is perfectly balanced and the number of")
+	fmt.Fprintln(w, "workers is one worker doing everything. With no stealing the first task's worker does all the work while")
+	fmt.Fprintln(w, "the rest sit idle, the shared queue balances but every spawn and take goes through its one lock, and")
+	fmt.Fprintln(w, "breadth first it holds a whole level of the recursion at once. Stealing balances with a handful of")
+	fmt.Fprintln(w, "steals, each takes the oldest task, the biggest, so a thief has work for a long while, and otherwise")
+	fmt.Fprintln(w, "every worker stays on its own deque, depth first, where it's short and nobody else is contending")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo workstealing
+func Main(args []string) {
+	fs := bench.NewFlagSet("workstealing", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the work stealing lesson measures a single run"
+	workers := fs.Int("workers", 4, "the goroutines in each pool")
+	fibN := fs.Int("fib", 32, "the Fibonacci number to compute")
+	fibCutoff := fs.Int("fib-cutoff", 12, "the Fibonacci numbers small enough for a task to compute in place")
+	sortN := fs.Int("sort", 1000000, "the integers to quicksort")
+	sortCutoff := fs.Int("sort-cutoff", 2048, "the ranges small enough for a task to sort in place")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "workstealing measures a single run, -trials isn't supported")
+	v.AtLeast("workers", *workers, 1)
+	v.AtLeast("fib", *fibN, 0)
+	v.Check(*fibN <= 40, "-fib must be at most 40, got %d", *fibN)
+	v.AtLeast("fib-cutoff", *fibCutoff, 1)
+	v.AtLeast("sort", *sortN, 1)
+	v.AtLeast("sort-cutoff", *sortCutoff, 2)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	config := RunConfig{Workers: *workers, Fib: *fibN, FibCutoff: *fibCutoff, Sort: *sortN, SortCutoff: *sortCutoff, Seed: globals.Seed}
+	result := RunResult{Config: config, Env: bench.CaptureEnv()}
+	want := fib(*fibN)
+	for _, mode := range modes {
+		slog.Info("running fibonacci", "scheduler", mode.String(), "n", *fibN)
+		var sum atomic.Int64
+		stats := NewPool(mode, *workers, globals.Seed).Run(fibTask(*fibN, *fibCutoff, &sum))
+		result.Runs = append(result.Runs, run("fib", mode, stats, sum.Load() == want))
+	}
+	rng := rand.New(rand.NewSource(globals.Seed))
+	input := make([]int, *sortN)
+	for i := range input {
+		input[i] = rng.Int()
+	}
+	for _, mode := range modes {
+		slog.Info("running quicksort", "scheduler", mode.String(), "n", *sortN)
+		data := slices.Clone(input)
+		stats := NewPool(mode, *workers, globals.Seed).Run(sortTask(data, *sortCutoff))
+		result.Runs = append(result.Runs, run("quicksort", mode, stats, slices.IsSorted(data)))
+	}
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per workload and scheduler, an op is one task
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/work_stealing"); err != nil {
+		return err
+	}
+	benchmarks := []bench.Benchmark{}
+	for _, r := range result.Runs {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: fmt.Sprintf("%s/%s/workers=%d", r.Workload, r.Mode, result.Config.Workers),
+			N:    r.Tasks,
+			Metrics: []bench.Metric{
+				bench.NsPerOp(r.ElapsedNs, r.Tasks),
+				{Value: r.Busiest, Unit: "busiest"},
+				{Value: float64(r.Steals), Unit: "steals"},
+			},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
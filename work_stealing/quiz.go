@@ -0,0 +1,63 @@
+package workstealing
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz workstealing, it asks why private deques alone leave one worker doing everything,
+// how stealing the oldest task balances the load, and what a single shared queue costs by comparison
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does one worker run every task when each keeps its own deque and nothing is stolen?",
+		Choices: []string{
+			"The other workers are only started once the first worker's deque is empty",
+			"Spawned tasks go on the spawner's deque, and every task descends from the first worker's",
+			"The deques are handed out round robin, and the first worker's turn comes round most",
+			"The other workers spin on their empty deques and are never scheduled by the runtime",
+		},
+		Answer:      1,
+		Explanation: "everything the first task splits into stays on the first worker's deque and the others never get any, with several CPUs it takes about as long as one worker alone, the others spin with nothing to do",
+	},
+	{
+		Prompt: "Why does work stealing balance the load with only a handful of steals?",
+		Choices: []string{
+			"A thief moves half of the victim's deque over at once",
+			"The workers take turns stealing, one steal each per round",
+			"A thief takes the oldest task, one of the biggest, and splits it for a long while",
+			"A thief takes every other task, so the two deques stay even",
+		},
+		Answer:      2,
+		Explanation: "the owner works on the newest, smallest tasks at the bottom and thieves take the biggest from the top, they rarely reach for the same one",
+	},
+	{
+		Prompt: "Why do so many more tasks wait at once on the shared queue than on any deque?",
+		Choices: []string{
+			"Oldest first runs the recursion breadth first, a whole level waiting, a deque goes depth first",
+			"The shared queue counts each task once when it is pushed and again when it is taken",
+			"The shared queue is slower to take from, so tasks pile up while workers wait on its lock",
+			"The deques drop tasks once they are full and the spawning worker runs them inline",
+		},
+		Answer:      0,
+		Explanation: "a worker popping its newest task goes depth first and holds only a path, depth first keeps a deque about as long as the recursion is deep, which is also what keeps a worker's data warm in its cache",
+	},
+	{
+		Prompt: "Why can the shared queue keep up with work stealing at a few workers, but not at many?",
+		Choices: []string{
+			"It switches to a spin lock at a few workers and back to a mutex at many",
+			"It runs fewer tasks at a few workers, since each task splits less",
+			"Every spawn and take goes through its one lock, which many workers queue on",
+			"Its channel buffer is sized for a few workers and overflows with many",
+		},
+		Answer:      2,
+		Explanation: "a few workers rarely contend for the one lock, per worker deques spread the spawns and takes over as many locks as workers, only a steal touches another worker's",
+	},
+	{
+		Prompt: "Why does the pool count pending tasks rather than waiting for every deque to be empty?",
+		Choices: []string{
+			"Reading a counter is cheaper than locking every deque to check its length",
+			"The deques don't track their length, so there is nothing else to check",
+			"The count is also what tells the pool how many tasks were stolen",
+			"A deque can be empty while a running task is about to spawn more",
+		},
+		Answer:      3,
+		Explanation: "a task only finishes after spawning its children, so the count reaches 0 only when nothing is left, every deque empty at one instant isn't the end, the tasks being run right then may still split",
+	},
+}
@@ -0,0 +1,216 @@
+package workstealing
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Task is a piece of work, run by a worker, which it can split by spawning more tasks on w
+type Task func(w *Worker)
+
+// Deque is a double ended queue of tasks, its owner pushes and pops at the bottom, newest first, so it works depth
+// first on what it has just split, which keeps the deque short, thieves take from the top, the oldest task, which
+// for a recursive split is the biggest, so a steal is rare and worth it
+// A mutex guards it, the owner and a thief only contend when they reach for the last task
+type Deque struct {
+	mu    sync.Mutex
+	tasks []Task
+	head  int
+}
+
+// PushBottom adds t at the owner's end, returning how many tasks the deque then holds
+func (d *Deque) PushBottom(t Task) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.tasks = append(d.tasks, t)
+	return len(d.tasks) - d.head
+}
+
+// PopBottom takes the task the owner pushed last
+func (d *Deque) PopBottom() (Task, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.tasks) == d.head {
+		return nil, false
+	}
+	t := d.tasks[len(d.tasks)-1]
+	d.tasks[len(d.tasks)-1] = nil
+	d.tasks = d.tasks[:len(d.tasks)-1]
+	d.reset()
+	return t, true
+}
+
+// StealTop takes the task pushed first
+func (d *Deque) StealTop() (Task, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.tasks) == d.head {
+		return nil, false
+	}
+	t := d.tasks[d.head]
+	d.tasks[d.head] = nil
+	d.head++
+	d.reset()
+	return t, true
+}
+
+// reset moves the tasks back to the start of the slice once the top has been stolen from past half of it, so the
+// slice doesn't grow without limit
+func (d *Deque) reset() {
+	if d.head > 0 && d.head*2 >= len(d.tasks) {
+		n := copy(d.tasks, d.tasks[d.head:])
+		clear(d.tasks[n:])
+		d.tasks, d.head = d.tasks[:n], 0
+	}
+}
+
+// Len is how many tasks the deque holds
+func (d *Deque) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.tasks) - d.head
+}
+
+// Mode is how a pool's workers share out the tasks
+type Mode int
+
+const (
+	// Shared puts every task on one queue that every worker takes from, first in first out
+	Shared Mode = iota
+	// Local keeps a task on the deque of the worker that spawned it, with no stealing, so whichever worker gets the
+	// first task does everything it splits into
+	Local
+	// Stealing keeps a task on its spawner's deque, and has a worker with nothing left steal from another's
+	Stealing
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Shared:
+		return "shared queue"
+	case Local:
+		return "own deques"
+	default:
+		return "work stealing"
+	}
+}
+
+// Worker is one of a pool's goroutines, with a deque of its own
+type Worker struct {
+	id       int
+	pool     *Pool
+	deque    Deque
+	rng      *rand.Rand
+	executed int64
+	steals   int64
+}
+
+// Spawn adds a task for the pool to run, on the worker's own deque, or the shared queue
+func (w *Worker) Spawn(t Task) {
+	w.pool.pending.Add(1)
+	if w.pool.mode == Shared {
+		w.pool.peak(w.pool.shared.PushBottom(t))
+		return
+	}
+	w.pool.peak(w.deque.PushBottom(t))
+}
+
+// next finds the worker its next task, from the top of the shared queue, its own deque, or another worker's
+func (w *Worker) next() (Task, bool) {
+	switch w.pool.mode {
+	case Shared:
+		return w.pool.shared.StealTop()
+	case Local:
+		return w.deque.PopBottom()
+	}
+	if t, ok := w.deque.PopBottom(); ok {
+		return t, true
+	}
+	// starting each hunt at a random victim spreads the thieves out rather than having them all try the same one
+	n := len(w.pool.workers)
+	start := w.rng.Intn(n)
+	for i := range n {
+		victim := w.pool.workers[(start+i)%n]
+		if victim == w {
+			continue
+		}
+		if t, ok := victim.deque.StealTop(); ok {
+			w.steals++
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func (w *Worker) run() {
+	for w.pool.pending.Load() > 0 {
+		t, ok := w.next()
+		if !ok {
+			runtime.Gosched()
+			continue
+		}
+		t(w)
+		w.executed++
+		w.pool.pending.Add(-1)
+	}
+}
+
+// Pool is a fixed set of workers running a task and everything it spawns
+type Pool struct {
+	mode    Mode
+	workers []*Worker
+	shared  Deque
+	// pending counts the tasks spawned but not yet finished, a task spawns its children before it finishes, so it
+	// only reaches 0 once there's nothing left to do anywhere
+	pending    atomic.Int64
+	peakQueued atomic.Int64
+}
+
+// NewPool creates a pool of workers sharing out tasks by mode, seed drives which victims thieves try first
+func NewPool(mode Mode, workers int, seed int64) *Pool {
+	p := &Pool{mode: mode}
+	for i := range max(workers, 1) {
+		p.workers = append(p.workers, &Worker{id: i, pool: p, rng: rand.New(rand.NewSource(seed + int64(i)))})
+	}
+	return p
+}
+
+func (p *Pool) peak(queued int) {
+	for old := p.peakQueued.Load(); int64(queued) > old && !p.peakQueued.CompareAndSwap(old, int64(queued)); old = p.peakQueued.Load() {
+	}
+}
+
+// Stats is what a pool's run did, Executed is the tasks each worker ran and PeakQueued the most tasks that waited on
+// one queue or deque at once
+type Stats struct {
+	Elapsed    time.Duration
+	Executed   []int64
+	Steals     int64
+	PeakQueued int64
+}
+
+// Run runs root on the first worker, or the shared queue, and everything it spawns, returning once it's all done
+func (p *Pool) Run(root Task) Stats {
+	p.pending.Store(1)
+	p.peakQueued.Store(1)
+	if p.mode == Shared {
+		p.shared.PushBottom(root)
+	} else {
+		p.workers[0].deque.PushBottom(root)
+	}
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, w := range p.workers {
+		wg.Go(w.run)
+	}
+	wg.Wait()
+	stats := Stats{Elapsed: time.Since(start), PeakQueued: p.peakQueued.Load()}
+	for _, w := range p.workers {
+		stats.Executed = append(stats.Executed, w.executed)
+		stats.Steals += w.steals
+	}
+	return stats
+}
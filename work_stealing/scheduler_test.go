@@ -0,0 +1,68 @@
+package workstealing
+
+import (
+	"math/rand"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDequeEnds(t *testing.T) {
+	var d Deque
+	order := []int{}
+	for i := range 4 {
+		d.PushBottom(func(*Worker) { order = append(order, i) })
+	}
+	top, _ := d.StealTop()
+	bottom, _ := d.PopBottom()
+	top(nil)
+	bottom(nil)
+	if !slices.Equal(order, []int{0, 3}) || d.Len() != 2 {
+		t.Fatalf("stole and popped %v leaving %d, want 0 from the top, 3 from the bottom and 2 left", order, d.Len())
+	}
+	for range 2 {
+		if _, ok := d.StealTop(); !ok {
+			t.Fatal("nothing to steal with tasks left")
+		}
+	}
+	if _, ok := d.PopBottom(); ok {
+		t.Fatal("popped from an empty deque")
+	}
+}
+
+func TestEveryModeFinishesTheWork(t *testing.T) {
+	for _, mode := range modes {
+		var sum atomic.Int64
+		stats := NewPool(mode, 4, 1).Run(fibTask(20, 5, &sum))
+		if sum.Load() != fib(20) {
+			t.Errorf("%v: fib(20) came to %d, want %d", mode, sum.Load(), fib(20))
+		}
+		if mode != Stealing && stats.Steals != 0 {
+			t.Errorf("%v: %d steals without stealing", mode, stats.Steals)
+		}
+
+		rng := rand.New(rand.NewSource(1))
+		data := make([]int, 50000)
+		for i := range data {
+			data[i] = rng.Intn(1000)
+		}
+		NewPool(mode, 4, 1).Run(sortTask(data, 64))
+		if !slices.IsSorted(data) {
+			t.Errorf("%v: quicksort left the data unsorted", mode)
+		}
+	}
+}
+
+func TestOwnDequesLeaveTheWorkOnOneWorker(t *testing.T) {
+	var sum atomic.Int64
+	stats := NewPool(Local, 4, 1).Run(fibTask(15, 3, &sum))
+	busy := 0
+	for _, n := range stats.Executed {
+		if n > 0 {
+			busy++
+		}
+	}
+	if busy != 1 {
+		t.Fatalf("%d workers ran tasks without stealing, want 1", busy)
+	}
+}
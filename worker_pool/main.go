@@ -0,0 +1,355 @@
+// Package workerpool is the worker pool lesson, teachgo workerpool, a fixed pool of workers fed from a bounded queue,
+// with per-job timeouts, queue latency measured over pool shapes, and a clean drain when the process gets SIGTERM
+package workerpool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+	"github.com/joshdurbin/teaching-go/internal/lesson"
+)
+
+// Description is the lesson's help text, shown by teachgo workerpool -h, its first line is the summary teachgo help
+// lists
+const Description = `Worker pools, a bounded queue, per-job timeouts and a clean drain on SIGTERM
+
+Builds a pool of -workers goroutines taking jobs from a queue of -queue slots, each job run under a -timeout, and
+pushes -jobs simulated jobs through it, sleeps of around -work with a few that fail and a few that hang until their
+timeout cuts them off. Times pools of a range of sizes and queue depths, measuring how long jobs wait in the queue
+and how long the producer spends blocked on a full one, the backpressure that keeps a fast producer from queueing
+without limit. Then sends itself SIGTERM, as a service manager stopping it would, and shuts the pool down, once
+draining every queued job within -grace, and once with jobs that hang, where the grace runs out and the running
+jobs are cancelled and the queued ones dropped.`
+
+func init() {
+	lesson.Register(lesson.Lesson{
+		Name:        "workerpool",
+		Description: Description,
+		Difficulty:  lesson.Intermediate,
+		Topics:      []string{"worker pools", "backpressure", "timeouts", "graceful shutdown", "signals"},
+		Main:        Main,
+		Quiz:        quiz,
+	})
+}
+
+// workerSweep and depthSweep are the pool shapes timed, the depths with -workers workers, the workers with -queue
+var (
+	workerSweep = []int{1, 4, 16, 64}
+	depthSweep  = []int{0, 16, 256}
+)
+
+// errSimulated is what a failing job returns
+var errSimulated = errors.New("simulated failure")
+
+// RunConfig records the settings a run was made with
+type RunConfig struct {
+	Jobs    int           `json:"jobs"`
+	Workers int           `json:"workers"`
+	Queue   int           `json:"queue"`
+	Timeout time.Duration `json:"timeout"`
+	Work    time.Duration `json:"work"`
+	Slow    float64       `json:"slow"`
+	Fail    float64       `json:"fail"`
+	Grace   time.Duration `json:"grace"`
+	Seed    int64         `json:"seed"`
+}
+
+// PoolResult is the jobs pushed through one shape of pool, Blocked is the time the producer spent in Submit
+type PoolResult struct {
+	Workers    int      `json:"workers"`
+	QueueDepth int      `json:"queue_depth"`
+	ElapsedNs  float64  `json:"elapsed_ns"`
+	QueueP50Ns float64  `json:"queue_p50_ns"`
+	QueueP99Ns float64  `json:"queue_p99_ns"`
+	QueueMaxNs float64  `json:"queue_max_ns"`
+	BlockedNs  float64  `json:"blocked_ns"`
+	Outcomes   Outcomes `json:"outcomes"`
+}
+
+// ShutdownResult is a pool shut down on SIGTERM, Queued is the jobs still waiting when the signal arrived, Drained
+// whether every one of them ran before the grace ran out
+type ShutdownResult struct {
+	Name       string   `json:"name"`
+	Submitted  int      `json:"submitted"`
+	Queued     int      `json:"queued"`
+	ShutdownNs float64  `json:"shutdown_ns"`
+	Drained    bool     `json:"drained"`
+	Outcomes   Outcomes `json:"outcomes"`
+}
+
+// RunResult is everything a run measured, in a form that can be written as JSON
+type RunResult struct {
+	Config    RunConfig        `json:"config"`
+	Env       bench.Env        `json:"env"`
+	Slow      int              `json:"slow"`
+	Failing   int              `json:"failing"`
+	Pools     []PoolResult     `json:"pools"`
+	Shutdowns []ShutdownResult `json:"shutdowns"`
+}
+
+// sleeper is a job that sleeps for d, or until its context is done, and then fails if fail is set
+func sleeper(d time.Duration, fail bool) Job {
+	return func(ctx context.Context) error {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if fail {
+			return errSimulated
+		}
+		return nil
+	}
+}
+
+// hang is a job stuck on a dependency that never answers, only its context ends it
+func hang(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// makeJobs draws the simulated jobs, the same for every pool, exponential sleeps around work capped well inside the
+// timeout, with a fraction slow, sleeping five times the timeout, and a fraction failing, returning the counts of each
+func makeJobs(rng *rand.Rand, config RunConfig) (jobs []Job, slow, failing int) {
+	hangFor := 5 * config.Timeout
+	if config.Timeout == 0 {
+		hangFor = 20 * config.Work
+	}
+	limit := 4 * config.Work
+	if config.Timeout > 0 {
+		limit = min(limit, config.Timeout/2)
+	}
+	for range config.Jobs {
+		d := min(time.Duration(rng.ExpFloat64()*float64(config.Work)), limit)
+		fail := false
+		switch r := rng.Float64(); {
+		case r < config.Slow:
+			d = hangFor
+			slow++
+		case r < config.Slow+config.Fail:
+			fail = true
+			failing++
+		}
+		jobs = append(jobs, sleeper(d, fail))
+	}
+	return jobs, slow, failing
+}
+
+// measurePool pushes every job through a pool of one shape as fast as it will take them, then drains it
+func measurePool(ctx context.Context, config Config, jobs []Job) PoolResult {
+	pool := New(config)
+	var blocked time.Duration
+	elapsed := bench.Phase(ctx, fmt.Sprintf("pool workers=%d queue=%d", config.Workers, config.QueueDepth), func() {
+		for _, job := range jobs {
+			start := time.Now()
+			if err := pool.Submit(ctx, job); err != nil {
+				slog.Error("failed to submit a job", "err", err)
+				break
+			}
+			blocked += time.Since(start)
+		}
+		pool.Shutdown(ctx)
+	})
+	return PoolResult{
+		Workers:    config.Workers,
+		QueueDepth: config.QueueDepth,
+		ElapsedNs:  float64(elapsed.Nanoseconds()),
+		QueueP50Ns: float64(pool.QueueLatency.Percentile(0.50).Nanoseconds()),
+		QueueP99Ns: float64(pool.QueueLatency.Percentile(0.99).Nanoseconds()),
+		QueueMaxNs: float64(pool.QueueLatency.Max().Nanoseconds()),
+		BlockedNs:  float64(blocked.Nanoseconds()),
+		Outcomes:   pool.Outcomes(),
+	}
+}
+
+// raise sends the process SIGTERM, where that isn't supported, on Windows, it stops the context the signal would
+// have stopped instead
+func raise(stop context.CancelFunc) {
+	self, err := os.FindProcess(os.Getpid())
+	if err == nil {
+		err = self.Signal(syscall.SIGTERM)
+	}
+	if err != nil {
+		slog.Warn("couldn't send SIGTERM, stopping as if it had arrived", "err", err)
+		stop()
+	}
+}
+
+// shutdownOnSignal runs the pool the way a service would, submitting jobs until SIGTERM or Ctrl+C arrives, then
+// shutting down with grace to drain what's queued
+// The lesson raises the signal itself once submitted jobs are in, so every run stops at the same point
+func shutdownOnSignal(name string, config Config, job Job, submitted int, grace time.Duration) ShutdownResult {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	pool := New(config)
+	result := ShutdownResult{Name: name}
+	for range submitted {
+		if err := pool.Submit(ctx, job); err != nil {
+			break
+		}
+		result.Submitted++
+	}
+	raise(stop)
+	<-ctx.Done()
+	result.Queued = pool.Queued()
+	slog.Info("signalled, shutting down", "pool", name, "queued", result.Queued, "grace", grace)
+
+	graceCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	var err error
+	result.ShutdownNs = float64(bench.Phase(context.Background(), "shutdown "+name, func() { err = pool.Shutdown(graceCtx) }).Nanoseconds())
+	result.Drained = err == nil
+	result.Outcomes = pool.Outcomes()
+	return result
+}
+
+func printResults(w io.Writer, result RunResult) {
+	config := result.Config
+	fmt.Fprintln(w, "Worker Pools")
+	fmt.Fprintln(w, "============")
+	fmt.Fprintf(w, "Jobs: %d, sleeping around %v, %d slow and %d failing\nTimeout: %v\nGrace: %v\n",
+		config.Jobs, config.Work, result.Slow, result.Failing, config.Timeout, config.Grace)
+	fmt.Fprintf(w, "Machine: %s\n", result.Env)
+
+	fmt.Fprintln(w, "\n=====Pool shapes=====")
+	fmt.Fprintf(w, "%8s %6s %14s %12s %12s %12s %12s %14s %10s %7s %9s\n",
+		"Workers", "Queue", "Elapsed", "Jobs/s", "Wait p50", "Wait p99", "Wait max", "Blocked", "Completed", "Failed", "Timed out")
+	for _, p := range result.Pools {
+		fmt.Fprintf(w, "%8d %6d %14v %12s %12v %12v %12v %14v %10d %7d %9d\n",
+			p.Workers, p.QueueDepth, time.Duration(p.ElapsedNs), bench.FormatRate(float64(config.Jobs)/(p.ElapsedNs/1e9)),
+			time.Duration(p.QueueP50Ns), time.Duration(p.QueueP99Ns), time.Duration(p.QueueMaxNs), time.Duration(p.BlockedNs),
+			p.Outcomes.Completed, p.Outcomes.Failed, p.Outcomes.TimedOut)
+	}
+	fmt.Fprintln(w, "\nWait is the time from Submit to a worker starting the job, Blocked the producer's time in Submit waiting")
+	fmt.Fprintln(w, "for room, more workers finish sooner while jobs sleep, a deeper queue lets the producer finish sooner, but")
+	fmt.Fprintln(w, "only by leaving jobs waiting longer, it holds the same work behind the same workers")
+
+	fmt.Fprintf(w, "\n=====Shutdown on SIGTERM, %d workers and %d queue slots=====\n", config.Workers, config.Queue)
+	fmt.Fprintf(w, "%-10s %10s %14s %-18s %10s %9s %8s\n", "Jobs", "Submitted", "Shutdown", "Ended", "Completed", "Canceled", "Dropped")
+	for _, s := range result.Shutdowns {
+		ended := "drained"
+		if !s.Drained {
+			ended = "grace ran out"
+		}
+		fmt.Fprintf(w, "%-10s %10d %14v %-18s %10d %9d %8d\n", s.Name, s.Submitted, time.Duration(s.ShutdownNs), ended,
+			s.Outcomes.Completed, s.Outcomes.Canceled, s.Outcomes.Dropped)
+	}
+	fmt.Fprintln(w, "\nShutdown closes the queue to new jobs and waits for the workers to empty it, sleeping jobs all finish")
+	fmt.Fprintln(w, "inside the grace, hanging ones never would, so when the grace runs out the running jobs' contexts are")
+	fmt.Fprintln(w, "cancelled and the queued ones dropped unrun, a job that ignored its context would hold shutdown up forever")
+}
+
+// Main runs the lesson with the given command line arguments, as teachgo workerpool
+func Main(args []string) {
+	fs := bench.NewFlagSet("workerpool", Description)
+	globals := bench.RegisterGlobals(fs)
+	fs.Lookup("format").Usage = "the output format of the results, text, json or bench, Go's benchmark format that benchstat reads"
+	fs.Lookup("trials").Usage = "not supported, the worker pool lesson measures a single run"
+	jobs := fs.Int("jobs", 500, "the number of jobs pushed through each pool")
+	workers := fs.Int("workers", 8, "the workers of the pools the queue depths are timed with, and of the shutdowns")
+	queue := fs.Int("queue", 16, "the queue depth of the pools the workers are timed with, and of the shutdowns")
+	timeout := fs.Duration("timeout", 10*time.Millisecond, "how long a job runs before its context is done, 0 for no limit")
+	work := fs.Duration("work", time.Millisecond, "the mean time a job sleeps for")
+	slow := fs.Float64("slow", 0.02, "the fraction of jobs that sleep five times -timeout, and are cut off by it")
+	fail := fs.Float64("fail", 0.01, "the fraction of jobs that return an error")
+	grace := fs.Duration("grace", 100*time.Millisecond, "how long a shutdown waits for the queued jobs to drain")
+	fs.Parse(args)
+
+	v := bench.NewValidator(fs)
+	v.Add(globals.Check("text", "json", "bench"))
+	v.Check(globals.Trials <= 1, "workerpool measures a single run, -trials isn't supported")
+	v.AtLeast("jobs", *jobs, 1)
+	v.AtLeast("workers", *workers, 1)
+	v.AtLeast("queue", *queue, 0)
+	v.NotNegative("timeout", *timeout)
+	v.NotNegative("work", *work)
+	v.Fraction("slow", *slow)
+	v.Fraction("fail", *fail)
+	v.Check(*slow+*fail <= 1, "-slow and -fail must add up to at most 1, got %g", *slow+*fail)
+	v.NotNegative("grace", *grace)
+	v.Exit()
+	slog.SetDefault(globals.Logger(os.Stderr))
+
+	config := RunConfig{Jobs: *jobs, Workers: *workers, Queue: *queue, Timeout: *timeout, Work: *work, Slow: *slow,
+		Fail: *fail, Grace: *grace, Seed: globals.Seed}
+	result := RunResult{Config: config, Env: bench.CaptureEnv()}
+	rng := rand.New(rand.NewSource(globals.Seed))
+	ctx := context.Background()
+	simulated, slowJobs, failing := makeJobs(rng, config)
+	result.Slow, result.Failing = slowJobs, failing
+
+	for _, n := range workerSweep {
+		slog.Info("timing a pool", "workers", n, "queue", *queue)
+		result.Pools = append(result.Pools, measurePool(ctx, Config{Workers: n, QueueDepth: *queue, JobTimeout: *timeout}, simulated))
+	}
+	for _, depth := range depthSweep {
+		slog.Info("timing a pool", "workers", *workers, "queue", depth)
+		result.Pools = append(result.Pools, measurePool(ctx, Config{Workers: *workers, QueueDepth: depth, JobTimeout: *timeout}, simulated))
+	}
+
+	// the sleeping jobs fill the pool twice over, so the producer has waited on it before the signal, the hanging ones
+	// fill it once, every worker busy and every slot taken, one more would never get in
+	shape := Config{Workers: *workers, QueueDepth: *queue}
+	capacity := *workers + *queue
+	result.Shutdowns = append(result.Shutdowns,
+		shutdownOnSignal("sleeping", shape, sleeper(*work, false), 2*capacity, *grace),
+		shutdownOnSignal("hanging", shape, hang, capacity, *grace),
+	)
+
+	var err error
+	switch globals.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	case "bench":
+		err = writeBenchmarks(os.Stdout, result)
+	default:
+		printResults(os.Stdout, result)
+	}
+	if err != nil {
+		slog.Error("failed to write results", "err", err)
+		os.Exit(1)
+	}
+}
+
+// writeBenchmarks writes a line per pool shape, an op is one job, and a line per shutdown, an op the whole shutdown
+func writeBenchmarks(w io.Writer, result RunResult) error {
+	if err := bench.WriteBenchHeader(w, result.Env, "github.com/joshdurbin/teaching-go/worker_pool"); err != nil {
+		return err
+	}
+	jobs := int64(result.Config.Jobs)
+	benchmarks := []bench.Benchmark{}
+	for _, p := range result.Pools {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name: fmt.Sprintf("Pool/workers=%d/queue=%d", p.Workers, p.QueueDepth),
+			N:    jobs,
+			Metrics: []bench.Metric{
+				bench.NsPerOp(p.ElapsedNs, jobs),
+				{Value: p.QueueP50Ns, Unit: "wait-p50-ns"},
+				{Value: p.QueueP99Ns, Unit: "wait-p99-ns"},
+				{Value: p.BlockedNs / float64(jobs), Unit: "blocked-ns/op"},
+			},
+		})
+	}
+	for _, s := range result.Shutdowns {
+		benchmarks = append(benchmarks, bench.Benchmark{
+			Name:    "Shutdown/" + s.Name,
+			N:       1,
+			Metrics: []bench.Metric{{Value: s.ShutdownNs, Unit: "ns/op"}, {Value: float64(s.Outcomes.Dropped), Unit: "dropped"}},
+		})
+	}
+	return bench.WriteBenchmarks(w, result.Env, benchmarks)
+}
@@ -0,0 +1,180 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/joshdurbin/teaching-go/internal/bench"
+)
+
+// ErrClosed is returned by Submit once Shutdown has begun, the pool takes no more jobs
+var ErrClosed = errors.New("the pool is shut down")
+
+// Job is a unit of work, Run is given a context that's done when the job's timeout passes or the pool is stopped
+// without waiting, a job that never looks at it can't be stopped
+type Job func(ctx context.Context) error
+
+// Config is the shape of a pool
+type Config struct {
+	Workers int
+	// QueueDepth is how many jobs wait for a worker before Submit blocks, 0 hands each job straight to a free worker
+	QueueDepth int
+	// JobTimeout is how long a job runs before its context is done, 0 for no limit
+	JobTimeout time.Duration
+}
+
+// Outcomes counts how the jobs a pool took ended
+// Canceled jobs were running when the pool was stopped without waiting, Dropped ones were still queued and never ran
+type Outcomes struct {
+	Completed int64 `json:"completed"`
+	Failed    int64 `json:"failed"`
+	TimedOut  int64 `json:"timed_out"`
+	Canceled  int64 `json:"canceled"`
+	Dropped   int64 `json:"dropped"`
+}
+
+// Total is every job counted
+func (o Outcomes) Total() int64 {
+	return o.Completed + o.Failed + o.TimedOut + o.Canceled + o.Dropped
+}
+
+// queued is a job and when it was submitted, so a worker can tell how long it waited
+type queued struct {
+	job       Job
+	submitted time.Time
+}
+
+// Pool runs jobs on a fixed number of worker goroutines fed from a bounded queue
+// The bound is the backpressure, when every worker is busy and the queue is full Submit blocks, so a producer
+// faster than the workers slows to their pace instead of piling up jobs in memory without limit
+type Pool struct {
+	config Config
+	queue  chan queued
+	// ctx is the parent of every job's context, cancelled to stop the pool without waiting
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// submitters hold mu for reading while they send, Shutdown takes it for writing to close the queue, so the queue is
+	// never closed under a send
+	mu     sync.RWMutex
+	closed bool
+
+	// QueueLatency is the time from Submit to a worker starting the job
+	QueueLatency *bench.Stats
+	completed    atomic.Int64
+	failed       atomic.Int64
+	timedOut     atomic.Int64
+	canceled     atomic.Int64
+	dropped      atomic.Int64
+}
+
+// New starts a pool's workers
+func New(config Config) *Pool {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		config:       config,
+		queue:        make(chan queued, config.QueueDepth),
+		ctx:          ctx,
+		cancel:       cancel,
+		done:         make(chan struct{}),
+		QueueLatency: bench.NewStats(),
+	}
+	var wg sync.WaitGroup
+	for range config.Workers {
+		wg.Go(p.work)
+	}
+	go func() {
+		wg.Wait()
+		close(p.done)
+	}()
+	return p
+}
+
+// work runs jobs until the queue is closed and empty, once the pool has been stopped it drops what's left unrun
+func (p *Pool) work() {
+	for q := range p.queue {
+		if p.ctx.Err() != nil {
+			p.dropped.Add(1)
+			continue
+		}
+		p.QueueLatency.Record(time.Since(q.submitted))
+		p.run(q.job)
+	}
+}
+
+func (p *Pool) run(job Job) {
+	ctx := p.ctx
+	if p.config.JobTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.JobTimeout)
+		defer cancel()
+	}
+	err := job(ctx)
+	switch {
+	case err == nil:
+		p.completed.Add(1)
+	case errors.Is(err, context.DeadlineExceeded):
+		p.timedOut.Add(1)
+	case errors.Is(err, context.Canceled):
+		p.canceled.Add(1)
+	default:
+		p.failed.Add(1)
+	}
+}
+
+// Submit queues a job, blocking while the queue is full until there's room or ctx is done
+func (p *Pool) Submit(ctx context.Context, job Job) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrClosed
+	}
+	select {
+	case p.queue <- queued{job: job, submitted: time.Now()}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Queued is the number of jobs waiting for a worker
+func (p *Pool) Queued() int {
+	return len(p.queue)
+}
+
+// Shutdown stops taking jobs and waits for the workers to finish every job already queued, the clean drain
+// If ctx is done first it stops waiting, cancelling the running jobs' contexts and dropping the queued ones, and
+// returns ctx's error once the workers have exited, a job that ignores its context keeps them waiting still
+// A Submit blocked on a full queue holds Shutdown up until it gets in or its own context is done
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+	select {
+	case <-p.done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-p.done
+		return ctx.Err()
+	}
+}
+
+// Outcomes counts the jobs so far by how they ended
+func (p *Pool) Outcomes() Outcomes {
+	return Outcomes{
+		Completed: p.completed.Load(),
+		Failed:    p.failed.Load(),
+		TimedOut:  p.timedOut.Load(),
+		Canceled:  p.canceled.Load(),
+		Dropped:   p.dropped.Load(),
+	}
+}
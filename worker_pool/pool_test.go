@@ -0,0 +1,68 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolRunsEveryJob(t *testing.T) {
+	pool := New(Config{Workers: 4, QueueDepth: 2})
+	var ran atomic.Int64
+	for range 100 {
+		if err := pool.Submit(context.Background(), func(ctx context.Context) error {
+			ran.Add(1)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ran.Load() != 100 || pool.Outcomes().Completed != 100 || pool.QueueLatency.Count() != 100 {
+		t.Errorf("ran %d jobs, outcomes %+v, want 100 completed", ran.Load(), pool.Outcomes())
+	}
+	if err := pool.Submit(context.Background(), hang); !errors.Is(err, ErrClosed) {
+		t.Errorf("Submit after Shutdown returned %v, want ErrClosed", err)
+	}
+}
+
+func TestOutcomes(t *testing.T) {
+	pool := New(Config{Workers: 3, JobTimeout: 10 * time.Millisecond})
+	for _, job := range []Job{sleeper(0, false), sleeper(0, true), hang} {
+		pool.Submit(context.Background(), job)
+	}
+	pool.Shutdown(context.Background())
+	if got, want := pool.Outcomes(), (Outcomes{Completed: 1, Failed: 1, TimedOut: 1}); got != want {
+		t.Errorf("outcomes %+v, want %+v", got, want)
+	}
+}
+
+func TestSubmitBlocksOnAFullQueue(t *testing.T) {
+	pool := New(Config{Workers: 1, QueueDepth: 1})
+	pool.Submit(context.Background(), hang)
+	pool.Submit(context.Background(), hang)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	// the worker may not have taken the first job yet, so keep submitting until one blocks
+	var err error
+	for range 3 {
+		if err = pool.Submit(ctx, hang); err != nil {
+			break
+		}
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Submit to a full queue returned %v, want it to block until its deadline", err)
+	}
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := pool.Shutdown(expired); !errors.Is(err, context.Canceled) {
+		t.Errorf("Shutdown returned %v, want the context's error", err)
+	}
+	if out := pool.Outcomes(); out.Canceled != 1 || out.Total() < 2 {
+		t.Errorf("outcomes %+v, want the running job canceled and the queued one dropped", out)
+	}
+}
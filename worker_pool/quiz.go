@@ -0,0 +1,63 @@
+package workerpool
+
+import "github.com/joshdurbin/teaching-go/internal/lesson"
+
+// quiz is asked by teachgo quiz workerpool, it asks how the bounded queue paces the producer, what queue depth and
+// worker count each change, and how timeouts and a graceful shutdown stop jobs
+var quiz = []lesson.Question{
+	{
+		Prompt: "Why does the producer spend so much of the run blocked in Submit?",
+		Choices: []string{
+			"Submit takes the pool's lock for every job and the workers hold it while they run",
+			"Submit sleeps between jobs so the producer doesn't use up the CPU the workers need",
+			"Every job is validated in Submit before it is queued, which takes as long as running it",
+			"It submits faster than jobs finish, so the queue fills and Submit waits for room",
+		},
+		Answer:      3,
+		Explanation: "that's the backpressure that paces it to the workers, without the bound the producer would finish at once and the jobs would pile up in memory, waiting just as long for a worker",
+	},
+	{
+		Prompt: "Why does a deeper queue make the producer block less but jobs wait longer?",
+		Choices: []string{
+			"The extra slots hand jobs over sooner, where they sit behind the others",
+			"A deeper queue is slower to take from, so each worker waits longer for a job",
+			"With more queued, the workers check the queue less often between jobs",
+			"A deep queue runs jobs newest first, so the oldest wait the longest",
+		},
+		Answer:      0,
+		Explanation: "the same workers do the same work, a queue buys smoothing over bursts, not throughput, only more workers, or faster jobs, finish the work sooner",
+	},
+	{
+		Prompt: "Why do more workers finish faster even though the sandbox has a single CPU?",
+		Choices: []string{
+			"Each worker is given a queue of its own, so they never contend for jobs",
+			"The jobs sleep rather than compute, and a sleeping goroutine takes no CPU",
+			"The runtime starts extra threads for the workers on the machine's other cores",
+			"With more workers the timeouts are shorter, so slow jobs give up sooner",
+		},
+		Answer:      1,
+		Explanation: "many jobs can wait at once, jobs that wait on the network or a disk behave the same way, jobs that compute would stop speeding up at GOMAXPROCS workers",
+	},
+	{
+		Prompt: "How does the pool stop the slow jobs at the timeout?",
+		Choices: []string{
+			"It kills the goroutine running the job once the deadline passes",
+			"It stops reading the job's result, which makes the job give up",
+			"Each job runs under a context with the deadline and returns once it's done",
+			"It restarts the worker, abandoning whatever job it was running",
+		},
+		Answer:      2,
+		Explanation: "Go can't stop a goroutine from outside, a job that never checks its context would run on, keeping its worker busy",
+	},
+	{
+		Prompt: "Why does the shutdown of the hanging jobs cancel some jobs and drop the others?",
+		Choices: []string{
+			"SIGTERM is delivered to half of the workers, which stop at once",
+			"The grace runs out, running jobs are cancelled and queued ones dropped",
+			"The queue was deeper than the grace allowed, so the tail was cut",
+			"The sleeping jobs used up the grace before the hanging ones could start",
+		},
+		Answer:      1,
+		Explanation: "the hanging jobs never finish, so the grace runs out, the sleeping jobs all finished inside the grace, draining cleanly, losing nothing that had been accepted",
+	},
+}